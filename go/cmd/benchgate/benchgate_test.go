@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const baselineOutput = `goos: linux
+goarch: amd64
+pkg: github.com/agentic-layer/agent-gateway-krakend/plugin/openai-a2a/openaia2a
+cpu: Intel(R) Xeon(R) Processor
+BenchmarkChatCompletions-8   	   10000	    100000 ns/op	   20000 B/op	     200 allocs/op
+BenchmarkChatCompletions-8   	   10000	    120000 ns/op	   20000 B/op	     200 allocs/op
+BenchmarkChatCompletions-8   	   10000	    110000 ns/op	   20000 B/op	     200 allocs/op
+BenchmarkTransformOpenAIToA2A-8	 1000000	       800 ns/op	     400 B/op	       7 allocs/op
+PASS
+pkg: github.com/agentic-layer/agent-gateway-krakend/plugin/a2a-openai/a2aopenai
+BenchmarkMessageSend-8 	 2026/10/17 - 21:51:48.245 ▶ INFO  [A2A-OPENAI] exposing 1 OpenAI models as A2A agents
+ 2026/10/17 - 21:51:48.254 ▶ INFO  [A2A-OPENAI] exposing 1 OpenAI models as A2A agents
+   14314	     87133 ns/op	   20319 B/op	     178 allocs/op
+PASS
+`
+
+func TestParseRun(t *testing.T) {
+	r, err := parseRun(strings.NewReader(baselineOutput))
+	require.NoError(t, err)
+
+	assert.Equal(t, "Intel(R) Xeon(R) Processor", r.cpu)
+	chat := r.samples["github.com/agentic-layer/agent-gateway-krakend/plugin/openai-a2a/openaia2a BenchmarkChatCompletions"]
+	assert.Equal(t, []float64{100000, 120000, 110000}, chat["ns/op"])
+	assert.Equal(t, 110000.0, median(chat["ns/op"]))
+	send := r.samples["github.com/agentic-layer/agent-gateway-krakend/plugin/a2a-openai/a2aopenai BenchmarkMessageSend"]
+	assert.Equal(t, []float64{178}, send["allocs/op"], "log lines between name and results are skipped")
+}
+
+func TestCompare(t *testing.T) {
+	baseline, err := parseRun(strings.NewReader(baselineOutput))
+	require.NoError(t, err)
+	current, err := parseRun(strings.NewReader(`pkg: github.com/agentic-layer/agent-gateway-krakend/plugin/openai-a2a/openaia2a
+cpu: Intel(R) Xeon(R) Processor
+BenchmarkChatCompletions-4   	   10000	    130000 ns/op	   21000 B/op	     240 allocs/op
+BenchmarkTransformOpenAIToA2A-4	 1000000	       700 ns/op	     400 B/op	       7 allocs/op
+BenchmarkTransformA2AToOpenAI-4	  400000	      2900 ns/op	    1424 B/op	       5 allocs/op
+`))
+	require.NoError(t, err)
+
+	r := compare(baseline, current, thresholds{time: 0.25, mem: 0.10})
+
+	assert.Equal(t, 1, r.regressions)
+	assert.Equal(t, []string{
+		"ok: github.com/agentic-layer/agent-gateway-krakend/plugin/openai-a2a/openaia2a BenchmarkChatCompletions ns/op 110000 -> 130000 (+18.2%)",
+		"ok: github.com/agentic-layer/agent-gateway-krakend/plugin/openai-a2a/openaia2a BenchmarkChatCompletions B/op 20000 -> 21000 (+5.0%)",
+		"regression: github.com/agentic-layer/agent-gateway-krakend/plugin/openai-a2a/openaia2a BenchmarkChatCompletions allocs/op 200 -> 240 (+20.0%)",
+		"new: github.com/agentic-layer/agent-gateway-krakend/plugin/openai-a2a/openaia2a BenchmarkTransformA2AToOpenAI",
+		"ok: github.com/agentic-layer/agent-gateway-krakend/plugin/openai-a2a/openaia2a BenchmarkTransformOpenAIToA2A ns/op 800 -> 700 (-12.5%)",
+		"ok: github.com/agentic-layer/agent-gateway-krakend/plugin/openai-a2a/openaia2a BenchmarkTransformOpenAIToA2A B/op 400 -> 400 (+0.0%)",
+		"ok: github.com/agentic-layer/agent-gateway-krakend/plugin/openai-a2a/openaia2a BenchmarkTransformOpenAIToA2A allocs/op 7 -> 7 (+0.0%)",
+	}, r.lines)
+}
+
+func TestCompare_OtherCPU(t *testing.T) {
+	baseline := &run{cpu: "A", samples: map[string]map[string][]float64{"pkg BenchmarkX": {"ns/op": {100}, "allocs/op": {0}}}}
+	current := &run{cpu: "B", samples: map[string]map[string][]float64{"pkg BenchmarkX": {"ns/op": {200}, "allocs/op": {1}}}}
+
+	r := compare(baseline, current, thresholds{time: 0.25, mem: 0.10})
+
+	assert.Equal(t, 1, r.regressions, "only the new allocation counts")
+	assert.Equal(t, []string{
+		"warning: baseline was recorded on 'A', not 'B'; ns/op regressions are not counted",
+		"warning: pkg BenchmarkX ns/op 100 -> 200 (+100.0%)",
+		"regression: pkg BenchmarkX allocs/op 0 -> 1 (+inf)",
+	}, r.lines)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Metrics compared by benchgate, in report order
+var metrics = []string{"ns/op", "B/op", "allocs/op"}
+
+// procsSuffix is the GOMAXPROCS suffix go test appends to benchmark names
+var procsSuffix = regexp.MustCompile(`-\d+$`)
+
+// run holds the results of a `go test -bench` run per package and benchmark
+type run struct {
+	cpu string
+	// samples holds the values of each metric per benchmark key, one per -count repetition
+	samples map[string]map[string][]float64
+}
+
+// parseRun reads `go test -bench -benchmem` output. Log lines that plugins print between the
+// name and the results of a benchmark are skipped.
+func parseRun(r io.Reader) (*run, error) {
+	result := &run{samples: make(map[string]map[string][]float64)}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	pkg, pending := "", ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "pkg: "):
+			pkg = strings.TrimPrefix(line, "pkg: ")
+			continue
+		case strings.HasPrefix(line, "cpu: "):
+			result.cpu = strings.TrimPrefix(line, "cpu: ")
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) > 0 && strings.HasPrefix(fields[0], "Benchmark") {
+			pending = pkg + " " + procsSuffix.ReplaceAllString(fields[0], "")
+			fields = fields[1:]
+		}
+		if pending == "" || len(fields) < 3 || !isCount(fields[0]) {
+			continue
+		}
+		if result.samples[pending] == nil {
+			result.samples[pending] = make(map[string][]float64)
+		}
+		for i := 1; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value '%s' of %s", fields[i], pending)
+			}
+			unit := fields[i+1]
+			result.samples[pending][unit] = append(result.samples[pending][unit], value)
+		}
+		pending = ""
+	}
+	return result, scanner.Err()
+}
+
+func isCount(field string) bool {
+	_, err := strconv.Atoi(field)
+	return err == nil
+}
+
+// median returns the median of the samples of a metric, which is robust against single noisy runs
+func median(samples []float64) float64 {
+	sorted := append([]float64{}, samples...)
+	sort.Float64s(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// thresholds are the allowed relative increases of the metrics
+type thresholds struct {
+	time, mem float64
+}
+
+type report struct {
+	lines       []string
+	regressions int
+}
+
+// compare reports the change of every metric of the benchmarks in both runs. Timings of runs on
+// different CPUs are not comparable, so their regressions are only warnings.
+func compare(baseline, current *run, limits thresholds) report {
+	var r report
+	sameCPU := baseline.cpu == current.cpu
+	if !sameCPU {
+		r.lines = append(r.lines, fmt.Sprintf("warning: baseline was recorded on '%s', not '%s'; ns/op regressions are not counted", baseline.cpu, current.cpu))
+	}
+
+	keys := make([]string, 0, len(current.samples))
+	for key := range current.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		old, ok := baseline.samples[key]
+		if !ok {
+			r.lines = append(r.lines, fmt.Sprintf("new: %s", key))
+			continue
+		}
+		for _, metric := range metrics {
+			if len(old[metric]) == 0 || len(current.samples[key][metric]) == 0 {
+				continue
+			}
+			before, after := median(old[metric]), median(current.samples[key][metric])
+			limit := limits.mem
+			if metric == "ns/op" {
+				limit = limits.time
+			}
+			status := "ok"
+			if exceeds(before, after, limit) {
+				status = "regression"
+				if metric == "ns/op" && !sameCPU {
+					status = "warning"
+				} else {
+					r.regressions++
+				}
+			}
+			r.lines = append(r.lines, fmt.Sprintf("%s: %s %s %s -> %s (%s)", status, key, metric, format(before), format(after), delta(before, after)))
+		}
+	}
+	return r
+}
+
+// exceeds reports whether after is more than limit above before. Metrics that were zero regress
+// as soon as they are not, e.g. a benchmark that starts allocating.
+func exceeds(before, after, limit float64) bool {
+	if before == 0 {
+		return after > 0
+	}
+	return (after-before)/before > limit
+}
+
+func format(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func delta(before, after float64) string {
+	if before == 0 {
+		if after == 0 {
+			return "~"
+		}
+		return "+inf"
+	}
+	return fmt.Sprintf("%+.1f%%", (after-before)/before*100)
+}
@@ -0,0 +1,62 @@
+// Command benchgate compares `go test -bench` output with a stored baseline:
+//
+//	benchgate [-time 0.25] [-mem 0.10] -baseline bench/baseline.txt results.txt
+//
+// Results are read from stdin if their path is "-". Benchmarks that got slower or allocate more
+// than the thresholds allow are reported as regressions, and benchgate exits with status 1.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+func main() {
+	baselinePath := flag.String("baseline", "", "go test -bench output to compare with")
+	timeThreshold := flag.Float64("time", 0.25, "allowed relative increase of ns/op")
+	memThreshold := flag.Float64("mem", 0.10, "allowed relative increase of B/op and allocs/op")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s -baseline <baseline.txt> <results.txt|->\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *baselinePath == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	baseline, err := readRun(*baselinePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "benchgate:", err)
+		os.Exit(2)
+	}
+	current, err := readRun(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "benchgate:", err)
+		os.Exit(2)
+	}
+
+	report := compare(baseline, current, thresholds{time: *timeThreshold, mem: *memThreshold})
+	for _, line := range report.lines {
+		fmt.Println(line)
+	}
+	if report.regressions > 0 {
+		fmt.Fprintf(os.Stderr, "benchgate: %d regressions\n", report.regressions)
+		os.Exit(1)
+	}
+}
+
+func readRun(path string) (*run, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	return parseRun(r)
+}
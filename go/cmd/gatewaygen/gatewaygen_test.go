@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const manifest = `
+name: agents
+port: 10000
+external_url: https://agents.example.com/
+auth:
+  api_keys_env: GATEWAY_API_KEYS
+agents:
+  - name: team/weather-agent
+    url: http://weather-agent:8000/
+    owned_by: weather-team
+    auth:
+      forward_headers: [Authorization]
+  - name: news-agent
+    url: http://news-agent:8000
+    transports: [jsonrpc]
+  - name: summarizer
+    url: http://summarizer:50051
+    transports: [GRPC, openai]
+`
+
+func TestGenerate(t *testing.T) {
+	m, err := parseManifest([]byte(manifest))
+	require.NoError(t, err)
+
+	cfg, err := generate(m)
+	require.NoError(t, err)
+
+	assert.Equal(t, "agents", cfg.Name)
+	assert.Equal(t, 10000, cfg.Port)
+	assert.Equal(t, defaultTimeout, cfg.Timeout)
+
+	httpServer := cfg.ExtraConfig[httpServerKey].(map[string]interface{})
+	assert.Equal(t, []string{agentCardRW, openAIA2A}, httpServer["name"])
+	assert.Equal(t, map[string]interface{}{"external_url": "https://agents.example.com"}, httpServer["agentcard_rw_config"])
+	assert.Equal(t, map[string]interface{}{
+		"api_keys": map[string]interface{}{"env": "GATEWAY_API_KEYS"},
+		"agents": []interface{}{
+			map[string]interface{}{"model_id": "team/weather-agent", "url": "http://weather-agent:8000", "owned_by": "weather-team"},
+			map[string]interface{}{"model_id": "summarizer", "url": "http://summarizer:50051", "transport": "GRPC"},
+		},
+	}, httpServer["openai_a2a_config"])
+
+	var paths []string
+	for _, e := range cfg.Endpoints {
+		paths = append(paths, e.Method+" "+e.Endpoint)
+	}
+	assert.Equal(t, []string{
+		"GET /team/weather-agent/.well-known/agent-card.json",
+		"POST /team/weather-agent",
+		"GET /news-agent/.well-known/agent-card.json",
+		"POST /news-agent",
+	}, paths)
+	assert.Equal(t, []string{"Authorization"}, cfg.Endpoints[1].InputHeaders)
+	assert.Nil(t, cfg.Endpoints[3].InputHeaders)
+	assert.Equal(t, []backend{{Host: []string{"http://weather-agent:8000"}, URLPattern: agentCardPath}}, cfg.Endpoints[0].Backend)
+	assert.Equal(t, []backend{{Host: []string{"http://news-agent:8000"}, URLPattern: ""}}, cfg.Endpoints[3].Backend)
+}
+
+func TestGenerate_OnlyOpenAI(t *testing.T) {
+	m, err := parseManifest([]byte("agents:\n  - name: a\n    url: http://a:8000\n    transports: [openai]\n"))
+	require.NoError(t, err)
+
+	cfg, err := generate(m)
+	require.NoError(t, err)
+
+	httpServer := cfg.ExtraConfig[httpServerKey].(map[string]interface{})
+	assert.Equal(t, []string{openAIA2A}, httpServer["name"])
+	assert.NotContains(t, httpServer, "agentcard_rw_config")
+	assert.Empty(t, cfg.Endpoints)
+	assert.Equal(t, defaultName, cfg.Name)
+	assert.Equal(t, defaultPort, cfg.Port)
+}
+
+func TestParseManifest_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		wantErr  string
+	}{
+		{name: "no agents", manifest: "name: gw\n", wantErr: "no agents configured"},
+		{name: "unknown key", manifest: "agents:\n  - name: a\n    uri: http://a\n", wantErr: "field uri not found"},
+		{name: "invalid name", manifest: "agents:\n  - name: /a\n    url: http://a\n", wantErr: "invalid name '/a'"},
+		{name: "invalid url", manifest: "agents:\n  - name: a\n    url: a:8000\n", wantErr: "invalid url 'a:8000'"},
+		{name: "duplicate agent", manifest: "agents:\n  - name: a\n    url: http://a\n  - name: a\n    url: http://b\n", wantErr: "duplicate agent 'a'"},
+		{name: "unknown transport", manifest: "agents:\n  - name: a\n    url: http://a\n    transports: [rest]\n", wantErr: "unknown transport 'rest'"},
+		{name: "jsonrpc and grpc", manifest: "agents:\n  - name: a\n    url: http://a\n    transports: [jsonrpc, grpc]\n", wantErr: "are exclusive"},
+		{name: "grpc without openai", manifest: "agents:\n  - name: a\n    url: http://a\n    transports: [grpc]\n", wantErr: "requires openai"},
+		{name: "auth without jsonrpc", manifest: "agents:\n  - name: a\n    url: http://a\n    transports: [openai]\n    auth:\n      forward_headers: [Authorization]\n", wantErr: "auth requires transport jsonrpc"},
+		{name: "empty gateway auth", manifest: "auth: {}\nagents:\n  - name: a\n    url: http://a\n", wantErr: "auth requires api_keys_env or api_keys_file"},
+		{name: "invalid port", manifest: "port: 70000\nagents:\n  - name: a\n    url: http://a\n", wantErr: "invalid port 70000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseManifest([]byte(tt.manifest))
+
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	manifestPath := filepath.Join(dir, "agents.yaml")
+	outputPath := filepath.Join(dir, "krakend.json")
+	require.NoError(t, os.WriteFile(manifestPath, []byte(manifest), 0o600))
+
+	require.NoError(t, run(manifestPath, outputPath))
+
+	raw, err := os.ReadFile(outputPath)
+	require.NoError(t, err)
+	var cfg map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &cfg))
+	assert.Equal(t, krakendSchema, cfg["$schema"])
+	assert.Len(t, cfg["endpoints"], 4)
+}
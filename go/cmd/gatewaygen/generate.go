@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/configschema"
+)
+
+const (
+	krakendSchema  = "https://www.krakend.io/schema/v2.10/krakend.json"
+	pluginFolder   = "/unleash/tentacles/"
+	httpServerKey  = "plugin/http-server"
+	agentCardPath  = "/.well-known/agent-card.json"
+	agentCardRW    = "agentcard-rw"
+	openAIA2A      = "openai-a2a"
+	noOpEncoding   = "no-op"
+	grpcTransport  = "GRPC"
+	handlerComment = "Name order defines handler order. Last entry is outermost/first handler."
+)
+
+// pluginConfigKeys maps the generated plugins to the key of their extra_config block
+var pluginConfigKeys = map[string]string{
+	agentCardRW: "agentcard_rw_config",
+	openAIA2A:   "openai_a2a_config",
+}
+
+// krakendConfig is the generated KrakenD configuration. Fields keep the order of hand-written files.
+type krakendConfig struct {
+	Schema         string                 `json:"$schema"`
+	Version        int                    `json:"version"`
+	Plugin         pluginSettings         `json:"plugin"`
+	Port           int                    `json:"port"`
+	ExtraConfig    map[string]interface{} `json:"extra_config"`
+	Timeout        string                 `json:"timeout"`
+	OutputEncoding string                 `json:"output_encoding"`
+	Name           string                 `json:"name"`
+	Endpoints      []endpoint             `json:"endpoints"`
+}
+
+type pluginSettings struct {
+	Pattern string `json:"pattern"`
+	Folder  string `json:"folder"`
+}
+
+type endpoint struct {
+	Endpoint       string    `json:"endpoint"`
+	OutputEncoding string    `json:"output_encoding"`
+	Method         string    `json:"method"`
+	InputHeaders   []string  `json:"input_headers,omitempty"`
+	Backend        []backend `json:"backend"`
+}
+
+type backend struct {
+	Host       []string `json:"host"`
+	URLPattern string   `json:"url_pattern"`
+}
+
+// generate builds the KrakenD configuration of a validated manifest. Agents with the jsonrpc
+// transport get an agent card and an A2A endpoint, rewritten by agentcard-rw; agents with the
+// openai transport are registered with openai-a2a. The plugin configuration is checked against
+// the plugin schemas, so the gateway does not fail at startup.
+func generate(m *Manifest) (*krakendConfig, error) {
+	cardConfig := map[string]interface{}{}
+	if m.ExternalURL != "" {
+		cardConfig["external_url"] = strings.TrimSuffix(m.ExternalURL, "/")
+	}
+	openAIConfig := map[string]interface{}{}
+	if m.Auth != nil {
+		apiKeys := map[string]interface{}{}
+		if m.Auth.APIKeysEnv != "" {
+			apiKeys["env"] = m.Auth.APIKeysEnv
+		}
+		if m.Auth.APIKeysFile != "" {
+			apiKeys["file"] = m.Auth.APIKeysFile
+		}
+		openAIConfig["api_keys"] = apiKeys
+	}
+
+	endpoints := []endpoint{}
+	var openAIAgents []interface{}
+	for _, agent := range m.Agents {
+		if agent.has(transportJSONRPC) {
+			endpoints = append(endpoints, agentEndpoints(agent)...)
+		}
+		if agent.has(transportOpenAI) {
+			openAIAgents = append(openAIAgents, openAIAgent(agent))
+		}
+	}
+
+	var plugins []string
+	httpServer := map[string]interface{}{"@comment_name": handlerComment}
+	if len(endpoints) > 0 {
+		plugins = append(plugins, agentCardRW)
+		httpServer[pluginConfigKeys[agentCardRW]] = cardConfig
+	}
+	if len(openAIAgents) > 0 {
+		plugins = append(plugins, openAIA2A)
+		openAIConfig["agents"] = openAIAgents
+		httpServer[pluginConfigKeys[openAIA2A]] = openAIConfig
+	}
+	httpServer["name"] = plugins
+
+	for _, plugin := range plugins {
+		if err := configschema.Validate(plugin, httpServer[pluginConfigKeys[plugin]]); err != nil {
+			return nil, fmt.Errorf("generated extra_config.%s is invalid: %w", pluginConfigKeys[plugin], err)
+		}
+	}
+
+	return &krakendConfig{
+		Schema:         krakendSchema,
+		Version:        3,
+		Plugin:         pluginSettings{Pattern: ".so", Folder: pluginFolder},
+		Port:           m.Port,
+		ExtraConfig:    map[string]interface{}{httpServerKey: httpServer},
+		Timeout:        m.Timeout,
+		OutputEncoding: "json",
+		Name:           m.Name,
+		Endpoints:      endpoints,
+	}, nil
+}
+
+// agentEndpoints proxies the agent card and A2A JSON-RPC requests of an agent below its name.
+// Responses are passed through unchanged, so agent cards reach agentcard-rw as sent by the agent.
+func agentEndpoints(agent Agent) []endpoint {
+	var headers []string
+	if agent.Auth != nil {
+		headers = agent.Auth.ForwardHeaders
+	}
+	path := "/" + agent.Name
+	return []endpoint{
+		{
+			Endpoint:       path + agentCardPath,
+			OutputEncoding: noOpEncoding,
+			Method:         http.MethodGet,
+			InputHeaders:   headers,
+			Backend:        []backend{{Host: []string{agent.URL}, URLPattern: agentCardPath}},
+		},
+		{
+			Endpoint:       path,
+			OutputEncoding: noOpEncoding,
+			Method:         http.MethodPost,
+			InputHeaders:   headers,
+			Backend:        []backend{{Host: []string{agent.URL}, URLPattern: ""}},
+		},
+	}
+}
+
+// openAIAgent registers an agent as model of the chat completions API
+func openAIAgent(agent Agent) map[string]interface{} {
+	cfg := map[string]interface{}{
+		"model_id": agent.Name,
+		"url":      agent.URL,
+	}
+	if agent.OwnedBy != "" {
+		cfg["owned_by"] = agent.OwnedBy
+	}
+	if agent.has(transportGRPC) {
+		cfg["transport"] = grpcTransport
+	}
+	return cfg
+}
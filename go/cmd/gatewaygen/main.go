@@ -0,0 +1,66 @@
+// Command gatewaygen generates a KrakenD configuration from a YAML manifest of agents:
+//
+//	gatewaygen [-o krakend.json] agents.yaml
+//
+// The manifest is read from stdin if its path is "-"; the configuration is written to stdout
+// unless -o is given.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+func main() {
+	output := flag.String("o", "", "write the configuration to this file instead of stdout")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-o krakend.json] <manifest.yaml|->\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(flag.Arg(0), *output); err != nil {
+		fmt.Fprintln(os.Stderr, "gatewaygen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(manifestPath, outputPath string) error {
+	var raw []byte
+	var err error
+	if manifestPath == "-" {
+		raw, err = io.ReadAll(os.Stdin)
+	} else {
+		raw, err = os.ReadFile(manifestPath)
+	}
+	if err != nil {
+		return fmt.Errorf("cannot read manifest: %w", err)
+	}
+
+	manifest, err := parseManifest(raw)
+	if err != nil {
+		return err
+	}
+	cfg, err := generate(manifest)
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("cannot encode configuration: %w", err)
+	}
+	out = append(out, '\n')
+
+	if outputPath == "" {
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	return os.WriteFile(outputPath, out, 0o644)
+}
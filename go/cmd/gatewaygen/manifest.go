@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Transports an agent can be exposed with
+const (
+	// transportJSONRPC proxies A2A JSON-RPC requests and the agent card through KrakenD endpoints
+	transportJSONRPC = "jsonrpc"
+	// transportGRPC reaches an A2A gRPC agent from the openai-a2a plugin; KrakenD cannot proxy gRPC
+	transportGRPC = "grpc"
+	// transportOpenAI exposes the agent as a model of the OpenAI chat completions API
+	transportOpenAI = "openai"
+)
+
+const (
+	defaultName    = "agent-gateway-krakend"
+	defaultPort    = 8080
+	defaultTimeout = "360s"
+)
+
+var defaultTransports = []string{transportJSONRPC, transportOpenAI}
+
+// agentNamePattern allows names that are usable as URL path and model ID, e.g. team/weather-agent
+var agentNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*(/[A-Za-z0-9][A-Za-z0-9._-]*)*$`)
+
+// Manifest lists the agents the gateway exposes
+type Manifest struct {
+	Name        string  `yaml:"name"`
+	Port        int     `yaml:"port"`
+	Timeout     string  `yaml:"timeout"`
+	ExternalURL string  `yaml:"external_url"`
+	Auth        *Auth   `yaml:"auth"`
+	Agents      []Agent `yaml:"agents"`
+}
+
+// Agent is an agent reachable at URL. Its name is the gateway path and the OpenAI model ID.
+type Agent struct {
+	Name       string     `yaml:"name"`
+	URL        string     `yaml:"url"`
+	Transports []string   `yaml:"transports"`
+	OwnedBy    string     `yaml:"owned_by"`
+	Auth       *AgentAuth `yaml:"auth"`
+}
+
+// Auth protects the OpenAI endpoints of the gateway with API keys
+type Auth struct {
+	APIKeysEnv  string `yaml:"api_keys_env"`
+	APIKeysFile string `yaml:"api_keys_file"`
+}
+
+// AgentAuth lists the client headers forwarded to an agent, e.g. Authorization
+type AgentAuth struct {
+	ForwardHeaders []string `yaml:"forward_headers"`
+}
+
+// parseManifest decodes a YAML manifest, rejecting unknown keys, and validates it
+func parseManifest(raw []byte) (*Manifest, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(raw))
+	decoder.KnownFields(true)
+	var m Manifest
+	if err := decoder.Decode(&m); err != nil {
+		return nil, fmt.Errorf("cannot parse manifest: %w", err)
+	}
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// validate checks the manifest and applies the defaults
+func (m *Manifest) validate() error {
+	if m.Name == "" {
+		m.Name = defaultName
+	}
+	if m.Port == 0 {
+		m.Port = defaultPort
+	}
+	if m.Port < 0 || m.Port > 65535 {
+		return fmt.Errorf("invalid port %d", m.Port)
+	}
+	if m.Timeout == "" {
+		m.Timeout = defaultTimeout
+	}
+	if m.ExternalURL != "" {
+		if err := validateURL(m.ExternalURL); err != nil {
+			return fmt.Errorf("invalid external_url: %w", err)
+		}
+	}
+	if m.Auth != nil && m.Auth.APIKeysEnv == "" && m.Auth.APIKeysFile == "" {
+		return errors.New("auth requires api_keys_env or api_keys_file")
+	}
+	if len(m.Agents) == 0 {
+		return errors.New("no agents configured")
+	}
+
+	names := make(map[string]bool, len(m.Agents))
+	for i := range m.Agents {
+		agent := &m.Agents[i]
+		if err := agent.validate(); err != nil {
+			return fmt.Errorf("agent %d (%s): %w", i, agent.Name, err)
+		}
+		if names[agent.Name] {
+			return fmt.Errorf("duplicate agent '%s'", agent.Name)
+		}
+		names[agent.Name] = true
+	}
+	return nil
+}
+
+// validate checks the agent and applies the default transports
+func (a *Agent) validate() error {
+	if !agentNamePattern.MatchString(a.Name) {
+		return fmt.Errorf("invalid name '%s', use letters, digits, '.', '_', '-' and '/' separated segments", a.Name)
+	}
+	if err := validateURL(a.URL); err != nil {
+		return err
+	}
+	a.URL = strings.TrimSuffix(a.URL, "/")
+
+	if len(a.Transports) == 0 {
+		a.Transports = slices.Clone(defaultTransports)
+	}
+	for i, transport := range a.Transports {
+		a.Transports[i] = strings.ToLower(transport)
+		switch a.Transports[i] {
+		case transportJSONRPC, transportGRPC, transportOpenAI:
+		default:
+			return fmt.Errorf("unknown transport '%s', expected %s, %s or %s", transport, transportJSONRPC, transportGRPC, transportOpenAI)
+		}
+	}
+	if a.has(transportJSONRPC) && a.has(transportGRPC) {
+		return fmt.Errorf("transports %s and %s are exclusive", transportJSONRPC, transportGRPC)
+	}
+	if a.has(transportGRPC) && !a.has(transportOpenAI) {
+		return fmt.Errorf("transport %s requires %s, as KrakenD cannot proxy gRPC", transportGRPC, transportOpenAI)
+	}
+	if a.Auth != nil && !a.has(transportJSONRPC) {
+		return fmt.Errorf("auth requires transport %s", transportJSONRPC)
+	}
+	return nil
+}
+
+// has reports whether the agent is exposed with a transport
+func (a *Agent) has(transport string) bool {
+	return slices.Contains(a.Transports, transport)
+}
+
+func validateURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return fmt.Errorf("invalid url '%s'", raw)
+	}
+	return nil
+}
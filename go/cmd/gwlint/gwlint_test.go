@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const config = `{
+  "extra_config": {
+    "plugin/http-server": {
+      "name": ["openai-a2a", "a2a-openai"],
+      "openai_a2a_config": {
+        "agents": [
+          {"model_id": "weather", "url": "http://weather:8000", "aliases": ["forecast"]},
+          {"model_id": "forecast", "url": "http://forecast:8000"},
+          {"model_id": "summarizer", "url": "http://summarizer:50051", "transport": "GRPC"}
+        ],
+        "health": {"timout": "1s"}
+      },
+      "a2a_openai_config": {"agents": [{"path": "/llm/gpt", "url": "http://llm:8080", "model": 4}]},
+      "cors_config": {"allowed_origins": ["*"]},
+      "rate_limit_config": {}
+    }
+  },
+  "endpoints": [
+    {"endpoint": "/weather/{id}", "method": "GET"},
+    {"endpoint": "/weather/{name}"},
+    {"endpoint": "/models", "method": "GET"},
+    {"endpoint": "/llm/gpt", "method": "POST"},
+    {"endpoint": "/weather", "method": "POST"}
+  ]
+}`
+
+func TestLint(t *testing.T) {
+	findings, targets, err := lint([]byte(config))
+	require.NoError(t, err)
+
+	var lines []string
+	for _, f := range findings {
+		lines = append(lines, f.String())
+	}
+	assert.ElementsMatch(t, []string{
+		"error: extra_config.plugin/http-server.a2a_openai_config: agents[0].model: got number, want string",
+		"warning: extra_config.plugin/http-server.cors_config: plugin cors is configured but not listed in name",
+		"error: extra_config.plugin/http-server.openai_a2a_config: health: additional properties 'timout' not allowed",
+		"warning: extra_config.plugin/http-server.rate_limit_config: no plugin of this repository reads this key",
+		"error: extra_config.plugin/http-server.openai_a2a_config: model ID 'forecast' of agents[1] is already used by agents[0] (alias)",
+		"error: endpoints[1]: GET /weather/{name} conflicts with endpoints[0]",
+		"warning: endpoints[2]: GET /models is served by plugin openai-a2a and never reaches the endpoint",
+		"warning: endpoints[3]: POST /llm/gpt is served by plugin a2a-openai and never reaches the endpoint",
+	}, lines)
+
+	assert.Equal(t, []target{
+		{Location: "extra_config.plugin/http-server.openai_a2a_config.agents[0].url", URL: "http://weather:8000"},
+		{Location: "extra_config.plugin/http-server.openai_a2a_config.agents[1].url", URL: "http://forecast:8000"},
+		{Location: "extra_config.plugin/http-server.openai_a2a_config.agents[2].url", URL: "http://summarizer:50051", GRPC: true},
+	}, targets)
+}
+
+func TestLint_GatewayModules(t *testing.T) {
+	findings, _, err := lint([]byte(`{
+  "extra_config": {
+    "plugin/http-server": {
+      "name": ["gateway"],
+      "gateway_config": {"openai_a2a": true, "agentcard_rw": false},
+      "openai_a2a_config": {},
+      "agentcard_rw_config": {}
+    }
+  },
+  "endpoints": [{"endpoint": "/models", "method": "GET"}, {"endpoint": "/.well-known/gateway-info", "method": "GET"}]
+}`))
+	require.NoError(t, err)
+
+	var lines []string
+	for _, f := range findings {
+		lines = append(lines, f.String())
+	}
+	assert.ElementsMatch(t, []string{
+		"warning: extra_config.plugin/http-server.agentcard_rw_config: plugin agentcard-rw is configured but neither listed in name nor enabled in gateway_config",
+		"warning: endpoints[0]: GET /models is served by plugin openai-a2a and never reaches the endpoint",
+		"warning: endpoints[1]: GET /.well-known/gateway-info is served by plugin gateway and never reaches the endpoint",
+	}, lines)
+}
+
+func TestLint_LocalConfig(t *testing.T) {
+	raw, err := os.ReadFile("../../../local/krakend.json")
+	require.NoError(t, err)
+
+	findings, targets, err := lint(raw)
+	require.NoError(t, err)
+
+	assert.Empty(t, findings)
+	assert.Len(t, targets, 1)
+}
+
+func TestLint_InvalidJSON(t *testing.T) {
+	_, _, err := lint([]byte(`{`))
+
+	assert.ErrorContains(t, err, "cannot parse configuration")
+}
+
+func TestProbe(t *testing.T) {
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != agentCardPath {
+			http.NotFound(w, r)
+		}
+	}))
+	defer agent.Close()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedURL := "http://" + closed.Addr().String()
+	require.NoError(t, closed.Close())
+
+	findings := probe(context.Background(), []target{
+		{Location: "up", URL: agent.URL},
+		{Location: "no card", URL: agent.URL + "/missing"},
+		{Location: "grpc up", URL: "http://" + listener.Addr().String(), GRPC: true},
+		{Location: "down", URL: closedURL},
+		{Location: "grpc down", URL: closedURL, GRPC: true},
+	}, agent.Client(), time.Second)
+
+	var locations []string
+	for _, f := range findings {
+		assert.Equal(t, severityError, f.Severity)
+		assert.True(t, strings.HasPrefix(f.Message, "agent "), f.Message)
+		locations = append(locations, f.Location)
+	}
+	assert.Equal(t, []string{"no card", "down", "grpc down"}, locations)
+}
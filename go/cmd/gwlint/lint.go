@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/configschema"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+)
+
+const (
+	httpServerKey = "plugin/http-server"
+	agentCardPath = "/.well-known/agent-card.json"
+)
+
+// Severities of findings. Errors fail the lint, warnings are only reported.
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+)
+
+// finding is a problem of the configuration at location, e.g. extra_config.openai_a2a_config
+type finding struct {
+	Severity string
+	Location string
+	Message  string
+}
+
+func (f finding) String() string {
+	return fmt.Sprintf("%s: %s: %s", f.Severity, f.Location, f.Message)
+}
+
+// krakendConfig holds the parts of krakend.json the linter checks
+type krakendConfig struct {
+	ExtraConfig map[string]json.RawMessage `json:"extra_config"`
+	Endpoints   []struct {
+		Endpoint string `json:"endpoint"`
+		Method   string `json:"method"`
+		Backend  []struct {
+			Host []string `json:"host"`
+		} `json:"backend"`
+	} `json:"endpoints"`
+}
+
+// openAIA2AConfig holds the agents of the openai-a2a plugin and its optional gateway card
+type openAIA2AConfig struct {
+	Agents []struct {
+		ModelID   string   `json:"model_id"`
+		URL       string   `json:"url"`
+		Aliases   []string `json:"aliases"`
+		Transport string   `json:"transport"`
+		Ensemble  *struct {
+			URLs []string `json:"urls"`
+		} `json:"ensemble"`
+		LoadBalancing *struct {
+			Replicas []struct {
+				URL string `json:"url"`
+			} `json:"replicas"`
+		} `json:"load_balancing"`
+		Canary *struct {
+			URL string `json:"url"`
+		} `json:"canary"`
+		MirrorTo *struct {
+			URL string `json:"url"`
+		} `json:"mirror_to"`
+	} `json:"agents"`
+	Sandbox *struct {
+		Agents []struct {
+			ModelID string `json:"model_id"`
+		} `json:"agents"`
+	} `json:"sandbox"`
+	AgentCard *struct {
+		IndexPath string `json:"index_path"`
+	} `json:"agent_card"`
+	Health *struct {
+		Endpoint string `json:"endpoint"`
+	} `json:"health"`
+}
+
+// a2aOpenAIConfig holds the agents of the a2a-openai plugin
+type a2aOpenAIConfig struct {
+	Agents []struct {
+		Path string `json:"path"`
+	} `json:"agents"`
+}
+
+// target is an agent URL the probe checks
+type target struct {
+	Location string
+	URL      string
+	GRPC     bool
+}
+
+// linter collects the findings of a configuration
+type linter struct {
+	findings []finding
+	targets  []target
+}
+
+func (l *linter) report(severity, location, format string, args ...interface{}) {
+	l.findings = append(l.findings, finding{Severity: severity, Location: location, Message: fmt.Sprintf(format, args...)})
+}
+
+// lint checks a krakend.json document offline and returns its findings and the agent URLs to probe
+func lint(raw []byte) ([]finding, []target, error) {
+	var cfg krakendConfig
+	if err := json.Unmarshal(raw, &cfg); err != nil {
+		return nil, nil, fmt.Errorf("cannot parse configuration: %w", err)
+	}
+
+	l := &linter{}
+	var httpServer map[string]json.RawMessage
+	if section, ok := cfg.ExtraConfig[httpServerKey]; ok {
+		if err := json.Unmarshal(section, &httpServer); err != nil {
+			l.report(severityError, "extra_config."+httpServerKey, "must be an object")
+		}
+	}
+	plugins := l.lintPlugins(httpServer)
+	l.lintModelIDs(httpServer)
+	l.lintPaths(cfg, httpServer, plugins)
+	return l.findings, l.targets, nil
+}
+
+// lintPlugins validates the extra_config blocks of all plugins against their schemas and returns
+// the enabled plugins
+func (l *linter) lintPlugins(httpServer map[string]json.RawMessage) map[string]bool {
+	const location = "extra_config." + httpServerKey
+	enabled := map[string]bool{}
+	if raw, ok := httpServer["name"]; ok {
+		var names []string
+		if err := json.Unmarshal(raw, &names); err != nil {
+			l.report(severityError, location+".name", "must be a list of plugin names")
+		}
+		for _, name := range names {
+			enabled[name] = true
+		}
+	}
+	// The gateway plugin runs the modules switched on in its configuration, e.g. openai_a2a
+	if enabled["gateway"] {
+		var modules map[string]interface{}
+		_ = json.Unmarshal(httpServer[configKey("gateway")], &modules)
+		for module, on := range modules {
+			if on == true {
+				enabled[strings.ReplaceAll(module, "_", "-")] = true
+			}
+		}
+	}
+
+	known := map[string]string{}
+	for _, plugin := range configschema.Plugins() {
+		known[configKey(plugin)] = plugin
+	}
+	keys := make([]string, 0, len(httpServer))
+	for key := range httpServer {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !strings.HasSuffix(key, "_config") {
+			continue
+		}
+		plugin, ok := known[key]
+		if !ok {
+			l.report(severityWarning, location+"."+key, "no plugin of this repository reads this key")
+			continue
+		}
+		if !enabled[plugin] && enabled["gateway"] {
+			l.report(severityWarning, location+"."+key, "plugin %s is configured but neither listed in name nor enabled in %s", plugin, configKey("gateway"))
+		} else if !enabled[plugin] {
+			l.report(severityWarning, location+"."+key, "plugin %s is configured but not listed in name", plugin)
+		}
+		var config interface{}
+		if err := json.Unmarshal(httpServer[key], &config); err != nil {
+			l.report(severityError, location+"."+key, "%v", err)
+			continue
+		}
+		err := configschema.Validate(plugin, config)
+		if validationErr, ok := err.(*configschema.ValidationError); ok {
+			for _, violation := range validationErr.Violations {
+				l.report(severityError, location+"."+key, "%s", violation)
+			}
+		} else if err != nil {
+			l.report(severityError, location+"."+key, "%v", err)
+		}
+	}
+	return enabled
+}
+
+// lintModelIDs reports model IDs and aliases of openai-a2a that resolve to more than one agent
+// and collects the agent URLs to probe
+func (l *linter) lintModelIDs(httpServer map[string]json.RawMessage) {
+	location := "extra_config." + httpServerKey + "." + configKey("openai-a2a")
+	var cfg openAIA2AConfig
+	if raw, ok := httpServer[configKey("openai-a2a")]; !ok || json.Unmarshal(raw, &cfg) != nil {
+		return
+	}
+
+	owners := map[string]string{}
+	claim := func(id, owner string) {
+		if id == "" {
+			return
+		}
+		if previous, ok := owners[id]; ok {
+			l.report(severityError, location, "model ID '%s' of %s is already used by %s", id, owner, previous)
+			return
+		}
+		owners[id] = owner
+	}
+	for i, agent := range cfg.Agents {
+		owner := fmt.Sprintf("agents[%d]", i)
+		claim(agent.ModelID, owner)
+		for _, alias := range agent.Aliases {
+			claim(alias, owner+" (alias)")
+		}
+
+		grpc := strings.EqualFold(agent.Transport, "GRPC")
+		add := func(field, url string) {
+			if url != "" {
+				l.targets = append(l.targets, target{Location: location + "." + owner + "." + field, URL: url, GRPC: grpc})
+			}
+		}
+		add("url", agent.URL)
+		if agent.Ensemble != nil {
+			for j, url := range agent.Ensemble.URLs {
+				add(fmt.Sprintf("ensemble.urls[%d]", j), url)
+			}
+		}
+		if agent.LoadBalancing != nil {
+			for j, replica := range agent.LoadBalancing.Replicas {
+				add(fmt.Sprintf("load_balancing.replicas[%d].url", j), replica.URL)
+			}
+		}
+		if agent.Canary != nil {
+			add("canary.url", agent.Canary.URL)
+		}
+		if agent.MirrorTo != nil {
+			add("mirror_to.url", agent.MirrorTo.URL)
+		}
+	}
+	if cfg.Sandbox != nil {
+		for i, agent := range cfg.Sandbox.Agents {
+			claim(agent.ModelID, fmt.Sprintf("sandbox.agents[%d]", i))
+		}
+	}
+}
+
+// lintPaths reports endpoints that KrakenD would register twice and endpoints shadowed by paths
+// the enabled plugins serve themselves
+func (l *linter) lintPaths(cfg krakendConfig, httpServer map[string]json.RawMessage, enabled map[string]bool) {
+	routes := map[string]string{}
+	for i, e := range cfg.Endpoints {
+		location := fmt.Sprintf("endpoints[%d]", i)
+		method := strings.ToUpper(e.Method)
+		if method == "" {
+			method = http.MethodGet
+		}
+		route := method + " " + normalizePath(e.Endpoint)
+		if previous, ok := routes[route]; ok {
+			l.report(severityError, location, "%s %s conflicts with %s", method, e.Endpoint, previous)
+			continue
+		}
+		routes[route] = location
+	}
+
+	for _, route := range pluginRoutes(httpServer, enabled) {
+		if endpoint, ok := routes[route.method+" "+normalizePath(route.path)]; ok {
+			l.report(severityWarning, endpoint, "%s %s is served by plugin %s and never reaches the endpoint", route.method, route.path, route.plugin)
+		}
+	}
+}
+
+// pluginRoute is a path an http-server plugin answers without passing the request on
+type pluginRoute struct {
+	plugin string
+	method string
+	path   string
+}
+
+// pluginRoutes lists the routes served by the enabled plugins
+func pluginRoutes(httpServer map[string]json.RawMessage, enabled map[string]bool) []pluginRoute {
+	var routes []pluginRoute
+	// Every plugin of this repository answers the info path
+	for _, plugin := range configschema.Plugins() {
+		if enabled[plugin] {
+			routes = append(routes, pluginRoute{plugin, http.MethodGet, pluginkit.InfoPath})
+			break
+		}
+	}
+	if enabled["openai-a2a"] {
+		routes = append(routes,
+			pluginRoute{"openai-a2a", http.MethodGet, "/models"},
+			pluginRoute{"openai-a2a", http.MethodPost, "/chat/completions"},
+		)
+		var cfg openAIA2AConfig
+		if raw, ok := httpServer[configKey("openai-a2a")]; !ok || json.Unmarshal(raw, &cfg) != nil {
+			cfg = openAIA2AConfig{}
+		}
+		healthEndpoint := "/health"
+		if cfg.Health != nil && cfg.Health.Endpoint != "" {
+			healthEndpoint = cfg.Health.Endpoint
+		}
+		routes = append(routes,
+			pluginRoute{"openai-a2a", http.MethodGet, healthEndpoint},
+			pluginRoute{"openai-a2a", http.MethodGet, healthEndpoint + "/agents"},
+		)
+		if cfg.AgentCard != nil {
+			indexPath := cfg.AgentCard.IndexPath
+			if indexPath == "" {
+				indexPath = "/agents"
+			}
+			routes = append(routes,
+				pluginRoute{"openai-a2a", http.MethodGet, agentCardPath},
+				pluginRoute{"openai-a2a", http.MethodGet, indexPath},
+			)
+		}
+	}
+	if enabled["a2a-openai"] {
+		var cfg a2aOpenAIConfig
+		if raw, ok := httpServer[configKey("a2a-openai")]; ok && json.Unmarshal(raw, &cfg) == nil {
+			for _, agent := range cfg.Agents {
+				path := strings.TrimSuffix(agent.Path, "/")
+				routes = append(routes,
+					pluginRoute{"a2a-openai", http.MethodPost, path},
+					pluginRoute{"a2a-openai", http.MethodGet, path + agentCardPath},
+				)
+			}
+		}
+	}
+	return routes
+}
+
+// normalizePath replaces the names of path parameters, as /a/{x} and /a/{y} are the same route
+func normalizePath(path string) string {
+	segments := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segments[i] = "{}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// configKey returns the extra_config key of a plugin, e.g. openai_a2a_config for openai-a2a
+func configKey(plugin string) string {
+	return strings.ReplaceAll(plugin, "-", "_") + "_config"
+}
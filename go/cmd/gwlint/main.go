@@ -0,0 +1,57 @@
+// Command gwlint checks an existing krakend.json without starting the gateway:
+//
+//	gwlint [-probe] [-timeout 5s] krakend.json
+//
+// The extra_config blocks of the plugins are validated against their schemas, and duplicate
+// model IDs and conflicting endpoint paths are reported. With -probe, every agent URL of the
+// openai-a2a plugin is contacted. gwlint exits with status 1 if it reports errors.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+func main() {
+	probeAgents := flag.Bool("probe", false, "check that the agent URLs are reachable")
+	timeout := flag.Duration("timeout", 5*time.Second, "timeout of each probe")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-probe] [-timeout 5s] <krakend.json>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	raw, err := os.ReadFile(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gwlint:", err)
+		os.Exit(2)
+	}
+	findings, targets, err := lint(raw)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gwlint:", err)
+		os.Exit(2)
+	}
+	if *probeAgents {
+		findings = append(findings, probe(context.Background(), targets, http.DefaultClient, *timeout)...)
+	}
+
+	errorCount := 0
+	for _, f := range findings {
+		fmt.Println(f)
+		if f.Severity == severityError {
+			errorCount++
+		}
+	}
+	if errorCount > 0 {
+		fmt.Fprintf(os.Stderr, "gwlint: %d errors, %d warnings\n", errorCount, len(findings)-errorCount)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// probe checks that every agent URL responds and reports the unreachable ones as errors.
+// HTTP agents must serve their agent card; gRPC agents must accept connections.
+func probe(ctx context.Context, targets []target, client *http.Client, timeout time.Duration) []finding {
+	findings := make([]*finding, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t target) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			var err error
+			if t.GRPC {
+				err = dial(ctx, t.URL)
+			} else {
+				err = fetchCard(ctx, client, t.URL)
+			}
+			if err != nil {
+				findings[i] = &finding{Severity: severityError, Location: t.Location, Message: fmt.Sprintf("agent %s is unreachable: %v", t.URL, err)}
+			}
+		}(i, t)
+	}
+	wg.Wait()
+
+	var result []finding
+	for _, f := range findings {
+		if f != nil {
+			result = append(result, *f)
+		}
+	}
+	return result
+}
+
+func fetchCard(ctx context.Context, client *http.Client, agentURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(agentURL, "/")+agentCardPath, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("agent card returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func dial(ctx context.Context, agentURL string) error {
+	parsed, err := url.Parse(agentURL)
+	if err != nil {
+		return err
+	}
+	port := parsed.Port()
+	if port == "" {
+		port = "80"
+		if parsed.Scheme == "https" {
+			port = "443"
+		}
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(parsed.Hostname(), port))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
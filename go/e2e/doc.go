@@ -0,0 +1,9 @@
+// Package e2e runs scenario tests against a real KrakenD process loading the plugins of this
+// repository, with stub agents served by the tests. The tests are built with the e2e tag and need
+// the KrakenD binary in E2E_KRAKEND and the folder of the built plugins in E2E_PLUGINS:
+//
+//	E2E_KRAKEND=/usr/bin/krakend E2E_PLUGINS=../build go test -tags e2e ./e2e/...
+//
+// The plugins must be built with the Go version and libraries of the KrakenD binary, so the
+// tests are usually run in the e2e stage of the Dockerfile with make e2e.
+package e2e
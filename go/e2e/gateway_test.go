@@ -0,0 +1,165 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	httpServerKey  = "plugin/http-server"
+	startupTimeout = 20 * time.Second
+)
+
+// gateway is a KrakenD process running with the plugins under test
+type gateway struct {
+	URL string
+}
+
+// gatewayConfig is the part of krakend.json a scenario defines. Plugins are listed innermost first.
+type gatewayConfig struct {
+	Plugins   []string
+	Config    map[string]interface{}
+	Endpoints []interface{}
+}
+
+// syncBuffer collects the output of KrakenD, which is written while tests read it
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// startGateway writes the configuration, starts KrakenD and waits until it is healthy.
+// KrakenD is stopped when the test ends; its output is logged if the test failed.
+func startGateway(t *testing.T, cfg gatewayConfig) *gateway {
+	t.Helper()
+	krakend, plugins := os.Getenv("E2E_KRAKEND"), os.Getenv("E2E_PLUGINS")
+	if krakend == "" || plugins == "" {
+		t.Skip("E2E_KRAKEND and E2E_PLUGINS are not set")
+	}
+	plugins, err := filepath.Abs(plugins)
+	require.NoError(t, err)
+
+	port := freePort(t)
+	httpServer := map[string]interface{}{"name": cfg.Plugins}
+	for key, value := range cfg.Config {
+		httpServer[key] = value
+	}
+	endpoints := cfg.Endpoints
+	if endpoints == nil {
+		endpoints = []interface{}{}
+	}
+	config := map[string]interface{}{
+		"version": 3,
+		"port":    port,
+		"plugin":  map[string]interface{}{"pattern": ".so", "folder": plugins + "/"},
+		"extra_config": map[string]interface{}{
+			httpServerKey:       httpServer,
+			"telemetry/logging": map[string]interface{}{"level": "DEBUG", "stdout": true},
+		},
+		"timeout":         "10s",
+		"output_encoding": "json",
+		"endpoints":       endpoints,
+	}
+	raw, err := json.MarshalIndent(config, "", "  ")
+	require.NoError(t, err)
+	configPath := filepath.Join(t.TempDir(), "krakend.json")
+	require.NoError(t, os.WriteFile(configPath, raw, 0o600))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	output := &syncBuffer{}
+	cmd := exec.CommandContext(ctx, krakend, "run", "-c", configPath)
+	cmd.Stdout, cmd.Stderr = output, output
+	require.NoError(t, cmd.Start())
+	exited := make(chan struct{})
+	go func() {
+		_ = cmd.Wait()
+		close(exited)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-exited
+		if t.Failed() {
+			t.Logf("krakend output:\n%s", output.String())
+		}
+	})
+
+	gw := &gateway{URL: fmt.Sprintf("http://127.0.0.1:%d", port)}
+	deadline := time.Now().Add(startupTimeout)
+	for {
+		select {
+		case <-exited:
+			t.Fatalf("krakend exited during startup:\n%s", output.String())
+		default:
+		}
+		if resp, err := http.Get(gw.URL + "/__health"); err == nil {
+			_ = resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return gw
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("krakend not healthy after %s:\n%s", startupTimeout, output.String())
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// post sends a JSON body to the gateway
+func (gw *gateway) post(t *testing.T, path string, body interface{}) *http.Response {
+	t.Helper()
+	raw, err := json.Marshal(body)
+	require.NoError(t, err)
+	resp, err := http.Post(gw.URL+path, "application/json", bytes.NewReader(raw))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+// freePort returns a port that is free at the time of the call
+func freePort(t *testing.T) int {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().(*net.TCPAddr).Port
+}
+
+// endpoint proxies method and path of the gateway unchanged to the path of a backend
+func endpoint(method, path, host, backendPath string) map[string]interface{} {
+	return map[string]interface{}{
+		"endpoint":        path,
+		"method":          method,
+		"output_encoding": "no-op",
+		"backend": []interface{}{map[string]interface{}{
+			"host":        []string{host},
+			"url_pattern": backendPath,
+			"encoding":    "no-op",
+		}},
+	}
+}
@@ -0,0 +1,171 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aclient"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentCardRewrite(t *testing.T) {
+	agent := newStubAgent(t, "echo")
+	gw := startGateway(t, gatewayConfig{
+		Plugins: []string{"body-logger", "agentcard-rw"},
+		Config:  map[string]interface{}{"agentcard_rw_config": map[string]interface{}{}},
+		Endpoints: []interface{}{
+			endpoint(http.MethodGet, "/echo"+a2aclient.AgentCardPath, agent.URL, a2aclient.AgentCardPath),
+		},
+	})
+
+	client, err := a2aclient.New(gw.URL+"/echo", a2aclient.Options{})
+	require.NoError(t, err)
+	card, err := client.GetAgentCard(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "echo", card.Name)
+	assert.Equal(t, gw.URL+"/echo", card.Url)
+	assert.NotContains(t, card.Url, agent.URL)
+}
+
+func TestA2AProxy(t *testing.T) {
+	agent := newStubAgent(t, "echo")
+	gw := startGateway(t, gatewayConfig{
+		Plugins: []string{"agentcard-rw"},
+		Endpoints: []interface{}{
+			endpoint(http.MethodPost, "/echo", agent.URL, ""),
+		},
+	})
+
+	client, err := a2aclient.New(gw.URL+"/echo", a2aclient.Options{})
+	require.NoError(t, err)
+	task, err := client.SendMessage(context.Background(), userMessage("hello"))
+	require.NoError(t, err)
+
+	assert.Equal(t, models.TaskStateCompleted, task.Status.State)
+	require.Len(t, task.Artifacts, 1)
+	assert.Equal(t, models.TextPart{Kind: "text", Text: "echo: hello"}, task.Artifacts[0].Parts[0])
+}
+
+func TestChatCompletion(t *testing.T) {
+	agent := newStubAgent(t, "echo")
+	gw := startGateway(t, gatewayConfig{
+		Plugins: []string{"openai-a2a"},
+		Config: map[string]interface{}{"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{map[string]interface{}{"model_id": "test/echo", "url": agent.URL, "owned_by": "e2e"}},
+		}},
+	})
+
+	resp, err := http.Get(gw.URL + "/models")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var modelList models.OpenAIModelsResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&modelList))
+	require.Len(t, modelList.Data, 1)
+	assert.Equal(t, "test/echo", modelList.Data[0].ID)
+
+	resp = gw.post(t, "/chat/completions", map[string]interface{}{
+		"model":    "test/echo",
+		"messages": []interface{}{map[string]interface{}{"role": "user", "content": "hello"}},
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var completion models.OpenAIResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&completion))
+	require.Len(t, completion.Choices, 1)
+	assert.Equal(t, "echo: hello", completion.Choices[0].Message.Content)
+	assert.Equal(t, "test/echo", completion.Model)
+}
+
+func TestStreaming(t *testing.T) {
+	llm := newStubLLM(t)
+	gw := startGateway(t, gatewayConfig{
+		Plugins: []string{"a2a-openai"},
+		Config: map[string]interface{}{"a2a_openai_config": map[string]interface{}{
+			"agents": []interface{}{map[string]interface{}{"path": "/llm/echo", "url": llm.URL + "/v1", "model": "echo", "api_key": "sk-e2e"}},
+		}},
+	})
+
+	client, err := a2aclient.New(gw.URL+"/llm/echo", a2aclient.Options{})
+	require.NoError(t, err)
+	stream, err := client.StreamMessage(context.Background(), userMessage("hello streaming world"))
+	require.NoError(t, err)
+	defer stream.Close()
+
+	var kinds []string
+	var text strings.Builder
+	var final models.SendStreamingMessageSuccessResponseResult
+	for {
+		event, err := stream.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		kinds = append(kinds, event.Kind)
+		for _, part := range event.Artifact.Parts {
+			if partText, ok := models.PartText(part); ok {
+				text.WriteString(partText)
+			}
+		}
+		final = event
+	}
+
+	assert.Equal(t, "echo: hello streaming world", text.String())
+	assert.Greater(t, len(kinds), 3, "the reply is streamed in several events")
+	assert.Equal(t, "status-update", final.Kind)
+	assert.True(t, final.Final)
+	assert.Equal(t, models.TaskStateCompleted, final.Status.State)
+}
+
+func TestGatewayPlugin(t *testing.T) {
+	agent := newStubAgent(t, "echo")
+	gw := startGateway(t, gatewayConfig{
+		Plugins: []string{"gateway"},
+		Config: map[string]interface{}{
+			"gateway_config":      map[string]interface{}{"agentcard_rw": true, "openai_a2a": true},
+			"agentcard_rw_config": map[string]interface{}{},
+			"openai_a2a_config": map[string]interface{}{
+				"agents": []interface{}{map[string]interface{}{"model_id": "test/echo", "url": agent.URL}},
+			},
+		},
+		Endpoints: []interface{}{
+			endpoint(http.MethodGet, "/echo"+a2aclient.AgentCardPath, agent.URL, a2aclient.AgentCardPath),
+		},
+	})
+
+	client, err := a2aclient.New(gw.URL+"/echo", a2aclient.Options{})
+	require.NoError(t, err)
+	card, err := client.GetAgentCard(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, gw.URL+"/echo", card.Url)
+
+	resp := gw.post(t, "/chat/completions", map[string]interface{}{
+		"model":    "test/echo",
+		"messages": []interface{}{map[string]interface{}{"role": "user", "content": "hello"}},
+	})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var completion models.OpenAIResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&completion))
+	require.Len(t, completion.Choices, 1)
+	assert.Equal(t, "echo: hello", completion.Choices[0].Message.Content)
+
+	infoResp, err := http.Get(gw.URL + pluginkit.InfoPath)
+	require.NoError(t, err)
+	defer infoResp.Body.Close()
+	var info pluginkit.GatewayInfo
+	require.NoError(t, json.NewDecoder(infoResp.Body).Decode(&info))
+	var names []string
+	for _, plugin := range info.Plugins {
+		names = append(names, plugin.Name)
+	}
+	assert.Equal(t, []string{"agentcard-rw", "gateway", "openai-a2a"}, names)
+}
@@ -0,0 +1,62 @@
+//go:build e2e
+
+package e2e
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/streamconv"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/testagent"
+)
+
+// newStubAgent starts an agent that answers every message with "echo: <text>", streamed word by
+// word. Its agent card advertises the URL of the stub, so rewriting by the gateway is visible.
+func newStubAgent(t *testing.T, name string) *testagent.Agent {
+	agent := testagent.New(testagent.Options{Name: name})
+	t.Cleanup(agent.Close)
+	return agent
+}
+
+// newStubLLM serves OpenAI chat completions, streamed if requested, answering "echo: <last message>"
+func newStubLLM(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req models.OpenAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		reply := "echo: " + req.Messages[len(req.Messages)-1].Content
+
+		if !req.Stream {
+			_ = pluginkit.WriteJSON(w, http.StatusOK, map[string]interface{}{
+				"id":      "c1",
+				"object":  "chat.completion",
+				"choices": []interface{}{map[string]interface{}{"index": 0, "message": map[string]interface{}{"role": "assistant", "content": reply}, "finish_reason": "stop"}},
+			})
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		chunks := streamconv.NewOpenAIWriter(w)
+		for _, word := range strings.SplitAfter(reply, " ") {
+			_ = chunks.Write(models.OpenAIChatCompletionChunk{ID: "c1", Object: "chat.completion.chunk", Choices: []models.OpenAIChunkChoice{{Delta: models.OpenAIDelta{Content: word}}}})
+		}
+		_ = chunks.Done()
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func userMessage(text string) models.MessageSendParams {
+	return models.MessageSendParams{Message: models.Message{
+		Kind:      "message",
+		MessageId: "m1",
+		Role:      models.MessageRoleUser,
+		Parts:     []models.MessagePartsElem{models.TextPart{Kind: "text", Text: text}},
+	}}
+}
@@ -0,0 +1,313 @@
+// Package a2aclient is a client for the JSON-RPC transport of A2A agents.
+//
+// A Client either posts requests to the URL of an agent or hands them to a Transport, e.g. to
+// route them through a KrakenD endpoint or transcode them to gRPC. Attempts can be bounded by a
+// timeout and retried, and an auth hook can sign every HTTP request.
+package a2aclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+)
+
+// AgentCardPath is the well-known path of agent cards below the agent URL
+const AgentCardPath = "/.well-known/agent-card.json"
+
+const (
+	defaultBackoff = 200 * time.Millisecond
+
+	// maxResponseSize limits the response bodies read into memory
+	maxResponseSize = 16 << 20
+)
+
+// ErrNoURL is returned by methods that need the agent URL when the client only has a Transport
+var ErrNoURL = errors.New("client has no agent URL")
+
+// Transport posts a JSON-RPC request body to an agent and returns the response body
+type Transport func(ctx context.Context, body []byte) ([]byte, error)
+
+// RPCError is a JSON-RPC error returned by an agent. The agent is reachable, so it is never
+// retried.
+type RPCError struct {
+	Code    int
+	Message string
+	Data    interface{}
+}
+
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("agent returned JSON-RPC error %d: %s", e.Code, e.Message)
+}
+
+// Response returns the error object of a JSON-RPC error response
+func (e *RPCError) Response() *models.JSONRPCErrorResponseError {
+	return &models.JSONRPCErrorResponseError{Code: e.Code, Message: e.Message, Data: e.Data}
+}
+
+// StatusError is an HTTP response of an agent that is neither OK nor a JSON-RPC error
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", e.StatusCode)
+}
+
+// Options configure a Client. The zero value sends every request once, without timeout, with
+// http.DefaultClient.
+type Options struct {
+	HTTPClient *http.Client
+	// Header is sent with every HTTP request
+	Header http.Header
+	// Auth is called for every HTTP request, e.g. to set a bearer token
+	Auth func(req *http.Request) error
+	// Timeout bounds every attempt. Streams are only bounded until the response headers arrive.
+	Timeout time.Duration
+	// Retries is the number of attempts after the first one. The backoff between attempts
+	// starts at Backoff (default 200ms) and doubles.
+	Retries int
+	Backoff time.Duration
+	// Retryable decides whether a failed attempt is retried. By default transport errors,
+	// timeouts of attempts and 429, 502, 503 and 504 responses are retried.
+	Retryable func(err error) bool
+}
+
+// Client calls one agent. It is safe for concurrent use.
+type Client struct {
+	url       string
+	transport Transport
+	opts      Options
+	lastID    atomic.Int64
+}
+
+// New creates a client for the agent at agentURL, e.g. http://weather-agent:8000
+func New(agentURL string, opts Options) (*Client, error) {
+	parsed, err := url.Parse(agentURL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, fmt.Errorf("invalid agent url '%s'", agentURL)
+	}
+	c := &Client{url: agentURL, opts: opts}
+	c.transport = c.post
+	return c, nil
+}
+
+// NewWithTransport creates a client sending JSON-RPC requests through transport. StreamMessage
+// and GetAgentCard return ErrNoURL.
+func NewWithTransport(transport Transport, opts Options) *Client {
+	return &Client{transport: transport, opts: opts}
+}
+
+// SendMessage sends message/send and returns the Task or Message the agent answered with
+func (c *Client) SendMessage(ctx context.Context, params models.MessageSendParams) (models.SendMessageSuccessResponseResult, error) {
+	var result models.SendMessageSuccessResponseResult
+	err := c.Call(ctx, "message/send", params, &result)
+	return result, err
+}
+
+// GetTask sends tasks/get
+func (c *Client) GetTask(ctx context.Context, params models.TaskQueryParams) (models.Task, error) {
+	var task models.Task
+	err := c.Call(ctx, "tasks/get", params, &task)
+	return task, err
+}
+
+// CancelTask sends tasks/cancel
+func (c *Client) CancelTask(ctx context.Context, params models.TaskIdParams) (models.Task, error) {
+	var task models.Task
+	err := c.Call(ctx, "tasks/cancel", params, &task)
+	return task, err
+}
+
+// Call sends a JSON-RPC request and decodes its result into result, if not nil
+func (c *Client) Call(ctx context.Context, method string, params, result interface{}) error {
+	body, err := json.Marshal(struct {
+		Jsonrpc string      `json:"jsonrpc"`
+		Id      int64       `json:"id"`
+		Method  string      `json:"method"`
+		Params  interface{} `json:"params"`
+	}{Jsonrpc: "2.0", Id: c.lastID.Add(1), Method: method, Params: params})
+	if err != nil {
+		return fmt.Errorf("cannot marshal %s request: %w", method, err)
+	}
+	return c.Do(ctx, body, result)
+}
+
+// Do sends an encoded JSON-RPC request and decodes the result of the response into result,
+// if not nil. JSON-RPC errors are returned as *RPCError.
+func (c *Client) Do(ctx context.Context, body []byte, result interface{}) error {
+	var respBody []byte
+	err := c.retry(ctx, func(ctx context.Context) error {
+		var err error
+		respBody, err = c.transport(ctx, body)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return decodeResponse(respBody, result)
+}
+
+// GetAgentCard fetches the agent card from the well-known path below the agent URL
+func (c *Client) GetAgentCard(ctx context.Context) (models.AgentCard, error) {
+	var card models.AgentCard
+	if c.url == "" {
+		return card, ErrNoURL
+	}
+	var body []byte
+	err := c.retry(ctx, func(ctx context.Context) error {
+		resp, err := c.send(ctx, http.MethodGet, strings.TrimSuffix(c.url, "/")+AgentCardPath, nil, "application/json")
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return &StatusError{StatusCode: resp.StatusCode}
+		}
+		body, err = io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+		return err
+	})
+	if err != nil {
+		return card, err
+	}
+	if err := json.Unmarshal(body, &card); err != nil {
+		return card, fmt.Errorf("invalid agent card: %w", err)
+	}
+	return card, nil
+}
+
+// post is the Transport of clients with an agent URL
+func (c *Client) post(ctx context.Context, body []byte) ([]byte, error) {
+	resp, err := c.send(ctx, http.MethodPost, c.url, body, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read response: %w", err)
+	}
+	if len(respBody) > maxResponseSize {
+		return nil, fmt.Errorf("response exceeds %d bytes", maxResponseSize)
+	}
+	// JSON-RPC errors are reported by decodeResponse regardless of the status code
+	if _, ok := a2aerrors.Parse(respBody); !ok && resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+	return respBody, nil
+}
+
+// send makes an HTTP request with the configured headers and auth hook
+func (c *Client) send(ctx context.Context, method, target string, body []byte, accept string) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request: %w", err)
+	}
+	for name, values := range c.opts.Header {
+		req.Header[name] = append([]string(nil), values...)
+	}
+	req.Header.Del("Content-Length")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", accept)
+	if c.opts.Auth != nil {
+		if err := c.opts.Auth(req); err != nil {
+			return nil, fmt.Errorf("cannot authenticate request: %w", err)
+		}
+	}
+
+	client := c.opts.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
+
+// retry runs attempt until it succeeds, fails with an error that is not retryable or the
+// retries are used up. Every attempt is bounded by the timeout.
+func (c *Client) retry(ctx context.Context, attempt func(ctx context.Context) error) error {
+	backoff := c.opts.Backoff
+	if backoff <= 0 {
+		backoff = defaultBackoff
+	}
+	retryable := c.opts.Retryable
+	if retryable == nil {
+		retryable = Retryable
+	}
+
+	for i := 0; ; i++ {
+		attemptCtx, cancel := ctx, context.CancelFunc(func() {})
+		if c.opts.Timeout > 0 {
+			attemptCtx, cancel = context.WithTimeout(ctx, c.opts.Timeout)
+		}
+		err := attempt(attemptCtx)
+		cancel()
+		if err == nil || i >= c.opts.Retries || ctx.Err() != nil || !retryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// Retryable is the default retry policy: transport errors, timeouts of attempts and 429, 502,
+// 503 and 504 responses are retried, JSON-RPC errors and other statuses are not
+func Retryable(err error) bool {
+	var rpcErr *RPCError
+	var statusErr *StatusError
+	switch {
+	case errors.As(err, &rpcErr):
+		return false
+	case errors.As(err, &statusErr):
+		switch statusErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	return !errors.Is(err, ErrNoURL) && !errors.Is(err, context.Canceled)
+}
+
+// decodeResponse returns the error of a JSON-RPC response as *RPCError or decodes its result
+func decodeResponse(body []byte, result interface{}) error {
+	if rpcErr, ok := a2aerrors.Parse(body); ok {
+		return &RPCError{Code: rpcErr.Code, Message: rpcErr.Message, Data: rpcErr.Data}
+	}
+	var resp struct {
+		Result json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return fmt.Errorf("cannot parse response: %w", err)
+	}
+	if len(resp.Result) == 0 {
+		return fmt.Errorf("cannot parse response: no result")
+	}
+	if result == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("cannot parse response: %w", err)
+	}
+	return nil
+}
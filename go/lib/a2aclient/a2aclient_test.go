@@ -0,0 +1,228 @@
+package a2aclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var helloParams = models.MessageSendParams{Message: models.Message{
+	Kind: "message", MessageId: "m1", Role: models.MessageRoleUser,
+	Parts: []models.MessagePartsElem{models.TextPart{Kind: "text", Text: "Hello"}},
+}}
+
+// newAgent answers JSON-RPC requests with the result of respond, or its error if it is an *RPCError
+func newAgent(t *testing.T, respond func(method string, params map[string]interface{}) interface{}) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Jsonrpc string                 `json:"jsonrpc"`
+			Id      interface{}            `json:"id"`
+			Method  string                 `json:"method"`
+			Params  map[string]interface{} `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "2.0", req.Jsonrpc)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.Id}
+		if rpcErr, ok := respond(req.Method, req.Params).(*RPCError); ok {
+			resp["error"] = rpcErr.Response()
+		} else {
+			resp["result"] = respond(req.Method, req.Params)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		assert.NoError(t, json.NewEncoder(w).Encode(resp))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClient_SendMessage(t *testing.T) {
+	agent := newAgent(t, func(method string, params map[string]interface{}) interface{} {
+		assert.Equal(t, "message/send", method)
+		assert.Equal(t, "m1", params["message"].(map[string]interface{})["messageId"])
+		return map[string]interface{}{"kind": "task", "id": "t1", "contextId": "c1", "status": map[string]interface{}{"state": "completed"}}
+	})
+	client, err := New(agent.URL, Options{})
+	require.NoError(t, err)
+
+	result, err := client.SendMessage(context.Background(), helloParams)
+
+	require.NoError(t, err)
+	assert.Equal(t, "task", result.Kind)
+	assert.Equal(t, "t1", result.Id)
+	assert.Equal(t, models.TaskStateCompleted, result.Status.State)
+}
+
+func TestClient_Tasks(t *testing.T) {
+	agent := newAgent(t, func(method string, params map[string]interface{}) interface{} {
+		if method == "tasks/cancel" {
+			return &RPCError{Code: a2aerrors.CodeTaskNotCancelable, Message: "task is completed"}
+		}
+		assert.Equal(t, "tasks/get", method)
+		return map[string]interface{}{"kind": "task", "id": params["id"], "contextId": "c1", "status": map[string]interface{}{"state": "working"}}
+	})
+	client, err := New(agent.URL, Options{})
+	require.NoError(t, err)
+
+	task, err := client.GetTask(context.Background(), models.TaskQueryParams{Id: "t1"})
+	require.NoError(t, err)
+	assert.Equal(t, "t1", task.Id)
+	assert.Equal(t, models.TaskStateWorking, task.Status.State)
+
+	_, err = client.CancelTask(context.Background(), models.TaskIdParams{Id: "t1"})
+	var rpcErr *RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, a2aerrors.CodeTaskNotCancelable, rpcErr.Code)
+}
+
+func TestClient_RetriesAndAuth(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token-1", r.Header.Get("Authorization"))
+		assert.Equal(t, "tenant-a", r.Header.Get("X-Tenant"))
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"jsonrpc":"2.0","id":1,"result":{"kind":"message","messageId":"r1","role":"agent","parts":[]}}`)
+	}))
+	defer server.Close()
+
+	client, err := New(server.URL, Options{
+		Header:  http.Header{"X-Tenant": {"tenant-a"}},
+		Auth:    func(req *http.Request) error { req.Header.Set("Authorization", "Bearer token-1"); return nil },
+		Retries: 2,
+		Backoff: time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	result, err := client.SendMessage(context.Background(), helloParams)
+
+	require.NoError(t, err)
+	assert.Equal(t, "r1", result.MessageId)
+	assert.Equal(t, int32(3), attempts.Load())
+}
+
+func TestClient_DoesNotRetryRPCErrors(t *testing.T) {
+	var attempts atomic.Int32
+	transport := func(ctx context.Context, body []byte) ([]byte, error) {
+		attempts.Add(1)
+		return []byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32001,"message":"task not found"}}`), nil
+	}
+	client := NewWithTransport(transport, Options{Retries: 3, Backoff: time.Millisecond})
+
+	_, err := client.GetTask(context.Background(), models.TaskQueryParams{Id: "t1"})
+
+	var rpcErr *RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, a2aerrors.CodeTaskNotFound, rpcErr.Code)
+	assert.Equal(t, int32(1), attempts.Load())
+
+	_, err = client.GetAgentCard(context.Background())
+	assert.ErrorIs(t, err, ErrNoURL)
+}
+
+func TestClient_Timeout(t *testing.T) {
+	var attempts atomic.Int32
+	transport := func(ctx context.Context, body []byte) ([]byte, error) {
+		attempts.Add(1)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	client := NewWithTransport(transport, Options{Timeout: 10 * time.Millisecond, Retries: 1, Backoff: time.Millisecond})
+
+	err := client.Call(context.Background(), "tasks/get", models.TaskQueryParams{Id: "t1"}, nil)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, int32(2), attempts.Load())
+}
+
+func TestClient_GetAgentCard(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != AgentCardPath {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"name":"weather","url":"http://weather:8000","version":"1.0.0"}`)
+	}))
+	defer server.Close()
+
+	client, err := New(server.URL+"/", Options{})
+	require.NoError(t, err)
+	card, err := client.GetAgentCard(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, "weather", card.Name)
+
+	client, err = New(server.URL+"/missing", Options{})
+	require.NoError(t, err)
+	_, err = client.GetAgentCard(context.Background())
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+}
+
+func TestClient_StreamMessage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "text/event-stream", r.Header.Get("Accept"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{\"kind\":\"task\",\"id\":\"t1\",\"contextId\":\"c1\",\"status\":{\"state\":\"working\"}}}\n\n")
+		fmt.Fprint(w, ": keep-alive\n\n")
+		fmt.Fprint(w, "data: {\"jsonrpc\":\"2.0\",\"id\":1,\n")
+		fmt.Fprint(w, "data: \"result\":{\"kind\":\"status-update\",\"taskId\":\"t1\",\"contextId\":\"c1\",\"final\":true,\"status\":{\"state\":\"completed\"}}}\n\n")
+	}))
+	defer server.Close()
+	client, err := New(server.URL, Options{Timeout: time.Second})
+	require.NoError(t, err)
+
+	stream, err := client.StreamMessage(context.Background(), helloParams)
+	require.NoError(t, err)
+	defer stream.Close()
+
+	event, err := stream.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "task", event.Kind)
+	event, err = stream.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "status-update", event.Kind)
+	assert.True(t, event.Final)
+	_, err = stream.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestClient_StreamMessageError(t *testing.T) {
+	agent := newAgent(t, func(method string, params map[string]interface{}) interface{} {
+		assert.Equal(t, "message/stream", method)
+		return &RPCError{Code: a2aerrors.CodeUnsupportedOperation, Message: "streaming is not supported"}
+	})
+	client, err := New(agent.URL, Options{})
+	require.NoError(t, err)
+
+	_, err = client.StreamMessage(context.Background(), helloParams)
+
+	var rpcErr *RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, a2aerrors.CodeUnsupportedOperation, rpcErr.Code)
+}
+
+func TestRetryable(t *testing.T) {
+	assert.True(t, Retryable(errors.New("connection refused")))
+	assert.True(t, Retryable(context.DeadlineExceeded))
+	assert.True(t, Retryable(&StatusError{StatusCode: http.StatusBadGateway}))
+	assert.False(t, Retryable(&StatusError{StatusCode: http.StatusBadRequest}))
+	assert.False(t, Retryable(&RPCError{Code: a2aerrors.CodeInternal}))
+	assert.False(t, Retryable(context.Canceled))
+}
@@ -0,0 +1,99 @@
+package a2aclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/streamconv"
+)
+
+// Stream reads the events of a message/stream response
+type Stream struct {
+	body   io.ReadCloser
+	events *streamconv.A2AReader
+	cancel context.CancelFunc
+}
+
+// StreamMessage sends message/stream and returns the stream of events once the agent accepted
+// it. Only establishing the stream is retried. The stream must be closed.
+func (c *Client) StreamMessage(ctx context.Context, params models.MessageSendParams) (*Stream, error) {
+	if c.url == "" {
+		return nil, ErrNoURL
+	}
+	body, err := json.Marshal(models.SendStreamingMessageRequest{
+		Jsonrpc: "2.0",
+		Id:      c.lastID.Add(1),
+		Method:  "message/stream",
+		Params:  params,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal message/stream request: %w", err)
+	}
+
+	// The timeout only applies until the response headers arrive, the stream lives as long as ctx
+	var resp *http.Response
+	var cancel context.CancelFunc
+	err = c.retry(ctx, func(attemptCtx context.Context) error {
+		streamCtx, cancelStream := context.WithCancel(ctx)
+		stop := context.AfterFunc(attemptCtx, cancelStream)
+		var err error
+		resp, err = c.send(streamCtx, http.MethodPost, c.url, body, "text/event-stream")
+		if !stop() && err == nil {
+			// The attempt timed out as the headers arrived
+			_ = resp.Body.Close()
+			err = attemptCtx.Err()
+		} else if err == nil && resp.StatusCode != http.StatusOK {
+			_ = resp.Body.Close()
+			err = &StatusError{StatusCode: resp.StatusCode}
+		}
+		if err != nil {
+			cancelStream()
+			return err
+		}
+		cancel = cancelStream
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Agents that do not stream answer with a single JSON-RPC response
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		defer cancel()
+		defer resp.Body.Close()
+		respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+		if err != nil {
+			return nil, fmt.Errorf("cannot read response: %w", err)
+		}
+		if err := decodeResponse(respBody, nil); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("agent did not return an event stream")
+	}
+
+	return &Stream{body: resp.Body, events: streamconv.NewA2AReader(resp.Body), cancel: cancel}, nil
+}
+
+// Next returns the next event: a Task, Message, TaskStatusUpdateEvent or
+// TaskArtifactUpdateEvent, told apart by Kind. It returns io.EOF at the end of the stream and
+// JSON-RPC errors as *RPCError.
+func (s *Stream) Next() (models.SendStreamingMessageSuccessResponseResult, error) {
+	event, err := s.events.Next()
+	var errorEvent *streamconv.ErrorEvent
+	if errors.As(err, &errorEvent) {
+		return event, &RPCError{Code: errorEvent.Code, Message: errorEvent.Message, Data: errorEvent.Data}
+	}
+	return event, err
+}
+
+// Close ends the stream
+func (s *Stream) Close() error {
+	s.cancel()
+	return s.body.Close()
+}
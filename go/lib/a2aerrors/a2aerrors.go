@@ -0,0 +1,95 @@
+// Package a2aerrors maps A2A JSON-RPC error codes to HTTP statuses and OpenAI error types.
+//
+// The table is shared by all plugins so that the same upstream error is always
+// reported to clients the same way, regardless of which plugin handled the request.
+package a2aerrors
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+)
+
+// JSON-RPC 2.0 and A2A error codes
+const (
+	CodeJSONParse                              = -32700
+	CodeInvalidRequest                         = -32600
+	CodeMethodNotFound                         = -32601
+	CodeInvalidParams                          = -32602
+	CodeInternal                               = -32603
+	CodeTaskNotFound                           = -32001
+	CodeTaskNotCancelable                      = -32002
+	CodePushNotificationNotSupported           = -32003
+	CodeUnsupportedOperation                   = -32004
+	CodeContentTypeNotSupported                = -32005
+	CodeInvalidAgentResponse                   = -32006
+	CodeAuthenticatedExtendedCardNotConfigured = -32007
+)
+
+// OpenAI error types
+const (
+	TypeInvalidRequest = "invalid_request_error"
+	TypeServer         = "server_error"
+)
+
+// Mapping describes how an upstream JSON-RPC error is reported to clients.
+type Mapping struct {
+	// Name is a stable snake_case identifier, used as the OpenAI error code.
+	Name       string
+	HTTPStatus int
+	OpenAIType string
+}
+
+// unknownMapping is used for codes not in the table, e.g. agent-specific errors.
+var unknownMapping = Mapping{Name: "agent_error", HTTPStatus: http.StatusBadGateway, OpenAIType: TypeServer}
+
+// Errors caused by the request the gateway built (parse, invalid request, internal)
+// are reported as 502, since the client cannot fix them.
+var table = map[int]Mapping{
+	CodeJSONParse:                              {Name: "json_parse_error", HTTPStatus: http.StatusBadGateway, OpenAIType: TypeServer},
+	CodeInvalidRequest:                         {Name: "invalid_request", HTTPStatus: http.StatusBadGateway, OpenAIType: TypeServer},
+	CodeMethodNotFound:                         {Name: "method_not_found", HTTPStatus: http.StatusNotImplemented, OpenAIType: TypeServer},
+	CodeInvalidParams:                          {Name: "invalid_params", HTTPStatus: http.StatusBadRequest, OpenAIType: TypeInvalidRequest},
+	CodeInternal:                               {Name: "agent_internal_error", HTTPStatus: http.StatusBadGateway, OpenAIType: TypeServer},
+	CodeTaskNotFound:                           {Name: "task_not_found", HTTPStatus: http.StatusNotFound, OpenAIType: TypeInvalidRequest},
+	CodeTaskNotCancelable:                      {Name: "task_not_cancelable", HTTPStatus: http.StatusConflict, OpenAIType: TypeInvalidRequest},
+	CodePushNotificationNotSupported:           {Name: "push_notification_not_supported", HTTPStatus: http.StatusBadRequest, OpenAIType: TypeInvalidRequest},
+	CodeUnsupportedOperation:                   {Name: "unsupported_operation", HTTPStatus: http.StatusBadRequest, OpenAIType: TypeInvalidRequest},
+	CodeContentTypeNotSupported:                {Name: "content_type_not_supported", HTTPStatus: http.StatusUnsupportedMediaType, OpenAIType: TypeInvalidRequest},
+	CodeInvalidAgentResponse:                   {Name: "invalid_agent_response", HTTPStatus: http.StatusBadGateway, OpenAIType: TypeServer},
+	CodeAuthenticatedExtendedCardNotConfigured: {Name: "extended_card_not_configured", HTTPStatus: http.StatusNotFound, OpenAIType: TypeInvalidRequest},
+}
+
+// Lookup returns the mapping for a JSON-RPC error code.
+// Unknown codes map to 502 Bad Gateway.
+func Lookup(code int) Mapping {
+	if m, ok := table[code]; ok {
+		return m
+	}
+	return unknownMapping
+}
+
+// Parse extracts the error object of a JSON-RPC error response.
+// It returns false if the body is not a JSON-RPC response carrying an error.
+func Parse(body []byte) (*models.JSONRPCErrorResponseError, bool) {
+	var envelope struct {
+		Error *models.JSONRPCErrorResponseError `json:"error"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil || envelope.Error == nil {
+		return nil, false
+	}
+	return envelope.Error, true
+}
+
+// OpenAIError converts a JSON-RPC error into an OpenAI error response and its HTTP status.
+func OpenAIError(rpcErr *models.JSONRPCErrorResponseError) (int, models.OpenAIErrorResponse) {
+	m := Lookup(rpcErr.Code)
+	return m.HTTPStatus, models.OpenAIErrorResponse{
+		Error: models.OpenAIError{
+			Message: rpcErr.Message,
+			Type:    m.OpenAIType,
+			Code:    &m.Name,
+		},
+	}
+}
@@ -0,0 +1,53 @@
+package a2aerrors
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		code       int
+		wantStatus int
+		wantType   string
+	}{
+		{code: CodeTaskNotFound, wantStatus: http.StatusNotFound, wantType: TypeInvalidRequest},
+		{code: CodeContentTypeNotSupported, wantStatus: http.StatusUnsupportedMediaType, wantType: TypeInvalidRequest},
+		{code: CodeTaskNotCancelable, wantStatus: http.StatusConflict, wantType: TypeInvalidRequest},
+		{code: CodeInternal, wantStatus: http.StatusBadGateway, wantType: TypeServer},
+		{code: -31999, wantStatus: http.StatusBadGateway, wantType: TypeServer},
+	}
+
+	for _, tt := range tests {
+		m := Lookup(tt.code)
+		assert.Equal(t, tt.wantStatus, m.HTTPStatus, "code %d", tt.code)
+		assert.Equal(t, tt.wantType, m.OpenAIType, "code %d", tt.code)
+		assert.NotEmpty(t, m.Name)
+	}
+}
+
+func TestParse(t *testing.T) {
+	rpcErr, ok := Parse([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32001,"message":"Task not found"}}`))
+	assert.True(t, ok)
+	assert.Equal(t, CodeTaskNotFound, rpcErr.Code)
+	assert.Equal(t, "Task not found", rpcErr.Message)
+
+	_, ok = Parse([]byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task"}}`))
+	assert.False(t, ok)
+
+	_, ok = Parse([]byte(`not json`))
+	assert.False(t, ok)
+}
+
+func TestOpenAIError(t *testing.T) {
+	rpcErr, _ := Parse([]byte(`{"error":{"code":-32005,"message":"Incompatible content types"}}`))
+
+	status, resp := OpenAIError(rpcErr)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, status)
+	assert.Equal(t, "Incompatible content types", resp.Error.Message)
+	assert.Equal(t, TypeInvalidRequest, resp.Error.Type)
+	assert.Equal(t, "content_type_not_supported", *resp.Error.Code)
+}
@@ -0,0 +1,220 @@
+// Package a2agrpc is a minimal client for the A2A gRPC service (a2a.v1.A2AService).
+//
+// It transcodes A2A JSON-RPC requests to unary gRPC calls and the responses back, so
+// agents that only expose the GRPC transport can be reached through the JSON-RPC paths
+// of the gateway. Messages are encoded directly in the protobuf wire format and sent over
+// HTTP/2 with net/http, without adding a gRPC runtime to the dependencies that plugins
+// must share with KrakenD.
+package a2agrpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+)
+
+const servicePath = "/a2a.v1.A2AService/"
+
+// maxMessageSize matches the default receive limit of gRPC servers
+const maxMessageSize = 4 << 20
+
+// gRPC status codes mapped to A2A errors
+const (
+	codeInvalidArgument    = 3
+	codeNotFound           = 5
+	codeFailedPrecondition = 9
+	codeUnimplemented      = 12
+)
+
+// unaryMethods maps the JSON-RPC methods to their gRPC method and request encoder.
+// Streaming and push notification methods are not transcoded.
+var unaryMethods = map[string]struct {
+	rpc    string
+	encode func(map[string]interface{}) ([]byte, error)
+	decode func([]byte) (map[string]interface{}, error)
+}{
+	"message/send": {rpc: "SendMessage", encode: encodeSendMessageRequest, decode: decodeSendMessageResponse},
+	"tasks/get":    {rpc: "GetTask", encode: encodeTaskRequest, decode: decodeTask},
+	"tasks/cancel": {rpc: "CancelTask", encode: encodeTaskRequest, decode: decodeTask},
+}
+
+// StatusError is a non-OK gRPC status returned by an agent
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("grpc status %d: %s", e.Code, e.Message)
+}
+
+// Client calls the A2A service of one agent. Targets with an https URL are called over TLS,
+// http URLs over HTTP/2 without TLS (h2c). It is safe for concurrent use.
+type Client struct {
+	target string
+	http   *http.Client
+}
+
+// NewClient creates a client for the agent at target, e.g. http://weather-agent:50051. The
+// transport is derived from base, or from http.DefaultTransport if base is nil.
+func NewClient(target string, base *http.Transport) (*Client, error) {
+	parsed, err := url.Parse(target)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return nil, fmt.Errorf("invalid grpc target '%s', expected http://host:port or https://host:port", target)
+	}
+
+	var protocols http.Protocols
+	if parsed.Scheme == "https" {
+		protocols.SetHTTP2(true)
+	} else {
+		protocols.SetUnencryptedHTTP2(true)
+	}
+	if base == nil {
+		base = http.DefaultTransport.(*http.Transport)
+	}
+	transport := base.Clone()
+	transport.Protocols = &protocols
+	return &Client{target: parsed.Scheme + "://" + parsed.Host, http: &http.Client{Transport: transport}}, nil
+}
+
+// Transcode sends a JSON-RPC request over gRPC and returns the JSON-RPC response.
+// Invalid requests, unsupported methods and gRPC statuses of the agent are returned as
+// JSON-RPC error responses; an error is returned only if the agent cannot be reached.
+func (c *Client) Transcode(ctx context.Context, body []byte) ([]byte, error) {
+	var rpcReq struct {
+		Id     interface{}            `json:"id"`
+		Method string                 `json:"method"`
+		Params map[string]interface{} `json:"params"`
+	}
+	if err := json.Unmarshal(body, &rpcReq); err != nil {
+		return errorResponse(nil, a2aerrors.CodeJSONParse, "request body is not valid JSON")
+	}
+
+	method, ok := unaryMethods[rpcReq.Method]
+	if !ok {
+		return errorResponse(rpcReq.Id, a2aerrors.CodeUnsupportedOperation,
+			fmt.Sprintf("method '%s' is not supported over the GRPC transport", rpcReq.Method))
+	}
+	req, err := method.encode(rpcReq.Params)
+	if err != nil {
+		return errorResponse(rpcReq.Id, a2aerrors.CodeInvalidParams, err.Error())
+	}
+
+	resp, err := c.Invoke(ctx, method.rpc, req)
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return errorResponse(rpcReq.Id, statusCode(statusErr.Code), statusErr.Message)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := method.decode(resp)
+	if err != nil {
+		return errorResponse(rpcReq.Id, a2aerrors.CodeInvalidAgentResponse, fmt.Sprintf("invalid %s response: %v", method.rpc, err))
+	}
+	return json.Marshal(struct {
+		Jsonrpc string                 `json:"jsonrpc"`
+		Id      interface{}            `json:"id"`
+		Result  map[string]interface{} `json:"result"`
+	}{Jsonrpc: "2.0", Id: rpcReq.Id, Result: result})
+}
+
+// Invoke makes a unary call of an A2AService method with an encoded request message
+// and returns the encoded response message. Non-OK statuses are returned as *StatusError.
+func (c *Client) Invoke(ctx context.Context, method string, message []byte) ([]byte, error) {
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	frame = append(frame, message...)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.target+servicePath+method, bytes.NewReader(frame))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("Te", "trailers")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxMessageSize+5))
+	if err != nil {
+		return nil, err
+	}
+	// Errors are usually sent as trailers-only responses, i.e. in the headers
+	if err := status(resp.Header, resp.Trailer); err != nil {
+		return nil, err
+	}
+	if len(body) < 5 {
+		return nil, errors.New("response holds no message")
+	}
+	if body[0] != 0 {
+		return nil, errors.New("compressed responses are not supported")
+	}
+	size := binary.BigEndian.Uint32(body[1:5])
+	if int(size) != len(body)-5 {
+		return nil, fmt.Errorf("response message of %d bytes is truncated or exceeds %d bytes", size, maxMessageSize)
+	}
+	return body[5:], nil
+}
+
+// status reads the gRPC status from the headers or trailers
+func status(header, trailer http.Header) error {
+	code, message := header.Get("Grpc-Status"), header.Get("Grpc-Message")
+	if code == "" {
+		code, message = trailer.Get("Grpc-Status"), trailer.Get("Grpc-Message")
+	}
+	if code == "" {
+		return errors.New("response holds no grpc status")
+	}
+	n, err := strconv.Atoi(code)
+	if err != nil {
+		return fmt.Errorf("invalid grpc status '%s'", code)
+	}
+	if n == 0 {
+		return nil
+	}
+	if unescaped, err := url.PathUnescape(message); err == nil {
+		message = unescaped
+	}
+	return &StatusError{Code: n, Message: message}
+}
+
+// statusCode maps a gRPC status code to an A2A JSON-RPC error code
+func statusCode(code int) int {
+	switch code {
+	case codeInvalidArgument:
+		return a2aerrors.CodeInvalidParams
+	case codeNotFound:
+		return a2aerrors.CodeTaskNotFound
+	case codeFailedPrecondition:
+		return a2aerrors.CodeTaskNotCancelable
+	case codeUnimplemented:
+		return a2aerrors.CodeUnsupportedOperation
+	default:
+		return a2aerrors.CodeInternal
+	}
+}
+
+func errorResponse(id interface{}, code int, message string) ([]byte, error) {
+	return json.Marshal(models.JSONRPCErrorResponse{
+		Jsonrpc: "2.0",
+		Id:      id,
+		Error:   models.JSONRPCErrorResponseError{Code: code, Message: message},
+	})
+}
@@ -0,0 +1,187 @@
+package a2agrpc
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// newAgent starts an h2c server answering A2AService calls with handle, which returns
+// the response message or a non-OK status
+func newAgent(t *testing.T, handle func(method string, req []byte) ([]byte, int, string)) string {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, 2, r.ProtoMajor)
+		assert.Equal(t, "application/grpc", r.Header.Get("Content-Type"))
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, uint32(len(body)-5), binary.BigEndian.Uint32(body[1:5]))
+
+		resp, code, message := handle(r.URL.Path[len(servicePath):], body[5:])
+		w.Header().Set("Content-Type", "application/grpc")
+		if code != 0 {
+			w.Header().Set("Grpc-Status", strconv.Itoa(code))
+			w.Header().Set("Grpc-Message", message)
+			return
+		}
+		w.Header().Set("Trailer", "Grpc-Status")
+		frame := make([]byte, 5)
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(resp)))
+		_, _ = w.Write(append(frame, resp...))
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	server.Config.Protocols = &protocols
+	server.Start()
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func encodeCompletedTask(id, contextID, text string) []byte {
+	var part encoder
+	part.string(1, text)
+	var artifact encoder
+	artifact.string(1, "artifact-1")
+	artifact.bytes(5, part)
+	var status encoder
+	status.varint(1, 3)
+	var task encoder
+	task.string(1, id)
+	task.string(2, contextID)
+	task.bytes(3, status)
+	task.bytes(4, artifact)
+	return task
+}
+
+func TestNewClient_InvalidTarget(t *testing.T) {
+	for _, target := range []string{"weather-agent:50051", "grpc://weather-agent:50051", ""} {
+		_, err := NewClient(target, nil)
+		assert.Error(t, err, target)
+	}
+}
+
+func TestTranscode_SendMessage(t *testing.T) {
+	var received map[string]interface{}
+	target := newAgent(t, func(method string, req []byte) ([]byte, int, string) {
+		assert.Equal(t, "SendMessage", method)
+		_ = fields(req, func(num protowire.Number, raw []byte, _ uint64) error {
+			if num == 1 {
+				received, _ = decodeMessage(raw)
+			}
+			return nil
+		})
+		var resp encoder
+		resp.bytes(1, encodeCompletedTask("task-1", "ctx-1", "Sunny."))
+		return resp, 0, ""
+	})
+	client, err := NewClient(target, nil)
+	assert.NoError(t, err)
+
+	body, err := client.Transcode(context.Background(), []byte(`{"jsonrpc":"2.0","id":"req-1","method":"message/send","params":{
+		"message":{"kind":"message","messageId":"m1","contextId":"ctx-1","role":"user","parts":[{"kind":"text","text":"Weather?"},{"kind":"data","data":{"city":"Paris"}}],"metadata":{"source":"test"}},
+		"configuration":{"blocking":true}}}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "m1", received["messageId"])
+	assert.Equal(t, "user", received["role"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"kind": "text", "text": "Weather?"},
+		map[string]interface{}{"kind": "data", "data": map[string]interface{}{"city": "Paris"}},
+	}, received["parts"])
+	assert.Equal(t, map[string]interface{}{"source": "test"}, received["metadata"])
+
+	var resp struct {
+		Id     string `json:"id"`
+		Result struct {
+			Kind      string `json:"kind"`
+			Id        string `json:"id"`
+			ContextId string `json:"contextId"`
+			Status    struct {
+				State string `json:"state"`
+			} `json:"status"`
+			Artifacts []struct {
+				Parts []map[string]interface{} `json:"parts"`
+			} `json:"artifacts"`
+		} `json:"result"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &resp))
+	assert.Equal(t, "req-1", resp.Id)
+	assert.Equal(t, "task", resp.Result.Kind)
+	assert.Equal(t, "task-1", resp.Result.Id)
+	assert.Equal(t, "completed", resp.Result.Status.State)
+	assert.Equal(t, "Sunny.", resp.Result.Artifacts[0].Parts[0]["text"])
+}
+
+func TestTranscode_Errors(t *testing.T) {
+	target := newAgent(t, func(method string, req []byte) ([]byte, int, string) {
+		assert.Equal(t, "GetTask", method)
+		return nil, codeNotFound, "task%20unknown"
+	})
+	client, err := NewClient(target, nil)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		wantMsg  string
+	}{
+		{name: "grpc status", body: `{"jsonrpc":"2.0","id":1,"method":"tasks/get","params":{"id":"t1"}}`, wantCode: a2aerrors.CodeTaskNotFound, wantMsg: "task unknown"},
+		{name: "streaming", body: `{"jsonrpc":"2.0","id":1,"method":"message/stream","params":{}}`, wantCode: a2aerrors.CodeUnsupportedOperation},
+		{name: "missing task id", body: `{"jsonrpc":"2.0","id":1,"method":"tasks/cancel","params":{}}`, wantCode: a2aerrors.CodeInvalidParams},
+		{name: "unsupported part", body: `{"jsonrpc":"2.0","id":1,"method":"message/send","params":{"message":{"parts":[{"kind":"audio"}]}}}`, wantCode: a2aerrors.CodeInvalidParams},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := client.Transcode(context.Background(), []byte(tt.body))
+
+			assert.NoError(t, err)
+			rpcErr, ok := a2aerrors.Parse(body)
+			if assert.True(t, ok) {
+				assert.Equal(t, tt.wantCode, rpcErr.Code)
+				assert.Contains(t, rpcErr.Message, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestTranscode_Unreachable(t *testing.T) {
+	client, err := NewClient("http://127.0.0.1:1", nil)
+	assert.NoError(t, err)
+
+	_, err = client.Transcode(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"tasks/get","params":{"id":"t1"}}`))
+
+	assert.Error(t, err)
+}
+
+func TestCodec_RoundTrip(t *testing.T) {
+	message := map[string]interface{}{
+		"kind":      "message",
+		"messageId": "m1",
+		"contextId": "ctx-1",
+		"taskId":    "task-1",
+		"role":      "agent",
+		"parts": []interface{}{
+			map[string]interface{}{"kind": "text", "text": ""},
+			map[string]interface{}{"kind": "file", "file": map[string]interface{}{"uri": "https://example.com/a.pdf", "mimeType": "application/pdf", "name": "a.pdf"}},
+			map[string]interface{}{"kind": "file", "file": map[string]interface{}{"bytes": "aGVsbG8=", "name": "hello.txt"}},
+		},
+		"extensions": []interface{}{"https://example.com/ext"},
+	}
+
+	encoded, err := encodeMessage(message)
+	assert.NoError(t, err)
+	decoded, err := decodeMessage(encoded)
+
+	assert.NoError(t, err)
+	assert.Equal(t, message, decoded)
+}
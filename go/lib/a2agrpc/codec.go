@@ -0,0 +1,455 @@
+package a2agrpc
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Field numbers and enums of a2a.v1 in the A2A protocol buffers definition.
+// JSON values are the JSON-RPC representation of the same objects.
+
+var roleValues = map[string]uint64{"user": 1, "agent": 2}
+var roleNames = map[uint64]string{1: "user", 2: "agent"}
+
+var stateNames = map[uint64]string{
+	0: "unknown",
+	1: "submitted",
+	2: "working",
+	3: "completed",
+	4: "failed",
+	5: "canceled",
+	6: "input-required",
+	7: "rejected",
+	8: "auth-required",
+}
+
+// encoder appends protobuf fields. Like proto3 scalars, empty strings and zero numbers are omitted.
+type encoder []byte
+
+func (e *encoder) string(num protowire.Number, s string) {
+	if s != "" {
+		e.bytes(num, []byte(s))
+	}
+}
+
+// bytes appends a length-delimited field, also if it is empty, so oneof fields stay set
+func (e *encoder) bytes(num protowire.Number, b []byte) {
+	*e = protowire.AppendTag(*e, num, protowire.BytesType)
+	*e = protowire.AppendBytes(*e, b)
+}
+
+func (e *encoder) varint(num protowire.Number, v uint64) {
+	if v != 0 {
+		*e = protowire.AppendTag(*e, num, protowire.VarintType)
+		*e = protowire.AppendVarint(*e, v)
+	}
+}
+
+// structValue appends a JSON object as google.protobuf.Struct
+func (e *encoder) structValue(num protowire.Number, v interface{}) error {
+	if v == nil {
+		return nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return errors.New("metadata and data must be objects")
+	}
+	s, err := structpb.NewStruct(m)
+	if err != nil {
+		return err
+	}
+	b, err := proto.Marshal(s)
+	if err != nil {
+		return err
+	}
+	e.bytes(num, b)
+	return nil
+}
+
+func str(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+func number(m map[string]interface{}, key string) uint64 {
+	if f, ok := m[key].(float64); ok && f > 0 {
+		return uint64(f)
+	}
+	return 0
+}
+
+func object(m map[string]interface{}, key string) map[string]interface{} {
+	o, _ := m[key].(map[string]interface{})
+	return o
+}
+
+func list(m map[string]interface{}, key string) []interface{} {
+	l, _ := m[key].([]interface{})
+	return l
+}
+
+// encodeSendMessageRequest encodes message/send params as SendMessageRequest
+func encodeSendMessageRequest(params map[string]interface{}) ([]byte, error) {
+	message := object(params, "message")
+	if message == nil {
+		return nil, errors.New("params.message is required")
+	}
+	msg, err := encodeMessage(message)
+	if err != nil {
+		return nil, err
+	}
+	var e encoder
+	e.bytes(1, msg)
+	if cfg := object(params, "configuration"); cfg != nil {
+		var ce encoder
+		for _, mode := range list(cfg, "acceptedOutputModes") {
+			if s, ok := mode.(string); ok {
+				ce.bytes(1, []byte(s))
+			}
+		}
+		ce.varint(3, number(cfg, "historyLength"))
+		if blocking, _ := cfg["blocking"].(bool); blocking {
+			ce.varint(4, 1)
+		}
+		e.bytes(2, ce)
+	}
+	if err := e.structValue(3, params["metadata"]); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// encodeTaskRequest encodes tasks/get and tasks/cancel params as GetTaskRequest or CancelTaskRequest,
+// which identify the task by its resource name
+func encodeTaskRequest(params map[string]interface{}) ([]byte, error) {
+	id := str(params, "id")
+	if id == "" {
+		return nil, errors.New("params.id is required")
+	}
+	var e encoder
+	e.string(1, "tasks/"+id)
+	e.varint(2, number(params, "historyLength"))
+	return e, nil
+}
+
+func encodeMessage(m map[string]interface{}) ([]byte, error) {
+	var e encoder
+	e.string(1, str(m, "messageId"))
+	e.string(2, str(m, "contextId"))
+	e.string(3, str(m, "taskId"))
+	e.varint(4, roleValues[str(m, "role")])
+	for _, part := range list(m, "parts") {
+		pm, ok := part.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("message parts must be objects")
+		}
+		b, err := encodePart(pm)
+		if err != nil {
+			return nil, err
+		}
+		e.bytes(5, b)
+	}
+	if err := e.structValue(6, m["metadata"]); err != nil {
+		return nil, err
+	}
+	for _, ext := range list(m, "extensions") {
+		if s, ok := ext.(string); ok {
+			e.string(7, s)
+		}
+	}
+	return e, nil
+}
+
+func encodePart(p map[string]interface{}) ([]byte, error) {
+	var e encoder
+	switch kind := str(p, "kind"); kind {
+	case "text":
+		e.bytes(1, []byte(str(p, "text")))
+	case "file":
+		file := object(p, "file")
+		var fe encoder
+		if uri := str(file, "uri"); uri != "" {
+			fe.bytes(1, []byte(uri))
+		} else {
+			raw, err := base64.StdEncoding.DecodeString(str(file, "bytes"))
+			if err != nil {
+				return nil, fmt.Errorf("invalid file bytes: %w", err)
+			}
+			fe.bytes(2, raw)
+		}
+		fe.string(3, str(file, "mimeType"))
+		fe.string(4, str(file, "name"))
+		e.bytes(2, fe)
+	case "data":
+		var de encoder
+		if err := de.structValue(1, p["data"]); err != nil {
+			return nil, err
+		}
+		e.bytes(3, de)
+	default:
+		return nil, fmt.Errorf("unsupported part kind '%s'", kind)
+	}
+	if err := e.structValue(4, p["metadata"]); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// fields calls fn for each field of an encoded message. Varint values are passed as v,
+// length-delimited values as raw; other wire types are skipped.
+func fields(b []byte, fn func(num protowire.Number, raw []byte, v uint64) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		var err error
+		switch typ {
+		case protowire.VarintType:
+			var v uint64
+			if v, n = protowire.ConsumeVarint(b); n >= 0 {
+				err = fn(num, nil, v)
+			}
+		case protowire.BytesType:
+			var raw []byte
+			if raw, n = protowire.ConsumeBytes(b); n >= 0 {
+				err = fn(num, raw, 0)
+			}
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		if err != nil {
+			return err
+		}
+		b = b[n:]
+	}
+	return nil
+}
+
+func decodeStruct(raw []byte) (map[string]interface{}, error) {
+	var s structpb.Struct
+	if err := proto.Unmarshal(raw, &s); err != nil {
+		return nil, err
+	}
+	return s.AsMap(), nil
+}
+
+// decodeSendMessageResponse decodes SendMessageResponse, which holds either a task or a message
+func decodeSendMessageResponse(b []byte) (map[string]interface{}, error) {
+	var result map[string]interface{}
+	err := fields(b, func(num protowire.Number, raw []byte, _ uint64) error {
+		var err error
+		switch num {
+		case 1:
+			result, err = decodeTask(raw)
+		case 2:
+			result, err = decodeMessage(raw)
+		}
+		return err
+	})
+	if err == nil && result == nil {
+		err = errors.New("response holds neither a task nor a message")
+	}
+	return result, err
+}
+
+func decodeTask(b []byte) (map[string]interface{}, error) {
+	task := map[string]interface{}{"kind": "task", "id": "", "contextId": ""}
+	err := fields(b, func(num protowire.Number, raw []byte, _ uint64) error {
+		switch num {
+		case 1:
+			task["id"] = string(raw)
+		case 2:
+			task["contextId"] = string(raw)
+		case 3:
+			status, err := decodeStatus(raw)
+			if err != nil {
+				return err
+			}
+			task["status"] = status
+		case 4:
+			artifact, err := decodeArtifact(raw)
+			if err != nil {
+				return err
+			}
+			artifacts, _ := task["artifacts"].([]interface{})
+			task["artifacts"] = append(artifacts, artifact)
+		case 5:
+			message, err := decodeMessage(raw)
+			if err != nil {
+				return err
+			}
+			history, _ := task["history"].([]interface{})
+			task["history"] = append(history, message)
+		case 6:
+			metadata, err := decodeStruct(raw)
+			if err != nil {
+				return err
+			}
+			task["metadata"] = metadata
+		}
+		return nil
+	})
+	if _, ok := task["status"]; !ok {
+		task["status"] = map[string]interface{}{"state": stateNames[0]}
+	}
+	return task, err
+}
+
+func decodeStatus(b []byte) (map[string]interface{}, error) {
+	status := map[string]interface{}{"state": stateNames[0]}
+	err := fields(b, func(num protowire.Number, raw []byte, v uint64) error {
+		switch num {
+		case 1:
+			if name, ok := stateNames[v]; ok {
+				status["state"] = name
+			}
+		case 2:
+			message, err := decodeMessage(raw)
+			if err != nil {
+				return err
+			}
+			status["message"] = message
+		case 3:
+			var ts timestamppb.Timestamp
+			if err := proto.Unmarshal(raw, &ts); err != nil {
+				return err
+			}
+			status["timestamp"] = ts.AsTime().UTC().Format(time.RFC3339Nano)
+		}
+		return nil
+	})
+	return status, err
+}
+
+func decodeArtifact(b []byte) (map[string]interface{}, error) {
+	artifact := map[string]interface{}{"artifactId": "", "parts": []interface{}{}}
+	err := fields(b, func(num protowire.Number, raw []byte, _ uint64) error {
+		switch num {
+		case 1:
+			artifact["artifactId"] = string(raw)
+		case 3:
+			artifact["name"] = string(raw)
+		case 4:
+			artifact["description"] = string(raw)
+		case 5:
+			part, err := decodePart(raw)
+			if err != nil {
+				return err
+			}
+			artifact["parts"] = append(artifact["parts"].([]interface{}), part)
+		case 6:
+			metadata, err := decodeStruct(raw)
+			if err != nil {
+				return err
+			}
+			artifact["metadata"] = metadata
+		case 7:
+			extensions, _ := artifact["extensions"].([]interface{})
+			artifact["extensions"] = append(extensions, string(raw))
+		}
+		return nil
+	})
+	return artifact, err
+}
+
+func decodeMessage(b []byte) (map[string]interface{}, error) {
+	message := map[string]interface{}{"kind": "message", "messageId": "", "role": "", "parts": []interface{}{}}
+	err := fields(b, func(num protowire.Number, raw []byte, v uint64) error {
+		switch num {
+		case 1:
+			message["messageId"] = string(raw)
+		case 2:
+			message["contextId"] = string(raw)
+		case 3:
+			message["taskId"] = string(raw)
+		case 4:
+			message["role"] = roleNames[v]
+		case 5:
+			part, err := decodePart(raw)
+			if err != nil {
+				return err
+			}
+			message["parts"] = append(message["parts"].([]interface{}), part)
+		case 6:
+			metadata, err := decodeStruct(raw)
+			if err != nil {
+				return err
+			}
+			message["metadata"] = metadata
+		case 7:
+			extensions, _ := message["extensions"].([]interface{})
+			message["extensions"] = append(extensions, string(raw))
+		}
+		return nil
+	})
+	return message, err
+}
+
+func decodePart(b []byte) (map[string]interface{}, error) {
+	part := map[string]interface{}{}
+	err := fields(b, func(num protowire.Number, raw []byte, _ uint64) error {
+		switch num {
+		case 1:
+			part["kind"] = "text"
+			part["text"] = string(raw)
+		case 2:
+			file := map[string]interface{}{}
+			err := fields(raw, func(num protowire.Number, raw []byte, _ uint64) error {
+				switch num {
+				case 1:
+					file["uri"] = string(raw)
+				case 2:
+					file["bytes"] = base64.StdEncoding.EncodeToString(raw)
+				case 3:
+					file["mimeType"] = string(raw)
+				case 4:
+					file["name"] = string(raw)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			part["kind"] = "file"
+			part["file"] = file
+		case 3:
+			data := map[string]interface{}{}
+			err := fields(raw, func(num protowire.Number, raw []byte, _ uint64) error {
+				var err error
+				if num == 1 {
+					data, err = decodeStruct(raw)
+				}
+				return err
+			})
+			if err != nil {
+				return err
+			}
+			part["kind"] = "data"
+			part["data"] = data
+		case 4:
+			metadata, err := decodeStruct(raw)
+			if err != nil {
+				return err
+			}
+			part["metadata"] = metadata
+		}
+		return nil
+	})
+	if err == nil && part["kind"] == nil {
+		err = errors.New("part holds no content")
+	}
+	return part, err
+}
@@ -0,0 +1,55 @@
+// Package bufpool pools the buffers of the request hot paths, i.e. captured responses and
+// marshalled JSON bodies, so intercepting a request does not allocate them anew.
+//
+// Every Buffer keeps a JSON encoder writing to it, which is reused along with the buffer.
+// Buffers that grew beyond MaxPooledSize are dropped on Release instead of pinning their memory.
+package bufpool
+
+import (
+	"bytes"
+	"encoding/json"
+	"sync"
+)
+
+// MaxPooledSize is the capacity up to which released buffers are reused
+const MaxPooledSize = 1 << 20
+
+// Buffer is a pooled bytes.Buffer
+type Buffer struct {
+	bytes.Buffer
+	enc *json.Encoder
+}
+
+var pool = sync.Pool{
+	New: func() interface{} {
+		b := &Buffer{}
+		b.enc = json.NewEncoder(&b.Buffer)
+		return b
+	},
+}
+
+// Get returns an empty buffer. Callers Release it once its contents are no longer used.
+func Get() *Buffer {
+	b := pool.Get().(*Buffer)
+	b.Reset()
+	return b
+}
+
+// Release returns the buffer to the pool. Neither the buffer nor slices of its contents may be
+// used afterwards. Releasing nil has no effect.
+func (b *Buffer) Release() {
+	if b == nil || b.Cap() > MaxPooledSize {
+		return
+	}
+	pool.Put(b)
+}
+
+// EncodeJSON appends the JSON encoding of v. The output equals json.Marshal, without the
+// newline added by json.Encoder.
+func (b *Buffer) EncodeJSON(v interface{}) error {
+	if err := b.enc.Encode(v); err != nil {
+		return err
+	}
+	b.Truncate(b.Len() - 1)
+	return nil
+}
@@ -0,0 +1,79 @@
+package bufpool
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuffer_EncodeJSON(t *testing.T) {
+	values := []interface{}{
+		map[string]interface{}{"url": "http://agent:8000/?a=1&b=<2>", "skills": []string{"forecast"}},
+		"text",
+		nil,
+		json.RawMessage(`{ "raw" : true }`),
+	}
+	for _, v := range values {
+		b := Get()
+		require.NoError(t, b.EncodeJSON(v))
+		want, err := json.Marshal(v)
+		require.NoError(t, err)
+		assert.Equal(t, string(want), b.String())
+		b.Release()
+	}
+
+	b := Get()
+	defer b.Release()
+	assert.Error(t, b.EncodeJSON(func() {}))
+}
+
+func TestGet_ReturnsEmptyBuffers(t *testing.T) {
+	b := Get()
+	b.WriteString("previous response")
+	b.Release()
+
+	for i := 0; i < 10; i++ {
+		b := Get()
+		assert.Zero(t, b.Len())
+		require.NoError(t, b.EncodeJSON([]int{i}))
+		b.Release()
+	}
+}
+
+func TestRelease_DropsLargeBuffers(t *testing.T) {
+	b := Get()
+	b.WriteString(strings.Repeat("x", MaxPooledSize+1))
+	b.Release()
+
+	var nilBuffer *Buffer
+	nilBuffer.Release()
+}
+
+var benchmarkCard = map[string]interface{}{
+	"name":        "weather-agent",
+	"description": "Provides weather forecasts",
+	"url":         "https://gateway.example.com/weather-agent",
+	"skills": []interface{}{
+		map[string]interface{}{"id": "forecast", "name": "Forecast", "tags": []interface{}{"weather", "forecast"}},
+		map[string]interface{}{"id": "alerts", "name": "Alerts", "tags": []interface{}{"weather", "alerts"}},
+	},
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = json.Marshal(benchmarkCard)
+	}
+}
+
+func BenchmarkEncodeJSON(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf := Get()
+		_ = buf.EncodeJSON(benchmarkCard)
+		buf.Release()
+	}
+}
@@ -0,0 +1,162 @@
+// Package capture buffers the response of a downstream handler, so plugins can transform it
+// before it is written to the client.
+//
+// Headers written by the handler are captured too; callers copy the ones that remain valid for the
+// transformed response with CopyHeader. Capturing is bounded: once a response exceeds the limit of
+// its Writer, the headers, the buffered part and everything after it are passed through unchanged,
+// and the caller must not write a response of its own. Flushes and hijacks of passed through
+// responses reach the client writer.
+//
+// Bodies are captured in pooled buffers. Callers release a Writer once they no longer need its body,
+// which returns the buffer to the pool. Writers that are never released show up as buffers in use
+// in ReadStats, which diagnostics use to detect leaks.
+package capture
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/bufpool"
+)
+
+// Stats counts the buffers of all Writers of the process
+type Stats struct {
+	Acquired int64 `json:"acquired"`
+	Released int64 `json:"released"`
+	InUse    int64 `json:"in_use"`
+}
+
+var acquired, released atomic.Int64
+
+// ReadStats returns the buffer counters
+func ReadStats() Stats {
+	r := released.Load()
+	a := acquired.Load()
+	return Stats{Acquired: a, Released: r, InUse: a - r}
+}
+
+// Writer captures the headers, status code and body of a response
+type Writer struct {
+	http.ResponseWriter
+	limit       int
+	header      http.Header
+	body        *bufpool.Buffer
+	statusCode  int
+	passthrough bool
+	released    bool
+}
+
+// New captures responses written to w up to limit bytes; a limit of 0 or less captures any size
+func New(w http.ResponseWriter, limit int) *Writer {
+	acquired.Add(1)
+	return &Writer{ResponseWriter: w, limit: limit, header: http.Header{}, body: bufpool.Get(), statusCode: http.StatusOK}
+}
+
+// Release returns the buffer of the Writer to the pool. The body must not be used afterwards. Releasing a nil
+// Writer or a Writer more than once has no effect.
+func (w *Writer) Release() {
+	if w == nil || w.released {
+		return
+	}
+	w.released = true
+	w.body.Release()
+	w.body = nil
+	released.Add(1)
+}
+
+// Header returns the captured headers of the response
+func (w *Writer) Header() http.Header {
+	return w.header
+}
+
+// Write buffers b, or passes the response through once it exceeds the limit
+func (w *Writer) Write(b []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.limit > 0 && w.body.Len()+len(b) > w.limit {
+		if err := w.startPassthrough(); err != nil {
+			return 0, err
+		}
+		return w.ResponseWriter.Write(b)
+	}
+	return w.body.Write(b)
+}
+
+// startPassthrough writes the headers, the status and the buffered body to the wrapped writer
+func (w *Writer) startPassthrough() error {
+	w.passthrough = true
+	CopyHeader(w.ResponseWriter.Header(), w.header)
+	w.ResponseWriter.WriteHeader(w.statusCode)
+	_, err := w.ResponseWriter.Write(w.body.Bytes())
+	w.body.Reset()
+	return err
+}
+
+// WriteHeader records the status code until the response is passed through
+func (w *Writer) WriteHeader(statusCode int) {
+	if w.passthrough {
+		return
+	}
+	w.statusCode = statusCode
+}
+
+// StatusCode returns the status code written by the handler, 200 if none was written
+func (w *Writer) StatusCode() int {
+	return w.statusCode
+}
+
+// Body returns the captured body. It is empty once the response is passed through or the Writer is released.
+func (w *Writer) Body() []byte {
+	if w.body == nil {
+		return nil
+	}
+	return w.body.Bytes()
+}
+
+// Passthrough reports whether the response exceeded the limit and was written to the client
+func (w *Writer) Passthrough() bool {
+	return w.passthrough
+}
+
+// Flush is ignored while the response is captured, as its body may still be transformed, and
+// passed on to the client once the response is passed through
+func (w *Writer) Flush() {
+	if w.passthrough {
+		_ = http.NewResponseController(w.ResponseWriter).Flush()
+	}
+}
+
+// Hijack hands the connection to the handler, e.g. for protocol upgrades. Nothing may have been
+// written before.
+func (w *Writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if len(w.Body()) > 0 || w.passthrough {
+		return nil, nil, fmt.Errorf("capture: cannot hijack a connection after writing the response")
+	}
+	w.passthrough = true
+	CopyHeader(w.ResponseWriter.Header(), w.header)
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap lets http.ResponseController reach the wrapped writer
+func (w *Writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// CopyHeader adds the values of src to dst, replacing values of the same name, and skips the
+// excluded headers
+func CopyHeader(dst, src http.Header, exclude ...string) {
+	skip := make(map[string]bool, len(exclude))
+	for _, name := range exclude {
+		skip[http.CanonicalHeaderKey(name)] = true
+	}
+	for name, values := range src {
+		if skip[http.CanonicalHeaderKey(name)] {
+			continue
+		}
+		dst[name] = append([]string(nil), values...)
+	}
+}
@@ -0,0 +1,114 @@
+package capture
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriter_CapturesWithinLimit(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := New(rec, 10)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	_, err := w.Write([]byte("0123456789"))
+	w.Flush()
+
+	assert.NoError(t, err)
+	assert.False(t, w.Passthrough())
+	assert.Equal(t, http.StatusAccepted, w.StatusCode())
+	assert.Equal(t, "0123456789", string(w.Body()))
+	assert.False(t, rec.Flushed)
+	assert.Empty(t, rec.Body.String())
+	assert.Empty(t, rec.Header().Get("Content-Type"))
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+}
+
+func TestWriter_PassesThroughBeyondLimit(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := New(rec, 10)
+
+	w.Header().Set("X-Request-Id", "r1")
+	w.WriteHeader(http.StatusCreated)
+	_, _ = w.Write([]byte("01234"))
+	_, err := w.Write([]byte("56789ab"))
+	_, _ = w.Write([]byte("cd"))
+	w.Flush()
+
+	assert.NoError(t, err)
+	assert.True(t, w.Passthrough())
+	assert.Empty(t, w.Body())
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "0123456789abcd", rec.Body.String())
+	assert.Equal(t, "r1", rec.Header().Get("X-Request-Id"))
+	assert.True(t, rec.Flushed)
+}
+
+func TestWriter_Unlimited(t *testing.T) {
+	w := New(httptest.NewRecorder(), 0)
+
+	_, _ = w.Write(make([]byte, 1<<20))
+
+	assert.False(t, w.Passthrough())
+	assert.Len(t, w.Body(), 1<<20)
+}
+
+func TestWriter_Release(t *testing.T) {
+	before := ReadStats()
+	w := New(httptest.NewRecorder(), 0)
+	_, _ = w.Write([]byte("body"))
+	assert.Equal(t, before.InUse+1, ReadStats().InUse)
+
+	w.Release()
+	w.Release()
+
+	after := ReadStats()
+	assert.Equal(t, before.Acquired+1, after.Acquired)
+	assert.Equal(t, before.Released+1, after.Released, "releasing twice counts once")
+	assert.Equal(t, before.InUse, after.InUse)
+	assert.Empty(t, w.Body())
+}
+
+func TestWriter_Hijack(t *testing.T) {
+	w := New(httptest.NewRecorder(), 0)
+	_, _ = w.Write([]byte("x"))
+
+	_, _, err := w.Hijack()
+
+	assert.Error(t, err)
+
+	// Recorders cannot be hijacked, the error of the wrapped writer is returned
+	w = New(httptest.NewRecorder(), 0)
+	_, _, err = w.Hijack()
+	assert.ErrorIs(t, err, http.ErrNotSupported)
+}
+
+func TestCopyHeader(t *testing.T) {
+	src := http.Header{"Cache-Control": {"max-age=60"}, "Etag": {`"v1"`}, "Vary": {"Accept", "Origin"}}
+	dst := http.Header{"Vary": {"Accept-Encoding"}, "Content-Type": {"application/json"}}
+
+	CopyHeader(dst, src, "ETag")
+
+	assert.Equal(t, http.Header{
+		"Cache-Control": {"max-age=60"},
+		"Vary":          {"Accept", "Origin"},
+		"Content-Type":  {"application/json"},
+	}, dst)
+	src["Vary"][0] = "changed"
+	assert.Equal(t, "Accept", dst.Get("Vary"))
+}
+
+func BenchmarkWriter(b *testing.B) {
+	body := []byte(strings.Repeat(`{"jsonrpc":"2.0","id":1,"result":{"kind":"message","parts":[{"kind":"text","text":"Sunny"}]}}`, 20))
+	rec := httptest.NewRecorder()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		w := New(rec, 0)
+		_, _ = w.Write(body)
+		w.Release()
+	}
+}
@@ -0,0 +1,160 @@
+// Package configschema holds the JSON schemas of the extra_config blocks of all plugins. Plugins
+// validate their configuration against them when KrakenD registers their handlers, so unknown
+// keys, wrong types and missing fields stop the gateway at startup instead of failing requests.
+package configschema
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+const schemaSuffix = ".schema.json"
+
+// maxViolations limits the violations reported for a single configuration
+const maxViolations = 20
+
+//go:embed schemas/*.schema.json
+var schemaFiles embed.FS
+
+var schemaMessages = message.NewPrinter(language.English)
+
+var (
+	compileOnce sync.Once
+	compiled    map[string]*jsonschema.Schema
+	compileErr  error
+)
+
+// ValidationError lists the violations of a configuration, e.g. "agents[0].url: got number, want string"
+type ValidationError struct {
+	Violations []string
+}
+
+func (e *ValidationError) Error() string {
+	return strings.Join(e.Violations, "; ")
+}
+
+// Plugins returns the names of all plugins with a schema, sorted
+func Plugins() []string {
+	entries, _ := schemaFiles.ReadDir("schemas")
+	plugins := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		plugins = append(plugins, strings.TrimSuffix(entry.Name(), schemaSuffix))
+	}
+	sort.Strings(plugins)
+	return plugins
+}
+
+// Schema returns the JSON schema of the extra_config block of a plugin
+func Schema(plugin string) ([]byte, bool) {
+	raw, err := schemaFiles.ReadFile(path.Join("schemas", plugin+schemaSuffix))
+	return raw, err == nil
+}
+
+// Validate checks the extra_config block of a plugin against its schema. A nil config is valid,
+// as plugins fall back to their defaults without configuration. Violations are returned as
+// *ValidationError.
+func Validate(plugin string, config interface{}) error {
+	if config == nil {
+		return nil
+	}
+	schemas, err := compileSchemas()
+	if err != nil {
+		return err
+	}
+	schema, ok := schemas[plugin]
+	if !ok {
+		return fmt.Errorf("no configuration schema for plugin '%s'", plugin)
+	}
+
+	// Round trip through JSON so configurations built in Go validate like parsed krakend.json
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("cannot read configuration: %w", err)
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	if err != nil {
+		return fmt.Errorf("cannot read configuration: %w", err)
+	}
+
+	err = schema.Validate(instance)
+	if validationErr, ok := err.(*jsonschema.ValidationError); ok {
+		return &ValidationError{Violations: violations(validationErr)}
+	}
+	return err
+}
+
+// compileSchemas compiles the schemas of all plugins once
+func compileSchemas() (map[string]*jsonschema.Schema, error) {
+	compileOnce.Do(func() {
+		compiler := jsonschema.NewCompiler()
+		compiled = make(map[string]*jsonschema.Schema)
+		for _, plugin := range Plugins() {
+			raw, _ := Schema(plugin)
+			doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+			if err != nil {
+				compileErr = fmt.Errorf("invalid schema of plugin '%s': %w", plugin, err)
+				return
+			}
+			url := plugin + schemaSuffix
+			if err := compiler.AddResource(url, doc); err != nil {
+				compileErr = fmt.Errorf("invalid schema of plugin '%s': %w", plugin, err)
+				return
+			}
+			if compiled[plugin], err = compiler.Compile(url); err != nil {
+				compileErr = fmt.Errorf("cannot compile schema of plugin '%s': %w", plugin, err)
+				return
+			}
+		}
+	})
+	return compiled, compileErr
+}
+
+// violations lists the most specific causes of a validation error with their location
+func violations(err *jsonschema.ValidationError) []string {
+	var result []string
+	var collect func(err *jsonschema.ValidationError)
+	collect = func(err *jsonschema.ValidationError) {
+		if len(result) == maxViolations {
+			return
+		}
+		if len(err.Causes) > 0 {
+			for _, cause := range err.Causes {
+				collect(cause)
+			}
+			return
+		}
+		location := fieldPath(err.InstanceLocation)
+		if location == "" {
+			location = "config"
+		}
+		result = append(result, location+": "+err.ErrorKind.LocalizedString(schemaMessages))
+	}
+	collect(err)
+	return result
+}
+
+// fieldPath converts a JSON pointer like /agents/0/url to agents[0].url
+func fieldPath(location []string) string {
+	var path string
+	for _, token := range location {
+		if _, err := strconv.Atoi(token); err == nil {
+			path += "[" + token + "]"
+		} else if path == "" {
+			path = token
+		} else {
+			path += "." + token
+		}
+	}
+	return path
+}
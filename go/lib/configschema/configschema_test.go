@@ -0,0 +1,112 @@
+package configschema
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlugins(t *testing.T) {
+	entries, err := os.ReadDir("../../plugin")
+	require.NoError(t, err)
+
+	var plugins []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			plugins = append(plugins, entry.Name())
+		}
+	}
+	assert.Equal(t, plugins, Plugins())
+}
+
+func TestSchema(t *testing.T) {
+	for _, plugin := range Plugins() {
+		raw, ok := Schema(plugin)
+		require.True(t, ok, plugin)
+		assert.True(t, json.Valid(raw), plugin)
+	}
+
+	_, ok := Schema("unknown")
+	assert.False(t, ok)
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name           string
+		plugin         string
+		config         interface{}
+		wantViolations []string
+	}{
+		{name: "no config", plugin: "cors", config: nil},
+		{name: "empty config", plugin: "url-rewriter", config: map[string]interface{}{}},
+		{
+			name:   "valid config",
+			plugin: "a2a-openai",
+			config: map[string]interface{}{
+				"agents":  []interface{}{map[string]interface{}{"path": "/llm", "url": "http://llm:8080", "model": "gpt-4o"}},
+				"logging": map[string]interface{}{"level": "debug", "debug_sample_rate": 0.5},
+			},
+		},
+		{
+			name:           "unknown key",
+			plugin:         "cors",
+			config:         map[string]interface{}{"allowed_origin": []interface{}{"*"}},
+			wantViolations: []string{"config: additional properties 'allowed_origin' not allowed"},
+		},
+		{
+			name:           "wrong type",
+			plugin:         "ip-filter",
+			config:         map[string]interface{}{"allow": "10.0.0.0/8"},
+			wantViolations: []string{"allow: got string, want array"},
+		},
+		{
+			name:           "missing required field",
+			plugin:         "a2a-openai",
+			config:         map[string]interface{}{"agents": []interface{}{map[string]interface{}{"path": "/llm", "url": "http://llm:8080"}}},
+			wantViolations: []string{"agents[0]: missing property 'model'"},
+		},
+		{
+			name:           "unknown enum value",
+			plugin:         "agentcard-rw",
+			config:         map[string]interface{}{"on_error": "ignore"},
+			wantViolations: []string{"on_error: value must be one of '', 'error', 'passthrough_original', 'retry'"},
+		},
+		{
+			name:           "out of range",
+			plugin:         "openai-a2a",
+			config:         map[string]interface{}{"logging": map[string]interface{}{"debug_sample_rate": 2}},
+			wantViolations: []string{"logging.debug_sample_rate: maximum: got 2, want 1"},
+		},
+		{
+			name:   "several violations",
+			plugin: "header-filter",
+			config: map[string]interface{}{"routes": []interface{}{map[string]interface{}{"request": map[string]interface{}{"allow": []interface{}{1}}}}},
+			wantViolations: []string{
+				"routes[0]: missing property 'path'",
+				"routes[0].request.allow[0]: got number, want string",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.plugin, tt.config)
+
+			if tt.wantViolations == nil {
+				assert.NoError(t, err)
+				return
+			}
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.ElementsMatch(t, tt.wantViolations, validationErr.Violations)
+		})
+	}
+}
+
+func TestValidate_Errors(t *testing.T) {
+	assert.ErrorContains(t, Validate("unknown", map[string]interface{}{}), "no configuration schema")
+	assert.ErrorContains(t, Validate("body-logger", make(chan int)), "cannot read configuration")
+}
@@ -0,0 +1,120 @@
+// Package consul is a minimal client for the health API of Consul.
+//
+// It lists the healthy instances of a service with blocking queries, so callers are notified of
+// registrations and deregistrations without polling and without adding the Consul API module to
+// the dependencies that plugins must share with KrakenD.
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ServiceEntry is a healthy instance of a service
+type ServiceEntry struct {
+	ID      string
+	Address string
+	Port    int
+	Tags    []string
+	Meta    map[string]string
+	// Weight is the weight of the instance while its checks are passing (default 1)
+	Weight int
+}
+
+// Client sends requests to the HTTP API of a Consul agent
+type Client struct {
+	// Address is the base URL of the agent, e.g. http://consul:8500
+	Address string
+	// Token is the ACL token of the requests, if any
+	Token string
+	// HTTP sends the requests; nil uses http.DefaultClient
+	HTTP *http.Client
+}
+
+// HealthyInstances returns the instances of service whose checks are passing, and the index of
+// the result. With a non-zero index, the request blocks until the result changes or wait
+// elapses; the returned index is then passed to the next call.
+func (c *Client) HealthyInstances(ctx context.Context, service string, index uint64, wait time.Duration) ([]ServiceEntry, uint64, error) {
+	query := url.Values{"passing": {"true"}}
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", fmt.Sprintf("%ds", int(wait.Seconds())))
+	}
+	endpoint := strings.TrimSuffix(c.Address, "/") + "/v1/health/service/" + url.PathEscape(service) + "?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if c.Token != "" {
+		req.Header.Set("X-Consul-Token", c.Token)
+	}
+
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, 0, fmt.Errorf("consul: status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var entries []struct {
+		Node struct {
+			Address string
+		}
+		Service struct {
+			ID      string
+			Address string
+			Port    int
+			Tags    []string
+			Meta    map[string]string
+			Weights struct {
+				Passing int
+			}
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, 0, fmt.Errorf("consul: cannot decode response: %w", err)
+	}
+	next, err := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	if err != nil {
+		return nil, 0, fmt.Errorf("consul: missing X-Consul-Index")
+	}
+	// Indexes must increase; after a reset, the next query starts over without blocking
+	if next < index {
+		next = 0
+	}
+
+	instances := make([]ServiceEntry, 0, len(entries))
+	for _, e := range entries {
+		instance := ServiceEntry{
+			ID:      e.Service.ID,
+			Address: e.Service.Address,
+			Port:    e.Service.Port,
+			Tags:    e.Service.Tags,
+			Meta:    e.Service.Meta,
+			Weight:  e.Service.Weights.Passing,
+		}
+		// Services registered without address use the address of their node
+		if instance.Address == "" {
+			instance.Address = e.Node.Address
+		}
+		if instance.Weight == 0 {
+			instance.Weight = 1
+		}
+		instances = append(instances, instance)
+	}
+	return instances, next, nil
+}
@@ -0,0 +1,61 @@
+package consul
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_HealthyInstances(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/health/service/a2a-agent", r.URL.Path)
+		assert.Equal(t, "secret", r.Header.Get("X-Consul-Token"))
+		queries = append(queries, r.URL.RawQuery)
+		w.Header().Set("X-Consul-Index", "42")
+		_, _ = w.Write([]byte(`[
+			{"Node": {"Address": "10.0.0.5"}, "Service": {"ID": "weather-1", "Port": 8000, "Meta": {"model_id": "weather-agent"}, "Weights": {"Passing": 3}}},
+			{"Node": {"Address": "10.0.0.6"}, "Service": {"ID": "news-1", "Address": "news.agents", "Port": 9000, "Tags": ["a2a"]}}
+		]`))
+	}))
+	defer server.Close()
+	client := &Client{Address: server.URL + "/", Token: "secret"}
+
+	instances, index, err := client.HealthyInstances(context.Background(), "a2a-agent", 0, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), index)
+	assert.Equal(t, []ServiceEntry{
+		{ID: "weather-1", Address: "10.0.0.5", Port: 8000, Meta: map[string]string{"model_id": "weather-agent"}, Weight: 3},
+		{ID: "news-1", Address: "news.agents", Port: 9000, Tags: []string{"a2a"}, Weight: 1},
+	}, instances)
+
+	_, _, err = client.HealthyInstances(context.Background(), "a2a-agent", 42, 5*time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"passing=true", "index=42&passing=true&wait=300s"}, queries)
+
+	_, index, err = client.HealthyInstances(context.Background(), "a2a-agent", 50, time.Minute)
+	require.NoError(t, err)
+	assert.Zero(t, index, "a decreasing index resets the query")
+}
+
+func TestClient_HealthyInstancesErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/health/service/denied" {
+			http.Error(w, "ACL not found", http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+	client := &Client{Address: server.URL}
+
+	_, _, err := client.HealthyInstances(context.Background(), "denied", 0, time.Minute)
+	assert.EqualError(t, err, "consul: status 403: ACL not found")
+	_, _, err = client.HealthyInstances(context.Background(), "a2a-agent", 0, time.Minute)
+	assert.EqualError(t, err, "consul: missing X-Consul-Index")
+}
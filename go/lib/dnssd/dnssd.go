@@ -0,0 +1,341 @@
+// Package dnssd is a minimal DNS client for service discovery with SRV and TXT records.
+//
+// Unlike net.Resolver, it returns the TTL of the records, so callers can refresh them when they
+// expire instead of polling on a fixed interval. Queries are sent over UDP and repeated over TCP
+// when the response is truncated. Only absolute names are resolved; search domains of
+// resolv.conf do not apply.
+package dnssd
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout    = 2 * time.Second
+	defaultResolvConf = "/etc/resolv.conf"
+	maxUDPSize        = 512
+)
+
+// Record types and class of the queries
+const (
+	typeTXT uint16 = 16
+	typeSRV uint16 = 33
+	classIN uint16 = 1
+)
+
+// Response codes of interest
+const (
+	rcodeSuccess  = 0
+	rcodeNXDomain = 3
+)
+
+var (
+	// ErrNotFound is returned for names that do not exist or have no records of the queried type
+	ErrNotFound = errors.New("dnssd: no such records")
+	// errMalformed is returned for responses that cannot be parsed
+	errMalformed = errors.New("dnssd: malformed response")
+)
+
+// SRV is a service record, see RFC 2782
+type SRV struct {
+	Target   string
+	Port     uint16
+	Priority uint16
+	Weight   uint16
+}
+
+// Resolver sends queries to the configured name servers in order until one of them answers.
+// It is safe for concurrent use.
+type Resolver struct {
+	// Servers are the host:port addresses of the name servers. Empty uses the name servers of
+	// /etc/resolv.conf.
+	Servers []string
+	// Timeout bounds the query to a single server (default 2s)
+	Timeout time.Duration
+}
+
+// LookupSRV returns the SRV records of name, e.g. _a2a._tcp.agents.example.com, and their
+// smallest TTL. Targets are returned without trailing dot.
+func (r *Resolver) LookupSRV(ctx context.Context, name string) ([]SRV, time.Duration, error) {
+	answers, ttl, err := r.lookup(ctx, name, typeSRV)
+	if err != nil {
+		return nil, 0, err
+	}
+	records := make([]SRV, 0, len(answers))
+	for _, a := range answers {
+		if len(a.data) < 7 {
+			return nil, 0, errMalformed
+		}
+		target, _, err := readName(a.msg, a.offset+6)
+		if err != nil {
+			return nil, 0, err
+		}
+		records = append(records, SRV{
+			Priority: binary.BigEndian.Uint16(a.data[0:2]),
+			Weight:   binary.BigEndian.Uint16(a.data[2:4]),
+			Port:     binary.BigEndian.Uint16(a.data[4:6]),
+			Target:   target,
+		})
+	}
+	return records, ttl, nil
+}
+
+// LookupTXT returns the strings of all TXT records of name and their smallest TTL
+func (r *Resolver) LookupTXT(ctx context.Context, name string) ([]string, time.Duration, error) {
+	answers, ttl, err := r.lookup(ctx, name, typeTXT)
+	if err != nil {
+		return nil, 0, err
+	}
+	var values []string
+	for _, a := range answers {
+		for data := a.data; len(data) > 0; {
+			n := int(data[0])
+			if len(data) < 1+n {
+				return nil, 0, errMalformed
+			}
+			values = append(values, string(data[1:1+n]))
+			data = data[1+n:]
+		}
+	}
+	return values, ttl, nil
+}
+
+// answer is a resource record of the queried type. offset is the position of data in msg,
+// which compressed names in data refer to.
+type answer struct {
+	msg    []byte
+	offset int
+	data   []byte
+}
+
+func (r *Resolver) lookup(ctx context.Context, name string, qtype uint16) ([]answer, time.Duration, error) {
+	servers := r.Servers
+	if len(servers) == 0 {
+		var err error
+		if servers, err = systemServers(defaultResolvConf); err != nil {
+			return nil, 0, err
+		}
+	}
+	query, id, err := newQuery(name, qtype)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var errs []error
+	for _, server := range servers {
+		answers, ttl, err := r.exchange(ctx, server, query, id, qtype)
+		if err == nil || errors.Is(err, ErrNotFound) {
+			return answers, ttl, err
+		}
+		if ctx.Err() != nil {
+			return nil, 0, ctx.Err()
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", server, err))
+	}
+	return nil, 0, fmt.Errorf("dnssd: cannot resolve %s: %w", name, errors.Join(errs...))
+}
+
+// exchange sends the query to a server over UDP and repeats it over TCP if the response is truncated
+func (r *Resolver) exchange(ctx context.Context, server string, query []byte, id, qtype uint16) ([]answer, time.Duration, error) {
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	msg, err := roundTrip(ctx, "udp", server, query)
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(msg) >= 4 && msg[2]&0x02 != 0 {
+		if msg, err = roundTrip(ctx, "tcp", server, query); err != nil {
+			return nil, 0, err
+		}
+	}
+	return parseResponse(msg, id, qtype)
+}
+
+func roundTrip(ctx context.Context, network, server string, query []byte) ([]byte, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if network == "udp" {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, maxUDPSize)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+
+	framed := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+	if _, err := conn.Write(append(framed, query...)); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	msg := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// newQuery encodes a recursive query for name and returns it with its ID
+func newQuery(name string, qtype uint16) ([]byte, uint16, error) {
+	id := uint16(rand.N(1 << 16))
+	msg := make([]byte, 12, 12+len(name)+6)
+	binary.BigEndian.PutUint16(msg[0:2], id)
+	binary.BigEndian.PutUint16(msg[2:4], 0x0100) // recursion desired
+	binary.BigEndian.PutUint16(msg[4:6], 1)      // one question
+
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return nil, 0, fmt.Errorf("dnssd: empty name")
+	}
+	for _, label := range strings.Split(name, ".") {
+		if label == "" || len(label) > 63 {
+			return nil, 0, fmt.Errorf("dnssd: invalid name '%s'", name)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, classIN)
+	return msg, id, nil
+}
+
+// parseResponse returns the answers of type qtype and their smallest TTL. Answers of other
+// types, e.g. the CNAME records leading to them, are skipped.
+func parseResponse(msg []byte, id, qtype uint16) ([]answer, time.Duration, error) {
+	if len(msg) < 12 || binary.BigEndian.Uint16(msg[0:2]) != id || msg[2]&0x80 == 0 {
+		return nil, 0, errMalformed
+	}
+	switch rcode := msg[3] & 0x0f; rcode {
+	case rcodeSuccess:
+	case rcodeNXDomain:
+		return nil, 0, ErrNotFound
+	default:
+		return nil, 0, fmt.Errorf("dnssd: server failure, rcode %d", rcode)
+	}
+
+	questions := int(binary.BigEndian.Uint16(msg[4:6]))
+	count := int(binary.BigEndian.Uint16(msg[6:8]))
+	offset := 12
+	for i := 0; i < questions; i++ {
+		_, next, err := readName(msg, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		offset = next + 4
+	}
+
+	var answers []answer
+	var ttl time.Duration
+	for i := 0; i < count; i++ {
+		_, next, err := readName(msg, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		if next+10 > len(msg) {
+			return nil, 0, errMalformed
+		}
+		rtype := binary.BigEndian.Uint16(msg[next : next+2])
+		recordTTL := time.Duration(binary.BigEndian.Uint32(msg[next+4:next+8])) * time.Second
+		length := int(binary.BigEndian.Uint16(msg[next+8 : next+10]))
+		offset = next + 10
+		if offset+length > len(msg) {
+			return nil, 0, errMalformed
+		}
+		if rtype == qtype {
+			answers = append(answers, answer{msg: msg, offset: offset, data: msg[offset : offset+length]})
+			if len(answers) == 1 || recordTTL < ttl {
+				ttl = recordTTL
+			}
+		}
+		offset += length
+	}
+	if len(answers) == 0 {
+		return nil, 0, ErrNotFound
+	}
+	return answers, ttl, nil
+}
+
+// readName decodes the possibly compressed name at offset and returns it with the offset
+// following it
+func readName(msg []byte, offset int) (string, int, error) {
+	var labels []string
+	next := -1
+	for jumps := 0; ; {
+		if offset >= len(msg) {
+			return "", 0, errMalformed
+		}
+		length := int(msg[offset])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = offset + 1
+			}
+			return strings.Join(labels, "."), next, nil
+		case length&0xc0 == 0xc0:
+			if offset+1 >= len(msg) || jumps > 16 {
+				return "", 0, errMalformed
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(msg[offset:offset+2]) & 0x3fff)
+			jumps++
+		case length&0xc0 != 0:
+			return "", 0, errMalformed
+		default:
+			if offset+1+length > len(msg) {
+				return "", 0, errMalformed
+			}
+			labels = append(labels, string(msg[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+}
+
+// systemServers reads the name servers of a resolv.conf file
+func systemServers(path string) ([]string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("dnssd: no name servers configured: %w", err)
+	}
+	var servers []string
+	for _, line := range strings.Split(string(raw), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			servers = append(servers, net.JoinHostPort(fields[1], "53"))
+		}
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("dnssd: no name servers in %s", path)
+	}
+	return servers, nil
+}
@@ -0,0 +1,99 @@
+package dnssd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/dnssd/dnstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolver_LookupSRVAndTXT(t *testing.T) {
+	server := dnstest.NewServer()
+	defer server.Close()
+	server.SetSRV("_a2a._tcp.agents.example.com", 60,
+		dnstest.SRV{Target: "weather.agents.example.com", Port: 8000, Priority: 10, Weight: 5},
+		dnstest.SRV{Target: "weather-2.agents.example.com.", Port: 8001, Priority: 20, Weight: 1},
+	)
+	server.SetTXT("weather.agents.example.com", 30, "model_id=weather-agent", "path=/a2a")
+	resolver := &Resolver{Servers: []string{server.Addr}}
+	ctx := context.Background()
+
+	records, ttl, err := resolver.LookupSRV(ctx, "_a2a._tcp.agents.example.com.")
+	require.NoError(t, err)
+	assert.Equal(t, []SRV{
+		{Target: "weather.agents.example.com", Port: 8000, Priority: 10, Weight: 5},
+		{Target: "weather-2.agents.example.com", Port: 8001, Priority: 20, Weight: 1},
+	}, records)
+	assert.Equal(t, time.Minute, ttl)
+
+	values, ttl, err := resolver.LookupTXT(ctx, "Weather.Agents.Example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"model_id=weather-agent", "path=/a2a"}, values)
+	assert.Equal(t, 30*time.Second, ttl)
+
+	_, _, err = resolver.LookupTXT(ctx, "_a2a._tcp.agents.example.com")
+	assert.ErrorIs(t, err, ErrNotFound, "the name exists without TXT records")
+	_, _, err = resolver.LookupSRV(ctx, "_a2a._tcp.missing.example.com")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestResolver_TruncatedResponsesUseTCP(t *testing.T) {
+	server := dnstest.NewServer()
+	defer server.Close()
+	var served []dnstest.SRV
+	var records []SRV
+	for i := 0; i < 20; i++ {
+		target := fmt.Sprintf("replica-%d.weather.agents.example.com", i)
+		served = append(served, dnstest.SRV{Target: target, Port: 8000})
+		records = append(records, SRV{Target: target, Port: 8000})
+	}
+	server.SetSRV("_a2a._tcp.agents.example.com", 60, served...)
+
+	found, _, err := (&Resolver{Servers: []string{server.Addr}}).LookupSRV(context.Background(), "_a2a._tcp.agents.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, records, found)
+	assert.Equal(t, []string{"udp SRV _a2a._tcp.agents.example.com", "tcp SRV _a2a._tcp.agents.example.com"}, server.Queries())
+}
+
+func TestResolver_FallsBackToNextServer(t *testing.T) {
+	server := dnstest.NewServer()
+	defer server.Close()
+	server.SetTXT("weather.agents.example.com", 30, "model_id=weather-agent")
+	unreachable := dnstest.NewServer()
+	unreachable.Close()
+
+	resolver := &Resolver{Servers: []string{unreachable.Addr, server.Addr}, Timeout: 200 * time.Millisecond}
+	values, _, err := resolver.LookupTXT(context.Background(), "weather.agents.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"model_id=weather-agent"}, values)
+
+	_, _, err = (&Resolver{Servers: []string{unreachable.Addr}, Timeout: 200 * time.Millisecond}).LookupTXT(context.Background(), "weather.agents.example.com")
+	assert.ErrorContains(t, err, "cannot resolve weather.agents.example.com")
+}
+
+func TestReadName_RejectsPointerLoops(t *testing.T) {
+	msg := append(make([]byte, 12), 0xc0, 12)
+	_, _, err := readName(msg, 12)
+	assert.ErrorIs(t, err, errMalformed)
+}
+
+func TestSystemServers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resolv.conf")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join([]string{
+		"# generated",
+		"search agents.svc.cluster.local",
+		"nameserver 10.96.0.10",
+		"nameserver fd00::10",
+	}, "\n")), 0o600))
+
+	servers, err := systemServers(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.96.0.10:53", "[fd00::10]:53"}, servers)
+}
@@ -0,0 +1,254 @@
+// Package dnstest provides an authoritative DNS server for tests, in the spirit of httptest.
+//
+// It answers SRV and TXT queries for the records set on it, over UDP and TCP on the same port.
+// UDP responses larger than 512 bytes are truncated, so clients have to repeat them over TCP.
+package dnstest
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+)
+
+const (
+	typeTXT = 16
+	typeSRV = 33
+)
+
+// SRV is a service record served by the server
+type SRV struct {
+	Target   string
+	Port     uint16
+	Priority uint16
+	Weight   uint16
+}
+
+// record is an encoded resource record without owner name
+type record struct {
+	rtype uint16
+	ttl   uint32
+	data  []byte
+}
+
+// Server is a DNS server listening on a local port
+type Server struct {
+	// Addr is the host:port the server listens on for UDP and TCP
+	Addr string
+
+	udp     net.PacketConn
+	tcp     net.Listener
+	mu      sync.Mutex
+	records map[string][]record
+	queries []string
+}
+
+// NewServer starts a server without records. Callers must Close it.
+func NewServer() *Server {
+	// The TCP port is taken from the UDP port, which is occasionally in use already
+	for attempt := 0; ; attempt++ {
+		udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+		if err != nil {
+			panic(fmt.Sprintf("dnstest: failed to listen: %v", err))
+		}
+		tcp, err := net.Listen("tcp", udp.LocalAddr().String())
+		if err != nil {
+			_ = udp.Close()
+			if attempt < 10 {
+				continue
+			}
+			panic(fmt.Sprintf("dnstest: failed to listen: %v", err))
+		}
+		s := &Server{Addr: udp.LocalAddr().String(), udp: udp, tcp: tcp, records: make(map[string][]record)}
+		go s.serveUDP()
+		go s.serveTCP()
+		return s
+	}
+}
+
+// Close stops the server
+func (s *Server) Close() {
+	_ = s.udp.Close()
+	_ = s.tcp.Close()
+}
+
+// SetSRV replaces the SRV records of name. Without records, the name has no SRV records.
+func (s *Server) SetSRV(name string, ttl uint32, records ...SRV) {
+	encoded := make([]record, 0, len(records))
+	for _, r := range records {
+		data := binary.BigEndian.AppendUint16(nil, r.Priority)
+		data = binary.BigEndian.AppendUint16(data, r.Weight)
+		data = binary.BigEndian.AppendUint16(data, r.Port)
+		encoded = append(encoded, record{rtype: typeSRV, ttl: ttl, data: appendName(data, r.Target)})
+	}
+	s.set(name, typeSRV, encoded)
+}
+
+// SetTXT replaces the TXT records of name by a single record with values
+func (s *Server) SetTXT(name string, ttl uint32, values ...string) {
+	var encoded []record
+	if len(values) > 0 {
+		var data []byte
+		for _, v := range values {
+			data = append(append(data, byte(len(v))), v...)
+		}
+		encoded = []record{{rtype: typeTXT, ttl: ttl, data: data}}
+	}
+	s.set(name, typeTXT, encoded)
+}
+
+// Queries returns the queries received so far as "network type name", e.g. "udp SRV _a2a._tcp.example"
+func (s *Server) Queries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.queries...)
+}
+
+func (s *Server) set(name string, rtype uint16, records []record) {
+	name = canonical(name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := records
+	for _, r := range s.records[name] {
+		if r.rtype != rtype {
+			kept = append(kept, r)
+		}
+	}
+	if len(kept) == 0 {
+		delete(s.records, name)
+		return
+	}
+	s.records[name] = kept
+}
+
+func (s *Server) serveUDP() {
+	buf := make([]byte, 512)
+	for {
+		n, addr, err := s.udp.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		resp := s.answer(buf[:n], "udp")
+		if resp == nil {
+			continue
+		}
+		if len(resp) > 512 {
+			resp = truncate(buf[:n], resp)
+		}
+		_, _ = s.udp.WriteTo(resp, addr)
+	}
+}
+
+func (s *Server) serveTCP() {
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			var length [2]byte
+			if _, err := io.ReadFull(conn, length[:]); err != nil {
+				return
+			}
+			query := make([]byte, binary.BigEndian.Uint16(length[:]))
+			if _, err := io.ReadFull(conn, query); err != nil {
+				return
+			}
+			resp := s.answer(query, "tcp")
+			if resp == nil {
+				return
+			}
+			_, _ = conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(resp))), resp...))
+		}()
+	}
+}
+
+// answer encodes the response to a query, or returns nil for queries it cannot parse
+func (s *Server) answer(query []byte, network string) []byte {
+	if len(query) < 12 {
+		return nil
+	}
+	var labels []string
+	offset := 12
+	for offset < len(query) && query[offset] != 0 {
+		n := int(query[offset])
+		if offset+1+n > len(query) {
+			return nil
+		}
+		labels = append(labels, string(query[offset+1:offset+1+n]))
+		offset += 1 + n
+	}
+	if offset+5 > len(query) {
+		return nil
+	}
+	question := query[12 : offset+5]
+	qtype := binary.BigEndian.Uint16(query[offset+1 : offset+3])
+	name := canonical(strings.Join(labels, "."))
+
+	s.mu.Lock()
+	s.queries = append(s.queries, fmt.Sprintf("%s %s %s", network, typeName(qtype), name))
+	records, exists := s.records[name]
+	s.mu.Unlock()
+
+	resp := append([]byte{}, query[0:2]...)
+	rcode := byte(0)
+	if !exists {
+		rcode = 3
+	}
+	var answers []byte
+	count := 0
+	for _, r := range records {
+		if r.rtype != qtype {
+			continue
+		}
+		// The owner name points to the name of the question
+		answers = append(answers, 0xc0, 12)
+		answers = binary.BigEndian.AppendUint16(answers, r.rtype)
+		answers = binary.BigEndian.AppendUint16(answers, 1)
+		answers = binary.BigEndian.AppendUint32(answers, r.ttl)
+		answers = binary.BigEndian.AppendUint16(answers, uint16(len(r.data)))
+		answers = append(answers, r.data...)
+		count++
+	}
+	resp = append(resp, 0x84|query[2]&0x01, 0x80|rcode) // response, authoritative, recursion available
+	resp = binary.BigEndian.AppendUint16(resp, 1)
+	resp = binary.BigEndian.AppendUint16(resp, uint16(count))
+	resp = append(resp, 0, 0, 0, 0)
+	resp = append(resp, question...)
+	return append(resp, answers...)
+}
+
+// truncate returns the response to query without answers and with the truncation flag set
+func truncate(query, resp []byte) []byte {
+	header := append([]byte{}, resp[:12]...)
+	header[2] |= 0x02
+	header[6], header[7] = 0, 0
+	return append(header, query[12:]...)
+}
+
+func appendName(data []byte, name string) []byte {
+	for _, label := range strings.Split(canonical(name), ".") {
+		if label != "" {
+			data = append(append(data, byte(len(label))), label...)
+		}
+	}
+	return append(data, 0)
+}
+
+func canonical(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+func typeName(qtype uint16) string {
+	switch qtype {
+	case typeSRV:
+		return "SRV"
+	case typeTXT:
+		return "TXT"
+	default:
+		return fmt.Sprintf("TYPE%d", qtype)
+	}
+}
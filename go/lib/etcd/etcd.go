@@ -0,0 +1,173 @@
+// Package etcd is a minimal client for the JSON gateway of the etcd v3 API.
+//
+// It reads the keys under a prefix and watches them for changes, which is what service discovery
+// needs, without adding the etcd client module and gRPC to the dependencies that plugins must
+// share with KrakenD. Keys and values are returned as strings.
+package etcd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// KeyValue is a key with its value
+type KeyValue struct {
+	Key   string
+	Value string
+}
+
+// Client sends requests to the JSON gateway of an etcd member
+type Client struct {
+	// Address is the base URL of the member, e.g. http://etcd:2379
+	Address string
+	// HTTP sends the requests; nil uses http.DefaultClient
+	HTTP *http.Client
+}
+
+// Range returns the keys under prefix in key order, and the revision of the store they were read at
+func (c *Client) Range(ctx context.Context, prefix string) ([]KeyValue, int64, error) {
+	body := map[string]string{"key": encode(prefix), "range_end": encode(prefixEnd(prefix))}
+	resp, err := c.post(ctx, "/v3/kv/range", body)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Header header `json:"header"`
+		KVs    []struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, 0, fmt.Errorf("etcd: cannot decode response: %w", err)
+	}
+	revision, err := result.Header.revision()
+	if err != nil {
+		return nil, 0, err
+	}
+	kvs := make([]KeyValue, 0, len(result.KVs))
+	for _, kv := range result.KVs {
+		key, err := base64.StdEncoding.DecodeString(kv.Key)
+		if err != nil {
+			return nil, 0, fmt.Errorf("etcd: invalid key: %w", err)
+		}
+		value, err := base64.StdEncoding.DecodeString(kv.Value)
+		if err != nil {
+			return nil, 0, fmt.Errorf("etcd: invalid value of %s: %w", key, err)
+		}
+		kvs = append(kvs, KeyValue{Key: string(key), Value: string(value)})
+	}
+	return kvs, revision, nil
+}
+
+// Watch blocks until a key under prefix changes at or after revision, or until ctx is done. It
+// also returns without error if revision was compacted, as the keys have to be read again then.
+func (c *Client) Watch(ctx context.Context, prefix string, revision int64) error {
+	body := map[string]interface{}{"create_request": map[string]string{
+		"key":            encode(prefix),
+		"range_end":      encode(prefixEnd(prefix)),
+		"start_revision": strconv.FormatInt(revision, 10),
+	}}
+	resp, err := c.post(ctx, "/v3/watch", body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// The response is a stream of JSON messages, the first one confirming the watch
+	decoder := json.NewDecoder(bufio.NewReader(resp.Body))
+	for {
+		var message struct {
+			Result struct {
+				Events          []json.RawMessage `json:"events"`
+				Canceled        bool              `json:"canceled"`
+				CompactRevision string            `json:"compact_revision"`
+				CancelReason    string            `json:"cancel_reason"`
+			} `json:"result"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := decoder.Decode(&message); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("etcd: watch ended: %w", err)
+		}
+		switch {
+		case message.Error != nil:
+			return fmt.Errorf("etcd: %s", message.Error.Message)
+		case len(message.Result.Events) > 0, message.Result.CompactRevision != "":
+			return nil
+		case message.Result.Canceled:
+			return fmt.Errorf("etcd: watch canceled: %s", message.Result.CancelReason)
+		}
+	}
+}
+
+func (c *Client) post(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(c.Address, "/")+path, bytes.NewReader(raw))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := c.HTTP
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("etcd: status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+// header is the response header; the gateway encodes 64-bit integers as strings
+type header struct {
+	Revision string `json:"revision"`
+}
+
+func (h header) revision() (int64, error) {
+	revision, err := strconv.ParseInt(h.Revision, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("etcd: invalid revision '%s'", h.Revision)
+	}
+	return revision, nil
+}
+
+// prefixEnd returns the end of the range of keys starting with prefix
+func prefixEnd(prefix string) string {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return string(end[:i+1])
+		}
+	}
+	// All keys
+	return "\x00"
+}
+
+func encode(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
@@ -0,0 +1,69 @@
+package etcd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func b64(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+func TestClient_Range(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/kv/range", r.URL.Path)
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, map[string]string{"key": b64("/agents/"), "range_end": b64("/agents0")}, body)
+		_, _ = w.Write([]byte(`{"header": {"revision": "17"}, "kvs": [{"key": "` + b64("/agents/weather") + `", "value": "` + b64(`{"model_id": "weather-agent"}`) + `"}]}`))
+	}))
+	defer server.Close()
+
+	kvs, revision, err := (&Client{Address: server.URL}).Range(context.Background(), "/agents/")
+	require.NoError(t, err)
+	assert.Equal(t, int64(17), revision)
+	assert.Equal(t, []KeyValue{{Key: "/agents/weather", Value: `{"model_id": "weather-agent"}`}}, kvs)
+}
+
+func TestClient_Watch(t *testing.T) {
+	messages := map[string][]string{
+		"18": {`{"result": {"header": {"revision": "17"}, "created": true}}`, `{"result": {"header": {"revision": "18"}, "events": [{"kv": {}}]}}`},
+		"2":  {`{"result": {"header": {"revision": "17"}, "created": true}}`, `{"result": {"canceled": true, "compact_revision": "10"}}`},
+		"19": {`{"result": {"header": {"revision": "18"}, "created": true}}`},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/watch", r.URL.Path)
+		var body struct {
+			CreateRequest map[string]string `json:"create_request"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		for _, message := range messages[body.CreateRequest["start_revision"]] {
+			_, _ = w.Write([]byte(message + "\n"))
+			w.(http.Flusher).Flush()
+		}
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	client := &Client{Address: server.URL}
+
+	assert.NoError(t, client.Watch(context.Background(), "/agents/", 18), "a change ends the watch")
+	assert.NoError(t, client.Watch(context.Background(), "/agents/", 2), "a compacted revision ends the watch")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, client.Watch(ctx, "/agents/", 19), context.DeadlineExceeded)
+}
+
+func TestPrefixEnd(t *testing.T) {
+	assert.Equal(t, "/agents0", prefixEnd("/agents/"))
+	assert.Equal(t, "b", prefixEnd("a\xff"))
+	assert.Equal(t, "\x00", prefixEnd("\xff"))
+}
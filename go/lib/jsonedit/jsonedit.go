@@ -0,0 +1,381 @@
+// Package jsonedit edits selected values of a JSON document without unmarshalling it.
+//
+// Values are located by scanning the document, and edits replace or remove their bytes. When the
+// document is written, everything that was not edited is copied unchanged, so the order of fields,
+// formatting and number representations are kept. Like package resp and memcache, it covers what
+// the plugins need, e.g. rewriting the URLs of large agent cards, without adding a JSON library to
+// the dependencies that plugins must share with KrakenD.
+package jsonedit
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+)
+
+// ErrInvalid is returned by Parse for documents that are not valid JSON
+var ErrInvalid = errors.New("jsonedit: invalid JSON")
+
+// Kind is the type of a JSON value
+type Kind int
+
+// Kinds of JSON values
+const (
+	Invalid Kind = iota
+	Object
+	Array
+	String
+	Number
+	Bool
+	Null
+)
+
+// Document is a JSON document with pending edits. Values of a document see the removals of
+// members and elements, but not the contents of replacements.
+type Document struct {
+	src          []byte
+	root         Value
+	replacements []edit
+	// removed holds the indexes of the removed members or elements per container start offset
+	removed map[int]map[int]bool
+}
+
+// edit replaces the bytes src[start:end]
+type edit struct {
+	start, end int
+	raw        []byte
+}
+
+// Value is a value of a Document
+type Value struct {
+	doc        *Document
+	start, end int
+}
+
+// Member is a key and value of an object
+type Member struct {
+	Key   string
+	Value Value
+}
+
+// Parse validates src and returns a document without edits. src must not be modified while the
+// document is used.
+func Parse(src []byte) (*Document, error) {
+	if !json.Valid(src) {
+		return nil, ErrInvalid
+	}
+	d := &Document{src: src, removed: make(map[int]map[int]bool)}
+	start := skipSpace(src, 0)
+	d.root = Value{doc: d, start: start, end: valueEnd(src, start)}
+	return d, nil
+}
+
+// Root returns the top-level value
+func (d *Document) Root() Value {
+	return d.root
+}
+
+// Replace replaces v by raw, which must be valid JSON. Replacing a value again overrides the
+// earlier replacement.
+func (d *Document) Replace(v Value, raw []byte) {
+	for i, e := range d.replacements {
+		if e.start == v.start && e.end == v.end {
+			d.replacements[i].raw = raw
+			return
+		}
+	}
+	d.replacements = append(d.replacements, edit{start: v.start, end: v.end, raw: raw})
+}
+
+// SetString replaces v by the string s, escaped like json.Marshal
+func (d *Document) SetString(v Value, s string) {
+	raw, _ := json.Marshal(s)
+	d.Replace(v, raw)
+}
+
+// Remove removes child from its object or array container. Removing a value that is not a
+// child of container has no effect.
+func (d *Document) Remove(container, child Value) {
+	for i, c := range container.children() {
+		if c.value.start == child.start {
+			if d.removed[container.start] == nil {
+				d.removed[container.start] = make(map[int]bool)
+			}
+			d.removed[container.start][i] = true
+			return
+		}
+	}
+}
+
+// WriteTo writes the edited document to w
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	edits := append([]edit{}, d.replacements...)
+	for start, indexes := range d.removed {
+		container := Value{doc: d, start: start, end: valueEnd(d.src, start)}
+		edits = append(edits, container.removals(indexes)...)
+	}
+	// Edits inside replaced or removed values are skipped
+	sort.Slice(edits, func(i, j int) bool {
+		if edits[i].start != edits[j].start {
+			return edits[i].start < edits[j].start
+		}
+		return edits[i].end > edits[j].end
+	})
+
+	var written int64
+	write := func(b []byte) error {
+		n, err := w.Write(b)
+		written += int64(n)
+		return err
+	}
+	cursor := d.root.start
+	for _, e := range edits {
+		if e.start < cursor {
+			continue
+		}
+		if err := write(d.src[cursor:e.start]); err != nil {
+			return written, err
+		}
+		if err := write(e.raw); err != nil {
+			return written, err
+		}
+		cursor = e.end
+	}
+	err := write(d.src[cursor:d.root.end])
+	return written, err
+}
+
+// Kind returns the type of v
+func (v Value) Kind() Kind {
+	if v.start >= v.end {
+		return Invalid
+	}
+	switch c := v.doc.src[v.start]; {
+	case c == '{':
+		return Object
+	case c == '[':
+		return Array
+	case c == '"':
+		return String
+	case c == 't' || c == 'f':
+		return Bool
+	case c == 'n':
+		return Null
+	default:
+		return Number
+	}
+}
+
+// Raw returns the unedited bytes of v
+func (v Value) Raw() []byte {
+	return v.doc.src[v.start:v.end]
+}
+
+// Text returns the contents of a string value
+func (v Value) Text() (string, bool) {
+	if v.Kind() != String {
+		return "", false
+	}
+	return unquote(v.Raw()), true
+}
+
+// Get returns the member value of an object. Like encoding/json, the last of duplicate keys wins.
+func (v Value) Get(key string) (Value, bool) {
+	var found Value
+	ok := false
+	for _, m := range v.Members() {
+		if m.Key == key {
+			found, ok = m.Value, true
+		}
+	}
+	return found, ok
+}
+
+// Members returns the members of an object that have not been removed
+func (v Value) Members() []Member {
+	if v.Kind() != Object || v.replaced() {
+		return nil
+	}
+	removed := v.doc.removed[v.start]
+	var members []Member
+	for i, c := range v.children() {
+		if !removed[i] {
+			members = append(members, Member{Key: unquote(v.doc.src[c.keyStart:c.keyEnd]), Value: c.value})
+		}
+	}
+	return members
+}
+
+// Elements returns the elements of an array that have not been removed
+func (v Value) Elements() []Value {
+	if v.Kind() != Array || v.replaced() {
+		return nil
+	}
+	removed := v.doc.removed[v.start]
+	var elements []Value
+	for i, c := range v.children() {
+		if !removed[i] {
+			elements = append(elements, c.value)
+		}
+	}
+	return elements
+}
+
+// Walk calls fn for v and all values below it that have not been removed, parents first
+func (v Value) Walk(fn func(Value)) {
+	fn(v)
+	for _, m := range v.Members() {
+		m.Value.Walk(fn)
+	}
+	for _, e := range v.Elements() {
+		e.Walk(fn)
+	}
+}
+
+// replaced reports whether v is part of a replacement
+func (v Value) replaced() bool {
+	for _, e := range v.doc.replacements {
+		if e.start <= v.start && v.end <= e.end {
+			return true
+		}
+	}
+	return false
+}
+
+// child is a member or element of a container. Elements have no key.
+type child struct {
+	keyStart, keyEnd int
+	value            Value
+}
+
+// children scans the members or elements of a container
+func (v Value) children() []child {
+	src := v.doc.src
+	var children []child
+	i := skipSpace(src, v.start+1)
+	for src[i] != '}' && src[i] != ']' {
+		var c child
+		if src[v.start] == '{' {
+			c.keyStart, c.keyEnd = i, stringEnd(src, i)
+			i = skipSpace(src, c.keyEnd) + 1
+			i = skipSpace(src, i)
+		}
+		c.value = Value{doc: v.doc, start: i, end: valueEnd(src, i)}
+		children = append(children, c)
+		i = skipSpace(src, c.value.end)
+		if src[i] == ',' {
+			i = skipSpace(src, i+1)
+		}
+	}
+	return children
+}
+
+// start returns the offset of the key of a member, or of the value of an element
+func (c child) start() int {
+	if c.keyEnd > 0 {
+		return c.keyStart
+	}
+	return c.value.start
+}
+
+// removals returns the edits removing the children at indexes along with their separators
+func (v Value) removals(indexes map[int]bool) []edit {
+	children := v.children()
+	kept := 0
+	for i := range children {
+		if !indexes[i] {
+			kept++
+		}
+	}
+	if kept == 0 {
+		return []edit{{start: v.start + 1, end: v.end - 1}}
+	}
+
+	// Runs of removed children are cut from the end of the previous child, or up to the next
+	// child if the run starts the container
+	var edits []edit
+	for i := 0; i < len(children); i++ {
+		if !indexes[i] {
+			continue
+		}
+		last := i
+		for last+1 < len(children) && indexes[last+1] {
+			last++
+		}
+		if i > 0 {
+			edits = append(edits, edit{start: children[i-1].value.end, end: children[last].value.end})
+		} else {
+			edits = append(edits, edit{start: children[0].start(), end: children[last+1].start()})
+		}
+		i = last
+	}
+	return edits
+}
+
+func skipSpace(src []byte, i int) int {
+	for i < len(src) && (src[i] == ' ' || src[i] == '\t' || src[i] == '\n' || src[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// valueEnd returns the offset after the value starting at i. The document is known to be valid.
+func valueEnd(src []byte, i int) int {
+	switch src[i] {
+	case '"':
+		return stringEnd(src, i)
+	case '{', '[':
+		depth := 0
+		for ; i < len(src); i++ {
+			switch src[i] {
+			case '"':
+				i = stringEnd(src, i) - 1
+			case '{', '[':
+				depth++
+			case '}', ']':
+				depth--
+				if depth == 0 {
+					return i + 1
+				}
+			}
+		}
+		return i
+	default:
+		for i < len(src) && src[i] != ',' && src[i] != '}' && src[i] != ']' && src[i] != ' ' &&
+			src[i] != '\t' && src[i] != '\n' && src[i] != '\r' {
+			i++
+		}
+		return i
+	}
+}
+
+// stringEnd returns the offset after the string starting with the quote at i
+func stringEnd(src []byte, i int) int {
+	for i++; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return i
+}
+
+// unquote decodes a string token, without allocating escape handling for plain strings
+func unquote(raw []byte) string {
+	plain := true
+	for _, c := range raw {
+		if c == '\\' {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return string(raw[1 : len(raw)-1])
+	}
+	var s string
+	_ = json.Unmarshal(raw, &s)
+	return s
+}
@@ -0,0 +1,176 @@
+package jsonedit
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func write(t *testing.T, doc *Document) string {
+	var buf bytes.Buffer
+	n, err := doc.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(buf.Len()), n)
+	return buf.String()
+}
+
+func TestParse(t *testing.T) {
+	_, err := Parse([]byte(`{"name":`))
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	doc, err := Parse([]byte(" \n{\"z\": 1e21, \"a\": [true, null, \"x\"]}\n"))
+	require.NoError(t, err)
+	root := doc.Root()
+	assert.Equal(t, Object, root.Kind())
+	assert.Equal(t, `{"z": 1e21, "a": [true, null, "x"]}`, write(t, doc), "surrounding whitespace is trimmed")
+
+	members := root.Members()
+	require.Len(t, members, 2)
+	assert.Equal(t, "z", members[0].Key)
+	assert.Equal(t, Number, members[0].Value.Kind())
+	assert.Equal(t, "1e21", string(members[0].Value.Raw()))
+
+	elements := members[1].Value.Elements()
+	require.Len(t, elements, 3)
+	assert.Equal(t, []Kind{Bool, Null, String}, []Kind{elements[0].Kind(), elements[1].Kind(), elements[2].Kind()})
+	_, ok := elements[0].Text()
+	assert.False(t, ok)
+}
+
+func TestValue_Text(t *testing.T) {
+	doc, err := Parse([]byte(`{"url":"http:\/\/agent:8000\/","k\"ey":"café","dup":1,"dup":2}`))
+	require.NoError(t, err)
+
+	url, ok := doc.Root().Get("url")
+	require.True(t, ok)
+	text, _ := url.Text()
+	assert.Equal(t, "http://agent:8000/", text)
+
+	value, ok := doc.Root().Get(`k"ey`)
+	require.True(t, ok)
+	text, _ = value.Text()
+	assert.Equal(t, "café", text)
+
+	dup, _ := doc.Root().Get("dup")
+	assert.Equal(t, "2", string(dup.Raw()), "the last duplicate key wins")
+	_, ok = doc.Root().Get("missing")
+	assert.False(t, ok)
+}
+
+func TestDocument_Replace(t *testing.T) {
+	doc, err := Parse([]byte(`{"url": "http://agent:8000", "skills": [{"id": "a"}], "n": 1.50}`))
+	require.NoError(t, err)
+	url, _ := doc.Root().Get("url")
+	doc.SetString(url, "https://gateway/<agent>")
+	doc.SetString(url, "overridden")
+	doc.SetString(url, "https://gateway/<agent>")
+	skills, _ := doc.Root().Get("skills")
+	id, _ := skills.Elements()[0].Get("id")
+	doc.SetString(id, "nested")
+	doc.Replace(skills, []byte(`[]`))
+
+	assert.Equal(t, `{"url": "https://gateway/\u003cagent\u003e", "skills": [], "n": 1.50}`, write(t, doc),
+		"edits inside replaced values are skipped")
+	assert.Empty(t, skills.Elements())
+	assert.Equal(t, "http://agent:8000", mustText(t, url), "values keep their unedited contents")
+}
+
+func mustText(t *testing.T, v Value) string {
+	text, ok := v.Text()
+	require.True(t, ok)
+	return text
+}
+
+func TestDocument_Remove(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		remove []int
+		want   string
+	}{
+		{name: "first", src: `[1, 2, 3]`, remove: []int{0}, want: `[2, 3]`},
+		{name: "middle", src: `[1, 2, 3]`, remove: []int{1}, want: `[1, 3]`},
+		{name: "last", src: `[1, 2, 3]`, remove: []int{2}, want: `[1, 2]`},
+		{name: "runs", src: `[1, 2, 3, 4, 5]`, remove: []int{0, 1, 3, 4}, want: `[3]`},
+		{name: "all", src: "[ 1,\n 2 ]", remove: []int{0, 1}, want: `[]`},
+		{name: "members", src: `{"a": 1, "b": {"c": 2}, "d": 3}`, remove: []int{0, 1}, want: `{"d": 3}`},
+		{name: "trailing members", src: `{"a": 1, "b": [2], "d": 3}`, remove: []int{1, 2}, want: `{"a": 1}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse([]byte(tt.src))
+			require.NoError(t, err)
+			root := doc.Root()
+			var children []Value
+			if root.Kind() == Object {
+				for _, m := range root.Members() {
+					children = append(children, m.Value)
+				}
+			} else {
+				children = root.Elements()
+			}
+			for _, i := range tt.remove {
+				doc.Remove(root, children[i])
+				doc.Remove(root, children[i])
+			}
+			assert.Equal(t, tt.want, write(t, doc))
+			assert.Len(t, append(root.Elements(), valuesOf(root.Members())...), len(children)-len(tt.remove))
+		})
+	}
+}
+
+func valuesOf(members []Member) []Value {
+	values := make([]Value, 0, len(members))
+	for _, m := range members {
+		values = append(values, m.Value)
+	}
+	return values
+}
+
+func TestDocument_EditsInsideRemovedValues(t *testing.T) {
+	doc, err := Parse([]byte(`{"interfaces": [{"url": "a"}, {"url": "b"}], "url": "c"}`))
+	require.NoError(t, err)
+	interfaces, _ := doc.Root().Get("interfaces")
+	for _, iface := range interfaces.Elements() {
+		url, _ := iface.Get("url")
+		doc.SetString(url, "rewritten")
+	}
+	doc.Remove(interfaces, interfaces.Elements()[0])
+	doc.Remove(doc.Root(), interfaces)
+	url, _ := doc.Root().Get("url")
+	doc.SetString(url, "d")
+
+	assert.Equal(t, `{"url": "d"}`, write(t, doc))
+}
+
+func TestValue_Walk(t *testing.T) {
+	doc, err := Parse([]byte(`{"a": ["x", {"b": "y"}], "c": "z"}`))
+	require.NoError(t, err)
+	c, _ := doc.Root().Get("c")
+	doc.Remove(doc.Root(), c)
+
+	var texts []string
+	doc.Root().Walk(func(v Value) {
+		if text, ok := v.Text(); ok {
+			texts = append(texts, text)
+		}
+	})
+	assert.Equal(t, []string{"x", "y"}, texts)
+}
+
+func BenchmarkDocument(b *testing.B) {
+	skills := strings.Repeat(`{"id":"forecast","name":"Forecast","description":"Provides forecasts","tags":["weather","forecast"]},`, 200)
+	src := []byte(`{"name":"Weather","url":"http://weather:8000","skills":[` + strings.TrimSuffix(skills, ",") + `]}`)
+	var buf bytes.Buffer
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		doc, _ := Parse(src)
+		url, _ := doc.Root().Get("url")
+		doc.SetString(url, "https://gateway.example.com/weather")
+		buf.Reset()
+		_, _ = doc.WriteTo(&buf)
+	}
+}
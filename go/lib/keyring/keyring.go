@@ -0,0 +1,270 @@
+// Package keyring manages sets of secrets that can be rotated without downtime.
+//
+// Several keys may be valid at the same time: during a rotation window both the old
+// and the new key are accepted, while new signatures are always created with the
+// primary key (the active key that became valid most recently). Keys may be split into
+// rotation groups, e.g. one per principal, each with its own primary key. Every successful
+// match is recorded per client, so operators can see which clients still use
+// credentials that are about to expire.
+package keyring
+
+import (
+	"container/list"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxUsageEntries bounds the recorded usage; the least recently seen client and key pairs are
+// dropped first.
+const maxUsageEntries = 10000
+
+// KeyConfig is the JSON representation of a key in plugin configuration.
+// NotBefore and NotAfter are optional RFC 3339 timestamps.
+type KeyConfig struct {
+	ID        string `json:"id"`
+	Secret    string `json:"secret"`
+	NotBefore string `json:"not_before,omitempty"`
+	NotAfter  string `json:"not_after,omitempty"`
+	Group     string `json:"group,omitempty"`
+}
+
+// Key is a secret with an optional validity window. Keys of the same Group replace each
+// other during a rotation; keys without a group form one group.
+type Key struct {
+	ID        string
+	Secret    []byte
+	NotBefore time.Time
+	NotAfter  time.Time
+	Group     string
+}
+
+// ValidAt reports whether the key is within its validity window at the given time.
+func (k Key) ValidAt(t time.Time) bool {
+	if !k.NotBefore.IsZero() && t.Before(k.NotBefore) {
+		return false
+	}
+	if !k.NotAfter.IsZero() && !t.Before(k.NotAfter) {
+		return false
+	}
+	return true
+}
+
+// Usage describes the most recent use of a key by a client.
+type Usage struct {
+	Client   string    `json:"client"`
+	KeyID    string    `json:"key_id"`
+	Group    string    `json:"group,omitempty"`
+	Count    int64     `json:"count"`
+	LastSeen time.Time `json:"last_seen"`
+	// Stale is true if the key is no longer the primary key of its group, i.e. the client should rotate.
+	Stale bool `json:"stale"`
+}
+
+type usageKey struct {
+	client string
+	keyID  string
+}
+
+// Keyring holds a set of keys and records which clients use them.
+// It is safe for concurrent use.
+type Keyring struct {
+	keys   []Key
+	groups map[string]string
+	now    func() time.Time
+
+	mu    sync.Mutex
+	usage map[usageKey]*list.Element
+	// recent orders the usage entries from the most to the least recently seen
+	recent *list.List
+}
+
+// New creates a keyring from parsed keys. Key IDs must be unique and secrets non-empty.
+func New(keys []Key) (*Keyring, error) {
+	groups := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("key without id")
+		}
+		if _, ok := groups[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id '%s'", key.ID)
+		}
+		if len(key.Secret) == 0 {
+			return nil, fmt.Errorf("key '%s' has an empty secret", key.ID)
+		}
+		if !key.NotBefore.IsZero() && !key.NotAfter.IsZero() && !key.NotBefore.Before(key.NotAfter) {
+			return nil, fmt.Errorf("key '%s' has not_before after not_after", key.ID)
+		}
+		groups[key.ID] = key.Group
+	}
+	return &Keyring{
+		keys:   keys,
+		groups: groups,
+		now:    time.Now,
+		usage:  make(map[usageKey]*list.Element),
+		recent: list.New(),
+	}, nil
+}
+
+// FromConfig parses key configurations and creates a keyring.
+func FromConfig(configs []KeyConfig) (*Keyring, error) {
+	keys := make([]Key, 0, len(configs))
+	for _, c := range configs {
+		key := Key{ID: c.ID, Secret: []byte(c.Secret), Group: c.Group}
+		var err error
+		if key.NotBefore, err = parseTime(c.NotBefore); err != nil {
+			return nil, fmt.Errorf("key '%s': invalid not_before: %w", c.ID, err)
+		}
+		if key.NotAfter, err = parseTime(c.NotAfter); err != nil {
+			return nil, fmt.Errorf("key '%s': invalid not_after: %w", c.ID, err)
+		}
+		keys = append(keys, key)
+	}
+	return New(keys)
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// Active returns all keys valid now, primary key first.
+func (k *Keyring) Active() []Key {
+	now := k.now()
+	var active []Key
+	for _, key := range k.keys {
+		if key.ValidAt(now) {
+			active = append(active, key)
+		}
+	}
+	// The key that became valid most recently is the primary key
+	sort.SliceStable(active, func(i, j int) bool {
+		return active[i].NotBefore.After(active[j].NotBefore)
+	})
+	return active
+}
+
+// Primary returns the key used to create new signatures, the most recent active key of all groups.
+func (k *Keyring) Primary() (Key, bool) {
+	active := k.Active()
+	if len(active) == 0 {
+		return Key{}, false
+	}
+	return active[0], true
+}
+
+// Match compares a presented secret against all active keys in constant time
+// and records the use for the client on success.
+func (k *Keyring) Match(secret []byte, client string) (Key, bool) {
+	var matched Key
+	found := false
+	for _, key := range k.Active() {
+		if subtle.ConstantTimeCompare(secret, key.Secret) == 1 && !found {
+			matched = key
+			found = true
+		}
+	}
+	if found {
+		k.record(client, matched.ID)
+	}
+	return matched, found
+}
+
+// Sign creates an HMAC-SHA256 signature of the message with the primary key.
+func (k *Keyring) Sign(message []byte) (keyID string, signature []byte, err error) {
+	primary, ok := k.Primary()
+	if !ok {
+		return "", nil, errors.New("no active key")
+	}
+	mac := hmac.New(sha256.New, primary.Secret)
+	mac.Write(message)
+	return primary.ID, mac.Sum(nil), nil
+}
+
+// VerifyHMAC checks an HMAC-SHA256 signature against all active keys
+// and records the use for the client on success.
+func (k *Keyring) VerifyHMAC(message, signature []byte, client string) (Key, bool) {
+	for _, key := range k.Active() {
+		mac := hmac.New(sha256.New, key.Secret)
+		mac.Write(message)
+		if hmac.Equal(mac.Sum(nil), signature) {
+			k.record(client, key.ID)
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+// record counts the use of a key by a client, dropping the least recently seen entry beyond
+// maxUsageEntries
+func (k *Keyring) record(client, keyID string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	uk := usageKey{client: client, keyID: keyID}
+	element, ok := k.usage[uk]
+	if ok {
+		k.recent.MoveToFront(element)
+	} else {
+		element = k.recent.PushFront(&Usage{Client: client, KeyID: keyID, Group: k.groups[keyID]})
+		k.usage[uk] = element
+		if k.recent.Len() > maxUsageEntries {
+			oldest := k.recent.Remove(k.recent.Back()).(*Usage)
+			delete(k.usage, usageKey{client: oldest.Client, keyID: oldest.KeyID})
+		}
+	}
+	u := element.Value.(*Usage)
+	u.Count++
+	u.LastSeen = k.now()
+}
+
+// primaries returns the ID of the primary key of every rotation group
+func (k *Keyring) primaries() map[string]string {
+	primaries := make(map[string]string)
+	for _, key := range k.Active() {
+		if _, ok := primaries[key.Group]; !ok {
+			primaries[key.Group] = key.ID
+		}
+	}
+	return primaries
+}
+
+// Report returns the recorded key usage per client and key, sorted by client and key ID.
+// Entries for keys other than the current primary key of their group are marked stale.
+func (k *Keyring) Report() []Usage {
+	primaries := k.primaries()
+
+	k.mu.Lock()
+	report := make([]Usage, 0, len(k.usage))
+	for element := k.recent.Front(); element != nil; element = element.Next() {
+		entry := *element.Value.(*Usage)
+		entry.Stale = entry.KeyID != primaries[entry.Group]
+		report = append(report, entry)
+	}
+	k.mu.Unlock()
+
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Client != report[j].Client {
+			return report[i].Client < report[j].Client
+		}
+		return report[i].KeyID < report[j].KeyID
+	})
+	return report
+}
+
+// StaleClients returns the usage entries of clients still presenting a non-primary key.
+func (k *Keyring) StaleClients() []Usage {
+	var stale []Usage
+	for _, u := range k.Report() {
+		if u.Stale {
+			stale = append(stale, u)
+		}
+	}
+	return stale
+}
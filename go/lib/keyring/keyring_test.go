@@ -0,0 +1,143 @@
+package keyring
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newRotatingKeyring(t *testing.T, now time.Time) *Keyring {
+	k, err := FromConfig([]KeyConfig{
+		{ID: "old", Secret: "old-secret", NotAfter: "2026-01-31T00:00:00Z"},
+		{ID: "new", Secret: "new-secret", NotBefore: "2026-01-01T00:00:00Z"},
+	})
+	assert.NoError(t, err)
+	k.now = func() time.Time { return now }
+	return k
+}
+
+func TestFromConfig_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		configs []KeyConfig
+	}{
+		{name: "missing id", configs: []KeyConfig{{Secret: "s"}}},
+		{name: "empty secret", configs: []KeyConfig{{ID: "a"}}},
+		{name: "duplicate id", configs: []KeyConfig{{ID: "a", Secret: "s"}, {ID: "a", Secret: "t"}}},
+		{name: "invalid timestamp", configs: []KeyConfig{{ID: "a", Secret: "s", NotBefore: "tomorrow"}}},
+		{name: "inverted window", configs: []KeyConfig{{ID: "a", Secret: "s", NotBefore: "2026-02-01T00:00:00Z", NotAfter: "2026-01-01T00:00:00Z"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := FromConfig(tt.configs)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestKeyring_OverlappingValidity(t *testing.T) {
+	tests := []struct {
+		name        string
+		now         time.Time
+		wantPrimary string
+		oldValid    bool
+		newValid    bool
+	}{
+		{name: "before rotation", now: time.Date(2025, 12, 15, 0, 0, 0, 0, time.UTC), wantPrimary: "old", oldValid: true},
+		{name: "during rotation window", now: time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), wantPrimary: "new", oldValid: true, newValid: true},
+		{name: "after rotation", now: time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC), wantPrimary: "new", newValid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := newRotatingKeyring(t, tt.now)
+
+			primary, ok := k.Primary()
+			assert.True(t, ok)
+			assert.Equal(t, tt.wantPrimary, primary.ID)
+
+			_, ok = k.Match([]byte("old-secret"), "client")
+			assert.Equal(t, tt.oldValid, ok)
+			_, ok = k.Match([]byte("new-secret"), "client")
+			assert.Equal(t, tt.newValid, ok)
+		})
+	}
+}
+
+func TestKeyring_SignAndVerifyDuringRotation(t *testing.T) {
+	before := newRotatingKeyring(t, time.Date(2025, 12, 15, 0, 0, 0, 0, time.UTC))
+	keyID, signature, err := before.Sign([]byte("payload"))
+	assert.NoError(t, err)
+	assert.Equal(t, "old", keyID)
+
+	during := newRotatingKeyring(t, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC))
+	key, ok := during.VerifyHMAC([]byte("payload"), signature, "client")
+	assert.True(t, ok)
+	assert.Equal(t, "old", key.ID)
+
+	_, ok = during.VerifyHMAC([]byte("tampered"), signature, "client")
+	assert.False(t, ok)
+}
+
+func TestKeyring_ReportsStaleClients(t *testing.T) {
+	k := newRotatingKeyring(t, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC))
+
+	k.Match([]byte("old-secret"), "billing-service")
+	k.Match([]byte("old-secret"), "billing-service")
+	k.Match([]byte("new-secret"), "chat-ui")
+	k.Match([]byte("wrong"), "intruder")
+
+	report := k.Report()
+	assert.Len(t, report, 2)
+
+	stale := k.StaleClients()
+	assert.Len(t, stale, 1)
+	assert.Equal(t, "billing-service", stale[0].Client)
+	assert.Equal(t, "old", stale[0].KeyID)
+	assert.Equal(t, int64(2), stale[0].Count)
+}
+
+func TestKeyring_ReportsStalenessPerGroup(t *testing.T) {
+	k, err := FromConfig([]KeyConfig{
+		{ID: "weather-2025", Secret: "weather-old", Group: "weather-team", NotAfter: "2026-01-31T00:00:00Z"},
+		{ID: "weather-2026", Secret: "weather-new", Group: "weather-team", NotBefore: "2026-01-01T00:00:00Z"},
+		{ID: "travel", Secret: "travel-secret", Group: "travel-team"},
+	})
+	assert.NoError(t, err)
+	k.now = func() time.Time { return time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC) }
+
+	k.Match([]byte("weather-old"), "10.0.0.1")
+	k.Match([]byte("weather-new"), "10.0.0.2")
+	k.Match([]byte("travel-secret"), "10.0.0.3")
+
+	stale := k.StaleClients()
+	assert.Len(t, stale, 1, "the primary key of another group is not a rotation")
+	assert.Equal(t, "weather-2025", stale[0].KeyID)
+	assert.Equal(t, "weather-team", stale[0].Group)
+}
+
+func TestKeyring_BoundsUsage(t *testing.T) {
+	k := newRotatingKeyring(t, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC))
+
+	k.Match([]byte("new-secret"), "first")
+	k.Match([]byte("new-secret"), "second")
+	for i := 0; i < maxUsageEntries; i++ {
+		k.Match([]byte("new-secret"), fmt.Sprintf("client-%d", i))
+		if i == 0 {
+			k.Match([]byte("new-secret"), "first")
+		}
+	}
+
+	report := k.Report()
+	assert.Len(t, report, maxUsageEntries)
+	clients := make(map[string]bool, len(report))
+	for _, u := range report {
+		clients[u.Client] = true
+	}
+	assert.True(t, clients["first"], "recently seen clients are kept")
+	assert.False(t, clients["second"], "the least recently seen client is dropped")
+}
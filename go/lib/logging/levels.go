@@ -0,0 +1,123 @@
+package logging
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Level is the minimum severity of messages a plugin logs
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarning
+	LevelError
+	LevelCritical
+	LevelFatal
+)
+
+// Options configure the logging of a plugin, in the "logging" object of its extra_config
+type Options struct {
+	// Level is debug, info, warning, error or critical; debug by default, so KrakenD's log level applies
+	Level string `json:"level,omitempty"`
+	// DebugSampleRate is the fraction of debug messages that are logged, 1 by default
+	DebugSampleRate *float64 `json:"debug_sample_rate,omitempty"`
+}
+
+// settings holds the effective options of a plugin
+type settings struct {
+	level      Level
+	sampleRate float64
+}
+
+var (
+	mu      sync.RWMutex
+	plugins = map[string]settings{}
+
+	// sample decides whether a sampled debug message is logged
+	sample = defaultSample
+)
+
+func defaultSample(rate float64) bool { return rand.Float64() < rate }
+
+// ParseLevel parses a level name; warn is accepted for warning
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarning, nil
+	case "error":
+		return LevelError, nil
+	case "critical":
+		return LevelCritical, nil
+	}
+	return LevelDebug, fmt.Errorf("unknown log level '%s', use debug, info, warning, error or critical", name)
+}
+
+// envPrefix returns the prefix of the environment variables of a plugin, e.g. OPENAI_A2A_
+func envPrefix(pluginName string) string {
+	return strings.ToUpper(strings.ReplaceAll(pluginName, "-", "_")) + "_"
+}
+
+// Configure sets the log level and debug sample rate of a plugin. The environment variables
+// {PLUGIN}_LOG_LEVEL and {PLUGIN}_LOG_DEBUG_SAMPLE_RATE, e.g. OPENAI_A2A_LOG_LEVEL, take
+// precedence over opts, so single plugins can be switched to debug logging without config changes.
+func Configure(pluginName string, opts Options) error {
+	s := settings{level: LevelDebug, sampleRate: 1}
+	prefix := envPrefix(pluginName)
+
+	level := opts.Level
+	if env := os.Getenv(prefix + "LOG_LEVEL"); env != "" {
+		level = env
+	}
+	if level != "" {
+		var err error
+		if s.level, err = ParseLevel(level); err != nil {
+			return err
+		}
+	}
+
+	if opts.DebugSampleRate != nil {
+		s.sampleRate = *opts.DebugSampleRate
+	}
+	if env := os.Getenv(prefix + "LOG_DEBUG_SAMPLE_RATE"); env != "" {
+		rate, err := strconv.ParseFloat(env, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %sLOG_DEBUG_SAMPLE_RATE '%s'", prefix, env)
+		}
+		s.sampleRate = rate
+	}
+	if s.sampleRate < 0 || s.sampleRate > 1 {
+		return fmt.Errorf("debug sample rate %g is not between 0 and 1", s.sampleRate)
+	}
+
+	mu.Lock()
+	plugins[pluginName] = s
+	mu.Unlock()
+	return nil
+}
+
+// enabled reports whether a message of a plugin at level is logged
+func enabled(pluginName string, level Level) bool {
+	mu.RLock()
+	s, ok := plugins[pluginName]
+	mu.RUnlock()
+	if !ok {
+		return true
+	}
+	if level < s.level {
+		return false
+	}
+	if level == LevelDebug && s.sampleRate < 1 {
+		return sample(s.sampleRate)
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingLogger records the levels of the messages it receives
+type recordingLogger struct {
+	levels []string
+}
+
+func (l *recordingLogger) Debug(...interface{})    { l.levels = append(l.levels, "debug") }
+func (l *recordingLogger) Info(...interface{})     { l.levels = append(l.levels, "info") }
+func (l *recordingLogger) Warning(...interface{})  { l.levels = append(l.levels, "warning") }
+func (l *recordingLogger) Error(...interface{})    { l.levels = append(l.levels, "error") }
+func (l *recordingLogger) Critical(...interface{}) { l.levels = append(l.levels, "critical") }
+func (l *recordingLogger) Fatal(...interface{})    { l.levels = append(l.levels, "fatal") }
+
+func logAll(l Logger) {
+	l.Debug("d")
+	l.Info("i")
+	l.Warning("w")
+	l.Error("e")
+	l.Critical("c")
+	l.Fatal("f")
+}
+
+func TestWrap_LogsEverythingByDefault(t *testing.T) {
+	inner := &recordingLogger{}
+	l, ok := Wrap(inner, "levels-default")
+	require.True(t, ok)
+
+	logAll(l)
+
+	assert.Equal(t, []string{"debug", "info", "warning", "error", "critical", "fatal"}, inner.levels)
+}
+
+func TestConfigure_Level(t *testing.T) {
+	require.NoError(t, Configure("levels-warn", Options{Level: "warn"}))
+	inner := &recordingLogger{}
+	l, _ := Wrap(inner, "levels-warn")
+
+	logAll(l)
+
+	assert.Equal(t, []string{"warning", "error", "critical", "fatal"}, inner.levels)
+}
+
+func TestConfigure_EnvironmentOverridesConfig(t *testing.T) {
+	t.Setenv("LEVELS_ENV_LOG_LEVEL", "ERROR")
+	require.NoError(t, Configure("levels-env", Options{Level: "debug"}))
+	inner := &recordingLogger{}
+	l, _ := Wrap(inner, "levels-env")
+
+	logAll(l)
+
+	assert.Equal(t, []string{"error", "critical", "fatal"}, inner.levels)
+}
+
+func TestConfigure_DebugSampleRate(t *testing.T) {
+	rate := 0.25
+	require.NoError(t, Configure("levels-sampled", Options{DebugSampleRate: &rate}))
+	var rates []float64
+	sample = func(r float64) bool { rates = append(rates, r); return false }
+	t.Cleanup(func() { sample = defaultSample })
+	inner := &recordingLogger{}
+	l, _ := Wrap(inner, "levels-sampled")
+
+	logAll(l)
+
+	assert.Equal(t, []float64{0.25}, rates)
+	assert.Equal(t, []string{"info", "warning", "error", "critical", "fatal"}, inner.levels)
+}
+
+func TestConfigure_Invalid(t *testing.T) {
+	assert.Error(t, Configure("levels-invalid", Options{Level: "verbose"}))
+
+	rate := 1.5
+	assert.Error(t, Configure("levels-invalid", Options{DebugSampleRate: &rate}))
+
+	t.Setenv("LEVELS_INVALID_LOG_DEBUG_SAMPLE_RATE", "often")
+	assert.Error(t, Configure("levels-invalid", Options{}))
+}
@@ -27,12 +27,37 @@ func (l *fallbackLogger) log(level string, v ...interface{}) {
 	fmt.Fprintf(os.Stdout, " %s ▶ %-5s [%s] %s\n", ts, level, strings.ToUpper(l.pluginName), fmt.Sprint(v...))
 }
 
-func (l *fallbackLogger) Debug(v ...interface{})    { l.log("DEBUG", v...) }
-func (l *fallbackLogger) Info(v ...interface{})     { l.log("INFO", v...) }
-func (l *fallbackLogger) Warning(v ...interface{})  { l.log("WARN", v...) }
-func (l *fallbackLogger) Error(v ...interface{})    { l.log("ERROR", v...) }
-func (l *fallbackLogger) Critical(v ...interface{}) { l.log("CRIT", v...) }
-func (l *fallbackLogger) Fatal(v ...interface{})    { l.log("FATAL", v...) }
+func (l *fallbackLogger) Debug(v ...interface{}) {
+	if enabled(l.pluginName, LevelDebug) {
+		l.log("DEBUG", v...)
+	}
+}
+
+func (l *fallbackLogger) Info(v ...interface{}) {
+	if enabled(l.pluginName, LevelInfo) {
+		l.log("INFO", v...)
+	}
+}
+
+func (l *fallbackLogger) Warning(v ...interface{}) {
+	if enabled(l.pluginName, LevelWarning) {
+		l.log("WARN", v...)
+	}
+}
+
+func (l *fallbackLogger) Error(v ...interface{}) {
+	if enabled(l.pluginName, LevelError) {
+		l.log("ERROR", v...)
+	}
+}
+
+func (l *fallbackLogger) Critical(v ...interface{}) {
+	if enabled(l.pluginName, LevelCritical) {
+		l.log("CRIT", v...)
+	}
+}
+
+func (l *fallbackLogger) Fatal(v ...interface{}) { l.log("FATAL", v...) }
 
 // New returns a Logger that produces output in KrakenD's log format.
 // Used as a fallback before KrakenD provides its own logger via RegisterLogger.
@@ -50,12 +75,37 @@ func (l *prefixLogger) tag(v []interface{}) []interface{} {
 	return append([]interface{}{fmt.Sprintf("[%s]", strings.ToUpper(l.pluginName))}, v...)
 }
 
-func (l *prefixLogger) Debug(v ...interface{})    { l.inner.Debug(l.tag(v)...) }
-func (l *prefixLogger) Info(v ...interface{})     { l.inner.Info(l.tag(v)...) }
-func (l *prefixLogger) Warning(v ...interface{})  { l.inner.Warning(l.tag(v)...) }
-func (l *prefixLogger) Error(v ...interface{})    { l.inner.Error(l.tag(v)...) }
-func (l *prefixLogger) Critical(v ...interface{}) { l.inner.Critical(l.tag(v)...) }
-func (l *prefixLogger) Fatal(v ...interface{})    { l.inner.Fatal(l.tag(v)...) }
+func (l *prefixLogger) Debug(v ...interface{}) {
+	if enabled(l.pluginName, LevelDebug) {
+		l.inner.Debug(l.tag(v)...)
+	}
+}
+
+func (l *prefixLogger) Info(v ...interface{}) {
+	if enabled(l.pluginName, LevelInfo) {
+		l.inner.Info(l.tag(v)...)
+	}
+}
+
+func (l *prefixLogger) Warning(v ...interface{}) {
+	if enabled(l.pluginName, LevelWarning) {
+		l.inner.Warning(l.tag(v)...)
+	}
+}
+
+func (l *prefixLogger) Error(v ...interface{}) {
+	if enabled(l.pluginName, LevelError) {
+		l.inner.Error(l.tag(v)...)
+	}
+}
+
+func (l *prefixLogger) Critical(v ...interface{}) {
+	if enabled(l.pluginName, LevelCritical) {
+		l.inner.Critical(l.tag(v)...)
+	}
+}
+
+func (l *prefixLogger) Fatal(v ...interface{}) { l.inner.Fatal(l.tag(v)...) }
 
 // Wrap adapts a KrakenD-provided logger to our Logger interface,
 // prepending the plugin name tag to every message.
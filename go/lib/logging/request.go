@@ -0,0 +1,76 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Headers that carry the correlation fields of a request
+const (
+	RequestIDHeader      = "X-Request-Id"
+	ConversationIDHeader = "X-Conversation-ID"
+)
+
+type modelKey struct{}
+
+// WithModel returns a shallow copy of req that carries the model requested by the client, so
+// loggers created from it include the model
+func WithModel(req *http.Request, model string) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), modelKey{}, model))
+}
+
+// fieldLogger appends the correlation fields of a request to every message
+type fieldLogger struct {
+	inner Logger
+
+	requestID, method, path, conversationID, model string
+}
+
+// FromRequest returns a logger that appends the request ID, method, path, conversation ID and
+// model of req to every message of base, so all messages of one request can be correlated.
+// Fields the request does not carry are left out. The fields are read when the logger is created
+// and only formatted when a message is logged.
+func FromRequest(base Logger, req *http.Request) Logger {
+	if req == nil {
+		return base
+	}
+	l := &fieldLogger{
+		inner:          base,
+		requestID:      req.Header.Get(RequestIDHeader),
+		method:         req.Method,
+		conversationID: req.Header.Get(ConversationIDHeader),
+	}
+	if req.URL != nil {
+		l.path = req.URL.Path
+	}
+	l.model, _ = req.Context().Value(modelKey{}).(string)
+	return l
+}
+
+// message joins v the way KrakenD's logger does, with spaces between all operands, and appends
+// the fields
+func (l *fieldLogger) message(v []interface{}) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+	for _, field := range [...]struct{ key, value string }{
+		{"request_id", l.requestID},
+		{"method", l.method},
+		{"path", l.path},
+		{"conversation_id", l.conversationID},
+		{"model", l.model},
+	} {
+		if field.value != "" {
+			fmt.Fprintf(&b, " %s=%q", field.key, field.value)
+		}
+	}
+	return b.String()
+}
+
+func (l *fieldLogger) Debug(v ...interface{})    { l.inner.Debug(l.message(v)) }
+func (l *fieldLogger) Info(v ...interface{})     { l.inner.Info(l.message(v)) }
+func (l *fieldLogger) Warning(v ...interface{})  { l.inner.Warning(l.message(v)) }
+func (l *fieldLogger) Error(v ...interface{})    { l.inner.Error(l.message(v)) }
+func (l *fieldLogger) Critical(v ...interface{}) { l.inner.Critical(l.message(v)) }
+func (l *fieldLogger) Fatal(v ...interface{})    { l.inner.Fatal(l.message(v)) }
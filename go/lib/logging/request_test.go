@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// messageLogger records the messages it receives
+type messageLogger struct {
+	recordingLogger
+	messages []interface{}
+}
+
+func (l *messageLogger) Info(v ...interface{}) { l.messages = append(l.messages, v...) }
+
+func TestFromRequest_AppendsFields(t *testing.T) {
+	req := httptest.NewRequest("POST", "/chat/completions", nil)
+	req.Header.Set("X-Request-Id", "req-1")
+	req.Header.Set("X-Conversation-ID", "conv-1")
+	req = WithModel(req, "weather-agent")
+	inner := &messageLogger{}
+
+	FromRequest(inner, req).Info("resolved model", 2)
+
+	assert.Equal(t, []interface{}{
+		`resolved model 2 request_id="req-1" method="POST" path="/chat/completions" conversation_id="conv-1" model="weather-agent"`,
+	}, inner.messages)
+}
+
+func TestFromRequest_OmitsMissingFields(t *testing.T) {
+	req := httptest.NewRequest("GET", "/models", nil)
+	inner := &messageLogger{}
+
+	FromRequest(inner, req).Info("listing models")
+
+	assert.Equal(t, []interface{}{`listing models method="GET" path="/models"`}, inner.messages)
+}
+
+func TestFromRequest_NilRequest(t *testing.T) {
+	inner := &messageLogger{}
+
+	assert.Same(t, inner, FromRequest(inner, nil))
+}
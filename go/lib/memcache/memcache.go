@@ -0,0 +1,231 @@
+// Package memcache is a minimal client for the memcached text protocol.
+//
+// Like package resp for Redis, it covers what the plugins need for shared counters, i.e.
+// creating, incrementing, decrementing and probing keys, without adding a memcached driver
+// to the dependencies that plugins must share with KrakenD. Keys are distributed over the
+// configured servers by hash.
+package memcache
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTimeout = 2 * time.Second
+	maxKeyLength   = 250
+)
+
+var (
+	// ErrNotFound is returned by Incr and Decr for missing keys
+	ErrNotFound = errors.New("memcache: key not found")
+	// ErrNotStored is returned by Add for existing keys
+	ErrNotStored = errors.New("memcache: item not stored")
+	// ErrMalformedKey is returned for keys that are too long or contain whitespace or control characters
+	ErrMalformedKey = errors.New("memcache: malformed key")
+)
+
+// Error is an error reply of the server, e.g. CLIENT_ERROR or SERVER_ERROR
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Client sends commands over one lazily established connection per server.
+// It is safe for concurrent use; commands to the same server are serialized.
+type Client struct {
+	Servers []string
+	Timeout time.Duration
+
+	mu    sync.Mutex
+	conns map[string]*serverConn
+}
+
+// serverConn is the connection to a single server
+type serverConn struct {
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// Incr increments the numeric value of a key and returns the new value
+func (c *Client) Incr(ctx context.Context, key string, delta uint64) (uint64, error) {
+	return c.incr(ctx, "incr", key, delta)
+}
+
+// Decr decrements the numeric value of a key and returns the new value. Values do not drop below 0.
+func (c *Client) Decr(ctx context.Context, key string, delta uint64) (uint64, error) {
+	return c.incr(ctx, "decr", key, delta)
+}
+
+func (c *Client) incr(ctx context.Context, command, key string, delta uint64) (uint64, error) {
+	if !ValidKey(key) {
+		return 0, ErrMalformedKey
+	}
+	line, err := c.do(ctx, c.server(key), fmt.Sprintf("%s %s %d\r\n", command, key, delta))
+	if err != nil {
+		return 0, err
+	}
+	if line == "NOT_FOUND" {
+		return 0, ErrNotFound
+	}
+	value, err := strconv.ParseUint(line, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("memcache: unexpected reply '%s'", line)
+	}
+	return value, nil
+}
+
+// Add stores a value unless the key exists. Expiration is an absolute time, the zero time never expires.
+func (c *Client) Add(ctx context.Context, key, value string, expiration time.Time) error {
+	if !ValidKey(key) {
+		return ErrMalformedKey
+	}
+	var exptime int64
+	if !expiration.IsZero() {
+		exptime = expiration.Unix()
+	}
+	line, err := c.do(ctx, c.server(key), fmt.Sprintf("add %s 0 %d %d\r\n%s\r\n", key, exptime, len(value), value))
+	if err != nil {
+		return err
+	}
+	switch line {
+	case "STORED":
+		return nil
+	case "NOT_STORED":
+		return ErrNotStored
+	default:
+		return fmt.Errorf("memcache: unexpected reply '%s'", line)
+	}
+}
+
+// Ping requests the version of every server and returns the first error
+func (c *Client) Ping(ctx context.Context) error {
+	if len(c.Servers) == 0 {
+		return errors.New("memcache: no servers configured")
+	}
+	for _, server := range c.Servers {
+		line, err := c.do(ctx, server, "version\r\n")
+		if err != nil {
+			return fmt.Errorf("%s: %w", server, err)
+		}
+		if !strings.HasPrefix(line, "VERSION ") {
+			return fmt.Errorf("%s: memcache: unexpected reply '%s'", server, line)
+		}
+	}
+	return nil
+}
+
+// Close closes the connections to all servers
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var errs []error
+	for _, sc := range c.conns {
+		sc.mu.Lock()
+		errs = append(errs, sc.close())
+		sc.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// ValidKey reports whether a key can be sent over the text protocol
+func ValidKey(key string) bool {
+	if key == "" || len(key) > maxKeyLength {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if key[i] <= ' ' || key[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// server returns the server holding a key
+func (c *Client) server(key string) string {
+	if len(c.Servers) == 0 {
+		return ""
+	}
+	return c.Servers[crc32.ChecksumIEEE([]byte(key))%uint32(len(c.Servers))]
+}
+
+// do sends a command to a server and returns its reply line. Connection errors close the
+// connection, so the next call reconnects.
+func (c *Client) do(ctx context.Context, server, command string) (string, error) {
+	if server == "" {
+		return "", errors.New("memcache: no servers configured")
+	}
+	c.mu.Lock()
+	if c.conns == nil {
+		c.conns = make(map[string]*serverConn)
+	}
+	sc := c.conns[server]
+	if sc == nil {
+		sc = &serverConn{}
+		c.conns[server] = sc
+	}
+	c.mu.Unlock()
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.conn == nil {
+		dialer := net.Dialer{Timeout: c.timeout()}
+		conn, err := dialer.DialContext(ctx, "tcp", server)
+		if err != nil {
+			return "", err
+		}
+		sc.conn, sc.rd = conn, bufio.NewReader(conn)
+	}
+	line, err := c.roundTrip(ctx, sc, command)
+	if err != nil {
+		sc.close()
+		return "", err
+	}
+	if line == "ERROR" || strings.HasPrefix(line, "CLIENT_ERROR") || strings.HasPrefix(line, "SERVER_ERROR") {
+		return "", Error(line)
+	}
+	return line, nil
+}
+
+func (c *Client) roundTrip(ctx context.Context, sc *serverConn, command string) (string, error) {
+	deadline := time.Now().Add(c.timeout())
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := sc.conn.SetDeadline(deadline); err != nil {
+		return "", err
+	}
+	if _, err := io.WriteString(sc.conn, command); err != nil {
+		return "", err
+	}
+	line, err := sc.rd.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(line, "\r\n"), nil
+}
+
+func (c *Client) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return defaultTimeout
+}
+
+func (sc *serverConn) close() error {
+	if sc.conn == nil {
+		return nil
+	}
+	err := sc.conn.Close()
+	sc.conn, sc.rd = nil, nil
+	return err
+}
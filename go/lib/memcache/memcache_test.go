@@ -0,0 +1,89 @@
+package memcache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/memcache/memcachetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_AddAndIncr(t *testing.T) {
+	server := memcachetest.NewServer()
+	defer server.Close()
+	client := &Client{Servers: []string{server.Addr}}
+	defer client.Close()
+	ctx := context.Background()
+
+	_, err := client.Incr(ctx, "requests", 1)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, client.Add(ctx, "requests", "1", expiresAt))
+	assert.ErrorIs(t, client.Add(ctx, "requests", "5", expiresAt), ErrNotStored)
+
+	value, err := client.Incr(ctx, "requests", 41)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(42), value)
+	expiry, ok := server.Expiry("requests")
+	assert.True(t, ok)
+	assert.Equal(t, expiresAt.Unix(), expiry.Unix())
+	assert.Equal(t, []string{"incr requests 1", "add requests 0 1893456000 1", "add requests 0 1893456000 1", "incr requests 41"}, server.Commands())
+
+	value, err = client.Decr(ctx, "requests", 2)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(40), value)
+	value, err = client.Decr(ctx, "requests", 50)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), value, "values do not drop below 0")
+	_, err = client.Decr(ctx, "missing", 1)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, client.Add(ctx, "name", "gateway", time.Time{}))
+	_, err = client.Incr(ctx, "name", 1)
+	assert.IsType(t, Error(""), err)
+}
+
+func TestClient_DistributesKeys(t *testing.T) {
+	first := memcachetest.NewServer()
+	defer first.Close()
+	second := memcachetest.NewServer()
+	defer second.Close()
+	client := &Client{Servers: []string{first.Addr, second.Addr}}
+	defer client.Close()
+
+	for i := 0; i < 20; i++ {
+		require.NoError(t, client.Add(context.Background(), "key-"+strings.Repeat("x", i), "0", time.Time{}))
+	}
+	assert.NotEmpty(t, first.Keys())
+	assert.NotEmpty(t, second.Keys())
+	assert.Len(t, append(first.Keys(), second.Keys()...), 20)
+	assert.NoError(t, client.Ping(context.Background()))
+}
+
+func TestClient_Reconnects(t *testing.T) {
+	server := memcachetest.NewServer()
+	client := &Client{Servers: []string{server.Addr}}
+	defer client.Close()
+	assert.NoError(t, client.Ping(context.Background()))
+
+	server.Close()
+	assert.Error(t, client.Ping(context.Background()))
+	assert.Nil(t, client.conns[server.Addr].conn)
+
+	assert.Error(t, (&Client{}).Ping(context.Background()))
+}
+
+func TestValidKey(t *testing.T) {
+	assert.True(t, ValidKey("openai-a2a:quota:weather-team:daily:2026-01-15"))
+	assert.False(t, ValidKey(""))
+	assert.False(t, ValidKey("weather team"))
+	assert.False(t, ValidKey("line\nbreak"))
+	assert.False(t, ValidKey(strings.Repeat("k", 251)))
+
+	_, err := (&Client{Servers: []string{"127.0.0.1:1"}}).Incr(context.Background(), "weather team", 1)
+	assert.ErrorIs(t, err, ErrMalformedKey)
+}
@@ -0,0 +1,185 @@
+// Package memcachetest provides an in-memory memcached server for tests, in the spirit of httptest.
+//
+// It implements the small command set used by the plugins: add, incr, decr and version.
+// Expiry is recorded but not enforced.
+package memcachetest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is an in-memory memcached server listening on a local port
+type Server struct {
+	// Addr is the host:port the server listens on
+	Addr string
+
+	listener net.Listener
+	mu       sync.Mutex
+	values   map[string]string
+	expiry   map[string]time.Time
+	commands []string
+	closed   bool
+}
+
+// NewServer starts a server. Callers must Close it.
+func NewServer() *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("memcachetest: failed to listen: %v", err))
+	}
+	s := &Server{
+		Addr:     listener.Addr().String(),
+		listener: listener,
+		values:   make(map[string]string),
+		expiry:   make(map[string]time.Time),
+	}
+	go s.serve()
+	return s
+}
+
+// Close stops the server and closes its connections
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	_ = s.listener.Close()
+}
+
+// Commands returns the command lines received so far, without data blocks
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.commands...)
+}
+
+// Value returns the value of a key, if any
+func (s *Server) Value(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// Expiry returns the expiry set for a key, if any
+func (s *Server) Expiry(key string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.expiry[key]
+	return t, ok
+}
+
+// Keys returns all keys in sorted order
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make([]string, 0, len(s.values))
+	for key := range s.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	for {
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			return
+		}
+		args := strings.Fields(line)
+		if len(args) == 0 {
+			continue
+		}
+		// Storage commands are followed by a data block of the given size
+		var data string
+		if args[0] == "add" && len(args) == 5 {
+			size, err := strconv.Atoi(args[4])
+			if err != nil {
+				return
+			}
+			block := make([]byte, size+2)
+			if _, err := io.ReadFull(rd, block); err != nil {
+				return
+			}
+			data = string(block[:size])
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+		s.commands = append(s.commands, strings.TrimSuffix(line, "\r\n"))
+		reply := s.execute(args, data)
+		s.mu.Unlock()
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// execute runs a command while holding the lock and returns the encoded reply
+func (s *Server) execute(args []string, data string) string {
+	switch {
+	case args[0] == "version":
+		return "VERSION 1.6.0-memcachetest\r\n"
+	case args[0] == "add" && len(args) == 5:
+		if _, ok := s.values[args[1]]; ok {
+			return "NOT_STORED\r\n"
+		}
+		exptime, err := strconv.ParseInt(args[3], 10, 64)
+		if err != nil {
+			return "CLIENT_ERROR bad command line format\r\n"
+		}
+		s.values[args[1]] = data
+		if exptime > 0 {
+			s.expiry[args[1]] = time.Unix(exptime, 0)
+		}
+		return "STORED\r\n"
+	case (args[0] == "incr" || args[0] == "decr") && len(args) == 3:
+		current, ok := s.values[args[1]]
+		if !ok {
+			return "NOT_FOUND\r\n"
+		}
+		n, err := strconv.ParseUint(current, 10, 64)
+		if err != nil {
+			return "CLIENT_ERROR cannot increment or decrement non-numeric value\r\n"
+		}
+		delta, err := strconv.ParseUint(args[2], 10, 64)
+		if err != nil {
+			return "CLIENT_ERROR invalid numeric delta argument\r\n"
+		}
+		switch {
+		case args[0] == "incr":
+			n += delta
+		case delta > n:
+			n = 0
+		default:
+			n -= delta
+		}
+		s.values[args[1]] = strconv.FormatUint(n, 10)
+		return s.values[args[1]] + "\r\n"
+	default:
+		return "ERROR\r\n"
+	}
+}
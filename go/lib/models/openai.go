@@ -1,18 +1,235 @@
 package models
 
+import (
+	"encoding/json"
+	"strings"
+)
+
 // NOTE: These types are manually defined because the OpenAI OpenAPI spec cannot be generated
 // due to OpenAPI 3.1.x compatibility issues with oapi-codegen.
 // See: https://github.com/oapi-codegen/oapi-codegen/issues/373
 // The OpenAI spec is available at: https://app.stainless.com/api/spec/documented/openai/openapi.documented.yml
 //
-// These types are simplified and only include the fields needed for basic chat completions.
-// The full OpenAI API supports many additional fields (tools, functions, multi-modal content,
-// streaming, advanced parameters, etc.) which are not included here.
+// They cover the chat completions API: content part arrays, tools and tool calls, response
+// formats, log probabilities, token usage details and streamed chunks. Deprecated fields such as
+// functions and function_call are not included.
+
+// OpenAI content part types
+const (
+	OpenAIContentText       = "text"
+	OpenAIContentImageURL   = "image_url"
+	OpenAIContentInputAudio = "input_audio"
+	OpenAIContentFile       = "file"
+	OpenAIContentRefusal    = "refusal"
+)
+
+// OpenAI error types of the error envelope
+const (
+	OpenAIErrorInvalidRequest = "invalid_request_error"
+	OpenAIErrorAuthentication = "authentication_error"
+	OpenAIErrorPermission     = "permission_error"
+	OpenAIErrorNotFound       = "not_found_error"
+	OpenAIErrorRateLimit      = "rate_limit_error"
+	OpenAIErrorServer         = "server_error"
+)
 
 // OpenAI Chat Completion Request structures
+
+// OpenAIContentPart is an element of the content array of a message. Type selects which of the
+// other fields is set.
+type OpenAIContentPart struct {
+	Type       string            `json:"type"`
+	Text       string            `json:"text,omitempty"`
+	Refusal    string            `json:"refusal,omitempty"`
+	ImageURL   *OpenAIImageURL   `json:"image_url,omitempty"`
+	InputAudio *OpenAIInputAudio `json:"input_audio,omitempty"`
+	File       *OpenAIFile       `json:"file,omitempty"`
+}
+
+type OpenAIImageURL struct {
+	// URL is a web URL or a base64 data URL
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type OpenAIInputAudio struct {
+	// Data is base64 encoded
+	Data   string `json:"data"`
+	Format string `json:"format"`
+}
+
+type OpenAIFile struct {
+	// FileData is base64 encoded
+	FileData string `json:"file_data,omitempty"`
+	FileID   string `json:"file_id,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// OpenAIMessage is a message of a chat completion request or response.
+//
+// Content may be sent as string or as array of content parts. Arrays are kept in ContentParts,
+// while Content holds their text parts joined by newlines, so plugins that only handle text can
+// ignore ContentParts. Messages with ContentParts are encoded as array, others as string, or as
+// null if they only carry tool calls.
 type OpenAIMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role         string              `json:"role"`
+	Content      string              `json:"content"`
+	ContentParts []OpenAIContentPart `json:"-"`
+	Name         string              `json:"name,omitempty"`
+	Refusal      string              `json:"refusal,omitempty"`
+	// ToolCalls are the calls requested by an assistant message
+	ToolCalls []OpenAIToolCall `json:"tool_calls,omitempty"`
+	// ToolCallID is the call a tool message answers
+	ToolCallID string `json:"tool_call_id,omitempty"`
+}
+
+// openAIMessageJSON is the wire format of OpenAIMessage
+type openAIMessageJSON struct {
+	Role       string           `json:"role"`
+	Content    json.RawMessage  `json:"content"`
+	Name       string           `json:"name,omitempty"`
+	Refusal    string           `json:"refusal,omitempty"`
+	ToolCalls  []OpenAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+func (m OpenAIMessage) MarshalJSON() ([]byte, error) {
+	var content interface{} = m.Content
+	switch {
+	case m.ContentParts != nil:
+		content = m.ContentParts
+	case m.Content == "" && len(m.ToolCalls) > 0:
+		content = nil
+	}
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(openAIMessageJSON{
+		Role: m.Role, Content: raw, Name: m.Name, Refusal: m.Refusal, ToolCalls: m.ToolCalls, ToolCallID: m.ToolCallID,
+	})
+}
+
+func (m *OpenAIMessage) UnmarshalJSON(data []byte) error {
+	var wire openAIMessageJSON
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	*m = OpenAIMessage{Role: wire.Role, Name: wire.Name, Refusal: wire.Refusal, ToolCalls: wire.ToolCalls, ToolCallID: wire.ToolCallID}
+
+	content := strings.TrimSpace(string(wire.Content))
+	switch {
+	case content == "" || content == "null":
+		return nil
+	case content[0] == '[':
+		if err := json.Unmarshal(wire.Content, &m.ContentParts); err != nil {
+			return err
+		}
+		var texts []string
+		for _, part := range m.ContentParts {
+			if part.Type == OpenAIContentText {
+				texts = append(texts, part.Text)
+			}
+		}
+		m.Content = strings.Join(texts, "\n")
+		return nil
+	}
+	return json.Unmarshal(wire.Content, &m.Content)
+}
+
+// OpenAITool is a tool the model may call
+type OpenAITool struct {
+	Type     string         `json:"type"`
+	Function OpenAIFunction `json:"function"`
+}
+
+type OpenAIFunction struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Parameters is the JSON schema of the arguments
+	Parameters json.RawMessage `json:"parameters,omitempty"`
+	Strict     *bool           `json:"strict,omitempty"`
+}
+
+// OpenAIToolCall is a call of a tool requested by the model
+type OpenAIToolCall struct {
+	// Index identifies the call across the chunks of a streamed response
+	Index    *int               `json:"index,omitempty"`
+	ID       string             `json:"id,omitempty"`
+	Type     string             `json:"type,omitempty"`
+	Function OpenAIFunctionCall `json:"function"`
+}
+
+type OpenAIFunctionCall struct {
+	Name string `json:"name,omitempty"`
+	// Arguments are JSON encoded; streamed calls carry them in fragments
+	Arguments string `json:"arguments"`
+}
+
+// OpenAIToolChoice controls whether and which tool the model calls. It is sent as one of the
+// modes "none", "auto" and "required", or as object naming a function.
+type OpenAIToolChoice struct {
+	Mode     string
+	Function string
+}
+
+func (c OpenAIToolChoice) MarshalJSON() ([]byte, error) {
+	if c.Function == "" {
+		return json.Marshal(c.Mode)
+	}
+	return json.Marshal(map[string]interface{}{"type": "function", "function": map[string]string{"name": c.Function}})
+}
+
+func (c *OpenAIToolChoice) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &c.Mode); err == nil {
+		c.Function = ""
+		return nil
+	}
+	var named struct {
+		Type     string `json:"type"`
+		Function struct {
+			Name string `json:"name"`
+		} `json:"function"`
+	}
+	if err := json.Unmarshal(data, &named); err != nil {
+		return err
+	}
+	c.Mode, c.Function = "", named.Function.Name
+	return nil
+}
+
+// OpenAIResponseFormat requests text, any JSON object or JSON matching a schema
+type OpenAIResponseFormat struct {
+	Type       string            `json:"type"`
+	JSONSchema *OpenAIJSONSchema `json:"json_schema,omitempty"`
+}
+
+type OpenAIJSONSchema struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Schema      json.RawMessage `json:"schema,omitempty"`
+	Strict      *bool           `json:"strict,omitempty"`
+}
+
+// OpenAIStop holds the stop sequences, sent as a single string or as array
+type OpenAIStop []string
+
+func (s *OpenAIStop) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*s = OpenAIStop{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*s = list
+	return nil
+}
+
+type OpenAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage,omitempty"`
 }
 
 type OpenAIRequest struct {
@@ -20,24 +237,115 @@ type OpenAIRequest struct {
 	Messages    []OpenAIMessage `json:"messages"`
 	Temperature float64         `json:"temperature,omitempty"`
 	Stream      bool            `json:"stream,omitempty"`
+	N           int             `json:"n,omitempty"`
+
+	TopP                *float64              `json:"top_p,omitempty"`
+	MaxTokens           int                   `json:"max_tokens,omitempty"`
+	MaxCompletionTokens int                   `json:"max_completion_tokens,omitempty"`
+	PresencePenalty     *float64              `json:"presence_penalty,omitempty"`
+	FrequencyPenalty    *float64              `json:"frequency_penalty,omitempty"`
+	Stop                OpenAIStop            `json:"stop,omitempty"`
+	Seed                *int64                `json:"seed,omitempty"`
+	User                string                `json:"user,omitempty"`
+	Tools               []OpenAITool          `json:"tools,omitempty"`
+	ToolChoice          *OpenAIToolChoice     `json:"tool_choice,omitempty"`
+	ParallelToolCalls   *bool                 `json:"parallel_tool_calls,omitempty"`
+	ResponseFormat      *OpenAIResponseFormat `json:"response_format,omitempty"`
+	Logprobs            bool                  `json:"logprobs,omitempty"`
+	TopLogprobs         *int                  `json:"top_logprobs,omitempty"`
+	StreamOptions       *OpenAIStreamOptions  `json:"stream_options,omitempty"`
+	Metadata            map[string]string     `json:"metadata,omitempty"`
 }
 
 // OpenAI Chat Completion Response structures
 type OpenAIChoice struct {
-	Index   int `json:"index"`
-	Message struct {
-		Role    string `json:"role"`
-		Content string `json:"content"`
-	} `json:"message"`
-	FinishReason string `json:"finish_reason"`
+	Index        int             `json:"index"`
+	Message      OpenAIMessage   `json:"message"`
+	Logprobs     *OpenAILogprobs `json:"logprobs,omitempty"`
+	FinishReason string          `json:"finish_reason"`
+}
+
+// OpenAILogprobs are the log probabilities of the tokens of a choice
+type OpenAILogprobs struct {
+	Content []OpenAITokenLogprob `json:"content"`
+	Refusal []OpenAITokenLogprob `json:"refusal,omitempty"`
+}
+
+type OpenAITokenLogprob struct {
+	Token       string             `json:"token"`
+	Logprob     float64            `json:"logprob"`
+	Bytes       []int              `json:"bytes"`
+	TopLogprobs []OpenAITopLogprob `json:"top_logprobs"`
+}
+
+type OpenAITopLogprob struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
+	Bytes   []int   `json:"bytes"`
 }
 
 type OpenAIResponse struct {
-	ID      string         `json:"id"`
-	Object  string         `json:"object"`
-	Created int64          `json:"created"`
-	Model   string         `json:"model"`
-	Choices []OpenAIChoice `json:"choices"`
+	ID                string         `json:"id"`
+	Object            string         `json:"object"`
+	Created           int64          `json:"created"`
+	Model             string         `json:"model"`
+	SystemFingerprint string         `json:"system_fingerprint,omitempty"`
+	Choices           []OpenAIChoice `json:"choices"`
+	Usage             *OpenAIUsage   `json:"usage,omitempty"`
+	// Warning explains why a completion is incomplete, e.g. because the agent ran out of time
+	Warning string `json:"warning,omitempty"`
+}
+
+// OpenAIUsage reports the token consumption of a chat completion
+type OpenAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+
+	PromptTokensDetails     *OpenAIPromptTokensDetails     `json:"prompt_tokens_details,omitempty"`
+	CompletionTokensDetails *OpenAICompletionTokensDetails `json:"completion_tokens_details,omitempty"`
+}
+
+type OpenAIPromptTokensDetails struct {
+	CachedTokens int `json:"cached_tokens"`
+	AudioTokens  int `json:"audio_tokens"`
+}
+
+type OpenAICompletionTokensDetails struct {
+	ReasoningTokens          int `json:"reasoning_tokens"`
+	AudioTokens              int `json:"audio_tokens"`
+	AcceptedPredictionTokens int `json:"accepted_prediction_tokens"`
+	RejectedPredictionTokens int `json:"rejected_prediction_tokens"`
+}
+
+// OpenAI streaming structures
+
+// OpenAIChatCompletionChunk is a server-sent event of a streamed chat completion
+type OpenAIChatCompletionChunk struct {
+	ID                string              `json:"id"`
+	Object            string              `json:"object"`
+	Created           int64               `json:"created"`
+	Model             string              `json:"model"`
+	SystemFingerprint string              `json:"system_fingerprint,omitempty"`
+	Choices           []OpenAIChunkChoice `json:"choices"`
+	// Usage is only set on the last chunk, if requested with stream_options
+	Usage *OpenAIUsage `json:"usage,omitempty"`
+}
+
+type OpenAIChunkChoice struct {
+	Index    int             `json:"index"`
+	Delta    OpenAIDelta     `json:"delta"`
+	Logprobs *OpenAILogprobs `json:"logprobs,omitempty"`
+	// FinishReason is null until the last chunk of the choice
+	FinishReason *string `json:"finish_reason"`
+}
+
+// OpenAIDelta is the part of a message carried by a chunk
+type OpenAIDelta struct {
+	Role      string           `json:"role,omitempty"`
+	Content   string           `json:"content,omitempty"`
+	Refusal   string           `json:"refusal,omitempty"`
+	ToolCalls []OpenAIToolCall `json:"tool_calls,omitempty"`
 }
 
 // OpenAI Models endpoint types
@@ -46,9 +354,40 @@ type OpenAIModel struct {
 	Object  string `json:"object"`
 	Created int64  `json:"created"`
 	OwnedBy string `json:"owned_by"`
+
+	// Gateway extensions announcing the planned removal of a model (Unix timestamps)
+	DeprecatedAt int64 `json:"deprecated_at,omitempty"`
+	Sunset       int64 `json:"sunset,omitempty"`
+
+	// Gateway extensions describing the model for model pickers
+	Description      string   `json:"description,omitempty"`
+	Capabilities     []string `json:"capabilities,omitempty"`
+	MaxContextLength int      `json:"max_context_length,omitempty"`
+	Tags             []string `json:"tags,omitempty"`
+
+	// Gateway extension with the A2A capabilities declared by the agent card of the model
+	AgentCapabilities *OpenAIAgentCapabilities `json:"agent_capabilities,omitempty"`
+}
+
+// OpenAIAgentCapabilities tells clients which OpenAI features a model supports
+type OpenAIAgentCapabilities struct {
+	Streaming         bool `json:"streaming"`
+	PushNotifications bool `json:"push_notifications"`
 }
 
 type OpenAIModelsResponse struct {
 	Object string        `json:"object"`
 	Data   []OpenAIModel `json:"data"`
 }
+
+// OpenAI error response structures
+type OpenAIError struct {
+	Message string  `json:"message"`
+	Type    string  `json:"type"`
+	Param   *string `json:"param,omitempty"`
+	Code    *string `json:"code"`
+}
+
+type OpenAIErrorResponse struct {
+	Error OpenAIError `json:"error"`
+}
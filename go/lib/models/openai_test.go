@@ -0,0 +1,145 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// assertRoundTrip decodes data into v and checks that encoding v yields the same JSON
+func assertRoundTrip(t *testing.T, data string, v interface{}) {
+	t.Helper()
+	require.NoError(t, json.Unmarshal([]byte(data), v))
+	encoded, err := json.Marshal(v)
+	require.NoError(t, err)
+	assert.JSONEq(t, data, string(encoded))
+}
+
+func TestOpenAIRequest_RoundTrip(t *testing.T) {
+	data := `{
+		"model": "weather-agent",
+		"messages": [
+			{"role": "system", "content": "Be brief."},
+			{"role": "user", "content": [
+				{"type": "text", "text": "What is on this picture?"},
+				{"type": "image_url", "image_url": {"url": "https://example.com/a.png", "detail": "low"}}
+			]},
+			{"role": "assistant", "content": null, "tool_calls": [
+				{"id": "call-1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}}
+			]},
+			{"role": "tool", "content": "sunny", "tool_call_id": "call-1"}
+		],
+		"temperature": 0.5,
+		"top_p": 0.9,
+		"max_completion_tokens": 100,
+		"stop": ["END"],
+		"seed": 42,
+		"tools": [{"type": "function", "function": {"name": "get_weather", "parameters": {"type": "object"}, "strict": true}}],
+		"tool_choice": {"type": "function", "function": {"name": "get_weather"}},
+		"response_format": {"type": "json_schema", "json_schema": {"name": "weather", "schema": {"type": "object"}}},
+		"logprobs": true,
+		"top_logprobs": 2,
+		"stream": true,
+		"stream_options": {"include_usage": true}
+	}`
+
+	var req OpenAIRequest
+	assertRoundTrip(t, data, &req)
+
+	assert.Equal(t, "What is on this picture?", req.Messages[1].Content)
+	assert.Len(t, req.Messages[1].ContentParts, 2)
+	assert.Equal(t, "https://example.com/a.png", req.Messages[1].ContentParts[1].ImageURL.URL)
+	assert.Equal(t, "get_weather", req.Messages[2].ToolCalls[0].Function.Name)
+	assert.Equal(t, &OpenAIToolChoice{Function: "get_weather"}, req.ToolChoice)
+}
+
+func TestOpenAIMessage_ContentPartsText(t *testing.T) {
+	var msg OpenAIMessage
+	require.NoError(t, json.Unmarshal([]byte(`{"role":"user","content":[
+		{"type":"text","text":"first"},
+		{"type":"input_audio","input_audio":{"data":"AAAA","format":"wav"}},
+		{"type":"text","text":"second"}
+	]}`), &msg))
+
+	assert.Equal(t, "first\nsecond", msg.Content)
+	assert.Equal(t, "wav", msg.ContentParts[1].InputAudio.Format)
+}
+
+func TestOpenAIMessage_StringContent(t *testing.T) {
+	encoded, err := json.Marshal(OpenAIMessage{Role: "user", Content: ""})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"role":"user","content":""}`, string(encoded))
+
+	var msg OpenAIMessage
+	require.NoError(t, json.Unmarshal([]byte(`{"role":"user","content":"Hi"}`), &msg))
+	assert.Equal(t, OpenAIMessage{Role: "user", Content: "Hi"}, msg)
+}
+
+func TestOpenAIRequest_StopString(t *testing.T) {
+	var req OpenAIRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"model":"m","messages":[],"stop":"END","tool_choice":"auto"}`), &req))
+
+	assert.Equal(t, OpenAIStop{"END"}, req.Stop)
+	assert.Equal(t, &OpenAIToolChoice{Mode: "auto"}, req.ToolChoice)
+
+	encoded, err := json.Marshal(req.ToolChoice)
+	require.NoError(t, err)
+	assert.Equal(t, `"auto"`, string(encoded))
+}
+
+func TestOpenAIResponse_RoundTrip(t *testing.T) {
+	data := `{
+		"id": "chatcmpl-1",
+		"object": "chat.completion",
+		"created": 1700000000,
+		"model": "weather-agent",
+		"system_fingerprint": "fp-1",
+		"choices": [{
+			"index": 0,
+			"message": {"role": "assistant", "content": "Sunny"},
+			"logprobs": {"content": [{"token": "Sunny", "logprob": -0.1, "bytes": [83], "top_logprobs": [{"token": "Sunny", "logprob": -0.1, "bytes": [83]}]}]},
+			"finish_reason": "stop"
+		}],
+		"usage": {
+			"prompt_tokens": 10, "completion_tokens": 1, "total_tokens": 11,
+			"prompt_tokens_details": {"cached_tokens": 4, "audio_tokens": 0},
+			"completion_tokens_details": {"reasoning_tokens": 0, "audio_tokens": 0, "accepted_prediction_tokens": 0, "rejected_prediction_tokens": 0}
+		}
+	}`
+
+	var resp OpenAIResponse
+	assertRoundTrip(t, data, &resp)
+
+	assert.Equal(t, 4, resp.Usage.PromptTokensDetails.CachedTokens)
+}
+
+func TestOpenAIChatCompletionChunk_RoundTrip(t *testing.T) {
+	data := `{
+		"id": "chatcmpl-1",
+		"object": "chat.completion.chunk",
+		"created": 1700000000,
+		"model": "weather-agent",
+		"choices": [{
+			"index": 0,
+			"delta": {"tool_calls": [{"index": 0, "function": {"arguments": "{\"ci"}}]},
+			"finish_reason": null
+		}]
+	}`
+
+	var chunk OpenAIChatCompletionChunk
+	assertRoundTrip(t, data, &chunk)
+
+	assert.Equal(t, 0, *chunk.Choices[0].Delta.ToolCalls[0].Index)
+	assert.Nil(t, chunk.Choices[0].FinishReason)
+}
+
+func TestOpenAIErrorResponse_RoundTrip(t *testing.T) {
+	data := `{"error": {"message": "model not found", "type": "invalid_request_error", "param": "model", "code": "model_not_found"}}`
+
+	var resp OpenAIErrorResponse
+	assertRoundTrip(t, data, &resp)
+
+	assert.Equal(t, OpenAIErrorInvalidRequest, resp.Error.Type)
+}
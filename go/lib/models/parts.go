@@ -0,0 +1,167 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Part kinds, the discriminator of message and artifact parts
+const (
+	PartKindText = "text"
+	PartKindFile = "file"
+	PartKindData = "data"
+)
+
+// DecodePart decodes a message or artifact part into a TextPart, DataPart or FilePart value,
+// depending on its kind. Parts of other kinds are kept as map[string]interface{}, so agents
+// using newer protocol versions are passed through unchanged.
+func DecodePart(data []byte) (interface{}, error) {
+	var head struct {
+		Kind string `json:"kind"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return nil, fmt.Errorf("part is not an object: %w", err)
+	}
+	switch head.Kind {
+	case PartKindText:
+		var part TextPart
+		err := json.Unmarshal(data, &part)
+		return part, err
+	case PartKindFile:
+		var part FilePart
+		err := json.Unmarshal(data, &part)
+		return part, err
+	case PartKindData:
+		var part DataPart
+		err := json.Unmarshal(data, &part)
+		return part, err
+	}
+	var part map[string]interface{}
+	err := json.Unmarshal(data, &part)
+	return part, err
+}
+
+// PartText returns the text of a text part
+func PartText(part interface{}) (string, bool) {
+	textPart, ok := part.(TextPart)
+	return textPart.Text, ok
+}
+
+func decodeParts(raw []json.RawMessage) ([]interface{}, error) {
+	if raw == nil {
+		return nil, nil
+	}
+	parts := make([]interface{}, len(raw))
+	for i, data := range raw {
+		part, err := DecodePart(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid part %d: %w", i, err)
+		}
+		parts[i] = part
+	}
+	return parts, nil
+}
+
+func messageParts(raw []json.RawMessage) ([]MessagePartsElem, error) {
+	parts, err := decodeParts(raw)
+	if parts == nil {
+		return nil, err
+	}
+	elems := make([]MessagePartsElem, len(parts))
+	for i, part := range parts {
+		elems[i] = part
+	}
+	return elems, nil
+}
+
+// UnmarshalJSON decodes the parts of the message into typed parts, see DecodePart
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type plain Message
+	var wire struct {
+		plain
+		Parts []json.RawMessage `json:"parts"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	parts, err := messageParts(wire.Parts)
+	if err != nil {
+		return err
+	}
+	*m = Message(wire.plain)
+	m.Parts = parts
+	return nil
+}
+
+// UnmarshalJSON decodes the parts of the artifact into typed parts, see DecodePart
+func (a *Artifact) UnmarshalJSON(data []byte) error {
+	type plain Artifact
+	var wire struct {
+		plain
+		Parts []json.RawMessage `json:"parts"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	parts, err := decodeParts(wire.Parts)
+	if err != nil {
+		return err
+	}
+	*a = Artifact(wire.plain)
+	if parts != nil {
+		a.Parts = make([]ArtifactPartsElem, len(parts))
+		for i, part := range parts {
+			a.Parts[i] = part
+		}
+	}
+	return nil
+}
+
+// UnmarshalJSON decodes the parts of a message result into typed parts, see DecodePart
+func (r *SendMessageSuccessResponseResult) UnmarshalJSON(data []byte) error {
+	type plain SendMessageSuccessResponseResult
+	var wire struct {
+		plain
+		Parts []json.RawMessage `json:"parts"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	parts, err := messageParts(wire.Parts)
+	if err != nil {
+		return err
+	}
+	*r = SendMessageSuccessResponseResult(wire.plain)
+	r.Parts = parts
+	return nil
+}
+
+// UnmarshalJSON decodes the parts of a streamed message result into typed parts, see DecodePart
+func (r *SendStreamingMessageSuccessResponseResult) UnmarshalJSON(data []byte) error {
+	type plain SendStreamingMessageSuccessResponseResult
+	var wire struct {
+		plain
+		Parts []json.RawMessage `json:"parts"`
+	}
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	parts, err := messageParts(wire.Parts)
+	if err != nil {
+		return err
+	}
+	*r = SendStreamingMessageSuccessResponseResult(wire.plain)
+	r.Parts = parts
+	return nil
+}
+
+// MarshalJSON leaves out whichever of bytes and uri is empty, so decoded file parts are encoded
+// as they were received
+func (f FilePartFile) MarshalJSON() ([]byte, error) {
+	type plain FilePartFile
+	return json.Marshal(struct {
+		plain
+		Bytes string `json:"bytes,omitempty"`
+		Uri   string `json:"uri,omitempty"`
+	}{plain: plain(f), Bytes: f.Bytes, Uri: f.Uri})
+}
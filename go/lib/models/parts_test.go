@@ -0,0 +1,86 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const partsMessage = `{
+	"kind": "message",
+	"messageId": "m1",
+	"role": "user",
+	"parts": [
+		{"kind": "text", "text": "Weather?"},
+		{"kind": "data", "data": {"city": "Paris"}, "metadata": {"source": "form"}},
+		{"kind": "file", "file": {"uri": "https://example.com/map.png", "mimeType": "image/png"}},
+		{"kind": "file", "file": {"bytes": "aGVsbG8=", "name": "hello.txt"}},
+		{"kind": "video", "url": "https://example.com/v.mp4"}
+	]
+}`
+
+func TestMessage_UnmarshalTypedParts(t *testing.T) {
+	var msg Message
+	require.NoError(t, json.Unmarshal([]byte(partsMessage), &msg))
+
+	require.Len(t, msg.Parts, 5)
+	assert.Equal(t, TextPart{Kind: "text", Text: "Weather?"}, msg.Parts[0])
+	assert.Equal(t, DataPart{Kind: "data", Data: map[string]interface{}{"city": "Paris"}, Metadata: map[string]interface{}{"source": "form"}}, msg.Parts[1])
+	assert.Equal(t, "https://example.com/map.png", msg.Parts[2].(FilePart).File.Uri)
+	assert.Equal(t, "aGVsbG8=", msg.Parts[3].(FilePart).File.Bytes)
+	assert.Equal(t, map[string]interface{}{"kind": "video", "url": "https://example.com/v.mp4"}, msg.Parts[4])
+	assert.Equal(t, "m1", msg.MessageId)
+}
+
+func TestMessage_RoundTrip(t *testing.T) {
+	var msg Message
+	assertRoundTrip(t, partsMessage, &msg)
+}
+
+func TestArtifact_RoundTrip(t *testing.T) {
+	data := `{"artifactId": "a1", "name": "answer", "parts": [{"kind": "text", "text": "Sunny"}]}`
+
+	var artifact Artifact
+	assertRoundTrip(t, data, &artifact)
+
+	text, ok := PartText(artifact.Parts[0])
+	assert.True(t, ok)
+	assert.Equal(t, "Sunny", text)
+}
+
+func TestSendMessageSuccessResponse_TypedParts(t *testing.T) {
+	data := `{"jsonrpc": "2.0", "id": 1, "result": {
+		"kind": "task", "id": "t1", "contextId": "c1",
+		"status": {"state": "completed", "message": {"kind": "message", "messageId": "m2", "role": "agent", "parts": [{"kind": "text", "text": "Done"}]}},
+		"artifacts": [{"artifactId": "a1", "parts": [{"kind": "data", "data": {"temp": 21}}]}]
+	}}`
+
+	var resp SendMessageSuccessResponse
+	require.NoError(t, json.Unmarshal([]byte(data), &resp))
+
+	assert.Equal(t, "t1", resp.Result.Id)
+	assert.Equal(t, TextPart{Kind: "text", Text: "Done"}, resp.Result.Status.Message.Parts[0])
+	assert.IsType(t, DataPart{}, resp.Result.Artifacts[0].Parts[0])
+}
+
+func TestSendMessageSuccessResponseResult_MessageParts(t *testing.T) {
+	var result SendMessageSuccessResponseResult
+	require.NoError(t, json.Unmarshal([]byte(`{"kind": "message", "messageId": "m3", "role": "agent", "parts": [{"kind": "text", "text": "Hi"}]}`), &result))
+
+	assert.Equal(t, []MessagePartsElem{TextPart{Kind: "text", Text: "Hi"}}, result.Parts)
+}
+
+func TestDecodePart_Invalid(t *testing.T) {
+	_, err := DecodePart([]byte(`"text"`))
+	assert.Error(t, err)
+
+	var msg Message
+	assert.Error(t, json.Unmarshal([]byte(`{"parts": [{"kind": "text", "text": 1}]}`), &msg))
+}
+
+func TestPartText_OtherKinds(t *testing.T) {
+	_, ok := PartText(DataPart{Kind: "data"})
+	assert.False(t, ok)
+}
@@ -0,0 +1,11 @@
+package models
+
+import _ "embed"
+
+// A2ASchema is the JSON schema of the A2A protocol the models are generated from
+//
+//go:embed schema/a2a.json
+var A2ASchema []byte
+
+// ProtocolVersion is the version of the A2A protocol the models implement
+const ProtocolVersion = "0.3.0"
@@ -0,0 +1,282 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// FieldError describes a field violating a constraint. Field is the JSON path of the field,
+// e.g. "messages[1].role".
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e FieldError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("invalid value for '%s': %s", e.Field, e.Message)
+}
+
+// FieldErrors are all violations found by a Validate method, in field order
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// validator collects field errors under a path prefix
+type validator struct {
+	errs FieldErrors
+}
+
+func (v *validator) add(field, format string, args ...interface{}) {
+	v.errs = append(v.errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) required(field, value string) {
+	if value == "" {
+		v.add(field, "is required")
+	}
+}
+
+func (v *validator) url(field, value string) {
+	if value == "" {
+		v.add(field, "is required")
+		return
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		v.add(field, "must be an absolute URL")
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// Valid reports whether the role is defined by the A2A protocol
+func (r MessageRole) Valid() bool {
+	return r == MessageRoleUser || r == MessageRoleAgent
+}
+
+// Valid reports whether the state is defined by the A2A protocol
+func (s TaskState) Valid() bool {
+	switch s {
+	case TaskStateSubmitted, TaskStateWorking, TaskStateInputRequired, TaskStateAuthRequired,
+		TaskStateCompleted, TaskStateCanceled, TaskStateFailed, TaskStateRejected, TaskStateUnknown:
+		return true
+	}
+	return false
+}
+
+// Valid reports whether the transport is defined by the A2A protocol
+func (t TransportProtocol) Valid() bool {
+	return t == TransportProtocolJSONRPC || t == TransportProtocolGRPC || t == TransportProtocolHTTPJSON
+}
+
+// Validate checks the required fields of an agent card, its URLs and transports. It returns
+// FieldErrors.
+func (c AgentCard) Validate() error {
+	var v validator
+	v.required("name", c.Name)
+	v.required("description", c.Description)
+	v.required("version", c.Version)
+	v.required("protocolVersion", c.ProtocolVersion)
+	v.url("url", c.Url)
+	if c.PreferredTransport != "" && !TransportProtocol(c.PreferredTransport).Valid() {
+		v.add("preferredTransport", "unknown transport '%s'", c.PreferredTransport)
+	}
+	for i, iface := range c.AdditionalInterfaces {
+		field := fmt.Sprintf("additionalInterfaces[%d]", i)
+		v.url(field+".url", iface.Url)
+		if !TransportProtocol(iface.Transport).Valid() {
+			v.add(field+".transport", "unknown transport '%s'", iface.Transport)
+		}
+	}
+	if c.Provider != nil {
+		v.required("provider.organization", c.Provider.Organization)
+		v.url("provider.url", c.Provider.Url)
+	}
+	if len(c.DefaultInputModes) == 0 {
+		v.add("defaultInputModes", "at least one mode is required")
+	}
+	if len(c.DefaultOutputModes) == 0 {
+		v.add("defaultOutputModes", "at least one mode is required")
+	}
+	for i, skill := range c.Skills {
+		field := fmt.Sprintf("skills[%d]", i)
+		v.required(field+".id", skill.Id)
+		v.required(field+".name", skill.Name)
+		v.required(field+".description", skill.Description)
+	}
+	return v.err()
+}
+
+// Validate checks the JSON-RPC envelope and the message of a message/send request. It returns
+// FieldErrors.
+func (r SendMessageRequest) Validate() error {
+	var v validator
+	if r.Jsonrpc != "2.0" {
+		v.add("jsonrpc", "must be exactly \"2.0\"")
+	}
+	if r.Method != "message/send" {
+		v.add("method", "must be \"message/send\"")
+	}
+	r.Params.validate(&v, "params")
+	return v.err()
+}
+
+// Validate checks the message of message/send and message/stream params. It returns FieldErrors.
+func (p MessageSendParams) Validate() error {
+	var v validator
+	p.validate(&v, "")
+	return v.err()
+}
+
+func (p MessageSendParams) validate(v *validator, prefix string) {
+	p.Message.validate(v, join(prefix, "message"))
+	if p.Configuration != nil && p.Configuration.HistoryLength != nil && *p.Configuration.HistoryLength < 0 {
+		v.add(join(prefix, "configuration.historyLength"), "must not be negative")
+	}
+}
+
+// Validate checks the required fields, the role and the parts of a message. It returns
+// FieldErrors.
+func (m Message) Validate() error {
+	var v validator
+	m.validate(&v, "")
+	return v.err()
+}
+
+func (m Message) validate(v *validator, prefix string) {
+	v.required(join(prefix, "messageId"), m.MessageId)
+	if m.Kind != "" && m.Kind != "message" {
+		v.add(join(prefix, "kind"), "must be \"message\"")
+	}
+	if !m.Role.Valid() {
+		v.add(join(prefix, "role"), "must be \"user\" or \"agent\", got '%s'", m.Role)
+	}
+	if len(m.Parts) == 0 {
+		v.add(join(prefix, "parts"), "at least one part is required")
+	}
+	for i, part := range m.Parts {
+		field := join(prefix, fmt.Sprintf("parts[%d]", i))
+		switch part := part.(type) {
+		case TextPart:
+		case DataPart:
+			if part.Data == nil {
+				v.add(field+".data", "is required")
+			}
+		case FilePart:
+			if part.File.Uri == "" && part.File.Bytes == "" {
+				v.add(field+".file", "either uri or bytes is required")
+			} else if part.File.Uri != "" && part.File.Bytes != "" {
+				v.add(field+".file", "only one of uri and bytes is allowed")
+			}
+		default:
+			v.add(field+".kind", "must be \"text\", \"file\" or \"data\"")
+		}
+	}
+}
+
+// openAIRoles are the roles of chat completion messages
+var openAIRoles = map[string]bool{"system": true, "developer": true, "user": true, "assistant": true, "tool": true}
+
+// Validate checks the model, the messages and the ranges of the sampling parameters of a chat
+// completion request. It returns FieldErrors.
+func (r OpenAIRequest) Validate() error {
+	var v validator
+	v.required("model", r.Model)
+	if len(r.Messages) == 0 {
+		v.add("messages", "at least one message is required")
+	}
+	for i, msg := range r.Messages {
+		field := fmt.Sprintf("messages[%d]", i)
+		if !openAIRoles[msg.Role] {
+			v.add(field+".role", "unknown role '%s'", msg.Role)
+		}
+		if msg.Role == "tool" && msg.ToolCallID == "" {
+			v.add(field+".tool_call_id", "is required for tool messages")
+		}
+		if len(msg.ToolCalls) > 0 && msg.Role != "assistant" {
+			v.add(field+".tool_calls", "only assistant messages can call tools")
+		}
+		for j, part := range msg.ContentParts {
+			if part.Type == "" {
+				v.add(fmt.Sprintf("%s.content[%d].type", field, j), "is required")
+			}
+		}
+	}
+
+	between := func(field string, value *float64, min, max float64) {
+		if value != nil && (*value < min || *value > max) {
+			v.add(field, "must be between %g and %g", min, max)
+		}
+	}
+	between("temperature", &r.Temperature, 0, 2)
+	between("top_p", r.TopP, 0, 1)
+	between("presence_penalty", r.PresencePenalty, -2, 2)
+	between("frequency_penalty", r.FrequencyPenalty, -2, 2)
+	if r.N < 0 || r.N > 10 {
+		v.add("n", "must be between 1 and 10")
+	}
+	if r.MaxTokens < 0 {
+		v.add("max_tokens", "must be positive")
+	}
+	if r.MaxCompletionTokens < 0 {
+		v.add("max_completion_tokens", "must be positive")
+	}
+	if len(r.Stop) > 4 {
+		v.add("stop", "at most 4 stop sequences are allowed")
+	}
+	if r.TopLogprobs != nil {
+		if *r.TopLogprobs < 0 || *r.TopLogprobs > 20 {
+			v.add("top_logprobs", "must be between 0 and 20")
+		} else if !r.Logprobs {
+			v.add("top_logprobs", "requires logprobs")
+		}
+	}
+
+	for i, tool := range r.Tools {
+		field := fmt.Sprintf("tools[%d]", i)
+		if tool.Type != "function" {
+			v.add(field+".type", "must be \"function\"")
+		}
+		v.required(field+".function.name", tool.Function.Name)
+	}
+	if c := r.ToolChoice; c != nil && c.Function == "" && c.Mode != "none" && c.Mode != "auto" && c.Mode != "required" {
+		v.add("tool_choice", "must be \"none\", \"auto\", \"required\" or name a function")
+	}
+	if f := r.ResponseFormat; f != nil {
+		switch f.Type {
+		case "text", "json_object":
+		case "json_schema":
+			if f.JSONSchema == nil {
+				v.add("response_format.json_schema", "is required for type \"json_schema\"")
+			} else {
+				v.required("response_format.json_schema.name", f.JSONSchema.Name)
+			}
+		default:
+			v.add("response_format.type", "must be \"text\", \"json_object\" or \"json_schema\"")
+		}
+	}
+	return v.err()
+}
+
+// join appends field to the JSON path prefix
+func join(prefix, field string) string {
+	if prefix == "" {
+		return field
+	}
+	return prefix + "." + field
+}
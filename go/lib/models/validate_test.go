@@ -0,0 +1,146 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fields(t *testing.T, err error) []string {
+	t.Helper()
+	var fieldErrs FieldErrors
+	require.ErrorAs(t, err, &fieldErrs)
+	var names []string
+	for _, fieldErr := range fieldErrs {
+		names = append(names, fieldErr.Field)
+	}
+	return names
+}
+
+func TestAgentCard_Validate(t *testing.T) {
+	card := AgentCard{
+		Name:                 "weather",
+		Description:          "Weather forecasts",
+		Url:                  "https://agents.example.com/weather",
+		Version:              "1.0.0",
+		ProtocolVersion:      "0.3.0",
+		DefaultInputModes:    []string{"text/plain"},
+		DefaultOutputModes:   []string{"text/plain"},
+		PreferredTransport:   "JSONRPC",
+		AdditionalInterfaces: []AgentInterface{{Transport: "GRPC", Url: "https://agents.example.com:50051"}},
+		Skills:               []AgentSkill{{Id: "forecast", Name: "Forecast", Description: "Forecasts the weather"}},
+	}
+	assert.NoError(t, card.Validate())
+
+	card.Url = "/weather"
+	card.Version = ""
+	card.PreferredTransport = "SOAP"
+	card.AdditionalInterfaces[0].Transport = "grpc"
+	card.Skills[0].Id = ""
+	err := card.Validate()
+
+	assert.Equal(t, []string{"version", "url", "preferredTransport", "additionalInterfaces[0].transport", "skills[0].id"}, fields(t, err))
+	assert.Contains(t, err.Error(), "invalid value for 'url': must be an absolute URL")
+}
+
+func TestSendMessageRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantFields []string
+	}{
+		{
+			name: "valid",
+			body: `{"jsonrpc":"2.0","id":1,"method":"message/send","params":{"message":{"kind":"message","messageId":"m1","role":"user","parts":[{"kind":"text","text":"hi"}]}}}`,
+		},
+		{
+			name:       "envelope",
+			body:       `{"jsonrpc":"1.0","id":1,"method":"message/stream","params":{"message":{"kind":"message","messageId":"m1","role":"user","parts":[{"kind":"text","text":"hi"}]}}}`,
+			wantFields: []string{"jsonrpc", "method"},
+		},
+		{
+			name:       "message",
+			body:       `{"jsonrpc":"2.0","id":1,"method":"message/send","params":{"message":{"kind":"message","role":"system","parts":[]}}}`,
+			wantFields: []string{"params.message.messageId", "params.message.role", "params.message.parts"},
+		},
+		{
+			name:       "parts",
+			body:       `{"jsonrpc":"2.0","id":1,"method":"message/send","params":{"message":{"kind":"message","messageId":"m1","role":"agent","parts":[{"kind":"file","file":{"name":"a.txt"}},{"kind":"video"}]}}}`,
+			wantFields: []string{"params.message.parts[0].file", "params.message.parts[1].kind"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req SendMessageRequest
+			require.NoError(t, json.Unmarshal([]byte(tt.body), &req))
+
+			err := req.Validate()
+
+			if tt.wantFields == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tt.wantFields, fields(t, err))
+			}
+		})
+	}
+}
+
+func TestEnums_Valid(t *testing.T) {
+	assert.True(t, MessageRoleAgent.Valid())
+	assert.False(t, MessageRole("system").Valid())
+	assert.True(t, TaskStateInputRequired.Valid())
+	assert.False(t, TaskState("done").Valid())
+	assert.True(t, TransportProtocolHTTPJSON.Valid())
+	assert.False(t, TransportProtocol("SOAP").Valid())
+}
+
+func TestOpenAIRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantFields []string
+	}{
+		{
+			name: "valid",
+			body: `{"model":"weather","messages":[{"role":"user","content":"hi"},{"role":"assistant","content":null,"tool_calls":[{"id":"c1","type":"function","function":{"name":"f","arguments":"{}"}}]},{"role":"tool","tool_call_id":"c1","content":"sunny"}],"temperature":0.7,"tools":[{"type":"function","function":{"name":"f"}}],"tool_choice":"auto"}`,
+		},
+		{
+			name:       "required",
+			body:       `{"messages":[]}`,
+			wantFields: []string{"model", "messages"},
+		},
+		{
+			name:       "messages",
+			body:       `{"model":"m","messages":[{"role":"robot","content":"hi"},{"role":"tool","content":"x"},{"role":"user","content":"x","tool_calls":[{"id":"c1","type":"function","function":{"name":"f"}}]}]}`,
+			wantFields: []string{"messages[0].role", "messages[1].tool_call_id", "messages[2].tool_calls"},
+		},
+		{
+			name:       "ranges",
+			body:       `{"model":"m","messages":[{"role":"user","content":"hi"}],"temperature":3,"top_p":1.5,"presence_penalty":-3,"n":11,"stop":["a","b","c","d","e"],"top_logprobs":5}`,
+			wantFields: []string{"temperature", "top_p", "presence_penalty", "n", "stop", "top_logprobs"},
+		},
+		{
+			name:       "tools and format",
+			body:       `{"model":"m","messages":[{"role":"user","content":"hi"}],"tools":[{"type":"retrieval","function":{}}],"tool_choice":"sometimes","response_format":{"type":"yaml"}}`,
+			wantFields: []string{"tools[0].type", "tools[0].function.name", "tool_choice", "response_format.type"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req OpenAIRequest
+			require.NoError(t, json.Unmarshal([]byte(tt.body), &req))
+
+			err := req.Validate()
+
+			if tt.wantFields == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, tt.wantFields, fields(t, err))
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+// Package netclass classifies the hosts and URLs that clients outside the cluster cannot reach,
+// e.g. to decide which URLs in agent responses the gateway rewrites.
+package netclass
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// DefaultSuffixes are the DNS suffixes of cluster-internal services and of Docker's host names
+var DefaultSuffixes = []string{".svc.cluster.local", ".cluster.local", ".svc", ".internal", ".local"}
+
+// Classifier classifies hosts as internal: localhost, loopback, private, link-local and
+// unspecified IPs, names with a default suffix and the configured hosts. A nil Classifier only
+// applies the defaults.
+type Classifier struct {
+	names    map[string]bool
+	suffixes []string
+	networks []*net.IPNet
+}
+
+var defaults = &Classifier{names: map[string]bool{"localhost": true}, suffixes: DefaultSuffixes}
+
+// New adds host names, suffixes starting with "." or "*." and IP addresses or CIDR ranges to the
+// defaults
+func New(extra []string) (*Classifier, error) {
+	c := &Classifier{names: map[string]bool{"localhost": true}, suffixes: DefaultSuffixes}
+	for _, entry := range extra {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			return nil, fmt.Errorf("empty host")
+		case strings.Contains(entry, "/"):
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR range '%s'", entry)
+			}
+			c.networks = append(c.networks, network)
+		case net.ParseIP(entry) != nil:
+			ip := net.ParseIP(entry)
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			c.networks = append(c.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		case strings.HasPrefix(entry, "*."):
+			c.suffixes = append(c.suffixes, entry[1:])
+		case strings.HasPrefix(entry, "."):
+			c.suffixes = append(c.suffixes, entry)
+		default:
+			c.names[entry] = true
+		}
+	}
+	return c, nil
+}
+
+// IsPrivateHost reports whether a host name or IP, optionally with a port, is internal
+func (c *Classifier) IsPrivateHost(host string) bool {
+	if c == nil {
+		c = defaults
+	}
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	host = strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+	if ip := net.ParseIP(host); ip != nil {
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+			return true
+		}
+		for _, network := range c.networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	if c.names[host] {
+		return true
+	}
+	for _, suffix := range c.suffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsInternalURL reports whether a URL is absolute and its host is internal. The scheme is not
+// checked.
+func (c *Classifier) IsInternalURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+	return c.IsPrivateHost(parsed.Hostname())
+}
+
+// IsPrivateHost reports whether a host is internal by the default rules
+func IsPrivateHost(host string) bool {
+	return defaults.IsPrivateHost(host)
+}
+
+// IsInternalURL reports whether the host of an absolute URL is internal by the default rules
+func IsInternalURL(rawURL string) bool {
+	return defaults.IsInternalURL(rawURL)
+}
@@ -0,0 +1,67 @@
+package netclass
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifier_IsPrivateHost(t *testing.T) {
+	c, err := New([]string{"minio", "*.corp.example", ".lan", "100.64.0.0/10", "203.0.113.5"})
+	require.NoError(t, err)
+
+	for host, want := range map[string]bool{
+		"localhost":                         true,
+		"localhost:8080":                    true,
+		"weather.agents.svc.cluster.local":  true,
+		"weather.agents.svc.cluster.local.": true,
+		"weather.agents.svc":                true,
+		"host.docker.internal":              true,
+		"127.0.0.1":                         true,
+		"10.1.2.3":                          true,
+		"192.168.0.10":                      true,
+		"169.254.169.254":                   true,
+		"::1":                               true,
+		"[::1]:8080":                        true,
+		"fd00::1":                           true,
+		"0.0.0.0":                           true,
+		"MINIO":                             true,
+		"files.corp.example":                true,
+		"nas.lan":                           true,
+		"100.64.1.1":                        true,
+		"203.0.113.5":                       true,
+		"203.0.113.6":                       false,
+		"8.8.8.8":                           false,
+		"example.com":                       false,
+		"minio.example.com":                 false,
+		"corp.example":                      false,
+	} {
+		assert.Equal(t, want, c.IsPrivateHost(host), host)
+	}
+}
+
+func TestNew_Invalid(t *testing.T) {
+	for _, entry := range []string{"", "10.0.0.0/33", "not-an-ip/8"} {
+		_, err := New([]string{entry})
+		assert.Error(t, err, entry)
+	}
+}
+
+func TestIsInternalURL(t *testing.T) {
+	for rawURL, want := range map[string]bool{
+		"http://weather.agents.svc.cluster.local:8000/": true,
+		"ws://localhost:8080/stream":                    true,
+		"https://10.0.0.5/files/report.pdf":             true,
+		"https://gateway.example.com/weather":           false,
+		"minio/bucket":                                  false,
+		"/relative/path":                                false,
+		"not a url":                                     false,
+	} {
+		assert.Equal(t, want, IsInternalURL(rawURL), rawURL)
+	}
+
+	var defaults *Classifier
+	assert.True(t, defaults.IsInternalURL("http://host.docker.internal:3000"))
+	assert.False(t, IsPrivateHost("minio"))
+}
@@ -0,0 +1,102 @@
+package pluginkit
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+)
+
+// InfoPath is the path every plugin answers with the build information of the loaded plugins
+const InfoPath = "/.well-known/gateway-info"
+
+// commit is set at build time with
+// -ldflags "-X github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit.commit=..."
+var commit string
+
+// PluginInfo describes a registered plugin and the features its configuration enables
+type PluginInfo struct {
+	Name     string   `json:"name"`
+	Version  string   `json:"version"`
+	Features []string `json:"features"`
+}
+
+// GatewayInfo is the response of InfoPath
+type GatewayInfo struct {
+	Version      string       `json:"version"`
+	Commit       string       `json:"commit"`
+	GoVersion    string       `json:"go_version"`
+	A2AProtocols []string     `json:"a2a_protocol_versions"`
+	Plugins      []PluginInfo `json:"plugins"`
+}
+
+// registry holds the plugins registered in this process. Plugins built together share this
+// package, so each of them can report all others.
+var registry = struct {
+	sync.Mutex
+	plugins map[string]PluginInfo
+}{plugins: map[string]PluginInfo{}}
+
+// Commit returns the git commit of the build, falling back to the VCS revision of the build info
+func Commit() string {
+	if commit != "" {
+		return commit
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" && setting.Value != "" {
+				return setting.Value
+			}
+		}
+	}
+	return "unknown"
+}
+
+// SetFeatures records the features a plugin enabled when its handler was created
+func SetFeatures(name string, features []string) {
+	registry.Lock()
+	defer registry.Unlock()
+	registry.plugins[name] = PluginInfo{Name: name, Version: Version(), Features: append([]string{}, features...)}
+}
+
+// registered records a plugin without replacing the features it reported
+func registered(name string) {
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.plugins[name]; !ok {
+		registry.plugins[name] = PluginInfo{Name: name, Version: Version(), Features: []string{}}
+	}
+}
+
+// Info returns the build information and the registered plugins, sorted by name
+func Info() GatewayInfo {
+	registry.Lock()
+	plugins := make([]PluginInfo, 0, len(registry.plugins))
+	for _, plugin := range registry.plugins {
+		plugins = append(plugins, plugin)
+	}
+	registry.Unlock()
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+
+	return GatewayInfo{
+		Version:      Version(),
+		Commit:       Commit(),
+		GoVersion:    runtime.Version(),
+		A2AProtocols: []string{models.ProtocolVersion},
+		Plugins:      plugins,
+	}
+}
+
+// ServeInfo answers GET InfoPath with Info and passes all other requests to next
+func ServeInfo(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != InfoPath || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+			next.ServeHTTP(w, req)
+			return
+		}
+		_ = WriteJSON(w, http.StatusOK, Info())
+	})
+}
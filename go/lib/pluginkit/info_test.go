@@ -0,0 +1,76 @@
+package pluginkit
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getInfo(t *testing.T, handler http.Handler) GatewayInfo {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, InfoPath, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var info GatewayInfo
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+	return info
+}
+
+func findPlugin(info GatewayInfo, name string) (PluginInfo, bool) {
+	for _, plugin := range info.Plugins {
+		if plugin.Name == name {
+			return plugin, true
+		}
+	}
+	return PluginInfo{}, false
+}
+
+func TestRegisterHandlers_ServesInfo(t *testing.T) {
+	var factory HandlerFactory
+	RegisterHandlers(func(_ string, f HandlerFactory) { factory = f }, "info-plugin", func(context.Context, map[string]interface{}, http.Handler) (http.Handler, error) {
+		SetFeatures("info-plugin", []string{"stats"})
+		return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusTeapot) }), nil
+	}, logging.New("info-plugin"))
+
+	handler, err := factory(context.Background(), map[string]interface{}{}, http.NotFoundHandler())
+	require.NoError(t, err)
+
+	info := getInfo(t, handler)
+	assert.Equal(t, Version(), info.Version)
+	assert.Equal(t, Commit(), info.Commit)
+	assert.Equal(t, runtime.Version(), info.GoVersion)
+	assert.Equal(t, []string{models.ProtocolVersion}, info.A2AProtocols)
+	plugin, ok := findPlugin(info, "info-plugin")
+	require.True(t, ok)
+	assert.Equal(t, PluginInfo{Name: "info-plugin", Version: Version(), Features: []string{"stats"}}, plugin)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/models", nil),
+		httptest.NewRequest(http.MethodPost, InfoPath, nil),
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusTeapot, rec.Code, req.Method+" "+req.URL.Path)
+	}
+}
+
+func TestRegisterHandlers_RecordsPluginsWithoutFeatures(t *testing.T) {
+	var factory HandlerFactory
+	RegisterHandlers(func(_ string, f HandlerFactory) { factory = f }, "plain-plugin", func(_ context.Context, _ map[string]interface{}, next http.Handler) (http.Handler, error) {
+		return next, nil
+	}, logging.New("plain-plugin"))
+
+	handler, err := factory(context.Background(), map[string]interface{}{}, http.NotFoundHandler())
+	require.NoError(t, err)
+
+	plugin, ok := findPlugin(getInfo(t, handler), "plain-plugin")
+	require.True(t, ok)
+	assert.Equal(t, []string{}, plugin.Features)
+}
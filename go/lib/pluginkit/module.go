@@ -0,0 +1,45 @@
+package pluginkit
+
+import (
+	"runtime/debug"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+)
+
+// version is set at build time with
+// -ldflags "-X github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit.version=..."
+var version string
+
+// Version returns the build version, falling back to the module version of the build
+func Version() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+// Module is the implementation of a plugin, importable so that it can be registered both by its
+// standalone plugin and by the consolidated gateway plugin
+type Module struct {
+	// Name is the plugin name the module registers under
+	Name string
+	// ConfigKey is the extra_config key of the module configuration
+	ConfigKey string
+	// Factory creates the handler of the module
+	Factory HandlerFactory
+	// Logger points to the logger of the module, replaced by RegisterLogger
+	Logger *logging.Logger
+}
+
+// RegisterHandlers registers the module as standalone plugin
+func (m Module) RegisterHandlers(register func(string, HandlerFactory)) {
+	RegisterHandlers(register, m.Name, m.Factory, *m.Logger)
+}
+
+// RegisterLogger replaces the module logger by the logger KrakenD passes to the plugin
+func (m Module) RegisterLogger(v interface{}) {
+	RegisterLogger(v, m.Name, m.Logger)
+}
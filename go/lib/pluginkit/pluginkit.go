@@ -0,0 +1,70 @@
+// Package pluginkit holds the boilerplate shared by the KrakenD HTTP server plugins: registering
+// handlers and loggers, decoding extra_config and writing JSON responses.
+//
+// Responses that plugins transform are buffered with package capture.
+package pluginkit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+)
+
+// HandlerFactory creates the handler of a plugin from its extra_config, wrapping the next handler
+type HandlerFactory = func(context.Context, map[string]interface{}, http.Handler) (http.Handler, error)
+
+// RegisterHandlers registers factory under the plugin name with the register function KrakenD
+// passes to the RegisterHandlers method of a plugin. Handlers of the plugin answer InfoPath.
+func RegisterHandlers(register func(string, HandlerFactory), name string, factory HandlerFactory, logger logging.Logger) {
+	register(name, func(ctx context.Context, extra map[string]interface{}, next http.Handler) (http.Handler, error) {
+		handler, err := factory(ctx, extra, next)
+		if err != nil {
+			return nil, err
+		}
+		registered(name)
+		return ServeInfo(handler), nil
+	})
+	logger.Info("registered")
+}
+
+// RegisterLogger replaces *logger by the logger KrakenD passes to the RegisterLogger method of a
+// plugin, tagged with the plugin name. Values that are no logger keep the fallback logger.
+func RegisterLogger(v interface{}, name string, logger *logging.Logger) {
+	if l, ok := logging.Wrap(v, name); ok {
+		*logger = l
+	}
+	(*logger).Info("logger registered")
+}
+
+// DecodeConfig decodes the object under key of extra_config into v, as json.Unmarshal does.
+// v is left unchanged if the key is missing.
+func DecodeConfig(extra map[string]interface{}, key string, v interface{}) error {
+	raw, ok := extra[key]
+	if !ok || raw == nil {
+		return nil
+	}
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return fmt.Errorf("cannot read extra_config.%s: %w", key, err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("cannot parse extra_config.%s: %w", key, err)
+	}
+	return nil
+}
+
+// WriteJSON writes v as JSON response with the status code
+func WriteJSON(w http.ResponseWriter, statusCode int, v interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	return json.NewEncoder(w).Encode(v)
+}
+
+// WriteJSONRPCError writes a JSON-RPC error response for the request id with the status code
+func WriteJSONRPCError(w http.ResponseWriter, statusCode int, id interface{}, rpcErr models.JSONRPCErrorResponseError) error {
+	return WriteJSON(w, statusCode, models.JSONRPCErrorResponse{Jsonrpc: "2.0", Id: id, Error: rpcErr})
+}
@@ -0,0 +1,81 @@
+package pluginkit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	Paths []string `json:"paths"`
+	Limit int      `json:"limit"`
+}
+
+func TestDecodeConfig(t *testing.T) {
+	extra := map[string]interface{}{
+		"test_config": map[string]interface{}{"paths": []interface{}{"/a"}, "limit": 3},
+	}
+
+	var cfg testConfig
+	require.NoError(t, DecodeConfig(extra, "test_config", &cfg))
+
+	assert.Equal(t, testConfig{Paths: []string{"/a"}, Limit: 3}, cfg)
+}
+
+func TestDecodeConfig_MissingKeyKeepsValue(t *testing.T) {
+	cfg := testConfig{Limit: 5}
+
+	require.NoError(t, DecodeConfig(map[string]interface{}{}, "test_config", &cfg))
+
+	assert.Equal(t, testConfig{Limit: 5}, cfg)
+}
+
+func TestDecodeConfig_Invalid(t *testing.T) {
+	var cfg testConfig
+
+	err := DecodeConfig(map[string]interface{}{"test_config": "paths"}, "test_config", &cfg)
+	assert.ErrorContains(t, err, "cannot parse extra_config.test_config")
+
+	err = DecodeConfig(map[string]interface{}{"test_config": make(chan int)}, "test_config", &cfg)
+	assert.ErrorContains(t, err, "cannot read extra_config.test_config")
+}
+
+func TestRegisterHandlers(t *testing.T) {
+	var registered string
+	factory := func(context.Context, map[string]interface{}, http.Handler) (http.Handler, error) {
+		return nil, nil
+	}
+
+	RegisterHandlers(func(name string, handler HandlerFactory) {
+		registered = name
+		assert.NotNil(t, handler)
+	}, "test-plugin", factory, logging.New("test-plugin"))
+
+	assert.Equal(t, "test-plugin", registered)
+}
+
+func TestRegisterLogger_KeepsFallbackForOtherValues(t *testing.T) {
+	logger := logging.New("test-plugin")
+	fallback := logger
+
+	RegisterLogger("not a logger", "test-plugin", &logger)
+
+	assert.Same(t, fallback, logger)
+}
+
+func TestWriteJSONRPCError(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	err := WriteJSONRPCError(rec, http.StatusBadRequest, "req-1", models.JSONRPCErrorResponseError{Code: -32602, Message: "invalid params"})
+
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":"req-1","error":{"code":-32602,"message":"invalid params"}}`, rec.Body.String())
+}
@@ -0,0 +1,215 @@
+// Package resp is a minimal client for the Redis serialization protocol (RESP2).
+//
+// It covers what the plugins need for shared counters, i.e. sending pipelined
+// commands and reading integer, string and array replies, without adding a Redis
+// driver to the dependencies that plugins must share with KrakenD. It works with
+// Redis, Valkey and other servers speaking RESP.
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultTimeout = 2 * time.Second
+
+// Error is an error reply of the server
+type Error string
+
+func (e Error) Error() string { return string(e) }
+
+// Client sends commands over a single lazily established connection.
+// It is safe for concurrent use; commands are serialized.
+type Client struct {
+	Address  string
+	Password string
+	DB       int
+	Timeout  time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// Do sends the commands as one pipeline and returns one reply per command.
+// Replies are int64, string, nil, []interface{} or Error values. Connection
+// errors close the connection, so the next call reconnects.
+func (c *Client) Do(ctx context.Context, commands ...[]string) ([]interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		if err := c.connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+	replies, err := c.roundTrip(ctx, commands)
+	if err != nil {
+		c.close()
+		return nil, err
+	}
+	return replies, nil
+}
+
+// Close closes the connection
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.close()
+}
+
+func (c *Client) close() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn, c.rd = nil, nil
+	return err
+}
+
+func (c *Client) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: c.timeout()}
+	conn, err := dialer.DialContext(ctx, "tcp", c.Address)
+	if err != nil {
+		return err
+	}
+	c.conn, c.rd = conn, bufio.NewReader(conn)
+
+	var setup [][]string
+	if c.Password != "" {
+		setup = append(setup, []string{"AUTH", c.Password})
+	}
+	if c.DB != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(c.DB)})
+	}
+	if len(setup) == 0 {
+		return nil
+	}
+	replies, err := c.roundTrip(ctx, setup)
+	if err == nil {
+		for _, reply := range replies {
+			if replyErr, ok := reply.(Error); ok {
+				err = replyErr
+				break
+			}
+		}
+	}
+	if err != nil {
+		c.close()
+		return fmt.Errorf("cannot set up connection: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) roundTrip(ctx context.Context, commands [][]string) ([]interface{}, error) {
+	deadline := time.Now().Add(c.timeout())
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	var buf strings.Builder
+	for _, args := range commands {
+		fmt.Fprintf(&buf, "*%d\r\n", len(args))
+		for _, arg := range args {
+			fmt.Fprintf(&buf, "$%d\r\n%s\r\n", len(arg), arg)
+		}
+	}
+	if _, err := io.WriteString(c.conn, buf.String()); err != nil {
+		return nil, err
+	}
+
+	replies := make([]interface{}, len(commands))
+	for i := range commands {
+		reply, err := readReply(c.rd)
+		if err != nil {
+			return nil, err
+		}
+		replies[i] = reply
+	}
+	return replies, nil
+}
+
+func (c *Client) timeout() time.Duration {
+	if c.Timeout > 0 {
+		return c.Timeout
+	}
+	return defaultTimeout
+}
+
+func readReply(rd *bufio.Reader) (interface{}, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errors.New("empty reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return Error(line[1:]), nil
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid bulk length: %w", err)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, data); err != nil {
+			return nil, err
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid array length: %w", err)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readReply(rd); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unknown reply type '%c'", line[0])
+	}
+}
+
+// Int converts an integer or numeric string reply, as returned by HGET, to int64.
+// A nil reply, e.g. for a missing key, is zero.
+func Int(reply interface{}) (int64, error) {
+	switch v := reply.(type) {
+	case nil:
+		return 0, nil
+	case int64:
+		return v, nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	case Error:
+		return 0, v
+	default:
+		return 0, fmt.Errorf("unexpected reply %T", reply)
+	}
+}
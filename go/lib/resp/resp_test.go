@@ -0,0 +1,98 @@
+package resp
+
+import (
+	"bufio"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/resp/resptest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient_Pipeline(t *testing.T) {
+	server := resptest.NewServer()
+	defer server.Close()
+	client := &Client{Address: server.Addr}
+	defer client.Close()
+
+	replies, err := client.Do(context.Background(),
+		[]string{"HINCRBY", "usage", "requests", "1"},
+		[]string{"HINCRBY", "usage", "tokens", "250"},
+		[]string{"HMGET", "usage", "requests", "tokens", "missing"},
+		[]string{"BOGUS"},
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), replies[0])
+	assert.Equal(t, int64(250), replies[1])
+	assert.Equal(t, []interface{}{"1", "250", nil}, replies[2])
+	assert.IsType(t, Error(""), replies[3])
+}
+
+func TestClient_AuthAndSelect(t *testing.T) {
+	server := resptest.NewServerWithPassword("secret")
+	defer server.Close()
+
+	client := &Client{Address: server.Addr, Password: "secret", DB: 2}
+	defer client.Close()
+	replies, err := client.Do(context.Background(), []string{"PING"})
+	assert.NoError(t, err)
+	assert.Equal(t, "PONG", replies[0])
+	assert.Equal(t, []string{"SELECT", "2"}, server.Commands()[1])
+
+	wrong := &Client{Address: server.Addr, Password: "wrong"}
+	defer wrong.Close()
+	_, err = wrong.Do(context.Background(), []string{"PING"})
+	assert.ErrorContains(t, err, "WRONGPASS")
+}
+
+func TestClient_Reconnects(t *testing.T) {
+	server := resptest.NewServer()
+	client := &Client{Address: server.Addr}
+	defer client.Close()
+	_, err := client.Do(context.Background(), []string{"PING"})
+	assert.NoError(t, err)
+
+	server.Close()
+	_, err = client.Do(context.Background(), []string{"PING"})
+	assert.Error(t, err)
+	assert.Nil(t, client.conn)
+}
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  interface{}
+	}{
+		{name: "simple string", input: "+OK\r\n", want: "OK"},
+		{name: "error", input: "-ERR boom\r\n", want: Error("ERR boom")},
+		{name: "integer", input: ":42\r\n", want: int64(42)},
+		{name: "bulk string", input: "$5\r\nhe\r\no\r\n", want: "he\r\no"},
+		{name: "null bulk string", input: "$-1\r\n", want: nil},
+		{name: "nested array", input: "*2\r\n:1\r\n*1\r\n+x\r\n", want: []interface{}{int64(1), []interface{}{"x"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply, err := readReply(bufio.NewReader(strings.NewReader(tt.input)))
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, reply)
+		})
+	}
+}
+
+func TestInt(t *testing.T) {
+	n, err := Int("17")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(17), n)
+
+	n, err = Int(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	_, err = Int(Error("ERR wrong type"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,303 @@
+// Package resptest provides an in-memory RESP server for tests, in the spirit of httptest.
+//
+// It implements the small command set used by the plugins: PING, AUTH, SELECT, GET, SET,
+// DEL, INCRBY, HINCRBY, HGET, HMGET, HGETALL, EXPIRE and EXPIREAT. Expiry is recorded
+// but not enforced.
+package resptest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server is an in-memory RESP server listening on a local port
+type Server struct {
+	// Addr is the host:port the server listens on
+	Addr string
+
+	// password is required with AUTH when set
+	password string
+	listener net.Listener
+	mu       sync.Mutex
+	strings  map[string]string
+	hashes   map[string]map[string]string
+	expiry   map[string]time.Time
+	commands [][]string
+	closed   bool
+}
+
+// NewServer starts a server. Callers must Close it.
+func NewServer() *Server {
+	return NewServerWithPassword("")
+}
+
+// NewServerWithPassword starts a server that requires the password with AUTH. Callers must Close it.
+func NewServerWithPassword(password string) *Server {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("resptest: failed to listen: %v", err))
+	}
+	s := &Server{
+		Addr:     listener.Addr().String(),
+		password: password,
+		listener: listener,
+		strings:  make(map[string]string),
+		hashes:   make(map[string]map[string]string),
+		expiry:   make(map[string]time.Time),
+	}
+	go s.serve()
+	return s
+}
+
+// Close stops the server and closes its connections
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	_ = s.listener.Close()
+}
+
+// Commands returns all commands received so far
+func (s *Server) Commands() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]string{}, s.commands...)
+}
+
+// Expiry returns the expiry set for a key, if any
+func (s *Server) Expiry(key string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.expiry[key]
+	return t, ok
+}
+
+// Keys returns all keys in sorted order
+func (s *Server) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []string
+	for key := range s.strings {
+		keys = append(keys, key)
+	}
+	for key := range s.hashes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *Server) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	authenticated := s.password == ""
+	for {
+		args, err := readCommand(rd)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			return
+		}
+		s.commands = append(s.commands, args)
+		var reply string
+		switch {
+		case strings.EqualFold(args[0], "AUTH"):
+			authenticated = len(args) == 2 && args[1] == s.password
+			reply = "+OK\r\n"
+			if !authenticated {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authenticated:
+			reply = "-NOAUTH Authentication required.\r\n"
+		default:
+			reply = s.execute(args)
+		}
+		s.mu.Unlock()
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// execute runs a command while holding the lock and returns the encoded reply
+func (s *Server) execute(args []string) string {
+	cmd := strings.ToUpper(args[0])
+	switch {
+	case cmd == "PING":
+		return "+PONG\r\n"
+	case cmd == "SELECT" && len(args) == 2:
+		return "+OK\r\n"
+	case cmd == "GET" && len(args) == 2:
+		if v, ok := s.strings[args[1]]; ok {
+			return bulk(v)
+		}
+		return "$-1\r\n"
+	case cmd == "SET" && len(args) >= 3:
+		s.strings[args[1]] = args[2]
+		return "+OK\r\n"
+	case cmd == "DEL" && len(args) >= 2:
+		deleted := 0
+		for _, key := range args[1:] {
+			_, isString := s.strings[key]
+			_, isHash := s.hashes[key]
+			if isString || isHash {
+				deleted++
+			}
+			delete(s.strings, key)
+			delete(s.hashes, key)
+			delete(s.expiry, key)
+		}
+		return integer(int64(deleted))
+	case cmd == "INCRBY" && len(args) == 3:
+		n, err := increment(s.strings[args[1]], args[2])
+		if err != nil {
+			return "-ERR value is not an integer or out of range\r\n"
+		}
+		s.strings[args[1]] = strconv.FormatInt(n, 10)
+		return integer(n)
+	case cmd == "HINCRBY" && len(args) == 4:
+		hash := s.hash(args[1])
+		n, err := increment(hash[args[2]], args[3])
+		if err != nil {
+			return "-ERR hash value is not an integer\r\n"
+		}
+		hash[args[2]] = strconv.FormatInt(n, 10)
+		return integer(n)
+	case cmd == "HGET" && len(args) == 3:
+		if v, ok := s.hashes[args[1]][args[2]]; ok {
+			return bulk(v)
+		}
+		return "$-1\r\n"
+	case cmd == "HMGET" && len(args) >= 3:
+		reply := fmt.Sprintf("*%d\r\n", len(args)-2)
+		for _, field := range args[2:] {
+			if v, ok := s.hashes[args[1]][field]; ok {
+				reply += bulk(v)
+			} else {
+				reply += "$-1\r\n"
+			}
+		}
+		return reply
+	case cmd == "HGETALL" && len(args) == 2:
+		hash := s.hashes[args[1]]
+		fields := make([]string, 0, len(hash))
+		for field := range hash {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		reply := fmt.Sprintf("*%d\r\n", 2*len(fields))
+		for _, field := range fields {
+			reply += bulk(field) + bulk(hash[field])
+		}
+		return reply
+	case cmd == "EXPIRE" && len(args) == 3:
+		seconds, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return "-ERR value is not an integer or out of range\r\n"
+		}
+		return s.expire(args[1], time.Now().Add(time.Duration(seconds)*time.Second))
+	case cmd == "EXPIREAT" && len(args) == 3:
+		unix, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return "-ERR value is not an integer or out of range\r\n"
+		}
+		return s.expire(args[1], time.Unix(unix, 0))
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+func (s *Server) hash(key string) map[string]string {
+	hash, ok := s.hashes[key]
+	if !ok {
+		hash = make(map[string]string)
+		s.hashes[key] = hash
+	}
+	return hash
+}
+
+func (s *Server) expire(key string, at time.Time) string {
+	_, isString := s.strings[key]
+	_, isHash := s.hashes[key]
+	if !isString && !isHash {
+		return integer(0)
+	}
+	s.expiry[key] = at
+	return integer(1)
+}
+
+func increment(current, delta string) (int64, error) {
+	n := int64(0)
+	if current != "" {
+		var err error
+		if n, err = strconv.ParseInt(current, 10, 64); err != nil {
+			return 0, err
+		}
+	}
+	d, err := strconv.ParseInt(delta, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return n + d, nil
+}
+
+func bulk(v string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+}
+
+func integer(n int64) string {
+	return fmt.Sprintf(":%d\r\n", n)
+}
+
+// readCommand reads a command sent as an array of bulk strings
+func readCommand(rd *bufio.Reader) ([]string, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if !strings.HasPrefix(line, "*") {
+		return nil, fmt.Errorf("expected array, got %q", line)
+	}
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("invalid array length %q", line)
+	}
+	args := make([]string, n)
+	for i := range args {
+		header, err := rd.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(header, "$"), "\r\n"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid bulk length %q", header)
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(rd, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
@@ -0,0 +1,77 @@
+package streamconv
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+)
+
+// A2AEvents is a source of the events of an A2A stream, e.g. an A2AReader
+type A2AEvents interface {
+	Next() (models.SendStreamingMessageSuccessResponseResult, error)
+}
+
+// ErrorEvent is a JSON-RPC error sent as event of an A2A stream
+type ErrorEvent struct {
+	Code    int
+	Message string
+	Data    interface{}
+}
+
+func (e *ErrorEvent) Error() string {
+	return fmt.Sprintf("agent returned JSON-RPC error %d: %s", e.Code, e.Message)
+}
+
+// A2AReader reads the JSON-RPC responses of a message/stream or tasks/resubscribe stream
+type A2AReader struct {
+	events *EventReader
+}
+
+func NewA2AReader(r io.Reader) *A2AReader {
+	return &A2AReader{events: NewEventReader(r)}
+}
+
+// Next returns the result of the next response: a Task, Message, TaskStatusUpdateEvent or
+// TaskArtifactUpdateEvent, told apart by Kind. Error responses are returned as *ErrorEvent,
+// the end of the stream as io.EOF.
+func (r *A2AReader) Next() (models.SendStreamingMessageSuccessResponseResult, error) {
+	var resp struct {
+		Result *models.SendStreamingMessageSuccessResponseResult `json:"result"`
+	}
+	data, err := r.events.Next()
+	if err != nil {
+		return models.SendStreamingMessageSuccessResponseResult{}, err
+	}
+	if rpcErr, ok := a2aerrors.Parse(data); ok {
+		return models.SendStreamingMessageSuccessResponseResult{}, &ErrorEvent{Code: rpcErr.Code, Message: rpcErr.Message, Data: rpcErr.Data}
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return models.SendStreamingMessageSuccessResponseResult{}, fmt.Errorf("invalid stream event: %w", err)
+	}
+	if resp.Result == nil {
+		return models.SendStreamingMessageSuccessResponseResult{}, fmt.Errorf("invalid stream event: no result")
+	}
+	return *resp.Result, nil
+}
+
+// A2AWriter writes events as JSON-RPC responses to the request with the given ID
+type A2AWriter struct {
+	events *EventWriter
+	id     interface{}
+}
+
+func NewA2AWriter(w io.Writer, id interface{}) *A2AWriter {
+	return &A2AWriter{events: NewEventWriter(w), id: id}
+}
+
+// Write writes an event: a Task, Message, TaskStatusUpdateEvent or TaskArtifactUpdateEvent
+func (w *A2AWriter) Write(event interface{}) error {
+	return w.events.WriteJSON(struct {
+		Jsonrpc string      `json:"jsonrpc"`
+		Id      interface{} `json:"id"`
+		Result  interface{} `json:"result"`
+	}{Jsonrpc: "2.0", Id: w.id, Result: event})
+}
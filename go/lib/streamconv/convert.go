@@ -0,0 +1,208 @@
+package streamconv
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+)
+
+// OpenAI finish reasons of converted streams
+const (
+	FinishStop          = "stop"
+	FinishContentFilter = "content_filter"
+)
+
+// ToOpenAI converts the events of one A2A task into the chunks of one chat completion. The
+// text parts of messages, artifact updates and agent status messages become content deltas;
+// the first chunk carries the assistant role and the chunk of the final event the finish
+// reason.
+type ToOpenAI struct {
+	ID      string
+	Model   string
+	Created int64
+	// Progress converts status updates without text into chunks with an empty delta, so clients
+	// see that a long-running task is still being worked on
+	Progress bool
+
+	started bool
+	done    bool
+}
+
+// Convert returns the chunks for an event, possibly none
+func (c *ToOpenAI) Convert(event models.SendStreamingMessageSuccessResponseResult) []models.OpenAIChatCompletionChunk {
+	if c.done {
+		return nil
+	}
+
+	var content string
+	var state models.TaskState
+	switch event.Kind {
+	case "message":
+		content = partsText(event.Parts)
+		state = models.TaskStateCompleted
+	case "task":
+		var texts []string
+		for _, artifact := range event.Artifacts {
+			texts = append(texts, artifactText(artifact.Parts))
+		}
+		content = strings.Join(texts, "")
+		if event.Status.State != models.TaskStateSubmitted && event.Status.State != models.TaskStateWorking {
+			content += statusText(event.Status)
+			state = event.Status.State
+		}
+	case "artifact-update":
+		content = artifactText(event.Artifact.Parts)
+	case "status-update":
+		content = statusText(event.Status)
+		if event.Final {
+			state = event.Status.State
+		}
+	}
+	progress := c.Progress && event.Kind == "status-update"
+	if content == "" && state == "" && c.started && !progress {
+		return nil
+	}
+
+	chunk := models.OpenAIChatCompletionChunk{
+		ID:      c.ID,
+		Object:  "chat.completion.chunk",
+		Created: c.Created,
+		Model:   c.Model,
+		Choices: []models.OpenAIChunkChoice{{Delta: models.OpenAIDelta{Content: content}}},
+	}
+	if !c.started {
+		chunk.Choices[0].Delta.Role = "assistant"
+		c.started = true
+	}
+	if state != "" {
+		reason := FinishReason(state)
+		chunk.Choices[0].FinishReason = &reason
+		c.done = true
+	}
+	return []models.OpenAIChatCompletionChunk{chunk}
+}
+
+// Done reports whether the final event was converted
+func (c *ToOpenAI) Done() bool {
+	return c.done
+}
+
+// FinishReason maps the state a task ended in to an OpenAI finish reason
+func FinishReason(state models.TaskState) string {
+	if state == models.TaskStateRejected {
+		return FinishContentFilter
+	}
+	return FinishStop
+}
+
+// statusText returns the text of an agent message attached to a status, e.g. the question of
+// an input-required task
+func statusText(status models.TaskStatus) string {
+	if status.Message == nil || status.Message.Role != models.MessageRoleAgent {
+		return ""
+	}
+	return partsText(status.Message.Parts)
+}
+
+func partsText(parts []models.MessagePartsElem) string {
+	var text strings.Builder
+	for _, part := range parts {
+		if t, ok := models.PartText(part); ok {
+			text.WriteString(t)
+		}
+	}
+	return text.String()
+}
+
+func artifactText(parts []models.ArtifactPartsElem) string {
+	var text strings.Builder
+	for _, part := range parts {
+		if t, ok := models.PartText(part); ok {
+			text.WriteString(t)
+		}
+	}
+	return text.String()
+}
+
+// ToA2A converts the chunks of one chat completion into artifact updates of one A2A task. The
+// content deltas of the first choice are appended to a single text artifact.
+type ToA2A struct {
+	TaskID     string
+	ContextID  string
+	ArtifactID string
+
+	chunks int
+}
+
+// Convert returns the artifact update for a chunk, or false if it carries no content
+func (c *ToA2A) Convert(chunk models.OpenAIChatCompletionChunk) (models.TaskArtifactUpdateEvent, bool) {
+	if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+		return models.TaskArtifactUpdateEvent{}, false
+	}
+	appendChunk := c.chunks > 0
+	c.chunks++
+	return models.TaskArtifactUpdateEvent{
+		Kind:      "artifact-update",
+		TaskId:    c.TaskID,
+		ContextId: c.ContextID,
+		Artifact: models.Artifact{
+			ArtifactId: c.ArtifactID,
+			Parts:      []models.ArtifactPartsElem{models.TextPart{Kind: "text", Text: chunk.Choices[0].Delta.Content}},
+		},
+		Append: &appendChunk,
+	}, true
+}
+
+// Final returns the final status update of the task
+func (c *ToA2A) Final(status models.TaskStatus) models.TaskStatusUpdateEvent {
+	return models.TaskStatusUpdateEvent{
+		Kind:      "status-update",
+		TaskId:    c.TaskID,
+		ContextId: c.ContextID,
+		Status:    status,
+		Final:     true,
+	}
+}
+
+// CopyToOpenAI writes the chunks for the events of src to dst until the final event or the
+// end of src, then ends dst with [DONE]. Errors of src, including error events, are returned
+// without ending dst.
+func CopyToOpenAI(dst *OpenAIWriter, src A2AEvents, c *ToOpenAI) error {
+	for !c.Done() {
+		event, err := src.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		for _, chunk := range c.Convert(event) {
+			if err := dst.Write(chunk); err != nil {
+				return err
+			}
+		}
+	}
+	return dst.Done()
+}
+
+// CopyToA2A writes the artifact updates for the chunks of src to dst until [DONE] or the end
+// of src. The final status update is left to the caller, which knows whether the completion
+// succeeded.
+func CopyToA2A(dst *A2AWriter, src *OpenAIReader, c *ToA2A) error {
+	for {
+		chunk, err := src.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if event, ok := c.Convert(chunk); ok {
+			if err := dst.Write(event); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package streamconv
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+)
+
+// DoneData ends an OpenAI chunk stream
+const DoneData = "[DONE]"
+
+// OpenAIReader reads the chunks of a streamed chat completion
+type OpenAIReader struct {
+	events *EventReader
+	// Skipped counts the events that were not valid chunks
+	Skipped int
+}
+
+func NewOpenAIReader(r io.Reader) *OpenAIReader {
+	return &OpenAIReader{events: NewEventReader(r)}
+}
+
+// Next returns the next chunk. It returns io.EOF after [DONE] or at the end of the stream.
+// Events that are not valid chunks are skipped.
+func (r *OpenAIReader) Next() (models.OpenAIChatCompletionChunk, error) {
+	for {
+		var chunk models.OpenAIChatCompletionChunk
+		data, err := r.events.Next()
+		if err != nil {
+			return chunk, err
+		}
+		if string(data) == DoneData {
+			return chunk, io.EOF
+		}
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			r.Skipped++
+			continue
+		}
+		return chunk, nil
+	}
+}
+
+// OpenAIWriter writes chat completion chunks
+type OpenAIWriter struct {
+	events *EventWriter
+}
+
+func NewOpenAIWriter(w io.Writer) *OpenAIWriter {
+	return &OpenAIWriter{events: NewEventWriter(w)}
+}
+
+func (w *OpenAIWriter) Write(chunk models.OpenAIChatCompletionChunk) error {
+	return w.events.WriteJSON(chunk)
+}
+
+// Done ends the stream
+func (w *OpenAIWriter) Done() error {
+	return w.events.WriteData([]byte(DoneData))
+}
+
+// KeepAlive writes heartbeats while no chunk is written, see EventWriter.KeepAlive
+func (w *OpenAIWriter) KeepAlive(interval time.Duration) (stop func()) {
+	return w.events.KeepAlive(interval)
+}
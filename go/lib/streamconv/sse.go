@@ -0,0 +1,144 @@
+// Package streamconv converts between A2A event streams and OpenAI chat completion chunk
+// streams.
+//
+// Both are carried as server-sent events. EventReader and EventWriter handle the framing, the
+// A2A and OpenAI readers and writers the payloads, and ToOpenAI and ToA2A convert the payloads
+// of one task or completion. CopyToOpenAI and CopyToA2A connect them.
+package streamconv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxEventSize limits the size of a single event
+	maxEventSize = 4 << 20
+	// HeartbeatComment is the comment written by KeepAlive
+	HeartbeatComment = "keep-alive"
+)
+
+// EventReader reads the data of server-sent events. Comments, other fields and events without
+// data are skipped; the data lines of an event are joined by newlines.
+type EventReader struct {
+	scanner *bufio.Scanner
+}
+
+func NewEventReader(r io.Reader) *EventReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxEventSize)
+	return &EventReader{scanner: scanner}
+}
+
+// Next returns the data of the next event, or io.EOF at the end of the stream
+func (r *EventReader) Next() ([]byte, error) {
+	var data bytes.Buffer
+	hasData := false
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		if line == "" {
+			if hasData {
+				return data.Bytes(), nil
+			}
+			continue
+		}
+		value, ok := strings.CutPrefix(line, "data:")
+		if !ok {
+			continue
+		}
+		if hasData {
+			data.WriteByte('\n')
+		}
+		data.WriteString(strings.TrimPrefix(value, " "))
+		hasData = true
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read event stream: %w", err)
+	}
+	if hasData {
+		return data.Bytes(), nil
+	}
+	return nil, io.EOF
+}
+
+// EventWriter writes server-sent events. Every event is flushed if the writer is an
+// http.Flusher. It is safe for concurrent use.
+type EventWriter struct {
+	w io.Writer
+
+	mu        sync.Mutex
+	heartbeat *time.Timer
+	interval  time.Duration
+}
+
+func NewEventWriter(w io.Writer) *EventWriter {
+	return &EventWriter{w: w}
+}
+
+// WriteData writes an event with a single line of data
+func (w *EventWriter) WriteData(data []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.write("data: %s\n\n", data)
+}
+
+// WriteComment writes a comment, which readers skip
+func (w *EventWriter) WriteComment(text string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.write(": %s\n\n", text)
+}
+
+// KeepAlive writes a HeartbeatComment whenever no event was written for interval, so proxies
+// and clients do not close idle streams. The returned function stops the heartbeats; it must
+// be called before w becomes invalid, e.g. before an HTTP handler returns.
+func (w *EventWriter) KeepAlive(interval time.Duration) (stop func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.interval = interval
+	w.heartbeat = time.AfterFunc(interval, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.heartbeat != nil {
+			_ = w.write(": %s\n\n", HeartbeatComment)
+		}
+	})
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if w.heartbeat != nil {
+			w.heartbeat.Stop()
+			w.heartbeat = nil
+		}
+	}
+}
+
+// write formats and flushes an event and postpones the next heartbeat. w.mu must be held.
+func (w *EventWriter) write(format string, value interface{}) error {
+	if w.heartbeat != nil {
+		w.heartbeat.Reset(w.interval)
+	}
+	if _, err := fmt.Fprintf(w.w, format, value); err != nil {
+		return err
+	}
+	if flusher, ok := w.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// WriteJSON writes an event with the JSON encoding of v as data
+func (w *EventWriter) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return w.WriteData(data)
+}
@@ -0,0 +1,236 @@
+package streamconv
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventReader(t *testing.T) {
+	r := NewEventReader(strings.NewReader(": comment\n\nevent: update\ndata: {\"a\":\ndata:1}\n\nid: 7\n\ndata: last"))
+
+	data, err := r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "{\"a\":\n1}", string(data))
+	data, err = r.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "last", string(data))
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+const a2aStream = `data: {"jsonrpc":"2.0","id":1,"result":{"kind":"task","id":"t1","contextId":"c1","status":{"state":"working"}}}
+
+data: {"jsonrpc":"2.0","id":1,"result":{"kind":"status-update","taskId":"t1","contextId":"c1","final":false,"status":{"state":"working","message":{"kind":"message","messageId":"s1","role":"agent","parts":[{"kind":"text","text":"Looking up "}]}}}}
+
+data: {"jsonrpc":"2.0","id":1,"result":{"kind":"artifact-update","taskId":"t1","contextId":"c1","artifact":{"artifactId":"a1","parts":[{"kind":"text","text":"Sunny"},{"kind":"data","data":{"temp":21}}]}}}
+
+data: {"jsonrpc":"2.0","id":1,"result":{"kind":"artifact-update","taskId":"t1","contextId":"c1","append":true,"artifact":{"artifactId":"a1","parts":[{"kind":"text","text":", 21°C"}]}}}
+
+data: {"jsonrpc":"2.0","id":1,"result":{"kind":"status-update","taskId":"t1","contextId":"c1","final":true,"status":{"state":"completed"}}}
+
+`
+
+// decodeChunks parses an OpenAI chunk stream and checks that it ends with [DONE]
+func decodeChunks(t *testing.T, stream string) []models.OpenAIChatCompletionChunk {
+	t.Helper()
+	require.True(t, strings.HasSuffix(stream, "data: [DONE]\n\n"), stream)
+	r := NewOpenAIReader(strings.NewReader(stream))
+	var chunks []models.OpenAIChatCompletionChunk
+	for {
+		chunk, err := r.Next()
+		if err == io.EOF {
+			return chunks
+		}
+		require.NoError(t, err)
+		chunks = append(chunks, chunk)
+	}
+}
+
+func TestCopyToOpenAI(t *testing.T) {
+	var out bytes.Buffer
+	conv := &ToOpenAI{ID: "chatcmpl-1", Model: "weather", Created: 1700000000}
+
+	err := CopyToOpenAI(NewOpenAIWriter(&out), NewA2AReader(strings.NewReader(a2aStream)), conv)
+
+	require.NoError(t, err)
+	chunks := decodeChunks(t, out.String())
+	var contents []string
+	for _, chunk := range chunks {
+		assert.Equal(t, "chatcmpl-1", chunk.ID)
+		assert.Equal(t, "chat.completion.chunk", chunk.Object)
+		assert.Equal(t, "weather", chunk.Model)
+		contents = append(contents, chunk.Choices[0].Delta.Content)
+	}
+	assert.Equal(t, []string{"", "Looking up ", "Sunny", ", 21°C", ""}, contents)
+	assert.Equal(t, "assistant", chunks[0].Choices[0].Delta.Role)
+	assert.Empty(t, chunks[1].Choices[0].Delta.Role)
+	assert.Nil(t, chunks[3].Choices[0].FinishReason)
+	assert.Equal(t, FinishStop, *chunks[4].Choices[0].FinishReason)
+	assert.True(t, conv.Done())
+}
+
+func TestCopyToOpenAI_Message(t *testing.T) {
+	var out bytes.Buffer
+	stream := `data: {"jsonrpc":"2.0","id":1,"result":{"kind":"message","messageId":"m2","role":"agent","parts":[{"kind":"text","text":"Hi!"}]}}` + "\n\n"
+
+	err := CopyToOpenAI(NewOpenAIWriter(&out), NewA2AReader(strings.NewReader(stream)), &ToOpenAI{ID: "c"})
+
+	require.NoError(t, err)
+	chunks := decodeChunks(t, out.String())
+	require.Len(t, chunks, 1)
+	assert.Equal(t, models.OpenAIDelta{Role: "assistant", Content: "Hi!"}, chunks[0].Choices[0].Delta)
+	assert.Equal(t, FinishStop, *chunks[0].Choices[0].FinishReason)
+}
+
+func TestCopyToOpenAI_ErrorEvent(t *testing.T) {
+	var out bytes.Buffer
+	stream := `data: {"jsonrpc":"2.0","id":1,"error":{"code":-32603,"message":"agent crashed"}}` + "\n\n"
+
+	err := CopyToOpenAI(NewOpenAIWriter(&out), NewA2AReader(strings.NewReader(stream)), &ToOpenAI{ID: "c"})
+
+	var errorEvent *ErrorEvent
+	require.ErrorAs(t, err, &errorEvent)
+	assert.Equal(t, -32603, errorEvent.Code)
+	assert.Empty(t, out.String())
+}
+
+func TestToOpenAI_Progress(t *testing.T) {
+	working := models.SendStreamingMessageSuccessResponseResult{Kind: "status-update", Status: models.TaskStatus{State: models.TaskStateWorking}}
+
+	tests := []struct {
+		name       string
+		progress   bool
+		wantChunks int
+	}{
+		{name: "skipped by default", wantChunks: 0},
+		{name: "empty progress chunk", progress: true, wantChunks: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conv := &ToOpenAI{ID: "c", Progress: tt.progress}
+			require.Len(t, conv.Convert(working), 1, "the first chunk carries the role")
+
+			chunks := conv.Convert(working)
+
+			require.Len(t, chunks, tt.wantChunks)
+			if tt.wantChunks > 0 {
+				assert.Equal(t, models.OpenAIDelta{}, chunks[0].Choices[0].Delta)
+				assert.Nil(t, chunks[0].Choices[0].FinishReason)
+			}
+		})
+	}
+}
+
+func TestToOpenAI_RejectedTask(t *testing.T) {
+	conv := &ToOpenAI{ID: "c"}
+	chunks := conv.Convert(models.SendStreamingMessageSuccessResponseResult{
+		Kind:   "task",
+		Status: models.TaskStatus{State: models.TaskStateRejected},
+	})
+
+	require.Len(t, chunks, 1)
+	assert.Equal(t, FinishContentFilter, *chunks[0].Choices[0].FinishReason)
+	assert.Nil(t, conv.Convert(models.SendStreamingMessageSuccessResponseResult{Kind: "status-update", Final: true}))
+}
+
+const openAIStream = `data: {"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"role":"assistant"},"finish_reason":null}]}
+
+data: {"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":"Paris"},"finish_reason":null}]}
+
+data: not json
+
+data: {"id":"c1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"content":" is the capital."},"finish_reason":"stop"}]}
+
+data: [DONE]
+
+`
+
+func TestCopyToA2A(t *testing.T) {
+	var out bytes.Buffer
+	conv := &ToA2A{TaskID: "t1", ContextID: "c1", ArtifactID: "a1"}
+	chunks := NewOpenAIReader(strings.NewReader(openAIStream))
+	events := NewA2AWriter(&out, "req-1")
+
+	require.NoError(t, CopyToA2A(events, chunks, conv))
+	require.NoError(t, events.Write(conv.Final(models.TaskStatus{State: models.TaskStateCompleted})))
+
+	assert.Equal(t, 1, chunks.Skipped)
+	r := NewA2AReader(&out)
+	var texts []string
+	var appends []bool
+	for {
+		event, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		if event.Kind == "artifact-update" {
+			assert.Equal(t, "a1", event.Artifact.ArtifactId)
+			text, _ := models.PartText(event.Artifact.Parts[0])
+			texts = append(texts, text)
+			appends = append(appends, *event.Append)
+			continue
+		}
+		assert.Equal(t, "status-update", event.Kind)
+		assert.True(t, event.Final)
+		assert.Equal(t, models.TaskStateCompleted, event.Status.State)
+	}
+	assert.Equal(t, []string{"Paris", " is the capital."}, texts)
+	assert.Equal(t, []bool{false, true}, appends)
+}
+
+// syncBuffer is a bytes.Buffer safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestEventWriter_KeepAlive(t *testing.T) {
+	var out syncBuffer
+	w := NewEventWriter(&out)
+	stop := w.KeepAlive(20 * time.Millisecond)
+
+	require.Eventually(t, func() bool { return strings.Count(out.String(), ": keep-alive\n\n") >= 2 }, time.Second, 5*time.Millisecond)
+	require.NoError(t, w.WriteData([]byte("event")))
+	stop()
+	stopped := out.String()
+	time.Sleep(50 * time.Millisecond)
+
+	assert.Contains(t, stopped, "data: event\n\n")
+	assert.Equal(t, stopped, out.String(), "no heartbeats after stop")
+	events, err := NewEventReader(strings.NewReader(stopped)).Next()
+	require.NoError(t, err)
+	assert.Equal(t, "event", string(events), "readers skip heartbeats")
+}
+
+func TestA2AWriter_Envelope(t *testing.T) {
+	var out bytes.Buffer
+	require.NoError(t, NewA2AWriter(&out, 7).Write(map[string]string{"kind": "task"}))
+
+	data, ok := strings.CutPrefix(strings.TrimSuffix(out.String(), "\n\n"), "data: ")
+	require.True(t, ok)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(data), &resp))
+	assert.Equal(t, map[string]interface{}{"jsonrpc": "2.0", "id": float64(7), "result": map[string]interface{}{"kind": "task"}}, resp)
+}
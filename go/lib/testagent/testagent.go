@@ -0,0 +1,341 @@
+// Package testagent provides a fake A2A agent for tests. It serves an agent card, message/send,
+// message/stream and tasks/get over JSON-RPC, answering messages with scripted responses and
+// latencies, and records the requests it receives:
+//
+//	agent := testagent.New(testagent.Options{Handler: testagent.Reply("Sunny, 22°C")})
+//	defer agent.Close()
+//
+// Completed tasks are kept, so clients can query them with tasks/get.
+package testagent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aclient"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/streamconv"
+)
+
+// Response scripts how the agent answers a message
+type Response struct {
+	// Text is the reply, sent as text artifact. Streams send it in Chunks if set, word by word otherwise.
+	Text   string
+	Chunks []string
+	// State is the final state of the task, completed by default
+	State models.TaskState
+	// Error answers with a JSON-RPC error instead of a task
+	Error *models.JSONRPCErrorResponseError
+	// StatusCode answers with an empty response of this HTTP status instead of a task
+	StatusCode int
+	// Latency delays the response; ChunkDelay delays every streamed chunk
+	Latency    time.Duration
+	ChunkDelay time.Duration
+}
+
+// Handler returns the response to a message
+type Handler func(message models.Message) Response
+
+// Echo answers every message with "echo: " and its text
+func Echo(message models.Message) Response {
+	return Response{Text: "echo: " + MessageText(message)}
+}
+
+// Reply answers every message with text
+func Reply(text string) Handler {
+	return func(models.Message) Response {
+		return Response{Text: text}
+	}
+}
+
+// Script answers messages with the responses in order, repeating the last one
+func Script(responses ...Response) Handler {
+	var mu sync.Mutex
+	next := 0
+	return func(models.Message) Response {
+		mu.Lock()
+		defer mu.Unlock()
+		response := responses[next]
+		if next < len(responses)-1 {
+			next++
+		}
+		return response
+	}
+}
+
+// Options configures an agent. The zero value is an echo agent named "test-agent".
+type Options struct {
+	Name string
+	// Card replaces the generated agent card; an empty url is set to the agent URL
+	Card *models.AgentCard
+	// Handler answers messages, Echo by default
+	Handler Handler
+	// Latency delays every response, including agent cards and task queries
+	Latency time.Duration
+}
+
+// Request is a JSON-RPC request received by the agent
+type Request struct {
+	Method string
+	Params json.RawMessage
+	Header http.Header
+}
+
+// Agent is a running fake agent
+type Agent struct {
+	// URL is the base URL of the agent, its JSON-RPC endpoint
+	URL string
+
+	server  *httptest.Server
+	name    string
+	card    *models.AgentCard
+	handler Handler
+	latency time.Duration
+
+	mu       sync.Mutex
+	requests []Request
+	tasks    map[string]models.SendMessageSuccessResponseResult
+	nextID   int
+}
+
+// New starts an agent on a local port; Close stops it
+func New(opts Options) *Agent {
+	a := &Agent{
+		name:    opts.Name,
+		card:    opts.Card,
+		handler: opts.Handler,
+		latency: opts.Latency,
+		tasks:   make(map[string]models.SendMessageSuccessResponseResult),
+	}
+	if a.name == "" {
+		a.name = "test-agent"
+	}
+	if a.handler == nil {
+		a.handler = Echo
+	}
+	a.server = httptest.NewServer(http.HandlerFunc(a.serveHTTP))
+	a.URL = a.server.URL
+	return a
+}
+
+// Close stops the agent
+func (a *Agent) Close() {
+	a.server.Close()
+}
+
+// Requests returns the JSON-RPC requests received so far
+func (a *Agent) Requests() []Request {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]Request(nil), a.requests...)
+}
+
+// Card returns the agent card the agent serves
+func (a *Agent) Card() models.AgentCard {
+	if a.card != nil {
+		card := *a.card
+		if card.Url == "" {
+			card.Url = a.URL
+		}
+		return card
+	}
+	streaming := true
+	return models.AgentCard{
+		Name:               a.name,
+		Description:        fmt.Sprintf("Test agent %s", a.name),
+		Url:                a.URL,
+		Version:            "1.0.0",
+		ProtocolVersion:    models.ProtocolVersion,
+		PreferredTransport: "JSONRPC",
+		Capabilities:       models.AgentCapabilities{Streaming: &streaming},
+		DefaultInputModes:  []string{"text/plain"},
+		DefaultOutputModes: []string{"text/plain"},
+		Skills:             []models.AgentSkill{{Id: "test", Name: "Test", Description: "Answers test messages", Tags: []string{"test"}}},
+	}
+}
+
+func (a *Agent) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	if !wait(req, a.latency) {
+		return
+	}
+	if req.Method == http.MethodGet && req.URL.Path == a2aclient.AgentCardPath {
+		_ = pluginkit.WriteJSON(w, http.StatusOK, a.Card())
+		return
+	}
+	if req.Method != http.MethodPost {
+		http.NotFound(w, req)
+		return
+	}
+
+	var rpcReq struct {
+		Id     interface{}     `json:"id"`
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	body, err := io.ReadAll(req.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &rpcReq)
+	}
+	if err != nil {
+		writeError(w, nil, a2aerrors.CodeJSONParse, "request body is not valid JSON")
+		return
+	}
+	a.mu.Lock()
+	a.requests = append(a.requests, Request{Method: rpcReq.Method, Params: rpcReq.Params, Header: req.Header.Clone()})
+	a.mu.Unlock()
+
+	switch rpcReq.Method {
+	case "message/send", "message/stream":
+		var params models.MessageSendParams
+		if err := json.Unmarshal(rpcReq.Params, &params); err != nil {
+			writeError(w, rpcReq.Id, a2aerrors.CodeInvalidParams, "invalid message params")
+			return
+		}
+		a.serveMessage(w, req, rpcReq.Id, rpcReq.Method == "message/stream", params.Message)
+	case "tasks/get":
+		var params models.TaskQueryParams
+		if err := json.Unmarshal(rpcReq.Params, &params); err != nil {
+			writeError(w, rpcReq.Id, a2aerrors.CodeInvalidParams, "invalid task params")
+			return
+		}
+		a.mu.Lock()
+		task, ok := a.tasks[params.Id]
+		a.mu.Unlock()
+		if !ok {
+			writeError(w, rpcReq.Id, a2aerrors.CodeTaskNotFound, "task not found")
+			return
+		}
+		writeResult(w, rpcReq.Id, task)
+	default:
+		writeError(w, rpcReq.Id, a2aerrors.CodeMethodNotFound, fmt.Sprintf("method '%s' is not supported", rpcReq.Method))
+	}
+}
+
+// serveMessage answers a message with the scripted response, as task or as event stream
+func (a *Agent) serveMessage(w http.ResponseWriter, req *http.Request, id interface{}, stream bool, message models.Message) {
+	response := a.handler(message)
+	if !wait(req, response.Latency) {
+		return
+	}
+	if response.StatusCode != 0 {
+		w.WriteHeader(response.StatusCode)
+		return
+	}
+	if response.Error != nil {
+		writeError(w, id, response.Error.Code, response.Error.Message)
+		return
+	}
+	state := response.State
+	if state == "" {
+		state = models.TaskStateCompleted
+	}
+
+	task := a.newTask(message)
+	artifactID := task.Id + "-artifact"
+	if !stream {
+		task.Status = models.TaskStatus{State: state}
+		if response.Text != "" {
+			task.Artifacts = []models.Artifact{{ArtifactId: artifactID, Parts: []models.ArtifactPartsElem{models.TextPart{Kind: "text", Text: response.Text}}}}
+		}
+		a.storeTask(task)
+		writeResult(w, id, task)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	events := streamconv.NewA2AWriter(w, id)
+	working := task
+	working.Status = models.TaskStatus{State: models.TaskStateWorking}
+	if events.Write(working) != nil {
+		return
+	}
+	convert := streamconv.ToA2A{TaskID: task.Id, ContextID: task.ContextId, ArtifactID: artifactID}
+	chunks := response.Chunks
+	if chunks == nil && response.Text != "" {
+		chunks = strings.SplitAfter(response.Text, " ")
+	}
+	for _, chunk := range chunks {
+		if !wait(req, response.ChunkDelay) {
+			return
+		}
+		event, _ := convert.Convert(models.OpenAIChatCompletionChunk{Choices: []models.OpenAIChunkChoice{{Delta: models.OpenAIDelta{Content: chunk}}}})
+		if events.Write(event) != nil {
+			return
+		}
+	}
+
+	task.Status = models.TaskStatus{State: state}
+	if len(chunks) > 0 {
+		task.Artifacts = []models.Artifact{{ArtifactId: artifactID, Parts: []models.ArtifactPartsElem{models.TextPart{Kind: "text", Text: strings.Join(chunks, "")}}}}
+	}
+	a.storeTask(task)
+	_ = events.Write(convert.Final(task.Status))
+}
+
+// newTask creates a task for the message in its context, starting a new context if it has none
+func (a *Agent) newTask(message models.Message) models.SendMessageSuccessResponseResult {
+	a.mu.Lock()
+	a.nextID++
+	n := a.nextID
+	a.mu.Unlock()
+
+	taskID := fmt.Sprintf("task-%d", n)
+	contextID := fmt.Sprintf("context-%d", n)
+	if message.ContextId != nil && *message.ContextId != "" {
+		contextID = *message.ContextId
+	}
+	message.TaskId = &taskID
+	message.ContextId = &contextID
+	return models.SendMessageSuccessResponseResult{
+		Id:        taskID,
+		Kind:      "task",
+		ContextId: contextID,
+		History:   []models.Message{message},
+	}
+}
+
+func (a *Agent) storeTask(task models.SendMessageSuccessResponseResult) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.tasks[task.Id] = task
+}
+
+// MessageText joins the text parts of a message
+func MessageText(message models.Message) string {
+	var texts []string
+	for _, part := range message.Parts {
+		if text, ok := models.PartText(part); ok {
+			texts = append(texts, text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// wait sleeps for d and reports whether the request is still alive
+func wait(req *http.Request, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	select {
+	case <-time.After(d):
+		return true
+	case <-req.Context().Done():
+		return false
+	}
+}
+
+func writeResult(w http.ResponseWriter, id interface{}, result interface{}) {
+	_ = pluginkit.WriteJSON(w, http.StatusOK, map[string]interface{}{"jsonrpc": "2.0", "id": id, "result": result})
+}
+
+func writeError(w http.ResponseWriter, id interface{}, code int, message string) {
+	_ = pluginkit.WriteJSONRPCError(w, http.StatusOK, id, models.JSONRPCErrorResponseError{Code: code, Message: message})
+}
@@ -0,0 +1,169 @@
+package testagent
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aclient"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newClient(t *testing.T, opts Options) (*Agent, *a2aclient.Client) {
+	agent := New(opts)
+	t.Cleanup(agent.Close)
+	client, err := a2aclient.New(agent.URL, a2aclient.Options{Header: http.Header{"X-Test": {"1"}}})
+	require.NoError(t, err)
+	return agent, client
+}
+
+func userMessage(text string) models.MessageSendParams {
+	return models.MessageSendParams{Message: models.Message{
+		Kind:      "message",
+		MessageId: "m1",
+		Role:      models.MessageRoleUser,
+		Parts:     []models.MessagePartsElem{models.TextPart{Kind: "text", Text: text}},
+	}}
+}
+
+func artifactText(t *testing.T, artifacts []models.Artifact) string {
+	require.Len(t, artifacts, 1)
+	text, ok := models.PartText(artifacts[0].Parts[0])
+	require.True(t, ok)
+	return text
+}
+
+func TestAgentCard(t *testing.T) {
+	agent, client := newClient(t, Options{Name: "weather"})
+
+	card, err := client.GetAgentCard(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, "weather", card.Name)
+	assert.Equal(t, agent.URL, card.Url)
+	assert.NoError(t, card.Validate())
+}
+
+func TestAgentCard_Custom(t *testing.T) {
+	agent, client := newClient(t, Options{Card: &models.AgentCard{Name: "custom"}})
+
+	card, err := client.GetAgentCard(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, models.AgentCard{Name: "custom", Url: agent.URL}, card)
+}
+
+func TestSendMessage(t *testing.T) {
+	agent, client := newClient(t, Options{})
+
+	task, err := client.SendMessage(context.Background(), userMessage("hello"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "task-1", task.Id)
+	assert.Equal(t, models.TaskStateCompleted, task.Status.State)
+	assert.Equal(t, "echo: hello", artifactText(t, task.Artifacts))
+
+	requests := agent.Requests()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "message/send", requests[0].Method)
+	assert.Equal(t, "1", requests[0].Header.Get("X-Test"))
+}
+
+func TestGetTask(t *testing.T) {
+	_, client := newClient(t, Options{Handler: Reply("done")})
+	sent, err := client.SendMessage(context.Background(), userMessage("hello"))
+	require.NoError(t, err)
+
+	task, err := client.GetTask(context.Background(), models.TaskQueryParams{Id: sent.Id})
+	require.NoError(t, err)
+	assert.Equal(t, sent.Id, task.Id)
+	assert.Equal(t, "done", artifactText(t, task.Artifacts))
+
+	_, err = client.GetTask(context.Background(), models.TaskQueryParams{Id: "unknown"})
+	var rpcErr *a2aclient.RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, a2aerrors.CodeTaskNotFound, rpcErr.Code)
+}
+
+func TestStreamMessage(t *testing.T) {
+	_, client := newClient(t, Options{Handler: func(models.Message) Response {
+		return Response{Chunks: []string{"Sunny", ", ", "22°C"}, ChunkDelay: time.Millisecond}
+	}})
+
+	stream, err := client.StreamMessage(context.Background(), userMessage("weather?"))
+	require.NoError(t, err)
+	defer stream.Close()
+
+	var kinds, chunks []string
+	var last models.SendStreamingMessageSuccessResponseResult
+	for {
+		event, err := stream.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(t, err)
+		kinds = append(kinds, event.Kind)
+		if event.Kind == "artifact-update" {
+			chunks = append(chunks, artifactText(t, []models.Artifact{event.Artifact}))
+		}
+		last = event
+	}
+
+	assert.Equal(t, []string{"task", "artifact-update", "artifact-update", "artifact-update", "status-update"}, kinds)
+	assert.Equal(t, []string{"Sunny", ", ", "22°C"}, chunks)
+	assert.True(t, last.Final)
+	assert.Equal(t, models.TaskStateCompleted, last.Status.State)
+}
+
+func TestScript(t *testing.T) {
+	_, client := newClient(t, Options{Handler: Script(
+		Response{Error: &models.JSONRPCErrorResponseError{Code: a2aerrors.CodeInternal, Message: "overloaded"}},
+		Response{Text: "input needed", State: models.TaskStateInputRequired},
+		Response{Text: "done"},
+	)})
+
+	_, err := client.SendMessage(context.Background(), userMessage("1"))
+	var rpcErr *a2aclient.RPCError
+	require.ErrorAs(t, err, &rpcErr)
+	assert.Equal(t, "overloaded", rpcErr.Message)
+
+	task, err := client.SendMessage(context.Background(), userMessage("2"))
+	require.NoError(t, err)
+	assert.Equal(t, models.TaskStateInputRequired, task.Status.State)
+
+	for range 2 {
+		task, err = client.SendMessage(context.Background(), userMessage("3"))
+		require.NoError(t, err)
+		assert.Equal(t, "done", artifactText(t, task.Artifacts))
+	}
+}
+
+func TestStatusCodeAndLatency(t *testing.T) {
+	_, client := newClient(t, Options{Handler: func(models.Message) Response {
+		return Response{StatusCode: http.StatusServiceUnavailable, Latency: 20 * time.Millisecond}
+	}})
+
+	start := time.Now()
+	_, err := client.SendMessage(context.Background(), userMessage("hello"))
+
+	var statusErr *a2aclient.StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+}
+
+func TestLatency_Cancelled(t *testing.T) {
+	_, client := newClient(t, Options{Latency: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GetAgentCard(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
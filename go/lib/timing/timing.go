@@ -0,0 +1,170 @@
+// Package timing reports where the time of a request is spent and enforces client latency budgets.
+//
+// Start attaches a timer to a request and wraps its response writer, which adds a Server-Timing
+// header with the time spent in the gateway and waiting for upstream services when the response
+// header is written. Upstream calls are measured with Upstream; concurrent calls count once.
+//
+// Clients may limit the latency of a request with the X-Latency-Budget header, either in
+// milliseconds ("1500") or as a Go duration ("1.5s"). WithBudget turns the budget into a deadline
+// of the request context, so upstream calls get the remainder of the budget after gateway processing.
+package timing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// Header reports the timing of a response
+	Header = "Server-Timing"
+	// BudgetHeader limits the latency of a request
+	BudgetHeader = "X-Latency-Budget"
+)
+
+// ErrBudgetExceeded is the cause of contexts cancelled because the latency budget of the request ran out
+var ErrBudgetExceeded = errors.New("latency budget exceeded")
+
+type timerKey struct{}
+
+// now is replaced in tests
+var now = time.Now
+
+// timer accumulates the time a request waits for upstream services
+type timer struct {
+	start time.Time
+
+	mu       sync.Mutex
+	active   int
+	since    time.Time
+	upstream time.Duration
+}
+
+// Writer adds the Server-Timing header of its request to the response
+type Writer struct {
+	http.ResponseWriter
+	timer       *timer
+	wroteHeader bool
+}
+
+// Start attaches a timer to req and wraps w to report it
+func Start(w http.ResponseWriter, req *http.Request) (*Writer, *http.Request) {
+	t := &timer{start: now()}
+	return &Writer{ResponseWriter: w, timer: t}, req.WithContext(context.WithValue(req.Context(), timerKey{}, t))
+}
+
+// Upstream starts measuring an upstream call of the request of ctx and returns the function that
+// stops it. It is a no-op if the request has no timer.
+func Upstream(ctx context.Context) (stop func()) {
+	t, ok := ctx.Value(timerKey{}).(*timer)
+	if !ok {
+		return func() {}
+	}
+	t.mu.Lock()
+	if t.active == 0 {
+		t.since = now()
+	}
+	t.active++
+	t.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+			t.active--
+			if t.active == 0 {
+				t.upstream += now().Sub(t.since)
+			}
+		})
+	}
+}
+
+// value formats the timing so far as Server-Timing metrics in milliseconds
+func (t *timer) value() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	current := now()
+	upstream := t.upstream
+	if t.active > 0 {
+		upstream += current.Sub(t.since)
+	}
+	total := current.Sub(t.start)
+	return fmt.Sprintf("gateway;dur=%s, upstream;dur=%s, total;dur=%s", millis(total-upstream), millis(upstream), millis(total))
+}
+
+func millis(d time.Duration) string {
+	return strconv.FormatFloat(float64(d.Microseconds())/1000, 'f', -1, 64)
+}
+
+func (w *Writer) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.Header().Add(Header, w.timer.value())
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *Writer) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush passes flushes of streamed responses on
+func (w *Writer) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the wrapped writer
+func (w *Writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// ParseBudget parses the value of the X-Latency-Budget header
+func ParseBudget(value string) (time.Duration, error) {
+	var budget time.Duration
+	ms, err := strconv.ParseInt(value, 10, 64)
+	if err == nil {
+		budget = time.Duration(ms) * time.Millisecond
+	} else {
+		budget, err = time.ParseDuration(value)
+	}
+	if err != nil || budget <= 0 {
+		return 0, fmt.Errorf("invalid %s '%s'", BudgetHeader, value)
+	}
+	return budget, nil
+}
+
+// WithBudget limits the context of req to the latency budget requested by the client, counted
+// from the start of its timer. Requests without budget are returned unchanged. The returned
+// function releases the context and must be called once the request is served.
+func WithBudget(req *http.Request) (*http.Request, context.CancelFunc, error) {
+	value := req.Header.Get(BudgetHeader)
+	if value == "" {
+		return req, func() {}, nil
+	}
+	budget, err := ParseBudget(value)
+	if err != nil {
+		return req, func() {}, err
+	}
+	start := now()
+	if t, ok := req.Context().Value(timerKey{}).(*timer); ok {
+		start = t.start
+	}
+	ctx, cancel := context.WithDeadlineCause(req.Context(), start.Add(budget), ErrBudgetExceeded)
+	return req.WithContext(ctx), cancel, nil
+}
+
+// BudgetExceeded reports whether ctx was cancelled because its latency budget ran out
+func BudgetExceeded(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrBudgetExceeded)
+}
@@ -0,0 +1,134 @@
+package timing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock advances only when told to
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func useFakeClock(t *testing.T) *fakeClock {
+	clock := &fakeClock{t: time.Unix(1700000000, 0)}
+	original := now
+	now = func() time.Time { return clock.t }
+	t.Cleanup(func() { now = original })
+	return clock
+}
+
+func TestWriter_ReportsGatewayAndUpstreamTime(t *testing.T) {
+	clock := useFakeClock(t)
+	rec := httptest.NewRecorder()
+	w, req := Start(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	clock.advance(2 * time.Millisecond)
+	stopA := Upstream(req.Context())
+	clock.advance(10 * time.Millisecond)
+	stopB := Upstream(req.Context())
+	clock.advance(20 * time.Millisecond)
+	stopA()
+	clock.advance(5 * time.Millisecond)
+	stopB()
+	stopB()
+	clock.advance(1500 * time.Microsecond)
+
+	w.Header().Set("Content-Type", "application/json")
+	_, err := w.Write([]byte("{}"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "gateway;dur=3.5, upstream;dur=35, total;dur=38.5", rec.Header().Get(Header), "overlapping calls count once")
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestWriter_KeepsUpstreamServerTiming(t *testing.T) {
+	useFakeClock(t)
+	rec := httptest.NewRecorder()
+	w, _ := Start(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	w.Header().Set(Header, "db;dur=12")
+	w.WriteHeader(http.StatusAccepted)
+
+	assert.Equal(t, []string{"db;dur=12", "gateway;dur=0, upstream;dur=0, total;dur=0"}, rec.Header().Values(Header))
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+}
+
+func TestUpstream_WithoutTimer(t *testing.T) {
+	assert.NotPanics(t, func() { Upstream(context.Background())() })
+}
+
+func TestParseBudget(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "1500", want: 1500 * time.Millisecond},
+		{value: "2s", want: 2 * time.Second},
+		{value: "250ms", want: 250 * time.Millisecond},
+		{value: "0", wantErr: true},
+		{value: "-1s", wantErr: true},
+		{value: "soon", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseBudget(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWithBudget(t *testing.T) {
+	clock := useFakeClock(t)
+	_, req := Start(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	clock.advance(100 * time.Millisecond)
+
+	unlimited, cancel, err := WithBudget(req)
+	require.NoError(t, err)
+	cancel()
+	_, hasDeadline := unlimited.Context().Deadline()
+	assert.False(t, hasDeadline)
+
+	req.Header.Set(BudgetHeader, "500")
+	limited, cancel, err := WithBudget(req)
+	require.NoError(t, err)
+	defer cancel()
+	deadline, ok := limited.Context().Deadline()
+	require.True(t, ok)
+	assert.Equal(t, time.Unix(1700000000, 0).Add(500*time.Millisecond), deadline, "the budget counts from the start of the request")
+
+	req.Header.Set(BudgetHeader, "later")
+	_, _, err = WithBudget(req)
+	assert.Error(t, err)
+}
+
+func TestBudgetExceeded(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(BudgetHeader, "1")
+	limited, cancel, err := WithBudget(req)
+	require.NoError(t, err)
+	defer cancel()
+
+	<-limited.Context().Done()
+	assert.True(t, BudgetExceeded(limited.Context()))
+
+	ctx, cancelOther := context.WithCancel(context.Background())
+	cancelOther()
+	assert.False(t, BudgetExceeded(ctx), "other cancellations are not budget overruns")
+}
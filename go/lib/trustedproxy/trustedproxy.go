@@ -0,0 +1,64 @@
+// Package trustedproxy decides whether the forwarded headers of a request, e.g. X-Forwarded-Proto,
+// were set by a trusted proxy rather than by the client.
+package trustedproxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Allowlist lists the peers whose forwarded headers are believed. A nil Allowlist trusts
+// no peer.
+type Allowlist struct {
+	networks []*net.IPNet
+}
+
+// New parses IP addresses and CIDR ranges. Without entries, it returns nil.
+func New(entries []string) (*Allowlist, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+	p := &Allowlist{}
+	for _, entry := range entries {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address '%s'", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			entry = fmt.Sprintf("%s/%d", ip, bits)
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR range '%s'", entry)
+		}
+		p.networks = append(p.networks, network)
+	}
+	return p, nil
+}
+
+// Trusts reports whether the forwarded headers of the peer that sent req are believed
+func (p *Allowlist) Trusts(req *http.Request) bool {
+	if p == nil {
+		return false
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range p.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
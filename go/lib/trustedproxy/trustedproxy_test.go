@@ -0,0 +1,37 @@
+package trustedproxy
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxies_Trusts(t *testing.T) {
+	p, err := New([]string{"10.0.0.0/8", "::1", "192.0.2.1"})
+	require.NoError(t, err)
+
+	for remoteAddr, want := range map[string]bool{
+		"10.1.2.3:51234":    true,
+		"[::1]:51234":       true,
+		"192.0.2.1:443":     true,
+		"192.0.2.2:443":     false,
+		"203.0.113.7:51234": false,
+		"not-an-address":    false,
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = remoteAddr
+		assert.Equal(t, want, p.Trusts(req), remoteAddr)
+	}
+
+	var none *Allowlist
+	assert.False(t, none.Trusts(httptest.NewRequest("GET", "/", nil)))
+}
+
+func TestNew_Invalid(t *testing.T) {
+	for _, entry := range []string{"10.0.0", "10.0.0.0/33", "proxy.internal"} {
+		_, err := New([]string{entry})
+		assert.Error(t, err, entry)
+	}
+}
@@ -0,0 +1,163 @@
+package upstream
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Transport sends requests to hosts with their own TLS or proxy configuration through a
+// transport of their own, and all other requests through the shared base transport. It is safe
+// for concurrent use.
+type Transport struct {
+	base *http.Transport
+	// tls is the global configuration that per-host configurations override
+	tls   *TLSConfig
+	hosts atomic.Pointer[map[string]*hostTransport]
+	mu    sync.Mutex
+}
+
+// HostConfig overrides the upstream configuration for the requests to a host
+type HostConfig struct {
+	// TLS overrides the set fields of the global TLS configuration
+	TLS *TLSConfig
+	// Proxy is the proxy of all requests to the host, regardless of no_proxy
+	Proxy string
+}
+
+// hostTransport is the transport of a host and the configuration it was built from
+type hostTransport struct {
+	tls       TLSConfig
+	proxy     string
+	transport *http.Transport
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.ForHost(req.URL.Host).RoundTrip(req)
+}
+
+// ForHost returns the transport of requests to host, e.g. weather-agent:8443, to derive
+// transports for other protocols
+func (t *Transport) ForHost(host string) *http.Transport {
+	if h, ok := (*t.hosts.Load())[host]; ok {
+		return h.transport
+	}
+	return t.base
+}
+
+// CloseIdleConnections closes the idle connections of all hosts
+func (t *Transport) CloseIdleConnections() {
+	t.base.CloseIdleConnections()
+	for _, h := range *t.hosts.Load() {
+		h.transport.CloseIdleConnections()
+	}
+}
+
+// SetHosts replaces the configurations per host, e.g. after agents were reloaded. Transports
+// of hosts whose configuration did not change keep their connections; on error, the previous
+// configurations are kept.
+func (t *Transport) SetHosts(configs map[string]HostConfig) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	previous := *t.hosts.Load()
+	hosts := make(map[string]*hostTransport, len(configs))
+	for host, cfg := range configs {
+		merged := mergeTLS(t.tls, cfg.TLS)
+		if h, ok := previous[host]; ok && reflect.DeepEqual(h.tls, merged) && h.proxy == cfg.Proxy {
+			hosts[host] = h
+			continue
+		}
+		tlsConfig, err := newTLSConfig(&merged)
+		if err != nil {
+			return fmt.Errorf("host %s: %w", host, err)
+		}
+		transport := t.base.Clone()
+		transport.TLSClientConfig = tlsConfig
+		if cfg.Proxy != "" {
+			proxyURL, err := parseProxy(cfg.Proxy)
+			if err != nil {
+				return fmt.Errorf("host %s: %w", host, err)
+			}
+			transport.Proxy = http.ProxyURL(proxyURL)
+		}
+		hosts[host] = &hostTransport{tls: merged, proxy: cfg.Proxy, transport: transport}
+	}
+	t.hosts.Store(&hosts)
+
+	for host, h := range previous {
+		if hosts[host] != h {
+			h.transport.CloseIdleConnections()
+		}
+	}
+	return nil
+}
+
+// mergeTLS overrides the set fields of global by those of host
+func mergeTLS(global, host *TLSConfig) TLSConfig {
+	var merged TLSConfig
+	if global != nil {
+		merged = *global
+	}
+	if host == nil {
+		return merged
+	}
+	if host.CAFile != "" {
+		merged.CAFile = host.CAFile
+	}
+	if host.CertFile != "" || host.KeyFile != "" {
+		merged.CertFile, merged.KeyFile = host.CertFile, host.KeyFile
+	}
+	if host.MinVersion != "" {
+		merged.MinVersion = host.MinVersion
+	}
+	merged.InsecureSkipVerify = merged.InsecureSkipVerify || host.InsecureSkipVerify
+	return merged
+}
+
+// certLoader loads a client certificate and reloads it when its files were modified
+type certLoader struct {
+	certFile, keyFile string
+
+	mu       sync.Mutex
+	cert     *tls.Certificate
+	modified time.Time
+}
+
+func (l *certLoader) load() (*tls.Certificate, error) {
+	modified, err := latestModification(l.certFile, l.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cert != nil && modified.Equal(l.modified) {
+		return l.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load client certificate: %w", err)
+	}
+	l.cert, l.modified = &cert, modified
+	return l.cert, nil
+}
+
+func latestModification(paths ...string) (time.Time, error) {
+	var latest time.Time
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("cannot read client certificate: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,152 @@
+package upstream
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeClientCert writes a self-signed client certificate and its key
+func writeClientCert(t *testing.T, commonName string) (certFile, keyFile string, cert *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err = x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile, cert
+}
+
+// newMTLSServer requires a client certificate signed by client and responds with its common name
+func newMTLSServer(t *testing.T, client *x509.Certificate) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	pool := x509.NewCertPool()
+	pool.AddCert(client)
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestTransport_SetHosts(t *testing.T) {
+	certFile, keyFile, clientCert := writeClientCert(t, "gateway")
+	mtls := newMTLSServer(t, clientCert)
+	public := newTLSServer(t)
+
+	transport, err := NewTransport(nil)
+	require.NoError(t, err)
+	client := &http.Client{Transport: transport}
+	mtlsHost := mtls.Listener.Addr().String()
+	require.NoError(t, transport.SetHosts(map[string]HostConfig{
+		mtlsHost: {TLS: &TLSConfig{CAFile: writeCA(t, mtls), CertFile: certFile, KeyFile: keyFile}},
+	}))
+
+	resp, err := client.Get(mtls.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "the client certificate of the host is presented")
+	_, err = client.Get(public.URL)
+	assert.Error(t, err, "other hosts keep the base configuration")
+
+	configured := transport.ForHost(mtlsHost)
+	require.NoError(t, transport.SetHosts(map[string]HostConfig{
+		mtlsHost:                        {TLS: &TLSConfig{CAFile: writeCA(t, mtls), CertFile: certFile, KeyFile: keyFile}},
+		public.Listener.Addr().String(): {TLS: &TLSConfig{InsecureSkipVerify: true}},
+	}))
+	assert.Equal(t, "HTTP/2.0", get(t, client, public.URL))
+	assert.NotSame(t, configured, transport.ForHost(mtlsHost), "the CA file is a new temporary file")
+
+	kept := transport.ForHost(public.Listener.Addr().String())
+	require.NoError(t, transport.SetHosts(map[string]HostConfig{public.Listener.Addr().String(): {TLS: &TLSConfig{InsecureSkipVerify: true}}}))
+	assert.Same(t, kept, transport.ForHost(public.Listener.Addr().String()), "unchanged hosts keep their transport")
+	assert.Same(t, transport.base, transport.ForHost(mtlsHost), "removed hosts use the base transport")
+
+	err = transport.SetHosts(map[string]HostConfig{"weather-agent:8443": {TLS: &TLSConfig{CertFile: certFile}}})
+	assert.ErrorContains(t, err, "host weather-agent:8443: cert_file and key_file must be set together")
+	assert.Same(t, kept, transport.ForHost(public.Listener.Addr().String()), "the previous hosts are kept on error")
+}
+
+func TestCertLoader_ReloadsRotatedCertificates(t *testing.T) {
+	certFile, keyFile, _ := writeClientCert(t, "gateway")
+	loader := &certLoader{certFile: certFile, keyFile: keyFile}
+	first, err := loader.load()
+	require.NoError(t, err)
+	again, err := loader.load()
+	require.NoError(t, err)
+	assert.Same(t, first, again)
+
+	rotatedCert, rotatedKey, rotated := writeClientCert(t, "gateway-rotated")
+	for _, pair := range [][2]string{{rotatedCert, certFile}, {rotatedKey, keyFile}} {
+		raw, err := os.ReadFile(pair[0])
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(pair[1], raw, 0o600))
+		require.NoError(t, os.Chtimes(pair[1], time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+	}
+	reloaded, err := loader.load()
+	require.NoError(t, err)
+	assert.Equal(t, rotated.Raw, reloaded.Certificate[0])
+}
+
+func TestMergeTLS(t *testing.T) {
+	global := &TLSConfig{CAFile: "/etc/ca.pem", CertFile: "/etc/client.pem", KeyFile: "/etc/client-key.pem", MinVersion: "1.3"}
+
+	assert.Equal(t, *global, mergeTLS(global, nil))
+	assert.Equal(t, TLSConfig{CAFile: "/agent/ca.pem", CertFile: "/etc/client.pem", KeyFile: "/etc/client-key.pem", MinVersion: "1.3", InsecureSkipVerify: true},
+		mergeTLS(global, &TLSConfig{CAFile: "/agent/ca.pem", InsecureSkipVerify: true}))
+	assert.Equal(t, TLSConfig{CertFile: "/agent/client.pem", KeyFile: "/agent/client-key.pem"},
+		mergeTLS(nil, &TLSConfig{CertFile: "/agent/client.pem", KeyFile: "/agent/client-key.pem"}))
+}
+
+func TestTransport_SetHosts_Proxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+	}))
+	defer proxy.Close()
+
+	transport, err := NewTransport(&Config{NoProxy: []string{"*.corp.example"}})
+	require.NoError(t, err)
+	require.NoError(t, transport.SetHosts(map[string]HostConfig{
+		"partner.example:8000":  {Proxy: proxy.URL},
+		"weather.agents.svc:80": {Proxy: proxy.URL},
+	}))
+	client := &http.Client{Transport: transport}
+	get(t, client, "http://partner.example:8000/.well-known/agent-card.json")
+	get(t, client, "http://weather.agents.svc:80/")
+
+	assert.Equal(t, []string{"http://partner.example:8000/.well-known/agent-card.json", "http://weather.agents.svc:80/"}, proxied,
+		"the proxy of a host applies regardless of no_proxy")
+
+	err = transport.SetHosts(map[string]HostConfig{"partner.example:8000": {Proxy: "partner-proxy:3128"}})
+	assert.ErrorContains(t, err, "host partner.example:8000: invalid proxy 'partner-proxy:3128'")
+}
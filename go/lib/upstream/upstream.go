@@ -0,0 +1,237 @@
+// Package upstream builds the HTTP client that plugins use for the requests they send themselves,
+// e.g. agent discovery, health probes, artifact downloads and task polling. Requests routed
+// through KrakenD use the backend configuration of KrakenD instead.
+//
+// net/http keeps only two idle connections per host, so bursts of requests to the same agent
+// open new connections. The defaults of Config keep more connections alive and negotiate HTTP/2
+// with agents served over TLS.
+package upstream
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/netclass"
+)
+
+// Defaults of Config
+const (
+	DefaultMaxIdleConns        = 100
+	DefaultMaxIdleConnsPerHost = 32
+	DefaultIdleConnTimeout     = 90 * time.Second
+	DefaultDialTimeout         = 10 * time.Second
+	DefaultKeepAlive           = 30 * time.Second
+	DefaultTLSHandshakeTimeout = 10 * time.Second
+)
+
+// Config tunes the connection pool of the upstream client. Durations are Go durations like "30s";
+// unset fields use the defaults.
+type Config struct {
+	MaxIdleConns        int `json:"max_idle_conns,omitempty"`
+	MaxIdleConnsPerHost int `json:"max_idle_conns_per_host,omitempty"`
+	// MaxConnsPerHost limits the connections per agent, including active ones. 0 is unlimited.
+	MaxConnsPerHost     int    `json:"max_conns_per_host,omitempty"`
+	IdleConnTimeout     string `json:"idle_conn_timeout,omitempty"`
+	DialTimeout         string `json:"dial_timeout,omitempty"`
+	TLSHandshakeTimeout string `json:"tls_handshake_timeout,omitempty"`
+	// HTTP2 negotiates HTTP/2 with agents served over TLS (default true)
+	HTTP2 *bool `json:"http2,omitempty"`
+	// HTTP2PingInterval pings HTTP/2 connections that received no frames for this long, so dead
+	// connections are closed instead of failing the next request. Unset disables pings.
+	HTTP2PingInterval string `json:"http2_ping_interval,omitempty"`
+	// Proxy is the http, https, socks5 or socks5h URL of the proxy of all requests. Without it,
+	// the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables apply.
+	Proxy string `json:"proxy,omitempty"`
+	// NoProxy lists host names, suffixes (".corp.example" or "*.corp.example"), IPs and CIDR
+	// ranges reached without proxy, in addition to cluster-internal hosts, see package netclass
+	NoProxy []string   `json:"no_proxy,omitempty"`
+	TLS     *TLSConfig `json:"tls,omitempty"`
+}
+
+// TLSConfig configures the TLS connections to agents. Per-agent configurations override the set
+// fields of the global one.
+type TLSConfig struct {
+	// CAFile is a PEM bundle of CAs trusted in addition to the system roots
+	CAFile string `json:"ca_file,omitempty"`
+	// CertFile and KeyFile are the PEM client certificate and key presented to agents that
+	// require mutual TLS. They are re-read when the files change, e.g. when they are rotated.
+	CertFile string `json:"cert_file,omitempty"`
+	KeyFile  string `json:"key_file,omitempty"`
+	// InsecureSkipVerify accepts any certificate of the agent. It is meant for tests only, as
+	// connections can be intercepted.
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+	// MinVersion is the lowest accepted TLS version, "1.2" (default) or "1.3"
+	MinVersion string `json:"min_version,omitempty"`
+}
+
+// NewClient returns a client with a transport built from cfg. A nil cfg uses the defaults.
+// Timeouts of single requests are set by their callers.
+func NewClient(cfg *Config) (*http.Client, error) {
+	transport, err := NewTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// NewTransport returns the transport of NewClient. Hosts of agents with their own TLS or proxy
+// configuration are added with SetHosts.
+func NewTransport(cfg *Config) (*Transport, error) {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	base, err := newHTTPTransport(cfg)
+	if err != nil {
+		return nil, err
+	}
+	t := &Transport{base: base, tls: cfg.TLS}
+	t.hosts.Store(&map[string]*hostTransport{})
+	return t, nil
+}
+
+func newHTTPTransport(cfg *Config) (*http.Transport, error) {
+	dialTimeout, err := duration(cfg.DialTimeout, DefaultDialTimeout, "dial_timeout")
+	if err != nil {
+		return nil, err
+	}
+	idleConnTimeout, err := duration(cfg.IdleConnTimeout, DefaultIdleConnTimeout, "idle_conn_timeout")
+	if err != nil {
+		return nil, err
+	}
+	tlsHandshakeTimeout, err := duration(cfg.TLSHandshakeTimeout, DefaultTLSHandshakeTimeout, "tls_handshake_timeout")
+	if err != nil {
+		return nil, err
+	}
+	pingInterval, err := duration(cfg.HTTP2PingInterval, 0, "http2_ping_interval")
+	if err != nil {
+		return nil, err
+	}
+	if cfg.MaxIdleConns < 0 || cfg.MaxIdleConnsPerHost < 0 || cfg.MaxConnsPerHost < 0 {
+		return nil, fmt.Errorf("connection limits must not be negative")
+	}
+
+	noProxy, err := netclass.New(cfg.NoProxy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid no_proxy: %w", err)
+	}
+	proxy := http.ProxyFromEnvironment
+	if cfg.Proxy != "" {
+		proxyURL, err := parseProxy(cfg.Proxy)
+		if err != nil {
+			return nil, err
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	transport := &http.Transport{
+		Proxy:                 bypass(noProxy, proxy),
+		DialContext:           (&net.Dialer{Timeout: dialTimeout, KeepAlive: DefaultKeepAlive}).DialContext,
+		MaxIdleConns:          orDefault(cfg.MaxIdleConns, DefaultMaxIdleConns),
+		MaxIdleConnsPerHost:   orDefault(cfg.MaxIdleConnsPerHost, DefaultMaxIdleConnsPerHost),
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       idleConnTimeout,
+		TLSHandshakeTimeout:   tlsHandshakeTimeout,
+		ExpectContinueTimeout: time.Second,
+		ForceAttemptHTTP2:     cfg.HTTP2 == nil || *cfg.HTTP2,
+	}
+	if !transport.ForceAttemptHTTP2 {
+		// A non-nil, empty map disables HTTP/2, see the net/http documentation
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	if pingInterval > 0 {
+		transport.HTTP2 = &http.HTTP2Config{SendPingTimeout: pingInterval}
+	}
+
+	if transport.TLSClientConfig, err = newTLSConfig(cfg.TLS); err != nil {
+		return nil, fmt.Errorf("invalid tls: %w", err)
+	}
+	return transport, nil
+}
+
+func parseProxy(raw string) (*url.URL, error) {
+	proxyURL, err := url.Parse(raw)
+	if err != nil || proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy '%s', expected an http, https, socks5 or socks5h URL", raw)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return proxyURL, nil
+	}
+	return nil, fmt.Errorf("invalid proxy '%s', expected an http, https, socks5 or socks5h URL", raw)
+}
+
+// bypass sends requests to the hosts of noProxy without proxy
+func bypass(noProxy *netclass.Classifier, proxy func(*http.Request) (*url.URL, error)) func(*http.Request) (*url.URL, error) {
+	return func(req *http.Request) (*url.URL, error) {
+		if noProxy.IsPrivateHost(req.URL.Host) {
+			return nil, nil
+		}
+		return proxy(req)
+	}
+}
+
+func newTLSConfig(cfg *TLSConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg == nil {
+		return tlsConfig, nil
+	}
+	tlsConfig.InsecureSkipVerify = cfg.InsecureSkipVerify
+	switch cfg.MinVersion {
+	case "", "1.2":
+	case "1.3":
+		tlsConfig.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported min_version '%s', use 1.2 or 1.3", cfg.MinVersion)
+	}
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read ca_file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca_file %s contains no PEM certificates", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return nil, fmt.Errorf("cert_file and key_file must be set together")
+	}
+	if cfg.CertFile != "" {
+		certs := &certLoader{certFile: cfg.CertFile, keyFile: cfg.KeyFile}
+		if _, err := certs.load(); err != nil {
+			return nil, err
+		}
+		tlsConfig.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return certs.load()
+		}
+	}
+	return tlsConfig, nil
+}
+
+func duration(value string, fallback time.Duration, name string) (time.Duration, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s '%s'", name, value)
+	}
+	return d, nil
+}
+
+func orDefault(value, fallback int) int {
+	if value == 0 {
+		return fallback
+	}
+	return value
+}
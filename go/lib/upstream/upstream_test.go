@@ -0,0 +1,122 @@
+package upstream
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTLSServer serves the protocol of each request over TLS, with HTTP/2 enabled
+func newTLSServer(t *testing.T) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.Proto))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// writeCA writes the certificate of server as PEM bundle
+func writeCA(t *testing.T, server *httptest.Server) string {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(path, ca, 0o600))
+	return path
+}
+
+func get(t *testing.T, client *http.Client, url string) string {
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	return resp.Proto
+}
+
+func TestNewTransport_Defaults(t *testing.T) {
+	upstreamTransport, err := NewTransport(nil)
+	require.NoError(t, err)
+	transport := upstreamTransport.ForHost("weather-agent:8000")
+
+	assert.Equal(t, DefaultMaxIdleConns, transport.MaxIdleConns)
+	assert.Equal(t, DefaultMaxIdleConnsPerHost, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, DefaultIdleConnTimeout, transport.IdleConnTimeout)
+	assert.True(t, transport.ForceAttemptHTTP2)
+	assert.Equal(t, uint16(tls.VersionTLS12), transport.TLSClientConfig.MinVersion)
+	assert.Nil(t, transport.HTTP2)
+}
+
+func TestNewClient_HTTP2AndCA(t *testing.T) {
+	server := newTLSServer(t)
+
+	client, err := NewClient(&Config{TLS: &TLSConfig{CAFile: writeCA(t, server)}})
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/2.0", get(t, client, server.URL))
+
+	http2 := false
+	client, err = NewClient(&Config{HTTP2: &http2, TLS: &TLSConfig{CAFile: writeCA(t, server)}})
+	require.NoError(t, err)
+	assert.Equal(t, "HTTP/1.1", get(t, client, server.URL))
+
+	client, err = NewClient(nil)
+	require.NoError(t, err)
+	_, err = client.Get(server.URL)
+	assert.Error(t, err, "the certificate of the server is not trusted without ca_file")
+}
+
+func TestNewClient_Proxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	client, err := NewClient(&Config{Proxy: proxy.URL, NoProxy: []string{"*.corp.example", "203.0.113.0/24"}})
+	require.NoError(t, err)
+	get(t, client, "http://weather-agent:8000/.well-known/agent-card.json")
+	assert.Equal(t, "http://weather-agent:8000/.well-known/agent-card.json", proxied)
+
+	transport := client.Transport.(*Transport).ForHost("")
+	for target, want := range map[string]bool{
+		"http://partner.example/":                  true,
+		"http://weather.agents.svc.cluster.local/": false,
+		"http://10.0.0.7:8000/":                    false,
+		"http://localhost:8000/":                   false,
+		"http://crm.corp.example/":                 false,
+		"http://203.0.113.9/":                      false,
+	} {
+		proxyURL, err := transport.Proxy(httptest.NewRequest(http.MethodGet, target, nil))
+		require.NoError(t, err)
+		assert.Equal(t, want, proxyURL != nil, target)
+	}
+}
+
+func TestNewTransport_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{name: "dial timeout", cfg: Config{DialTimeout: "soon"}, want: "invalid dial_timeout 'soon'"},
+		{name: "negative idle timeout", cfg: Config{IdleConnTimeout: "-1s"}, want: "invalid idle_conn_timeout '-1s'"},
+		{name: "ping interval", cfg: Config{HTTP2PingInterval: "often"}, want: "invalid http2_ping_interval 'often'"},
+		{name: "negative limit", cfg: Config{MaxConnsPerHost: -1}, want: "connection limits must not be negative"},
+		{name: "proxy scheme", cfg: Config{Proxy: "ftp://proxy:21"}, want: "invalid proxy 'ftp://proxy:21'"},
+		{name: "no proxy", cfg: Config{NoProxy: []string{"10.0.0.0/33"}}, want: "invalid no_proxy: invalid CIDR range '10.0.0.0/33'"},
+		{name: "tls version", cfg: Config{TLS: &TLSConfig{MinVersion: "1.0"}}, want: "unsupported min_version '1.0'"},
+		{name: "missing ca file", cfg: Config{TLS: &TLSConfig{CAFile: "/nonexistent/ca.pem"}}, want: "cannot read ca_file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewTransport(&tt.cfg)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
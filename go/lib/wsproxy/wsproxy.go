@@ -0,0 +1,292 @@
+// Package wsproxy proxies WebSocket connections to an upstream agent.
+//
+// KrakenD endpoints cannot carry upgraded connections, so plugins hand WebSocket
+// handshakes to a Proxy instead. It forwards the handshake to the upstream, hijacks
+// the client connection once the upstream switched protocols and copies bytes in
+// both directions until either side closes or the connection is idle. Frames are not parsed.
+package wsproxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const defaultDialTimeout = 10 * time.Second
+
+// errUpstream is the body of 502 responses; the cause is only passed to Proxy.OnError, so
+// clients do not learn internal hosts
+const errUpstream = "websocket upstream is not available"
+
+// hopHeaders are removed from forwarded handshakes, except Connection and Upgrade
+// which are set again for the upstream
+var hopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// IsUpgrade reports whether req is a WebSocket handshake
+func IsUpgrade(req *http.Request) bool {
+	return headerHasToken(req.Header, "Connection", "upgrade") &&
+		strings.EqualFold(req.Header.Get("Upgrade"), "websocket")
+}
+
+func headerHasToken(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, field := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Proxy forwards WebSocket connections to a single upstream URL. Headers lists the client
+// headers forwarded besides Sec-WebSocket-*, Origin and User-Agent. Connections are closed
+// after IdleTimeout without bytes in either direction, and at most MaxConnections are relayed
+// at a time; zero disables either limit. OnError receives the causes of failed handshakes.
+type Proxy struct {
+	target         *url.URL
+	DialTimeout    time.Duration
+	TLSConfig      *tls.Config
+	Headers        []string
+	IdleTimeout    time.Duration
+	MaxConnections int
+	OnError        func(error)
+
+	active atomic.Int64
+}
+
+// New creates a proxy for the upstream at target, e.g. ws://weather-agent:8000/ws
+func New(target string) (*Proxy, error) {
+	parsed, err := url.Parse(target)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "ws" && parsed.Scheme != "wss") {
+		return nil, fmt.Errorf("invalid websocket url '%s', expected ws://host/path or wss://host/path", target)
+	}
+	return &Proxy{target: parsed, DialTimeout: defaultDialTimeout}, nil
+}
+
+// Target returns the upstream URL
+func (p *Proxy) Target() string {
+	return p.target.String()
+}
+
+// ServeHTTP forwards the handshake in req and, once the upstream accepted it, relays the connection.
+// Requests that are no WebSocket handshake are rejected with 400 Bad Request, handshakes beyond
+// MaxConnections with 503 Service Unavailable and failed dials with 502 Bad Gateway. Rejected
+// handshakes are answered with the response of the upstream.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !IsUpgrade(req) {
+		http.Error(w, "expected a websocket handshake", http.StatusBadRequest)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "connection cannot be upgraded", http.StatusInternalServerError)
+		return
+	}
+	defer p.active.Add(-1)
+	if active := p.active.Add(1); p.MaxConnections > 0 && active > int64(p.MaxConnections) {
+		http.Error(w, "too many websocket connections", http.StatusServiceUnavailable)
+		return
+	}
+
+	upstream, err := p.dial(req.Context())
+	if err != nil {
+		p.fail(w, fmt.Errorf("cannot reach websocket upstream: %w", err))
+		return
+	}
+	defer upstream.Close()
+
+	outReq := p.handshake(req)
+	if err := outReq.Write(upstream); err != nil {
+		p.fail(w, fmt.Errorf("cannot send websocket handshake: %w", err))
+		return
+	}
+	upstreamReader := bufio.NewReader(upstream)
+	resp, err := http.ReadResponse(upstreamReader, outReq)
+	if err != nil {
+		p.fail(w, fmt.Errorf("invalid websocket handshake response: %w", err))
+		return
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		defer resp.Body.Close()
+		for name, values := range resp.Header {
+			w.Header()[name] = values
+		}
+		w.WriteHeader(resp.StatusCode)
+		_, _ = io.Copy(w, resp.Body)
+		return
+	}
+
+	client, clientBuf, err := hijacker.Hijack()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("cannot hijack connection: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer client.Close()
+
+	if _, err := fmt.Fprintf(clientBuf, "HTTP/1.1 %s\r\n", resp.Status); err != nil {
+		return
+	}
+	if err := resp.Header.Write(clientBuf); err != nil {
+		return
+	}
+	if _, err := clientBuf.WriteString("\r\n"); err != nil {
+		return
+	}
+	if err := clientBuf.Flush(); err != nil {
+		return
+	}
+
+	relay(client, clientBuf.Reader, upstream, upstreamReader, p.IdleTimeout)
+}
+
+// fail reports err and answers with a generic 502 Bad Gateway
+func (p *Proxy) fail(w http.ResponseWriter, err error) {
+	if p.OnError != nil {
+		p.OnError(err)
+	}
+	http.Error(w, errUpstream, http.StatusBadGateway)
+}
+
+// dial connects to the upstream, over TLS for wss URLs
+func (p *Proxy) dial(ctx context.Context) (net.Conn, error) {
+	host := p.target.Host
+	if p.target.Port() == "" {
+		port := "80"
+		if p.target.Scheme == "wss" {
+			port = "443"
+		}
+		host = net.JoinHostPort(p.target.Hostname(), port)
+	}
+
+	dialer := &net.Dialer{Timeout: p.DialTimeout}
+	if p.target.Scheme == "wss" {
+		config := p.TLSConfig.Clone()
+		if config == nil {
+			config = &tls.Config{}
+		}
+		if config.ServerName == "" {
+			config.ServerName = p.target.Hostname()
+		}
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: config}
+		return tlsDialer.DialContext(ctx, "tcp", host)
+	}
+	return dialer.DialContext(ctx, "tcp", host)
+}
+
+// handshake builds the upstream handshake: the target URL with the query of the client, the
+// Sec-WebSocket-*, Origin and User-Agent headers and the allowlisted Headers of the client, and
+// the forwarding headers
+func (p *Proxy) handshake(req *http.Request) *http.Request {
+	target := *p.target
+	target.RawQuery = req.URL.RawQuery
+	if target.Scheme == "wss" {
+		target.Scheme = "https"
+	} else {
+		target.Scheme = "http"
+	}
+
+	header := http.Header{}
+	for name, values := range req.Header {
+		if strings.HasPrefix(name, "Sec-Websocket-") || name == "Origin" || name == "User-Agent" || name == "X-Forwarded-For" ||
+			name == "X-Forwarded-Host" || name == "X-Forwarded-Proto" {
+			header[name] = values
+		}
+	}
+	for _, name := range p.Headers {
+		if values := req.Header.Values(name); len(values) > 0 {
+			header[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	for _, name := range hopHeaders {
+		header.Del(name)
+	}
+	header.Set("Connection", "Upgrade")
+	header.Set("Upgrade", "websocket")
+	if clientIP, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		if prior := req.Header.Get("X-Forwarded-For"); prior != "" {
+			clientIP = prior + ", " + clientIP
+		}
+		header.Set("X-Forwarded-For", clientIP)
+	}
+	if header.Get("X-Forwarded-Host") == "" {
+		header.Set("X-Forwarded-Host", req.Host)
+	}
+	if header.Get("X-Forwarded-Proto") == "" {
+		proto := "http"
+		if req.TLS != nil {
+			proto = "https"
+		}
+		header.Set("X-Forwarded-Proto", proto)
+	}
+
+	return &http.Request{
+		Method:     http.MethodGet,
+		URL:        &target,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     header,
+		Host:       target.Host,
+	}
+}
+
+// relay copies bytes in both directions until one side closes or, with an idle timeout, no bytes
+// were copied in either direction for that long, then closes both. Bytes already buffered while
+// reading the handshakes are sent first.
+func relay(client net.Conn, clientReader io.Reader, upstream net.Conn, upstreamReader io.Reader, idle time.Duration) {
+	var lastActive atomic.Int64
+	lastActive.Store(time.Now().UnixNano())
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(upstream, activityReader{clientReader, &lastActive})
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(client, activityReader{upstreamReader, &lastActive})
+		done <- struct{}{}
+	}()
+
+	var expired <-chan time.Time
+	if idle > 0 {
+		ticker := time.NewTicker(idle / 4)
+		defer ticker.Stop()
+		expired = ticker.C
+	}
+	for waiting := true; waiting; {
+		select {
+		case <-done:
+			waiting = false
+		case <-expired:
+			waiting = time.Since(time.Unix(0, lastActive.Load())) < idle
+		}
+	}
+	_ = client.Close()
+	_ = upstream.Close()
+	<-done
+}
+
+// activityReader records when bytes were last read
+type activityReader struct {
+	io.Reader
+	lastActive *atomic.Int64
+}
+
+func (r activityReader) Read(b []byte) (int, error) {
+	n, err := r.Reader.Read(b)
+	if n > 0 {
+		r.lastActive.Store(time.Now().UnixNano())
+	}
+	return n, err
+}
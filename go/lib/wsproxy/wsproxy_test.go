@@ -0,0 +1,174 @@
+package wsproxy
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const handshake = "GET /agent/ws?session=1 HTTP/1.1\r\n" +
+	"Host: gateway.example.com\r\n" +
+	"Connection: Upgrade\r\n" +
+	"Upgrade: websocket\r\n" +
+	"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+	"Sec-WebSocket-Version: 13\r\n\r\n"
+
+// newEchoUpstream accepts handshakes on /ws and echoes every byte back
+func newEchoUpstream(t *testing.T, received *http.Request) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*received = *r
+		if r.URL.Path != "/ws" {
+			http.Error(w, "no websocket here", http.StatusNotFound)
+			return
+		}
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+		_, _ = buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n" +
+			"Sec-WebSocket-Accept: s3pPLMBiTxaQ9kYGzzhZRbK+xOo=\r\n\r\n")
+		_ = buf.Flush()
+		_, _ = io.Copy(conn, buf)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// dialGateway sends the handshake to a gateway serving proxy and returns the connection
+// and the handshake response
+func dialGateway(t *testing.T, proxy http.Handler) (net.Conn, *bufio.Reader, *http.Response) {
+	gateway := httptest.NewServer(proxy)
+	t.Cleanup(gateway.Close)
+	return dialServer(t, gateway, handshake)
+}
+
+// dialServer sends a handshake to a running gateway
+func dialServer(t *testing.T, gateway *httptest.Server, handshake string) (net.Conn, *bufio.Reader, *http.Response) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(gateway.URL, "http://"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	_, err = conn.Write([]byte(handshake))
+	assert.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	assert.NoError(t, err)
+	return conn, reader, resp
+}
+
+func TestNew_InvalidTarget(t *testing.T) {
+	for _, target := range []string{"http://agent:8000/ws", "agent:8000", "ws://"} {
+		_, err := New(target)
+		assert.Error(t, err, target)
+	}
+}
+
+func TestIsUpgrade(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	assert.False(t, IsUpgrade(req))
+
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	req.Header.Set("Upgrade", "WebSocket")
+	assert.True(t, IsUpgrade(req))
+}
+
+func TestProxy_RelaysConnection(t *testing.T) {
+	var received http.Request
+	upstream := newEchoUpstream(t, &received)
+	proxy, err := New("ws" + strings.TrimPrefix(upstream.URL, "http") + "/ws")
+	assert.NoError(t, err)
+
+	conn, reader, resp := dialGateway(t, proxy)
+
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+	assert.Equal(t, "session=1", received.URL.RawQuery)
+	assert.Equal(t, "dGhlIHNhbXBsZSBub25jZQ==", received.Header.Get("Sec-WebSocket-Key"))
+	assert.Equal(t, "gateway.example.com", received.Header.Get("X-Forwarded-Host"))
+
+	_, err = conn.Write([]byte("hello agent"))
+	assert.NoError(t, err)
+	echo := make([]byte, len("hello agent"))
+	_, err = io.ReadFull(reader, echo)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello agent", string(echo))
+}
+
+func TestProxy_RejectedHandshake(t *testing.T) {
+	var received http.Request
+	upstream := newEchoUpstream(t, &received)
+	proxy, err := New("ws" + strings.TrimPrefix(upstream.URL, "http") + "/missing")
+	assert.NoError(t, err)
+
+	_, _, resp := dialGateway(t, proxy)
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestProxy_Errors(t *testing.T) {
+	proxy, err := New("ws://127.0.0.1:1/ws")
+	assert.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/agent/ws", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	var cause error
+	proxy.OnError = func(err error) { cause = err }
+	_, _, resp := dialGateway(t, proxy)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	assert.Equal(t, errUpstream+"\n", string(body), "clients do not learn internal hosts")
+	assert.ErrorContains(t, cause, "127.0.0.1:1")
+}
+
+func TestProxy_ForwardsAllowlistedHeadersOnly(t *testing.T) {
+	var received http.Request
+	upstream := newEchoUpstream(t, &received)
+	proxy, err := New("ws" + strings.TrimPrefix(upstream.URL, "http") + "/ws")
+	assert.NoError(t, err)
+	proxy.Headers = []string{"authorization"}
+	gateway := httptest.NewServer(proxy)
+	t.Cleanup(gateway.Close)
+
+	_, _, resp := dialServer(t, gateway, strings.TrimSuffix(handshake, "\r\n")+
+		"Origin: https://app.example.com\r\nAuthorization: Bearer token\r\nCookie: session=secret\r\n\r\n")
+
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "Bearer token", received.Header.Get("Authorization"))
+	assert.Equal(t, "https://app.example.com", received.Header.Get("Origin"))
+	assert.Equal(t, "dGhlIHNhbXBsZSBub25jZQ==", received.Header.Get("Sec-WebSocket-Key"))
+	assert.Empty(t, received.Header.Get("Cookie"))
+}
+
+func TestProxy_LimitsConnections(t *testing.T) {
+	var received http.Request
+	upstream := newEchoUpstream(t, &received)
+	proxy, err := New("ws" + strings.TrimPrefix(upstream.URL, "http") + "/ws")
+	assert.NoError(t, err)
+	proxy.MaxConnections = 1
+	proxy.IdleTimeout = 100 * time.Millisecond
+	gateway := httptest.NewServer(proxy)
+	t.Cleanup(gateway.Close)
+
+	conn, reader, resp := dialServer(t, gateway, handshake)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	_, _, resp = dialServer(t, gateway, handshake)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	// The idle connection is closed, which frees its slot
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err = reader.ReadByte()
+	assert.ErrorIs(t, err, io.EOF)
+	assert.Eventually(t, func() bool { return proxy.active.Load() == 0 }, time.Second, 10*time.Millisecond)
+	_, _, resp = dialServer(t, gateway, handshake)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+}
@@ -0,0 +1,342 @@
+package a2aopenai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/configschema"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/upstream"
+)
+
+const (
+	pluginName = "a2a-openai"
+	configKey  = "a2a_openai_config"
+)
+
+const (
+	agentCardPath  = "/.well-known/agent-card.json"
+	defaultTimeout = 120 * time.Second
+)
+
+type registerer string
+
+// module holds the handlers of the plugin
+var module = registerer(pluginName)
+var logger = logging.New(pluginName)
+
+// Module registers a2a-openai as standalone plugin or as part of the gateway plugin
+var Module = pluginkit.Module{Name: pluginName, ConfigKey: configKey, Factory: module.registerHandlers, Logger: &logger}
+
+func init() {
+	logger.Debug("loaded")
+}
+
+// config lists the OpenAI-compatible backends exposed as A2A agents
+type config struct {
+	Agents  []LLMAgent      `json:"agents"`
+	Logging logging.Options `json:"logging,omitempty"`
+	// Upstream tunes the connection pool of the requests to the OpenAI-compatible backends
+	Upstream *upstream.Config `json:"upstream,omitempty"`
+}
+
+// LLMAgent exposes a model of an OpenAI-compatible endpoint as an A2A agent at Path.
+// The API key is read from the environment variable APIKeyEnv if set, so it can be kept
+// out of krakend.json.
+type LLMAgent struct {
+	Path         string `json:"path"`
+	URL          string `json:"url"`
+	Model        string `json:"model"`
+	APIKey       string `json:"api_key"`
+	APIKeyEnv    string `json:"api_key_env"`
+	SystemPrompt string `json:"system_prompt"`
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	Timeout      string `json:"timeout"`
+	// TLS and Proxy override the upstream configuration for the requests to URL
+	TLS   *upstream.TLSConfig `json:"tls,omitempty"`
+	Proxy string              `json:"proxy,omitempty"`
+
+	timeout time.Duration
+}
+
+func (r registerer) registerHandlers(_ context.Context, extra map[string]interface{}, handler http.Handler) (http.Handler, error) {
+	var cfg config
+	if err := parseConfig(extra, &cfg); err != nil {
+		return nil, err
+	}
+	if err := logging.Configure(pluginName, cfg.Logging); err != nil {
+		return nil, fmt.Errorf("invalid logging configuration: %w", err)
+	}
+	transport, err := upstream.NewTransport(cfg.Upstream)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream configuration: %w", err)
+	}
+	agents := make(map[string]*LLMAgent, len(cfg.Agents))
+	hosts := make(map[string]upstream.HostConfig)
+	for i := range cfg.Agents {
+		agent := &cfg.Agents[i]
+		if err := agent.validate(); err != nil {
+			return nil, fmt.Errorf("invalid agent '%s': %w", agent.Path, err)
+		}
+		if _, ok := agents[agent.Path]; ok {
+			return nil, fmt.Errorf("duplicate agent path '%s'", agent.Path)
+		}
+		agents[agent.Path] = agent
+		if agent.TLS != nil || agent.Proxy != "" {
+			parsed, _ := url.Parse(agent.URL)
+			hostConfig := upstream.HostConfig{TLS: agent.TLS, Proxy: agent.Proxy}
+			if other, ok := hosts[parsed.Host]; ok && !reflect.DeepEqual(other, hostConfig) {
+				return nil, fmt.Errorf("invalid agent '%s': another agent configures different tls or proxy for host %s", agent.Path, parsed.Host)
+			}
+			hosts[parsed.Host] = hostConfig
+			if agent.TLS != nil && agent.TLS.InsecureSkipVerify {
+				logger.Warning(fmt.Sprintf("agent %s: tls.insecure_skip_verify is set, certificates of %s are NOT verified and connections to it can be intercepted", agent.Path, parsed.Host))
+			}
+		}
+	}
+	if err := transport.SetHosts(hosts); err != nil {
+		return nil, fmt.Errorf("invalid upstream configuration: %w", err)
+	}
+	if cfg.Upstream != nil && cfg.Upstream.TLS != nil && cfg.Upstream.TLS.InsecureSkipVerify {
+		logger.Warning("upstream.tls.insecure_skip_verify is set: certificates of all backends are NOT verified and connections to them can be intercepted")
+	}
+	upstreamClient = &http.Client{Transport: transport}
+
+	pluginkit.SetFeatures(pluginName, nil)
+	logger.Info(fmt.Sprintf("exposing %d OpenAI models as A2A agents", len(agents)))
+	return http.HandlerFunc(handleRequest(agents, handler)), nil
+}
+
+func parseConfig(extra map[string]interface{}, cfg *config) error {
+	if err := configschema.Validate(pluginName, extra[configKey]); err != nil {
+		return fmt.Errorf("invalid extra_config.%s: %w", configKey, err)
+	}
+	return pluginkit.DecodeConfig(extra, configKey, cfg)
+}
+
+// validate checks the agent configuration and resolves the API key and timeout
+func (a *LLMAgent) validate() error {
+	a.Path = strings.TrimSuffix(a.Path, "/")
+	if !strings.HasPrefix(a.Path, "/") {
+		return errors.New("path must start with '/'")
+	}
+	if parsed, err := url.Parse(a.URL); err != nil || parsed.Host == "" {
+		return fmt.Errorf("invalid url '%s'", a.URL)
+	}
+	a.URL = strings.TrimSuffix(a.URL, "/")
+	if a.Model == "" {
+		return errors.New("model is required")
+	}
+	if a.APIKeyEnv != "" {
+		a.APIKey = os.Getenv(a.APIKeyEnv)
+		if a.APIKey == "" {
+			return fmt.Errorf("environment variable %s is not set", a.APIKeyEnv)
+		}
+	}
+	if a.Name == "" {
+		a.Name = a.Model
+	}
+	a.timeout = defaultTimeout
+	if a.Timeout != "" {
+		var err error
+		if a.timeout, err = time.ParseDuration(a.Timeout); err != nil || a.timeout <= 0 {
+			return fmt.Errorf("invalid timeout '%s'", a.Timeout)
+		}
+	}
+	return nil
+}
+
+func handleRequest(agents map[string]*LLMAgent, handler http.Handler) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet && strings.HasSuffix(req.URL.Path, agentCardPath) {
+			if agent, ok := agents[strings.TrimSuffix(req.URL.Path, agentCardPath)]; ok {
+				serveAgentCard(w, req, agent)
+				return
+			}
+		}
+		if req.Method == http.MethodPost {
+			if agent, ok := agents[strings.TrimSuffix(req.URL.Path, "/")]; ok {
+				serveJSONRPC(w, req, agent)
+				return
+			}
+		}
+
+		// Pass through all other requests
+		handler.ServeHTTP(w, req)
+	}
+}
+
+// jsonRPCRequest is the envelope of an A2A request; params are decoded per method
+type jsonRPCRequest struct {
+	Jsonrpc string          `json:"jsonrpc"`
+	Id      interface{}     `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// jsonRPCResponse is a JSON-RPC success response carrying any A2A result
+type jsonRPCResponse struct {
+	Jsonrpc string      `json:"jsonrpc"`
+	Id      interface{} `json:"id"`
+	Result  interface{} `json:"result"`
+}
+
+// serveJSONRPC dispatches an A2A JSON-RPC request. The bridge keeps no state: every task
+// completes within its request, so task queries are answered with task not found.
+func serveJSONRPC(w http.ResponseWriter, req *http.Request, agent *LLMAgent) {
+	var rpcReq jsonRPCRequest
+	if err := json.NewDecoder(req.Body).Decode(&rpcReq); err != nil {
+		writeJSONRPCError(w, nil, a2aerrors.CodeJSONParse, "request body is not valid JSON")
+		return
+	}
+	if rpcReq.Jsonrpc != "2.0" {
+		writeJSONRPCError(w, rpcReq.Id, a2aerrors.CodeInvalidRequest, "jsonrpc must be exactly \"2.0\"")
+		return
+	}
+
+	switch rpcReq.Method {
+	case "message/send", "message/stream":
+		var params models.MessageSendParams
+		if err := json.Unmarshal(rpcReq.Params, &params); err != nil {
+			writeJSONRPCError(w, rpcReq.Id, a2aerrors.CodeInvalidParams, "invalid message params")
+			return
+		}
+		if err := params.Validate(); err != nil {
+			writeJSONRPCError(w, rpcReq.Id, a2aerrors.CodeInvalidParams, err.Error())
+			return
+		}
+		text := messageText(params.Message)
+		if text == "" {
+			writeJSONRPCError(w, rpcReq.Id, a2aerrors.CodeInvalidParams, "message must contain a text part")
+			return
+		}
+		if rpcReq.Method == "message/stream" {
+			streamMessage(w, req, agent, rpcReq.Id, params.Message, text)
+			return
+		}
+		sendMessage(w, req, agent, rpcReq.Id, params.Message, text)
+	case "tasks/get", "tasks/cancel", "tasks/resubscribe":
+		writeJSONRPCError(w, rpcReq.Id, a2aerrors.CodeTaskNotFound, "task not found, tasks are not kept after completion")
+	default:
+		writeJSONRPCError(w, rpcReq.Id, a2aerrors.CodeMethodNotFound, fmt.Sprintf("method '%s' is not supported", rpcReq.Method))
+	}
+}
+
+// sendMessage answers message/send with a completed task carrying the model's reply as artifact
+func sendMessage(w http.ResponseWriter, req *http.Request, agent *LLMAgent, id interface{}, message models.Message, text string) {
+	log := logging.FromRequest(logger, req)
+	reply, err := agent.complete(req.Context(), text)
+	if err != nil {
+		log.Error(fmt.Sprintf("chat completion for agent %s failed: %v", agent.Path, err))
+		writeJSONRPCError(w, id, a2aerrors.CodeInternal, "model request failed")
+		return
+	}
+
+	task := newTask(message)
+	task.Status = taskStatus(models.TaskStateCompleted)
+	task.Artifacts = []models.Artifact{textArtifact(newID(), reply)}
+	writeJSONRPCResult(w, id, task)
+}
+
+// serveAgentCard describes the model as an A2A agent reachable at the path of the request
+func serveAgentCard(w http.ResponseWriter, req *http.Request, agent *LLMAgent) {
+	log := logging.FromRequest(logger, req)
+	scheme := "http"
+	if proto := req.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	agentURL := fmt.Sprintf("%s://%s%s", scheme, req.Host, agent.Path)
+	description := agent.Description
+	if description == "" {
+		description = fmt.Sprintf("The %s model, exposed as an A2A agent", agent.Model)
+	}
+	streaming := true
+	card := models.AgentCard{
+		Name:               agent.Name,
+		Description:        description,
+		Url:                agentURL,
+		Version:            "1.0.0",
+		ProtocolVersion:    models.ProtocolVersion,
+		PreferredTransport: "JSONRPC",
+		Capabilities:       models.AgentCapabilities{Streaming: &streaming},
+		DefaultInputModes:  []string{"text/plain"},
+		DefaultOutputModes: []string{"text/plain"},
+		Skills: []models.AgentSkill{{
+			Id:          agent.Model,
+			Name:        agent.Name,
+			Description: description,
+			Tags:        []string{"llm"},
+		}},
+	}
+
+	if err := pluginkit.WriteJSON(w, http.StatusOK, card); err != nil {
+		log.Error("failed to write response:", err)
+	}
+}
+
+// messageText joins the text parts of a message; other parts cannot be sent to the model
+func messageText(message models.Message) string {
+	var texts []string
+	for _, part := range message.Parts {
+		if text, ok := models.PartText(part); ok && text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// newTask creates a task in the context of the message, starting a new context if it has none
+func newTask(message models.Message) models.SendMessageSuccessResponseResult {
+	contextID := newID()
+	if message.ContextId != nil && *message.ContextId != "" {
+		contextID = *message.ContextId
+	}
+	taskID := newID()
+	message.ContextId = &contextID
+	message.TaskId = &taskID
+	return models.SendMessageSuccessResponseResult{
+		Id:        taskID,
+		Kind:      "task",
+		ContextId: contextID,
+		History:   []models.Message{message},
+	}
+}
+
+func taskStatus(state models.TaskState) models.TaskStatus {
+	timestamp := time.Now().UTC().Format(time.RFC3339)
+	return models.TaskStatus{State: state, Timestamp: &timestamp}
+}
+
+func textArtifact(id, text string) models.Artifact {
+	return models.Artifact{
+		ArtifactId: id,
+		Parts:      []models.ArtifactPartsElem{models.TextPart{Kind: "text", Text: text}},
+	}
+}
+
+func writeJSONRPCResult(w http.ResponseWriter, id interface{}, result interface{}) {
+	if err := pluginkit.WriteJSON(w, http.StatusOK, jsonRPCResponse{Jsonrpc: "2.0", Id: id, Result: result}); err != nil {
+		logger.Error("failed to write response:", err)
+	}
+}
+
+// writeJSONRPCError writes a JSON-RPC error response. Like A2A agents, the bridge reports
+// JSON-RPC errors with 200 OK; the gateway maps the error code to an HTTP status.
+func writeJSONRPCError(w http.ResponseWriter, id interface{}, code int, message string) {
+	rpcErr := models.JSONRPCErrorResponseError{Code: code, Message: message}
+	if err := pluginkit.WriteJSONRPCError(w, http.StatusOK, id, rpcErr); err != nil {
+		logger.Error("failed to write response:", err)
+	}
+}
@@ -0,0 +1,269 @@
+package a2aopenai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/upstream"
+	"github.com/stretchr/testify/assert"
+)
+
+const sendRequest = `{"jsonrpc":"2.0","id":"req-1","method":"message/send","params":{"message":{"kind":"message","messageId":"m1","contextId":"ctx-1","role":"user","parts":[{"kind":"text","text":"What is the capital of France?"}]}}}`
+
+// newLLM serves OpenAI chat completions, streamed if requested, and records the last request
+func newLLM(t *testing.T, received *models.OpenAIRequest) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer sk-test", r.Header.Get("Authorization"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(received))
+
+		if received.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, delta := range []string{"Paris", " is the capital."} {
+				fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", delta)
+			}
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"c1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"Paris."},"finish_reason":"stop"}]}`)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newBridge(t *testing.T, llmURL string, next http.Handler) http.Handler {
+	extraConfig := map[string]interface{}{
+		"a2a_openai_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{
+					"path":          "/llm/gpt",
+					"url":           llmURL + "/v1",
+					"model":         "gpt-4o-mini",
+					"api_key":       "sk-test",
+					"system_prompt": "Answer briefly.",
+				},
+			},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, next)
+	assert.NoError(t, err)
+	return handler
+}
+
+func post(handler http.Handler, path, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+	return rec
+}
+
+func TestRegisterHandlers_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		agent map[string]interface{}
+	}{
+		{name: "relative path", agent: map[string]interface{}{"path": "llm", "url": "http://llm:8080", "model": "m"}},
+		{name: "invalid url", agent: map[string]interface{}{"path": "/llm", "url": "llm", "model": "m"}},
+		{name: "missing model", agent: map[string]interface{}{"path": "/llm", "url": "http://llm:8080"}},
+		{name: "unset api key env", agent: map[string]interface{}{"path": "/llm", "url": "http://llm:8080", "model": "m", "api_key_env": "A2A_OPENAI_TEST_UNSET_KEY"}},
+		{name: "invalid timeout", agent: map[string]interface{}{"path": "/llm", "url": "http://llm:8080", "model": "m", "timeout": "soon"}},
+		{name: "invalid proxy", agent: map[string]interface{}{"path": "/llm", "url": "https://api.openai.com/v1", "model": "m", "proxy": "egress-proxy:3128"}},
+		{name: "client cert without key", agent: map[string]interface{}{"path": "/llm", "url": "https://llm:8443", "model": "m", "tls": map[string]interface{}{"cert_file": "client.pem"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extraConfig := map[string]interface{}{
+				"a2a_openai_config": map[string]interface{}{"agents": []interface{}{tt.agent}},
+			}
+			_, err := module.registerHandlers(context.Background(), extraConfig, http.NotFoundHandler())
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestMessageSend(t *testing.T) {
+	var received models.OpenAIRequest
+	handler := newBridge(t, newLLM(t, &received).URL, http.NotFoundHandler())
+
+	rec := post(handler, "/llm/gpt/", sendRequest)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "gpt-4o-mini", received.Model)
+	assert.Equal(t, []models.OpenAIMessage{
+		{Role: "system", Content: "Answer briefly."},
+		{Role: "user", Content: "What is the capital of France?"},
+	}, received.Messages)
+
+	var resp models.SendMessageSuccessResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "req-1", resp.Id)
+	assert.Equal(t, "task", resp.Result.Kind)
+	assert.Equal(t, "ctx-1", resp.Result.ContextId)
+	assert.Equal(t, models.TaskStateCompleted, resp.Result.Status.State)
+	assert.Len(t, resp.Result.History, 1)
+	if assert.Len(t, resp.Result.Artifacts, 1) {
+		assert.Equal(t, models.TextPart{Kind: "text", Text: "Paris."}, resp.Result.Artifacts[0].Parts[0])
+	}
+}
+
+func TestMessageStream(t *testing.T) {
+	var received models.OpenAIRequest
+	handler := newBridge(t, newLLM(t, &received).URL, http.NotFoundHandler())
+
+	rec := post(handler, "/llm/gpt", strings.Replace(sendRequest, "message/send", "message/stream", 1))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	assert.True(t, received.Stream)
+
+	var kinds []string
+	var text strings.Builder
+	var final map[string]interface{}
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event struct {
+			Id     string                 `json:"id"`
+			Result map[string]interface{} `json:"result"`
+		}
+		assert.NoError(t, json.Unmarshal([]byte(data), &event))
+		assert.Equal(t, "req-1", event.Id)
+		kinds = append(kinds, event.Result["kind"].(string))
+		if artifact, ok := event.Result["artifact"].(map[string]interface{}); ok {
+			text.WriteString(artifact["parts"].([]interface{})[0].(map[string]interface{})["text"].(string))
+		}
+		final = event.Result
+	}
+
+	assert.Equal(t, []string{"task", "artifact-update", "artifact-update", "status-update"}, kinds)
+	assert.Equal(t, "Paris is the capital.", text.String())
+	assert.Equal(t, true, final["final"])
+	assert.Equal(t, "completed", final["status"].(map[string]interface{})["state"])
+}
+
+func TestJSONRPCErrors(t *testing.T) {
+	var received models.OpenAIRequest
+	handler := newBridge(t, newLLM(t, &received).URL, http.NotFoundHandler())
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+	}{
+		{name: "malformed json", body: `{`, wantCode: a2aerrors.CodeJSONParse},
+		{name: "wrong version", body: `{"jsonrpc":"1.0","id":1,"method":"message/send"}`, wantCode: a2aerrors.CodeInvalidRequest},
+		{name: "no text part", body: `{"jsonrpc":"2.0","id":1,"method":"message/send","params":{"message":{"kind":"message","messageId":"m1","role":"user","parts":[]}}}`, wantCode: a2aerrors.CodeInvalidParams},
+		{name: "task query", body: `{"jsonrpc":"2.0","id":1,"method":"tasks/get","params":{"id":"t1"}}`, wantCode: a2aerrors.CodeTaskNotFound},
+		{name: "unknown method", body: `{"jsonrpc":"2.0","id":1,"method":"agent/dance"}`, wantCode: a2aerrors.CodeMethodNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := post(handler, "/llm/gpt", tt.body)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			rpcErr, ok := a2aerrors.Parse(rec.Body.Bytes())
+			if assert.True(t, ok) {
+				assert.Equal(t, tt.wantCode, rpcErr.Code)
+			}
+		})
+	}
+}
+
+func TestMessageSend_UpstreamError(t *testing.T) {
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"rate limited"}}`, http.StatusTooManyRequests)
+	}))
+	defer llm.Close()
+	handler := newBridge(t, llm.URL, http.NotFoundHandler())
+
+	rec := post(handler, "/llm/gpt", sendRequest)
+
+	rpcErr, ok := a2aerrors.Parse(rec.Body.Bytes())
+	if assert.True(t, ok) {
+		assert.Equal(t, a2aerrors.CodeInternal, rpcErr.Code)
+	}
+}
+
+func TestAgentCard(t *testing.T) {
+	handler := newBridge(t, "http://llm:8080", http.NotFoundHandler())
+
+	req := httptest.NewRequest(http.MethodGet, "/llm/gpt/.well-known/agent-card.json", nil)
+	req.Host = "gateway.example.com"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var card models.AgentCard
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &card))
+	assert.Equal(t, "http://gateway.example.com/llm/gpt", card.Url)
+	assert.Equal(t, "gpt-4o-mini", card.Name)
+	assert.True(t, *card.Capabilities.Streaming)
+}
+
+func TestPassthrough(t *testing.T) {
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	})
+	handler := newBridge(t, "http://llm:8080", next)
+
+	post(handler, "/other-agent", sendRequest)
+
+	assert.True(t, called)
+}
+
+func BenchmarkMessageSend(b *testing.B) {
+	llm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"c1","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"Paris."},"finish_reason":"stop"}]}`)
+	}))
+	defer llm.Close()
+	extraConfig := map[string]interface{}{
+		"a2a_openai_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"path": "/llm/gpt", "url": llm.URL + "/v1", "model": "gpt-4o-mini"},
+			},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, http.NotFoundHandler())
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		post(handler, "/llm/gpt/", sendRequest)
+	}
+}
+
+func TestRegisterHandlers_Upstream(t *testing.T) {
+	extraConfig := map[string]interface{}{
+		"a2a_openai_config": map[string]interface{}{
+			"agents":   []interface{}{},
+			"upstream": map[string]interface{}{"max_conns_per_host": 8},
+		},
+	}
+	_, err := module.registerHandlers(context.Background(), extraConfig, http.NotFoundHandler())
+	assert.NoError(t, err)
+	if transport, ok := upstreamClient.Transport.(*upstream.Transport); assert.True(t, ok) {
+		assert.Equal(t, 8, transport.ForHost("llm:8080").MaxConnsPerHost)
+	}
+
+	extraConfig["a2a_openai_config"].(map[string]interface{})["upstream"] = map[string]interface{}{"dial_timeout": "soon"}
+	_, err = module.registerHandlers(context.Background(), extraConfig, http.NotFoundHandler())
+	assert.ErrorContains(t, err, "invalid upstream configuration")
+}
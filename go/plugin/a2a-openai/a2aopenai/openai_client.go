@@ -0,0 +1,124 @@
+package a2aopenai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/streamconv"
+	"github.com/go-http-utils/headers"
+	"github.com/google/uuid"
+)
+
+const maxErrorBodyBytes = 1024
+
+// upstreamClient is shared by all agents and built from the upstream configuration; timeouts are
+// set per request
+var upstreamClient = &http.Client{}
+
+func newID() string {
+	return uuid.New().String()
+}
+
+// complete sends text to the model and returns its reply
+func (a *LLMAgent) complete(ctx context.Context, text string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.timeout)
+	defer cancel()
+
+	resp, err := a.post(ctx, text, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var completion models.OpenAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		return "", fmt.Errorf("invalid chat completion response: %w", err)
+	}
+	if len(completion.Choices) == 0 {
+		return "", fmt.Errorf("chat completion response has no choices")
+	}
+	return completion.Choices[0].Message.Content, nil
+}
+
+// post sends a chat completion request and checks the response status
+func (a *LLMAgent) post(ctx context.Context, text string, stream bool) (*http.Response, error) {
+	var messages []models.OpenAIMessage
+	if a.SystemPrompt != "" {
+		messages = append(messages, models.OpenAIMessage{Role: "system", Content: a.SystemPrompt})
+	}
+	messages = append(messages, models.OpenAIMessage{Role: "user", Content: text})
+	body, err := json.Marshal(models.OpenAIRequest{Model: a.Model, Messages: messages, Stream: stream})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.URL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set(headers.ContentType, "application/json")
+	if a.APIKey != "" {
+		req.Header.Set(headers.Authorization, "Bearer "+a.APIKey)
+	}
+	resp, err := upstreamClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(errBody)))
+	}
+	return resp, nil
+}
+
+// streamMessage answers message/stream with server-sent events: the submitted task, an artifact
+// update per streamed delta and a final status update. Failures after the first event are
+// reported as a failed task, since the JSON-RPC response has already started.
+func streamMessage(w http.ResponseWriter, req *http.Request, agent *LLMAgent, id interface{}, message models.Message, text string) {
+	log := logging.FromRequest(logger, req)
+	ctx, cancel := context.WithTimeout(req.Context(), agent.timeout)
+	defer cancel()
+
+	resp, err := agent.post(ctx, text, true)
+	if err != nil {
+		log.Error(fmt.Sprintf("streaming chat completion for agent %s failed: %v", agent.Path, err))
+		writeJSONRPCError(w, id, a2aerrors.CodeInternal, "model request failed")
+		return
+	}
+	defer resp.Body.Close()
+
+	w.Header().Set(headers.ContentType, "text/event-stream")
+	w.Header().Set(headers.CacheControl, "no-cache")
+	w.WriteHeader(http.StatusOK)
+	events := streamconv.NewA2AWriter(w, id)
+
+	task := newTask(message)
+	task.Status = taskStatus(models.TaskStateWorking)
+	if err := events.Write(task); err != nil {
+		log.Error("failed to write stream event:", err)
+		return
+	}
+
+	conv := &streamconv.ToA2A{TaskID: task.Id, ContextID: task.ContextId, ArtifactID: newID()}
+	chunks := streamconv.NewOpenAIReader(resp.Body)
+	state := models.TaskStateCompleted
+	if err := streamconv.CopyToA2A(events, chunks, conv); err != nil {
+		log.Error(fmt.Sprintf("streaming chat completion for agent %s failed: %v", agent.Path, err))
+		state = models.TaskStateFailed
+	}
+	if chunks.Skipped > 0 {
+		log.Warning(fmt.Sprintf("skipped %d invalid chat completion chunks of agent %s", chunks.Skipped, agent.Path))
+	}
+	if err := events.Write(conv.Final(taskStatus(state))); err != nil {
+		log.Error("failed to write stream event:", err)
+	}
+}
@@ -0,0 +1,28 @@
+// Package main is the standalone a2a-openai plugin, implemented by package a2aopenai
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/agentic-layer/agent-gateway-krakend/plugin/a2a-openai/a2aopenai"
+)
+
+type registerer string
+
+// HandlerRegisterer is the symbol KrakenD looks up to register plugins
+var HandlerRegisterer = registerer(a2aopenai.Module.Name)
+
+func main() {}
+
+// RegisterHandlers registers the plugin with KrakenD
+func (r registerer) RegisterHandlers(f func(
+	name string,
+	handler func(context.Context, map[string]interface{}, http.Handler) (http.Handler, error),
+)) {
+	a2aopenai.Module.RegisterHandlers(f)
+}
+
+func (r registerer) RegisterLogger(v interface{}) {
+	a2aopenai.Module.RegisterLogger(v)
+}
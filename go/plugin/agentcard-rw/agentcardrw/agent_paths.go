@@ -0,0 +1,35 @@
+package agentcardrw
+
+import (
+	"fmt"
+	"strings"
+)
+
+// agentPaths maps the KrakenD endpoint paths of agents to the paths under which they are
+// advertised, for routes that a proxy in front of the gateway exposes under other paths
+type agentPaths map[string]string
+
+func newAgentPaths(paths map[string]string) (agentPaths, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	p := make(agentPaths, len(paths))
+	for agentPath, externalPath := range paths {
+		if !strings.HasPrefix(agentPath, "/") || agentPath == "/" {
+			return nil, fmt.Errorf("agent path '%s' must start with / and name an agent", agentPath)
+		}
+		if !strings.HasPrefix(externalPath, "/") {
+			return nil, fmt.Errorf("external path '%s' of agent path '%s' must start with /", externalPath, agentPath)
+		}
+		p[strings.TrimSuffix(agentPath, "/")] = strings.TrimSuffix(externalPath, "/")
+	}
+	return p, nil
+}
+
+// external returns the path under which an agent is advertised, its KrakenD path unless mapped
+func (p agentPaths) external(agentPath string) string {
+	if externalPath, ok := p[agentPath]; ok {
+		return externalPath
+	}
+	return agentPath
+}
@@ -0,0 +1,53 @@
+package agentcardrw
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterHandlers_InvalidAgentPaths(t *testing.T) {
+	for _, paths := range []map[string]interface{}{
+		{"weather-agent": "/weather"},
+		{"/": "/weather"},
+		{"/weather-agent": "weather"},
+	} {
+		extra := map[string]interface{}{configKey: map[string]interface{}{"agent_paths": paths}}
+		_, err := module.registerHandlers(context.Background(), extra, http.NotFoundHandler())
+		assert.Error(t, err, paths)
+	}
+}
+
+func TestAgentCard_MappedAgentPaths(t *testing.T) {
+	backend := newTestHelper(t).createJSONBackend(`{"url":"http://weather:8000","additionalInterfaces":[{"transport":"HTTP+JSON","url":"http://weather:8000"}]}`)
+	extra := map[string]interface{}{configKey: map[string]interface{}{
+		"external_urls": map[string]interface{}{"/internal/weather-v2": "https://weather.example.com"},
+		"agent_paths":   map[string]interface{}{"/internal/weather-v2/": "/weather/", "/internal/news": "/"},
+	}}
+	handler, err := module.registerHandlers(context.Background(), extra, backend)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		path    string
+		wantURL string
+	}{
+		{path: "/internal/weather-v2", wantURL: "https://weather.example.com/weather"},
+		{path: "/internal/news", wantURL: "https://" + testGatewayHost},
+		{path: "/internal/sports", wantURL: "https://" + testGatewayHost + "/internal/sports"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := newTestHelper(t).makeRequest(handler, http.MethodGet, tt.path+testAgentCardPath, testGatewayHost, testHTTPSProtocol)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			var card map[string]interface{}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &card))
+			assert.Equal(t, tt.wantURL, card["url"])
+			assert.Equal(t, tt.wantURL, card["additionalInterfaces"].([]interface{})[0].(map[string]interface{})["url"])
+		})
+	}
+}
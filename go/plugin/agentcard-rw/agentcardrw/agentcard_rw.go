@@ -0,0 +1,540 @@
+package agentcardrw
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/bufpool"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/capture"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/configschema"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/jsonedit"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/netclass"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/timing"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/trustedproxy"
+)
+
+const (
+	pluginName = "agentcard-rw"
+	configKey  = "agentcard_rw_config"
+
+	// defaultMaxCardSize limits how much of a backend response is buffered to rewrite the card
+	defaultMaxCardSize = 8 << 20
+)
+
+// managedHeaders describe the body of the backend response and are set for the rewritten card
+var managedHeaders = []string{"Content-Length", "Content-Encoding"}
+
+// defaultExcludeHeaders are backend headers that rewriting invalidates
+var defaultExcludeHeaders = []string{"ETag", "Content-MD5", "Digest", "Content-Digest", "Repr-Digest"}
+
+// excludedHeaders returns the configured or default excluded headers and the managed headers
+func excludedHeaders(configured []string) []string {
+	if configured == nil {
+		configured = defaultExcludeHeaders
+	}
+	return append(append([]string{}, managedHeaders...), configured...)
+}
+
+type config struct {
+	ExternalURL       string            `json:"external_url,omitempty"`
+	ExternalURLs      map[string]string `json:"external_urls,omitempty"`
+	AllowedTransports []string          `json:"allowed_transports,omitempty"`
+	TrustedProxies    []string          `json:"trusted_proxies,omitempty"`
+	URLFields         []string          `json:"url_fields,omitempty"`
+	StripFields       []string          `json:"strip_fields,omitempty"`
+	WebSockets        []WebSocketRoute  `json:"websockets,omitempty"`
+	Validation        string            `json:"validation,omitempty"`
+	Signatures        *SignatureConfig  `json:"signatures,omitempty"`
+	MaxCardSize       int               `json:"max_card_size,omitempty"`
+	ExcludeHeaders    []string          `json:"exclude_headers,omitempty"`
+	OnError           string            `json:"on_error,omitempty"`
+	OnErrorRetries    int               `json:"on_error_retries,omitempty"`
+	AgentPaths        map[string]string `json:"agent_paths,omitempty"`
+	InternalHosts     []string          `json:"internal_hosts,omitempty"`
+	Logging           logging.Options   `json:"logging,omitempty"`
+}
+
+// settings holds the validated configuration; unconfigured features are nil
+type settings struct {
+	urls        *externalURLs
+	proxies     *trustedproxy.Allowlist
+	transports  transportSet
+	urlFields   urlFields
+	stripFields stripFields
+	websockets  *websocketRoutes
+	validator   *cardValidator
+	signer      *cardSigner
+	maxCardSize int
+	// excludeHeaders are the backend headers not copied to rewritten cards
+	excludeHeaders []string
+	onError        *errorPolicy
+	paths          agentPaths
+	internal       *netclass.Classifier
+}
+
+type registerer string
+
+// module holds the handlers of the plugin
+var module = registerer(pluginName)
+var logger = logging.New(pluginName)
+
+// Module registers agentcard-rw as standalone plugin or as part of the gateway plugin
+var Module = pluginkit.Module{Name: pluginName, ConfigKey: configKey, Factory: module.registerHandlers, Logger: &logger}
+
+func init() {
+	logger.Info("loaded")
+}
+
+// gatewayURL returns the external gateway URL for an agent: a configured external URL or the URL
+// the request was sent to, described by forwarded headers if the peer is a trusted proxy
+func (s settings) gatewayURL(req *http.Request, agentPath string) (string, error) {
+	if externalURL, ok := s.urls.lookup(agentPath); ok {
+		return externalURL, nil
+	}
+	if s.proxies != nil && !s.proxies.Trusts(req) {
+		return directGatewayURL(req), nil
+	}
+	return getGatewayURL(req)
+}
+
+func parseConfig(extra map[string]interface{}) (config, error) {
+	var cfg config
+	if err := configschema.Validate(pluginName, extra[configKey]); err != nil {
+		return cfg, fmt.Errorf("invalid extra_config.%s: %w", configKey, err)
+	}
+	err := pluginkit.DecodeConfig(extra, configKey, &cfg)
+	return cfg, err
+}
+
+func (r registerer) registerHandlers(_ context.Context, extra map[string]interface{}, handler http.Handler) (http.Handler, error) {
+	cfg, err := parseConfig(extra)
+	if err != nil {
+		return nil, err
+	}
+	if err := logging.Configure(pluginName, cfg.Logging); err != nil {
+		return nil, fmt.Errorf("invalid logging configuration: %w", err)
+	}
+	var s settings
+	if s.urls, err = newExternalURLs(cfg.ExternalURL, cfg.ExternalURLs); err != nil {
+		return nil, fmt.Errorf("invalid external url configuration: %w", err)
+	}
+	if s.proxies, err = trustedproxy.New(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted_proxies configuration: %w", err)
+	}
+	if s.transports, err = newTransportSet(cfg.AllowedTransports); err != nil {
+		return nil, fmt.Errorf("invalid allowed_transports configuration: %w", err)
+	}
+	if s.urlFields, err = newURLFields(cfg.URLFields); err != nil {
+		return nil, fmt.Errorf("invalid url_fields configuration: %w", err)
+	}
+	if s.stripFields, err = newStripFields(cfg.StripFields); err != nil {
+		return nil, fmt.Errorf("invalid strip_fields configuration: %w", err)
+	}
+	if s.websockets, err = newWebSocketRoutes(cfg.WebSockets); err != nil {
+		return nil, fmt.Errorf("invalid websockets configuration: %w", err)
+	}
+	if s.validator, err = newCardValidator(cfg.Validation); err != nil {
+		return nil, fmt.Errorf("invalid validation configuration: %w", err)
+	}
+	if s.signer, err = newCardSigner(cfg.Signatures); err != nil {
+		return nil, fmt.Errorf("invalid signatures configuration: %w", err)
+	}
+	if cfg.MaxCardSize < 0 {
+		return nil, fmt.Errorf("invalid max_card_size configuration: %d is negative", cfg.MaxCardSize)
+	}
+	s.maxCardSize = cfg.MaxCardSize
+	if s.maxCardSize == 0 {
+		s.maxCardSize = defaultMaxCardSize
+	}
+	s.excludeHeaders = excludedHeaders(cfg.ExcludeHeaders)
+	if s.onError, err = newErrorPolicy(cfg.OnError, cfg.OnErrorRetries); err != nil {
+		return nil, fmt.Errorf("invalid on_error configuration: %w", err)
+	}
+	if s.paths, err = newAgentPaths(cfg.AgentPaths); err != nil {
+		return nil, fmt.Errorf("invalid agent_paths configuration: %w", err)
+	}
+	if s.internal, err = netclass.New(cfg.InternalHosts); err != nil {
+		return nil, fmt.Errorf("invalid internal_hosts configuration: %w", err)
+	}
+
+	pluginkit.SetFeatures(pluginName, enabledFeatures(cfg))
+	logger.Info("plugin initialized successfully")
+	return http.HandlerFunc(r.handleRequest(handler, s)), nil
+}
+
+// enabledFeatures lists the optional features cfg configures, reported at pluginkit.InfoPath
+func enabledFeatures(cfg config) []string {
+	features := []struct {
+		name    string
+		enabled bool
+	}{
+		{"external_url", cfg.ExternalURL != "" || len(cfg.ExternalURLs) > 0},
+		{"trusted_proxies", len(cfg.TrustedProxies) > 0},
+		{"allowed_transports", len(cfg.AllowedTransports) > 0},
+		{"url_fields", len(cfg.URLFields) > 0},
+		{"strip_fields", len(cfg.StripFields) > 0},
+		{"websockets", len(cfg.WebSockets) > 0},
+		{"validation", cfg.Validation != ""},
+		{"signatures", cfg.Signatures != nil},
+		{"on_error", cfg.OnError != ""},
+		{"agent_paths", len(cfg.AgentPaths) > 0},
+		{"internal_hosts", len(cfg.InternalHosts) > 0},
+	}
+	enabled := []string{}
+	for _, feature := range features {
+		if feature.enabled {
+			enabled = append(enabled, feature.name)
+		}
+	}
+	return enabled
+}
+
+func (r registerer) handleRequest(handler http.Handler, s settings) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		log := logging.FromRequest(logger, req)
+
+		// WebSocket handshakes on allowlisted paths are proxied, KrakenD cannot upgrade connections.
+		// KrakenD does not authenticate them either, so routes check origins or credentials.
+		if route, ok := s.websockets.route(req); ok {
+			if route.admit(w, req) {
+				log.Debug(fmt.Sprintf("proxying websocket %s to %s", req.URL.Path, route.proxy.Target()))
+				route.proxy.ServeHTTP(w, req)
+			}
+			return
+		}
+
+		// Check if this is a GET request to an agent card endpoint
+		if req.Method == http.MethodGet && isAgentCardEndpoint(req.URL.Path) {
+			log.Debug("intercepted agent card request:", req.URL.Path)
+
+			// Extract full agent path from request (everything before /.well-known)
+			agentPath := extractAgentPath(req.URL.Path)
+			if agentPath == "" {
+				log.Warning(fmt.Sprintf("cannot extract agent path from: %s - passing through", req.URL.Path))
+				handler.ServeHTTP(w, req)
+				return
+			}
+			serveAgentCard(w, req, handler, s, cardRequest{agentPath: agentPath})
+			return
+		}
+
+		// Authenticated extended cards carry the same URLs as public cards. They are served over
+		// HTTP GET or returned as result of the agent/getAuthenticatedExtendedCard JSON-RPC method.
+		if agentPath, rpc, ok := extendedCardRequest(req); ok {
+			log.Debug("intercepted extended agent card request:", req.URL.Path)
+			serveAgentCard(w, req, handler, s, cardRequest{agentPath: agentPath, extended: true, rpc: rpc})
+			return
+		}
+
+		// Not an agent card endpoint, pass through
+		handler.ServeHTTP(w, req)
+	}
+}
+
+// cardRequest describes an intercepted agent card request
+type cardRequest struct {
+	agentPath string
+	// externalPath is the advertised path of the agent, agentPath unless mapped in agent_paths
+	externalPath string
+	// extended cards differ per user and must not be stored by shared caches
+	extended bool
+	// rpc responses wrap the card in a JSON-RPC result
+	rpc bool
+}
+
+// serveAgentCard forwards an agent card request to the backend and rewrites the card of the response.
+// Backend headers are kept, except the excluded ones that rewriting invalidates. Cards that cannot
+// be transformed are handled according to the on_error policy. The response reports rewriting and
+// backend time in Server-Timing, and the backend is cut off when the latency budget of the client runs out.
+func serveAgentCard(w http.ResponseWriter, req *http.Request, handler http.Handler, s settings, cr cardRequest) {
+	w, req = timing.Start(w, req)
+	log := logging.FromRequest(logger, req)
+
+	req, cancel, err := timing.WithBudget(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer cancel()
+
+	// Get gateway URL, configured external URLs take precedence over request headers
+	gatewayURL, err := s.gatewayURL(req, cr.agentPath)
+	if err != nil {
+		log.Error("cannot determine gateway URL:", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// URLs in the card point to the path under which the agent is advertised
+	cr.externalPath = s.paths.external(cr.agentPath)
+
+	log.Debug(fmt.Sprintf("rewriting URLs for agent path: %s, gateway: %s%s", cr.agentPath, gatewayURL, cr.externalPath))
+
+	// Retried requests are sent with the same body again
+	replay, err := s.onError.replayable(req)
+	if err != nil {
+		http.Error(w, "cannot read request body", http.StatusBadRequest)
+		return
+	}
+
+	var rw *capture.Writer
+	var card *bufpool.Buffer
+	var encoding string
+	var transformErr *transformError
+	defer func() {
+		rw.Release()
+		card.Release()
+	}()
+	for attempt := 0; ; attempt++ {
+		replay()
+
+		// Wrap response writer to capture backend response, releasing the response of a failed attempt
+		rw.Release()
+		rw = capture.New(w, s.maxCardSize)
+
+		// Forward request to backend, including its credentials
+		stop := timing.Upstream(req.Context())
+		handler.ServeHTTP(rw, req)
+		stop()
+
+		// Responses too large to buffer have been passed through unchanged
+		if rw.Passthrough() {
+			log.Warning(fmt.Sprintf("agent card of %s exceeds %d bytes - passed through without rewriting", cr.agentPath, s.maxCardSize))
+			return
+		}
+		if timing.BudgetExceeded(req.Context()) {
+			log.Warning(fmt.Sprintf("agent card of %s not received within the latency budget", cr.agentPath))
+			http.Error(w, timing.ErrBudgetExceeded.Error(), http.StatusGatewayTimeout)
+			return
+		}
+
+		card, encoding, transformErr = transformCard(req, rw, s, cr, gatewayURL)
+		if !s.onError.retry(transformErr, attempt) {
+			break
+		}
+		log.Warning(fmt.Sprintf("cannot transform agent card of %s: %s - retrying", cr.agentPath, transformErr.message))
+	}
+
+	if transformErr != nil {
+		s.onError.handle(w, rw, cr, transformErr, s.excludeHeaders)
+		return
+	}
+
+	// Keep caching, tracing and CORS headers of the backend
+	capture.CopyHeader(w.Header(), rw.Header(), s.excludeHeaders...)
+	cr.setCacheHeaders(w.Header())
+	if encoding != encodingIdentity {
+		w.Header().Set("Content-Encoding", encoding)
+	}
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(card.Bytes()); err != nil {
+		log.Error("failed to write response:", err)
+	}
+}
+
+// setCacheHeaders keeps shared caches from storing extended cards, which differ per user
+func (cr cardRequest) setCacheHeaders(header http.Header) {
+	if cr.extended {
+		header.Set("Cache-Control", "private")
+		header.Add("Vary", "Authorization")
+	}
+}
+
+// transformCard rewrites the card of a captured backend response and compresses it for the client.
+// It returns the rewritten body, which the caller releases, and its content encoding.
+func transformCard(req *http.Request, rw *capture.Writer, s settings, cr cardRequest, gatewayURL string) (*bufpool.Buffer, string, *transformError) {
+	log := logging.FromRequest(logger, req)
+
+	// Only transform successful responses
+	if rw.StatusCode() != http.StatusOK {
+		log.Info(fmt.Sprintf("backend returned non-OK status: %d - returning error", rw.StatusCode()))
+		return nil, "", &transformError{status: rw.StatusCode(), message: "Backend service returned an error", rejected: true}
+	}
+
+	// Decompress gzip and deflate responses before looking into them
+	backendEncoding := rw.Header().Get("Content-Encoding")
+	body, err := decodeBody(rw.Body(), backendEncoding)
+	if err != nil {
+		log.Error(fmt.Sprintf("cannot decode agent card: %s", err))
+		return nil, "", &transformError{status: http.StatusBadGateway, message: "Failed to decode agent card"}
+	}
+
+	// Report JSON-RPC errors with the status from the shared error table
+	if rpcErr, ok := a2aerrors.Parse(body); ok {
+		log.Info(fmt.Sprintf("backend returned JSON-RPC error %d: %s - returning error", rpcErr.Code, rpcErr.Message))
+		return nil, "", &transformError{status: a2aerrors.Lookup(rpcErr.Code).HTTPStatus, message: rpcErr.Message, rejected: true}
+	}
+
+	// Validate content type
+	contentType := rw.Header().Get("Content-Type")
+	if !strings.Contains(contentType, "application/json") {
+		log.Warning(fmt.Sprintf("unexpected content-type: %s", contentType))
+		return nil, "", &transformError{status: http.StatusUnsupportedMediaType, message: "Expected application/json content type"}
+	}
+
+	// Cards are edited in place, which keeps the order of their fields. Signing canonicalizes the
+	// card and needs it unmarshalled.
+	var rewrittenBody *bufpool.Buffer
+	var transformErr *transformError
+	if !s.signer.signs() {
+		rewrittenBody, transformErr = editCard(req, body, s, cr, gatewayURL)
+	} else {
+		rewrittenBody, transformErr = rewriteCard(req, body, s, cr, gatewayURL)
+	}
+	if transformErr != nil {
+		return nil, "", transformErr
+	}
+	defer rewrittenBody.Release()
+
+	log.Debug("transformed agent card URLs to external gateway format")
+
+	// Compress the rewritten card again if the client accepts it
+	encoding := negotiateEncoding(req.Header.Get("Accept-Encoding"), backendEncoding)
+	card := bufpool.Get()
+	if err := encodeBody(card, rewrittenBody.Bytes(), encoding); err != nil {
+		card.Release()
+		log.Error("failed to compress rewritten agent card:", err)
+		return nil, "", &transformError{status: http.StatusInternalServerError, message: "failed to create rewritten agent card"}
+	}
+	return card, encoding, nil
+}
+
+// editCard edits the card of body, or the result of a JSON-RPC response, in place with
+// editAgentCard and returns the edited body, which the caller releases
+func editCard(req *http.Request, body []byte, s settings, cr cardRequest, gatewayURL string) (*bufpool.Buffer, *transformError) {
+	log := logging.FromRequest(logger, req)
+	doc, err := jsonedit.Parse(body)
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to parse agent card: %s", err))
+		return nil, &transformError{status: http.StatusInternalServerError, message: "Failed to parse agent card JSON"}
+	}
+	card := doc.Root()
+	if cr.rpc {
+		card, _ = card.Get("result")
+	}
+	if card.Kind() != jsonedit.Object {
+		if cr.rpc {
+			log.Error("JSON-RPC response has no agent card result")
+		} else {
+			log.Error("failed to parse agent card: not a JSON object")
+		}
+		return nil, &transformError{status: http.StatusInternalServerError, message: "Failed to parse agent card JSON"}
+	}
+
+	// Check the card of the agent against the A2A schema before it is rewritten
+	if violations := s.validator.validate(cr.agentPath, card.Raw()); len(violations) > 0 {
+		return nil, &transformError{status: http.StatusBadGateway, message: "Agent card violates the A2A schema: " + strings.Join(violations, "; "), rejected: true}
+	}
+
+	editAgentCard(doc, card, gatewayURL, cr.externalPath, s)
+	rewritten := bufpool.Get()
+	_, _ = doc.WriteTo(rewritten)
+
+	// Look for internal URLs in the edited card, which is valid JSON like the card it was edited from
+	if edited, err := jsonedit.Parse(rewritten.Bytes()); err == nil {
+		card = edited.Root()
+		if cr.rpc {
+			card, _ = card.Get("result")
+		}
+		if urls := internalURLsOf(card, s.internal, gatewayURL); len(urls) > 0 {
+			log.Warning(fmt.Sprintf("rewritten agent card of %s still contains internal URLs: %s", cr.agentPath, strings.Join(urls, ", ")))
+		}
+	}
+	return rewritten, nil
+}
+
+// rewriteCard unmarshals the card of body, or the result of a JSON-RPC response, rewrites it with
+// rewriteAgentCardMap, signs it and returns the marshalled body, which the caller releases
+func rewriteCard(req *http.Request, body []byte, s settings, cr cardRequest, gatewayURL string) (*bufpool.Buffer, *transformError) {
+	log := logging.FromRequest(logger, req)
+
+	// Parse agent card into map to preserve unknown fields
+	var agentCardMap map[string]interface{}
+	var rpcResp map[string]interface{}
+	var err error
+	if cr.rpc {
+		if err := json.Unmarshal(body, &rpcResp); err == nil {
+			agentCardMap, _ = rpcResp["result"].(map[string]interface{})
+		}
+		if agentCardMap == nil {
+			log.Error("JSON-RPC response has no agent card result")
+			return nil, &transformError{status: http.StatusInternalServerError, message: "Failed to parse agent card JSON"}
+		}
+		if body, err = json.Marshal(agentCardMap); err != nil {
+			return nil, &transformError{status: http.StatusInternalServerError, message: "Failed to parse agent card JSON"}
+		}
+	} else if err := json.Unmarshal(body, &agentCardMap); err != nil {
+		log.Error(fmt.Sprintf("failed to parse agent card: %s", err))
+		return nil, &transformError{status: http.StatusInternalServerError, message: "Failed to parse agent card JSON"}
+	}
+
+	// Check the card of the agent against the A2A schema before it is rewritten
+	if violations := s.validator.validate(cr.agentPath, body); len(violations) > 0 {
+		return nil, &transformError{status: http.StatusBadGateway, message: "Agent card violates the A2A schema: " + strings.Join(violations, "; "), rejected: true}
+	}
+
+	// Rewrite agent card URLs (preserves unknown fields)
+	agentCardMap = rewriteAgentCardMap(agentCardMap, gatewayURL, cr.externalPath, s)
+	if urls := internalURLs(agentCardMap, s.internal, gatewayURL); len(urls) > 0 {
+		log.Warning(fmt.Sprintf("rewritten agent card of %s still contains internal URLs: %s", cr.agentPath, strings.Join(urls, ", ")))
+	}
+
+	// Rewriting invalidates the signatures of the agent, replace them by the gateway's
+	if agentCardMap, err = s.signer.apply(agentCardMap); err != nil {
+		log.Error("failed to sign rewritten agent card:", err)
+		return nil, &transformError{status: http.StatusInternalServerError, message: "failed to create rewritten agent card"}
+	}
+
+	// Marshal rewritten agent card
+	var rewritten interface{} = agentCardMap
+	if cr.rpc {
+		rpcResp["result"] = agentCardMap
+		rewritten = rpcResp
+	}
+	rewrittenBody := bufpool.Get()
+	if err := rewrittenBody.EncodeJSON(rewritten); err != nil {
+		rewrittenBody.Release()
+		log.Error("failed to marshal rewritten agent card:", err)
+		return nil, &transformError{status: http.StatusInternalServerError, message: "failed to create rewritten agent card"}
+	}
+	return rewrittenBody, nil
+}
+
+// getGatewayURL extracts the gateway URL from request headers
+// The RFC 7239 Forwarded header takes precedence over X-Forwarded-Proto, -Host and -Port,
+// which take precedence over the Host header and the TLS state of the request.
+// Returns the full URL scheme + host, or an error if Host header is missing
+func getGatewayURL(req *http.Request) (string, error) {
+	f := parseForwarded(req.Header)
+
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	if proto := strings.ToLower(f.proto); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+
+	host := req.Host
+	if validHost(f.host) {
+		host = f.host
+	}
+	// A forwarded port is added to hosts without one, unless it is the default of the scheme
+	if _, _, err := net.SplitHostPort(host); err != nil && f.port != "" {
+		if !(scheme == "http" && f.port == "80") && !(scheme == "https" && f.port == "443") {
+			host = net.JoinHostPort(strings.Trim(host, "[]"), f.port)
+		}
+	}
+
+	return fmt.Sprintf("%s://%s", scheme, host), nil
+}
@@ -1,13 +1,16 @@
-package main
+package agentcardrw
 
 import (
 	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/timing"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -29,7 +32,7 @@ func newTestHelper(t *testing.T) *testHelper {
 }
 
 func (h *testHelper) createPluginHandler(backend http.HandlerFunc) http.Handler {
-	handler, err := HandlerRegisterer.registerHandlers(context.Background(), map[string]interface{}{}, backend)
+	handler, err := module.registerHandlers(context.Background(), map[string]interface{}{}, backend)
 	if err != nil {
 		h.t.Fatalf("failed to register handler: %v", err)
 	}
@@ -67,7 +70,7 @@ func (h *testHelper) createJSONBackend(jsonData string) http.HandlerFunc {
 // TestPluginRegistration verifies the plugin can be registered
 func TestPluginRegistration(t *testing.T) {
 	var called bool
-	HandlerRegisterer.RegisterHandlers(func(name string, handler func(context.Context, map[string]interface{}, http.Handler) (http.Handler, error)) {
+	Module.RegisterHandlers(func(name string, handler func(context.Context, map[string]interface{}, http.Handler) (http.Handler, error)) {
 		called = true
 		if name != pluginName {
 			t.Errorf("RegisterHandlers called with name %q, want %q", name, pluginName)
@@ -117,7 +120,7 @@ func TestRequestPassThrough(t *testing.T) {
 			})
 
 			// Create the plugin handler
-			handler, err := HandlerRegisterer.registerHandlers(context.Background(), map[string]interface{}{}, backend)
+			handler, err := module.registerHandlers(context.Background(), map[string]interface{}{}, backend)
 			if err != nil {
 				t.Fatalf("failed to register handler: %v", err)
 			}
@@ -174,7 +177,7 @@ func TestAgentCardInterception(t *testing.T) {
 	})
 
 	// Create the plugin handler
-	handler, err := HandlerRegisterer.registerHandlers(context.Background(), map[string]interface{}{}, backend)
+	handler, err := module.registerHandlers(context.Background(), map[string]interface{}{}, backend)
 	if err != nil {
 		t.Fatalf("failed to register handler: %v", err)
 	}
@@ -312,7 +315,7 @@ func TestEmptyAgentNamePassThrough(t *testing.T) {
 	})
 
 	// Create the plugin handler
-	handler, err := HandlerRegisterer.registerHandlers(context.Background(), map[string]interface{}{}, backend)
+	handler, err := module.registerHandlers(context.Background(), map[string]interface{}{}, backend)
 	if err != nil {
 		t.Fatalf("failed to register handler: %v", err)
 	}
@@ -369,7 +372,7 @@ func TestUnknownFieldPreservation(t *testing.T) {
 	})
 
 	// Create the plugin handler
-	handler, err := HandlerRegisterer.registerHandlers(context.Background(), map[string]interface{}{}, backend)
+	handler, err := module.registerHandlers(context.Background(), map[string]interface{}{}, backend)
 	if err != nil {
 		t.Fatalf("failed to register handler: %v", err)
 	}
@@ -545,3 +548,106 @@ func TestGetGatewayURL(t *testing.T) {
 		})
 	}
 }
+
+func TestAgentCard_PassesThroughOversizedCards(t *testing.T) {
+	card := `{"url":"http://weather:8000","description":"` + strings.Repeat("x", 100) + `"}`
+	extra := map[string]interface{}{configKey: map[string]interface{}{"max_card_size": 64}}
+	handler, err := module.registerHandlers(context.Background(), extra, newTestHelper(t).createJSONBackend(card))
+	assert.NoError(t, err)
+
+	rec := newTestHelper(t).makeRequest(handler, http.MethodGet, "/weather-agent"+testAgentCardPath, testGatewayHost, testHTTPSProtocol)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, card, rec.Body.String())
+}
+
+func TestAgentCard_PreservesBackendHeaders(t *testing.T) {
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentTypeJSON)
+		w.Header().Set("Cache-Control", "max-age=300")
+		w.Header().Set("X-Request-Id", "req-1")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("ETag", `"backend-etag"`)
+		w.Header().Set("Content-Length", "42")
+		_, _ = w.Write([]byte(`{"url":"http://weather:8000"}`))
+	})
+
+	tests := []struct {
+		name           string
+		excludeHeaders []interface{}
+		wantHeaders    map[string]string
+	}{
+		{
+			name: "default excludes",
+			wantHeaders: map[string]string{
+				"Cache-Control": "max-age=300", "X-Request-Id": "req-1", "Access-Control-Allow-Origin": "*", "Etag": "", "Content-Length": "",
+			},
+		},
+		{
+			name:           "configured excludes",
+			excludeHeaders: []interface{}{"x-request-id"},
+			wantHeaders: map[string]string{
+				"Cache-Control": "max-age=300", "X-Request-Id": "", "Etag": `"backend-etag"`, "Content-Length": "",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extra := map[string]interface{}{}
+			if tt.excludeHeaders != nil {
+				extra[configKey] = map[string]interface{}{"exclude_headers": tt.excludeHeaders}
+			}
+			handler, err := module.registerHandlers(context.Background(), extra, backend)
+			assert.NoError(t, err)
+
+			rec := newTestHelper(t).makeRequest(handler, http.MethodGet, "/weather-agent"+testAgentCardPath, testGatewayHost, testHTTPSProtocol)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, contentTypeJSON, rec.Header().Get("Content-Type"))
+			for name, want := range tt.wantHeaders {
+				assert.Equal(t, want, rec.Header().Get(name), name)
+			}
+		})
+	}
+}
+
+func TestAgentCard_ServerTimingAndLatencyBudget(t *testing.T) {
+	card := `{"url":"http://weather:8000"}`
+	slowBackend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+			w.Header().Set("Content-Type", contentTypeJSON)
+			_, _ = w.Write([]byte(card))
+		}
+	})
+
+	tests := []struct {
+		name       string
+		backend    http.HandlerFunc
+		budget     string
+		wantStatus int
+	}{
+		{name: "no budget", backend: newTestHelper(t).createJSONBackend(card), wantStatus: http.StatusOK},
+		{name: "budget exceeded", backend: slowBackend, budget: "50ms", wantStatus: http.StatusGatewayTimeout},
+		{name: "invalid budget", backend: newTestHelper(t).createJSONBackend(card), budget: "-1", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newTestHelper(t).createPluginHandler(tt.backend)
+			req := httptest.NewRequest(http.MethodGet, "/weather-agent"+testAgentCardPath, nil)
+			req.Host = testGatewayHost
+			if tt.budget != "" {
+				req.Header.Set(timing.BudgetHeader, tt.budget)
+			}
+			start := time.Now()
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Less(t, time.Since(start), 500*time.Millisecond)
+			assert.Regexp(t, `^gateway;dur=[0-9.]+, upstream;dur=[0-9.]+, total;dur=[0-9.]+$`, rec.Header().Get(timing.Header))
+		})
+	}
+}
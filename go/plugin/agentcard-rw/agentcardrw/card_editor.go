@@ -0,0 +1,165 @@
+package agentcardrw
+
+import (
+	"net/url"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/jsonedit"
+)
+
+// editAgentCard rewrites an agent card like rewriteAgentCardMap, but edits only the fields it
+// changes in place. The order of fields and all other bytes of the card are kept, and large
+// cards are rewritten without unmarshalling them.
+func editAgentCard(doc *jsonedit.Document, card jsonedit.Value, gatewayURL string, agentPath string, s settings) {
+	externalURL := constructExternalURL(gatewayURL, agentPath)
+
+	// Remove internal-only fields before anything else is rewritten
+	for _, path := range s.stripFields {
+		stripValue(doc, card, path)
+	}
+
+	// Rewrite main URL, URLs of the agent in other fields are rewritten relative to it. Later
+	// replacements of a field override earlier ones, so the main URL is replaced last.
+	if agentURL, ok := card.Get("url"); ok {
+		if value, ok := agentURL.Text(); ok {
+			editURLFields(doc, card, s.urlFields, value, externalURL)
+			doc.SetString(agentURL, externalURL)
+		}
+	}
+
+	// Rewrite and filter additional interfaces
+	if interfaces, ok := card.Get("additionalInterfaces"); ok && interfaces.Kind() == jsonedit.Array {
+		editAdditionalInterfaces(doc, interfaces, gatewayURL, externalURL, s)
+	}
+	editPreferredTransport(doc, card, s.transports)
+
+	// Rewriting invalidates the signatures of the agent, cards are only edited if they are not re-signed
+	if s.signer != nil {
+		if signatures, ok := card.Get("signatures"); ok {
+			doc.Remove(card, signatures)
+		}
+	}
+}
+
+// stripValue removes the fields of path below value, see stripFields.strip
+func stripValue(doc *jsonedit.Document, value jsonedit.Value, path []string) {
+	last := len(path) == 1
+	switch value.Kind() {
+	case jsonedit.Object:
+		for _, m := range value.Members() {
+			if path[0] != "*" && path[0] != m.Key {
+				continue
+			}
+			if last {
+				doc.Remove(value, m.Value)
+			} else {
+				stripValue(doc, m.Value, path[1:])
+			}
+		}
+	case jsonedit.Array:
+		if path[0] != "*" {
+			return
+		}
+		for _, element := range value.Elements() {
+			if last {
+				doc.Remove(value, element)
+			} else {
+				stripValue(doc, element, path[1:])
+			}
+		}
+	}
+}
+
+// editURLFields replaces URLs below the agent origin in the fields of card, see urlFields.rewrite
+func editURLFields(doc *jsonedit.Document, card jsonedit.Value, fields urlFields, agentURL, externalURL string) {
+	parsed, err := url.Parse(agentURL)
+	if err != nil || parsed.Host == "" {
+		return
+	}
+	origin := parsed.Scheme + "://" + parsed.Host
+	for _, path := range fields {
+		editURLField(doc, card, path, origin, externalURL)
+	}
+}
+
+func editURLField(doc *jsonedit.Document, value jsonedit.Value, path []string, origin, externalURL string) {
+	if len(path) == 0 {
+		values := []jsonedit.Value{value}
+		if value.Kind() == jsonedit.Array {
+			values = value.Elements()
+		}
+		for _, v := range values {
+			if s, ok := v.Text(); ok {
+				if rewritten := rewriteAgentURL(s, origin, externalURL); rewritten != s {
+					doc.SetString(v, rewritten)
+				}
+			}
+		}
+		return
+	}
+
+	switch value.Kind() {
+	case jsonedit.Object:
+		for _, m := range value.Members() {
+			if path[0] == "*" || path[0] == m.Key {
+				editURLField(doc, m.Value, path[1:], origin, externalURL)
+			}
+		}
+	case jsonedit.Array:
+		if path[0] == "*" {
+			for _, element := range value.Elements() {
+				editURLField(doc, element, path[1:], origin, externalURL)
+			}
+		}
+	}
+}
+
+// editAdditionalInterfaces removes the interfaces that are not kept and rewrites the URLs of the
+// others, see rewriteAdditionalInterfacesMap
+func editAdditionalInterfaces(doc *jsonedit.Document, interfaces jsonedit.Value, gatewayURL, externalURL string, s settings) {
+	for _, iface := range interfaces.Elements() {
+		// Skip entries without transport, including non-object entries
+		transportValue, _ := iface.Get("transport")
+		transport, ok := transportValue.Text()
+		if !ok {
+			doc.Remove(interfaces, iface)
+			continue
+		}
+
+		urlValue, hasURL := iface.Get("url")
+		var u string
+		if hasURL {
+			u, hasURL = urlValue.Text()
+		}
+		rewritten, keep := rewriteInterface(transport, u, hasURL, gatewayURL, externalURL, s)
+		switch {
+		case !keep:
+			doc.Remove(interfaces, iface)
+		case hasURL:
+			doc.SetString(urlValue, rewritten)
+		}
+	}
+}
+
+// editPreferredTransport replaces or removes a preferred transport that is not allowed, see
+// normalizePreferredTransport
+func editPreferredTransport(doc *jsonedit.Document, card jsonedit.Value, transports transportSet) {
+	preferredValue, ok := card.Get("preferredTransport")
+	if !ok {
+		return
+	}
+	preferred, ok := preferredValue.Text()
+	if !ok || transports.allows(preferred) {
+		return
+	}
+	if interfaces, ok := card.Get("additionalInterfaces"); ok {
+		for _, iface := range interfaces.Elements() {
+			if value, ok := iface.Get("transport"); ok {
+				if transport, ok := value.Text(); ok && transports.allows(transport) {
+					doc.SetString(preferredValue, transport)
+					return
+				}
+			}
+		}
+	}
+	doc.Remove(card, preferredValue)
+}
@@ -0,0 +1,123 @@
+package agentcardrw
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/jsonedit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const editorTestCard = `{
+  "name": "Weather",
+  "url": "http://weather:8000/",
+  "preferredTransport": "GRPC",
+  "documentationUrl": "http://weather:8000/docs",
+  "iconUrl": "https://cdn.example.com/weather.png",
+  "x-internal": {"owner": "team-weather"},
+  "skills": [
+    {"id": "forecast", "examples": ["http://weather:8000/examples/1", "Weather in Paris?"], "x-notes": "internal"},
+    {"id": "alerts", "x-notes": "internal"}
+  ],
+  "additionalInterfaces": [
+    {"transport": "GRPC", "url": "http://weather:9000"},
+    {"transport": "JSONRPC", "url": "http://weather:8000/"},
+    {"transport": "JSONRPC", "url": "ws://weather:8000/live"},
+    {"transport": "SOAP", "url": "http://weather:8000/soap"},
+    {"url": "http://weather:8000/unknown"},
+    "invalid"
+  ],
+  "version": 1.10
+}`
+
+func TestEditAgentCard_MatchesMapRewrite(t *testing.T) {
+	tests := []struct {
+		name       string
+		transports []string
+		urlFields  []string
+		strip      []string
+	}{
+		{name: "defaults"},
+		{name: "allowed transports", transports: []string{"jsonrpc"}},
+		{name: "no allowed transports", transports: []string{"http+json"}},
+		{name: "url fields and strip fields", urlFields: []string{"url", "additionalInterfaces.*.url", "skills.*.examples"}, strip: []string{"x-internal", "skills.*.x-notes", "additionalInterfaces.*"}},
+		{name: "strip url", strip: []string{"url", "skills.*"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var s settings
+			var err error
+			s.transports, err = newTransportSet(tt.transports)
+			require.NoError(t, err)
+			s.urlFields, err = newURLFields(tt.urlFields)
+			require.NoError(t, err)
+			s.stripFields, err = newStripFields(tt.strip)
+			require.NoError(t, err)
+
+			var cardMap map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(editorTestCard), &cardMap))
+			want, err := json.Marshal(rewriteAgentCardMap(cardMap, "https://gateway.example.com", "/weather-agent", s))
+			require.NoError(t, err)
+
+			doc, err := jsonedit.Parse([]byte(editorTestCard))
+			require.NoError(t, err)
+			editAgentCard(doc, doc.Root(), "https://gateway.example.com", "/weather-agent", s)
+			var edited bytes.Buffer
+			_, err = doc.WriteTo(&edited)
+			require.NoError(t, err)
+
+			// Interfaces filtered to none are marshalled as null from the map, but kept as an empty array
+			assert.JSONEq(t, strings.ReplaceAll(string(want), `"additionalInterfaces":null`, `"additionalInterfaces":[]`), edited.String())
+		})
+	}
+}
+
+func TestAgentCard_KeepsFieldOrder(t *testing.T) {
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentTypeJSON)
+		_, _ = w.Write([]byte(`{"version": "1.0", "url": "http://weather:8000", "name": "Weather", "capabilities": {"streaming": true}}`))
+	})
+	handler := newTestHelper(t).createPluginHandler(backend)
+
+	rec := newTestHelper(t).makeRequest(handler, http.MethodGet, "/weather-agent"+testAgentCardPath, testGatewayHost, testHTTPSProtocol)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `{"version": "1.0", "url": "https://gateway.agentic-layer.ai/weather-agent", "name": "Weather", "capabilities": {"streaming": true}}`, rec.Body.String())
+}
+
+func BenchmarkRewriteCard(b *testing.B) {
+	var skills []string
+	for i := 0; i < 500; i++ {
+		skills = append(skills, fmt.Sprintf(`{"id":"skill-%d","name":"Skill %d","description":"Answers questions about topic %d","tags":["weather","topic-%d"],"examples":["What about topic %d?"]}`, i, i, i, i, i))
+	}
+	card := []byte(`{"name":"Weather","url":"http://weather:8000","documentationUrl":"http://weather:8000/docs","skills":[` + strings.Join(skills, ",") + `]}`)
+	var s settings
+	s.urlFields, _ = newURLFields(nil)
+	var out bytes.Buffer
+
+	b.Run("map", func(b *testing.B) {
+		b.SetBytes(int64(len(card)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var cardMap map[string]interface{}
+			_ = json.Unmarshal(card, &cardMap)
+			out.Reset()
+			_ = json.NewEncoder(&out).Encode(rewriteAgentCardMap(cardMap, "https://gateway.example.com", "/weather-agent", s))
+		}
+	})
+	b.Run("edit", func(b *testing.B) {
+		b.SetBytes(int64(len(card)))
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			doc, _ := jsonedit.Parse(card)
+			editAgentCard(doc, doc.Root(), "https://gateway.example.com", "/weather-agent", s)
+			out.Reset()
+			_, _ = doc.WriteTo(&out)
+		}
+	})
+}
@@ -0,0 +1,165 @@
+package agentcardrw
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// defaultURLFields are the card fields that may hold URLs of the agent itself. Segments are
+// object keys; * matches every key of an object or every element of an array.
+var defaultURLFields = []string{
+	"documentationUrl",
+	"iconUrl",
+	"skills.*.*",
+	"securitySchemes.*.openIdConnectUrl",
+	"securitySchemes.*.oauth2MetadataUrl",
+	"securitySchemes.*.flows.*.authorizationUrl",
+	"securitySchemes.*.flows.*.tokenUrl",
+	"securitySchemes.*.flows.*.refreshUrl",
+}
+
+// parseFieldPaths splits dot-separated field paths into their segments
+func parseFieldPaths(fields []string) ([][]string, error) {
+	parsed := make([][]string, 0, len(fields))
+	for _, field := range fields {
+		segments := strings.Split(field, ".")
+		for _, segment := range segments {
+			if segment == "" {
+				return nil, fmt.Errorf("invalid field path '%s'", field)
+			}
+		}
+		parsed = append(parsed, segments)
+	}
+	return parsed, nil
+}
+
+// urlFields are the parsed field paths whose agent URLs are rewritten
+type urlFields [][]string
+
+// newURLFields parses field paths. Without a configured list, the defaults are used;
+// an empty list rewrites no fields.
+func newURLFields(fields []string) (urlFields, error) {
+	if fields == nil {
+		fields = defaultURLFields
+	}
+	return parseFieldPaths(fields)
+}
+
+// stripFields are the parsed field paths removed from published cards
+type stripFields [][]string
+
+func newStripFields(fields []string) (stripFields, error) {
+	return parseFieldPaths(fields)
+}
+
+// strip removes the fields from cardMap. A trailing * removes every key of an object
+// or every element of an array.
+func (f stripFields) strip(cardMap map[string]interface{}) {
+	for _, path := range f {
+		stripField(cardMap, path)
+	}
+}
+
+func stripField(value interface{}, path []string) interface{} {
+	last := len(path) == 1
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if path[0] != "*" && path[0] != key {
+				continue
+			}
+			if last {
+				delete(v, key)
+			} else {
+				v[key] = stripField(child, path[1:])
+			}
+		}
+	case []interface{}:
+		if path[0] != "*" {
+			return value
+		}
+		if last {
+			return []interface{}{}
+		}
+		for i, child := range v {
+			v[i] = stripField(child, path[1:])
+		}
+	}
+	return value
+}
+
+// rewrite replaces URLs below the agent origin in the fields of cardMap with externalURL.
+// URLs of other origins, e.g. external documentation, are left unchanged.
+func (f urlFields) rewrite(cardMap map[string]interface{}, agentURL, externalURL string) {
+	parsed, err := url.Parse(agentURL)
+	if err != nil || parsed.Host == "" {
+		return
+	}
+	origin := parsed.Scheme + "://" + parsed.Host
+	for _, path := range f {
+		rewriteField(cardMap, path, origin, externalURL)
+	}
+}
+
+func rewriteField(value interface{}, path []string, origin, externalURL string) interface{} {
+	if len(path) == 0 {
+		switch v := value.(type) {
+		case string:
+			return rewriteAgentURL(v, origin, externalURL)
+		case []interface{}:
+			for i, element := range v {
+				if s, ok := element.(string); ok {
+					v[i] = rewriteAgentURL(s, origin, externalURL)
+				}
+			}
+		}
+		return value
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if path[0] == "*" || path[0] == key {
+				v[key] = rewriteField(child, path[1:], origin, externalURL)
+			}
+		}
+	case []interface{}:
+		if path[0] == "*" {
+			for i, child := range v {
+				v[i] = rewriteField(child, path[1:], origin, externalURL)
+			}
+		}
+	}
+	return value
+}
+
+// rewriteAgentURL replaces the agent origin of a URL, e.g. http://weather-agent:8000/docs
+// becomes https://gateway.example.com/weather-agent/docs
+func rewriteAgentURL(value, origin, externalURL string) string {
+	rest, ok := strings.CutPrefix(value, origin)
+	if !ok || (rest != "" && !strings.ContainsAny(rest[:1], "/?#")) {
+		return value
+	}
+	return externalURL + rest
+}
+
+// normalizePreferredTransport replaces a preferred transport that is not allowed by the transport
+// of the first remaining additional interface, or removes it, so clients fall back to JSONRPC
+func normalizePreferredTransport(cardMap map[string]interface{}, transports transportSet) {
+	preferred, ok := safeGetString(cardMap, "preferredTransport")
+	if !ok || transports.allows(preferred) {
+		return
+	}
+	if interfaces, ok := safeGetArray(cardMap, "additionalInterfaces"); ok {
+		for _, iface := range interfaces {
+			if ifaceMap, ok := iface.(map[string]interface{}); ok {
+				if transport, ok := safeGetString(ifaceMap, "transport"); ok && transports.allows(transport) {
+					cardMap["preferredTransport"] = transport
+					return
+				}
+			}
+		}
+	}
+	delete(cardMap, "preferredTransport")
+}
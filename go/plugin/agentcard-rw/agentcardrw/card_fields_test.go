@@ -0,0 +1,103 @@
+package agentcardrw
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewriteAgentCardMap_URLFields(t *testing.T) {
+	fields, err := newURLFields(nil)
+	assert.NoError(t, err)
+	var cardMap map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"url": "http://weather-agent:8000/",
+		"documentationUrl": "http://weather-agent:8000/docs",
+		"iconUrl": "https://cdn.example.com/weather.png",
+		"skills": [{"id": "forecast", "name": "Forecast", "examples": ["http://weather-agent:8000/examples/1", "Weather in Paris?"]}],
+		"securitySchemes": {
+			"oauth": {"type": "oauth2", "flows": {"clientCredentials": {"tokenUrl": "http://weather-agent:8000/oauth/token?scope=a"}}},
+			"oidc": {"type": "openIdConnect", "openIdConnectUrl": "http://weather-agent:8000x/.well-known/openid-configuration"}
+		}
+	}`), &cardMap))
+
+	result := rewriteAgentCardMap(cardMap, "https://gateway.example.com", "/weather-agent", settings{urlFields: fields})
+
+	assert.Equal(t, "https://gateway.example.com/weather-agent", result["url"])
+	assert.Equal(t, "https://gateway.example.com/weather-agent/docs", result["documentationUrl"])
+	assert.Equal(t, "https://cdn.example.com/weather.png", result["iconUrl"])
+	skill := result["skills"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, []interface{}{"https://gateway.example.com/weather-agent/examples/1", "Weather in Paris?"}, skill["examples"])
+	assert.Equal(t, "forecast", skill["id"])
+	schemes := result["securitySchemes"].(map[string]interface{})
+	flow := schemes["oauth"].(map[string]interface{})["flows"].(map[string]interface{})["clientCredentials"].(map[string]interface{})
+	assert.Equal(t, "https://gateway.example.com/weather-agent/oauth/token?scope=a", flow["tokenUrl"])
+	assert.Equal(t, "http://weather-agent:8000x/.well-known/openid-configuration", schemes["oidc"].(map[string]interface{})["openIdConnectUrl"])
+}
+
+func TestRewriteAgentCardMap_PreferredTransport(t *testing.T) {
+	tests := []struct {
+		name          string
+		preferred     string
+		interfaces    []interface{}
+		wantPreferred interface{}
+	}{
+		{name: "allowed", preferred: "JSONRPC", wantPreferred: "JSONRPC"},
+		{
+			name:      "filtered out",
+			preferred: "WEBSOCKET",
+			interfaces: []interface{}{
+				map[string]interface{}{"transport": "WEBSOCKET", "url": "ws://agent:8000/ws"},
+				map[string]interface{}{"transport": "GRPC", "url": "http://agent:50051"},
+			},
+			wantPreferred: "GRPC",
+		},
+		{name: "no remaining interface", preferred: "SOAP", wantPreferred: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cardMap := map[string]interface{}{"url": "http://agent:8000", "preferredTransport": tt.preferred}
+			if tt.interfaces != nil {
+				cardMap["additionalInterfaces"] = tt.interfaces
+			}
+
+			result := rewriteAgentCardMap(cardMap, "https://gateway.example.com", "/agent", settings{})
+
+			assert.Equal(t, tt.wantPreferred, result["preferredTransport"])
+		})
+	}
+}
+
+func TestNewURLFields(t *testing.T) {
+	fields, err := newURLFields([]string{})
+	assert.NoError(t, err)
+	assert.Empty(t, fields)
+
+	_, err = newURLFields([]string{"skills..url"})
+	assert.Error(t, err)
+}
+
+func TestRewriteAgentCardMap_StripFields(t *testing.T) {
+	fields, err := newStripFields([]string{"metadata.annotations", "skills.*.debugEndpoint", "provider.contacts.*", "internal"})
+	assert.NoError(t, err)
+	var cardMap map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"url": "http://weather-agent:8000",
+		"internal": {"cluster": "eu-1"},
+		"metadata": {"annotations": {"team": "weather"}, "version": "2"},
+		"provider": {"organization": "Example", "contacts": ["oncall@weather.internal"]},
+		"skills": [{"id": "forecast", "debugEndpoint": "http://weather-agent:8000/debug"}]
+	}`), &cardMap))
+
+	result := rewriteAgentCardMap(cardMap, "https://gateway.example.com", "/weather-agent", settings{stripFields: fields})
+
+	assert.NotContains(t, result, "internal")
+	assert.Equal(t, map[string]interface{}{"version": "2"}, result["metadata"])
+	assert.Equal(t, map[string]interface{}{"organization": "Example", "contacts": []interface{}{}}, result["provider"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"id": "forecast"}}, result["skills"])
+
+	_, err = newStripFields([]string{"metadata."})
+	assert.Error(t, err)
+}
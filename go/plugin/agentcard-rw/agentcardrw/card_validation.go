@@ -0,0 +1,126 @@
+package agentcardrw
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+const (
+	validationWarn    = "warn"
+	validationEnforce = "enforce"
+
+	a2aSchemaURL = "a2a.json"
+
+	// maxValidationErrors limits the schema violations reported for a single card
+	maxValidationErrors = 10
+)
+
+var schemaMessages = message.NewPrinter(language.English)
+
+// cardValidator checks backend agent cards against the AgentCard definition of the A2A schema
+type cardValidator struct {
+	schema  *jsonschema.Schema
+	enforce bool
+}
+
+// newCardValidator compiles the AgentCard schema. Without a mode, cards are not validated.
+func newCardValidator(mode string) (*cardValidator, error) {
+	if mode == "" {
+		return nil, nil
+	}
+	if mode != validationWarn && mode != validationEnforce {
+		return nil, fmt.Errorf("unknown mode '%s', use '%s' or '%s'", mode, validationWarn, validationEnforce)
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(models.A2ASchema))
+	if err != nil {
+		return nil, fmt.Errorf("invalid A2A schema: %w", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(a2aSchemaURL, doc); err != nil {
+		return nil, fmt.Errorf("invalid A2A schema: %w", err)
+	}
+	schema, err := compiler.Compile(a2aSchemaURL + "#/definitions/AgentCard")
+	if err != nil {
+		return nil, fmt.Errorf("cannot compile AgentCard schema: %w", err)
+	}
+	return &cardValidator{schema: schema, enforce: mode == validationEnforce}, nil
+}
+
+// validate returns the schema violations of a card, e.g. "skills[0].id: got number, want string".
+// Violations are logged; they are only returned in enforce mode, so warned cards pass through.
+func (v *cardValidator) validate(agentPath string, body []byte) []string {
+	if v == nil {
+		return nil
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return nil
+	}
+	err = v.schema.Validate(instance)
+	if err == nil {
+		return nil
+	}
+	validationErr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		logger.Error(fmt.Sprintf("cannot validate agent card of %s: %v", agentPath, err))
+		return nil
+	}
+
+	violations := schemaViolations(validationErr)
+	logger.Warning(fmt.Sprintf("agent card of %s violates the A2A schema: %s", agentPath, strings.Join(violations, "; ")))
+	if !v.enforce {
+		return nil
+	}
+	return violations
+}
+
+// schemaViolations lists the most specific causes of a validation error with their location
+func schemaViolations(err *jsonschema.ValidationError) []string {
+	var violations []string
+	var collect func(err *jsonschema.ValidationError)
+	collect = func(err *jsonschema.ValidationError) {
+		if len(violations) == maxValidationErrors {
+			return
+		}
+		if len(err.Causes) > 0 {
+			for _, cause := range err.Causes {
+				collect(cause)
+			}
+			return
+		}
+		location := fieldPath(err.InstanceLocation)
+		if location == "" {
+			location = "card"
+		}
+		violations = append(violations, location+": "+err.ErrorKind.LocalizedString(schemaMessages))
+	}
+	collect(err)
+	return violations
+}
+
+// fieldPath converts a JSON pointer like /skills/0/id to skills[0].id
+func fieldPath(location []string) string {
+	var path string
+	for _, token := range location {
+		path = joinFieldPath(path, token)
+	}
+	return path
+}
+
+func joinFieldPath(path, token string) string {
+	if _, err := strconv.Atoi(token); err == nil {
+		return path + "[" + token + "]"
+	}
+	if path == "" {
+		return token
+	}
+	return path + "." + token
+}
@@ -0,0 +1,80 @@
+package agentcardrw
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const validAgentCard = `{
+	"name": "Weather",
+	"description": "Weather forecasts",
+	"url": "http://weather:8000",
+	"version": "1.0.0",
+	"protocolVersion": "0.3.0",
+	"capabilities": {},
+	"defaultInputModes": ["text/plain"],
+	"defaultOutputModes": ["text/plain"],
+	"skills": [{"id": "forecast", "name": "Forecast", "description": "Daily forecast", "tags": []}]
+}`
+
+func validationHandler(t *testing.T, mode, card string) http.Handler {
+	extra := map[string]interface{}{configKey: map[string]interface{}{"validation": mode}}
+	handler, err := module.registerHandlers(context.Background(), extra, newTestHelper(t).createJSONBackend(card))
+	assert.NoError(t, err)
+	return handler
+}
+
+func TestRegisterHandlers_InvalidValidationMode(t *testing.T) {
+	extra := map[string]interface{}{configKey: map[string]interface{}{"validation": "strict"}}
+	_, err := module.registerHandlers(context.Background(), extra, http.NotFoundHandler())
+
+	assert.Error(t, err)
+}
+
+func TestCardValidation(t *testing.T) {
+	invalidCard := `{"name": "Weather", "url": "http://weather:8000", "skills": [{"id": 7}]}`
+
+	tests := []struct {
+		name       string
+		mode       string
+		card       string
+		wantStatus int
+	}{
+		{name: "valid card enforced", mode: validationEnforce, card: validAgentCard, wantStatus: http.StatusOK},
+		{name: "invalid card enforced", mode: validationEnforce, card: invalidCard, wantStatus: http.StatusBadGateway},
+		{name: "invalid card warned", mode: validationWarn, card: invalidCard, wantStatus: http.StatusOK},
+		{name: "invalid card without validation", mode: "", card: invalidCard, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := validationHandler(t, tt.mode, tt.card)
+
+			rec := newTestHelper(t).makeRequest(handler, http.MethodGet, "/weather-agent"+testAgentCardPath, testGatewayHost, testHTTPSProtocol)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus == http.StatusOK {
+				var card map[string]interface{}
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &card))
+				assert.Equal(t, "https://"+testGatewayHost+"/weather-agent", card["url"])
+			}
+		})
+	}
+}
+
+func TestCardValidation_ReportsViolations(t *testing.T) {
+	validator, err := newCardValidator(validationEnforce)
+	assert.NoError(t, err)
+
+	violations := validator.validate("/weather-agent", []byte(`{"name": "Weather", "url": "http://weather:8000", "skills": [{"id": 7}]}`))
+
+	assert.Equal(t, []string{
+		"card: missing properties 'capabilities', 'defaultInputModes', 'defaultOutputModes', 'description', 'protocolVersion', 'version'",
+		"skills[0]: missing properties 'description', 'name', 'tags'",
+		"skills[0].id: got number, want string",
+	}, violations)
+}
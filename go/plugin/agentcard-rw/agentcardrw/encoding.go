@@ -0,0 +1,160 @@
+package agentcardrw
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	encodingIdentity = "identity"
+	encodingGzip     = "gzip"
+	encodingDeflate  = "deflate"
+
+	// maxDecodedCardSize limits decompressed agent cards to guard against compression bombs
+	maxDecodedCardSize = 8 << 20
+)
+
+// Decompressors and compressors allocate large state, so they are reset and reused across cards
+var (
+	gzipReaders sync.Pool
+	zlibReaders sync.Pool
+	gzipWriters = sync.Pool{New: func() interface{} { return gzip.NewWriter(io.Discard) }}
+	zlibWriters = sync.Pool{New: func() interface{} { return zlib.NewWriter(io.Discard) }}
+)
+
+// decodeBody decompresses a backend response according to its Content-Encoding
+func decodeBody(body []byte, contentEncoding string) ([]byte, error) {
+	var r io.Reader
+	switch encoding := strings.ToLower(strings.TrimSpace(contentEncoding)); encoding {
+	case "", encodingIdentity:
+		return body, nil
+	case encodingGzip, "x-gzip":
+		gr, err := newGzipReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip body: %w", err)
+		}
+		defer gzipReaders.Put(gr)
+		defer gr.Close()
+		r = gr
+	case encodingDeflate:
+		// deflate is zlib-wrapped in HTTP, but some servers send raw deflate streams
+		zr, err := newZlibReader(body)
+		if zr != nil {
+			defer zlibReaders.Put(zr)
+		}
+		if err != nil {
+			r = flate.NewReader(bytes.NewReader(body))
+		} else {
+			defer zr.Close()
+			r = zr
+		}
+	default:
+		return nil, fmt.Errorf("unsupported content encoding '%s'", contentEncoding)
+	}
+
+	decoded, err := io.ReadAll(io.LimitReader(r, maxDecodedCardSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("cannot decompress %s body: %w", contentEncoding, err)
+	}
+	if len(decoded) > maxDecodedCardSize {
+		return nil, fmt.Errorf("decompressed body exceeds %d bytes", maxDecodedCardSize)
+	}
+	return decoded, nil
+}
+
+// negotiateEncoding picks gzip or deflate if the client accepts it, preferring the
+// encoding of the backend response, and identity otherwise
+func negotiateEncoding(acceptEncoding, preferred string) string {
+	accepted := map[string]float64{}
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if name == "*" {
+			wildcard = q
+			continue
+		}
+		accepted[name] = q
+	}
+	acceptable := func(encoding string) bool {
+		if q, ok := accepted[encoding]; ok {
+			return q > 0
+		}
+		return wildcard > 0
+	}
+
+	preferred = strings.ToLower(strings.TrimSpace(preferred))
+	if preferred == "x-gzip" {
+		preferred = encodingGzip
+	}
+	for _, encoding := range []string{preferred, encodingGzip, encodingDeflate} {
+		if (encoding == encodingGzip || encoding == encodingDeflate) && acceptable(encoding) {
+			return encoding
+		}
+	}
+	return encodingIdentity
+}
+
+// newGzipReader returns a pooled gzip reader of body
+func newGzipReader(body []byte) (*gzip.Reader, error) {
+	if gr, ok := gzipReaders.Get().(*gzip.Reader); ok {
+		if err := gr.Reset(bytes.NewReader(body)); err != nil {
+			gzipReaders.Put(gr)
+			return nil, err
+		}
+		return gr, nil
+	}
+	return gzip.NewReader(bytes.NewReader(body))
+}
+
+// newZlibReader returns a pooled zlib reader of body. The reader is returned along with header
+// errors, so callers can return it to the pool.
+func newZlibReader(body []byte) (io.ReadCloser, error) {
+	if zr, ok := zlibReaders.Get().(io.ReadCloser); ok {
+		return zr, zr.(zlib.Resetter).Reset(bytes.NewReader(body), nil)
+	}
+	return zlib.NewReader(bytes.NewReader(body))
+}
+
+// compressor is a gzip or zlib writer
+type compressor interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// encodeBody writes a rewritten agent card to dst, compressed with gzip or deflate
+func encodeBody(dst io.Writer, body []byte, encoding string) error {
+	var pool *sync.Pool
+	switch encoding {
+	case encodingGzip:
+		pool = &gzipWriters
+	case encodingDeflate:
+		pool = &zlibWriters
+	default:
+		_, err := dst.Write(body)
+		return err
+	}
+	w := pool.Get().(compressor)
+	defer pool.Put(w)
+	w.Reset(dst)
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	return w.Close()
+}
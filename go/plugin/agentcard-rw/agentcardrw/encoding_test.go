@@ -0,0 +1,153 @@
+package agentcardrw
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func gzipBytes(t *testing.T, data string) []byte {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(data))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestAgentCard_CompressedBackend(t *testing.T) {
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentTypeJSON)
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(gzipBytes(t, `{"name":"Weather","url":"http://weather:8000"}`))
+	})
+	handler := newTestHelper(t).createPluginHandler(backend)
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		wantEncoding   string
+	}{
+		{name: "gzip client", acceptEncoding: "gzip, deflate", wantEncoding: "gzip"},
+		{name: "deflate client", acceptEncoding: "deflate, gzip;q=0", wantEncoding: "deflate"},
+		{name: "identity client", acceptEncoding: "", wantEncoding: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/weather-agent"+testAgentCardPath, nil)
+			req.Host = testGatewayHost
+			req.Header.Set("X-Forwarded-Proto", testHTTPSProtocol)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.wantEncoding, rec.Header().Get("Content-Encoding"))
+			assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+
+			var r io.Reader = rec.Body
+			switch tt.wantEncoding {
+			case "gzip":
+				gr, err := gzip.NewReader(r)
+				assert.NoError(t, err)
+				r = gr
+			case "deflate":
+				zr, err := zlib.NewReader(r)
+				assert.NoError(t, err)
+				r = zr
+			}
+			var card map[string]interface{}
+			assert.NoError(t, json.NewDecoder(r).Decode(&card))
+			assert.Equal(t, "https://"+testGatewayHost+"/weather-agent", card["url"])
+		})
+	}
+}
+
+func TestAgentCard_CorruptCompressedBackend(t *testing.T) {
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentTypeJSON)
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write([]byte(`{"name":"not compressed"}`))
+	})
+
+	rec := newTestHelper(t).makeRequest(newTestHelper(t).createPluginHandler(backend), http.MethodGet, "/weather-agent"+testAgentCardPath, testGatewayHost, testHTTPSProtocol)
+
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		preferred      string
+		want           string
+	}{
+		{acceptEncoding: "", preferred: "gzip", want: "identity"},
+		{acceptEncoding: "gzip, deflate", preferred: "deflate", want: "deflate"},
+		{acceptEncoding: "gzip, deflate", preferred: "", want: "gzip"},
+		{acceptEncoding: "br, gzip;q=0", preferred: "gzip", want: "identity"},
+		{acceptEncoding: "*", preferred: "x-gzip", want: "gzip"},
+		{acceptEncoding: "*, gzip;q=0", preferred: "gzip", want: "deflate"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, negotiateEncoding(tt.acceptEncoding, tt.preferred), tt.acceptEncoding)
+	}
+}
+
+func TestDecodeBody(t *testing.T) {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	_, _ = w.Write([]byte(`{}`))
+	_ = w.Close()
+
+	decoded, err := decodeBody(buf.Bytes(), "deflate")
+	assert.NoError(t, err)
+	assert.Equal(t, `{}`, string(decoded))
+
+	_, err = decodeBody([]byte(`{}`), "br")
+	assert.Error(t, err)
+}
+
+func BenchmarkAgentCard(b *testing.B) {
+	card := `{"name":"Weather","url":"http://weather:8000","skills":[{"id":"forecast","name":"Forecast","tags":["weather"]}]}`
+	var compressed bytes.Buffer
+	gw := gzip.NewWriter(&compressed)
+	_, _ = gw.Write([]byte(card))
+	_ = gw.Close()
+
+	for _, encoding := range []string{"identity", "gzip"} {
+		b.Run(encoding, func(b *testing.B) {
+			backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", contentTypeJSON)
+				if encoding == "gzip" {
+					w.Header().Set("Content-Encoding", "gzip")
+					_, _ = w.Write(compressed.Bytes())
+					return
+				}
+				_, _ = w.Write([]byte(card))
+			})
+			handler, err := module.registerHandlers(context.Background(), map[string]interface{}{}, backend)
+			if err != nil {
+				b.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/weather-agent"+testAgentCardPath, nil)
+			req.Host = testGatewayHost
+			req.Header.Set("Accept-Encoding", encoding)
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				handler.ServeHTTP(httptest.NewRecorder(), req)
+			}
+		})
+	}
+}
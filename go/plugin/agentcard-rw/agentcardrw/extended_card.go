@@ -0,0 +1,62 @@
+package agentcardrw
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// extendedCardMethod is the JSON-RPC method returning the authenticated extended agent card
+const extendedCardMethod = "agent/getAuthenticatedExtendedCard"
+
+// extendedCardSuffixes are the path suffixes of the authenticated extended agent card: the HTTP
+// endpoint of A2A 0.2 and the card resource of the HTTP+JSON transport
+var extendedCardSuffixes = []string{"/agent/authenticatedExtendedCard", "/v1/card"}
+
+// maxRPCPeek limits how much of a POST body is read to recognize extended card requests.
+// Extended card requests have no params, so they are always much smaller.
+const maxRPCPeek = 4 << 10
+
+// extendedCardRequest reports whether req asks for the extended card of an agent and returns the
+// agent path. rpc is true for JSON-RPC requests, whose response wraps the card in a result.
+func extendedCardRequest(req *http.Request) (agentPath string, rpc bool, ok bool) {
+	switch req.Method {
+	case http.MethodGet:
+		for _, suffix := range extendedCardSuffixes {
+			if agentPath, found := strings.CutSuffix(req.URL.Path, suffix); found && agentPath != "" {
+				return agentPath, false, true
+			}
+		}
+	case http.MethodPost:
+		agentPath = strings.TrimSuffix(req.URL.Path, "/")
+		if agentPath != "" && isExtendedCardRPC(req) {
+			return agentPath, true, true
+		}
+	}
+	return "", false, false
+}
+
+// isExtendedCardRPC peeks at a POST body for the extended card JSON-RPC method. The body is
+// restored, so all requests are forwarded unchanged.
+func isExtendedCardRPC(req *http.Request) bool {
+	if req.Body == nil || req.Body == http.NoBody || req.ContentLength > maxRPCPeek {
+		return false
+	}
+	peek, err := io.ReadAll(io.LimitReader(req.Body, maxRPCPeek+1))
+	req.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(peek), req.Body), Closer: req.Body}
+	if err != nil || len(peek) > maxRPCPeek || !bytes.Contains(peek, []byte(extendedCardMethod)) {
+		return false
+	}
+	var rpcReq struct {
+		Method string `json:"method"`
+	}
+	return json.Unmarshal(peek, &rpcReq) == nil && rpcReq.Method == extendedCardMethod
+}
+
+// readCloser replays a peeked body and closes the original one
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,128 @@
+package agentcardrw
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const extendedCard = `{"name":"Weather","url":"http://weather:8000","additionalInterfaces":[{"transport":"JSONRPC","url":"http://weather:8000"}],"skills":[{"id":"premium"}]}`
+
+func TestExtendedCard_HTTPEndpoints(t *testing.T) {
+	var authorization string
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", contentTypeJSON)
+		_, _ = w.Write([]byte(extendedCard))
+	})
+	handler := newTestHelper(t).createPluginHandler(backend)
+
+	for _, path := range []string{"/weather-agent/agent/authenticatedExtendedCard", "/weather-agent/v1/card"} {
+		t.Run(path, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req.Host = testGatewayHost
+			req.Header.Set("X-Forwarded-Proto", testHTTPSProtocol)
+			req.Header.Set("Authorization", "Bearer user-token")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "Bearer user-token", authorization)
+			assert.Equal(t, "private", rec.Header().Get("Cache-Control"))
+			var card map[string]interface{}
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &card))
+			assert.Equal(t, "https://"+testGatewayHost+"/weather-agent", card["url"])
+			assert.Equal(t, []interface{}{map[string]interface{}{"id": "premium"}}, card["skills"])
+		})
+	}
+}
+
+func TestExtendedCard_JSONRPC(t *testing.T) {
+	var received string
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.Header().Set("Content-Type", contentTypeJSON)
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":` + extendedCard + `}`))
+	})
+	handler := newTestHelper(t).createPluginHandler(backend)
+	request := `{"jsonrpc":"2.0","id":1,"method":"agent/getAuthenticatedExtendedCard"}`
+
+	req := httptest.NewRequest(http.MethodPost, "/weather-agent", strings.NewReader(request))
+	req.Host = testGatewayHost
+	req.Header.Set("X-Forwarded-Proto", testHTTPSProtocol)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, request, received)
+	var resp struct {
+		Jsonrpc string                 `json:"jsonrpc"`
+		Id      int                    `json:"id"`
+		Result  map[string]interface{} `json:"result"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "2.0", resp.Jsonrpc)
+	assert.Equal(t, 1, resp.Id)
+	assert.Equal(t, "https://"+testGatewayHost+"/weather-agent", resp.Result["url"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"transport": "JSONRPC", "url": "https://" + testGatewayHost + "/weather-agent"},
+	}, resp.Result["additionalInterfaces"])
+}
+
+func TestExtendedCard_OtherRequestsPassThrough(t *testing.T) {
+	response := `{"jsonrpc":"2.0","id":1,"result":{"kind":"message","url":"http://weather:8000"}}`
+	var received string
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.Header().Set("Content-Type", contentTypeJSON)
+		_, _ = w.Write([]byte(response))
+	})
+	handler := newTestHelper(t).createPluginHandler(backend)
+	request := `{"jsonrpc":"2.0","id":1,"method":"message/send","params":{"message":{"parts":[{"kind":"text","text":"` + strings.Repeat("x", maxRPCPeek) + `"}]}}}`
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/weather-agent", strings.NewReader(request)))
+
+	assert.Equal(t, request, received)
+	assert.Equal(t, response, rec.Body.String())
+}
+
+func TestExtendedCardRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		body      string
+		wantPath  string
+		wantRPC   bool
+		wantMatch bool
+	}{
+		{name: "http endpoint", method: http.MethodGet, path: "/agents/weather/agent/authenticatedExtendedCard", wantPath: "/agents/weather", wantMatch: true},
+		{name: "rest card", method: http.MethodGet, path: "/weather/v1/card", wantPath: "/weather", wantMatch: true},
+		{name: "root card", method: http.MethodGet, path: "/v1/card"},
+		{name: "rpc", method: http.MethodPost, path: "/weather/", body: `{"jsonrpc":"2.0","id":"a","method":"agent/getAuthenticatedExtendedCard"}`, wantPath: "/weather", wantRPC: true, wantMatch: true},
+		{name: "rpc mentioning method", method: http.MethodPost, path: "/weather", body: `{"method":"message/send","params":{"text":"agent/getAuthenticatedExtendedCard"}}`},
+		{name: "post to card endpoint", method: http.MethodPost, path: "/weather/v1/card", body: `{}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+
+			agentPath, rpc, ok := extendedCardRequest(req)
+
+			assert.Equal(t, tt.wantMatch, ok)
+			assert.Equal(t, tt.wantPath, agentPath)
+			assert.Equal(t, tt.wantRPC, rpc)
+			body, _ := io.ReadAll(req.Body)
+			assert.Equal(t, tt.body, string(body))
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package agentcardrw
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// externalURLs replaces the gateway URL derived from request headers with configured URLs,
+// for deployments where Host and X-Forwarded-Proto do not describe the external URL
+type externalURLs struct {
+	base   string
+	agents map[string]string
+}
+
+func newExternalURLs(base string, agents map[string]string) (*externalURLs, error) {
+	if base == "" && len(agents) == 0 {
+		return nil, nil
+	}
+	e := &externalURLs{agents: make(map[string]string, len(agents))}
+	if base != "" {
+		if err := validateExternalURL(base); err != nil {
+			return nil, err
+		}
+		e.base = strings.TrimSuffix(base, "/")
+	}
+	for agentPath, agentURL := range agents {
+		if !strings.HasPrefix(agentPath, "/") {
+			return nil, fmt.Errorf("agent path '%s' must start with /", agentPath)
+		}
+		if err := validateExternalURL(agentURL); err != nil {
+			return nil, err
+		}
+		e.agents[strings.TrimSuffix(agentPath, "/")] = strings.TrimSuffix(agentURL, "/")
+	}
+	return e, nil
+}
+
+func validateExternalURL(externalURL string) error {
+	parsed, err := url.Parse(externalURL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return fmt.Errorf("invalid external url '%s', expected http(s)://host", externalURL)
+	}
+	return nil
+}
+
+// lookup returns the configured external URL for an agent: its override or the external URL
+func (e *externalURLs) lookup(agentPath string) (string, bool) {
+	if e == nil {
+		return "", false
+	}
+	if agentURL, ok := e.agents[agentPath]; ok {
+		return agentURL, true
+	}
+	return e.base, e.base != ""
+}
@@ -0,0 +1,58 @@
+package agentcardrw
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewExternalURLs_Invalid(t *testing.T) {
+	_, err := newExternalURLs("gateway.example.com", nil)
+	assert.Error(t, err)
+	_, err = newExternalURLs("", map[string]string{"weather-agent": "https://weather.example.com"})
+	assert.Error(t, err)
+	_, err = newExternalURLs("", map[string]string{"/weather-agent": "ftp://weather.example.com"})
+	assert.Error(t, err)
+}
+
+func TestExternalURL_OverridesRequestHeaders(t *testing.T) {
+	h := newTestHelper(t)
+	backend := h.createAgentCardBackend(models.AgentCard{
+		Url: "http://weather-agent:8000",
+		AdditionalInterfaces: []models.AgentInterface{
+			{Transport: "JSONRPC", Url: "http://weather-agent:8000"},
+		},
+	})
+	extra := map[string]interface{}{
+		configKey: map[string]interface{}{
+			"external_url":  "https://api.example.com/gateway/",
+			"external_urls": map[string]interface{}{"/news-agent": "https://news.example.com"},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extra, backend)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		path    string
+		wantURL string
+	}{
+		{path: "/weather-agent" + testAgentCardPath, wantURL: "https://api.example.com/gateway/weather-agent"},
+		{path: "/news-agent" + testAgentCardPath, wantURL: "https://news.example.com/news-agent"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := h.makeRequest(handler, http.MethodGet, tt.path, "internal-lb:8080", "http")
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			var card models.AgentCard
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &card))
+			assert.Equal(t, tt.wantURL, card.Url)
+			assert.Equal(t, tt.wantURL, card.AdditionalInterfaces[0].Url)
+		})
+	}
+}
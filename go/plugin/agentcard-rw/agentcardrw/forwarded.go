@@ -0,0 +1,64 @@
+package agentcardrw
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// forwarded holds the original request as described by forwarded headers
+type forwarded struct {
+	proto string
+	host  string
+	port  string
+}
+
+// parseForwarded reads the first element of the RFC 7239 Forwarded header, which describes the
+// request of the client, and completes it from X-Forwarded-Proto, -Host and -Port
+func parseForwarded(header http.Header) forwarded {
+	var f forwarded
+	if value := header.Get("Forwarded"); value != "" {
+		element, _, _ := strings.Cut(value, ",")
+		for _, pair := range strings.Split(element, ";") {
+			key, val, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				continue
+			}
+			val = strings.Trim(val, `"`)
+			switch strings.ToLower(key) {
+			case "proto":
+				f.proto = val
+			case "host":
+				f.host = val
+			}
+		}
+	}
+	if f.proto == "" {
+		f.proto = firstValue(header.Get("X-Forwarded-Proto"))
+	}
+	if f.host == "" {
+		f.host = firstValue(header.Get("X-Forwarded-Host"))
+	}
+	f.port = firstValue(header.Get("X-Forwarded-Port"))
+	return f
+}
+
+// firstValue returns the first entry of a comma-separated header, set by the proxy closest to the client
+func firstValue(value string) string {
+	first, _, _ := strings.Cut(value, ",")
+	return strings.TrimSpace(first)
+}
+
+// validHost rejects forwarded hosts that would change more than the authority of the URL
+func validHost(host string) bool {
+	return host != "" && !strings.ContainsAny(host, "/\\@?# \t")
+}
+
+// directGatewayURL builds the gateway URL from the request alone, ignoring forwarded headers
+func directGatewayURL(req *http.Request) string {
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, req.Host)
+}
@@ -0,0 +1,106 @@
+package agentcardrw
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetGatewayURL_ForwardedHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		expected string
+	}{
+		{
+			name:     "x-forwarded host and proto",
+			headers:  map[string]string{"X-Forwarded-Host": "gateway.example.com", "X-Forwarded-Proto": "https"},
+			expected: "https://gateway.example.com",
+		},
+		{
+			name:     "first of several proxies",
+			headers:  map[string]string{"X-Forwarded-Host": "gateway.example.com, ingress.internal", "X-Forwarded-Proto": "https, http"},
+			expected: "https://gateway.example.com",
+		},
+		{
+			name:     "non-default forwarded port",
+			headers:  map[string]string{"X-Forwarded-Host": "gateway.example.com", "X-Forwarded-Proto": "https", "X-Forwarded-Port": "8443"},
+			expected: "https://gateway.example.com:8443",
+		},
+		{
+			name:     "default forwarded port",
+			headers:  map[string]string{"X-Forwarded-Host": "gateway.example.com", "X-Forwarded-Proto": "https", "X-Forwarded-Port": "443"},
+			expected: "https://gateway.example.com",
+		},
+		{
+			name:     "rfc 7239 takes precedence",
+			headers:  map[string]string{"Forwarded": `for=192.0.2.60;proto=https;host="api.example.com", for=10.0.0.1`, "X-Forwarded-Host": "other.example.com"},
+			expected: "https://api.example.com",
+		},
+		{
+			name:     "rfc 7239 completed by x-forwarded",
+			headers:  map[string]string{"Forwarded": "host=api.example.com", "X-Forwarded-Proto": "https"},
+			expected: "https://api.example.com",
+		},
+		{
+			name:     "invalid host and proto are ignored",
+			headers:  map[string]string{"X-Forwarded-Host": "evil.example.com/path", "X-Forwarded-Proto": "javascript"},
+			expected: "http://internal:8080",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/agent"+testAgentCardPath, nil)
+			req.Host = "internal:8080"
+			for name, value := range tt.headers {
+				req.Header.Set(name, value)
+			}
+
+			result, err := getGatewayURL(req)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestTrustedProxies(t *testing.T) {
+	h := newTestHelper(t)
+	backend := h.createAgentCardBackend(models.AgentCard{Url: "http://weather-agent:8000"})
+	extra := map[string]interface{}{
+		configKey: map[string]interface{}{"trusted_proxies": []interface{}{"10.0.0.0/8", "::1"}},
+	}
+	handler, err := module.registerHandlers(context.Background(), extra, backend)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		remoteAddr string
+		expected   string
+	}{
+		{remoteAddr: "10.1.2.3:51234", expected: "https://gateway.example.com/weather-agent"},
+		{remoteAddr: "[::1]:51234", expected: "https://gateway.example.com/weather-agent"},
+		{remoteAddr: "203.0.113.7:51234", expected: "http://internal:8080/weather-agent"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.remoteAddr, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/weather-agent"+testAgentCardPath, nil)
+			req.Host = "internal:8080"
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-Host", "gateway.example.com")
+			req.Header.Set("X-Forwarded-Proto", "https")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			var card models.AgentCard
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &card))
+			assert.Equal(t, tt.expected, card.Url)
+		})
+	}
+}
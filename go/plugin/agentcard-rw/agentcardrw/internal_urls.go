@@ -0,0 +1,62 @@
+package agentcardrw
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/jsonedit"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/netclass"
+)
+
+// internalURLs returns the URLs with an internal host left in a rewritten card, e.g. of other
+// cluster services the url_fields do not cover. URLs below the gateway URL are skipped, clients
+// inside the cluster may address the gateway by an internal name.
+func internalURLs(cardMap map[string]interface{}, hosts *netclass.Classifier, gatewayURL string) []string {
+	found := internalURLSet{}
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case string:
+			found.add(v, hosts, gatewayURL)
+		case map[string]interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	walk(cardMap)
+	return found.sorted()
+}
+
+// internalURLsOf returns the internal URLs of an edited card like internalURLs
+func internalURLsOf(card jsonedit.Value, hosts *netclass.Classifier, gatewayURL string) []string {
+	found := internalURLSet{}
+	card.Walk(func(v jsonedit.Value) {
+		if s, ok := v.Text(); ok {
+			found.add(s, hosts, gatewayURL)
+		}
+	})
+	return found.sorted()
+}
+
+// internalURLSet collects internal URLs that are not below the gateway URL
+type internalURLSet map[string]bool
+
+func (f internalURLSet) add(value string, hosts *netclass.Classifier, gatewayURL string) {
+	if hosts.IsInternalURL(value) && !strings.HasPrefix(value, gatewayURL) {
+		f[value] = true
+	}
+}
+
+func (f internalURLSet) sorted() []string {
+	urls := make([]string, 0, len(f))
+	for u := range f {
+		urls = append(urls, u)
+	}
+	sort.Strings(urls)
+	return urls
+}
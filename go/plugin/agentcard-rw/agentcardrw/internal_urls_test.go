@@ -0,0 +1,27 @@
+package agentcardrw
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/netclass"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInternalURLs(t *testing.T) {
+	hosts, err := netclass.New([]string{"minio"})
+	require.NoError(t, err)
+	var cardMap map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"url": "http://gateway.agents.svc.cluster.local/weather-agent",
+		"documentationUrl": "http://docs.agents.svc.cluster.local/weather",
+		"iconUrl": "https://cdn.example.com/weather.png",
+		"skills": [{"id": "forecast", "examples": ["http://minio:9000/examples/1", "Weather in Paris?", "http://10.0.0.5/x"]}],
+		"provider": {"organization": "Example", "url": "http://10.0.0.5/x"}
+	}`), &cardMap))
+
+	urls := internalURLs(cardMap, hosts, "http://gateway.agents.svc.cluster.local")
+
+	assert.Equal(t, []string{"http://10.0.0.5/x", "http://docs.agents.svc.cluster.local/weather", "http://minio:9000/examples/1"}, urls)
+}
@@ -0,0 +1,98 @@
+package agentcardrw
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/capture"
+)
+
+const (
+	onErrorError       = "error"
+	onErrorPassthrough = "passthrough_original"
+	onErrorRetry       = "retry"
+
+	defaultOnErrorRetries = 1
+
+	// maxReplayBody limits the request bodies buffered to retry card requests
+	maxReplayBody = 64 << 10
+)
+
+// transformError describes why a card was not transformed and the status answered by default.
+// Rejected cards, i.e. backend errors and schema violations, are never subject to on_error.
+type transformError struct {
+	status   int
+	message  string
+	rejected bool
+}
+
+// errorPolicy decides what clients receive when a card cannot be transformed. The nil policy
+// answers with an error.
+type errorPolicy struct {
+	mode    string
+	retries int
+}
+
+// newErrorPolicy validates the on_error mode. Without a mode, errors are returned.
+func newErrorPolicy(mode string, retries int) (*errorPolicy, error) {
+	if retries < 0 {
+		return nil, fmt.Errorf("on_error_retries %d is negative", retries)
+	}
+	switch mode {
+	case "", onErrorError:
+		return nil, nil
+	case onErrorPassthrough:
+		return &errorPolicy{mode: mode}, nil
+	case onErrorRetry:
+		if retries == 0 {
+			retries = defaultOnErrorRetries
+		}
+		return &errorPolicy{mode: mode, retries: retries}, nil
+	default:
+		return nil, fmt.Errorf("unknown mode '%s', use '%s', '%s' or '%s'", mode, onErrorError, onErrorPassthrough, onErrorRetry)
+	}
+}
+
+// replayable buffers the request body for retries and returns a function restoring it before each attempt
+func (p *errorPolicy) replayable(req *http.Request) (func(), error) {
+	if p == nil || p.mode != onErrorRetry || req.Body == nil || req.Body == http.NoBody {
+		return func() {}, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxReplayBody+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxReplayBody {
+		return nil, fmt.Errorf("request body exceeds %d bytes", maxReplayBody)
+	}
+	return func() {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}, nil
+}
+
+// retry reports whether the card request is sent again after a failed attempt
+func (p *errorPolicy) retry(err *transformError, attempt int) bool {
+	return p != nil && p.mode == onErrorRetry && err != nil && !err.rejected && attempt < p.retries
+}
+
+// handle answers a card request that could not be transformed. Cards are passed through with the
+// original headers and body in passthrough_original mode; otherwise the error status is returned.
+func (p *errorPolicy) handle(w http.ResponseWriter, rw *capture.Writer, cr cardRequest, err *transformError, excludeHeaders []string) {
+	if p != nil && p.mode == onErrorPassthrough && !err.rejected {
+		logger.Warning(fmt.Sprintf("cannot transform agent card of %s: %s - passing original through", cr.agentPath, err.message))
+		capture.CopyHeader(w.Header(), rw.Header())
+		cr.setCacheHeaders(w.Header())
+		w.WriteHeader(rw.StatusCode())
+		if _, err := w.Write(rw.Body()); err != nil {
+			logger.Error("failed to write response:", err)
+		}
+		return
+	}
+
+	// Keep tracing and CORS headers, but not those describing the backend body
+	capture.CopyHeader(w.Header(), rw.Header(), excludeHeaders...)
+	cr.setCacheHeaders(w.Header())
+	http.Error(w, err.message, err.status)
+}
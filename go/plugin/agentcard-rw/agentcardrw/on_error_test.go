@@ -0,0 +1,111 @@
+package agentcardrw
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func onErrorHandler(t *testing.T, onError string, backend http.Handler) http.Handler {
+	extra := map[string]interface{}{configKey: map[string]interface{}{"on_error": onError, "on_error_retries": 2}}
+	handler, err := module.registerHandlers(context.Background(), extra, backend)
+	assert.NoError(t, err)
+	return handler
+}
+
+func TestRegisterHandlers_InvalidOnError(t *testing.T) {
+	for _, cfg := range []map[string]interface{}{{"on_error": "ignore"}, {"on_error": "retry", "on_error_retries": -1}} {
+		_, err := module.registerHandlers(context.Background(), map[string]interface{}{configKey: cfg}, http.NotFoundHandler())
+		assert.Error(t, err, cfg)
+	}
+}
+
+func TestOnError_MalformedCard(t *testing.T) {
+	malformed := `{"url": "http://weather:8000"`
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentTypeJSON)
+		w.Header().Set("X-Request-Id", "req-1")
+		_, _ = w.Write([]byte(malformed))
+	})
+
+	tests := []struct {
+		onError    string
+		wantStatus int
+		wantBody   string
+	}{
+		{onError: "", wantStatus: http.StatusInternalServerError, wantBody: "Failed to parse agent card JSON\n"},
+		{onError: onErrorError, wantStatus: http.StatusInternalServerError, wantBody: "Failed to parse agent card JSON\n"},
+		{onError: onErrorPassthrough, wantStatus: http.StatusOK, wantBody: malformed},
+		{onError: onErrorRetry, wantStatus: http.StatusInternalServerError, wantBody: "Failed to parse agent card JSON\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.onError, func(t *testing.T) {
+			rec := newTestHelper(t).makeRequest(onErrorHandler(t, tt.onError, backend), http.MethodGet, "/weather-agent"+testAgentCardPath, testGatewayHost, testHTTPSProtocol)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantBody, rec.Body.String())
+			assert.Equal(t, "req-1", rec.Header().Get("X-Request-Id"))
+		})
+	}
+}
+
+func TestOnError_PassthroughKeepsEncoding(t *testing.T) {
+	original := gzipBytes(t, "<html>not a card</html>")
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Encoding", "gzip")
+		_, _ = w.Write(original)
+	})
+
+	rec := newTestHelper(t).makeRequest(onErrorHandler(t, onErrorPassthrough, backend), http.MethodGet, "/weather-agent"+testAgentCardPath, testGatewayHost, testHTTPSProtocol)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, original, rec.Body.Bytes())
+}
+
+func TestOnError_RetrySucceeds(t *testing.T) {
+	var calls int
+	var bodies []string
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		w.Header().Set("Content-Type", contentTypeJSON)
+		if calls < 3 {
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"url":"http://weather:8000"}}`))
+	})
+	request := `{"jsonrpc":"2.0","id":1,"method":"agent/getAuthenticatedExtendedCard"}`
+
+	req := httptest.NewRequest(http.MethodPost, "/weather-agent", strings.NewReader(request))
+	req.Host = testGatewayHost
+	rec := httptest.NewRecorder()
+	onErrorHandler(t, onErrorRetry, backend).ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []string{request, request, request}, bodies)
+	assert.JSONEq(t, `{"jsonrpc":"2.0","id":1,"result":{"url":"http://`+testGatewayHost+`/weather-agent"}}`, rec.Body.String())
+}
+
+func TestOnError_BackendErrorsAreNotRetried(t *testing.T) {
+	var calls int
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	rec := newTestHelper(t).makeRequest(onErrorHandler(t, onErrorRetry, backend), http.MethodGet, "/weather-agent"+testAgentCardPath, testGatewayHost, testHTTPSProtocol)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, 1, calls)
+}
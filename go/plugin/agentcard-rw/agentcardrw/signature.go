@@ -0,0 +1,276 @@
+package agentcardrw
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+)
+
+const (
+	signaturesKeep  = "keep"
+	signaturesStrip = "strip"
+	signaturesSign  = "sign"
+)
+
+// SignatureConfig controls the signatures of agent cards, which rewriting invalidates. Cards are
+// passed on with their signatures unless mode is strip, or sign to re-sign them with key_file.
+type SignatureConfig struct {
+	Mode    string `json:"mode"`
+	KeyFile string `json:"key_file,omitempty"`
+	KeyID   string `json:"key_id,omitempty"`
+	JKU     string `json:"jku,omitempty"`
+}
+
+// cardSigner strips the signatures of rewritten cards and optionally signs them with the gateway key
+type cardSigner struct {
+	key    crypto.Signer
+	alg    string
+	header string
+}
+
+// newCardSigner loads the signing key. Without config or in keep mode, signatures are kept.
+func newCardSigner(cfg *SignatureConfig) (*cardSigner, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	switch cfg.Mode {
+	case "", signaturesKeep:
+		return nil, nil
+	case signaturesStrip:
+		return &cardSigner{}, nil
+	case signaturesSign:
+	default:
+		return nil, fmt.Errorf("unknown mode '%s', use '%s', '%s' or '%s'", cfg.Mode, signaturesKeep, signaturesStrip, signaturesSign)
+	}
+
+	if cfg.KeyFile == "" {
+		return nil, fmt.Errorf("key_file is required to sign agent cards")
+	}
+	raw, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read key_file: %w", err)
+	}
+	key, alg, err := parseSigningKey(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	header := map[string]string{"alg": alg, "typ": "JOSE"}
+	if cfg.KeyID != "" {
+		header["kid"] = cfg.KeyID
+	}
+	if cfg.JKU != "" {
+		header["jku"] = cfg.JKU
+	}
+	protected, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+	return &cardSigner{key: key, alg: alg, header: base64.RawURLEncoding.EncodeToString(protected)}, nil
+}
+
+// parseSigningKey parses a PEM encoded PKCS#8, SEC 1 or PKCS#1 private key and returns its JWS algorithm
+func parseSigningKey(raw []byte) (crypto.Signer, string, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, "", fmt.Errorf("key_file is not PEM encoded")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("cannot parse private key: %w", err)
+	}
+
+	switch key := key.(type) {
+	case *ecdsa.PrivateKey:
+		if key.Curve != elliptic.P256() {
+			return nil, "", fmt.Errorf("unsupported EC curve %s, use P-256", key.Curve.Params().Name)
+		}
+		return key, "ES256", nil
+	case *rsa.PrivateKey:
+		return key, "RS256", nil
+	case ed25519.PrivateKey:
+		return key, "EdDSA", nil
+	default:
+		return nil, "", fmt.Errorf("unsupported private key type %T", key)
+	}
+}
+
+// signs reports whether cards are signed, which needs them unmarshalled
+func (s *cardSigner) signs() bool {
+	return s != nil && s.key != nil
+}
+
+// apply removes the signatures of a rewritten card and adds the signature of the gateway if it has a key
+func (s *cardSigner) apply(cardMap map[string]interface{}) (map[string]interface{}, error) {
+	if s == nil {
+		return cardMap, nil
+	}
+	delete(cardMap, "signatures")
+	if s.key == nil {
+		return cardMap, nil
+	}
+	signature, err := s.sign(cardMap)
+	if err != nil {
+		return nil, err
+	}
+	cardMap["signatures"] = []interface{}{signature}
+	return cardMap, nil
+}
+
+// sign creates a detached JWS of the card, whose payload is the JCS canonical form (RFC 8785)
+// of the card without signatures, as A2A requires
+func (s *cardSigner) sign(cardMap map[string]interface{}) (map[string]interface{}, error) {
+	var payload bytes.Buffer
+	if err := writeCanonicalJSON(&payload, cardMap); err != nil {
+		return nil, fmt.Errorf("cannot canonicalize agent card: %w", err)
+	}
+	signingInput := s.header + "." + base64.RawURLEncoding.EncodeToString(payload.Bytes())
+
+	var signature []byte
+	var err error
+	switch key := s.key.(type) {
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(key, []byte(signingInput))
+	case *ecdsa.PrivateKey:
+		digest := sha256.Sum256([]byte(signingInput))
+		var r, sig *big.Int
+		if r, sig, err = ecdsa.Sign(rand.Reader, key, digest[:]); err == nil {
+			// JWS uses the fixed-size concatenation of r and s instead of ASN.1
+			signature = make([]byte, 64)
+			r.FillBytes(signature[:32])
+			sig.FillBytes(signature[32:])
+		}
+	default:
+		digest := sha256.Sum256([]byte(signingInput))
+		signature, err = s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot sign agent card: %w", err)
+	}
+	return map[string]interface{}{
+		"protected": s.header,
+		"signature": base64.RawURLEncoding.EncodeToString(signature),
+	}, nil
+}
+
+// writeCanonicalJSON serializes a decoded JSON value per RFC 8785: object keys sorted by UTF-16
+// code units, no whitespace, minimal string escaping and ES6 number formatting
+func writeCanonicalJSON(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case float64:
+		// encoding/json formats floats like ES6 Number.prototype.toString
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return err
+		}
+		return writeCanonicalJSON(buf, f)
+	case string:
+		writeCanonicalString(buf, v)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonicalJSON(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool { return lessUTF16(keys[i], keys[j]) })
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeCanonicalString(buf, key)
+			buf.WriteByte(':')
+			if err := writeCanonicalJSON(buf, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("unsupported JSON value %T", v)
+	}
+	return nil
+}
+
+func writeCanonicalString(buf *bytes.Buffer, s string) {
+	buf.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(buf, `\u%04x`, r)
+			} else {
+				buf.WriteRune(r)
+			}
+		}
+	}
+	buf.WriteByte('"')
+}
+
+// lessUTF16 compares strings by their UTF-16 code units, as RFC 8785 sorts object keys
+func lessUTF16(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
@@ -0,0 +1,136 @@
+package agentcardrw
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const signedCard = `{"name":"Weather","url":"http://weather:8000","signatures":[{"protected":"eyJhbGciOiJFUzI1NiJ9","signature":"agent-signature"}]}`
+
+func writeKeyFile(t *testing.T, key interface{}) string {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "key.pem")
+	assert.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	return path
+}
+
+func signatureHandler(t *testing.T, signatures map[string]interface{}) http.Handler {
+	extra := map[string]interface{}{configKey: map[string]interface{}{"signatures": signatures}}
+	handler, err := module.registerHandlers(context.Background(), extra, newTestHelper(t).createJSONBackend(signedCard))
+	assert.NoError(t, err)
+	return handler
+}
+
+func fetchCard(t *testing.T, handler http.Handler) map[string]interface{} {
+	rec := newTestHelper(t).makeRequest(handler, http.MethodGet, "/weather-agent"+testAgentCardPath, testGatewayHost, testHTTPSProtocol)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var card map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &card))
+	return card
+}
+
+func TestRegisterHandlers_InvalidSignatures(t *testing.T) {
+	garbagePath := filepath.Join(t.TempDir(), "garbage.pem")
+	assert.NoError(t, os.WriteFile(garbagePath, []byte("not a key"), 0o600))
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	assert.NoError(t, err)
+
+	for _, signatures := range []map[string]interface{}{
+		{"mode": "verify"},
+		{"mode": "sign"},
+		{"mode": "sign", "key_file": filepath.Join(t.TempDir(), "missing.pem")},
+		{"mode": "sign", "key_file": garbagePath},
+		{"mode": "sign", "key_file": writeKeyFile(t, p384)},
+	} {
+		extra := map[string]interface{}{configKey: map[string]interface{}{"signatures": signatures}}
+		_, err := module.registerHandlers(context.Background(), extra, http.NotFoundHandler())
+		assert.Error(t, err, signatures)
+	}
+}
+
+func TestSignatures_KeptByDefault(t *testing.T) {
+	card := fetchCard(t, signatureHandler(t, map[string]interface{}{"mode": "keep"}))
+
+	assert.Len(t, card["signatures"], 1)
+}
+
+func TestSignatures_Strip(t *testing.T) {
+	card := fetchCard(t, signatureHandler(t, map[string]interface{}{"mode": "strip"}))
+
+	assert.NotContains(t, card, "signatures")
+	assert.Equal(t, "https://"+testGatewayHost+"/weather-agent", card["url"])
+}
+
+func TestSignatures_SignES256(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	handler := signatureHandler(t, map[string]interface{}{"mode": "sign", "key_file": writeKeyFile(t, key), "key_id": "gateway-1", "jku": "https://gateway.example.com/jwks.json"})
+
+	card := fetchCard(t, handler)
+
+	signatures := card["signatures"].([]interface{})
+	assert.Len(t, signatures, 1)
+	signature := signatures[0].(map[string]interface{})
+	header, err := base64.RawURLEncoding.DecodeString(signature["protected"].(string))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"alg":"ES256","typ":"JOSE","kid":"gateway-1","jku":"https://gateway.example.com/jwks.json"}`, string(header))
+
+	delete(card, "signatures")
+	var payload bytes.Buffer
+	assert.NoError(t, writeCanonicalJSON(&payload, card))
+	digest := sha256.Sum256([]byte(signature["protected"].(string) + "." + base64.RawURLEncoding.EncodeToString(payload.Bytes())))
+	sig, err := base64.RawURLEncoding.DecodeString(signature["signature"].(string))
+	assert.NoError(t, err)
+	assert.Len(t, sig, 64)
+	assert.True(t, ecdsa.Verify(&key.PublicKey, digest[:], new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])))
+}
+
+func TestSignatures_SignEdDSA(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	signer, err := newCardSigner(&SignatureConfig{Mode: signaturesSign, KeyFile: writeKeyFile(t, private)})
+	assert.NoError(t, err)
+
+	card, err := signer.apply(map[string]interface{}{"name": "Weather", "signatures": []interface{}{}})
+	assert.NoError(t, err)
+
+	signature := card["signatures"].([]interface{})[0].(map[string]interface{})
+	sig, err := base64.RawURLEncoding.DecodeString(signature["signature"].(string))
+	assert.NoError(t, err)
+	signingInput := signature["protected"].(string) + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"name":"Weather"}`))
+	assert.True(t, ed25519.Verify(public, []byte(signingInput), sig))
+}
+
+func TestWriteCanonicalJSON(t *testing.T) {
+	// Examples of RFC 8785, section 3.2.2 and 3.2.3
+	var value interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"numbers": [333333333.33333329, 1E30, 4.50, 2e-3, 0.000000000000000000000000001],
+		"string": "\u20ac$\u000F\u000aA'\u0042\u0022\u005c\\\"\/",
+		"literals": [null, true, false]
+	}`), &value))
+	var buf bytes.Buffer
+	assert.NoError(t, writeCanonicalJSON(&buf, value))
+	assert.Equal(t, `{"literals":[null,true,false],"numbers":[333333333.3333333,1e+30,4.5,0.002,1e-27],"string":"€$\u000f\nA'B\"\\\\\"/"}`, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, writeCanonicalJSON(&buf, map[string]interface{}{"\u20ac": 1.0, "\r": 2.0, "\U0001F600": 3.0, "\ufb33": 4.0, "1": 5.0}))
+	assert.Equal(t, "{\"\\r\":2,\"1\":5,\"€\":1,\"\U0001F600\":3,\"\ufb33\":4}", buf.String())
+}
@@ -0,0 +1,152 @@
+package agentcardrw
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Valid transport protocol constants
+const (
+	transportJSONRPC  = "jsonrpc"
+	transportGRPC     = "grpc"
+	transportHTTPJSON = "http+json"
+)
+
+// isValidTransport checks if a transport type is valid (case-insensitive)
+func isValidTransport(transport string) bool {
+	normalized := strings.ToLower(transport)
+	return normalized == transportJSONRPC ||
+		normalized == transportGRPC ||
+		normalized == transportHTTPJSON
+}
+
+// constructExternalURL builds the external gateway URL from gateway URL and agent path
+func constructExternalURL(gatewayURL string, agentPath string) string {
+	// Remove trailing slashes
+	cleanGatewayURL := strings.TrimSuffix(gatewayURL, "/")
+	cleanAgentPath := strings.TrimSuffix(agentPath, "/")
+
+	return cleanGatewayURL + cleanAgentPath
+}
+
+// safeGetString safely extracts a string value from a map
+func safeGetString(m map[string]interface{}, key string) (string, bool) {
+	if val, ok := m[key]; ok {
+		if str, ok := val.(string); ok {
+			return str, true
+		}
+	}
+	return "", false
+}
+
+// safeGetArray safely extracts an array value from a map
+func safeGetArray(m map[string]interface{}, key string) ([]interface{}, bool) {
+	if val, ok := m[key]; ok {
+		if arr, ok := val.([]interface{}); ok {
+			return arr, true
+		}
+	}
+	return nil, false
+}
+
+// transportSet is the allowlist of interface transports, lowercased. A nil set allows the
+// default transports (JSONRPC, GRPC, HTTP+JSON).
+type transportSet map[string]bool
+
+func newTransportSet(transports []string) (transportSet, error) {
+	if transports == nil {
+		return nil, nil
+	}
+	set := make(transportSet, len(transports))
+	for _, transport := range transports {
+		if strings.TrimSpace(transport) == "" {
+			return nil, fmt.Errorf("transport names must not be empty")
+		}
+		set[strings.ToLower(transport)] = true
+	}
+	return set, nil
+}
+
+// allows checks if interfaces of a transport are kept (case-insensitive)
+func (t transportSet) allows(transport string) bool {
+	if t == nil {
+		return isValidTransport(transport)
+	}
+	return t[strings.ToLower(transport)]
+}
+
+// rewriteAdditionalInterfacesMap filters and rewrites additional interfaces using map representation
+// - Keeps only allowed transports, by default JSONRPC, GRPC and HTTP+JSON - case-insensitive
+// - Keeps ws/wss interfaces if their URL is an allowlisted WebSocket route or their transport is allowed
+// - Rewrites URLs to external gateway URLs
+// - Preserves all other fields in the interface objects
+func rewriteAdditionalInterfacesMap(interfaces []interface{}, gatewayURL string, agentPath string, s settings) []interface{} {
+	var result []interface{}
+	externalURL := constructExternalURL(gatewayURL, agentPath)
+
+	for _, iface := range interfaces {
+		// Try to cast to map
+		ifaceMap, ok := iface.(map[string]interface{})
+		if !ok {
+			continue // Skip non-map entries
+		}
+
+		// Get transport type
+		transport, ok := safeGetString(ifaceMap, "transport")
+		if !ok {
+			continue // Skip entries without transport
+		}
+
+		url, hasURL := safeGetString(ifaceMap, "url")
+		if rewritten, keep := rewriteInterface(transport, url, hasURL, gatewayURL, externalURL, s); keep {
+			if hasURL {
+				ifaceMap["url"] = rewritten
+			}
+			result = append(result, ifaceMap)
+		}
+	}
+
+	return result
+}
+
+// rewriteInterface decides whether an additional interface is kept and returns the URL it is
+// published with. hasURL reports whether the interface has a url.
+func rewriteInterface(transport, url string, hasURL bool, gatewayURL, externalURL string, s settings) (string, bool) {
+	// WebSocket interfaces are proxied by the gateway if allowlisted, any transport name is kept
+	if hasURL && isWebSocketURL(url) {
+		if wsURL, ok := s.websockets.externalURL(url, gatewayURL); ok {
+			return wsURL, true
+		}
+		if s.transports.allows(transport) {
+			// http becomes ws and https becomes wss
+			return "ws" + strings.TrimPrefix(externalURL, "http"), true
+		}
+		return "", false
+	}
+
+	// Only keep allowed transports and rewrite their URLs to the gateway URL,
+	// all other transports are implicitly removed
+	return externalURL, s.transports.allows(transport)
+}
+
+// rewriteAgentCardMap transforms URLs to external gateway URLs in an agent card map
+// This function preserves all unknown fields in the agent card
+func rewriteAgentCardMap(cardMap map[string]interface{}, gatewayURL string, agentPath string, s settings) map[string]interface{} {
+	externalURL := constructExternalURL(gatewayURL, agentPath)
+
+	// Remove internal-only fields before anything else is rewritten
+	s.stripFields.strip(cardMap)
+
+	// Rewrite main URL, URLs of the agent in other fields are rewritten relative to it
+	if agentURL, ok := safeGetString(cardMap, "url"); ok {
+		cardMap["url"] = externalURL
+		s.urlFields.rewrite(cardMap, agentURL, externalURL)
+	}
+
+	// Rewrite and filter additional interfaces
+	if interfaces, ok := safeGetArray(cardMap, "additionalInterfaces"); ok {
+		cardMap["additionalInterfaces"] = rewriteAdditionalInterfacesMap(interfaces, gatewayURL, agentPath, s)
+	}
+	normalizePreferredTransport(cardMap, s.transports)
+	return cardMap
+}
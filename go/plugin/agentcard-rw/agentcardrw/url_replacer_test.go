@@ -1,7 +1,10 @@
-package main
+package agentcardrw
 
 import (
+	"encoding/json"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestConstructExternalURL(t *testing.T) {
@@ -152,7 +155,7 @@ func TestRewriteAdditionalInterfacesMap(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := rewriteAdditionalInterfacesMap(tt.interfaces, tt.gatewayURL, tt.agentPath)
+			result := rewriteAdditionalInterfacesMap(tt.interfaces, tt.gatewayURL, tt.agentPath, settings{})
 
 			if len(result) != len(tt.expected) {
 				t.Errorf("rewriteAdditionalInterfacesMap() returned %d interfaces, want %d",
@@ -292,8 +295,60 @@ func TestRewriteAgentCardMap(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := rewriteAgentCardMap(tt.cardMap, tt.gatewayURL, tt.agentPath)
+			result := rewriteAgentCardMap(tt.cardMap, tt.gatewayURL, tt.agentPath, settings{})
 			tt.checkFunc(t, result)
 		})
 	}
 }
+
+func TestRewriteAdditionalInterfacesMap_AllowedTransports(t *testing.T) {
+	transports, err := newTransportSet([]string{"JSONRPC", "websocket", "SSE"})
+	assert.NoError(t, err)
+	interfaces := []interface{}{
+		map[string]interface{}{"transport": "jsonrpc", "url": "http://agent:8080/"},
+		map[string]interface{}{"transport": "GRPC", "url": "http://agent:50051/"},
+		map[string]interface{}{"transport": "sse", "url": "http://agent:8080/events"},
+		map[string]interface{}{"transport": "WEBSOCKET", "url": "ws://agent:8080/ws"},
+	}
+
+	result := rewriteAdditionalInterfacesMap(interfaces, "https://gateway.example.com", "/agent", settings{transports: transports})
+
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"transport": "jsonrpc", "url": "https://gateway.example.com/agent"},
+		map[string]interface{}{"transport": "sse", "url": "https://gateway.example.com/agent"},
+		map[string]interface{}{"transport": "WEBSOCKET", "url": "wss://gateway.example.com/agent"},
+	}, result)
+
+	none, err := newTransportSet([]string{})
+	assert.NoError(t, err)
+	assert.Empty(t, rewriteAdditionalInterfacesMap(interfaces, "https://gateway.example.com", "/agent", settings{transports: none}))
+
+	_, err = newTransportSet([]string{" "})
+	assert.Error(t, err)
+}
+
+func BenchmarkRewriteAgentCardMap(b *testing.B) {
+	card := []byte(`{
+		"name": "Weather",
+		"url": "http://weather.default.svc.cluster.local:8000/",
+		"documentationUrl": "http://weather.default.svc.cluster.local:8000/docs",
+		"preferredTransport": "JSONRPC",
+		"skills": [{"id": "forecast", "name": "Forecast", "tags": ["weather"]}],
+		"additionalInterfaces": [
+			{"transport": "JSONRPC", "url": "http://weather.default.svc.cluster.local:8000/"},
+			{"transport": "GRPC", "url": "http://weather.default.svc.cluster.local:9000"}
+		]
+	}`)
+	var s settings
+	s.urlFields, _ = newURLFields(nil)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		// The rewrite modifies the map, so every iteration gets a fresh one
+		b.StopTimer()
+		var cardMap map[string]interface{}
+		_ = json.Unmarshal(card, &cardMap)
+		b.StartTimer()
+		_ = rewriteAgentCardMap(cardMap, "https://gateway.example.com", "/weather-agent", s)
+	}
+}
@@ -0,0 +1,167 @@
+package agentcardrw
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/wsproxy"
+)
+
+const (
+	defaultWebSocketIdleTimeout    = 5 * time.Minute
+	defaultWebSocketMaxConnections = 100
+)
+
+// websocketAuthClient asks the auth_url of routes whether a handshake is authenticated
+var websocketAuthClient = &http.Client{Timeout: 5 * time.Second}
+
+// WebSocketRoute allows proxying WebSocket connections from a gateway path to an agent URL.
+// Handshakes must come from one of AllowedOrigins, be accepted by AuthURL, or both.
+// ForwardHeaders lists the client headers passed to the agent besides the WebSocket headers.
+type WebSocketRoute struct {
+	Path           string   `json:"path"`
+	URL            string   `json:"url"`
+	AllowedOrigins []string `json:"allowed_origins,omitempty"`
+	AuthURL        string   `json:"auth_url,omitempty"`
+	ForwardHeaders []string `json:"forward_headers,omitempty"`
+	IdleTimeout    string   `json:"idle_timeout,omitempty"`
+	MaxConnections int      `json:"max_connections,omitempty"`
+}
+
+// websocketRoute is a validated route
+type websocketRoute struct {
+	proxy   *wsproxy.Proxy
+	origins []string
+	authURL string
+}
+
+// websocketRoutes is the allowlist of WebSocket upstreams. Only upgrades on configured
+// paths are proxied, and only ws/wss interfaces of configured upstreams are kept in agent cards.
+type websocketRoutes struct {
+	routes map[string]*websocketRoute
+}
+
+func newWebSocketRoutes(routes []WebSocketRoute) (*websocketRoutes, error) {
+	if len(routes) == 0 {
+		return nil, nil
+	}
+	parsed := make(map[string]*websocketRoute, len(routes))
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, "/") {
+			return nil, fmt.Errorf("path '%s' must start with /", route.Path)
+		}
+		path := strings.TrimSuffix(route.Path, "/")
+		if _, ok := parsed[path]; ok {
+			return nil, fmt.Errorf("duplicate path '%s'", route.Path)
+		}
+		if len(route.AllowedOrigins) == 0 && route.AuthURL == "" {
+			return nil, fmt.Errorf("path '%s' requires allowed_origins or auth_url", route.Path)
+		}
+		if route.AuthURL != "" && !strings.HasPrefix(route.AuthURL, "http://") && !strings.HasPrefix(route.AuthURL, "https://") {
+			return nil, fmt.Errorf("invalid auth_url '%s'", route.AuthURL)
+		}
+		if route.MaxConnections < 0 {
+			return nil, fmt.Errorf("max_connections %d is negative", route.MaxConnections)
+		}
+		proxy, err := wsproxy.New(route.URL)
+		if err != nil {
+			return nil, err
+		}
+		proxy.Headers = route.ForwardHeaders
+		proxy.IdleTimeout = defaultWebSocketIdleTimeout
+		if route.IdleTimeout != "" {
+			if proxy.IdleTimeout, err = time.ParseDuration(route.IdleTimeout); err != nil || proxy.IdleTimeout <= 0 {
+				return nil, fmt.Errorf("invalid idle_timeout '%s'", route.IdleTimeout)
+			}
+		}
+		proxy.MaxConnections = defaultWebSocketMaxConnections
+		if route.MaxConnections > 0 {
+			proxy.MaxConnections = route.MaxConnections
+		}
+		proxy.OnError = func(err error) {
+			logger.Warning(fmt.Sprintf("websocket %s failed: %v", path, err))
+		}
+		origins := make([]string, len(route.AllowedOrigins))
+		for i, origin := range route.AllowedOrigins {
+			origins[i] = strings.ToLower(strings.TrimSuffix(origin, "/"))
+		}
+		parsed[path] = &websocketRoute{proxy: proxy, origins: origins, authURL: route.AuthURL}
+	}
+	return &websocketRoutes{routes: parsed}, nil
+}
+
+// route returns the route of a WebSocket handshake on a configured path
+func (r *websocketRoutes) route(req *http.Request) (*websocketRoute, bool) {
+	if r == nil || !wsproxy.IsUpgrade(req) {
+		return nil, false
+	}
+	route, ok := r.routes[strings.TrimSuffix(req.URL.Path, "/")]
+	return route, ok
+}
+
+// admit checks the origin of a handshake against allowed_origins and has auth_url authenticate it
+// with the Authorization and Cookie headers of the client. It writes an error and returns false
+// if the handshake is rejected.
+func (route *websocketRoute) admit(w http.ResponseWriter, req *http.Request) bool {
+	if len(route.origins) > 0 && !slices.Contains(route.origins, strings.ToLower(req.Header.Get("Origin"))) {
+		logger.Warning(fmt.Sprintf("rejected websocket handshake on %s from origin '%s'", req.URL.Path, req.Header.Get("Origin")))
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return false
+	}
+	if route.authURL == "" {
+		return true
+	}
+	if err := route.authenticate(req); err != nil {
+		logger.Warning(fmt.Sprintf("rejected websocket handshake on %s: %v", req.URL.Path, err))
+		http.Error(w, "websocket handshake not authorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// authenticate asks auth_url whether the credentials of the handshake are valid
+func (route *websocketRoute) authenticate(req *http.Request) error {
+	authReq, err := http.NewRequestWithContext(req.Context(), http.MethodGet, route.authURL, nil)
+	if err != nil {
+		return err
+	}
+	for _, name := range []string{"Authorization", "Cookie"} {
+		if values := req.Header.Values(name); len(values) > 0 {
+			authReq.Header[name] = values
+		}
+	}
+	authReq.Header.Set("X-Forwarded-Uri", req.URL.RequestURI())
+	resp, err := websocketAuthClient.Do(authReq)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.New("auth_url answered " + resp.Status)
+	}
+	return nil
+}
+
+// externalURL returns the gateway URL of a ws/wss agent URL, if the agent URL is allowlisted
+func (r *websocketRoutes) externalURL(agentURL, gatewayURL string) (string, bool) {
+	if r == nil {
+		return "", false
+	}
+	for path, route := range r.routes {
+		if strings.TrimSuffix(route.proxy.Target(), "/") == strings.TrimSuffix(agentURL, "/") {
+			// http becomes ws and https becomes wss
+			return "ws" + strings.TrimPrefix(strings.TrimSuffix(gatewayURL, "/"), "http") + path, true
+		}
+	}
+	return "", false
+}
+
+// isWebSocketURL checks if a URL uses the ws or wss scheme (case-insensitive)
+func isWebSocketURL(url string) bool {
+	lower := strings.ToLower(url)
+	return strings.HasPrefix(lower, "ws://") || strings.HasPrefix(lower, "wss://")
+}
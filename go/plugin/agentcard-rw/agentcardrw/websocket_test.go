@@ -0,0 +1,184 @@
+package agentcardrw
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testOrigin = "https://app.example.com"
+
+func websocketConfig(url string) map[string]interface{} {
+	return websocketRouteConfig(map[string]interface{}{"path": "/weather-agent/ws", "url": url, "allowed_origins": []interface{}{testOrigin}})
+}
+
+func websocketRouteConfig(route map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		configKey: map[string]interface{}{
+			"websockets": []interface{}{route},
+		},
+	}
+}
+
+// newEchoAgent accepts WebSocket handshakes and echoes every byte back
+func newEchoAgent(t *testing.T) *httptest.Server {
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, buf, err := w.(http.Hijacker).Hijack()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+		_, _ = buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+		_ = buf.Flush()
+		_, _ = io.Copy(conn, buf)
+	}))
+	t.Cleanup(agent.Close)
+	return agent
+}
+
+// handshakeStatus sends a WebSocket handshake with the extra header lines to handler
+func handshakeStatus(t *testing.T, handler http.Handler, headerLines string) int {
+	gateway := httptest.NewServer(handler)
+	t.Cleanup(gateway.Close)
+	conn, err := net.Dial("tcp", strings.TrimPrefix(gateway.URL, "http://"))
+	if !assert.NoError(t, err) {
+		return 0
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET /weather-agent/ws HTTP/1.1\r\nHost: gateway\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n" + headerLines + "\r\n"))
+	assert.NoError(t, err)
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if !assert.NoError(t, err) {
+		return 0
+	}
+	return resp.StatusCode
+}
+
+func TestRegisterHandlers_InvalidWebSockets(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes []interface{}
+	}{
+		{name: "relative path", routes: []interface{}{map[string]interface{}{"path": "ws", "url": "ws://agent:8000/ws"}}},
+		{name: "http url", routes: []interface{}{map[string]interface{}{"path": "/ws", "url": "http://agent:8000/ws"}}},
+		{name: "duplicate path", routes: []interface{}{
+			map[string]interface{}{"path": "/ws", "url": "ws://a:8000/ws", "allowed_origins": []interface{}{testOrigin}},
+			map[string]interface{}{"path": "/ws/", "url": "ws://b:8000/ws", "allowed_origins": []interface{}{testOrigin}},
+		}},
+		{name: "unprotected", routes: []interface{}{map[string]interface{}{"path": "/ws", "url": "ws://agent:8000/ws"}}},
+		{name: "auth url", routes: []interface{}{map[string]interface{}{"path": "/ws", "url": "ws://agent:8000/ws", "auth_url": "auth:8080"}}},
+		{name: "idle timeout", routes: []interface{}{map[string]interface{}{"path": "/ws", "url": "ws://agent:8000/ws", "auth_url": "http://auth:8080", "idle_timeout": "0s"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			extra := map[string]interface{}{configKey: map[string]interface{}{"websockets": tt.routes}}
+			_, err := module.registerHandlers(context.Background(), extra, http.NotFoundHandler())
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAgentCard_KeepsAllowlistedWebSocketInterfaces(t *testing.T) {
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentTypeJSON)
+		_, _ = w.Write([]byte(`{"url":"http://weather:8000","additionalInterfaces":[
+			{"transport":"WEBSOCKET","url":"ws://weather:8000/ws"},
+			{"transport":"WEBSOCKET","url":"ws://weather:8000/other"}]}`))
+	})
+	handler, err := module.registerHandlers(context.Background(), websocketConfig("ws://weather:8000/ws"), backend)
+	assert.NoError(t, err)
+
+	rec := newTestHelper(t).makeRequest(handler, http.MethodGet, "/weather-agent"+testAgentCardPath, testGatewayHost, testHTTPSProtocol)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var card map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &card))
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"transport": "WEBSOCKET", "url": "wss://" + testGatewayHost + "/weather-agent/ws"},
+	}, card["additionalInterfaces"])
+}
+
+func TestWebSocketProxy(t *testing.T) {
+	agent := newEchoAgent(t)
+	backendCalled := false
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		backendCalled = true
+	})
+	handler, err := module.registerHandlers(context.Background(), websocketConfig("ws"+strings.TrimPrefix(agent.URL, "http")+"/ws"), backend)
+	assert.NoError(t, err)
+	gateway := httptest.NewServer(handler)
+	defer gateway.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(gateway.URL, "http://"))
+	assert.NoError(t, err)
+	defer conn.Close()
+	_, err = conn.Write([]byte("GET /weather-agent/ws HTTP/1.1\r\nHost: gateway\r\nConnection: Upgrade\r\nUpgrade: websocket\r\nOrigin: https://app.example.com\r\n\r\nping"))
+	assert.NoError(t, err)
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	echo := make([]byte, 4)
+	_, err = io.ReadFull(reader, echo)
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", string(echo))
+	assert.False(t, backendCalled)
+}
+
+func TestWebSocketProxy_ChecksOrigin(t *testing.T) {
+	agent := newEchoAgent(t)
+	handler, err := module.registerHandlers(context.Background(), websocketConfig("ws"+strings.TrimPrefix(agent.URL, "http")+"/ws"), http.NotFoundHandler())
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusSwitchingProtocols, handshakeStatus(t, handler, "Origin: HTTPS://app.example.com\r\n"))
+	assert.Equal(t, http.StatusForbidden, handshakeStatus(t, handler, "Origin: https://evil.example.com\r\n"))
+	assert.Equal(t, http.StatusForbidden, handshakeStatus(t, handler, ""), "handshakes without origin are rejected")
+}
+
+func TestWebSocketProxy_AuthenticatesHandshakes(t *testing.T) {
+	agent := newEchoAgent(t)
+	auth := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer valid" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	t.Cleanup(auth.Close)
+	handler, err := module.registerHandlers(context.Background(), websocketRouteConfig(map[string]interface{}{
+		"path": "/weather-agent/ws", "url": "ws" + strings.TrimPrefix(agent.URL, "http") + "/ws", "auth_url": auth.URL,
+	}), http.NotFoundHandler())
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusSwitchingProtocols, handshakeStatus(t, handler, "Authorization: Bearer valid\r\n"))
+	assert.Equal(t, http.StatusUnauthorized, handshakeStatus(t, handler, "Authorization: Bearer forged\r\n"))
+	assert.Equal(t, http.StatusUnauthorized, handshakeStatus(t, handler, ""))
+}
+
+func TestWebSocketProxy_HidesUpstreamErrors(t *testing.T) {
+	handler, err := module.registerHandlers(context.Background(), websocketConfig("ws://127.0.0.1:1/ws"), http.NotFoundHandler())
+	assert.NoError(t, err)
+	gateway := httptest.NewServer(handler)
+	t.Cleanup(gateway.Close)
+
+	req, _ := http.NewRequest(http.MethodGet, gateway.URL+"/weather-agent/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Origin", testOrigin)
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.NotContains(t, string(body), "127.0.0.1")
+}
@@ -0,0 +1,28 @@
+// Package main is the standalone agentcard-rw plugin, implemented by package agentcardrw
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/agentic-layer/agent-gateway-krakend/plugin/agentcard-rw/agentcardrw"
+)
+
+type registerer string
+
+// HandlerRegisterer is the symbol KrakenD looks up to register plugins
+var HandlerRegisterer = registerer(agentcardrw.Module.Name)
+
+func main() {}
+
+// RegisterHandlers registers the plugin with KrakenD
+func (r registerer) RegisterHandlers(f func(
+	name string,
+	handler func(context.Context, map[string]interface{}, http.Handler) (http.Handler, error),
+)) {
+	agentcardrw.Module.RegisterHandlers(f)
+}
+
+func (r registerer) RegisterLogger(v interface{}) {
+	agentcardrw.Module.RegisterLogger(v)
+}
@@ -3,12 +3,14 @@ package main
 import (
 	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 
+	"github.com/agentic-layer/agent-gateway-krakend/lib/capture"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/configschema"
 	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
 )
 
 const pluginName = "body-logger"
@@ -27,38 +29,28 @@ func init() {
 }
 
 type config struct {
-	SkipPaths []string `json:"skip_paths"`
+	SkipPaths []string        `json:"skip_paths"`
+	Logging   logging.Options `json:"logging,omitempty"`
 }
 
 func parseConfig(extra map[string]interface{}) (config, error) {
 	var cfg config
-	raw, ok := extra["body_logger_config"]
-	if !ok {
-		return cfg, nil
+	if err := configschema.Validate(pluginName, extra["body_logger_config"]); err != nil {
+		return cfg, fmt.Errorf("invalid extra_config.body_logger_config: %w", err)
 	}
-	b, err := json.Marshal(raw)
-	if err != nil {
-		return cfg, fmt.Errorf("failed to marshal config: %w", err)
-	}
-	if err := json.Unmarshal(b, &cfg); err != nil {
-		return cfg, fmt.Errorf("failed to unmarshal config: %w", err)
-	}
-	return cfg, nil
+	err := pluginkit.DecodeConfig(extra, "body_logger_config", &cfg)
+	return cfg, err
 }
 
 func (r registerer) RegisterHandlers(f func(
 	name string,
 	handler func(context.Context, map[string]interface{}, http.Handler) (http.Handler, error),
 )) {
-	f(string(r), r.registerHandlers)
-	logger.Info("registered")
+	pluginkit.RegisterHandlers(f, string(r), r.registerHandlers, logger)
 }
 
 func (r registerer) RegisterLogger(v interface{}) {
-	if kl, ok := logging.Wrap(v, pluginName); ok {
-		logger = kl
-	}
-	logger.Info("logger registered")
+	pluginkit.RegisterLogger(v, pluginName, &logger)
 }
 
 func (r registerer) registerHandlers(_ context.Context, extra map[string]interface{}, handler http.Handler) (http.Handler, error) {
@@ -66,6 +58,9 @@ func (r registerer) registerHandlers(_ context.Context, extra map[string]interfa
 	if err != nil {
 		logger.Warning(fmt.Sprintf("failed to parse config, continuing without skip_paths: %v", err))
 	}
+	if err := logging.Configure(pluginName, cfg.Logging); err != nil {
+		return nil, fmt.Errorf("invalid logging configuration: %w", err)
+	}
 
 	skipPaths := make(map[string]bool, len(cfg.SkipPaths))
 	for _, p := range cfg.SkipPaths {
@@ -78,6 +73,7 @@ func (r registerer) registerHandlers(_ context.Context, extra map[string]interfa
 
 func (r registerer) handleRequest(handler http.Handler, skipPaths map[string]bool) func(w http.ResponseWriter, req *http.Request) {
 	return func(w http.ResponseWriter, req *http.Request) {
+		log := logging.FromRequest(logger, req)
 		if skipPaths[req.URL.Path] {
 			handler.ServeHTTP(w, req)
 			return
@@ -87,42 +83,30 @@ func (r registerer) handleRequest(handler http.Handler, skipPaths map[string]boo
 		if req.Body != nil {
 			body, err := io.ReadAll(req.Body)
 			if err != nil {
-				logger.Error("failed to read request body:", err)
+				log.Error("failed to read request body:", err)
 			} else {
 				if len(body) > 0 {
-					logger.Debug(fmt.Sprintf("request [%s %s]:\n%s", req.Method, req.URL.Path, string(body)))
+					log.Debug(fmt.Sprintf("request [%s %s]:\n%s", req.Method, req.URL.Path, string(body)))
 				}
 				req.Body = io.NopCloser(bytes.NewReader(body))
 			}
 		}
 
 		// Capture response to log it
-		rw := &captureWriter{ResponseWriter: w, body: &bytes.Buffer{}, statusCode: http.StatusOK}
+		rw := capture.New(w, 0)
+		defer rw.Release()
 		handler.ServeHTTP(rw, req)
 
 		// Log response body
-		if rw.body.Len() > 0 {
-			logger.Debug(fmt.Sprintf("response [%s %s] status=%d:\n%s", req.Method, req.URL.Path, rw.statusCode, rw.body.String()))
+		if len(rw.Body()) > 0 {
+			log.Debug(fmt.Sprintf("response [%s %s] status=%d:\n%s", req.Method, req.URL.Path, rw.StatusCode(), rw.Body()))
 		}
 
 		// Flush captured response to actual writer
-		w.WriteHeader(rw.statusCode)
-		if _, err := w.Write(rw.body.Bytes()); err != nil {
-			logger.Error("failed to write response:", err)
+		capture.CopyHeader(w.Header(), rw.Header())
+		w.WriteHeader(rw.StatusCode())
+		if _, err := w.Write(rw.Body()); err != nil {
+			log.Error("failed to write response:", err)
 		}
 	}
 }
-
-type captureWriter struct {
-	http.ResponseWriter
-	body       *bytes.Buffer
-	statusCode int
-}
-
-func (cw *captureWriter) Write(b []byte) (int, error) {
-	return cw.body.Write(b)
-}
-
-func (cw *captureWriter) WriteHeader(statusCode int) {
-	cw.statusCode = statusCode
-}
@@ -6,6 +6,8 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/capture"
 )
 
 func newHandler(t *testing.T, extra map[string]interface{}, next http.Handler) http.Handler {
@@ -45,7 +47,7 @@ func TestHandleRequest_LogsRequestAndResponse(t *testing.T) {
 }
 
 // TestHandleRequest_SkipPaths verifies that requests to a configured skip_path
-// bypass the captureWriter and are written directly to the real ResponseWriter.
+// bypass the capture writer and are written directly to the real ResponseWriter.
 func TestHandleRequest_SkipPaths(t *testing.T) {
 	const responseBody = "health ok"
 
@@ -77,9 +79,9 @@ func TestHandleRequest_SkipPaths(t *testing.T) {
 	}
 
 	// For a skipped path the handler should write directly to the real ResponseWriter,
-	// not a captureWriter – verify the writer seen by the backend is not a captureWriter.
-	if _, isCaptured := backendWriter.(*captureWriter); isCaptured {
-		t.Error("skipped path should not wrap ResponseWriter in captureWriter")
+	// not a capture writer – verify the writer seen by the backend is not a capture writer.
+	if _, isCaptured := backendWriter.(*capture.Writer); isCaptured {
+		t.Error("skipped path should not wrap ResponseWriter in a capture writer")
 	}
 }
 
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/configschema"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+)
+
+const (
+	pluginName = "client-cert"
+	configKey  = "client_cert_config"
+)
+
+// Identity headers forwarded to agents. Values sent by clients are always removed.
+const (
+	headerSAN         = "X-Client-Cert-SAN"
+	headerSPIFFEID    = "X-Client-Cert-SPIFFE-ID"
+	headerSubject     = "X-Client-Cert-Subject"
+	headerFingerprint = "X-Client-Cert-Fingerprint"
+)
+
+var identityHeaders = []string{headerSAN, headerSPIFFEID, headerSubject, headerFingerprint}
+
+type config struct {
+	// CertHeader names the header in which a TLS-terminating proxy passes the URL-encoded PEM
+	// client certificate. Without it, the certificate of the TLS connection is used. The header
+	// is only accepted from TrustedProxies and its certificate must chain to a CA of CAFile.
+	CertHeader       string          `json:"cert_header,omitempty"`
+	TrustedProxies   []string        `json:"trusted_proxies,omitempty"`
+	CAFile           string          `json:"ca_file,omitempty"`
+	Required         bool            `json:"required,omitempty"`
+	AllowedSPIFFEIDs []string        `json:"allowed_spiffe_ids,omitempty"`
+	AllowedSANs      []string        `json:"allowed_sans,omitempty"`
+	Logging          logging.Options `json:"logging,omitempty"`
+}
+
+// identity is the client identity taken from a certificate
+type identity struct {
+	sans        []string
+	spiffeID    string
+	subject     string
+	fingerprint string
+}
+
+func newIdentity(cert *x509.Certificate) identity {
+	id := identity{subject: cert.Subject.String()}
+	sum := sha256.Sum256(cert.Raw)
+	id.fingerprint = hex.EncodeToString(sum[:])
+	id.sans = append(id.sans, cert.DNSNames...)
+	id.sans = append(id.sans, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		id.sans = append(id.sans, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		id.sans = append(id.sans, uri.String())
+		if uri.Scheme == "spiffe" && id.spiffeID == "" {
+			id.spiffeID = uri.String()
+		}
+	}
+	return id
+}
+
+// matchers match names exactly, by a trailing * as prefix or, for DNS names, by a leading *. as
+// subdomain
+type matchers []string
+
+func (m matchers) match(value string) bool {
+	for _, pattern := range m {
+		switch {
+		case strings.HasSuffix(pattern, "*"):
+			if strings.HasPrefix(value, strings.TrimSuffix(pattern, "*")) {
+				return true
+			}
+		case strings.HasPrefix(pattern, "*."):
+			if strings.HasSuffix(value, pattern[1:]) && len(value) > len(pattern)-1 {
+				return true
+			}
+		case strings.EqualFold(pattern, value):
+			return true
+		}
+	}
+	return false
+}
+
+// verifier extracts and authorizes client certificates
+type verifier struct {
+	certHeader string
+	proxies    []*net.IPNet
+	roots      *x509.CertPool
+	required   bool
+	spiffeIDs  matchers
+	sans       matchers
+}
+
+func newVerifier(cfg config) (*verifier, error) {
+	if cfg.CertHeader != "" && (len(cfg.TrustedProxies) == 0 || cfg.CAFile == "") {
+		return nil, errors.New("invalid cert_header configuration: trusted_proxies and ca_file are required to accept certificates from a proxy")
+	}
+	proxies, err := parseNetworks(cfg.TrustedProxies)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trusted_proxies configuration: %w", err)
+	}
+	var roots *x509.CertPool
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read ca_file: %w", err)
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("ca_file %s contains no PEM certificates", cfg.CAFile)
+		}
+	}
+	for _, id := range cfg.AllowedSPIFFEIDs {
+		if !strings.HasPrefix(id, "spiffe://") {
+			return nil, fmt.Errorf("invalid allowed_spiffe_ids configuration: '%s' is not a SPIFFE ID", id)
+		}
+	}
+	for _, san := range cfg.AllowedSANs {
+		if san == "" || san == "*" {
+			return nil, fmt.Errorf("invalid allowed_sans configuration: '%s' matches any certificate", san)
+		}
+	}
+	return &verifier{
+		certHeader: cfg.CertHeader,
+		proxies:    proxies,
+		roots:      roots,
+		required:   cfg.Required || len(cfg.AllowedSPIFFEIDs) > 0 || len(cfg.AllowedSANs) > 0,
+		spiffeIDs:  cfg.AllowedSPIFFEIDs,
+		sans:       cfg.AllowedSANs,
+	}, nil
+}
+
+// parseNetworks parses IP addresses and CIDR ranges
+func parseNetworks(entries []string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range entries {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address '%s'", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			entry = fmt.Sprintf("%s/%d", ip, bits)
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR range '%s'", entry)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// trustedProxy reports whether the peer of a request is a trusted proxy
+func (v *verifier) trustedProxy(req *http.Request) bool {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range v.proxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// certificate returns the verified client certificate of a request, if any. The certificate
+// header is ignored unless the peer is a trusted proxy.
+func (v *verifier) certificate(req *http.Request) (*x509.Certificate, error) {
+	if v.certHeader == "" {
+		if req.TLS == nil || len(req.TLS.PeerCertificates) == 0 {
+			return nil, nil
+		}
+		return v.verify(req.TLS.PeerCertificates[0], req.TLS.PeerCertificates[1:])
+	}
+
+	value := req.Header.Get(v.certHeader)
+	if value == "" || !v.trustedProxy(req) {
+		return nil, nil
+	}
+	decoded, err := url.QueryUnescape(value)
+	if err != nil {
+		return nil, fmt.Errorf("certificate in %s is not URL-encoded", v.certHeader)
+	}
+	// The leaf certificate may be followed by intermediates
+	var chain []*x509.Certificate
+	for rest := []byte(decoded); ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("certificate in %s is not PEM encoded", v.certHeader)
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("certificate in %s is not PEM encoded", v.certHeader)
+	}
+	return v.verify(chain[0], chain[1:])
+}
+
+// verify checks that a client certificate chains to a CA of ca_file. Without ca_file,
+// certificates of TLS connections have been verified by KrakenD.
+func (v *verifier) verify(cert *x509.Certificate, intermediates []*x509.Certificate) (*x509.Certificate, error) {
+	if v.roots == nil {
+		return cert, nil
+	}
+	pool := x509.NewCertPool()
+	for _, intermediate := range intermediates {
+		pool.AddCert(intermediate)
+	}
+	opts := x509.VerifyOptions{Roots: v.roots, Intermediates: pool, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}
+	if _, err := cert.Verify(opts); err != nil {
+		return nil, fmt.Errorf("untrusted client certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// allows reports whether the identity matches an allowed SPIFFE ID or SAN. Without allow
+// lists, any certificate is allowed.
+func (v *verifier) allows(id identity) bool {
+	if len(v.spiffeIDs) == 0 && len(v.sans) == 0 {
+		return true
+	}
+	if id.spiffeID != "" && v.spiffeIDs.match(id.spiffeID) {
+		return true
+	}
+	for _, san := range id.sans {
+		if v.sans.match(san) {
+			return true
+		}
+	}
+	return false
+}
+
+type registerer string
+
+// HandlerRegisterer is the symbol KrakenD looks up to register plugins
+var HandlerRegisterer = registerer(pluginName)
+var logger = logging.New(pluginName)
+
+func main() {}
+
+func init() {
+	logger.Info("loaded")
+}
+
+// RegisterHandlers registers the plugin with KrakenD
+func (r registerer) RegisterHandlers(f func(
+	name string,
+	handler func(context.Context, map[string]interface{}, http.Handler) (http.Handler, error),
+)) {
+	pluginkit.RegisterHandlers(f, string(r), r.registerHandlers, logger)
+}
+
+func (r registerer) RegisterLogger(v interface{}) {
+	pluginkit.RegisterLogger(v, pluginName, &logger)
+}
+
+func parseConfig(extra map[string]interface{}) (config, error) {
+	var cfg config
+	if err := configschema.Validate(pluginName, extra[configKey]); err != nil {
+		return cfg, fmt.Errorf("invalid extra_config.%s: %w", configKey, err)
+	}
+	err := pluginkit.DecodeConfig(extra, configKey, &cfg)
+	return cfg, err
+}
+
+func (r registerer) registerHandlers(_ context.Context, extra map[string]interface{}, handler http.Handler) (http.Handler, error) {
+	cfg, err := parseConfig(extra)
+	if err != nil {
+		return nil, err
+	}
+	if err := logging.Configure(pluginName, cfg.Logging); err != nil {
+		return nil, fmt.Errorf("invalid logging configuration: %w", err)
+	}
+	v, err := newVerifier(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("plugin initialized successfully")
+	return http.HandlerFunc(r.handleRequest(handler, v)), nil
+}
+
+func (r registerer) handleRequest(handler http.Handler, v *verifier) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		log := logging.FromRequest(logger, req)
+
+		// Identity headers are only ever set by the gateway
+		for _, name := range identityHeaders {
+			req.Header.Del(name)
+		}
+
+		cert, err := v.certificate(req)
+		if v.certHeader != "" {
+			req.Header.Del(v.certHeader)
+		}
+		if err != nil {
+			log.Warning(fmt.Sprintf("rejected request to %s: %v", req.URL.Path, err))
+			http.Error(w, "invalid client certificate", http.StatusBadRequest)
+			return
+		}
+		if cert == nil {
+			if v.required {
+				log.Info(fmt.Sprintf("rejected request to %s without client certificate", req.URL.Path))
+				http.Error(w, "client certificate required", http.StatusUnauthorized)
+				return
+			}
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		id := newIdentity(cert)
+		if !v.allows(id) {
+			log.Info(fmt.Sprintf("rejected request to %s from certificate %s (%s)", req.URL.Path, id.subject, strings.Join(id.sans, ", ")))
+			http.Error(w, "client certificate not allowed", http.StatusForbidden)
+			return
+		}
+
+		if len(id.sans) > 0 {
+			req.Header.Set(headerSAN, strings.Join(id.sans, ","))
+		}
+		if id.spiffeID != "" {
+			req.Header.Set(headerSPIFFEID, id.spiffeID)
+		}
+		req.Header.Set(headerSubject, id.subject)
+		req.Header.Set(headerFingerprint, id.fingerprint)
+		handler.ServeHTTP(w, req)
+	}
+}
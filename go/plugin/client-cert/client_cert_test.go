@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newCertificate(t *testing.T, dnsNames []string, uris ...string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "client", Organization: []string{"Example"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     dnsNames,
+	}
+	for _, uri := range uris {
+		parsed, err := url.Parse(uri)
+		assert.NoError(t, err)
+		template.URIs = append(template.URIs, parsed)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return cert
+}
+
+// newCA returns a CA certificate and its key, and a ca_file containing the certificate
+func newCA(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Example CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	ca, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	file := filepath.Join(t.TempDir(), "ca.pem")
+	assert.NoError(t, os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	return ca, key, file
+}
+
+// issueCertificate returns a client certificate signed by the CA
+func issueCertificate(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, dnsNames []string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     dnsNames,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return cert
+}
+
+func createHandler(t *testing.T, cfg map[string]interface{}, received *http.Header) http.Handler {
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*received = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	})
+	handler, err := HandlerRegisterer.registerHandlers(context.Background(), map[string]interface{}{configKey: cfg}, backend)
+	assert.NoError(t, err)
+	return handler
+}
+
+func request(handler http.Handler, cert *x509.Certificate, header http.Header) int {
+	req := httptest.NewRequest(http.MethodPost, "/weather-agent", nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if cert != nil {
+		req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestRegisterHandlers_InvalidConfig(t *testing.T) {
+	_, _, caFile := newCA(t)
+	for _, cfg := range []map[string]interface{}{
+		{"allowed_spiffe_ids": []interface{}{"example.org/ns/agents"}},
+		{"allowed_sans": []interface{}{"*"}},
+		{"cert_header": "X-SSL-Client-Cert"},
+		{"cert_header": "X-SSL-Client-Cert", "ca_file": caFile},
+		{"cert_header": "X-SSL-Client-Cert", "trusted_proxies": []interface{}{"10.0.0.0/8"}},
+		{"cert_header": "X-SSL-Client-Cert", "trusted_proxies": []interface{}{"proxy"}, "ca_file": caFile},
+		{"cert_header": "X-SSL-Client-Cert", "trusted_proxies": []interface{}{"10.0.0.0/8"}, "ca_file": "/does/not/exist.pem"},
+	} {
+		_, err := HandlerRegisterer.registerHandlers(context.Background(), map[string]interface{}{configKey: cfg}, http.NotFoundHandler())
+		assert.Error(t, err, cfg)
+	}
+}
+
+func TestClientCert_ForwardsIdentity(t *testing.T) {
+	var received http.Header
+	handler := createHandler(t, map[string]interface{}{}, &received)
+	cert := newCertificate(t, []string{"client.example.com"}, "spiffe://example.org/ns/apps/sa/chat")
+
+	code := request(handler, cert, http.Header{headerSPIFFEID: {"spiffe://example.org/ns/admin"}})
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "client.example.com,spiffe://example.org/ns/apps/sa/chat", received.Get(headerSAN))
+	assert.Equal(t, "spiffe://example.org/ns/apps/sa/chat", received.Get(headerSPIFFEID))
+	assert.Equal(t, "CN=client,O=Example", received.Get(headerSubject))
+	assert.Len(t, received.Get(headerFingerprint), 64)
+
+	// Spoofed identity headers are removed from requests without certificate
+	code = request(handler, nil, http.Header{headerSAN: {"admin.example.com"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Empty(t, received.Get(headerSAN))
+}
+
+func TestClientCert_AllowLists(t *testing.T) {
+	var received http.Header
+	handler := createHandler(t, map[string]interface{}{
+		"allowed_spiffe_ids": []interface{}{"spiffe://example.org/ns/apps/*"},
+		"allowed_sans":       []interface{}{"*.corp.example.com"},
+	}, &received)
+
+	tests := []struct {
+		name string
+		cert *x509.Certificate
+		want int
+	}{
+		{"allowed SPIFFE ID", newCertificate(t, nil, "spiffe://example.org/ns/apps/sa/chat"), http.StatusOK},
+		{"allowed SAN", newCertificate(t, []string{"laptop.corp.example.com"}), http.StatusOK},
+		{"other SPIFFE ID", newCertificate(t, nil, "spiffe://example.org/ns/batch/sa/job"), http.StatusForbidden},
+		{"other SAN", newCertificate(t, []string{"corp.example.com.evil.com"}), http.StatusForbidden},
+		{"no certificate", nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, request(handler, tt.cert, nil), tt.name)
+	}
+}
+
+func TestClientCert_FromProxyHeader(t *testing.T) {
+	var received http.Header
+	ca, caKey, caFile := newCA(t)
+	handler := createHandler(t, map[string]interface{}{
+		"cert_header":     "X-SSL-Client-Cert",
+		"trusted_proxies": []interface{}{"192.0.2.0/24"},
+		"ca_file":         caFile,
+		"required":        true,
+	}, &received)
+	cert := issueCertificate(t, ca, caKey, []string{"client.example.com"})
+	encode := func(cert *x509.Certificate) string {
+		return url.QueryEscape(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})))
+	}
+
+	// httptest requests come from 192.0.2.1, a trusted proxy
+	assert.Equal(t, http.StatusOK, request(handler, nil, http.Header{"X-Ssl-Client-Cert": {encode(cert)}}))
+	assert.Equal(t, "client.example.com", received.Get(headerSAN))
+	assert.Empty(t, received.Get("X-SSL-Client-Cert"))
+
+	assert.Equal(t, http.StatusBadRequest, request(handler, nil, http.Header{"X-Ssl-Client-Cert": {"garbage"}}))
+	// Certificates not issued by a trusted CA are rejected
+	assert.Equal(t, http.StatusBadRequest, request(handler, nil, http.Header{"X-Ssl-Client-Cert": {encode(newCertificate(t, []string{"client.example.com"}))}}))
+	// The certificate of the connection is ignored when a proxy header is configured
+	assert.Equal(t, http.StatusUnauthorized, request(handler, cert, nil))
+
+	// The header is ignored unless it is sent by a trusted proxy
+	req := httptest.NewRequest(http.MethodPost, "/weather-agent", nil)
+	req.RemoteAddr = "203.0.113.7:4000"
+	req.Header.Set("X-SSL-Client-Cert", encode(cert))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/configschema"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+)
+
+const (
+	pluginName = "cors"
+	configKey  = "cors_config"
+
+	defaultMaxAge = 10 * time.Minute
+)
+
+var (
+	defaultMethods = []string{http.MethodGet, http.MethodPost, http.MethodOptions}
+	defaultHeaders = []string{"Authorization", "Content-Type", "Accept", "X-Conversation-ID", "X-A2A-Extensions"}
+)
+
+type config struct {
+	AllowedOrigins   []string        `json:"allowed_origins"`
+	AllowedMethods   []string        `json:"allowed_methods,omitempty"`
+	AllowedHeaders   []string        `json:"allowed_headers,omitempty"`
+	ExposedHeaders   []string        `json:"exposed_headers,omitempty"`
+	AllowCredentials bool            `json:"allow_credentials,omitempty"`
+	MaxAge           string          `json:"max_age,omitempty"`
+	Paths            []string        `json:"paths,omitempty"`
+	Logging          logging.Options `json:"logging,omitempty"`
+}
+
+// policy is the validated CORS configuration
+type policy struct {
+	anyOrigin   bool
+	origins     map[string]bool
+	wildcards   []wildcardOrigin
+	methods     string
+	headers     map[string]bool
+	headerList  string
+	exposed     string
+	credentials bool
+	maxAge      string
+	paths       []string
+}
+
+// wildcardOrigin matches the subdomains of an origin, e.g. https://*.example.com
+type wildcardOrigin struct {
+	prefix string
+	suffix string
+}
+
+type registerer string
+
+// HandlerRegisterer is the symbol KrakenD looks up to register plugins
+var HandlerRegisterer = registerer(pluginName)
+var logger = logging.New(pluginName)
+
+func main() {}
+
+func init() {
+	logger.Info("loaded")
+}
+
+// RegisterHandlers registers the plugin with KrakenD
+func (r registerer) RegisterHandlers(f func(
+	name string,
+	handler func(context.Context, map[string]interface{}, http.Handler) (http.Handler, error),
+)) {
+	pluginkit.RegisterHandlers(f, string(r), r.registerHandlers, logger)
+}
+
+func (r registerer) RegisterLogger(v interface{}) {
+	pluginkit.RegisterLogger(v, pluginName, &logger)
+}
+
+func parseConfig(extra map[string]interface{}) (config, error) {
+	var cfg config
+	if err := configschema.Validate(pluginName, extra[configKey]); err != nil {
+		return cfg, fmt.Errorf("invalid extra_config.%s: %w", configKey, err)
+	}
+	err := pluginkit.DecodeConfig(extra, configKey, &cfg)
+	return cfg, err
+}
+
+func (r registerer) registerHandlers(_ context.Context, extra map[string]interface{}, handler http.Handler) (http.Handler, error) {
+	cfg, err := parseConfig(extra)
+	if err != nil {
+		return nil, err
+	}
+	if err := logging.Configure(pluginName, cfg.Logging); err != nil {
+		return nil, fmt.Errorf("invalid logging configuration: %w", err)
+	}
+	p, err := newPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("plugin initialized successfully")
+	return http.HandlerFunc(r.handleRequest(handler, p)), nil
+}
+
+func newPolicy(cfg config) (*policy, error) {
+	if len(cfg.AllowedOrigins) == 0 {
+		return nil, fmt.Errorf("invalid allowed_origins configuration: at least one origin is required")
+	}
+	p := &policy{origins: map[string]bool{}, headers: map[string]bool{}, credentials: cfg.AllowCredentials}
+	for _, origin := range cfg.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+		switch {
+		case origin == "*":
+			p.anyOrigin = true
+		case strings.Count(origin, "*") == 1 && strings.Contains(origin, "://*."):
+			prefix, suffix, _ := strings.Cut(origin, "*")
+			p.wildcards = append(p.wildcards, wildcardOrigin{prefix: prefix, suffix: suffix})
+		case strings.Contains(origin, "*") || !strings.Contains(origin, "://"):
+			return nil, fmt.Errorf("invalid allowed_origins configuration: '%s' is not an origin like https://app.example.com", origin)
+		default:
+			p.origins[origin] = true
+		}
+	}
+	if p.anyOrigin && p.credentials {
+		return nil, fmt.Errorf("invalid allowed_origins configuration: '*' cannot be combined with allow_credentials")
+	}
+
+	methods := cfg.AllowedMethods
+	if len(methods) == 0 {
+		methods = defaultMethods
+	}
+	for i, method := range methods {
+		methods[i] = strings.ToUpper(method)
+	}
+	p.methods = strings.Join(methods, ", ")
+
+	allowed := cfg.AllowedHeaders
+	if len(allowed) == 0 {
+		allowed = defaultHeaders
+	}
+	for _, header := range allowed {
+		p.headers[strings.ToLower(header)] = true
+	}
+	p.headerList = strings.Join(allowed, ", ")
+	p.exposed = strings.Join(cfg.ExposedHeaders, ", ")
+
+	maxAge := defaultMaxAge
+	if cfg.MaxAge != "" {
+		var err error
+		if maxAge, err = time.ParseDuration(cfg.MaxAge); err != nil || maxAge < 0 {
+			return nil, fmt.Errorf("invalid max_age configuration: '%s'", cfg.MaxAge)
+		}
+	}
+	p.maxAge = strconv.Itoa(int(maxAge.Seconds()))
+
+	for _, path := range cfg.Paths {
+		if !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("invalid paths configuration: '%s' must start with /", path)
+		}
+		p.paths = append(p.paths, path)
+	}
+	return p, nil
+}
+
+// applies reports whether the CORS policy covers a path. Without paths, it covers all endpoints.
+func (p *policy) applies(path string) bool {
+	if len(p.paths) == 0 {
+		return true
+	}
+	for _, prefix := range p.paths {
+		if path == prefix || strings.HasPrefix(path, strings.TrimSuffix(prefix, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsOrigin reports whether browsers on origin may call the gateway
+func (p *policy) allowsOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if p.origins[origin] {
+		return true
+	}
+	for _, w := range p.wildcards {
+		if strings.HasPrefix(origin, w.prefix) && strings.HasSuffix(origin, w.suffix) && len(origin) > len(w.prefix)+len(w.suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsHeaders reports whether all headers of a preflight request are allowed
+func (p *policy) allowsHeaders(requested string) bool {
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.ToLower(strings.TrimSpace(header))
+		if header != "" && !p.headers[header] {
+			return false
+		}
+	}
+	return true
+}
+
+// setOriginHeaders sets the headers every CORS response of an allowed origin carries
+func (p *policy) setOriginHeaders(h http.Header, origin string) {
+	if p.anyOrigin {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if p.credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (r registerer) handleRequest(handler http.Handler, p *policy) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		origin := req.Header.Get("Origin")
+		if !p.applies(req.URL.Path) {
+			handler.ServeHTTP(w, req)
+			return
+		}
+		if !p.anyOrigin {
+			w.Header().Add("Vary", "Origin")
+		}
+
+		// Preflights are answered by the gateway and never reach agents
+		if req.Method == http.MethodOptions && origin != "" && req.Header.Get("Access-Control-Request-Method") != "" {
+			servePreflight(w, req, p, origin)
+			return
+		}
+		allowed := origin != "" && p.allowsOrigin(origin)
+		handler.ServeHTTP(&corsWriter{ResponseWriter: w, policy: p, origin: origin, allowed: allowed}, req)
+	}
+}
+
+// servePreflight answers a preflight request, without CORS headers if it is not allowed
+func servePreflight(w http.ResponseWriter, req *http.Request, p *policy, origin string) {
+	log := logging.FromRequest(logger, req)
+	w.Header().Add("Vary", "Access-Control-Request-Method")
+	w.Header().Add("Vary", "Access-Control-Request-Headers")
+	method := strings.ToUpper(req.Header.Get("Access-Control-Request-Method"))
+	requested := req.Header.Get("Access-Control-Request-Headers")
+	if !p.allowsOrigin(origin) || !strings.Contains(", "+p.methods+", ", ", "+method+", ") || !p.allowsHeaders(requested) {
+		log.Debug(fmt.Sprintf("rejected CORS preflight of %s for %s %s", origin, method, req.URL.Path))
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	p.setOriginHeaders(w.Header(), origin)
+	w.Header().Set("Access-Control-Allow-Methods", p.methods)
+	w.Header().Set("Access-Control-Allow-Headers", p.headerList)
+	w.Header().Set("Access-Control-Max-Age", p.maxAge)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// corsWriter replaces the CORS headers of agents by those of the gateway once the handler writes
+// the response. Responses to other origins carry no CORS headers at all.
+type corsWriter struct {
+	http.ResponseWriter
+	policy      *policy
+	origin      string
+	allowed     bool
+	wroteHeader bool
+}
+
+func (w *corsWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		h := w.Header()
+		for name := range h {
+			if strings.HasPrefix(name, "Access-Control-") {
+				h.Del(name)
+			}
+		}
+		if w.allowed {
+			w.policy.setOriginHeaders(h, w.origin)
+			if w.policy.exposed != "" {
+				h.Set("Access-Control-Expose-Headers", w.policy.exposed)
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *corsWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush passes flushes of streamed responses on
+func (w *corsWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the wrapped writer
+func (w *corsWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
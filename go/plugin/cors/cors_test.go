@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createHandler(t *testing.T, cfg map[string]interface{}, backend http.Handler) http.Handler {
+	handler, err := HandlerRegisterer.registerHandlers(context.Background(), map[string]interface{}{configKey: cfg}, backend)
+	assert.NoError(t, err)
+	return handler
+}
+
+func request(handler http.Handler, method, path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func agentBackend(reached *bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*reached = true
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	})
+}
+
+func TestRegisterHandlers_InvalidConfig(t *testing.T) {
+	for _, cfg := range []map[string]interface{}{
+		{},
+		{"allowed_origins": []interface{}{"app.example.com"}},
+		{"allowed_origins": []interface{}{"https://app.*.com"}},
+		{"allowed_origins": []interface{}{"*"}, "allow_credentials": true},
+		{"allowed_origins": []interface{}{"*"}, "max_age": "forever"},
+		{"allowed_origins": []interface{}{"*"}, "paths": []interface{}{"agents"}},
+		{"allowed_origins": []interface{}{"*"}, "allowed_method": []interface{}{"GET"}},
+		{"allowed_origins": "*"},
+	} {
+		_, err := HandlerRegisterer.registerHandlers(context.Background(), map[string]interface{}{configKey: cfg}, http.NotFoundHandler())
+		assert.Error(t, err, cfg)
+	}
+}
+
+func TestCORS_Preflight(t *testing.T) {
+	reached := false
+	handler := createHandler(t, map[string]interface{}{
+		"allowed_origins":   []interface{}{"https://app.example.com", "https://*.agents.example.com"},
+		"allow_credentials": true,
+		"max_age":           "1h",
+	}, agentBackend(&reached))
+
+	tests := []struct {
+		name   string
+		origin string
+		method string
+		hdrs   string
+		want   int
+	}{
+		{name: "allowed", origin: "https://app.example.com", method: "POST", hdrs: "content-type, authorization", want: http.StatusNoContent},
+		{name: "wildcard subdomain", origin: "https://chat.agents.example.com", method: "GET", want: http.StatusNoContent},
+		{name: "unknown origin", origin: "https://evil.example.com", method: "POST", want: http.StatusForbidden},
+		{name: "wildcard without subdomain", origin: "https://.agents.example.com", method: "POST", want: http.StatusForbidden},
+		{name: "method not allowed", origin: "https://app.example.com", method: "DELETE", want: http.StatusForbidden},
+		{name: "header not allowed", origin: "https://app.example.com", method: "POST", hdrs: "x-internal-token", want: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{"Origin": {tt.origin}, "Access-Control-Request-Method": {tt.method}}
+			if tt.hdrs != "" {
+				header.Set("Access-Control-Request-Headers", tt.hdrs)
+			}
+			rec := request(handler, http.MethodOptions, "/weather-agent", header)
+
+			assert.Equal(t, tt.want, rec.Code)
+			assert.Contains(t, rec.Header().Values("Vary"), "Origin")
+			if tt.want == http.StatusNoContent {
+				assert.Equal(t, tt.origin, rec.Header().Get("Access-Control-Allow-Origin"))
+				assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+				assert.Equal(t, "GET, POST, OPTIONS", rec.Header().Get("Access-Control-Allow-Methods"))
+				assert.Equal(t, "3600", rec.Header().Get("Access-Control-Max-Age"))
+			} else {
+				assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+			}
+		})
+	}
+	assert.False(t, reached)
+}
+
+func TestCORS_ActualRequests(t *testing.T) {
+	reached := false
+	handler := createHandler(t, map[string]interface{}{
+		"allowed_origins": []interface{}{"https://app.example.com"},
+		"exposed_headers": []interface{}{"X-Request-Id"},
+	}, agentBackend(&reached))
+
+	rec := request(handler, http.MethodPost, "/chat/completions", http.Header{"Origin": {"https://app.example.com"}})
+	assert.True(t, reached)
+	assert.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "X-Request-Id", rec.Header().Get("Access-Control-Expose-Headers"))
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Credentials"))
+
+	// CORS headers of agents never reach the client
+	rec = request(handler, http.MethodPost, "/chat/completions", http.Header{"Origin": {"https://evil.example.com"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+	rec = request(handler, http.MethodGet, "/models", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCORS_AnyOriginAndPaths(t *testing.T) {
+	reached := false
+	handler := createHandler(t, map[string]interface{}{
+		"allowed_origins": []interface{}{"*"},
+		"paths":           []interface{}{"/weather-agent"},
+	}, agentBackend(&reached))
+
+	header := http.Header{"Origin": {"https://app.example.com"}, "Access-Control-Request-Method": {"POST"}}
+	rec := request(handler, http.MethodOptions, "/weather-agent/.well-known/agent-card.json", header)
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.NotContains(t, rec.Header().Values("Vary"), "Origin")
+
+	reached = false
+	request(handler, http.MethodOptions, "/news-agent", header)
+	assert.True(t, reached)
+}
@@ -0,0 +1,110 @@
+// Package main is the consolidated gateway plugin. It registers a single handler that chains the
+// modules enabled in extra_config.gateway_config, so one .so replaces the standalone plugins.
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/configschema"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+	"github.com/agentic-layer/agent-gateway-krakend/plugin/a2a-openai/a2aopenai"
+	"github.com/agentic-layer/agent-gateway-krakend/plugin/agentcard-rw/agentcardrw"
+	"github.com/agentic-layer/agent-gateway-krakend/plugin/openai-a2a/openaia2a"
+)
+
+const (
+	pluginName = "gateway"
+	configKey  = "gateway_config"
+)
+
+type registerer string
+
+// HandlerRegisterer is the symbol KrakenD looks up to register plugins
+var HandlerRegisterer = registerer(pluginName)
+var logger = logging.New(pluginName)
+
+func main() {}
+
+func init() {
+	logger.Info("loaded")
+}
+
+// module is a subsystem of the gateway, switched on by its flag in the configuration
+type module struct {
+	pluginkit.Module
+	enabled func(config) bool
+}
+
+// modules are ordered outermost first: requests pass through them in this order, so the OpenAI
+// endpoints are served before agent cards of the backend are rewritten
+var modules = []module{
+	{a2aopenai.Module, func(cfg config) bool { return cfg.A2AOpenAI }},
+	{openaia2a.Module, func(cfg config) bool { return cfg.OpenAIA2A }},
+	{agentcardrw.Module, func(cfg config) bool { return cfg.AgentCardRW }},
+}
+
+// config switches the modules on; each enabled module reads its own extra_config key
+type config struct {
+	AgentCardRW bool            `json:"agentcard_rw"`
+	OpenAIA2A   bool            `json:"openai_a2a"`
+	A2AOpenAI   bool            `json:"a2a_openai"`
+	Logging     logging.Options `json:"logging,omitempty"`
+}
+
+// RegisterHandlers registers the plugin with KrakenD
+func (r registerer) RegisterHandlers(f func(
+	name string,
+	handler func(context.Context, map[string]interface{}, http.Handler) (http.Handler, error),
+)) {
+	pluginkit.RegisterHandlers(f, string(r), r.registerHandlers, logger)
+}
+
+// RegisterLogger passes the KrakenD logger to the gateway and all modules
+func (r registerer) RegisterLogger(v interface{}) {
+	pluginkit.RegisterLogger(v, pluginName, &logger)
+	for _, m := range modules {
+		m.RegisterLogger(v)
+	}
+}
+
+func parseConfig(extra map[string]interface{}) (config, error) {
+	var cfg config
+	if err := configschema.Validate(pluginName, extra[configKey]); err != nil {
+		return cfg, fmt.Errorf("invalid extra_config.%s: %w", configKey, err)
+	}
+	err := pluginkit.DecodeConfig(extra, configKey, &cfg)
+	return cfg, err
+}
+
+func (r registerer) registerHandlers(ctx context.Context, extra map[string]interface{}, handler http.Handler) (http.Handler, error) {
+	cfg, err := parseConfig(extra)
+	if err != nil {
+		return nil, err
+	}
+	if err := logging.Configure(pluginName, cfg.Logging); err != nil {
+		return nil, fmt.Errorf("invalid logging configuration: %w", err)
+	}
+
+	var enabled []string
+	for i := len(modules) - 1; i >= 0; i-- {
+		m := modules[i]
+		if !m.enabled(cfg) {
+			continue
+		}
+		if handler, err = m.Factory(ctx, extra, handler); err != nil {
+			return nil, fmt.Errorf("cannot start module %s: %w", m.Name, err)
+		}
+		enabled = append([]string{m.Name}, enabled...)
+	}
+	if len(enabled) == 0 {
+		return nil, fmt.Errorf("no module is enabled in extra_config.%s", configKey)
+	}
+
+	pluginkit.SetFeatures(pluginName, enabled)
+	logger.Info(fmt.Sprintf("modules enabled: %s", strings.Join(enabled, ", ")))
+	return handler, nil
+}
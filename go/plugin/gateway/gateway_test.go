@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/testagent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newGateway registers the gateway with the modules enabled by flags in front of a backend that
+// proxies to agent, as KrakenD endpoints do
+func newGateway(t *testing.T, flags map[string]interface{}, agent *testagent.Agent) http.Handler {
+	extra := map[string]interface{}{
+		configKey: flags,
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{map[string]interface{}{"model_id": "test/echo", "url": agent.URL}},
+		},
+	}
+	target, err := url.Parse(agent.URL)
+	require.NoError(t, err)
+	backend := http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		req.URL.Path = strings.TrimPrefix(req.URL.Path, "/echo")
+		req.Host = target.Host
+		httputil.NewSingleHostReverseProxy(target).ServeHTTP(w, req)
+	})
+	handler, err := HandlerRegisterer.registerHandlers(context.Background(), extra, backend)
+	require.NoError(t, err)
+	return handler
+}
+
+func getCard(t *testing.T, handler http.Handler) models.AgentCard {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://gateway.example/echo/.well-known/agent-card.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var card models.AgentCard
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &card))
+	return card
+}
+
+func TestGateway_ChainsEnabledModules(t *testing.T) {
+	agent := testagent.New(testagent.Options{Name: "echo"})
+	defer agent.Close()
+	handler := newGateway(t, map[string]interface{}{"agentcard_rw": true, "openai_a2a": true}, agent)
+
+	body := `{"model":"test/echo","messages":[{"role":"user","content":"hello"}]}`
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat/completions", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var completion models.OpenAIResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &completion))
+	assert.Equal(t, "echo: hello", completion.Choices[0].Message.Content)
+
+	assert.Equal(t, "http://gateway.example/echo", getCard(t, handler).Url)
+}
+
+func TestGateway_DisabledModulesPassThrough(t *testing.T) {
+	agent := testagent.New(testagent.Options{Name: "echo"})
+	defer agent.Close()
+	handler := newGateway(t, map[string]interface{}{"openai_a2a": true}, agent)
+
+	assert.Equal(t, agent.URL, getCard(t, handler).Url, "agent cards are not rewritten")
+}
+
+func TestGateway_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		extra   map[string]interface{}
+		wantErr string
+	}{
+		{
+			name:    "no module enabled",
+			extra:   map[string]interface{}{configKey: map[string]interface{}{"openai_a2a": false}},
+			wantErr: "no module is enabled",
+		},
+		{
+			name:    "unknown flag",
+			extra:   map[string]interface{}{configKey: map[string]interface{}{"cors": true}},
+			wantErr: "invalid extra_config.gateway_config",
+		},
+		{
+			name: "invalid module configuration",
+			extra: map[string]interface{}{
+				configKey:             map[string]interface{}{"agentcard_rw": true},
+				"agentcard_rw_config": map[string]interface{}{"on_error": "explode"},
+			},
+			wantErr: "cannot start module agentcard-rw",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := HandlerRegisterer.registerHandlers(context.Background(), tt.extra, http.NotFoundHandler())
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
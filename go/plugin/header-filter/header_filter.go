@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/configschema"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+)
+
+const (
+	pluginName = "header-filter"
+	configKey  = "header_filter_config"
+)
+
+// essentialHeaders frame the message and are kept by allow lists
+var essentialHeaders = []string{"Content-Type", "Content-Length", "Content-Encoding", "Transfer-Encoding"}
+
+// RuleConfig lists header names to remove (deny) or to keep (allow). Names are case-insensitive;
+// a trailing * matches all names with the prefix, e.g. X-Internal-*.
+type RuleConfig struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+// RouteConfig adds rules for the requests to a path prefix
+type RouteConfig struct {
+	Path     string      `json:"path"`
+	Request  *RuleConfig `json:"request,omitempty"`
+	Response *RuleConfig `json:"response,omitempty"`
+}
+
+type config struct {
+	Request  *RuleConfig     `json:"request,omitempty"`
+	Response *RuleConfig     `json:"response,omitempty"`
+	Routes   []RouteConfig   `json:"routes,omitempty"`
+	Logging  logging.Options `json:"logging,omitempty"`
+}
+
+// patterns match header names exactly or by prefix
+type patterns struct {
+	names    map[string]bool
+	prefixes []string
+}
+
+func newPatterns(names []string) (*patterns, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	p := &patterns{names: map[string]bool{}}
+	for _, name := range names {
+		if prefix, ok := strings.CutSuffix(name, "*"); ok {
+			if prefix == "" || strings.Contains(prefix, "*") {
+				return nil, fmt.Errorf("invalid header pattern '%s'", name)
+			}
+			p.prefixes = append(p.prefixes, http.CanonicalHeaderKey(prefix))
+			continue
+		}
+		if name == "" || strings.Contains(name, "*") {
+			return nil, fmt.Errorf("invalid header pattern '%s'", name)
+		}
+		p.names[http.CanonicalHeaderKey(name)] = true
+	}
+	return p, nil
+}
+
+func (p *patterns) match(name string) bool {
+	if p == nil {
+		return false
+	}
+	name = http.CanonicalHeaderKey(name)
+	if p.names[name] {
+		return true
+	}
+	for _, prefix := range p.prefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// rule removes denied headers and, with an allow list, all headers not allowed
+type rule struct {
+	allow *patterns
+	deny  []*patterns
+}
+
+func newRule(cfg *RuleConfig) (rule, error) {
+	var r rule
+	if cfg == nil {
+		return r, nil
+	}
+	var err error
+	if r.allow, err = newPatterns(cfg.Allow); err != nil {
+		return r, err
+	}
+	deny, err := newPatterns(cfg.Deny)
+	if err != nil {
+		return r, err
+	}
+	if deny != nil {
+		r.deny = []*patterns{deny}
+	}
+	return r, nil
+}
+
+// extend adds the deny list of a route to r; the allow list of the route replaces the one of r
+func (r rule) extend(route rule) rule {
+	extended := rule{allow: r.allow, deny: append(append([]*patterns{}, r.deny...), route.deny...)}
+	if route.allow != nil {
+		extended.allow = route.allow
+	}
+	return extended
+}
+
+// apply removes the filtered headers and returns their names
+func (r rule) apply(h http.Header) []string {
+	var removed []string
+	for name := range h {
+		if r.removes(name) {
+			h.Del(name)
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+	return removed
+}
+
+func (r rule) removes(name string) bool {
+	for _, deny := range r.deny {
+		if deny.match(name) {
+			return true
+		}
+	}
+	if r.allow == nil {
+		return false
+	}
+	for _, essential := range essentialHeaders {
+		if http.CanonicalHeaderKey(name) == essential {
+			return false
+		}
+	}
+	return !r.allow.match(name)
+}
+
+// route holds the rules of a path prefix, including the global ones
+type route struct {
+	path     string
+	request  rule
+	response rule
+}
+
+// filter holds the global rules and the routes, longest path first
+type filter struct {
+	request  rule
+	response rule
+	routes   []route
+}
+
+func newFilter(cfg config) (*filter, error) {
+	f := &filter{}
+	var err error
+	if f.request, err = newRule(cfg.Request); err != nil {
+		return nil, fmt.Errorf("invalid request configuration: %w", err)
+	}
+	if f.response, err = newRule(cfg.Response); err != nil {
+		return nil, fmt.Errorf("invalid response configuration: %w", err)
+	}
+	for _, rc := range cfg.Routes {
+		if !strings.HasPrefix(rc.Path, "/") {
+			return nil, fmt.Errorf("invalid routes configuration: path '%s' must start with /", rc.Path)
+		}
+		request, err := newRule(rc.Request)
+		if err != nil {
+			return nil, fmt.Errorf("invalid routes configuration for %s: %w", rc.Path, err)
+		}
+		response, err := newRule(rc.Response)
+		if err != nil {
+			return nil, fmt.Errorf("invalid routes configuration for %s: %w", rc.Path, err)
+		}
+		f.routes = append(f.routes, route{
+			path:     strings.TrimSuffix(rc.Path, "/"),
+			request:  f.request.extend(request),
+			response: f.response.extend(response),
+		})
+	}
+	sort.SliceStable(f.routes, func(i, j int) bool { return len(f.routes[i].path) > len(f.routes[j].path) })
+	return f, nil
+}
+
+// rules returns the rules of the longest route matching path, or the global rules
+func (f *filter) rules(path string) (rule, rule) {
+	for _, r := range f.routes {
+		if path == r.path || strings.HasPrefix(path, r.path+"/") || r.path == "" {
+			return r.request, r.response
+		}
+	}
+	return f.request, f.response
+}
+
+type registerer string
+
+// HandlerRegisterer is the symbol KrakenD looks up to register plugins
+var HandlerRegisterer = registerer(pluginName)
+var logger = logging.New(pluginName)
+
+func main() {}
+
+func init() {
+	logger.Info("loaded")
+}
+
+// RegisterHandlers registers the plugin with KrakenD
+func (r registerer) RegisterHandlers(f func(
+	name string,
+	handler func(context.Context, map[string]interface{}, http.Handler) (http.Handler, error),
+)) {
+	pluginkit.RegisterHandlers(f, string(r), r.registerHandlers, logger)
+}
+
+func (r registerer) RegisterLogger(v interface{}) {
+	pluginkit.RegisterLogger(v, pluginName, &logger)
+}
+
+func parseConfig(extra map[string]interface{}) (config, error) {
+	var cfg config
+	if err := configschema.Validate(pluginName, extra[configKey]); err != nil {
+		return cfg, fmt.Errorf("invalid extra_config.%s: %w", configKey, err)
+	}
+	err := pluginkit.DecodeConfig(extra, configKey, &cfg)
+	return cfg, err
+}
+
+func (r registerer) registerHandlers(_ context.Context, extra map[string]interface{}, handler http.Handler) (http.Handler, error) {
+	cfg, err := parseConfig(extra)
+	if err != nil {
+		return nil, err
+	}
+	if err := logging.Configure(pluginName, cfg.Logging); err != nil {
+		return nil, fmt.Errorf("invalid logging configuration: %w", err)
+	}
+	f, err := newFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info(fmt.Sprintf("plugin initialized successfully with %d routes", len(f.routes)))
+	return http.HandlerFunc(r.handleRequest(handler, f)), nil
+}
+
+func (r registerer) handleRequest(handler http.Handler, f *filter) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		log := logging.FromRequest(logger, req)
+		request, response := f.rules(req.URL.Path)
+		if removed := request.apply(req.Header); len(removed) > 0 {
+			log.Debug(fmt.Sprintf("removed request headers of %s: %s", req.URL.Path, strings.Join(removed, ", ")))
+		}
+		handler.ServeHTTP(&filterWriter{ResponseWriter: w, rule: response, path: req.URL.Path}, req)
+	}
+}
+
+// filterWriter removes the filtered headers of a response before it is written
+type filterWriter struct {
+	http.ResponseWriter
+	rule        rule
+	path        string
+	wroteHeader bool
+}
+
+func (w *filterWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if removed := w.rule.apply(w.Header()); len(removed) > 0 {
+			logger.Debug(fmt.Sprintf("removed response headers of %s: %s", w.path, strings.Join(removed, ", ")))
+		}
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *filterWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush passes flushes of streamed responses on
+func (w *filterWriter) Flush() {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the wrapped writer
+func (w *filterWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createHandler(t *testing.T, cfg map[string]interface{}, received *http.Header) http.Handler {
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*received = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Server", "uvicorn")
+		w.Header().Set("X-Envoy-Upstream-Service-Time", "12")
+		w.Header().Set("Traceparent", "00-abc-def-01")
+		w.Header().Set("X-Request-Id", "r1")
+		_, _ = w.Write([]byte(`{}`))
+	})
+	handler, err := HandlerRegisterer.registerHandlers(context.Background(), map[string]interface{}{configKey: cfg}, backend)
+	assert.NoError(t, err)
+	return handler
+}
+
+func request(handler http.Handler, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	req.Header.Set("Cookie", "session=1")
+	req.Header.Set("Authorization", "Bearer client")
+	req.Header.Set("X-Internal-Token", "secret")
+	req.Header.Set("X-Conversation-ID", "c1")
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRegisterHandlers_InvalidConfig(t *testing.T) {
+	for _, cfg := range []map[string]interface{}{
+		{"request": map[string]interface{}{"deny": []interface{}{"*"}}},
+		{"response": map[string]interface{}{"allow": []interface{}{"X-*-Id"}}},
+		{"routes": []interface{}{map[string]interface{}{"path": "weather-agent"}}},
+		{"routes": []interface{}{map[string]interface{}{"path": "/weather-agent", "request": map[string]interface{}{"deny": []interface{}{""}}}}},
+	} {
+		_, err := HandlerRegisterer.registerHandlers(context.Background(), map[string]interface{}{configKey: cfg}, http.NotFoundHandler())
+		assert.Error(t, err, cfg)
+	}
+}
+
+func TestHeaderFilter_GlobalDenyLists(t *testing.T) {
+	var received http.Header
+	handler := createHandler(t, map[string]interface{}{
+		"request":  map[string]interface{}{"deny": []interface{}{"cookie", "X-Internal-*"}},
+		"response": map[string]interface{}{"deny": []interface{}{"Server", "X-Envoy-*"}},
+	}, &received)
+
+	rec := request(handler, "/weather-agent")
+
+	assert.Empty(t, received.Get("Cookie"))
+	assert.Empty(t, received.Get("X-Internal-Token"))
+	assert.Equal(t, "Bearer client", received.Get("Authorization"))
+	assert.Empty(t, rec.Header().Get("Server"))
+	assert.Empty(t, rec.Header().Get("X-Envoy-Upstream-Service-Time"))
+	assert.Equal(t, "r1", rec.Header().Get("X-Request-Id"))
+	assert.Equal(t, "{}", rec.Body.String())
+}
+
+func TestHeaderFilter_Routes(t *testing.T) {
+	var received http.Header
+	handler := createHandler(t, map[string]interface{}{
+		"request": map[string]interface{}{"deny": []interface{}{"Cookie"}},
+		"routes": []interface{}{
+			map[string]interface{}{
+				"path":     "/weather-agent",
+				"request":  map[string]interface{}{"deny": []interface{}{"Authorization"}},
+				"response": map[string]interface{}{"allow": []interface{}{"X-Request-Id"}},
+			},
+			map[string]interface{}{
+				"path":    "/weather-agent/admin",
+				"request": map[string]interface{}{"allow": []interface{}{"X-Conversation-ID"}},
+			},
+		},
+	}, &received)
+
+	rec := request(handler, "/weather-agent/v1/message:send")
+	assert.Empty(t, received.Get("Cookie"))
+	assert.Empty(t, received.Get("Authorization"))
+	assert.Equal(t, "secret", received.Get("X-Internal-Token"))
+	assert.Equal(t, http.Header{"Content-Type": {"application/json"}, "X-Request-Id": {"r1"}}, rec.Header())
+
+	request(handler, "/weather-agent/admin")
+	assert.Equal(t, http.Header{"Content-Type": {"application/json"}, "X-Conversation-Id": {"c1"}}, received)
+
+	rec = request(handler, "/weather-agent-v2")
+	assert.Empty(t, received.Get("Cookie"))
+	assert.Equal(t, "Bearer client", received.Get("Authorization"))
+	assert.Equal(t, "uvicorn", rec.Header().Get("Server"))
+}
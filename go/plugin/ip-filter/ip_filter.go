@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/configschema"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+)
+
+const (
+	pluginName = "ip-filter"
+	configKey  = "ip_filter_config"
+)
+
+// RouteConfig restricts the requests to a path prefix
+type RouteConfig struct {
+	Path  string   `json:"path"`
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+type config struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+	// TrustedProxyDepth is the number of proxies in front of KrakenD that append to X-Forwarded-For
+	TrustedProxyDepth int             `json:"trusted_proxy_depth,omitempty"`
+	Routes            []RouteConfig   `json:"routes,omitempty"`
+	Logging           logging.Options `json:"logging,omitempty"`
+}
+
+// networks is a list of IP addresses and CIDR ranges
+type networks []*net.IPNet
+
+// parseNetworks parses IP addresses and CIDR ranges
+func parseNetworks(entries []string) (networks, error) {
+	var n networks
+	for _, entry := range entries {
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address '%s'", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			entry = fmt.Sprintf("%s/%d", ip, bits)
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR range '%s'", entry)
+		}
+		n = append(n, network)
+	}
+	return n, nil
+}
+
+func (n networks) contains(ip net.IP) bool {
+	for _, network := range n {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// policy admits client IPs that are not denied and, with an allow list, allowed
+type policy struct {
+	allow networks
+	deny  networks
+}
+
+// admits returns false and the reason if ip is rejected
+func (p policy) admits(ip net.IP) (bool, string) {
+	if p.deny.contains(ip) {
+		return false, "denied"
+	}
+	if len(p.allow) > 0 && !p.allow.contains(ip) {
+		return false, "not allowed"
+	}
+	return true, ""
+}
+
+type route struct {
+	path   string
+	policy policy
+}
+
+// filter holds the global policy and the routes, longest path first
+type filter struct {
+	global policy
+	routes []route
+	depth  int
+}
+
+func newFilter(cfg config) (*filter, error) {
+	f := &filter{depth: cfg.TrustedProxyDepth}
+	if cfg.TrustedProxyDepth < 0 {
+		return nil, fmt.Errorf("invalid trusted_proxy_depth configuration: %d is negative", cfg.TrustedProxyDepth)
+	}
+	var err error
+	if f.global.allow, err = parseNetworks(cfg.Allow); err != nil {
+		return nil, fmt.Errorf("invalid allow configuration: %w", err)
+	}
+	if f.global.deny, err = parseNetworks(cfg.Deny); err != nil {
+		return nil, fmt.Errorf("invalid deny configuration: %w", err)
+	}
+	for _, rc := range cfg.Routes {
+		if !strings.HasPrefix(rc.Path, "/") {
+			return nil, fmt.Errorf("invalid routes configuration: path '%s' must start with /", rc.Path)
+		}
+		allow, err := parseNetworks(rc.Allow)
+		if err != nil {
+			return nil, fmt.Errorf("invalid routes configuration for %s: %w", rc.Path, err)
+		}
+		deny, err := parseNetworks(rc.Deny)
+		if err != nil {
+			return nil, fmt.Errorf("invalid routes configuration for %s: %w", rc.Path, err)
+		}
+		// Routes add to the global deny list and replace the global allow list
+		p := policy{allow: f.global.allow, deny: append(append(networks{}, f.global.deny...), deny...)}
+		if len(allow) > 0 {
+			p.allow = allow
+		}
+		f.routes = append(f.routes, route{path: strings.TrimSuffix(rc.Path, "/"), policy: p})
+	}
+	sort.SliceStable(f.routes, func(i, j int) bool { return len(f.routes[i].path) > len(f.routes[j].path) })
+	return f, nil
+}
+
+// policy returns the policy of the longest route matching path, or the global policy
+func (f *filter) policy(path string) policy {
+	for _, r := range f.routes {
+		if r.path == "" || path == r.path || strings.HasPrefix(path, r.path+"/") {
+			return r.policy
+		}
+	}
+	return f.global
+}
+
+// clientIP returns the IP of the client. With a trusted proxy depth of n, it is the address the
+// nth proxy appended to X-Forwarded-For, counting from the right; addresses further left can
+// be set by the client and are ignored. Without trusted proxies, it is the peer address.
+func (f *filter) clientIP(req *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	if f.depth == 0 {
+		return net.ParseIP(host)
+	}
+	var forwarded []string
+	for _, value := range req.Header.Values("X-Forwarded-For") {
+		for _, address := range strings.Split(value, ",") {
+			if address = strings.TrimSpace(address); address != "" {
+				forwarded = append(forwarded, address)
+			}
+		}
+	}
+	// Requests that carry fewer addresses than trusted proxies did not pass all of them, so
+	// every forwarded address may be set by the client and the peer is attributed instead
+	if len(forwarded) < f.depth {
+		return net.ParseIP(host)
+	}
+	return net.ParseIP(forwarded[len(forwarded)-f.depth])
+}
+
+type registerer string
+
+// HandlerRegisterer is the symbol KrakenD looks up to register plugins
+var HandlerRegisterer = registerer(pluginName)
+var logger = logging.New(pluginName)
+
+func main() {}
+
+func init() {
+	logger.Info("loaded")
+}
+
+// RegisterHandlers registers the plugin with KrakenD
+func (r registerer) RegisterHandlers(f func(
+	name string,
+	handler func(context.Context, map[string]interface{}, http.Handler) (http.Handler, error),
+)) {
+	pluginkit.RegisterHandlers(f, string(r), r.registerHandlers, logger)
+}
+
+func (r registerer) RegisterLogger(v interface{}) {
+	pluginkit.RegisterLogger(v, pluginName, &logger)
+}
+
+func parseConfig(extra map[string]interface{}) (config, error) {
+	var cfg config
+	if err := configschema.Validate(pluginName, extra[configKey]); err != nil {
+		return cfg, fmt.Errorf("invalid extra_config.%s: %w", configKey, err)
+	}
+	err := pluginkit.DecodeConfig(extra, configKey, &cfg)
+	return cfg, err
+}
+
+func (r registerer) registerHandlers(_ context.Context, extra map[string]interface{}, handler http.Handler) (http.Handler, error) {
+	cfg, err := parseConfig(extra)
+	if err != nil {
+		return nil, err
+	}
+	if err := logging.Configure(pluginName, cfg.Logging); err != nil {
+		return nil, fmt.Errorf("invalid logging configuration: %w", err)
+	}
+	f, err := newFilter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info(fmt.Sprintf("plugin initialized successfully with %d routes", len(f.routes)))
+	return http.HandlerFunc(r.handleRequest(handler, f)), nil
+}
+
+func (r registerer) handleRequest(handler http.Handler, f *filter) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		log := logging.FromRequest(logger, req)
+		ip := f.clientIP(req)
+		if ip == nil {
+			log.Warning(fmt.Sprintf("rejected request to %s: cannot determine client IP from %s", req.URL.Path, req.RemoteAddr))
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		if ok, reason := f.policy(req.URL.Path).admits(ip); !ok {
+			log.Info(fmt.Sprintf("rejected request to %s from %s: %s", req.URL.Path, ip, reason))
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, req)
+	}
+}
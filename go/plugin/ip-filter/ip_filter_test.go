@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func createHandler(t *testing.T, cfg map[string]interface{}) http.Handler {
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler, err := HandlerRegisterer.registerHandlers(context.Background(), map[string]interface{}{configKey: cfg}, backend)
+	assert.NoError(t, err)
+	return handler
+}
+
+func request(handler http.Handler, path, remoteAddr string, forwardedFor ...string) int {
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	req.RemoteAddr = remoteAddr
+	for _, value := range forwardedFor {
+		req.Header.Add("X-Forwarded-For", value)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestRegisterHandlers_InvalidConfig(t *testing.T) {
+	for _, cfg := range []map[string]interface{}{
+		{"allow": []interface{}{"10.0.0.0/33"}},
+		{"deny": []interface{}{"corp"}},
+		{"trusted_proxy_depth": -1},
+		{"routes": []interface{}{map[string]interface{}{"path": "hr-agent"}}},
+		{"routes": []interface{}{map[string]interface{}{"path": "/hr-agent", "allow": []interface{}{"10.0.0"}}}},
+	} {
+		_, err := HandlerRegisterer.registerHandlers(context.Background(), map[string]interface{}{configKey: cfg}, http.NotFoundHandler())
+		assert.Error(t, err, cfg)
+	}
+}
+
+func TestIPFilter_GlobalAndRoutes(t *testing.T) {
+	handler := createHandler(t, map[string]interface{}{
+		"deny": []interface{}{"203.0.113.7"},
+		"routes": []interface{}{
+			map[string]interface{}{"path": "/hr-agent", "allow": []interface{}{"10.0.0.0/8", "2001:db8::/32"}},
+			map[string]interface{}{"path": "/hr-agent/public", "deny": []interface{}{"10.66.0.0/16"}},
+		},
+	})
+
+	tests := []struct {
+		path       string
+		remoteAddr string
+		want       int
+	}{
+		{"/weather-agent", "203.0.113.8:4000", http.StatusOK},
+		{"/weather-agent", "203.0.113.7:4000", http.StatusForbidden},
+		{"/hr-agent", "10.1.2.3:4000", http.StatusOK},
+		{"/hr-agent/v1/message:send", "[2001:db8::1]:4000", http.StatusOK},
+		{"/hr-agent", "203.0.113.8:4000", http.StatusForbidden},
+		{"/hr-agent-v2", "203.0.113.8:4000", http.StatusOK},
+		{"/hr-agent/public", "203.0.113.8:4000", http.StatusOK},
+		{"/hr-agent/public", "10.66.1.1:4000", http.StatusForbidden},
+		{"/hr-agent/public", "203.0.113.7:4000", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, request(handler, tt.path, tt.remoteAddr), "%s from %s", tt.path, tt.remoteAddr)
+	}
+}
+
+func TestIPFilter_TrustedProxyDepth(t *testing.T) {
+	handler := createHandler(t, map[string]interface{}{"allow": []interface{}{"192.0.2.0/24"}, "trusted_proxy_depth": 1})
+
+	// The load balancer appends the client address; spoofed addresses further left are ignored
+	assert.Equal(t, http.StatusOK, request(handler, "/agent", "10.0.0.1:80", "192.0.2.10"))
+	assert.Equal(t, http.StatusForbidden, request(handler, "/agent", "10.0.0.1:80", "192.0.2.10, 198.51.100.1"))
+	assert.Equal(t, http.StatusOK, request(handler, "/agent", "10.0.0.1:80", "198.51.100.1", "192.0.2.10"))
+	assert.Equal(t, http.StatusForbidden, request(handler, "/agent", "10.0.0.1:80", "not-an-ip"))
+
+	// Without forwarded addresses, the peer is the client
+	assert.Equal(t, http.StatusOK, request(handler, "/agent", "192.0.2.99:80"))
+
+	// With fewer forwarded addresses than trusted proxies, the spoofable leftmost address is ignored
+	handler = createHandler(t, map[string]interface{}{"allow": []interface{}{"192.0.2.0/24"}, "trusted_proxy_depth": 2})
+	assert.Equal(t, http.StatusForbidden, request(handler, "/agent", "10.0.0.1:80", "192.0.2.10"))
+	assert.Equal(t, http.StatusOK, request(handler, "/agent", "192.0.2.99:80", "198.51.100.1"))
+	assert.Equal(t, http.StatusOK, request(handler, "/agent", "10.0.0.1:80", "198.51.100.1", "192.0.2.10, 10.0.0.2"))
+
+	handler = createHandler(t, map[string]interface{}{"allow": []interface{}{"192.0.2.0/24"}})
+	assert.Equal(t, http.StatusForbidden, request(handler, "/agent", "10.0.0.1:80", "192.0.2.10"))
+}
@@ -0,0 +1,28 @@
+// Package main is the standalone openai-a2a plugin, implemented by package openaia2a
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/agentic-layer/agent-gateway-krakend/plugin/openai-a2a/openaia2a"
+)
+
+type registerer string
+
+// HandlerRegisterer is the symbol KrakenD looks up to register plugins
+var HandlerRegisterer = registerer(openaia2a.Module.Name)
+
+func main() {}
+
+// RegisterHandlers registers the plugin with KrakenD
+func (r registerer) RegisterHandlers(f func(
+	name string,
+	handler func(context.Context, map[string]interface{}, http.Handler) (http.Handler, error),
+)) {
+	openaia2a.Module.RegisterHandlers(f)
+}
+
+func (r registerer) RegisterLogger(v interface{}) {
+	openaia2a.Module.RegisterLogger(v)
+}
@@ -0,0 +1,196 @@
+package openaia2a
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+)
+
+const a2aSchemaURL = "a2a.json"
+
+// a2aRequestDefinitions maps the A2A JSON-RPC methods to their request definition in the A2A schema
+var a2aRequestDefinitions = map[string]string{
+	"message/send":                        "SendMessageRequest",
+	"message/stream":                      "SendStreamingMessageRequest",
+	"tasks/get":                           "GetTaskRequest",
+	"tasks/list":                          "ListTasksRequest",
+	"tasks/cancel":                        "CancelTaskRequest",
+	"tasks/resubscribe":                   "TaskResubscriptionRequest",
+	"tasks/pushNotificationConfig/set":    "SetTaskPushNotificationConfigRequest",
+	"tasks/pushNotificationConfig/get":    "GetTaskPushNotificationConfigRequest",
+	"tasks/pushNotificationConfig/list":   "ListTaskPushNotificationConfigRequest",
+	"tasks/pushNotificationConfig/delete": "DeleteTaskPushNotificationConfigRequest",
+	"agent/getAuthenticatedExtendedCard":  "GetAuthenticatedExtendedCardRequest",
+}
+
+// A2AValidationConfig validates native A2A JSON-RPC requests sent to /{agent} before they are
+// forwarded. Only the listed methods are accepted; all A2A methods if the list is empty.
+type A2AValidationConfig struct {
+	Methods []string `json:"methods"`
+}
+
+// a2aValidator checks the JSON-RPC envelope and the params of A2A requests against the A2A schema
+type a2aValidator struct {
+	schemas map[string]*jsonschema.Schema
+}
+
+// newA2AValidator compiles the request schemas of the accepted methods. A nil config disables validation.
+func newA2AValidator(cfg *A2AValidationConfig) (*a2aValidator, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	methods := cfg.Methods
+	if len(methods) == 0 {
+		for method := range a2aRequestDefinitions {
+			methods = append(methods, method)
+		}
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(models.A2ASchema))
+	if err != nil {
+		return nil, fmt.Errorf("invalid A2A schema: %w", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(a2aSchemaURL, doc); err != nil {
+		return nil, fmt.Errorf("invalid A2A schema: %w", err)
+	}
+
+	v := &a2aValidator{schemas: make(map[string]*jsonschema.Schema, len(methods))}
+	for _, method := range methods {
+		definition, ok := a2aRequestDefinitions[method]
+		if !ok {
+			return nil, fmt.Errorf("unknown method '%s'", method)
+		}
+		if v.schemas[method], err = compiler.Compile(a2aSchemaURL + "#/definitions/" + definition); err != nil {
+			return nil, fmt.Errorf("cannot compile schema of method '%s': %w", method, err)
+		}
+	}
+	return v, nil
+}
+
+// nativeA2AAgent returns the agent of a native A2A call, i.e. a POST to /{model} or /{model}/
+func nativeA2AAgent(req *http.Request, agents []AgentInfo) (AgentInfo, bool) {
+	if req.Method != http.MethodPost {
+		return AgentInfo{}, false
+	}
+	model := strings.TrimSuffix(strings.TrimPrefix(req.URL.Path, "/"), "/")
+	for _, agent := range agents {
+		if agent.ModelID == model {
+			return agent, true
+		}
+	}
+	return AgentInfo{}, false
+}
+
+// admitNativeA2A restricts native A2A callers to the models of their tenant and JWT, like chat
+// completion clients. Forbidden agents are reported as unknown with a JSON-RPC error.
+func admitNativeA2A(w http.ResponseWriter, req *http.Request, tn *tenancy, modelID string) bool {
+	log := logging.FromRequest(logger, req)
+	t, err := tn.identify(req)
+	if err != nil {
+		log.Warning("rejected request:", err)
+		writeA2AError(w, http.StatusForbidden, nil, &models.JSONRPCErrorResponseError{Code: a2aerrors.CodeInvalidRequest, Message: err.Error()})
+		return false
+	}
+	if !t.allows(modelID) || !modelAllowed(req.Context(), modelID) {
+		log.Info("caller may not address agent:", modelID)
+		writeA2AError(w, http.StatusNotFound, nil, &models.JSONRPCErrorResponseError{Code: a2aerrors.CodeInvalidRequest, Message: fmt.Sprintf("agent %s not found", modelID)})
+		return false
+	}
+	return true
+}
+
+// handles reports whether the request is a native A2A call of an agent
+func (v *a2aValidator) handles(req *http.Request, agents []AgentInfo) bool {
+	if v == nil {
+		return false
+	}
+	_, ok := nativeA2AAgent(req, agents)
+	return ok
+}
+
+// admit validates the request and writes a JSON-RPC error response if it is malformed.
+// The body of admitted requests is restored for forwarding.
+func (v *a2aValidator) admit(w http.ResponseWriter, req *http.Request) bool {
+	log := logging.FromRequest(logger, req)
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		writeA2AError(w, http.StatusBadRequest, nil, &models.JSONRPCErrorResponseError{Code: a2aerrors.CodeJSONParse, Message: "cannot read request body"})
+		return false
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	id, rpcErr := v.validate(body)
+	if rpcErr != nil {
+		log.Info(fmt.Sprintf("rejected A2A request to %s: %s", req.URL.Path, rpcErr.Message))
+		writeA2AError(w, http.StatusBadRequest, id, rpcErr)
+		return false
+	}
+	return true
+}
+
+// validate checks the body and returns the request ID and the JSON-RPC error of a malformed request
+func (v *a2aValidator) validate(body []byte) (interface{}, *models.JSONRPCErrorResponseError) {
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return nil, &models.JSONRPCErrorResponseError{Code: a2aerrors.CodeJSONParse, Message: "request body is not valid JSON"}
+	}
+	request, ok := instance.(map[string]interface{})
+	if !ok {
+		return nil, &models.JSONRPCErrorResponseError{Code: a2aerrors.CodeInvalidRequest, Message: "request must be a JSON-RPC request object"}
+	}
+
+	id := request["id"]
+	switch id.(type) {
+	case string, json.Number:
+	default:
+		return nil, &models.JSONRPCErrorResponseError{Code: a2aerrors.CodeInvalidRequest, Message: "id must be a string or number"}
+	}
+	if request["jsonrpc"] != "2.0" {
+		return id, &models.JSONRPCErrorResponseError{Code: a2aerrors.CodeInvalidRequest, Message: "jsonrpc must be exactly \"2.0\""}
+	}
+	method, ok := request["method"].(string)
+	if !ok || method == "" {
+		return id, &models.JSONRPCErrorResponseError{Code: a2aerrors.CodeInvalidRequest, Message: "method is required"}
+	}
+	schema, ok := v.schemas[method]
+	if !ok {
+		return id, &models.JSONRPCErrorResponseError{Code: a2aerrors.CodeMethodNotFound, Message: fmt.Sprintf("method '%s' is not supported", method)}
+	}
+
+	err = schema.Validate(instance)
+	if err == nil {
+		return id, nil
+	}
+	validationErr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return id, &models.JSONRPCErrorResponseError{Code: a2aerrors.CodeInvalidParams, Message: err.Error()}
+	}
+	leaf := firstLeafError(validationErr)
+	param := paramFromLocation(leaf.InstanceLocation)
+	if required, ok := leaf.ErrorKind.(*kind.Required); ok && len(required.Missing) > 0 {
+		param = joinParam(param, required.Missing[0])
+	}
+	return id, &models.JSONRPCErrorResponseError{
+		Code:    a2aerrors.CodeInvalidParams,
+		Message: (&RequestValidationError{Param: param, Message: leaf.ErrorKind.LocalizedString(schemaMessages)}).Error(),
+	}
+}
+
+// writeA2AError answers a native A2A request with a JSON-RPC error response. Requests rejected
+// by the gateway use an error status, so they are not counted as successful calls.
+func writeA2AError(w http.ResponseWriter, statusCode int, id interface{}, rpcErr *models.JSONRPCErrorResponseError) {
+	if err := pluginkit.WriteJSONRPCError(w, statusCode, id, *rpcErr); err != nil {
+		logger.Error("failed to write response:", err)
+	}
+}
@@ -0,0 +1,106 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+const validA2ARequest = `{"jsonrpc":"2.0","id":"1","method":"message/send","params":{"message":{"kind":"message","messageId":"m1","role":"user","parts":[{"kind":"text","text":"Hi"}]}}}`
+
+func TestNewA2AValidator_UnknownMethod(t *testing.T) {
+	_, err := newA2AValidator(&A2AValidationConfig{Methods: []string{"message/send", "tasks/delete"}})
+
+	assert.ErrorContains(t, err, "tasks/delete")
+}
+
+func TestA2AValidator_Validate(t *testing.T) {
+	v, err := newA2AValidator(&A2AValidationConfig{Methods: []string{"message/send", "tasks/get"}})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		body     string
+		wantID   interface{}
+		wantCode int
+		wantMsg  string
+	}{
+		{name: "valid message", body: validA2ARequest, wantID: "1"},
+		{name: "valid task query", body: `{"jsonrpc":"2.0","id":7,"method":"tasks/get","params":{"id":"task-1"}}`, wantID: json.Number("7")},
+		{name: "malformed json", body: `{"jsonrpc":`, wantCode: a2aerrors.CodeJSONParse},
+		{name: "batch", body: `[` + validA2ARequest + `]`, wantCode: a2aerrors.CodeInvalidRequest},
+		{name: "missing id", body: `{"jsonrpc":"2.0","method":"tasks/get","params":{"id":"task-1"}}`, wantCode: a2aerrors.CodeInvalidRequest},
+		{name: "wrong version", body: `{"jsonrpc":"1.0","id":"1","method":"tasks/get","params":{"id":"task-1"}}`, wantID: "1", wantCode: a2aerrors.CodeInvalidRequest},
+		{name: "method not allowed", body: `{"jsonrpc":"2.0","id":"1","method":"tasks/cancel","params":{"id":"task-1"}}`, wantID: "1", wantCode: a2aerrors.CodeMethodNotFound},
+		{name: "unknown method", body: `{"jsonrpc":"2.0","id":"1","method":"tasks/purge"}`, wantID: "1", wantCode: a2aerrors.CodeMethodNotFound},
+		{
+			name:     "missing parts",
+			body:     `{"jsonrpc":"2.0","id":"1","method":"message/send","params":{"message":{"kind":"message","messageId":"m1","role":"user"}}}`,
+			wantID:   "1",
+			wantCode: a2aerrors.CodeInvalidParams,
+			wantMsg:  "params.message.parts",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, rpcErr := v.validate([]byte(tt.body))
+
+			assert.Equal(t, tt.wantID, id)
+			if tt.wantCode == 0 {
+				assert.Nil(t, rpcErr)
+				return
+			}
+			if assert.NotNil(t, rpcErr) {
+				assert.Equal(t, tt.wantCode, rpcErr.Code)
+				assert.Contains(t, rpcErr.Message, tt.wantMsg)
+			}
+		})
+	}
+}
+
+func TestA2AValidation_FrontDoor(t *testing.T) {
+	mockHandler := &MockHandler{StatusCode: http.StatusOK, Response: []byte(`{"jsonrpc":"2.0","id":"1","result":{}}`)}
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "weather-agent", "url": "http://localhost:8001"},
+			},
+			"a2a_validation": map[string]interface{}{},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+
+	send := func(path, body string) *httptest.ResponseRecorder {
+		mockHandler.ReceivedRequest = nil
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := send("/weather-agent/", validA2ARequest)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, validA2ARequest, string(mockHandler.ReceivedBody))
+
+	rec = send("/weather-agent", `{"jsonrpc":"2.0","id":"2","method":"message/send","params":{}}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Nil(t, mockHandler.ReceivedRequest)
+	var resp models.JSONRPCErrorResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "2.0", resp.Jsonrpc)
+	assert.Equal(t, "2", resp.Id)
+	assert.Equal(t, a2aerrors.CodeInvalidParams, resp.Error.Code)
+
+	// Requests to other paths are passed through unvalidated
+	rec = send("/other-service", `not json`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotNil(t, mockHandler.ReceivedRequest)
+}
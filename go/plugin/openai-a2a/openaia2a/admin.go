@@ -0,0 +1,151 @@
+package openaia2a
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/keyring"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+)
+
+// adminKeyUsagePath reports which clients use which credentials of the gateway's keyrings
+const adminKeyUsagePath = "/admin/keys/usage"
+
+// clientIDHeader lets callers identify themselves in key usage reports
+const clientIDHeader = "X-Client-ID"
+
+// AdminConfig protects the admin endpoints with rotatable bearer tokens
+type AdminConfig struct {
+	Keys []keyring.KeyConfig `json:"keys"`
+}
+
+// admin serves the admin endpoints and collects the keyrings that appear in usage reports
+type admin struct {
+	tokens   *keyring.Keyring
+	keyrings map[string]*keyring.Keyring
+	// routes maps methods and paths below /admin/, e.g. "GET /admin/keys/usage", to the subsystems serving them
+	routes map[string]http.HandlerFunc
+}
+
+// newAdmin creates the admin endpoints. A nil config disables them.
+func newAdmin(cfg *AdminConfig) (*admin, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if len(cfg.Keys) == 0 {
+		return nil, errors.New("at least one admin key is required")
+	}
+	tokens, err := keyring.FromConfig(cfg.Keys)
+	if err != nil {
+		return nil, err
+	}
+	a := &admin{
+		tokens:   tokens,
+		keyrings: map[string]*keyring.Keyring{"admin": tokens},
+		routes:   make(map[string]http.HandlerFunc),
+	}
+	a.route(adminKeyUsagePath, a.serveKeyUsage)
+	return a, nil
+}
+
+// register adds a keyring to the usage report. It is a no-op if the admin endpoints are disabled.
+func (a *admin) register(name string, kr *keyring.Keyring) {
+	if a == nil || kr == nil {
+		return
+	}
+	a.keyrings[name] = kr
+}
+
+// route adds an authenticated GET endpoint. It is a no-op if the admin endpoints are disabled.
+func (a *admin) route(path string, handler http.HandlerFunc) {
+	a.handle(http.MethodGet, path, handler)
+}
+
+// handle adds an authenticated endpoint for method and path. It is a no-op if the admin endpoints are disabled.
+func (a *admin) handle(method, path string, handler http.HandlerFunc) {
+	if a == nil {
+		return
+	}
+	a.routes[method+" "+path] = handler
+}
+
+// clientID identifies the caller for key usage reports by its host, since every connection
+// of a client uses another port
+func clientID(req *http.Request) string {
+	if id := req.Header.Get(clientIDHeader); id != "" {
+		return id
+	}
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
+	return host
+}
+
+// handles reports whether the request targets an admin endpoint
+func (a *admin) handles(req *http.Request) bool {
+	return a != nil && strings.HasPrefix(req.URL.Path, "/admin/")
+}
+
+// serveHTTP authenticates the caller and serves the admin endpoints
+func (a *admin) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	if !a.authorize(w, req) {
+		return
+	}
+	handler, ok := a.routes[req.Method+" "+req.URL.Path]
+	if !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+	handler(w, req)
+}
+
+// authorized reports whether the request carries a valid admin token without rejecting it,
+// e.g. to include internal details in public endpoints
+func (a *admin) authorized(req *http.Request) bool {
+	if a == nil {
+		return false
+	}
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	_, ok = a.tokens.Match([]byte(token), clientID(req))
+	return ok
+}
+
+// authorize checks the bearer token of the caller and rejects the request with 401 Unauthorized if it is invalid
+func (a *admin) authorize(w http.ResponseWriter, req *http.Request) bool {
+	log := logging.FromRequest(logger, req)
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	key, ok := a.tokens.Match([]byte(token), clientID(req))
+	if !ok {
+		log.Warning("rejected admin request with invalid token")
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	if primary, ok := a.tokens.Primary(); ok && primary.ID != key.ID {
+		log.Warning(fmt.Sprintf("admin client %s uses non-primary key %s", clientID(req), key.ID))
+	}
+	return true
+}
+
+// serveKeyUsage reports the usage of every registered keyring
+func (a *admin) serveKeyUsage(w http.ResponseWriter, _ *http.Request) {
+	report := make(map[string][]keyring.Usage, len(a.keyrings))
+	for name, kr := range a.keyrings {
+		report[name] = kr.Report()
+	}
+
+	if err := pluginkit.WriteJSON(w, http.StatusOK, map[string]interface{}{"keyrings": report}); err != nil {
+		logger.Error("failed to write response:", err)
+	}
+}
@@ -0,0 +1,82 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newAdminHandler(t *testing.T, mockHandler *MockHandler) http.Handler {
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"admin": map[string]interface{}{
+				"keys": []interface{}{
+					map[string]interface{}{"id": "2025", "secret": "old-admin-token", "not_after": "2999-01-01T00:00:00Z"},
+					map[string]interface{}{"id": "2026", "secret": "new-admin-token", "not_before": "2026-01-01T00:00:00Z"},
+				},
+			},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+	return handler
+}
+
+func adminRequest(handler http.Handler, token, client string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, adminKeyUsagePath, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set(clientIDHeader, client)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAdmin_RequiresValidToken(t *testing.T) {
+	mockHandler := &MockHandler{}
+	handler := newAdminHandler(t, mockHandler)
+
+	assert.Equal(t, http.StatusUnauthorized, adminRequest(handler, "", "ops").Code)
+	assert.Equal(t, http.StatusUnauthorized, adminRequest(handler, "wrong", "ops").Code)
+	assert.Nil(t, mockHandler.ReceivedRequest)
+}
+
+func TestAdmin_KeyUsageReport(t *testing.T) {
+	handler := newAdminHandler(t, &MockHandler{})
+
+	assert.Equal(t, http.StatusOK, adminRequest(handler, "old-admin-token", "legacy-dashboard").Code)
+	rec := adminRequest(handler, "new-admin-token", "ops")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var report struct {
+		Keyrings map[string][]struct {
+			Client string `json:"client"`
+			KeyID  string `json:"key_id"`
+			Stale  bool   `json:"stale"`
+		} `json:"keyrings"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+
+	usage := report.Keyrings["admin"]
+	assert.Len(t, usage, 2)
+	assert.Equal(t, "legacy-dashboard", usage[0].Client)
+	assert.True(t, usage[0].Stale)
+	assert.Equal(t, "ops", usage[1].Client)
+	assert.False(t, usage[1].Stale)
+}
+
+func TestAdmin_DisabledByDefault(t *testing.T) {
+	mockHandler := &MockHandler{StatusCode: http.StatusNotFound}
+	handler, err := module.registerHandlers(context.Background(), map[string]interface{}{}, mockHandler)
+	assert.NoError(t, err)
+
+	rec := adminRequest(handler, "token", "ops")
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.NotNil(t, mockHandler.ReceivedRequest)
+}
@@ -0,0 +1,375 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2agrpc"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/upstream"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultRefreshInterval = 30 * time.Second
+
+// AgentsSource points to an external agents list that is re-read periodically and on SIGHUP.
+// Exactly one of Path, URL, DNS and Registry must be set. The document of Path and URL has the
+// same shape as openai_a2a_config, i.e. an object with an "agents" array, in JSON or YAML.
+type AgentsSource struct {
+	Path            string          `json:"path"`
+	URL             string          `json:"url"`
+	DNS             *DNSSource      `json:"dns,omitempty"`
+	Registry        *RegistrySource `json:"registry,omitempty"`
+	Format          string          `json:"format"`
+	RefreshInterval string          `json:"refresh_interval"`
+}
+
+// agentSet is an immutable snapshot of the configured agents and their routing state
+type agentSet struct {
+	agents    []AgentInfo
+	routes    *routingTable
+	balancers map[string]*replicaBalancer
+	grpc      map[string]*a2agrpc.Client
+}
+
+// newAgentSet validates agents and builds their routing state. Balancers of agents whose
+// load balancing configuration did not change are taken over from the previous set,
+// so passive health and round-robin positions survive reloads.
+func newAgentSet(agents []AgentInfo, previous *agentSet, matcher *modelMatcher) (*agentSet, error) {
+	if err := validateCanaries(agents); err != nil {
+		return nil, fmt.Errorf("invalid canary configuration: %w", err)
+	}
+	if err := validateMirrors(agents); err != nil {
+		return nil, fmt.Errorf("invalid mirror configuration: %w", err)
+	}
+	if err := validateDeprecations(agents); err != nil {
+		return nil, fmt.Errorf("invalid deprecation configuration: %w", err)
+	}
+	if err := validateCancellations(agents); err != nil {
+		return nil, fmt.Errorf("invalid cancellation configuration: %w", err)
+	}
+	if err := validateAliases(agents); err != nil {
+		return nil, fmt.Errorf("invalid alias configuration: %w", err)
+	}
+	if err := validateSystemPrompts(agents); err != nil {
+		return nil, fmt.Errorf("invalid system prompt configuration: %w", err)
+	}
+	if err := validateMetadata(agents); err != nil {
+		return nil, fmt.Errorf("invalid model metadata: %w", err)
+	}
+	balancers, err := newBalancers(agents)
+	if err != nil {
+		return nil, fmt.Errorf("invalid load balancing configuration: %w", err)
+	}
+
+	if previous != nil {
+		for _, agent := range agents {
+			if agent.LoadBalancing == nil {
+				continue
+			}
+			for _, old := range previous.agents {
+				if old.ModelID == agent.ModelID && reflect.DeepEqual(old.LoadBalancing, agent.LoadBalancing) {
+					balancers[agent.ModelID] = previous.balancers[agent.ModelID]
+				}
+			}
+		}
+	}
+
+	if err := configureAgentHosts(agents, previous); err != nil {
+		return nil, fmt.Errorf("invalid upstream configuration: %w", err)
+	}
+
+	routes := newRoutingTable(agents, matcher)
+	grpc, err := newGRPCClients(agents, previous)
+	if err != nil {
+		return nil, fmt.Errorf("invalid transport configuration: %w", err)
+	}
+
+	return &agentSet{agents: agents, routes: routes, balancers: balancers, grpc: grpc}, nil
+}
+
+// agent returns the agent configured for a model ID or alias
+func (set *agentSet) agent(model string) (AgentInfo, bool) {
+	return set.routes.agent(model)
+}
+
+// agentStore holds the current agent set and swaps it atomically on reload
+type agentStore struct {
+	current   atomic.Pointer[agentSet]
+	source    *AgentsSource
+	discovery agentDiscovery
+	client    *http.Client
+	matcher   *modelMatcher
+}
+
+// newAgentStore creates the store from the static agents of the configuration.
+// If a source is configured, it is loaded immediately; a failing initial load keeps
+// the static agents and is retried on the next refresh.
+func newAgentStore(agents []AgentInfo, source *AgentsSource, matcher *modelMatcher) (*agentStore, error) {
+	set, err := newAgentSet(agents, nil, matcher)
+	if err != nil {
+		return nil, err
+	}
+	store := &agentStore{source: source, client: upstreamClient, matcher: matcher}
+	store.current.Store(set)
+
+	if source != nil {
+		sources := 0
+		for _, set := range []bool{source.Path != "", source.URL != "", source.DNS != nil, source.Registry != nil} {
+			if set {
+				sources++
+			}
+		}
+		if sources != 1 {
+			return nil, errors.New("agents_source requires exactly one of path, url, dns or registry")
+		}
+		interval, err := source.refreshInterval()
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case source.DNS != nil:
+			if store.discovery, err = newDNSDiscovery(source.DNS, interval); err != nil {
+				return nil, err
+			}
+		case source.Registry != nil:
+			if store.discovery, err = newRegistryDiscovery(source.Registry, store.client, interval); err != nil {
+				return nil, err
+			}
+		}
+		if err := store.reload(context.Background()); err != nil {
+			logger.Warning("initial load of agents_source failed, using static agents:", err)
+		}
+	}
+	return store, nil
+}
+
+// load returns the current agent set
+func (s *agentStore) load() *agentSet {
+	return s.current.Load()
+}
+
+// agents returns the currently configured agents
+func (s *agentStore) agents() []AgentInfo {
+	return s.load().agents
+}
+
+// reload reads the source and replaces the agent set. The current set is kept on any error.
+func (s *agentStore) reload(ctx context.Context) error {
+	agents, err := s.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	previous := s.load()
+	set, err := newAgentSet(agents, previous, s.matcher)
+	if err != nil {
+		return err
+	}
+	s.current.Store(set)
+	publishAgentChanges(previous.agents, set.agents)
+	logger.Info(fmt.Sprintf("reloaded %d agents from agents_source", len(agents)))
+	return nil
+}
+
+// fetch returns the agents of the discovery or of the agents document
+func (s *agentStore) fetch(ctx context.Context) ([]AgentInfo, error) {
+	if s.discovery != nil {
+		return s.discovery.discover(ctx)
+	}
+	raw, err := s.source.read(ctx, s.client)
+	if err != nil {
+		return nil, err
+	}
+	var doc struct {
+		Agents []AgentInfo `json:"agents"`
+	}
+	if err := decodeAgentsDocument(raw, s.source.format(), &doc); err != nil {
+		return nil, err
+	}
+	return doc.Agents, nil
+}
+
+// refreshDelay returns the delay until the next reload. Discoveries decide it themselves,
+// e.g. by the TTL of DNS records, or reload right away as they block until a change.
+func (s *agentStore) refreshDelay(interval time.Duration) time.Duration {
+	if s.discovery != nil {
+		return s.discovery.next()
+	}
+	return interval
+}
+
+// watch reloads the agents when they are due and on SIGHUP until ctx is done
+func (s *agentStore) watch(ctx context.Context) {
+	if s.source == nil {
+		return
+	}
+	interval, _ := s.source.refreshInterval()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(hup)
+		timer := time.NewTimer(s.refreshDelay(interval))
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timer.C:
+			case <-hup:
+				logger.Info("received SIGHUP, reloading agents")
+			}
+			if err := s.reload(ctx); err != nil {
+				logger.Error("failed to reload agents, keeping current configuration:", err)
+			}
+			timer.Reset(s.refreshDelay(interval))
+		}
+	}()
+}
+
+// publishAgentChanges publishes the models added and removed by a reload
+func publishAgentChanges(previous, current []AgentInfo) {
+	known := make(map[string]bool, len(previous))
+	for _, agent := range previous {
+		known[agent.ModelID] = true
+	}
+	for _, agent := range current {
+		if !known[agent.ModelID] {
+			events.publish(eventAgentRegistered, map[string]interface{}{"model": agent.ModelID})
+		}
+		delete(known, agent.ModelID)
+	}
+	for _, agent := range previous {
+		if known[agent.ModelID] {
+			events.publish(eventAgentUnregistered, map[string]interface{}{"model": agent.ModelID})
+		}
+	}
+}
+
+func (src *AgentsSource) refreshInterval() (time.Duration, error) {
+	if src.RefreshInterval == "" {
+		return defaultRefreshInterval, nil
+	}
+	d, err := time.ParseDuration(src.RefreshInterval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid refresh_interval: %w", err)
+	}
+	if d <= 0 {
+		return 0, errors.New("refresh_interval must be positive")
+	}
+	return d, nil
+}
+
+// format returns the configured document format, falling back to the file extension
+func (src *AgentsSource) format() string {
+	if src.Format != "" {
+		return strings.ToLower(src.Format)
+	}
+	location := src.Path
+	if location == "" {
+		location = src.URL
+	}
+	switch strings.ToLower(filepath.Ext(location)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	default:
+		return "json"
+	}
+}
+
+// read fetches the raw source document from the file system or via HTTP GET
+func (src *AgentsSource) read(ctx context.Context, client *http.Client) ([]byte, error) {
+	if src.Path != "" {
+		return os.ReadFile(src.Path)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("agents_source returned status %d", resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// decodeAgentsDocument decodes JSON or YAML into v. YAML is converted to JSON first
+// so that the JSON field names of AgentInfo apply to both formats.
+func decodeAgentsDocument(raw []byte, format string, v interface{}) error {
+	switch format {
+	case "json":
+	case "yaml":
+		var doc interface{}
+		if err := yaml.Unmarshal(raw, &doc); err != nil {
+			return fmt.Errorf("cannot parse YAML agents document: %w", err)
+		}
+		var err error
+		if raw, err = json.Marshal(doc); err != nil {
+			return fmt.Errorf("cannot convert YAML agents document: %w", err)
+		}
+	default:
+		return fmt.Errorf("unknown agents document format '%s'", format)
+	}
+
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("cannot parse agents document: %w", err)
+	}
+	return nil
+}
+
+// configureAgentHosts configures the upstream transport with the TLS and proxy configuration
+// of every host an agent routes to. Insecure configurations are logged when they are added.
+func configureAgentHosts(agents []AgentInfo, previous *agentSet) error {
+	configs := make(map[string]upstream.HostConfig)
+	owners := make(map[string]string)
+	for _, agent := range agents {
+		if agent.TLS == nil && agent.Proxy == "" {
+			continue
+		}
+		hostConfig := upstream.HostConfig{TLS: agent.TLS, Proxy: agent.Proxy}
+		targets := agentTargets(agent)
+		if agent.MirrorTo != nil {
+			targets = append(targets, agent.MirrorTo.URL)
+		}
+		for _, target := range targets {
+			parsed, err := url.Parse(target)
+			if err != nil || parsed.Host == "" {
+				continue
+			}
+			if owner, ok := owners[parsed.Host]; ok && !reflect.DeepEqual(configs[parsed.Host], hostConfig) {
+				return fmt.Errorf("agents %s and %s configure different tls or proxy for host %s", owner, agent.ModelID, parsed.Host)
+			}
+			configs[parsed.Host], owners[parsed.Host] = hostConfig, agent.ModelID
+		}
+
+		if agent.TLS == nil || !agent.TLS.InsecureSkipVerify {
+			continue
+		}
+		if previous != nil {
+			if old, ok := previous.agent(agent.ModelID); ok && old.TLS != nil && old.TLS.InsecureSkipVerify {
+				continue
+			}
+		}
+		logger.Warning(fmt.Sprintf("agent %s: tls.insecure_skip_verify is set, its certificates are NOT verified and connections to it can be intercepted", agent.ModelID))
+	}
+	return upstreamTransport.SetHosts(configs)
+}
@@ -0,0 +1,130 @@
+package openaia2a
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/upstream"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAgentStore_InvalidSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		source AgentsSource
+	}{
+		{name: "no location", source: AgentsSource{}},
+		{name: "path and url", source: AgentsSource{Path: "agents.json", URL: "http://config:8080/agents"}},
+		{name: "invalid interval", source: AgentsSource{Path: "agents.json", RefreshInterval: "often"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newAgentStore(nil, &tt.source, nil)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAgentStore_ReloadFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agents.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+agents:
+  - model_id: weather-agent
+    url: http://weather:8000
+    owned_by: weather-team
+`), 0o600))
+
+	store, err := newAgentStore([]AgentInfo{{ModelID: "static-agent", URL: "http://static:8000"}}, &AgentsSource{Path: path}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []AgentInfo{{ModelID: "weather-agent", URL: "http://weather:8000", OwnedBy: "weather-team"}}, store.agents())
+
+	// Agents are added without restarting
+	assert.NoError(t, os.WriteFile(path, []byte(`
+agents:
+  - model_id: weather-agent
+    url: http://weather:8000
+  - model_id: news-agent
+    url: http://news:8000
+`), 0o600))
+	assert.NoError(t, store.reload(context.Background()))
+	assert.Len(t, store.agents(), 2)
+
+	// Invalid documents keep the current agents
+	assert.NoError(t, os.WriteFile(path, []byte(`agents: [`), 0o600))
+	assert.Error(t, store.reload(context.Background()))
+	assert.Len(t, store.agents(), 2)
+}
+
+func TestAgentStore_ReloadFromURL(t *testing.T) {
+	document := `{"agents": [{"model_id": "a", "load_balancing": {"replicas": [{"url": "http://a1:8000"}, {"url": "http://a2:8000"}]}}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(document))
+	}))
+	defer server.Close()
+
+	store, err := newAgentStore(nil, &AgentsSource{URL: server.URL}, nil)
+	assert.NoError(t, err)
+	balancer := store.load().balancers["a"]
+	assert.NotNil(t, balancer)
+
+	// Unchanged load balancing keeps the balancer and its replica state
+	assert.NoError(t, store.reload(context.Background()))
+	assert.Same(t, balancer, store.load().balancers["a"])
+
+	// Invalid agent configuration is rejected
+	document = `{"agents": [{"model_id": "a", "load_balancing": {"replicas": []}}]}`
+	assert.Error(t, store.reload(context.Background()))
+	assert.Same(t, balancer, store.load().balancers["a"])
+}
+
+func TestAgentStore_InitialLoadFailureKeepsStaticAgents(t *testing.T) {
+	static := []AgentInfo{{ModelID: "static-agent", URL: "http://static:8000"}}
+
+	store, err := newAgentStore(static, &AgentsSource{Path: filepath.Join(t.TempDir(), "missing.json")}, nil)
+
+	assert.NoError(t, err)
+	assert.Equal(t, static, store.agents())
+}
+
+func TestNewAgentSet_AgentTLS(t *testing.T) {
+	t.Cleanup(func() { _ = upstreamTransport.SetHosts(nil) })
+	agents := []AgentInfo{
+		{ModelID: "weather", URL: "https://weather:8443", TLS: &upstream.TLSConfig{InsecureSkipVerify: true},
+			Canary: &CanaryConfig{URL: "https://weather-canary:8443", Percentage: 10}},
+		{ModelID: "forecast", URL: "https://forecast:8443"},
+	}
+
+	_, err := newAgentSet(agents, nil, nil)
+	assert.NoError(t, err)
+	assert.True(t, upstreamTransport.ForHost("weather:8443").TLSClientConfig.InsecureSkipVerify)
+	assert.True(t, upstreamTransport.ForHost("weather-canary:8443").TLSClientConfig.InsecureSkipVerify, "all targets of an agent use its tls")
+	assert.False(t, upstreamTransport.ForHost("forecast:8443").TLSClientConfig.InsecureSkipVerify)
+
+	agents = append(agents, AgentInfo{ModelID: "alerts", URL: "https://weather:8443/alerts", TLS: &upstream.TLSConfig{MinVersion: "1.3"}})
+	_, err = newAgentSet(agents, nil, nil)
+	assert.ErrorContains(t, err, "agents weather and alerts configure different tls or proxy for host weather:8443")
+}
+
+func TestNewAgentSet_AgentProxy(t *testing.T) {
+	t.Cleanup(func() { _ = upstreamTransport.SetHosts(nil) })
+	agents := []AgentInfo{
+		{ModelID: "partner", URL: "https://agents.partner.example", Proxy: "http://egress-proxy:3128"},
+		{ModelID: "weather", URL: "http://weather:8000"},
+	}
+
+	_, err := newAgentSet(agents, nil, nil)
+	assert.NoError(t, err)
+	proxyURL, err := upstreamTransport.ForHost("agents.partner.example").Proxy(httptest.NewRequest(http.MethodPost, "https://agents.partner.example", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "http://egress-proxy:3128", proxyURL.String())
+
+	agents[0].Proxy = "egress-proxy"
+	_, err = newAgentSet(agents, nil, nil)
+	assert.ErrorContains(t, err, "invalid proxy 'egress-proxy'")
+}
@@ -0,0 +1,277 @@
+package openaia2a
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+)
+
+// adminAnomaliesPath reports token usage anomalies per API key
+const adminAnomaliesPath = "/admin/usage/anomalies"
+
+const (
+	defaultAnomalyWindow          = time.Minute
+	defaultAnomalyBaselineWindows = 60
+	defaultAnomalyWarmupWindows   = 5
+	defaultAnomalyThreshold       = 5.0
+	defaultAnomalyMinTokens       = 1000
+	// maxTrackedKeys bounds memory use; idle keys are evicted beyond it
+	maxTrackedKeys = 10000
+)
+
+// AnomalyConfig flags API keys whose token usage in a window spikes far above their baseline.
+// The baseline is an exponential moving average over roughly BaselineWindows windows. Flagged
+// keys are published as usage.anomaly webhook events.
+type AnomalyConfig struct {
+	Window          string  `json:"window"`
+	BaselineWindows int     `json:"baseline_windows"`
+	WarmupWindows   int     `json:"warmup_windows"`
+	Threshold       float64 `json:"threshold"`
+	MinTokens       int     `json:"min_tokens"`
+	Throttle        string  `json:"throttle"`
+}
+
+// KeyAnomalies is the anomaly counter of a single API key in the admin report
+type KeyAnomalies struct {
+	KeyID          string  `json:"key_id"`
+	Anomalies      int     `json:"anomalies"`
+	LastFlagged    int64   `json:"last_flagged,omitempty"`
+	ThrottledUntil int64   `json:"throttled_until,omitempty"`
+	WindowTokens   int     `json:"window_tokens"`
+	Baseline       float64 `json:"baseline"`
+}
+
+// keyUsage is the rolling usage state of one API key
+type keyUsage struct {
+	windowStart    time.Time
+	tokens         int
+	baseline       float64
+	windows        int
+	flagged        bool
+	anomalies      int
+	lastFlagged    time.Time
+	throttledUntil time.Time
+}
+
+// anomalyDetector tracks per-key token usage and flags sudden spikes
+type anomalyDetector struct {
+	window    time.Duration
+	alpha     float64
+	warmup    int
+	threshold float64
+	minTokens int
+	throttle  time.Duration
+
+	mu   sync.Mutex
+	keys map[string]*keyUsage
+	now  func() time.Time
+}
+
+// newAnomalyDetector validates the configuration. A nil config disables anomaly detection.
+func newAnomalyDetector(cfg *AnomalyConfig) (*anomalyDetector, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+
+	d := &anomalyDetector{
+		window:    defaultAnomalyWindow,
+		warmup:    defaultAnomalyWarmupWindows,
+		threshold: defaultAnomalyThreshold,
+		minTokens: defaultAnomalyMinTokens,
+		keys:      make(map[string]*keyUsage),
+		now:       time.Now,
+	}
+
+	var err error
+	if cfg.Window != "" {
+		if d.window, err = time.ParseDuration(cfg.Window); err != nil || d.window <= 0 {
+			return nil, fmt.Errorf("invalid window '%s'", cfg.Window)
+		}
+	}
+	if cfg.Throttle != "" {
+		if d.throttle, err = time.ParseDuration(cfg.Throttle); err != nil || d.throttle <= 0 {
+			return nil, fmt.Errorf("invalid throttle '%s'", cfg.Throttle)
+		}
+	}
+	if cfg.BaselineWindows < 0 || cfg.WarmupWindows < 0 || cfg.MinTokens < 0 || cfg.Threshold < 0 {
+		return nil, errors.New("baseline_windows, warmup_windows, threshold and min_tokens must not be negative")
+	}
+	baselineWindows := defaultAnomalyBaselineWindows
+	if cfg.BaselineWindows > 0 {
+		baselineWindows = cfg.BaselineWindows
+	}
+	d.alpha = 2 / float64(baselineWindows+1)
+	if cfg.WarmupWindows > 0 {
+		d.warmup = cfg.WarmupWindows
+	}
+	if cfg.Threshold > 0 {
+		if cfg.Threshold <= 1 {
+			return nil, errors.New("threshold must be greater than 1")
+		}
+		d.threshold = cfg.Threshold
+	}
+	if cfg.MinTokens > 0 {
+		d.minTokens = cfg.MinTokens
+	}
+	return d, nil
+}
+
+// apiKeyID identifies the caller of a request by its authenticated principal or, without
+// authentication, the API key by a fingerprint, so raw keys are never kept
+func apiKeyID(req *http.Request) (string, bool) {
+	if p := principal(req.Context()); p != "" {
+		return p, true
+	}
+	key, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || key == "" {
+		return "", false
+	}
+	sum := sha256.Sum256([]byte(key))
+	return "key-" + hex.EncodeToString(sum[:6]), true
+}
+
+// throttled reports whether requests of the key are currently rejected after an anomaly
+func (d *anomalyDetector) throttled(req *http.Request) bool {
+	if d == nil {
+		return false
+	}
+	keyID, ok := apiKeyID(req)
+	if !ok {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	usage, ok := d.keys[keyID]
+	return ok && d.now().Before(usage.throttledUntil)
+}
+
+// record adds the token usage of a completion to the key's current window
+// and flags the key if the window exceeds its baseline by the threshold.
+func (d *anomalyDetector) record(req *http.Request, tokens int) {
+	log := logging.FromRequest(logger, req)
+	if d == nil || tokens <= 0 {
+		return
+	}
+	keyID, ok := apiKeyID(req)
+	if !ok {
+		return
+	}
+
+	d.mu.Lock()
+	now := d.now()
+	usage, ok := d.keys[keyID]
+	if !ok {
+		if len(d.keys) >= maxTrackedKeys {
+			d.evictIdle(now)
+		}
+		usage = &keyUsage{windowStart: now}
+		d.keys[keyID] = usage
+	}
+	d.roll(usage, now)
+	usage.tokens += tokens
+
+	var event map[string]interface{}
+	if !usage.flagged && usage.windows >= d.warmup && usage.tokens >= d.minTokens &&
+		float64(usage.tokens) > d.threshold*usage.baseline {
+		usage.flagged = true
+		usage.anomalies++
+		usage.lastFlagged = now
+		if d.throttle > 0 {
+			usage.throttledUntil = now.Add(d.throttle)
+		}
+		event = map[string]interface{}{
+			"key_id":       keyID,
+			"window_start": usage.windowStart.UTC(),
+			"tokens":       usage.tokens,
+			"baseline":     usage.baseline,
+		}
+		if !usage.throttledUntil.IsZero() {
+			event["throttled_until"] = usage.throttledUntil.UTC()
+		}
+	}
+	d.mu.Unlock()
+
+	if event != nil {
+		log.Warning(fmt.Sprintf("token usage anomaly for %s: %d tokens in window, baseline %.1f", keyID, event["tokens"], event["baseline"]))
+		events.publish(eventUsageAnomaly, event)
+	}
+}
+
+// roll closes every window that ended before now and folds it into the baseline.
+// Windows without requests count as zero usage.
+func (d *anomalyDetector) roll(usage *keyUsage, now time.Time) {
+	elapsed := int(now.Sub(usage.windowStart) / d.window)
+	if elapsed <= 0 {
+		return
+	}
+	// After enough empty windows the baseline has decayed to roughly zero anyway
+	limit := min(elapsed, int(4/d.alpha)+1)
+	for i := 0; i < limit; i++ {
+		tokens := 0
+		if i == 0 {
+			tokens = usage.tokens
+		}
+		if usage.windows == 0 {
+			usage.baseline = float64(tokens)
+		} else {
+			usage.baseline += d.alpha * (float64(tokens) - usage.baseline)
+		}
+		usage.windows++
+	}
+	usage.windowStart = usage.windowStart.Add(time.Duration(elapsed) * d.window)
+	usage.tokens = 0
+	usage.flagged = false
+}
+
+// evictIdle drops keys without usage in the last baseline period and not throttled
+func (d *anomalyDetector) evictIdle(now time.Time) {
+	idle := time.Duration(2/d.alpha) * d.window
+	for keyID, usage := range d.keys {
+		if now.Sub(usage.windowStart) > idle && now.After(usage.throttledUntil) {
+			delete(d.keys, keyID)
+		}
+	}
+}
+
+// serveReport lists the anomaly counters of all keys flagged at least once
+func (d *anomalyDetector) serveReport(w http.ResponseWriter, _ *http.Request) {
+	d.mu.Lock()
+	now := d.now()
+	total := 0
+	report := make([]KeyAnomalies, 0)
+	for keyID, usage := range d.keys {
+		d.roll(usage, now)
+		if usage.anomalies == 0 {
+			continue
+		}
+		total += usage.anomalies
+		entry := KeyAnomalies{
+			KeyID:        keyID,
+			Anomalies:    usage.anomalies,
+			LastFlagged:  usage.lastFlagged.Unix(),
+			WindowTokens: usage.tokens,
+			Baseline:     usage.baseline,
+		}
+		if now.Before(usage.throttledUntil) {
+			entry.ThrottledUntil = usage.throttledUntil.Unix()
+		}
+		report = append(report, entry)
+	}
+	d.mu.Unlock()
+
+	sort.Slice(report, func(i, j int) bool { return report[i].KeyID < report[j].KeyID })
+
+	if err := pluginkit.WriteJSON(w, http.StatusOK, map[string]interface{}{"anomalies_total": total, "keys": report}); err != nil {
+		logger.Error("failed to write response:", err)
+	}
+}
@@ -0,0 +1,148 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func keyRequest(key string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/chat/completions", nil)
+	req.Header.Set("Authorization", "Bearer "+key)
+	return req
+}
+
+func newTestAnomalyDetector(t *testing.T, cfg AnomalyConfig) (*anomalyDetector, *time.Time, chan WebhookEvent) {
+	d, err := newAnomalyDetector(&cfg)
+	assert.NoError(t, err)
+	now := time.Unix(1731679800, 0)
+	d.now = func() time.Time { return now }
+	return d, &now, captureEvents(t)
+}
+
+func TestNewAnomalyDetector_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  AnomalyConfig
+	}{
+		{name: "invalid window", cfg: AnomalyConfig{Window: "hourly"}},
+		{name: "invalid throttle", cfg: AnomalyConfig{Throttle: "-1m"}},
+		{name: "threshold not above 1", cfg: AnomalyConfig{Threshold: 0.5}},
+		{name: "negative min tokens", cfg: AnomalyConfig{MinTokens: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newAnomalyDetector(&tt.cfg)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAnomalyDetector_FlagsSpikeAboveBaseline(t *testing.T) {
+	d, now, events := newTestAnomalyDetector(t, AnomalyConfig{
+		Window:        "1m",
+		WarmupWindows: 3,
+		Threshold:     5,
+		MinTokens:     100,
+		Throttle:      "10m",
+	})
+	req := keyRequest("sk-leaked")
+
+	// Steady usage builds the baseline
+	for i := 0; i < 5; i++ {
+		d.record(req, 100)
+		*now = now.Add(time.Minute)
+	}
+	d.record(req, 400)
+	assert.Empty(t, events)
+	assert.False(t, d.throttled(req))
+
+	// A spike far above the baseline is flagged once per window
+	d.record(req, 200)
+	d.record(req, 100)
+
+	event := <-events
+	assert.Equal(t, eventUsageAnomaly, event.Type)
+	assert.Equal(t, 600, event.Data["tokens"])
+	assert.InDelta(t, 100, event.Data["baseline"], 0.01)
+	assert.Equal(t, now.Add(10*time.Minute).UTC(), event.Data["throttled_until"])
+	assert.NotContains(t, event.Data["key_id"], "sk-leaked")
+	assert.Empty(t, events)
+	assert.True(t, d.throttled(req))
+	assert.False(t, d.throttled(keyRequest("sk-other")))
+
+	*now = now.Add(11 * time.Minute)
+	assert.False(t, d.throttled(req))
+}
+
+func TestAnomalyDetector_IgnoresSmallAndWarmupUsage(t *testing.T) {
+	d, now, events := newTestAnomalyDetector(t, AnomalyConfig{WarmupWindows: 2, MinTokens: 1000})
+	req := keyRequest("sk-new")
+
+	// No baseline yet
+	d.record(req, 5000)
+	*now = now.Add(time.Minute)
+	d.record(req, 10)
+	*now = now.Add(time.Minute)
+	// Far above baseline but below min_tokens
+	d.record(req, 999)
+
+	assert.Empty(t, events)
+}
+
+func TestAnomalyDetector_KeysBaselinesOnThePrincipal(t *testing.T) {
+	d, now, events := newTestAnomalyDetector(t, AnomalyConfig{WarmupWindows: 1, MinTokens: 10})
+	authenticated := func(key string) *http.Request {
+		req := keyRequest(key)
+		return req.WithContext(context.WithValue(req.Context(), principalKey{}, "weather-team"))
+	}
+
+	// Rotating to a new key of the same group keeps the baseline
+	d.record(authenticated("sk-old"), 10)
+	*now = now.Add(time.Minute)
+	d.record(authenticated("sk-new"), 100)
+
+	event := <-events
+	assert.Equal(t, "weather-team", event.Data["key_id"])
+}
+
+func TestAnomalyDetector_AdminReport(t *testing.T) {
+	d, now, _ := newTestAnomalyDetector(t, AnomalyConfig{WarmupWindows: 1, MinTokens: 10})
+	req := keyRequest("sk-leaked")
+	d.record(req, 10)
+	*now = now.Add(time.Minute)
+	d.record(req, 100)
+	d.record(keyRequest("sk-normal"), 10)
+
+	rec := httptest.NewRecorder()
+	d.serveReport(rec, httptest.NewRequest(http.MethodGet, adminAnomaliesPath, nil))
+
+	var report struct {
+		Total int            `json:"anomalies_total"`
+		Keys  []KeyAnomalies `json:"keys"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, 1, report.Total)
+	assert.Len(t, report.Keys, 1)
+	keyID, _ := apiKeyID(req)
+	assert.Equal(t, keyID, report.Keys[0].KeyID)
+	assert.NotContains(t, rec.Body.String(), "sk-leaked")
+}
+
+func TestEstimateUsage(t *testing.T) {
+	usage := estimateUsage(models.OpenAIRequest{
+		Messages: []models.OpenAIMessage{{Role: "user", Content: "12345678"}, {Role: "user", Content: "123"}},
+	}, "12345")
+
+	assert.Equal(t, 3, usage.PromptTokens)
+	assert.Equal(t, 2, usage.CompletionTokens)
+	assert.Equal(t, 5, usage.TotalTokens)
+}
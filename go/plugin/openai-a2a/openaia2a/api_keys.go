@@ -0,0 +1,134 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/keyring"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+)
+
+// APIKeysConfig enforces API keys on the OpenAI-compatible endpoints. Keys are merged from
+// the static list, a JSON file with the same shape, and an environment variable holding
+// comma-separated "id:secret" pairs. The key group is the principal of the caller, so the old
+// and the new key of a principal share its group during a rotation; it defaults to the key ID.
+type APIKeysConfig struct {
+	Keys []keyring.KeyConfig `json:"keys"`
+	File string              `json:"file"`
+	Env  string              `json:"env"`
+}
+
+type principalKey struct{}
+
+// apiKeys authenticates callers of the OpenAI-compatible endpoints
+type apiKeys struct {
+	keys *keyring.Keyring
+}
+
+// newAPIKeys loads all configured keys. A nil config disables API key enforcement.
+func newAPIKeys(cfg *APIKeysConfig) (*apiKeys, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	configs := append([]keyring.KeyConfig{}, cfg.Keys...)
+	if cfg.File != "" {
+		raw, err := os.ReadFile(cfg.File)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read keys file: %w", err)
+		}
+		var fileKeys []keyring.KeyConfig
+		if err := json.Unmarshal(raw, &fileKeys); err != nil {
+			return nil, fmt.Errorf("cannot parse keys file: %w", err)
+		}
+		configs = append(configs, fileKeys...)
+	}
+	if cfg.Env != "" {
+		envKeys, err := parseEnvKeys(os.Getenv(cfg.Env))
+		if err != nil {
+			return nil, fmt.Errorf("invalid keys in %s: %w", cfg.Env, err)
+		}
+		configs = append(configs, envKeys...)
+	}
+	if len(configs) == 0 {
+		return nil, errors.New("at least one key is required")
+	}
+	for i := range configs {
+		if configs[i].Group == "" {
+			configs[i].Group = configs[i].ID
+		}
+	}
+	keys, err := keyring.FromConfig(configs)
+	if err != nil {
+		return nil, err
+	}
+	return &apiKeys{keys: keys}, nil
+}
+
+// parseEnvKeys parses comma-separated "id:secret" pairs
+func parseEnvKeys(value string) ([]keyring.KeyConfig, error) {
+	var configs []keyring.KeyConfig
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, errors.New("expected comma-separated id:secret pairs")
+		}
+		configs = append(configs, keyring.KeyConfig{ID: id, Secret: secret})
+	}
+	return configs, nil
+}
+
+// authenticate validates the bearer key of a request and returns the request with the
+// key's principal attached. It writes an OpenAI 401 error and returns false on failure.
+func (k *apiKeys) authenticate(w http.ResponseWriter, req *http.Request) (*http.Request, bool) {
+	log := logging.FromRequest(logger, req)
+	if k == nil {
+		return req, true
+	}
+	secret, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok || secret == "" {
+		writeOpenAIError(w, http.StatusUnauthorized, models.OpenAIError{
+			Message: "You didn't provide an API key. You need to provide your API key in an Authorization header using Bearer auth (i.e. Authorization: Bearer YOUR_KEY).",
+			Type:    "invalid_request_error",
+		})
+		return req, false
+	}
+	key, ok := k.keys.Match([]byte(secret), clientID(req))
+	if !ok {
+		log.Warning("rejected request with invalid API key")
+		code := "invalid_api_key"
+		writeOpenAIError(w, http.StatusUnauthorized, models.OpenAIError{
+			Message: fmt.Sprintf("Incorrect API key provided: %s.", maskKey(secret)),
+			Type:    "invalid_request_error",
+			Code:    &code,
+		})
+		return req, false
+	}
+
+	log.Debug("authenticated principal:", key.Group)
+	recordPrincipal(req.Context(), key.Group)
+	return req.WithContext(context.WithValue(req.Context(), principalKey{}, key.Group)), true
+}
+
+// principal returns the authenticated principal of a request, if any
+func principal(ctx context.Context) string {
+	id, _ := ctx.Value(principalKey{}).(string)
+	return id
+}
+
+// maskKey shows only the last characters of a rejected key, like OpenAI does
+func maskKey(secret string) string {
+	if len(secret) <= 8 {
+		return "****"
+	}
+	return secret[:3] + "****" + secret[len(secret)-4:]
+}
@@ -0,0 +1,175 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/keyring"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewAPIKeys_Sources(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`[{"id": "file-team", "secret": "sk-file"}]`), 0o600))
+	t.Setenv("GATEWAY_API_KEYS", "env-team:sk-env, other-team:sk-other")
+
+	k, err := newAPIKeys(&APIKeysConfig{
+		Keys: []keyring.KeyConfig{{ID: "static-team", Secret: "sk-static"}},
+		File: path,
+		Env:  "GATEWAY_API_KEYS",
+	})
+	assert.NoError(t, err)
+
+	for secret, id := range map[string]string{"sk-static": "static-team", "sk-file": "file-team", "sk-env": "env-team", "sk-other": "other-team"} {
+		key, ok := k.keys.Match([]byte(secret), "test")
+		assert.True(t, ok)
+		assert.Equal(t, id, key.ID)
+	}
+}
+
+func TestNewAPIKeys_InvalidConfig(t *testing.T) {
+	t.Setenv("MALFORMED_KEYS", "sk-without-id")
+
+	tests := []struct {
+		name string
+		cfg  APIKeysConfig
+	}{
+		{name: "no keys", cfg: APIKeysConfig{}},
+		{name: "missing file", cfg: APIKeysConfig{File: filepath.Join(t.TempDir(), "missing.json")}},
+		{name: "malformed env", cfg: APIKeysConfig{Env: "MALFORMED_KEYS"}},
+		{name: "duplicate id", cfg: APIKeysConfig{Keys: []keyring.KeyConfig{{ID: "a", Secret: "x"}, {ID: "a", Secret: "y"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newAPIKeys(&tt.cfg)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAPIKeys_Enforced(t *testing.T) {
+	mockHandler := &MockHandler{Response: []byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task"}}`), StatusCode: http.StatusOK}
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "agent", "url": "http://localhost:8001"},
+			},
+			"api_keys": map[string]interface{}{
+				"keys": []interface{}{map[string]interface{}{"id": "weather-team", "secret": "sk-weather-team-key"}},
+			},
+			"sandbox": map[string]interface{}{"key_prefix": "sk-sandbox-"},
+			"admin": map[string]interface{}{
+				"keys": []interface{}{map[string]interface{}{"id": "2026", "secret": "admin-token"}},
+			},
+			"stats": map[string]interface{}{},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+
+	invalidAPIKey := "invalid_api_key"
+	tests := []struct {
+		name       string
+		model      string
+		apiKey     string
+		wantStatus int
+		wantCode   *string
+	}{
+		{name: "valid key", model: "agent", apiKey: "sk-weather-team-key", wantStatus: http.StatusOK},
+		{name: "missing key", model: "agent", wantStatus: http.StatusUnauthorized},
+		{name: "invalid key", model: "agent", apiKey: "sk-guessed-key-1234", wantStatus: http.StatusUnauthorized, wantCode: &invalidAPIKey},
+		{name: "sandbox key", model: defaultSandboxModelID, apiKey: "sk-sandbox-dev", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := sendChatCompletion(handler, tt.model, tt.apiKey)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus == http.StatusUnauthorized {
+				var errResp models.OpenAIErrorResponse
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+				assert.Equal(t, "invalid_request_error", errResp.Error.Type)
+				assert.Equal(t, tt.wantCode, errResp.Error.Code)
+			}
+		})
+	}
+
+	// Authenticated requests are attributed to the key's principal
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, statsPath, nil)
+	req.Header.Set("Authorization", "Bearer admin-token")
+	handler.ServeHTTP(rec, req)
+	var report StatsReport
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, []PrincipalStats{{Principal: "weather-team", Requests: 1}}, report.TopPrincipals)
+}
+
+func TestAPIKeys_RotationGroups(t *testing.T) {
+	mockHandler := &MockHandler{Response: []byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task"}}`), StatusCode: http.StatusOK}
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "agent", "url": "http://localhost:8001"},
+			},
+			"api_keys": map[string]interface{}{
+				"keys": []interface{}{
+					map[string]interface{}{"id": "weather-2025", "secret": "sk-weather-old", "group": "weather-team"},
+					map[string]interface{}{"id": "weather-2026", "secret": "sk-weather-new", "group": "weather-team", "not_before": "2026-01-01T00:00:00Z"},
+					map[string]interface{}{"id": "travel-team", "secret": "sk-travel-team"},
+				},
+			},
+			"admin": map[string]interface{}{
+				"keys": []interface{}{map[string]interface{}{"id": "2026", "secret": "admin-token"}},
+			},
+			"stats": map[string]interface{}{},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, sendChatCompletion(handler, "agent", "sk-weather-old").Code)
+	assert.Equal(t, http.StatusOK, sendChatCompletion(handler, "agent", "sk-weather-old").Code)
+	assert.Equal(t, http.StatusOK, sendChatCompletion(handler, "agent", "sk-weather-new").Code)
+	assert.Equal(t, http.StatusOK, sendChatCompletion(handler, "agent", "sk-travel-team").Code)
+
+	// The keys of a group share its principal
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, statsPath, nil)
+	req.Header.Set("Authorization", "Bearer admin-token")
+	handler.ServeHTTP(rec, req)
+	var stats StatsReport
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &stats))
+	assert.Equal(t, []PrincipalStats{{Principal: "weather-team", Requests: 3}, {Principal: "travel-team", Requests: 1}}, stats.TopPrincipals)
+
+	// Only the old key of a group is stale, not the keys of other principals
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, adminKeyUsagePath, nil)
+	req.Header.Set("Authorization", "Bearer admin-token")
+	handler.ServeHTTP(rec, req)
+	var report struct {
+		Keyrings map[string][]keyring.Usage `json:"keyrings"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	usage := report.Keyrings["api_keys"]
+	assert.Len(t, usage, 3)
+	stale := map[string]bool{}
+	for _, u := range usage {
+		assert.Equal(t, "192.0.2.1", u.Client, "clients are identified by their host")
+		stale[u.KeyID] = u.Stale
+	}
+	assert.Equal(t, map[string]bool{"weather-2025": true, "weather-2026": false, "travel-team": false}, stale)
+	assert.Equal(t, int64(2), usage[1].Count)
+}
+
+func TestMaskKey(t *testing.T) {
+	assert.Equal(t, "sk-****1234", maskKey("sk-guessed-key-1234"))
+	assert.Equal(t, "****", maskKey("short"))
+}
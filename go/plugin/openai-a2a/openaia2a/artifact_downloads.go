@@ -0,0 +1,310 @@
+package openaia2a
+
+import (
+	"container/list"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aclient"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/go-http-utils/headers"
+)
+
+// artifactPart is a part of an artifact as returned by tasks/get. Exactly one of text, data or
+// file is set, depending on its kind.
+type artifactPart struct {
+	Kind string      `json:"kind"`
+	Text string      `json:"text"`
+	Data interface{} `json:"data"`
+	File *struct {
+		URI      string `json:"uri"`
+		Bytes    string `json:"bytes"`
+		Name     string `json:"name"`
+		MimeType string `json:"mimeType"`
+	} `json:"file"`
+}
+
+type taskArtifact struct {
+	ArtifactId string         `json:"artifactId"`
+	Name       string         `json:"name"`
+	Parts      []artifactPart `json:"parts"`
+}
+
+// artifactContent is the content of an artifact served to clients
+type artifactContent struct {
+	contentType string
+	name        string
+	body        []byte
+}
+
+// serveTaskArtifact fetches a task from the agent owning it and serves the content of one of its
+// artifacts: the first file part, or else the text parts or the first data part. Only callers
+// that may query the task get its artifacts.
+func (p *artifactProxy) serveTaskArtifact(w http.ResponseWriter, req *http.Request, handler http.Handler, agents *agentSet, tasks *taskStore, taskID, artifactID string) {
+	log := logging.FromRequest(logger, req)
+	model, ok := tasks.owner(req.Context(), taskID)
+	agent, known := agents.agent(model)
+	if !ok || !known {
+		http.Error(w, "artifact not found", http.StatusNotFound)
+		return
+	}
+
+	// Cached artifacts are kept per caller, so they are never served to anyone else
+	caller := tasks.caller(req.Context())
+	key := strings.Join([]string{caller.principal, caller.tenant, taskID, artifactID}, "\n")
+	if content, ok := p.cache.get(key); ok {
+		p.writeContent(w, req, content, true)
+		return
+	}
+
+	// Artifacts are read with tasks/get, whatever the method of the client request
+	rpcReq := req.Clone(req.Context())
+	rpcReq.Method = http.MethodPost
+	rpc := krakendRPC(rpcReq, handler, "/"+agent.ModelID)
+	if client := agents.grpc[agent.ModelID]; client != nil {
+		rpc = client.Transcode
+	}
+	var task struct {
+		Status struct {
+			State string `json:"state"`
+		} `json:"status"`
+		Artifacts []taskArtifact `json:"artifacts"`
+	}
+	err := a2aclient.NewWithTransport(rpc, a2aclient.Options{}).Call(req.Context(), "tasks/get", models.TaskQueryParams{Id: taskID}, &task)
+	var rpcErr *a2aclient.RPCError
+	if errors.As(err, &rpcErr) {
+		log.Info(fmt.Sprintf("tasks/get of task %s returned error %d: %s", taskID, rpcErr.Code, rpcErr.Message))
+		http.Error(w, "artifact not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("tasks/get of task %s failed: %v", taskID, err))
+		http.Error(w, "agent did not return the task", http.StatusBadGateway)
+		return
+	}
+	var artifact *taskArtifact
+	for i := range task.Artifacts {
+		if task.Artifacts[i].ArtifactId == artifactID {
+			artifact = &task.Artifacts[i]
+			break
+		}
+	}
+	if artifact == nil {
+		http.Error(w, "artifact not found", http.StatusNotFound)
+		return
+	}
+
+	// Artifacts of finished tasks no longer change, so they can be cached
+	cacheable := p.cache != nil && isTerminalState(task.Status.State)
+	content, uri, err := p.content(artifact)
+	if err != nil {
+		log.Warning(fmt.Sprintf("cannot serve artifact %s of task %s: %v", artifactID, taskID, err))
+		http.Error(w, "artifact is not available", http.StatusBadGateway)
+		return
+	}
+	if uri == "" {
+		if cacheable {
+			p.cache.put(key, content)
+		}
+		p.writeContent(w, req, content, cacheable)
+		return
+	}
+	if !cacheable || req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		p.proxy(w, req, uri)
+		return
+	}
+
+	// Cached files are read completely, without the conditions of the client; the size limit
+	// bounds the memory they take
+	fileReq := req.Clone(req.Context())
+	for _, name := range artifactRequestHeaders {
+		fileReq.Header.Del(name)
+	}
+	fileResp, cancel, ok := p.fetch(w, fileReq, uri)
+	if !ok {
+		return
+	}
+	defer cancel()
+	defer fileResp.Body.Close()
+	data, err := io.ReadAll(fileResp.Body)
+	if err != nil || fileResp.StatusCode != http.StatusOK {
+		log.Warning(fmt.Sprintf("cannot read artifact %s of task %s: status %d, %v", artifactID, taskID, fileResp.StatusCode, err))
+		http.Error(w, "artifact is not available", http.StatusBadGateway)
+		return
+	}
+	if content.contentType == "" {
+		content.contentType = fileResp.Header.Get(headers.ContentType)
+	}
+	content.body = data
+	p.cache.put(key, content)
+	p.writeContent(w, req, content, true)
+}
+
+// content returns the inline content of an artifact, or the URI of its file
+func (p *artifactProxy) content(artifact *taskArtifact) (artifactContent, string, error) {
+	var text strings.Builder
+	var data interface{}
+	for _, part := range artifact.Parts {
+		switch {
+		case part.Kind == "file" && part.File != nil:
+			content := artifactContent{contentType: part.File.MimeType, name: part.File.Name}
+			if content.name == "" {
+				content.name = artifact.Name
+			}
+			if part.File.URI != "" {
+				return content, part.File.URI, nil
+			}
+			if int64(base64.StdEncoding.DecodedLen(len(part.File.Bytes))) > p.maxSize+2 {
+				return content, "", fmt.Errorf("file exceeds %d bytes", p.maxSize)
+			}
+			body, err := base64.StdEncoding.DecodeString(part.File.Bytes)
+			if err != nil {
+				return content, "", fmt.Errorf("file bytes are not base64 encoded")
+			}
+			if content.contentType == "" {
+				content.contentType = "application/octet-stream"
+			}
+			content.body = body
+			return content, "", nil
+		case part.Kind == "text":
+			text.WriteString(part.Text)
+		case part.Kind == "data" && data == nil:
+			data = part.Data
+		}
+	}
+
+	if text.Len() > 0 {
+		return artifactContent{contentType: "text/plain; charset=utf-8", name: artifact.Name, body: []byte(text.String())}, "", nil
+	}
+	if data != nil {
+		body, err := json.Marshal(data)
+		return artifactContent{contentType: "application/json", name: artifact.Name, body: body}, "", err
+	}
+	return artifactContent{contentType: "text/plain; charset=utf-8", name: artifact.Name}, "", nil
+}
+
+// writeContent writes inline or cached artifact content. Cached content may be cached by clients
+// for the cache TTL, too.
+func (p *artifactProxy) writeContent(w http.ResponseWriter, req *http.Request, content artifactContent, cached bool) {
+	log := logging.FromRequest(logger, req)
+	if int64(len(content.body)) > p.maxSize {
+		http.Error(w, "artifact is not available", http.StatusBadGateway)
+		return
+	}
+	if content.contentType != "" {
+		w.Header().Set(headers.ContentType, content.contentType)
+	}
+	if content.name != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": content.name}))
+	}
+	if cached {
+		w.Header().Set(headers.CacheControl, fmt.Sprintf("private, max-age=%d", int(p.cache.ttl.Seconds())))
+	}
+	w.Header().Set(headers.ContentLength, strconv.Itoa(len(content.body)))
+	w.WriteHeader(http.StatusOK)
+	if req.Method == http.MethodHead {
+		return
+	}
+	if _, err := w.Write(content.body); err != nil {
+		log.Error("failed to write response:", err)
+	}
+}
+
+// isTerminalState reports whether a task has finished
+func isTerminalState(state string) bool {
+	switch state {
+	case "completed", "canceled", "failed", "rejected":
+		return true
+	}
+	return false
+}
+
+type cachedArtifact struct {
+	key     string
+	content artifactContent
+	expires time.Time
+}
+
+// artifactCache keeps the content of artifacts of finished tasks in memory for a TTL. The
+// content of all entries is limited to maxSize bytes; beyond it, the least recently used
+// entries are evicted.
+type artifactCache struct {
+	ttl     time.Duration
+	maxSize int64
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// recent orders the entries from the most to the least recently used
+	recent    *list.List
+	size      int64
+	nextPurge time.Time
+	now       func() time.Time
+}
+
+func newArtifactCache(ttl time.Duration, maxSize int64) *artifactCache {
+	return &artifactCache{ttl: ttl, maxSize: maxSize, entries: make(map[string]*list.Element), recent: list.New(), now: time.Now}
+}
+
+func (c *artifactCache) get(key string) (artifactContent, bool) {
+	if c == nil {
+		return artifactContent{}, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if !ok {
+		return artifactContent{}, false
+	}
+	entry := element.Value.(*cachedArtifact)
+	if c.now().After(entry.expires) {
+		c.remove(element)
+		return artifactContent{}, false
+	}
+	c.recent.MoveToFront(element)
+	return entry.content, true
+}
+
+// put caches content unless it exceeds maxSize on its own, evicting the least recently used
+// entries to make room. Expired entries are purged at most once per minute.
+func (c *artifactCache) put(key string, content artifactContent) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if now.After(c.nextPurge) {
+		for _, element := range c.entries {
+			if now.After(element.Value.(*cachedArtifact).expires) {
+				c.remove(element)
+			}
+		}
+		c.nextPurge = now.Add(time.Minute)
+	}
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	size := int64(len(content.body))
+	if size > c.maxSize {
+		return
+	}
+	for c.size+size > c.maxSize {
+		c.remove(c.recent.Back())
+	}
+	c.entries[key] = c.recent.PushFront(&cachedArtifact{key: key, content: content, expires: now.Add(c.ttl)})
+	c.size += size
+}
+
+// remove drops an entry. The caller holds mu.
+func (c *artifactCache) remove(element *list.Element) {
+	entry := c.recent.Remove(element).(*cachedArtifact)
+	delete(c.entries, entry.key)
+	c.size -= int64(len(entry.content.body))
+}
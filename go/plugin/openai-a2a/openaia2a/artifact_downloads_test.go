@@ -0,0 +1,203 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newDownloadHandler(t *testing.T, fileURI string, state string, taskGets *int32) http.Handler {
+	task := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task","id":"task-7","status":{"state":%q},"artifacts":[
+		{"artifactId":"summary","name":"summary.txt","parts":[{"kind":"text","text":"Sunny, "},{"kind":"text","text":"25°C"}]},
+		{"artifactId":"chart","parts":[{"kind":"file","file":{"bytes":%q,"mimeType":"image/png","name":"chart.png"}}]},
+		{"artifactId":"report","parts":[{"kind":"file","file":{"uri":%q,"name":"report.pdf"}}]},
+		{"artifactId":"stats","parts":[{"kind":"data","data":{"max":25}}]}
+	]}}`, state, base64.StdEncoding.EncodeToString([]byte("PNG")), fileURI)
+	agent := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "tasks/get") {
+			atomic.AddInt32(taskGets, 1)
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Contains(t, string(body), `"id":"task-7"`)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(task))
+	})
+
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents":     []interface{}{map[string]interface{}{"model_id": "weather-agent", "url": "http://localhost:8001"}},
+			"task_store": map[string]interface{}{},
+			"artifacts":  map[string]interface{}{"secret": "test-secret", "cache_ttl": "10m", "max_size": 1024},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, agent)
+	assert.NoError(t, err)
+
+	// The gateway learns the owner of the task from the agent response
+	postA2A(handler, "/weather-agent", `{"jsonrpc":"2.0","id":1,"method":"message/send","params":{}}`, nil)
+	return handler
+}
+
+func download(handler http.Handler, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func TestArtifacts_DownloadsTaskArtifacts(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write([]byte("%PDF-1.7"))
+	}))
+	defer origin.Close()
+	var taskGets int32
+	handler := newDownloadHandler(t, origin.URL+"/report.pdf", "working", &taskGets)
+
+	tests := []struct {
+		artifact    string
+		contentType string
+		disposition string
+		body        string
+	}{
+		{"summary", "text/plain; charset=utf-8", `inline; filename=summary.txt`, "Sunny, 25°C"},
+		{"chart", "image/png", `inline; filename=chart.png`, "PNG"},
+		{"report", "application/pdf", "", "%PDF-1.7"},
+		{"stats", "application/json", "", `{"max":25}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.artifact, func(t *testing.T) {
+			rec := download(handler, "/artifacts/task-7/"+tt.artifact)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.contentType, rec.Header().Get("Content-Type"))
+			assert.Equal(t, tt.disposition, rec.Header().Get("Content-Disposition"))
+			assert.Empty(t, rec.Header().Get("Cache-Control"))
+			assert.Equal(t, tt.body, rec.Body.String())
+		})
+	}
+	assert.Equal(t, int32(4), atomic.LoadInt32(&taskGets))
+
+	assert.Equal(t, http.StatusNotFound, download(handler, "/artifacts/task-7/missing").Code)
+	assert.Equal(t, http.StatusNotFound, download(handler, "/artifacts/task-unknown/summary").Code)
+}
+
+func TestArtifacts_CachesArtifactsOfFinishedTasks(t *testing.T) {
+	var fetches int32
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write([]byte("%PDF-1.7"))
+	}))
+	defer origin.Close()
+	var taskGets int32
+	handler := newDownloadHandler(t, origin.URL+"/report.pdf", "completed", &taskGets)
+
+	for i := 0; i < 3; i++ {
+		rec := download(handler, "/artifacts/task-7/report")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "%PDF-1.7", rec.Body.String())
+		assert.Equal(t, "private, max-age=600", rec.Header().Get("Cache-Control"))
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&taskGets))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&fetches))
+}
+
+func TestArtifacts_OnlyServesTheTaskCreator(t *testing.T) {
+	var taskGets int32
+	agent := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), "tasks/get") {
+			atomic.AddInt32(&taskGets, 1)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task","id":"task-7","status":{"state":"completed"},
+			"artifacts":[{"artifactId":"summary","parts":[{"kind":"text","text":"Sunny"}]}]}}`))
+	})
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{map[string]interface{}{"model_id": "weather-agent", "url": "http://localhost:8001"}},
+			"api_keys": map[string]interface{}{"keys": []interface{}{
+				map[string]interface{}{"id": "alice", "secret": "sk-alice-key"},
+				map[string]interface{}{"id": "bob", "secret": "sk-bob-key"},
+			}},
+			"task_store": map[string]interface{}{},
+			"artifacts":  map[string]interface{}{"secret": "test-secret", "cache_ttl": "10m"},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, agent)
+	assert.NoError(t, err)
+	postA2A(handler, "/weather-agent", `{"jsonrpc":"2.0","id":1,"method":"message/send","params":{}}`, http.Header{"Authorization": {"Bearer sk-alice-key"}})
+	downloadAs := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/artifacts/task-7/summary", nil)
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := downloadAs("sk-alice-key")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "Sunny", rec.Body.String())
+
+	// The cached artifact is not served to other callers
+	assert.Equal(t, http.StatusUnauthorized, downloadAs("").Code)
+	assert.Equal(t, http.StatusNotFound, downloadAs("sk-bob-key").Code)
+	assert.Equal(t, http.StatusOK, downloadAs("sk-alice-key").Code)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&taskGets))
+}
+
+func TestArtifacts_EnforcesSizeLimit(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(make([]byte, 2048))
+	}))
+	defer origin.Close()
+	var taskGets int32
+	handler := newDownloadHandler(t, origin.URL+"/large.bin", "completed", &taskGets)
+
+	rec := download(handler, "/artifacts/task-7/report")
+
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+}
+
+func TestArtifactCache_EvictsLeastRecentlyUsedBeyondMaxSize(t *testing.T) {
+	cache := newArtifactCache(time.Minute, 10)
+	content := func(size int) artifactContent { return artifactContent{body: make([]byte, size)} }
+
+	cache.put("a", content(4))
+	cache.put("b", content(4))
+	_, ok := cache.get("a")
+	assert.True(t, ok)
+
+	// Making room for c evicts b, the least recently used entry
+	cache.put("c", content(4))
+	_, ok = cache.get("b")
+	assert.False(t, ok)
+	_, ok = cache.get("a")
+	assert.True(t, ok)
+	_, ok = cache.get("c")
+	assert.True(t, ok)
+	assert.Equal(t, int64(8), cache.size)
+
+	// Content larger than the cache is not cached and evicts nothing
+	cache.put("d", content(11))
+	_, ok = cache.get("d")
+	assert.False(t, ok)
+	assert.Equal(t, int64(8), cache.size)
+
+	// Replacing an entry counts its new size only
+	cache.put("a", content(6))
+	assert.Equal(t, int64(10), cache.size)
+	assert.Len(t, cache.entries, 2)
+}
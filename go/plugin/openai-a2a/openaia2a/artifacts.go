@@ -0,0 +1,488 @@
+package openaia2a
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/capture"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/netclass"
+	"github.com/go-http-utils/headers"
+)
+
+const (
+	defaultArtifactPath    = "/artifacts"
+	defaultArtifactTTL     = time.Hour
+	defaultArtifactTimeout = 60 * time.Second
+	defaultArtifactMaxSize = 64 << 20
+	// defaultArtifactCacheSize bounds the memory held by cached artifacts
+	defaultArtifactCacheSize = 256 << 20
+)
+
+// artifactRequestHeaders are forwarded to the origin of an artifact, artifactResponseHeaders back to the client
+var (
+	artifactRequestHeaders  = []string{"Range", "If-None-Match", "If-Modified-Since", "If-Range"}
+	artifactResponseHeaders = []string{"Content-Type", "Content-Length", "Content-Disposition", "Content-Range",
+		"Accept-Ranges", "ETag", "Last-Modified", "Cache-Control"}
+)
+
+// ArtifactConfig routes the URIs of file parts in agent responses through the gateway. URIs with
+// an internal host are replaced by signed links below Path, which the gateway proxies to the
+// original URI until they expire. With a task store, artifacts can also be downloaded from
+// Path/{taskId}/{artifactId}; CacheTTL keeps those of finished tasks in memory, up to
+// CacheMaxSize bytes in total.
+type ArtifactConfig struct {
+	Path          string   `json:"path,omitempty"`
+	BaseURL       string   `json:"base_url,omitempty"`
+	Secret        string   `json:"secret,omitempty"`
+	SecretEnv     string   `json:"secret_env,omitempty"`
+	TTL           string   `json:"ttl,omitempty"`
+	Timeout       string   `json:"timeout,omitempty"`
+	InternalHosts []string `json:"internal_hosts,omitempty"`
+	MaxSize       int64    `json:"max_size,omitempty"`
+	CacheTTL      string   `json:"cache_ttl,omitempty"`
+	CacheMaxSize  int64    `json:"cache_max_size,omitempty"`
+}
+
+// artifactProxy rewrites file URIs to signed gateway links and serves them
+type artifactProxy struct {
+	path     string
+	baseURL  string
+	secret   []byte
+	ttl      time.Duration
+	timeout  time.Duration
+	maxSize  int64
+	cache    *artifactCache
+	internal *netclass.Classifier
+	now      func() time.Time
+}
+
+// newArtifactProxy validates the configuration. A nil config leaves file URIs unchanged.
+func newArtifactProxy(cfg *ArtifactConfig) (*artifactProxy, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	p := &artifactProxy{
+		path:    defaultArtifactPath,
+		ttl:     defaultArtifactTTL,
+		timeout: defaultArtifactTimeout,
+		maxSize: defaultArtifactMaxSize,
+		now:     time.Now,
+	}
+
+	var err error
+	if cfg.Path != "" {
+		if !strings.HasPrefix(cfg.Path, "/") || cfg.Path == "/" {
+			return nil, fmt.Errorf("path '%s' must start with / and not be the root", cfg.Path)
+		}
+		p.path = strings.TrimSuffix(cfg.Path, "/")
+	}
+	if cfg.BaseURL != "" {
+		if parsed, err := url.Parse(cfg.BaseURL); err != nil || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid base_url '%s'", cfg.BaseURL)
+		}
+		p.baseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	}
+	if cfg.TTL != "" {
+		if p.ttl, err = time.ParseDuration(cfg.TTL); err != nil || p.ttl <= 0 {
+			return nil, fmt.Errorf("invalid ttl '%s'", cfg.TTL)
+		}
+	}
+	if cfg.Timeout != "" {
+		if p.timeout, err = time.ParseDuration(cfg.Timeout); err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+	}
+	if cfg.MaxSize < 0 {
+		return nil, fmt.Errorf("max_size %d is negative", cfg.MaxSize)
+	}
+	if cfg.MaxSize > 0 {
+		p.maxSize = cfg.MaxSize
+	}
+	if cfg.CacheTTL != "" {
+		ttl, err := time.ParseDuration(cfg.CacheTTL)
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("invalid cache_ttl '%s'", cfg.CacheTTL)
+		}
+		cacheSize := int64(defaultArtifactCacheSize)
+		if cfg.CacheMaxSize < 0 {
+			return nil, fmt.Errorf("cache_max_size %d is negative", cfg.CacheMaxSize)
+		}
+		if cfg.CacheMaxSize > 0 {
+			cacheSize = cfg.CacheMaxSize
+		}
+		p.cache = newArtifactCache(ttl, cacheSize)
+	}
+
+	secret := cfg.Secret
+	if cfg.SecretEnv != "" {
+		if secret = os.Getenv(cfg.SecretEnv); secret == "" {
+			return nil, fmt.Errorf("environment variable %s is not set", cfg.SecretEnv)
+		}
+	}
+	if secret == "" {
+		// Links signed with a random key only work on this replica until it restarts
+		logger.Warning("no artifact secret configured, artifact links are only valid on this gateway instance")
+		p.secret = make([]byte, 32)
+		if _, err := rand.Read(p.secret); err != nil {
+			return nil, err
+		}
+	} else {
+		p.secret = []byte(secret)
+	}
+
+	if p.internal, err = netclass.New(cfg.InternalHosts); err != nil {
+		return nil, fmt.Errorf("invalid internal_hosts: %w", err)
+	}
+	return p, nil
+}
+
+// artifactLink is the signed payload of a gateway link
+type artifactLink struct {
+	URI     string `json:"u"`
+	Expires int64  `json:"x"`
+}
+
+// link returns the gateway link of an internal URI. The file name, if known, is appended so
+// clients save downloads under it.
+func (p *artifactProxy) link(baseURL, uri, name string) (string, error) {
+	payload, err := json.Marshal(artifactLink{URI: uri, Expires: p.now().Add(p.ttl).Unix()})
+	if err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(payload)
+	token += "." + base64.RawURLEncoding.EncodeToString(p.sign(token))
+	link := baseURL + p.path + "/" + token
+	if name != "" {
+		link += "/" + url.PathEscape(name)
+	}
+	return link, nil
+}
+
+func (p *artifactProxy) sign(token string) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(token))
+	return mac.Sum(nil)
+}
+
+// resolve verifies the token of a gateway link and returns the original URI
+func (p *artifactProxy) resolve(token string) (string, error) {
+	payload, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return "", fmt.Errorf("malformed artifact link")
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, p.sign(payload)) {
+		return "", fmt.Errorf("invalid artifact link signature")
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", fmt.Errorf("malformed artifact link")
+	}
+	var link artifactLink
+	if err := json.Unmarshal(raw, &link); err != nil {
+		return "", fmt.Errorf("malformed artifact link")
+	}
+	if p.now().Unix() > link.Expires {
+		return "", fmt.Errorf("artifact link expired")
+	}
+	return link.URI, nil
+}
+
+// rewrite replaces the internal URIs of all file parts in a JSON-RPC response or SSE event by
+// gateway links. The body is returned unchanged if it has none.
+func (p *artifactProxy) rewrite(body []byte, baseURL string) ([]byte, bool) {
+	if p == nil || !bytes.Contains(body, []byte(`"file"`)) {
+		return body, false
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return body, false
+	}
+	if !p.rewriteParts(value, baseURL) {
+		return body, false
+	}
+	rewritten, err := json.Marshal(value)
+	if err != nil {
+		logger.Error("failed to marshal response with artifact links:", err)
+		return body, false
+	}
+	return rewritten, true
+}
+
+// rewriteParts walks a decoded JSON value for file parts, i.e. objects of kind file, wherever
+// they appear: in messages, artifacts, task history and status updates
+func (p *artifactProxy) rewriteParts(value interface{}, baseURL string) bool {
+	rewritten := false
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if kind, _ := v["kind"].(string); kind == "file" {
+			if file, ok := v["file"].(map[string]interface{}); ok {
+				rewritten = p.rewriteFile(file, baseURL)
+			}
+		}
+		for _, child := range v {
+			rewritten = p.rewriteParts(child, baseURL) || rewritten
+		}
+	case []interface{}:
+		for _, child := range v {
+			rewritten = p.rewriteParts(child, baseURL) || rewritten
+		}
+	}
+	return rewritten
+}
+
+func (p *artifactProxy) rewriteFile(file map[string]interface{}, baseURL string) bool {
+	uri, _ := file["uri"].(string)
+	parsed, err := url.Parse(uri)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || !p.internal.IsPrivateHost(parsed.Hostname()) {
+		return false
+	}
+	name, _ := file["name"].(string)
+	link, err := p.link(baseURL, uri, name)
+	if err != nil {
+		logger.Error("failed to create artifact link:", err)
+		return false
+	}
+	file["uri"] = link
+	return true
+}
+
+// gatewayURL returns the base URL of artifact links: the configured one or the URL of the request
+func (p *artifactProxy) gatewayURL(req *http.Request) string {
+	if p.baseURL != "" {
+		return p.baseURL
+	}
+	return requestBaseURL(req)
+}
+
+// handles reports whether the request fetches an artifact through a gateway link
+func (p *artifactProxy) handles(req *http.Request) bool {
+	return p != nil && (req.Method == http.MethodGet || req.Method == http.MethodHead) &&
+		strings.HasPrefix(req.URL.Path, p.path+"/")
+}
+
+// serveHTTP serves a signed link or, with a task store, an artifact by task and artifact ID
+func (p *artifactProxy) serveHTTP(w http.ResponseWriter, req *http.Request, handler http.Handler, agents *agentSet, tasks *taskStore) {
+	log := logging.FromRequest(logger, req)
+	first, rest, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, p.path+"/"), "/")
+	uri, err := p.resolve(first)
+	if err == nil {
+		p.proxy(w, req, uri)
+		return
+	}
+	if rest != "" && !strings.Contains(rest, "/") && tasks != nil {
+		p.serveTaskArtifact(w, req, handler, agents, tasks, first, rest)
+		return
+	}
+	log.Debug(fmt.Sprintf("rejected artifact request: %v", err))
+	http.Error(w, "artifact not found", http.StatusNotFound)
+}
+
+// proxy streams a file from its original URI
+func (p *artifactProxy) proxy(w http.ResponseWriter, req *http.Request, uri string) {
+	log := logging.FromRequest(logger, req)
+	resp, cancel, ok := p.fetch(w, req, uri)
+	if !ok {
+		return
+	}
+	defer cancel()
+	defer resp.Body.Close()
+
+	for _, name := range artifactResponseHeaders {
+		if values := resp.Header.Values(name); len(values) > 0 {
+			w.Header()[name] = values
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		log.Warning(fmt.Sprintf("artifact %s was not fully sent: %v", uri, err))
+	}
+}
+
+// fetch requests a file from its original URI with the range and conditional headers of req.
+// Failures are answered on w; otherwise the caller must close the response and call cancel.
+func (p *artifactProxy) fetch(w http.ResponseWriter, req *http.Request, uri string) (*http.Response, context.CancelFunc, bool) {
+	log := logging.FromRequest(logger, req)
+	ctx, cancel := context.WithTimeout(req.Context(), p.timeout)
+	originReq, err := http.NewRequestWithContext(ctx, req.Method, uri, nil)
+	if err != nil {
+		cancel()
+		http.Error(w, "artifact not found", http.StatusNotFound)
+		return nil, nil, false
+	}
+	for _, name := range artifactRequestHeaders {
+		if value := req.Header.Get(name); value != "" {
+			originReq.Header.Set(name, value)
+		}
+	}
+	resp, err := upstreamClient.Do(originReq)
+	if err != nil {
+		cancel()
+		log.Error(fmt.Sprintf("cannot fetch artifact %s: %v", uri, err))
+		http.Error(w, "artifact is not available", http.StatusBadGateway)
+		return nil, nil, false
+	}
+
+	failure := ""
+	switch {
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		resp.Body.Close()
+		cancel()
+		http.Error(w, "artifact not found", http.StatusNotFound)
+		return nil, nil, false
+	case resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotModified && resp.StatusCode != http.StatusRequestedRangeNotSatisfiable:
+		failure = fmt.Sprintf("returned status %d", resp.StatusCode)
+	case resp.ContentLength > p.maxSize:
+		failure = fmt.Sprintf("has %d bytes, more than the limit of %d", resp.ContentLength, p.maxSize)
+	}
+	if failure != "" {
+		resp.Body.Close()
+		cancel()
+		log.Warning(fmt.Sprintf("artifact %s %s", uri, failure))
+		http.Error(w, "artifact is not available", http.StatusBadGateway)
+		return nil, nil, false
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: p.maxSize}
+	return resp, cancel, true
+}
+
+// limitedBody fails reading a response body of unknown length beyond the size limit
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(buf []byte) (int, error) {
+	n, err := b.ReadCloser.Read(buf)
+	if b.remaining -= int64(n); b.remaining < 0 {
+		return n, fmt.Errorf("artifact exceeds the size limit")
+	}
+	return n, err
+}
+
+// writer wraps w to rewrite the file URIs in an agent response. JSON responses are buffered up to
+// the maximum response size, streamed responses are rewritten event by event.
+func (p *artifactProxy) writer(w http.ResponseWriter, req *http.Request) *artifactWriter {
+	return &artifactWriter{ResponseWriter: w, proxy: p, baseURL: p.gatewayURL(req), statusCode: http.StatusOK}
+}
+
+// artifactWriter rewrites file URIs in the response written to it. done must be called once the
+// handler returned.
+type artifactWriter struct {
+	http.ResponseWriter
+	proxy      *artifactProxy
+	baseURL    string
+	statusCode int
+	started    bool
+
+	// buffered captures non-streamed responses, stream is set for server-sent events
+	buffered *capture.Writer
+	stream   bool
+	line     bytes.Buffer
+}
+
+// start decides how the response is rewritten, once its headers are complete
+func (w *artifactWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+	if strings.HasPrefix(w.Header().Get(headers.ContentType), "text/event-stream") {
+		w.stream = true
+		w.ResponseWriter.WriteHeader(w.statusCode)
+		return
+	}
+	w.buffered = capture.New(w.ResponseWriter, maxResponseSize)
+	w.buffered.WriteHeader(w.statusCode)
+}
+
+func (w *artifactWriter) WriteHeader(statusCode int) {
+	if !w.started {
+		w.statusCode = statusCode
+		w.start()
+	}
+}
+
+func (w *artifactWriter) Write(b []byte) (int, error) {
+	w.start()
+	if !w.stream {
+		return w.buffered.Write(b)
+	}
+	w.line.Write(b)
+	for {
+		i := bytes.IndexByte(w.line.Bytes(), '\n')
+		if i < 0 {
+			return len(b), nil
+		}
+		if _, err := w.ResponseWriter.Write(w.rewriteLine(w.line.Next(i + 1))); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// rewriteLine rewrites the JSON data of an SSE data line
+func (w *artifactWriter) rewriteLine(line []byte) []byte {
+	data, ok := bytes.CutPrefix(line, []byte("data:"))
+	if !ok {
+		return line
+	}
+	rewritten, ok := w.proxy.rewrite(bytes.TrimSpace(data), w.baseURL)
+	if !ok {
+		return line
+	}
+	return append(append([]byte("data: "), rewritten...), '\n')
+}
+
+// Flush passes flushes of streamed responses on
+func (w *artifactWriter) Flush() {
+	switch {
+	case w.stream:
+		_ = http.NewResponseController(w.ResponseWriter).Flush()
+	case w.buffered != nil:
+		w.buffered.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the wrapped writer
+func (w *artifactWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// done writes the rewritten buffered response or the rest of a stream
+func (w *artifactWriter) done() {
+	switch {
+	case w.stream:
+		if w.line.Len() > 0 {
+			_, _ = w.ResponseWriter.Write(w.rewriteLine(w.line.Bytes()))
+		}
+		return
+	case w.buffered == nil:
+		return
+	}
+	defer w.buffered.Release()
+	if w.buffered.Passthrough() {
+		return
+	}
+
+	body, rewritten := w.proxy.rewrite(w.buffered.Body(), w.baseURL)
+	if rewritten {
+		w.Header().Del(headers.ContentLength)
+	}
+	w.ResponseWriter.WriteHeader(w.buffered.StatusCode())
+	if _, err := w.ResponseWriter.Write(body); err != nil {
+		logger.Error("failed to write response:", err)
+	}
+}
@@ -0,0 +1,169 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newArtifactHandler(t *testing.T, agent http.Handler) http.Handler {
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "file-agent", "url": "http://localhost:8001"},
+			},
+			"artifacts": map[string]interface{}{"secret": "test-secret", "internal_hosts": []interface{}{"minio"}},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, agent)
+	assert.NoError(t, err)
+	return handler
+}
+
+func fileTask(uri string) string {
+	return fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task","id":"task-1","status":{"state":"completed"},
+		"artifacts":[{"artifactId":"a1","parts":[{"kind":"file","file":{"uri":%q,"name":"report 1.pdf","mimeType":"application/pdf"}}]}]}}`, uri)
+}
+
+func fileURI(t *testing.T, body []byte) string {
+	var resp struct {
+		Result struct {
+			Artifacts []struct {
+				Parts []struct {
+					File struct {
+						URI string `json:"uri"`
+					} `json:"file"`
+				} `json:"parts"`
+			} `json:"artifacts"`
+		} `json:"result"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &resp))
+	return resp.Result.Artifacts[0].Parts[0].File.URI
+}
+
+func TestNewArtifactProxy_InvalidConfig(t *testing.T) {
+	for _, cfg := range []ArtifactConfig{
+		{Path: "artifacts"}, {Path: "/"}, {BaseURL: "gateway"}, {TTL: "0s"}, {Timeout: "soon"},
+		{SecretEnv: "ARTIFACT_SECRET_THAT_IS_NOT_SET"}, {InternalHosts: []string{"10.0.0.0/33"}},
+		{CacheTTL: "10m", CacheMaxSize: -1},
+	} {
+		_, err := newArtifactProxy(&cfg)
+		assert.Error(t, err, cfg)
+	}
+}
+
+func TestArtifactProxy_Internal(t *testing.T) {
+	p, err := newArtifactProxy(&ArtifactConfig{Secret: "s", InternalHosts: []string{"minio", "*.corp.example", "100.64.0.0/10"}})
+	assert.NoError(t, err)
+
+	for host, want := range map[string]bool{
+		"localhost":                      true,
+		"files.agents.svc.cluster.local": true,
+		"host.docker.internal":           true,
+		"10.0.0.5":                       true,
+		"169.254.1.1":                    true,
+		"::1":                            true,
+		"minio":                          true,
+		"s3.corp.example":                true,
+		"100.64.0.1":                     true,
+		"storage.googleapis.com":         false,
+		"8.8.8.8":                        false,
+		"minio.example.com":              false,
+	} {
+		assert.Equal(t, want, p.internal.IsPrivateHost(host), host)
+	}
+}
+
+func TestArtifacts_RewritesAndProxiesFileURIs(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/bucket/report.pdf", r.URL.Path)
+		assert.Equal(t, "bytes=0-3", r.Header.Get("Range"))
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("X-Internal", "secret")
+		w.WriteHeader(http.StatusPartialContent)
+		_, _ = w.Write([]byte("%PDF"))
+	}))
+	defer origin.Close()
+
+	agent := &MockHandler{Response: []byte(fileTask(origin.URL + "/bucket/report.pdf"))}
+	handler := newArtifactHandler(t, agent)
+
+	req := httptest.NewRequest(http.MethodPost, "/file-agent", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"message/send","params":{}}`))
+	req.Host = "gateway.example.com"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	link := fileURI(t, rec.Body.Bytes())
+	assert.True(t, strings.HasPrefix(link, "http://gateway.example.com/artifacts/"), link)
+	assert.True(t, strings.HasSuffix(link, "/report%201.pdf"), link)
+
+	req = httptest.NewRequest(http.MethodGet, strings.TrimPrefix(link, "http://gateway.example.com"), nil)
+	req.Header.Set("Range", "bytes=0-3")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusPartialContent, rec.Code)
+	assert.Equal(t, "application/pdf", rec.Header().Get("Content-Type"))
+	assert.Empty(t, rec.Header().Get("X-Internal"))
+	assert.Equal(t, "%PDF", rec.Body.String())
+}
+
+func TestArtifacts_KeepsPublicURIs(t *testing.T) {
+	body := fileTask("https://storage.example.com/report.pdf")
+	handler := newArtifactHandler(t, &MockHandler{Response: []byte(body)})
+
+	rec := postA2A(handler, "/file-agent", `{"jsonrpc":"2.0","id":1,"method":"message/send","params":{}}`, nil)
+
+	assert.Equal(t, body, rec.Body.String())
+}
+
+func TestArtifacts_RewritesStreamedEvents(t *testing.T) {
+	agent := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte("data: {\"result\":{\"kind\":\"artifact-update\",\"artifact\":{\"parts\":[{\"kind\":\"file\",\"file\":{\"uri\":\"http://min"))
+		_, _ = w.Write([]byte("io:9000/a.png\"}}]}}}\n\ndata: {\"result\":{\"kind\":\"status-update\"}}\n\n"))
+	})
+	handler := newArtifactHandler(t, agent)
+
+	rec := postA2A(handler, "/file-agent", `{"jsonrpc":"2.0","id":1,"method":"message/stream","params":{}}`, nil)
+
+	lines := strings.Split(rec.Body.String(), "\n")
+	assert.Len(t, lines, 5)
+	assert.True(t, strings.HasPrefix(lines[0], `data: {"result":{"artifact":{"parts":[{"file":{"uri":"http://example.com/artifacts/`), lines[0])
+	assert.Equal(t, `data: {"result":{"kind":"status-update"}}`, lines[2])
+}
+
+func TestArtifactProxy_RejectsInvalidLinks(t *testing.T) {
+	p, err := newArtifactProxy(&ArtifactConfig{Secret: "s", TTL: "1m"})
+	assert.NoError(t, err)
+	link, err := p.link("https://gateway", "http://minio/a", "")
+	assert.NoError(t, err)
+	token := strings.TrimPrefix(link, "https://gateway/artifacts/")
+
+	uri, err := p.resolve(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://minio/a", uri)
+
+	other, _ := newArtifactProxy(&ArtifactConfig{Secret: "other"})
+	_, err = other.resolve(token)
+	assert.Error(t, err)
+
+	_, err = p.resolve("not-a-token")
+	assert.Error(t, err)
+
+	p.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	_, err = p.resolve(token)
+	assert.Error(t, err)
+
+	rec := httptest.NewRecorder()
+	p.serveHTTP(rec, httptest.NewRequest(http.MethodGet, "/artifacts/"+token, nil), http.NotFoundHandler(), nil, nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,283 @@
+package openaia2a
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aclient"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+)
+
+// Load balancing strategy constants
+const (
+	balanceRoundRobin     = "round_robin"
+	balanceWeightedRandom = "weighted_random"
+)
+
+// defaultUnhealthyCooldown is how long a failed replica is skipped when no cooldown is configured
+const defaultUnhealthyCooldown = 30 * time.Second
+
+// Replica is a single agent URL taking part in load balancing
+type Replica struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// LoadBalancingConfig spreads a model's traffic across several agent replicas
+type LoadBalancingConfig struct {
+	Replicas          []Replica `json:"replicas"`
+	Strategy          string    `json:"strategy"`
+	UnhealthyCooldown string    `json:"unhealthy_cooldown"`
+	Sticky            bool      `json:"sticky"`
+}
+
+type replicaState struct {
+	url            string
+	weight         int
+	current        int
+	unhealthyUntil time.Time
+}
+
+// replicaBalancer picks replicas for a model and tracks their passive health.
+// Replicas that fail are skipped until their cooldown expires; if every replica
+// is unhealthy, all of them are considered again rather than failing the request.
+type replicaBalancer struct {
+	mu       sync.Mutex
+	model    string
+	strategy string
+	sticky   bool
+	cooldown time.Duration
+	replicas []*replicaState
+	rand     *rand.Rand
+	now      func() time.Time
+}
+
+// newReplicaBalancer validates a load balancing configuration and creates its balancer.
+// Weights default to 1 and an empty strategy defaults to round-robin.
+func newReplicaBalancer(cfg LoadBalancingConfig) (*replicaBalancer, error) {
+	if len(cfg.Replicas) == 0 {
+		return nil, errors.New("no replicas configured")
+	}
+
+	strategy := strings.ToLower(cfg.Strategy)
+	if strategy == "" {
+		strategy = balanceRoundRobin
+	}
+	if strategy != balanceRoundRobin && strategy != balanceWeightedRandom {
+		return nil, fmt.Errorf("unknown strategy '%s'", cfg.Strategy)
+	}
+
+	cooldown := defaultUnhealthyCooldown
+	if cfg.UnhealthyCooldown != "" {
+		d, err := time.ParseDuration(cfg.UnhealthyCooldown)
+		if err != nil {
+			return nil, fmt.Errorf("invalid unhealthy_cooldown: %w", err)
+		}
+		cooldown = d
+	}
+
+	b := &replicaBalancer{
+		strategy: strategy,
+		sticky:   cfg.Sticky,
+		cooldown: cooldown,
+		rand:     rand.New(rand.NewSource(time.Now().UnixNano())),
+		now:      time.Now,
+	}
+	for _, replica := range cfg.Replicas {
+		if parsed, err := url.Parse(replica.URL); err != nil || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid replica URL '%s'", replica.URL)
+		}
+		if replica.Weight < 0 {
+			return nil, fmt.Errorf("negative weight for replica '%s'", replica.URL)
+		}
+		weight := replica.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		b.replicas = append(b.replicas, &replicaState{url: replica.URL, weight: weight})
+	}
+	return b, nil
+}
+
+// newBalancers creates a balancer for every agent with load balancing configured, keyed by model ID
+func newBalancers(agents []AgentInfo) (map[string]*replicaBalancer, error) {
+	balancers := make(map[string]*replicaBalancer)
+	for _, agent := range agents {
+		if agent.LoadBalancing == nil {
+			continue
+		}
+		b, err := newReplicaBalancer(*agent.LoadBalancing)
+		if err != nil {
+			return nil, fmt.Errorf("agent %s: %w", agent.ModelID, err)
+		}
+		b.model = agent.ModelID
+		balancers[agent.ModelID] = b
+	}
+	return balancers, nil
+}
+
+// pick selects the next replica URL, ignoring replicas listed in tried.
+// For sticky balancers, a non-empty affinity key (the conversation ID) always maps
+// to the same replica as long as that replica is healthy.
+// Returns false once every replica has been tried.
+func (b *replicaBalancer) pick(tried map[string]bool, affinityKey string) (string, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	var candidates []*replicaState
+	for _, r := range b.replicas {
+		if !tried[r.url] && !now.Before(r.unhealthyUntil) {
+			candidates = append(candidates, r)
+		}
+	}
+	if len(candidates) == 0 {
+		// Fail open: retry unhealthy replicas instead of rejecting the request
+		for _, r := range b.replicas {
+			if !tried[r.url] {
+				candidates = append(candidates, r)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	if b.sticky && affinityKey != "" {
+		return pickRendezvous(candidates, affinityKey).url, true
+	}
+	if b.strategy == balanceWeightedRandom {
+		return b.pickWeightedRandom(candidates).url, true
+	}
+	return pickSmoothRoundRobin(candidates).url, true
+}
+
+// pickRendezvous implements weighted rendezvous (highest random weight) hashing.
+// Each key consistently maps to one replica, and removing a replica only moves
+// the keys that were mapped to it.
+func pickRendezvous(candidates []*replicaState, key string) *replicaState {
+	var best *replicaState
+	bestScore := math.Inf(-1)
+	for _, r := range candidates {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write([]byte(r.url))
+		// Map the hash to (0, 1) and weight it so heavier replicas win proportionally more keys
+		unit := (float64(h.Sum64()>>11) + 0.5) / float64(1<<53)
+		score := -float64(r.weight) / math.Log(unit)
+		if score > bestScore {
+			best = r
+			bestScore = score
+		}
+	}
+	return best
+}
+
+// pickSmoothRoundRobin implements the smooth weighted round-robin algorithm,
+// which interleaves replicas proportionally to their weights.
+func pickSmoothRoundRobin(candidates []*replicaState) *replicaState {
+	total := 0
+	var best *replicaState
+	for _, r := range candidates {
+		r.current += r.weight
+		total += r.weight
+		if best == nil || r.current > best.current {
+			best = r
+		}
+	}
+	best.current -= total
+	return best
+}
+
+func (b *replicaBalancer) pickWeightedRandom(candidates []*replicaState) *replicaState {
+	total := 0
+	for _, r := range candidates {
+		total += r.weight
+	}
+	n := b.rand.Intn(total)
+	for _, r := range candidates {
+		if n < r.weight {
+			return r
+		}
+		n -= r.weight
+	}
+	return candidates[len(candidates)-1]
+}
+
+// markFailed takes a replica out of rotation until its cooldown expires. Taking a healthy
+// replica out of rotation opens its circuit.
+func (b *replicaBalancer) markFailed(replicaURL string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	for _, r := range b.replicas {
+		if r.url == replicaURL {
+			if !now.Before(r.unhealthyUntil) {
+				events.publish(eventCircuitOpened, map[string]interface{}{"model": b.model, "replica": r.url, "cooldown": b.cooldown.String()})
+			}
+			r.unhealthyUntil = now.Add(b.cooldown)
+		}
+	}
+}
+
+// markHealthy puts a replica back into rotation immediately
+func (b *replicaBalancer) markHealthy(replicaURL string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, r := range b.replicas {
+		if r.url == replicaURL {
+			r.unhealthyUntil = time.Time{}
+		}
+	}
+}
+
+// sendBalanced sends the A2A request to a replica chosen by the balancer. message/send is not
+// idempotent, so the request only fails over to the remaining replicas if a replica could not be
+// connected to; other failures mark the replica unhealthy and are returned.
+// The affinity key is used for sticky routing.
+func sendBalanced(ctx context.Context, b *replicaBalancer, affinityKey string, body []byte, header http.Header) (models.SendMessageSuccessResponse, error) {
+	tried := make(map[string]bool)
+	var errs []error
+	for {
+		target, ok := b.pick(tried, affinityKey)
+		if !ok {
+			return models.SendMessageSuccessResponse{}, fmt.Errorf("all replicas failed: %w", errors.Join(errs...))
+		}
+		tried[target] = true
+
+		resp, err := sendA2A(ctx, target, body, header)
+		if err == nil {
+			b.markHealthy(target)
+			return resp, nil
+		}
+		var rpcErr *a2aclient.RPCError
+		if ctx.Err() != nil || errors.As(err, &rpcErr) {
+			return resp, err
+		}
+
+		logger.Warning(fmt.Sprintf("replica %s failed, marking unhealthy for %s: %v", target, b.cooldown, err))
+		b.markFailed(target)
+		if !notDelivered(err) {
+			return resp, err
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", target, err))
+	}
+}
+
+// notDelivered reports whether a request failed before any of it was sent, because no
+// connection to the agent or the proxy in front of it could be established
+func notDelivered(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && (opErr.Op == "dial" || opErr.Op == "proxyconnect")
+}
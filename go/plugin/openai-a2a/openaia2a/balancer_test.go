@@ -0,0 +1,174 @@
+package openaia2a
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewReplicaBalancer_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  LoadBalancingConfig
+	}{
+		{name: "no replicas", cfg: LoadBalancingConfig{}},
+		{name: "unknown strategy", cfg: LoadBalancingConfig{Replicas: []Replica{{URL: "http://a:8000"}}, Strategy: "least_conn"}},
+		{name: "invalid cooldown", cfg: LoadBalancingConfig{Replicas: []Replica{{URL: "http://a:8000"}}, UnhealthyCooldown: "soon"}},
+		{name: "invalid URL", cfg: LoadBalancingConfig{Replicas: []Replica{{URL: "not-a-url"}}}},
+		{name: "negative weight", cfg: LoadBalancingConfig{Replicas: []Replica{{URL: "http://a:8000", Weight: -1}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newReplicaBalancer(tt.cfg)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestReplicaBalancer_WeightedRoundRobin(t *testing.T) {
+	b, err := newReplicaBalancer(LoadBalancingConfig{
+		Replicas: []Replica{{URL: "http://a:8000", Weight: 3}, {URL: "http://b:8000", Weight: 1}},
+	})
+	assert.NoError(t, err)
+
+	counts := map[string]int{}
+	for i := 0; i < 8; i++ {
+		target, ok := b.pick(nil, "")
+		assert.True(t, ok)
+		counts[target]++
+	}
+
+	assert.Equal(t, 6, counts["http://a:8000"])
+	assert.Equal(t, 2, counts["http://b:8000"])
+}
+
+func TestReplicaBalancer_WeightedRandomSkipsTriedReplicas(t *testing.T) {
+	b, err := newReplicaBalancer(LoadBalancingConfig{
+		Replicas: []Replica{{URL: "http://a:8000"}, {URL: "http://b:8000"}},
+		Strategy: balanceWeightedRandom,
+	})
+	assert.NoError(t, err)
+
+	target, ok := b.pick(map[string]bool{"http://a:8000": true}, "")
+	assert.True(t, ok)
+	assert.Equal(t, "http://b:8000", target)
+
+	_, ok = b.pick(map[string]bool{"http://a:8000": true, "http://b:8000": true}, "")
+	assert.False(t, ok)
+}
+
+func TestReplicaBalancer_PassiveHealth(t *testing.T) {
+	now := time.Unix(1731679815, 0)
+	b, err := newReplicaBalancer(LoadBalancingConfig{
+		Replicas:          []Replica{{URL: "http://a:8000"}, {URL: "http://b:8000"}},
+		UnhealthyCooldown: "10s",
+	})
+	assert.NoError(t, err)
+	b.now = func() time.Time { return now }
+
+	b.markFailed("http://a:8000")
+	for i := 0; i < 3; i++ {
+		target, _ := b.pick(nil, "")
+		assert.Equal(t, "http://b:8000", target)
+	}
+
+	// Every replica unhealthy: fail open
+	b.markFailed("http://b:8000")
+	_, ok := b.pick(nil, "")
+	assert.True(t, ok)
+
+	// Cooldown expired
+	now = now.Add(11 * time.Second)
+	seen := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		target, _ := b.pick(nil, "")
+		seen[target] = true
+	}
+	assert.Len(t, seen, 2)
+}
+
+func TestSendBalanced_FailsOverToHealthyReplica(t *testing.T) {
+	unreachable := newA2AAgent(t, "", 0, http.StatusOK)
+	unreachable.Close()
+	healthy := newA2AAgent(t, "replica answer", 0, http.StatusOK)
+
+	b, err := newReplicaBalancer(LoadBalancingConfig{
+		Replicas: []Replica{{URL: unreachable.URL, Weight: 10}, {URL: healthy.URL, Weight: 1}},
+	})
+	assert.NoError(t, err)
+
+	resp, err := sendBalanced(context.Background(), b, "", []byte(`{}`), http.Header{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "replica answer", extractA2AContent(resp))
+
+	// The unreachable replica is now skipped despite its higher weight
+	target, _ := b.pick(nil, "")
+	assert.Equal(t, healthy.URL, target)
+}
+
+func TestSendBalanced_DoesNotResendDeliveredRequests(t *testing.T) {
+	var received atomic.Int32
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(broken.Close)
+
+	b, err := newReplicaBalancer(LoadBalancingConfig{
+		Replicas: []Replica{{URL: broken.URL, Weight: 10}, {URL: broken.URL + "/other", Weight: 1}},
+	})
+	assert.NoError(t, err)
+
+	// The replica may have started the task before failing, so it is not sent again
+	_, err = sendBalanced(context.Background(), b, "", []byte(`{}`), http.Header{})
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), received.Load())
+
+	target, _ := b.pick(nil, "")
+	assert.Equal(t, broken.URL+"/other", target, "the failed replica is taken out of rotation")
+}
+
+func TestReplicaBalancer_StickyConversations(t *testing.T) {
+	b, err := newReplicaBalancer(LoadBalancingConfig{
+		Replicas: []Replica{{URL: "http://a:8000"}, {URL: "http://b:8000"}, {URL: "http://c:8000"}},
+		Sticky:   true,
+	})
+	assert.NoError(t, err)
+
+	assigned := map[string]string{}
+	used := map[string]bool{}
+	for i := 0; i < 30; i++ {
+		conversation := fmt.Sprintf("conversation-%d", i)
+		target, ok := b.pick(nil, conversation)
+		assert.True(t, ok)
+		assigned[conversation] = target
+		used[target] = true
+	}
+	assert.Len(t, used, 3, "conversations should spread across replicas")
+
+	// Same conversation, same replica
+	for conversation, target := range assigned {
+		again, _ := b.pick(nil, conversation)
+		assert.Equal(t, target, again)
+	}
+
+	// Only conversations of a failed replica move
+	b.markFailed("http://a:8000")
+	for conversation, target := range assigned {
+		again, _ := b.pick(nil, conversation)
+		if target == "http://a:8000" {
+			assert.NotEqual(t, target, again)
+		} else {
+			assert.Equal(t, target, again)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package openaia2a
+
+import (
+	"fmt"
+	"math/rand"
+	"net/url"
+)
+
+// variantHeader identifies which agent variant served a request of a model with a canary
+const variantHeader = "X-Agent-Variant"
+
+// Agent variant constants
+const (
+	variantStable = "stable"
+	variantCanary = "canary"
+)
+
+// CanaryConfig diverts a percentage of a model's traffic to an alternate agent URL
+type CanaryConfig struct {
+	URL        string  `json:"url"`
+	Percentage float64 `json:"percentage"`
+}
+
+// canaryRoll returns a random number in [0, 100) used to decide canary routing
+var canaryRoll = func() float64 {
+	return rand.Float64() * 100
+}
+
+// validateCanaries checks the canary configuration of all agents
+func validateCanaries(agents []AgentInfo) error {
+	for _, agent := range agents {
+		if agent.Canary == nil {
+			continue
+		}
+		if parsed, err := url.Parse(agent.Canary.URL); err != nil || parsed.Host == "" {
+			return fmt.Errorf("agent %s: invalid canary URL '%s'", agent.ModelID, agent.Canary.URL)
+		}
+		if agent.Canary.Percentage < 0 || agent.Canary.Percentage > 100 {
+			return fmt.Errorf("agent %s: canary percentage must be between 0 and 100, got %v", agent.ModelID, agent.Canary.Percentage)
+		}
+	}
+	return nil
+}
+
+// selectVariant decides whether a request is served by the stable agent or its canary
+func selectVariant(canary *CanaryConfig) string {
+	if canary != nil && canaryRoll() < canary.Percentage {
+		return variantCanary
+	}
+	return variantStable
+}
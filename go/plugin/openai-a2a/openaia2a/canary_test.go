@@ -0,0 +1,90 @@
+package openaia2a
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCanaries(t *testing.T) {
+	tests := []struct {
+		name    string
+		canary  CanaryConfig
+		wantErr bool
+	}{
+		{name: "valid", canary: CanaryConfig{URL: "http://agent-v2:8000", Percentage: 5}},
+		{name: "invalid URL", canary: CanaryConfig{URL: "agent-v2", Percentage: 5}, wantErr: true},
+		{name: "percentage above 100", canary: CanaryConfig{URL: "http://agent-v2:8000", Percentage: 101}, wantErr: true},
+		{name: "negative percentage", canary: CanaryConfig{URL: "http://agent-v2:8000", Percentage: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCanaries([]AgentInfo{{ModelID: "agent", Canary: &tt.canary}})
+
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestChatCompletions_CanaryRouting(t *testing.T) {
+	canaryAgent := newA2AAgent(t, "canary answer", 0, http.StatusOK)
+
+	originalRoll := canaryRoll
+	t.Cleanup(func() { canaryRoll = originalRoll })
+
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{
+					"model_id": "test-agent",
+					"url":      "http://localhost:8001",
+					"canary":   map[string]interface{}{"url": canaryAgent.URL, "percentage": 5},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		roll        float64
+		wantVariant string
+		wantContent string
+		wantBackend bool
+	}{
+		{name: "stable", roll: 5, wantVariant: variantStable, wantContent: "stable answer", wantBackend: true},
+		{name: "canary", roll: 4.9, wantVariant: variantCanary, wantContent: "canary answer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			canaryRoll = func() float64 { return tt.roll }
+			mockHandler := &MockHandler{
+				Response: []byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task","artifacts":[{"artifactId":"a","parts":[{"kind":"text","text":"stable answer"}]}]}}`),
+			}
+			handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+			assert.NoError(t, err)
+
+			reqBody, _ := json.Marshal(models.OpenAIRequest{
+				Model:    "test-agent",
+				Messages: []models.OpenAIMessage{{Role: "user", Content: "Hello"}},
+			})
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat/completions", bytes.NewReader(reqBody)))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.wantVariant, rec.Header().Get(variantHeader))
+			assert.Equal(t, tt.wantBackend, mockHandler.ReceivedRequest != nil)
+
+			var openAIResp models.OpenAIResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &openAIResp))
+			assert.Equal(t, tt.wantContent, openAIResp.Choices[0].Message.Content)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package openaia2a
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aclient"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+)
+
+const (
+	defaultTaskPollInterval = 500 * time.Millisecond
+	taskCancelTimeout       = 5 * time.Second
+)
+
+// CancellationConfig lets the gateway stop an agent's task when the OpenAI client disconnects.
+// Messages are sent non-blocking and the task is polled via tasks/get until it finishes,
+// so the gateway knows the task ID and can issue tasks/cancel for abandoned work.
+type CancellationConfig struct {
+	PollInterval string `json:"poll_interval"`
+}
+
+// rpcFunc posts a JSON-RPC request body to an agent and returns the raw response body
+type rpcFunc = a2aclient.Transport
+
+// validateCancellations checks the cancellation configuration of all agents.
+// Polling requires that every request of a task reaches the same agent.
+func validateCancellations(agents []AgentInfo) error {
+	for _, agent := range agents {
+		if agent.Cancellation == nil {
+			continue
+		}
+		if agent.Ensemble != nil || agent.LoadBalancing != nil || agent.Canary != nil {
+			return fmt.Errorf("agent %s: cancellation is only supported for agents with a single url", agent.ModelID)
+		}
+		if _, err := agent.Cancellation.pollInterval(); err != nil {
+			return fmt.Errorf("agent %s: %w", agent.ModelID, err)
+		}
+	}
+	return nil
+}
+
+func (c *CancellationConfig) pollInterval() (time.Duration, error) {
+	if c.PollInterval == "" {
+		return defaultTaskPollInterval, nil
+	}
+	d, err := time.ParseDuration(c.PollInterval)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid poll_interval '%s'", c.PollInterval)
+	}
+	return d, nil
+}
+
+// nonBlocking asks the agent to return the task immediately instead of waiting for its completion
+func nonBlocking(a2aReq *models.SendMessageRequest) {
+	blocking := false
+	if a2aReq.Params.Configuration == nil {
+		a2aReq.Params.Configuration = &models.MessageSendConfiguration{}
+	}
+	a2aReq.Params.Configuration.Blocking = &blocking
+}
+
+// isTerminal reports whether a task will not change its state anymore without client input
+func isTerminal(state models.TaskState) bool {
+	switch state {
+	case models.TaskStateSubmitted, models.TaskStateWorking, models.TaskStateUnknown:
+		return false
+	default:
+		return true
+	}
+}
+
+// cancellableRoute returns a sender that polls non-blocking tasks until they finish.
+// If ctx ends first, the task is cancelled on the agent.
+func cancellableRoute(rpc rpcFunc, interval time.Duration) sendFunc {
+	return func(ctx context.Context, body []byte) (string, error) {
+		a2aResp, err := callRPC(ctx, rpc, body)
+		if err != nil {
+			return "", err
+		}
+		if a2aResp.Result.Kind == "task" {
+			defer activities.begin(activityPollers, a2aResp.Result.Id)()
+		}
+
+		for a2aResp.Result.Kind == "task" && !isTerminal(a2aResp.Result.Status.State) {
+			taskID := a2aResp.Result.Id
+			select {
+			case <-ctx.Done():
+				cancelTask(rpc, taskID)
+				return "", ctx.Err()
+			case <-time.After(interval):
+			}
+
+			client := a2aclient.NewWithTransport(rpc, a2aclient.Options{})
+			if err := client.Call(ctx, "tasks/get", models.TaskQueryParams{Id: taskID}, &a2aResp.Result); err != nil {
+				if ctx.Err() != nil {
+					cancelTask(rpc, taskID)
+				}
+				return "", err
+			}
+		}
+		return extractA2AContent(a2aResp), nil
+	}
+}
+
+// callRPC sends a JSON-RPC request and parses a message/send or tasks/get result,
+// both of which carry a Task or Message. JSON-RPC errors are returned as *a2aclient.RPCError.
+func callRPC(ctx context.Context, rpc rpcFunc, body []byte) (models.SendMessageSuccessResponse, error) {
+	var a2aResp models.SendMessageSuccessResponse
+	err := a2aclient.NewWithTransport(rpc, a2aclient.Options{}).Do(ctx, body, &a2aResp.Result)
+	return a2aResp, err
+}
+
+// cancelTask issues tasks/cancel for an abandoned task. It runs detached from the client
+// request, which is already done, and only logs failures.
+func cancelTask(rpc rpcFunc, taskID string) {
+	logger.Info("client disconnected, cancelling agent task:", taskID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), taskCancelTimeout)
+	defer cancel()
+
+	client := a2aclient.NewWithTransport(rpc, a2aclient.Options{})
+	if _, err := client.CancelTask(ctx, models.TaskIdParams{Id: taskID}); err != nil {
+		var rpcErr *a2aclient.RPCError
+		if errors.As(err, &rpcErr) && rpcErr.Code == a2aerrors.CodeTaskNotCancelable {
+			logger.Debug("agent task already finished:", taskID)
+			return
+		}
+		logger.Error("failed to cancel agent task:", err)
+	}
+}
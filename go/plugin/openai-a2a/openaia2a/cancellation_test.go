@@ -0,0 +1,132 @@
+package openaia2a
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// taskAgent runs every message as a task that completes after a number of tasks/get polls
+type taskAgent struct {
+	pollsUntilDone int
+
+	mu       sync.Mutex
+	blocking *bool
+	polls    int
+	canceled chan string
+}
+
+func (a *taskAgent) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var rpcReq struct {
+		Method string          `json:"method"`
+		Params json.RawMessage `json:"params"`
+	}
+	_ = json.NewDecoder(req.Body).Decode(&rpcReq)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	state := models.TaskStateWorking
+	switch rpcReq.Method {
+	case "message/send":
+		var params models.MessageSendParams
+		_ = json.Unmarshal(rpcReq.Params, &params)
+		if params.Configuration != nil {
+			a.blocking = params.Configuration.Blocking
+		}
+	case "tasks/get":
+		a.polls++
+		if a.polls >= a.pollsUntilDone {
+			state = models.TaskStateCompleted
+		}
+	case "tasks/cancel":
+		var params models.TaskIdParams
+		_ = json.Unmarshal(rpcReq.Params, &params)
+		a.canceled <- params.Id
+		state = models.TaskStateCanceled
+	}
+
+	task := models.SendMessageSuccessResponseResult{Id: "task-1", Kind: "task", Status: models.TaskStatus{State: state}}
+	if state == models.TaskStateCompleted {
+		task.Artifacts = []models.Artifact{{ArtifactId: "a", Parts: []models.ArtifactPartsElem{models.TextPart{Kind: "text", Text: "done"}}}}
+	}
+	_ = json.NewEncoder(w).Encode(models.SendMessageSuccessResponse{Jsonrpc: "2.0", Id: 1, Result: task})
+}
+
+func newCancellationHandler(t *testing.T, agent *taskAgent) http.Handler {
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{
+					"model_id":     "task-agent",
+					"url":          "http://localhost:8001",
+					"cancellation": map[string]interface{}{"poll_interval": "5ms"},
+				},
+			},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, agent)
+	assert.NoError(t, err)
+	return handler
+}
+
+func TestValidateCancellations(t *testing.T) {
+	assert.NoError(t, validateCancellations([]AgentInfo{{ModelID: "a", URL: "http://a:8000", Cancellation: &CancellationConfig{}}}))
+	assert.Error(t, validateCancellations([]AgentInfo{{ModelID: "a", URL: "http://a:8000", Cancellation: &CancellationConfig{PollInterval: "often"}}}))
+	assert.Error(t, validateCancellations([]AgentInfo{{
+		ModelID:       "a",
+		LoadBalancing: &LoadBalancingConfig{Replicas: []Replica{{URL: "http://a:8000"}}},
+		Cancellation:  &CancellationConfig{},
+	}}))
+}
+
+func TestCancellation_PollsTaskUntilCompleted(t *testing.T) {
+	agent := &taskAgent{pollsUntilDone: 3, canceled: make(chan string, 1)}
+	handler := newCancellationHandler(t, agent)
+
+	rec := sendChatCompletion(handler, "task-agent", "")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp models.OpenAIResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "done", resp.Choices[0].Message.Content)
+	assert.Equal(t, 3, agent.polls)
+	assert.False(t, *agent.blocking)
+	assert.Empty(t, agent.canceled)
+}
+
+func TestCancellation_CancelsTaskOnClientDisconnect(t *testing.T) {
+	agent := &taskAgent{pollsUntilDone: 1 << 30, canceled: make(chan string, 1)}
+	handler := newCancellationHandler(t, agent)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reqBody, _ := json.Marshal(models.OpenAIRequest{
+		Model:    "task-agent",
+		Messages: []models.OpenAIMessage{{Role: "user", Content: "Long running work"}},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/chat/completions", bytes.NewReader(reqBody)).WithContext(ctx)
+
+	done := make(chan struct{})
+	go func() {
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	select {
+	case taskID := <-agent.canceled:
+		assert.Equal(t, "task-1", taskID)
+	case <-time.After(time.Second):
+		t.Fatal("abandoned task was not cancelled")
+	}
+	<-done
+}
@@ -0,0 +1,143 @@
+package openaia2a
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+)
+
+const (
+	defaultCapabilityTimeout  = 2 * time.Second
+	defaultCapabilityCacheTTL = 5 * time.Minute
+)
+
+// CapabilityDiscoveryConfig reads the capabilities and modes of agents from their agent cards.
+// Streams and push notifications are only offered for models whose agent declares them, and
+// files are only sent in the input modes the agent accepts.
+type CapabilityDiscoveryConfig struct {
+	Timeout  string `json:"timeout,omitempty"`
+	CacheTTL string `json:"cache_ttl,omitempty"`
+}
+
+// capabilityDiscovery fetches and caches the agent cards of models for their capabilities and
+// modes. Agents whose card cannot be fetched are treated as unknown, and the gateway behaves as
+// without discovery.
+type capabilityDiscovery struct {
+	timeout  time.Duration
+	cacheTTL time.Duration
+
+	mu      sync.Mutex
+	entries map[string]capabilityEntry
+	now     func() time.Time
+}
+
+// capabilityEntry caches the agent card of a model, nil if it could not be fetched
+type capabilityEntry struct {
+	target string
+	card   *models.AgentCard
+	expiry time.Time
+}
+
+// newCapabilityDiscovery validates the configuration. A nil config disables discovery.
+func newCapabilityDiscovery(cfg *CapabilityDiscoveryConfig) (*capabilityDiscovery, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	d := &capabilityDiscovery{
+		timeout:  defaultCapabilityTimeout,
+		cacheTTL: defaultCapabilityCacheTTL,
+		entries:  make(map[string]capabilityEntry),
+		now:      time.Now,
+	}
+	var err error
+	if cfg.Timeout != "" {
+		if d.timeout, err = time.ParseDuration(cfg.Timeout); err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+	}
+	if cfg.CacheTTL != "" {
+		if d.cacheTTL, err = time.ParseDuration(cfg.CacheTTL); err != nil {
+			return nil, fmt.Errorf("invalid cache_ttl: %w", err)
+		}
+	}
+	return d, nil
+}
+
+// card returns the agent card of a model, or false if it is unknown
+func (d *capabilityDiscovery) card(ctx context.Context, agent AgentInfo) (models.AgentCard, bool) {
+	if d == nil {
+		return models.AgentCard{}, false
+	}
+	targets := agentTargets(agent)
+	if len(targets) == 0 {
+		return models.AgentCard{}, false
+	}
+
+	d.mu.Lock()
+	entry, ok := d.entries[agent.ModelID]
+	now := d.now()
+	d.mu.Unlock()
+	if !ok || entry.target != targets[0] || !now.Before(entry.expiry) {
+		entry = capabilityEntry{target: targets[0], expiry: now.Add(d.cacheTTL)}
+		card, err := fetchAgentCard(ctx, targets[0], d.timeout)
+		if err != nil {
+			logger.Warning(fmt.Sprintf("cannot fetch agent card of model '%s', its capabilities are unknown: %v", agent.ModelID, err))
+		} else {
+			entry.card = &card
+		}
+		d.mu.Lock()
+		d.entries[agent.ModelID] = entry
+		d.mu.Unlock()
+	}
+	if entry.card == nil {
+		return models.AgentCard{}, false
+	}
+	return *entry.card, true
+}
+
+// lookup returns the capabilities declared by the agent card of a model, or false if they are unknown
+func (d *capabilityDiscovery) lookup(ctx context.Context, agent AgentInfo) (models.AgentCapabilities, bool) {
+	card, ok := d.card(ctx, agent)
+	return card.Capabilities, ok
+}
+
+// cards returns the agent cards of several models, nil where unknown, fetching them concurrently
+func (d *capabilityDiscovery) cards(ctx context.Context, agents []AgentInfo) []*models.AgentCard {
+	cards := make([]*models.AgentCard, len(agents))
+	if d == nil {
+		return cards
+	}
+	var wg sync.WaitGroup
+	for i, agent := range agents {
+		wg.Add(1)
+		go func(i int, agent AgentInfo) {
+			defer wg.Done()
+			if card, ok := d.card(ctx, agent); ok {
+				cards[i] = &card
+			}
+		}(i, agent)
+	}
+	wg.Wait()
+	return cards
+}
+
+// supportsStreaming reports whether a model may be streamed: agents with unknown capabilities are
+// given the benefit of the doubt
+func (d *capabilityDiscovery) supportsStreaming(ctx context.Context, agent AgentInfo) bool {
+	c, ok := d.lookup(ctx, agent)
+	return !ok || declared(c.Streaming)
+}
+
+// supportsPushNotifications reports whether the agent of a model may be sent push notification configs
+func (d *capabilityDiscovery) supportsPushNotifications(ctx context.Context, agent AgentInfo) bool {
+	c, ok := d.lookup(ctx, agent)
+	return !ok || declared(c.PushNotifications)
+}
+
+// declared reports whether an optional capability flag is set; A2A capabilities default to false
+func declared(flag *bool) bool {
+	return flag != nil && *flag
+}
@@ -0,0 +1,115 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aclient"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/testagent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapabilityGating(t *testing.T) {
+	streamingAgent := testagent.New(testagent.Options{})
+	t.Cleanup(streamingAgent.Close)
+	plainAgent := testagent.New(testagent.Options{Card: &models.AgentCard{Name: "plain", Capabilities: models.AgentCapabilities{}}})
+	t.Cleanup(plainAgent.Close)
+
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "streaming-agent", "url": streamingAgent.URL},
+				map[string]interface{}{"model_id": "plain-agent", "url": plainAgent.URL},
+				map[string]interface{}{"model_id": "offline-agent", "url": "http://127.0.0.1:1"},
+			},
+			"streaming":            map[string]interface{}{},
+			"push_notifications":   map[string]interface{}{"callback_url": "http://gateway:10000"},
+			"capability_discovery": map[string]interface{}{"timeout": "1s"},
+			"openai_errors":        true,
+		},
+	}
+	mockHandler := &MockHandler{}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	require.NoError(t, err)
+
+	t.Run("models list declared capabilities", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/models", nil))
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var resp models.OpenAIModelsResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		require.Len(t, resp.Data, 3)
+		assert.Equal(t, &models.OpenAIAgentCapabilities{Streaming: true}, resp.Data[0].AgentCapabilities)
+		assert.Equal(t, &models.OpenAIAgentCapabilities{}, resp.Data[1].AgentCapabilities)
+		assert.Nil(t, resp.Data[2].AgentCapabilities, "capabilities of unreachable agents are unknown")
+	})
+
+	t.Run("streams require the streaming capability", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat/completions", strings.NewReader(`{"model":"plain-agent","stream":true,"messages":[{"role":"user","content":"Hi"}]}`)))
+
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		var errorResp models.OpenAIErrorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResp))
+		assert.Equal(t, "model plain-agent does not support streaming: its agent card does not declare the streaming capability", errorResp.Error.Message)
+		assert.Equal(t, "stream", *errorResp.Error.Param)
+		assert.Empty(t, plainAgent.Requests())
+
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat/completions", strings.NewReader(`{"model":"streaming-agent","stream":true,"messages":[{"role":"user","content":"Hi"}]}`)))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("push notification configs require the capability", func(t *testing.T) {
+		rec := postA2A(handler, "/plain-agent", `{"jsonrpc":"2.0","id":7,"method":"message/send","params":{"message":{"role":"user","messageId":"m1","parts":[{"kind":"text","text":"Hi"}]},
+			"configuration":{"pushNotificationConfig":{"url":"https://client.example/hook"}}}}`, nil)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		var rpcResp models.JSONRPCErrorResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &rpcResp))
+		assert.Equal(t, a2aerrors.CodePushNotificationNotSupported, rpcResp.Error.Code)
+		assert.Nil(t, mockHandler.ReceivedRequest)
+	})
+}
+
+func TestCapabilityDiscovery_CachesCards(t *testing.T) {
+	var fetches atomic.Int32
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, a2aclient.AgentCardPath, r.URL.Path)
+		fetches.Add(1)
+		streaming := true
+		_ = json.NewEncoder(w).Encode(models.AgentCard{Capabilities: models.AgentCapabilities{Streaming: &streaming}})
+	}))
+	defer agent.Close()
+
+	d, err := newCapabilityDiscovery(&CapabilityDiscoveryConfig{CacheTTL: "1m"})
+	require.NoError(t, err)
+	clock := time.Unix(1700000000, 0)
+	d.now = func() time.Time { return clock }
+	info := AgentInfo{ModelID: "weather-agent", URL: agent.URL}
+
+	assert.True(t, d.supportsStreaming(context.Background(), info))
+	assert.False(t, d.supportsPushNotifications(context.Background(), info))
+	assert.Equal(t, int32(1), fetches.Load())
+
+	clock = clock.Add(time.Minute)
+	assert.True(t, d.supportsStreaming(context.Background(), info))
+	assert.Equal(t, int32(2), fetches.Load(), "cards are fetched again after cache_ttl")
+
+	var none *capabilityDiscovery
+	assert.True(t, none.supportsStreaming(context.Background(), info), "without discovery, all models may be streamed")
+
+	_, err = newCapabilityDiscovery(&CapabilityDiscoveryConfig{Timeout: "soon"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,173 @@
+package openaia2a
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/capture"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/timing"
+	"github.com/go-http-utils/headers"
+)
+
+// sendFunc sends a marshalled A2A request to a model's agent backend and returns the agent's text output
+type sendFunc func(ctx context.Context, body []byte) (string, error)
+
+// directRoute returns the sender for models whose agents are called without KrakenD,
+// along with the client message used if no agent answers. It returns nil for all other models.
+func directRoute(w http.ResponseWriter, req *http.Request, agents *agentSet, modelInfo *ModelInfo, conversationId string) (sendFunc, string) {
+	log := logging.FromRequest(logger, req)
+	header := agentHeader(req)
+
+	// Ensembles fan out to all configured agent URLs
+	if modelInfo.Ensemble != nil {
+		ensemble := *modelInfo.Ensemble
+		log.Debug(fmt.Sprintf("fanning out model %s to %d agents using strategy %s",
+			modelInfo.ModelID, len(ensemble.URLs), ensemble.Strategy))
+		return func(ctx context.Context, body []byte) (string, error) {
+			return fanOut(ctx, ensemble, body, header)
+		}, "no ensemble agent returned a response"
+	}
+
+	// Models with a canary divert a share of their traffic to the canary agent
+	if modelInfo.Canary != nil {
+		variant := selectVariant(modelInfo.Canary)
+		w.Header().Set(variantHeader, variant)
+		if variant == variantCanary {
+			target := modelInfo.Canary.URL
+			log.Debug(fmt.Sprintf("routing model %s to canary %s", modelInfo.ModelID, target))
+			return func(ctx context.Context, body []byte) (string, error) {
+				a2aResp, err := sendA2A(ctx, target, body, header)
+				if err != nil {
+					return "", err
+				}
+				return extractA2AContent(a2aResp), nil
+			}, "canary agent did not return a response"
+		}
+	}
+
+	// Load-balanced agents are called directly on one of their replicas
+	if balancer := agents.balancers[modelInfo.ModelID]; balancer != nil {
+		return func(ctx context.Context, body []byte) (string, error) {
+			a2aResp, err := sendBalanced(ctx, balancer, conversationId, body, header)
+			if err != nil {
+				return "", err
+			}
+			return extractA2AContent(a2aResp), nil
+		}, "no agent replica returned a response"
+	}
+
+	return nil, ""
+}
+
+// detachedWriter is the client side of a captured KrakenD response that is never sent to the client
+type detachedWriter struct {
+	header http.Header
+}
+
+func (d *detachedWriter) Header() http.Header         { return d.header }
+func (d *detachedWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (d *detachedWriter) WriteHeader(int)             {}
+
+// rpcRoute returns a sender forwarding each request through rpc, e.g. the KrakenD endpoint of a model.
+// Unlike the single-choice path, non-OK responses are reported as errors instead of passed through.
+func rpcRoute(rpc rpcFunc) sendFunc {
+	return func(ctx context.Context, body []byte) (string, error) {
+		a2aResp, err := callRPC(ctx, rpc, body)
+		if err != nil {
+			return "", err
+		}
+		return extractA2AContent(a2aResp), nil
+	}
+}
+
+// krakendRPC posts JSON-RPC requests through the KrakenD endpoint of a model
+func krakendRPC(req *http.Request, handler http.Handler, path string) rpcFunc {
+	return func(ctx context.Context, body []byte) ([]byte, error) {
+		backendReq := req.Clone(ctx)
+		backendReq.Body = io.NopCloser(bytes.NewReader(body))
+		backendReq.ContentLength = int64(len(body))
+		backendReq.URL.Path = path
+		backendReq.Header.Set(headers.ContentType, "application/json")
+		backendReq.Header.Set(headers.ContentLength, fmt.Sprintf("%d", len(body)))
+
+		rw := capture.New(&detachedWriter{header: http.Header{}}, maxResponseSize)
+		defer rw.Release()
+		handler.ServeHTTP(rw, backendReq)
+		if rw.Passthrough() {
+			return nil, fmt.Errorf("response exceeds %d bytes", maxResponseSize)
+		}
+
+		// JSON-RPC errors are reported by callRPC regardless of the status code
+		if _, ok := a2aerrors.Parse(rw.Body()); !ok && rw.StatusCode() != http.StatusOK {
+			return nil, fmt.Errorf("unexpected status %d", rw.StatusCode())
+		}
+		return bytes.Clone(rw.Body()), nil
+	}
+}
+
+// handleMultipleChoices emulates OpenAI sampling by sending openAIReq.N independent copies of the
+// A2A request concurrently. Copies share the context ID but carry distinct message IDs.
+// If any copy fails, the others are cancelled and the request fails.
+func handleMultipleChoices(w http.ResponseWriter, req *http.Request, cfg config, openAIReq models.OpenAIRequest,
+	a2aReq models.SendMessageRequest, send sendFunc, failure string) {
+	log := logging.FromRequest(logger, req)
+	n := openAIReq.N
+	bodies := make([][]byte, n)
+	for i := range bodies {
+		choiceReq := a2aReq
+		choiceReq.Id = i + 1
+		choiceReq.Params.Message.MessageId = newID()
+		body, err := json.Marshal(choiceReq)
+		if err != nil {
+			log.Error("failed to marshal A2A request:", err)
+			writeError(w, req, http.StatusInternalServerError, "failed to create A2A request")
+			return
+		}
+		bodies[i] = body
+	}
+
+	log.Debug(fmt.Sprintf("sending %d concurrent A2A requests for model %s", n, openAIReq.Model))
+
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	contents := make([]string, n)
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for i, body := range bodies {
+		wg.Add(1)
+		go func(i int, body []byte) {
+			defer wg.Done()
+			stop := timing.Upstream(ctx)
+			content, err := send(ctx, body)
+			stop()
+			if err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			contents[i] = content
+		}(i, body)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		log.Error("choice request failed:", firstErr)
+		writeUpstreamError(w, req, firstErr, failure)
+		return
+	}
+	writeCompletion(w, req, cfg, newOpenAIChoicesResponse(contents, openAIReq))
+}
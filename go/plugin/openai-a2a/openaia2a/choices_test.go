@@ -0,0 +1,102 @@
+package openaia2a
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// recordingAgent answers every A2A request with its message ID and records the received messages
+type recordingAgent struct {
+	mu       sync.Mutex
+	messages []models.Message
+	fail     bool
+}
+
+func (a *recordingAgent) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var a2aReq models.SendMessageRequest
+	_ = json.NewDecoder(req.Body).Decode(&a2aReq)
+	a.mu.Lock()
+	a.messages = append(a.messages, a2aReq.Params.Message)
+	a.mu.Unlock()
+
+	if a.fail {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(models.SendMessageSuccessResponse{
+		Jsonrpc: "2.0",
+		Id:      a2aReq.Id,
+		Result: models.SendMessageSuccessResponseResult{
+			Kind: "task",
+			Artifacts: []models.Artifact{{ArtifactId: "a", Parts: []models.ArtifactPartsElem{
+				models.TextPart{Kind: "text", Text: "answer to " + a2aReq.Params.Message.MessageId},
+			}}},
+		},
+	})
+}
+
+func sendChoicesRequest(t *testing.T, agent http.Handler, n int) *httptest.ResponseRecorder {
+	var extraConfig map[string]interface{}
+	json.Unmarshal([]byte(configStrWithAgents), &extraConfig)
+	handler, err := module.registerHandlers(context.Background(), extraConfig, agent)
+	assert.NoError(t, err)
+
+	reqBody, _ := json.Marshal(models.OpenAIRequest{
+		Model:    "test-agent-v2",
+		Messages: []models.OpenAIMessage{{Role: "user", Content: "Tell me a joke"}},
+		N:        n,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/chat/completions", bytes.NewReader(reqBody))
+	req.Header.Set("X-Conversation-ID", "conversation-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestChatCompletions_MultipleChoices(t *testing.T) {
+	agent := &recordingAgent{}
+
+	rec := sendChoicesRequest(t, agent, 3)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp models.OpenAIResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Len(t, resp.Choices, 3)
+
+	assert.Len(t, agent.messages, 3)
+	messageIDs := map[string]bool{}
+	for _, msg := range agent.messages {
+		assert.Equal(t, "conversation-1", *msg.ContextId)
+		messageIDs[msg.MessageId] = true
+	}
+	assert.Len(t, messageIDs, 3, "every choice needs a distinct message ID")
+
+	for i, choice := range resp.Choices {
+		assert.Equal(t, i, choice.Index)
+		assert.Contains(t, choice.Message.Content, "answer to ")
+	}
+	assert.NotEqual(t, resp.Choices[0].Message.Content, resp.Choices[1].Message.Content)
+}
+
+func TestChatCompletions_MultipleChoicesFailure(t *testing.T) {
+	rec := sendChoicesRequest(t, &recordingAgent{fail: true}, 2)
+
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+}
+
+func TestChatCompletions_TooManyChoices(t *testing.T) {
+	agent := &recordingAgent{}
+
+	rec := sendChoicesRequest(t, agent, 11)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Empty(t, agent.messages)
+}
@@ -0,0 +1,73 @@
+package openaia2a
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers announcing the deprecation (RFC 9745) and removal (RFC 8594) of a model
+const (
+	deprecationHeader = "Deprecation"
+	sunsetHeader      = "Sunset"
+)
+
+// parseDeprecationDate accepts an RFC 3339 timestamp or a plain date (midnight UTC)
+func parseDeprecationDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// deprecationDates returns the parsed deprecated_at and sunset dates of an agent.
+// Unset dates are returned as zero times.
+func deprecationDates(agent AgentInfo) (deprecatedAt, sunset time.Time, err error) {
+	if deprecatedAt, err = parseDeprecationDate(agent.DeprecatedAt); err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("agent %s: invalid deprecated_at '%s'", agent.ModelID, agent.DeprecatedAt)
+	}
+	if sunset, err = parseDeprecationDate(agent.Sunset); err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("agent %s: invalid sunset '%s'", agent.ModelID, agent.Sunset)
+	}
+	return deprecatedAt, sunset, nil
+}
+
+// validateDeprecations checks the deprecation dates of all agents
+func validateDeprecations(agents []AgentInfo) error {
+	for _, agent := range agents {
+		deprecatedAt, sunset, err := deprecationDates(agent)
+		if err != nil {
+			return err
+		}
+		if !deprecatedAt.IsZero() && !sunset.IsZero() && sunset.Before(deprecatedAt) {
+			return fmt.Errorf("agent %s: sunset must not be before deprecated_at", agent.ModelID)
+		}
+	}
+	return nil
+}
+
+// unixOrZero returns the Unix timestamp of t, or 0 for the zero time so it is omitted from JSON
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// setDeprecationHeaders announces the deprecation schedule of the agent serving a request
+func setDeprecationHeaders(header http.Header, agent AgentInfo) {
+	deprecatedAt, sunset, err := deprecationDates(agent)
+	if err != nil {
+		return
+	}
+	if !deprecatedAt.IsZero() {
+		header.Set(deprecationHeader, "@"+strconv.FormatInt(deprecatedAt.Unix(), 10))
+	}
+	if !sunset.IsZero() {
+		header.Set(sunsetHeader, sunset.UTC().Format(http.TimeFormat))
+	}
+}
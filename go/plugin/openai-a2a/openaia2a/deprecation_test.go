@@ -0,0 +1,87 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newDeprecationHandler(t *testing.T) http.Handler {
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "current-agent", "url": "http://localhost:8001"},
+				map[string]interface{}{
+					"model_id":      "legacy-agent",
+					"url":           "http://localhost:8002",
+					"deprecated_at": "2025-01-01",
+					"sunset":        "2025-06-30T12:00:00Z",
+				},
+			},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, &MockHandler{
+		Response: []byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"message","messageId":"m1","role":"agent","parts":[{"kind":"text","text":"ok"}]}}`),
+	})
+	assert.NoError(t, err)
+	return handler
+}
+
+func TestValidateDeprecations(t *testing.T) {
+	tests := []struct {
+		name    string
+		agent   AgentInfo
+		wantErr bool
+	}{
+		{name: "no dates", agent: AgentInfo{ModelID: "a"}},
+		{name: "date and timestamp", agent: AgentInfo{ModelID: "a", DeprecatedAt: "2025-01-01", Sunset: "2025-06-30T12:00:00Z"}},
+		{name: "invalid date", agent: AgentInfo{ModelID: "a", Sunset: "next summer"}, wantErr: true},
+		{name: "sunset before deprecation", agent: AgentInfo{ModelID: "a", DeprecatedAt: "2025-06-01", Sunset: "2025-01-01"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDeprecations([]AgentInfo{tt.agent})
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestModels_DeprecationExtension(t *testing.T) {
+	handler := newDeprecationHandler(t)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/models", nil))
+
+	var resp models.OpenAIModelsResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Len(t, resp.Data, 2)
+	assert.Zero(t, resp.Data[0].DeprecatedAt)
+	assert.Zero(t, resp.Data[0].Sunset)
+	assert.Equal(t, int64(1735689600), resp.Data[1].DeprecatedAt)
+	assert.Equal(t, int64(1751284800), resp.Data[1].Sunset)
+}
+
+func TestChatCompletions_SunsetHeaders(t *testing.T) {
+	handler := newDeprecationHandler(t)
+
+	rec := sendChatCompletion(handler, "legacy-agent", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "@1735689600", rec.Header().Get("Deprecation"))
+	assert.Equal(t, "Mon, 30 Jun 2025 12:00:00 GMT", rec.Header().Get("Sunset"))
+
+	rec = sendChatCompletion(handler, "current-agent", "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Deprecation"))
+	assert.Empty(t, rec.Header().Get("Sunset"))
+}
@@ -0,0 +1,183 @@
+package openaia2a
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/capture"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+	"github.com/go-http-utils/headers"
+)
+
+// Diagnostics endpoints
+const (
+	debugGoroutinesPath = "/__debug/goroutines"
+	debugPoolsPath      = "/__debug/pools"
+)
+
+// Kinds of tracked activities
+const (
+	activityStreaming = "streaming_sessions"
+	activityPollers   = "pollers"
+)
+
+const defaultLeakAge = 10 * time.Minute
+
+// DiagnosticsConfig exposes goroutine and buffer counters at /__debug/goroutines and /__debug/pools.
+// Activities running longer than LeakAge, e.g. streams of agents that never end their tasks,
+// are reported as suspected leaks. If admin endpoints are configured, the admin keys are required.
+type DiagnosticsConfig struct {
+	LeakAge string `json:"leak_age,omitempty"`
+}
+
+// diagnostics serves the debug endpoints
+type diagnostics struct {
+	leakAge time.Duration
+	now     func() time.Time
+}
+
+// newDiagnostics validates the configuration. A nil config disables the endpoints.
+func newDiagnostics(cfg *DiagnosticsConfig) (*diagnostics, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	d := &diagnostics{leakAge: defaultLeakAge, now: time.Now}
+	if cfg.LeakAge != "" {
+		var err error
+		if d.leakAge, err = time.ParseDuration(cfg.LeakAge); err != nil || d.leakAge <= 0 {
+			return nil, fmt.Errorf("invalid leak_age '%s'", cfg.LeakAge)
+		}
+	}
+	return d, nil
+}
+
+// activityTracker counts long-running request activities, i.e. streaming sessions and task
+// pollers, which stay alive as long as agents keep them open
+type activityTracker struct {
+	mu      sync.Mutex
+	next    uint64
+	active  map[uint64]activity
+	started map[string]int64
+	ended   map[string]int64
+}
+
+// activity is a running session or poller
+type activity struct {
+	kind  string
+	label string
+	since time.Time
+}
+
+// activities tracks the activities of all plugin instances, like the capture buffer counters
+var activities = newActivityTracker()
+
+func newActivityTracker() *activityTracker {
+	return &activityTracker{
+		active:  make(map[uint64]activity),
+		started: make(map[string]int64),
+		ended:   make(map[string]int64),
+	}
+}
+
+// begin records the start of an activity and returns the function ending it
+func (t *activityTracker) begin(kind, label string) func() {
+	t.mu.Lock()
+	t.next++
+	id := t.next
+	t.active[id] = activity{kind: kind, label: label, since: time.Now()}
+	t.started[kind]++
+	t.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			delete(t.active, id)
+			t.ended[kind]++
+			t.mu.Unlock()
+		})
+	}
+}
+
+// ActivityCounts are the counters of one kind of activity
+type ActivityCounts struct {
+	Active  int   `json:"active"`
+	Started int64 `json:"started"`
+	Ended   int64 `json:"ended"`
+}
+
+// SuspectedLeak is an activity running longer than the leak age
+type SuspectedLeak struct {
+	Kind       string    `json:"kind"`
+	Label      string    `json:"label"`
+	Since      time.Time `json:"since"`
+	AgeSeconds int64     `json:"age_seconds"`
+}
+
+// GoroutineReport is served at /__debug/goroutines
+type GoroutineReport struct {
+	Goroutines     int                       `json:"goroutines"`
+	Activities     map[string]ActivityCounts `json:"activities"`
+	SuspectedLeaks []SuspectedLeak           `json:"suspected_leaks"`
+}
+
+// report counts the activities and lists those started before now minus leakAge, oldest first
+func (t *activityTracker) report(now time.Time, leakAge time.Duration) GoroutineReport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	report := GoroutineReport{
+		Goroutines:     runtime.NumGoroutine(),
+		Activities:     make(map[string]ActivityCounts),
+		SuspectedLeaks: []SuspectedLeak{},
+	}
+	for _, kind := range []string{activityStreaming, activityPollers} {
+		report.Activities[kind] = ActivityCounts{Started: t.started[kind], Ended: t.ended[kind]}
+	}
+	for _, a := range t.active {
+		counts := report.Activities[a.kind]
+		counts.Active++
+		report.Activities[a.kind] = counts
+		if age := now.Sub(a.since); age > leakAge {
+			report.SuspectedLeaks = append(report.SuspectedLeaks, SuspectedLeak{Kind: a.kind, Label: a.label, Since: a.since.UTC(), AgeSeconds: int64(age.Seconds())})
+		}
+	}
+	sort.Slice(report.SuspectedLeaks, func(i, j int) bool {
+		return report.SuspectedLeaks[i].Since.Before(report.SuspectedLeaks[j].Since)
+	})
+	return report
+}
+
+// handles reports whether the request targets a diagnostics endpoint
+func (d *diagnostics) handles(req *http.Request) bool {
+	return d != nil && (req.URL.Path == debugGoroutinesPath || req.URL.Path == debugPoolsPath)
+}
+
+// serveHTTP serves the counters. /__debug/goroutines?stacks=1 dumps the stacks of all goroutines.
+func (d *diagnostics) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		writeError(w, req, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var body interface{}
+	switch {
+	case req.URL.Path == debugPoolsPath:
+		body = map[string]capture.Stats{"capture_buffers": capture.ReadStats()}
+	case req.URL.Query().Get("stacks") == "1":
+		w.Header().Set(headers.ContentType, "text/plain; charset=utf-8")
+		if err := pprof.Lookup("goroutine").WriteTo(w, 1); err != nil {
+			logger.Error("failed to write goroutine stacks:", err)
+		}
+		return
+	default:
+		body = activities.report(d.now(), d.leakAge)
+	}
+	if err := pluginkit.WriteJSON(w, http.StatusOK, body); err != nil {
+		logger.Error("failed to write response:", err)
+	}
+}
@@ -0,0 +1,165 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/capture"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/testagent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// diagnosticsAdmin configures the admin endpoints the diagnostics endpoints require
+var diagnosticsAdmin = map[string]interface{}{
+	"keys": []interface{}{map[string]interface{}{"id": "ops", "secret": "admin-token"}},
+}
+
+func debugRequest(handler http.Handler, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func readGoroutineReport(t *testing.T, handler http.Handler) GoroutineReport {
+	rec := debugRequest(handler, debugGoroutinesPath, "admin-token")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var report GoroutineReport
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	return report
+}
+
+func TestDiagnostics_CountsStreamsAndCaptureBuffers(t *testing.T) {
+	agent := testagent.New(testagent.Options{Handler: func(models.Message) testagent.Response {
+		return testagent.Response{Chunks: []string{"Sunny"}}
+	}})
+	t.Cleanup(agent.Close)
+
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "weather-agent", "url": agent.URL},
+			},
+			"streaming":   map[string]interface{}{},
+			"admin":       diagnosticsAdmin,
+			"diagnostics": map[string]interface{}{},
+		},
+	}
+	mockHandler := &MockHandler{}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	require.NoError(t, err)
+
+	before := readGoroutineReport(t, handler)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat/completions", strings.NewReader(`{"model":"weather-agent","stream":true,"messages":[{"role":"user","content":"Weather?"}]}`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	after := readGoroutineReport(t, handler)
+	assert.Positive(t, after.Goroutines)
+	streams := after.Activities[activityStreaming]
+	assert.Equal(t, before.Activities[activityStreaming].Started+1, streams.Started)
+	assert.Equal(t, before.Activities[activityStreaming].Ended+1, streams.Ended)
+	assert.Contains(t, after.Activities, activityPollers)
+	assert.Empty(t, after.SuspectedLeaks)
+
+	rec = debugRequest(handler, debugPoolsPath, "admin-token")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var pools map[string]capture.Stats
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &pools))
+	assert.Contains(t, pools, "capture_buffers")
+
+	rec = debugRequest(handler, debugGoroutinesPath+"?stacks=1", "admin-token")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "goroutine profile:")
+
+	req := httptest.NewRequest(http.MethodPost, debugPoolsPath, nil)
+	req.Header.Set("Authorization", "Bearer admin-token")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Nil(t, mockHandler.ReceivedRequest)
+}
+
+func TestDiagnostics_RequiresAdminToken(t *testing.T) {
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"admin":       diagnosticsAdmin,
+			"diagnostics": map[string]interface{}{"leak_age": "1m"},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, &MockHandler{})
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusUnauthorized, debugRequest(handler, debugPoolsPath, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, debugRequest(handler, debugGoroutinesPath, "wrong").Code)
+	assert.Equal(t, http.StatusOK, debugRequest(handler, debugPoolsPath, "admin-token").Code)
+}
+
+func TestRegisterHandlers_DiagnosticsRequireAdmin(t *testing.T) {
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"diagnostics": map[string]interface{}{},
+		},
+	}
+
+	_, err := module.registerHandlers(context.Background(), extraConfig, &MockHandler{})
+
+	assert.ErrorContains(t, err, "diagnostics endpoints require admin endpoints")
+}
+
+func TestDiagnostics_Disabled(t *testing.T) {
+	mockHandler := &MockHandler{}
+	handler, err := module.registerHandlers(context.Background(), map[string]interface{}{"openai_a2a_config": map[string]interface{}{}}, mockHandler)
+	require.NoError(t, err)
+
+	debugRequest(handler, debugGoroutinesPath, "")
+	assert.NotNil(t, mockHandler.ReceivedRequest, "debug paths pass through when diagnostics are disabled")
+}
+
+func TestActivityTracker_ReportsSuspectedLeaks(t *testing.T) {
+	tracker := newActivityTracker()
+	endStream := tracker.begin(activityStreaming, "slow-agent")
+	endPoller := tracker.begin(activityPollers, "task-1")
+	tracker.begin(activityPollers, "task-2")()
+	endPoller()
+	endPoller()
+
+	report := tracker.report(time.Now(), time.Minute)
+	assert.Equal(t, ActivityCounts{Active: 1, Started: 1}, report.Activities[activityStreaming])
+	assert.Equal(t, ActivityCounts{Started: 2, Ended: 2}, report.Activities[activityPollers], "ending twice counts once")
+	assert.Empty(t, report.SuspectedLeaks)
+
+	report = tracker.report(time.Now().Add(time.Hour), time.Minute)
+	require.Len(t, report.SuspectedLeaks, 1)
+	assert.Equal(t, activityStreaming, report.SuspectedLeaks[0].Kind)
+	assert.Equal(t, "slow-agent", report.SuspectedLeaks[0].Label)
+	assert.GreaterOrEqual(t, report.SuspectedLeaks[0].AgeSeconds, int64(3599))
+
+	endStream()
+	assert.Empty(t, tracker.report(time.Now().Add(time.Hour), time.Minute).SuspectedLeaks)
+}
+
+func TestNewDiagnostics(t *testing.T) {
+	d, err := newDiagnostics(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, d)
+
+	d, err = newDiagnostics(&DiagnosticsConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, defaultLeakAge, d.leakAge)
+
+	for _, leakAge := range []string{"soon", "0s", "-1m"} {
+		_, err := newDiagnostics(&DiagnosticsConfig{LeakAge: leakAge})
+		assert.ErrorContains(t, err, "invalid leak_age", leakAge)
+	}
+}
@@ -0,0 +1,106 @@
+package openaia2a
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// agentDiscovery resolves the agents of a registry instead of reading an agents document
+type agentDiscovery interface {
+	discover(ctx context.Context) ([]AgentInfo, error)
+	// next returns the delay until the agents are discovered again
+	next() time.Duration
+}
+
+// discoveredEndpoint is an agent URL found by a discovery. Endpoints of the same model with the
+// lowest priority are load balanced by weight; those with a higher priority are backups.
+type discoveredEndpoint struct {
+	agent    AgentInfo
+	priority int
+	weight   int
+}
+
+// mergeEndpoints returns one agent per model, sorted by model ID. The settings of the agent are
+// taken from its first endpoint in URL order.
+func mergeEndpoints(endpoints []discoveredEndpoint) ([]AgentInfo, error) {
+	byModel := make(map[string][]discoveredEndpoint)
+	for _, e := range endpoints {
+		byModel[e.agent.ModelID] = append(byModel[e.agent.ModelID], e)
+	}
+
+	agents := make([]AgentInfo, 0, len(byModel))
+	for model, endpoints := range byModel {
+		sort.Slice(endpoints, func(i, j int) bool {
+			if endpoints[i].priority != endpoints[j].priority {
+				return endpoints[i].priority < endpoints[j].priority
+			}
+			return endpoints[i].agent.URL < endpoints[j].agent.URL
+		})
+		agent := endpoints[0].agent
+		if len(endpoints) == 1 {
+			agents = append(agents, agent)
+			continue
+		}
+
+		var replicas []Replica
+		for _, e := range endpoints {
+			if e.agent.URL == "" {
+				return nil, fmt.Errorf("model %s is registered more than once, but not by URL", model)
+			}
+			if e.priority == endpoints[0].priority {
+				replicas = append(replicas, Replica{URL: e.agent.URL, Weight: e.weight})
+			}
+		}
+		if len(replicas) > 1 {
+			agent.URL = ""
+			agent.LoadBalancing = &LoadBalancingConfig{Replicas: replicas}
+		}
+		agents = append(agents, agent)
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].ModelID < agents[j].ModelID })
+	return agents, nil
+}
+
+// agentFromMeta builds the agent at host:port from key=value metadata, e.g. of TXT records or
+// service registrations. model and scheme apply unless the metadata sets model_id and scheme.
+func agentFromMeta(meta map[string]string, model, scheme, host string, port int) (AgentInfo, error) {
+	if meta["model_id"] != "" {
+		model = meta["model_id"]
+	}
+	if meta["scheme"] != "" {
+		scheme = meta["scheme"]
+	}
+	agent := AgentInfo{
+		ModelID:      model,
+		URL:          fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(host, strconv.Itoa(port)), meta["path"]),
+		OwnedBy:      meta["owned_by"],
+		Description:  meta["description"],
+		HealthPath:   meta["health_path"],
+		Transport:    meta["transport"],
+		Tags:         splitList(meta["tags"]),
+		Capabilities: splitList(meta["capabilities"]),
+	}
+	if raw := meta["max_context_length"]; raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return AgentInfo{}, fmt.Errorf("invalid max_context_length '%s'", raw)
+		}
+		agent.MaxContextLength = n
+	}
+	return agent, nil
+}
+
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
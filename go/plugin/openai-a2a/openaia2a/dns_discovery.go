@@ -0,0 +1,128 @@
+package openaia2a
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/dnssd"
+)
+
+const defaultDNSMinRefresh = 5 * time.Second
+
+// DNSSource discovers agents from the SRV records of a service name, e.g.
+// _a2a._tcp.agents.example.com. Every record is an agent URL; the TXT records of its target
+// carry the model ID and further metadata as key=value strings.
+type DNSSource struct {
+	Name string `json:"name"`
+	// Nameservers are host:port addresses; empty uses /etc/resolv.conf
+	Nameservers []string `json:"nameservers,omitempty"`
+	// Scheme of the agent URLs unless set by TXT (default http)
+	Scheme string `json:"scheme,omitempty"`
+	// MinRefresh bounds the refresh of records with short TTLs (default 5s)
+	MinRefresh string `json:"min_refresh,omitempty"`
+}
+
+// dnsDiscovery resolves agents from DNS and refreshes them when their records expire
+type dnsDiscovery struct {
+	name       string
+	scheme     string
+	resolver   *dnssd.Resolver
+	minRefresh time.Duration
+	retry      time.Duration
+	delay      time.Duration
+}
+
+func newDNSDiscovery(cfg *DNSSource, retry time.Duration) (*dnsDiscovery, error) {
+	if cfg.Name == "" {
+		return nil, errors.New("dns requires a name")
+	}
+	d := &dnsDiscovery{
+		name:       cfg.Name,
+		scheme:     "http",
+		resolver:   &dnssd.Resolver{Servers: cfg.Nameservers},
+		minRefresh: defaultDNSMinRefresh,
+		retry:      retry,
+		delay:      retry,
+	}
+	switch cfg.Scheme {
+	case "":
+	case "http", "https":
+		d.scheme = cfg.Scheme
+	default:
+		return nil, fmt.Errorf("unsupported dns scheme '%s', use http or https", cfg.Scheme)
+	}
+	for _, server := range cfg.Nameservers {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			return nil, fmt.Errorf("invalid nameserver '%s', expected host:port", server)
+		}
+	}
+	if cfg.MinRefresh != "" {
+		minRefresh, err := time.ParseDuration(cfg.MinRefresh)
+		if err != nil || minRefresh <= 0 {
+			return nil, fmt.Errorf("invalid min_refresh '%s'", cfg.MinRefresh)
+		}
+		d.minRefresh = minRefresh
+	}
+	return d, nil
+}
+
+func (d *dnsDiscovery) next() time.Duration {
+	return d.delay
+}
+
+// discover resolves the SRV records and the TXT records of their targets. Without model_id,
+// the first label of the target is the model ID, e.g. weather-agent for
+// weather-agent.agents.example.com. A missing SRV name is an error, so a typo or an outage of
+// the zone does not remove all agents.
+func (d *dnsDiscovery) discover(ctx context.Context) ([]AgentInfo, error) {
+	d.delay = d.retry
+	records, ttl, err := d.resolver.LookupSRV(ctx, d.name)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoints []discoveredEndpoint
+	metadata := make(map[string]map[string]string)
+	for _, record := range records {
+		meta, ok := metadata[record.Target]
+		if !ok {
+			values, txtTTL, err := d.resolver.LookupTXT(ctx, record.Target)
+			if err != nil && !errors.Is(err, dnssd.ErrNotFound) {
+				return nil, err
+			}
+			if err == nil && txtTTL < ttl {
+				ttl = txtTTL
+			}
+			meta = parseTXT(values)
+			metadata[record.Target] = meta
+		}
+		model, _, _ := strings.Cut(record.Target, ".")
+		agent, err := agentFromMeta(meta, model, d.scheme, record.Target, int(record.Port))
+		if err != nil {
+			return nil, fmt.Errorf("TXT record of %s: %w", record.Target, err)
+		}
+		endpoints = append(endpoints, discoveredEndpoint{agent: agent, priority: int(record.Priority), weight: int(record.Weight)})
+	}
+	agents, err := mergeEndpoints(endpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	d.delay = max(ttl, d.minRefresh)
+	return agents, nil
+}
+
+// parseTXT parses key=value strings. Keys are case-insensitive; strings without = are ignored.
+func parseTXT(values []string) map[string]string {
+	meta := make(map[string]string, len(values))
+	for _, v := range values {
+		if key, value, ok := strings.Cut(v, "="); ok {
+			meta[strings.ToLower(strings.TrimSpace(key))] = value
+		}
+	}
+	return meta
+}
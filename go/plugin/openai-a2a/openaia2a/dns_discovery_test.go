@@ -0,0 +1,93 @@
+package openaia2a
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/dnssd/dnstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgentStore_DNSDiscovery(t *testing.T) {
+	server := dnstest.NewServer()
+	defer server.Close()
+	service := "_a2a._tcp.agents.example.com"
+	server.SetSRV(service, 300,
+		dnstest.SRV{Target: "weather-1.agents.example.com", Port: 8000, Priority: 10, Weight: 3},
+		dnstest.SRV{Target: "weather-2.agents.example.com", Port: 8000, Priority: 10, Weight: 1},
+		dnstest.SRV{Target: "weather-dr.agents.example.com", Port: 8000, Priority: 20, Weight: 1},
+		dnstest.SRV{Target: "news-agent.agents.example.com", Port: 9000, Priority: 10},
+	)
+	for _, target := range []string{"weather-1.agents.example.com", "weather-2.agents.example.com", "weather-dr.agents.example.com"} {
+		server.SetTXT(target, 60, "model_id=weather-agent", "path=/a2a", "owned_by=weather-team", "tags=forecast, europe")
+	}
+
+	source := &AgentsSource{DNS: &DNSSource{Name: service, Nameservers: []string{server.Addr}}, RefreshInterval: "10s"}
+	store, err := newAgentStore([]AgentInfo{{ModelID: "static-agent", URL: "http://static:8000"}}, source, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, []AgentInfo{
+		{ModelID: "news-agent", URL: "http://news-agent.agents.example.com:9000"},
+		{ModelID: "weather-agent", OwnedBy: "weather-team", Tags: []string{"forecast", "europe"}, LoadBalancing: &LoadBalancingConfig{Replicas: []Replica{
+			{URL: "http://weather-1.agents.example.com:8000/a2a", Weight: 3},
+			{URL: "http://weather-2.agents.example.com:8000/a2a", Weight: 1},
+		}}},
+	}, store.agents(), "the backup with a higher priority is not used")
+	assert.Equal(t, time.Minute, store.refreshDelay(time.Hour), "the records are refreshed with the smallest TTL")
+	balancer := store.load().balancers["weather-agent"]
+
+	// Removed records remove their agents, unchanged replicas keep their balancer
+	server.SetSRV(service, 300,
+		dnstest.SRV{Target: "weather-1.agents.example.com", Port: 8000, Priority: 10, Weight: 3},
+		dnstest.SRV{Target: "weather-2.agents.example.com", Port: 8000, Priority: 10, Weight: 1},
+	)
+	require.NoError(t, store.reload(context.Background()))
+	assert.Len(t, store.agents(), 1)
+	assert.Same(t, balancer, store.load().balancers["weather-agent"])
+
+	// A missing service keeps the current agents and is retried on the refresh interval
+	server.SetSRV(service, 300)
+	assert.Error(t, store.reload(context.Background()))
+	assert.Len(t, store.agents(), 1)
+	assert.Equal(t, 10*time.Second, store.refreshDelay(time.Hour))
+}
+
+func TestDNSDiscovery_MinRefreshAndScheme(t *testing.T) {
+	server := dnstest.NewServer()
+	defer server.Close()
+	server.SetSRV("_a2a._tcp.agents.example.com", 1, dnstest.SRV{Target: "weather-agent.agents.example.com", Port: 8443})
+	server.SetTXT("weather-agent.agents.example.com", 1, "transport=GRPC", "max_context_length=8192")
+
+	discovery, err := newDNSDiscovery(&DNSSource{Name: "_a2a._tcp.agents.example.com", Nameservers: []string{server.Addr}, Scheme: "https", MinRefresh: "15s"}, time.Minute)
+	require.NoError(t, err)
+	agents, err := discovery.discover(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []AgentInfo{{ModelID: "weather-agent", URL: "https://weather-agent.agents.example.com:8443", Transport: "GRPC", MaxContextLength: 8192}}, agents)
+	assert.Equal(t, 15*time.Second, discovery.next())
+
+	server.SetTXT("weather-agent.agents.example.com", 1, "max_context_length=large")
+	_, err = discovery.discover(context.Background())
+	assert.ErrorContains(t, err, "TXT record of weather-agent.agents.example.com: invalid max_context_length 'large'")
+}
+
+func TestNewAgentStore_InvalidDNSSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		source AgentsSource
+		want   string
+	}{
+		{name: "path and dns", source: AgentsSource{Path: "/etc/agents.yaml", DNS: &DNSSource{Name: "_a2a._tcp.example"}}, want: "exactly one of path, url, dns or registry"},
+		{name: "missing name", source: AgentsSource{DNS: &DNSSource{}}, want: "dns requires a name"},
+		{name: "scheme", source: AgentsSource{DNS: &DNSSource{Name: "_a2a._tcp.example", Scheme: "ftp"}}, want: "unsupported dns scheme 'ftp'"},
+		{name: "nameserver", source: AgentsSource{DNS: &DNSSource{Name: "_a2a._tcp.example", Nameservers: []string{"10.96.0.10"}}}, want: "invalid nameserver '10.96.0.10'"},
+		{name: "min refresh", source: AgentsSource{DNS: &DNSSource{Name: "_a2a._tcp.example", MinRefresh: "0s"}}, want: "invalid min_refresh '0s'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newAgentStore(nil, &tt.source, nil)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
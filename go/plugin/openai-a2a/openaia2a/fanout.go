@@ -0,0 +1,199 @@
+package openaia2a
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aclient"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/upstream"
+)
+
+// Fan-out strategy constants
+const (
+	fanOutFirst   = "first"
+	fanOutAll     = "all"
+	fanOutFastest = "fastest"
+)
+
+// upstreamClient sends A2A requests directly to agent URLs when a request
+// cannot be routed through a single KrakenD backend (e.g. ensemble fan-out).
+// It is built from the upstream configuration when the handlers are registered.
+var upstreamClient = &http.Client{Transport: upstreamTransport}
+
+// upstreamTransport connects to agents with their TLS configuration
+var upstreamTransport, _ = upstream.NewTransport(nil)
+
+// agentHeaders are the client headers forwarded on direct agent calls: conversation and request
+// IDs, trace context, the language and the identity headers of the client-cert plugin.
+// Credentials, cookies and transport headers of the client never reach agents.
+var agentHeaders = []string{
+	logging.ConversationIDHeader,
+	logging.RequestIDHeader,
+	"Traceparent",
+	"Tracestate",
+	"Baggage",
+	"Accept-Language",
+	"X-Client-Cert-SAN",
+	"X-Client-Cert-SPIFFE-ID",
+	"X-Client-Cert-Subject",
+	"X-Client-Cert-Fingerprint",
+}
+
+// agentHeader returns the headers of req that direct agent calls forward
+func agentHeader(req *http.Request) http.Header {
+	header := make(http.Header, len(agentHeaders))
+	for _, name := range agentHeaders {
+		if values := req.Header.Values(name); len(values) > 0 {
+			header[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+		}
+	}
+	return header
+}
+
+// fanOutResult is the outcome of a single ensemble member call
+type fanOutResult struct {
+	index   int
+	content string
+	err     error
+}
+
+// isValidFanOutStrategy checks if a fan-out strategy is known (case-insensitive)
+func isValidFanOutStrategy(strategy string) bool {
+	normalized := strings.ToLower(strategy)
+	return normalized == fanOutFirst ||
+		normalized == fanOutAll ||
+		normalized == fanOutFastest
+}
+
+// resolveEnsemble validates the ensemble configuration of an agent and returns its routing information.
+// An empty strategy defaults to "first".
+func resolveEnsemble(agent AgentInfo) (*ModelInfo, error) {
+	ensemble := agent.Ensemble
+	if len(ensemble.URLs) == 0 {
+		return nil, &AgentResolutionError{
+			Type:        "configuration_error",
+			InternalMsg: fmt.Sprintf("ensemble %s has no URLs configured", agent.ModelID),
+			ClientMsg:   "model is not available",
+		}
+	}
+
+	strategy := strings.ToLower(ensemble.Strategy)
+	if strategy == "" {
+		strategy = fanOutFirst
+	}
+	if !isValidFanOutStrategy(strategy) {
+		return nil, &AgentResolutionError{
+			Type:        "configuration_error",
+			InternalMsg: fmt.Sprintf("ensemble %s has unknown strategy '%s'", agent.ModelID, ensemble.Strategy),
+			ClientMsg:   "model is not available",
+		}
+	}
+
+	for _, target := range ensemble.URLs {
+		if _, err := url.Parse(target); err != nil || target == "" {
+			return nil, &AgentResolutionError{
+				Type:        "configuration_error",
+				InternalMsg: fmt.Sprintf("invalid ensemble URL '%s' for %s: %v", target, agent.ModelID, err),
+				ClientMsg:   "model is not available",
+			}
+		}
+	}
+
+	return &ModelInfo{
+		ModelID: agent.ModelID,
+		Path:    "/" + agent.ModelID,
+		URL:     agent.URL,
+		Ensemble: &EnsembleConfig{
+			URLs:     ensemble.URLs,
+			Strategy: strategy,
+		},
+	}, nil
+}
+
+// fanOut sends the A2A request body to all ensemble URLs concurrently and combines the answers:
+//   - first: content of the first URL in configured order that answers successfully
+//   - fastest: content of whichever URL answers successfully first
+//   - all: contents of all successful answers in configured order, separated by blank lines
+//
+// An error is returned only if no ensemble member answered successfully.
+func fanOut(ctx context.Context, ensemble EnsembleConfig, body []byte, header http.Header) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make(chan fanOutResult, len(ensemble.URLs))
+	for i, target := range ensemble.URLs {
+		go func(i int, target string) {
+			resp, err := sendA2A(ctx, target, body, header)
+			if err != nil {
+				results <- fanOutResult{index: i, err: fmt.Errorf("%s: %w", target, err)}
+				return
+			}
+			results <- fanOutResult{index: i, content: extractA2AContent(resp)}
+		}(i, target)
+	}
+
+	collected := make([]*fanOutResult, len(ensemble.URLs))
+	var errs []error
+	for range ensemble.URLs {
+		result := <-results
+		collected[result.index] = &result
+		if result.err != nil {
+			logger.Warning("ensemble member failed: ", result.err)
+			errs = append(errs, result.err)
+		} else if ensemble.Strategy == fanOutFastest {
+			return result.content, nil
+		}
+
+		if ensemble.Strategy == fanOutFirst {
+			if content, done := firstInOrder(collected); done {
+				return content, nil
+			}
+		}
+	}
+
+	if ensemble.Strategy == fanOutAll {
+		var parts []string
+		for _, result := range collected {
+			if result.err == nil {
+				parts = append(parts, result.content)
+			}
+		}
+		if len(parts) > 0 {
+			return strings.Join(parts, "\n\n"), nil
+		}
+	}
+
+	return "", fmt.Errorf("all %d ensemble members failed: %w", len(ensemble.URLs), errors.Join(errs...))
+}
+
+// firstInOrder returns the content of the lowest-indexed successful result,
+// once every result before it is known to have failed.
+func firstInOrder(collected []*fanOutResult) (string, bool) {
+	for _, result := range collected {
+		if result == nil {
+			return "", false
+		}
+		if result.err == nil {
+			return result.content, true
+		}
+	}
+	return "", false
+}
+
+// sendA2A posts an A2A JSON-RPC request to an agent URL and parses the successful response.
+// JSON-RPC errors of the agent are returned as *a2aclient.RPCError.
+func sendA2A(ctx context.Context, target string, body []byte, header http.Header) (models.SendMessageSuccessResponse, error) {
+	var a2aResp models.SendMessageSuccessResponse
+	client, err := a2aclient.New(target, a2aclient.Options{HTTPClient: upstreamClient, Header: header})
+	if err != nil {
+		return a2aResp, err
+	}
+	err = client.Do(ctx, body, &a2aResp.Result)
+	return a2aResp, err
+}
@@ -0,0 +1,157 @@
+package openaia2a
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// newA2AAgent starts a test agent that answers message/send with the given text after an optional delay
+func newA2AAgent(t *testing.T, text string, delay time.Duration, statusCode int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		if statusCode != http.StatusOK {
+			w.WriteHeader(statusCode)
+			return
+		}
+		resp := models.SendMessageSuccessResponse{
+			Jsonrpc: "2.0",
+			Id:      1,
+			Result: models.SendMessageSuccessResponseResult{
+				Kind: "task",
+				Artifacts: []models.Artifact{
+					{ArtifactId: "a", Parts: []models.ArtifactPartsElem{models.TextPart{Kind: "text", Text: text}}},
+				},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRoutingTable_ResolveEnsemble(t *testing.T) {
+	agents := []AgentInfo{
+		{
+			ModelID:  "team/ensemble",
+			Ensemble: &EnsembleConfig{URLs: []string{"http://a:8000", "http://b:8000"}},
+		},
+	}
+
+	modelInfo, err := newRoutingTable(agents, nil).resolve("team/ensemble")
+
+	assert.NoError(t, err)
+	assert.NotNil(t, modelInfo.Ensemble)
+	assert.Equal(t, fanOutFirst, modelInfo.Ensemble.Strategy)
+	assert.Len(t, modelInfo.Ensemble.URLs, 2)
+}
+
+func TestRoutingTable_ResolveEnsembleInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		ensemble EnsembleConfig
+	}{
+		{name: "no URLs", ensemble: EnsembleConfig{Strategy: fanOutAll}},
+		{name: "unknown strategy", ensemble: EnsembleConfig{URLs: []string{"http://a:8000"}, Strategy: "random"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agents := []AgentInfo{{ModelID: "ensemble", Ensemble: &tt.ensemble}}
+
+			_, err := newRoutingTable(agents, nil).resolve("ensemble")
+
+			resErr, ok := err.(*AgentResolutionError)
+			assert.True(t, ok)
+			assert.Equal(t, "configuration_error", resErr.Type)
+		})
+	}
+}
+
+func TestFanOut_Strategies(t *testing.T) {
+	slow := newA2AAgent(t, "slow answer", 200*time.Millisecond, http.StatusOK)
+	fast := newA2AAgent(t, "fast answer", 0, http.StatusOK)
+	broken := newA2AAgent(t, "", 0, http.StatusInternalServerError)
+
+	tests := []struct {
+		name     string
+		strategy string
+		urls     []string
+		want     string
+	}{
+		{name: "first waits for configured order", strategy: fanOutFirst, urls: []string{slow.URL, fast.URL}, want: "slow answer"},
+		{name: "first skips failed members", strategy: fanOutFirst, urls: []string{broken.URL, fast.URL}, want: "fast answer"},
+		{name: "fastest returns earliest answer", strategy: fanOutFastest, urls: []string{slow.URL, fast.URL}, want: "fast answer"},
+		{name: "all aggregates in configured order", strategy: fanOutAll, urls: []string{slow.URL, broken.URL, fast.URL}, want: "slow answer\n\nfast answer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := fanOut(context.Background(), EnsembleConfig{URLs: tt.urls, Strategy: tt.strategy}, []byte(`{}`), http.Header{})
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, content)
+		})
+	}
+}
+
+func TestFanOut_AllMembersFail(t *testing.T) {
+	broken := newA2AAgent(t, "", 0, http.StatusBadGateway)
+
+	_, err := fanOut(context.Background(), EnsembleConfig{URLs: []string{broken.URL, broken.URL}, Strategy: fanOutAll}, []byte(`{}`), http.Header{})
+
+	assert.Error(t, err)
+}
+
+func TestChatCompletions_EnsembleBypassesBackend(t *testing.T) {
+	first := newA2AAgent(t, "answer one", 0, http.StatusOK)
+	second := newA2AAgent(t, "answer two", 0, http.StatusOK)
+
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{
+					"model_id": "team/ensemble",
+					"ensemble": map[string]interface{}{
+						"urls":     []interface{}{first.URL, second.URL},
+						"strategy": "all",
+					},
+				},
+			},
+		},
+	}
+
+	mockHandler := &MockHandler{}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+
+	reqBody, _ := json.Marshal(models.OpenAIRequest{
+		Model:    "team/ensemble",
+		Messages: []models.OpenAIMessage{{Role: "user", Content: "Hello"}},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/chat/completions", bytes.NewReader(reqBody))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Nil(t, mockHandler.ReceivedRequest)
+
+	var openAIResp models.OpenAIResponse
+	respBody, _ := io.ReadAll(rec.Body)
+	assert.NoError(t, json.Unmarshal(respBody, &openAIResp))
+	assert.Equal(t, "answer one\n\nanswer two", openAIResp.Choices[0].Message.Content)
+}
@@ -0,0 +1,355 @@
+package openaia2a
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aclient"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/trustedproxy"
+)
+
+// agentCardPath is the well-known path of A2A agent cards
+const agentCardPath = a2aclient.AgentCardPath
+
+const (
+	defaultGatewayCardName     = "Agent Gateway"
+	defaultGatewayCardTimeout  = 2 * time.Second
+	defaultGatewayCardCacheTTL = time.Minute
+	defaultAgentIndexPath      = "/agents"
+	transportJSONRPC           = "JSONRPC"
+	transportOpenAI            = "OPENAI"
+)
+
+// GatewayCardConfig describes the gateway in the agent card served at /.well-known/agent-card.json.
+// Without url, the card points to the scheme and host the card was requested from. The agents are
+// also listed with their gateway URLs at index_path.
+type GatewayCardConfig struct {
+	Name        string                `json:"name"`
+	Description string                `json:"description"`
+	URL         string                `json:"url"`
+	Provider    *models.AgentProvider `json:"provider"`
+	Timeout     string                `json:"timeout"`
+	CacheTTL    string                `json:"cache_ttl"`
+	IndexPath   string                `json:"index_path"`
+}
+
+// gatewayCard synthesizes the agent card of the gateway and the agent index from the cards of its
+// agents. Agent cards are fetched concurrently and cached, like health probes.
+type gatewayCard struct {
+	agents      func() []AgentInfo
+	name        string
+	description string
+	url         string
+	provider    *models.AgentProvider
+	timeout     time.Duration
+	cacheTTL    time.Duration
+
+	indexPath string
+
+	mu     sync.Mutex
+	cards  []agentCard
+	expiry time.Time
+	now    func() time.Time
+	// fetching is closed when the running round of fetches is done
+	fetching chan struct{}
+}
+
+// newGatewayCard validates the configuration. A nil config disables the gateway card.
+func newGatewayCard(cfg *GatewayCardConfig, agents func() []AgentInfo) (*gatewayCard, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	gc := &gatewayCard{
+		agents:      agents,
+		name:        cfg.Name,
+		description: cfg.Description,
+		url:         strings.TrimSuffix(cfg.URL, "/"),
+		provider:    cfg.Provider,
+		timeout:     defaultGatewayCardTimeout,
+		cacheTTL:    defaultGatewayCardCacheTTL,
+		indexPath:   defaultAgentIndexPath,
+		now:         time.Now,
+	}
+	if gc.name == "" {
+		gc.name = defaultGatewayCardName
+	}
+	if gc.description == "" {
+		gc.description = "OpenAI-compatible and A2A gateway to the agents of the Agentic Layer"
+	}
+	if gc.url != "" {
+		if parsed, err := url.Parse(gc.url); err != nil || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid url '%s'", cfg.URL)
+		}
+	}
+	if cfg.IndexPath != "" {
+		if !strings.HasPrefix(cfg.IndexPath, "/") {
+			return nil, fmt.Errorf("index_path '%s' must start with /", cfg.IndexPath)
+		}
+		gc.indexPath = cfg.IndexPath
+	}
+	if cfg.Provider != nil && cfg.Provider.Organization == "" {
+		return nil, errors.New("provider organization is required")
+	}
+
+	var err error
+	if cfg.Timeout != "" {
+		if gc.timeout, err = time.ParseDuration(cfg.Timeout); err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+	}
+	if cfg.CacheTTL != "" {
+		if gc.cacheTTL, err = time.ParseDuration(cfg.CacheTTL); err != nil {
+			return nil, fmt.Errorf("invalid cache_ttl: %w", err)
+		}
+	}
+	return gc, nil
+}
+
+// handles reports whether the request targets the agent card of the gateway
+func (gc *gatewayCard) handles(req *http.Request) bool {
+	return gc != nil && req.Method == http.MethodGet && req.URL.Path == agentCardPath
+}
+
+// serveHTTP serves the agent card of the gateway
+func (gc *gatewayCard) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	log := logging.FromRequest(logger, req)
+	if err := pluginkit.WriteJSON(w, http.StatusOK, gc.card(req)); err != nil {
+		log.Error("failed to write response:", err)
+	}
+}
+
+// card builds the agent card. The gateway accepts A2A requests at its base URL and OpenAI
+// chat completions at /chat/completions; its skills are the skills of all agents.
+func (gc *gatewayCard) card(req *http.Request) models.AgentCard {
+	baseURL := gc.url
+	if baseURL == "" {
+		baseURL = requestBaseURL(req)
+	}
+	streaming := false
+	return models.AgentCard{
+		Name:               gc.name,
+		Description:        gc.description,
+		Url:                baseURL,
+		Version:            pluginkit.Version(),
+		ProtocolVersion:    models.ProtocolVersion,
+		PreferredTransport: transportJSONRPC,
+		AdditionalInterfaces: []models.AgentInterface{
+			{Transport: transportJSONRPC, Url: baseURL},
+			{Transport: transportOpenAI, Url: baseURL + "/chat/completions"},
+		},
+		Provider:           gc.provider,
+		Capabilities:       models.AgentCapabilities{Streaming: &streaming},
+		DefaultInputModes:  []string{"text/plain"},
+		DefaultOutputModes: []string{"text/plain"},
+		Skills:             gc.aggregateSkills(req.Context()),
+	}
+}
+
+// agentCard is the card of an agent, nil if it could not be fetched
+type agentCard struct {
+	agent AgentInfo
+	card  *models.AgentCard
+}
+
+// agentCards returns the cached cards, or waits for a new round of fetches until ctx is done.
+// Callers that stop waiting get the expired cards, if any.
+func (gc *gatewayCard) agentCards(ctx context.Context) []agentCard {
+	gc.mu.Lock()
+	if gc.cards != nil && gc.now().Before(gc.expiry) {
+		cards := gc.cards
+		gc.mu.Unlock()
+		return cards
+	}
+	done := gc.fetching
+	if done == nil {
+		done = make(chan struct{})
+		gc.fetching = done
+		go gc.refresh(done)
+	}
+	gc.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	return gc.cards
+}
+
+// refresh fetches the cards of all agents concurrently without holding the lock, caches them and
+// closes done. Fetches are bounded by the timeout only, not by the requests waiting for them.
+func (gc *gatewayCard) refresh(done chan struct{}) {
+	ctx := context.Background()
+	agents := gc.agents()
+	cards := make([]agentCard, len(agents))
+	var wg sync.WaitGroup
+	for i, agent := range agents {
+		wg.Add(1)
+		go func(i int, agent AgentInfo) {
+			defer wg.Done()
+			cards[i] = agentCard{agent: agent}
+			targets := agentTargets(agent)
+			if len(targets) == 0 {
+				return
+			}
+			card, err := fetchAgentCard(ctx, targets[0], gc.timeout)
+			if err != nil {
+				logger.Warning(fmt.Sprintf("cannot fetch agent card of model '%s': %v", agent.ModelID, err))
+				return
+			}
+			cards[i].card = &card
+		}(i, agent)
+	}
+	wg.Wait()
+
+	gc.mu.Lock()
+	gc.cards = cards
+	gc.expiry = gc.now().Add(gc.cacheTTL)
+	gc.fetching = nil
+	gc.mu.Unlock()
+	close(done)
+}
+
+// aggregateSkills returns the skills of all agents. Skill IDs are prefixed with the model ID and
+// tagged with it, so clients can pick the model serving a skill. Agents without a reachable card
+// contribute a single skill named after the model.
+func (gc *gatewayCard) aggregateSkills(ctx context.Context) []models.AgentSkill {
+	skills := []models.AgentSkill{}
+	for _, ac := range gc.agentCards(ctx) {
+		skills = append(skills, agentSkills(ac)...)
+	}
+	return skills
+}
+
+// agentSkills returns the skills of an agent card, attributed to the model of the agent
+func agentSkills(ac agentCard) []models.AgentSkill {
+	fallback := []models.AgentSkill{{
+		Id:          ac.agent.ModelID,
+		Name:        ac.agent.ModelID,
+		Description: fmt.Sprintf("Chat with the %s agent", ac.agent.ModelID),
+		Tags:        []string{ac.agent.ModelID},
+	}}
+	if ac.card == nil {
+		return fallback
+	}
+	if len(ac.card.Skills) == 0 {
+		if ac.card.Description != "" {
+			fallback[0].Description = ac.card.Description
+		}
+		return fallback
+	}
+
+	skills := make([]models.AgentSkill, len(ac.card.Skills))
+	for i, skill := range ac.card.Skills {
+		skill.Id = ac.agent.ModelID + "/" + skill.Id
+		skill.Tags = append(append([]string{}, skill.Tags...), ac.agent.ModelID)
+		skills[i] = skill
+	}
+	return skills
+}
+
+// AgentIndex lists the agents reachable through the gateway
+type AgentIndex struct {
+	Agents []AgentIndexEntry `json:"agents"`
+}
+
+// AgentIndexEntry describes an agent with its gateway URLs. Name, description, version and skills
+// are taken from the agent card; Available is false if the card could not be fetched.
+type AgentIndexEntry struct {
+	ModelID      string              `json:"model_id"`
+	Name         string              `json:"name"`
+	Description  string              `json:"description,omitempty"`
+	Version      string              `json:"version,omitempty"`
+	URL          string              `json:"url"`
+	AgentCardURL string              `json:"agent_card_url"`
+	Skills       []models.AgentSkill `json:"skills"`
+	Available    bool                `json:"available"`
+}
+
+// handlesIndex reports whether the request targets the agent index
+func (gc *gatewayCard) handlesIndex(req *http.Request) bool {
+	return gc != nil && req.Method == http.MethodGet && req.URL.Path == gc.indexPath
+}
+
+// serveIndex serves the index of the visible agents with the URLs under which the gateway exposes them
+func (gc *gatewayCard) serveIndex(w http.ResponseWriter, req *http.Request, visible []AgentInfo) {
+	log := logging.FromRequest(logger, req)
+	baseURL := gc.url
+	if baseURL == "" {
+		baseURL = requestBaseURL(req)
+	}
+
+	shown := make(map[string]bool, len(visible))
+	for _, agent := range visible {
+		shown[agent.ModelID] = true
+	}
+
+	index := AgentIndex{Agents: []AgentIndexEntry{}}
+	for _, ac := range gc.agentCards(req.Context()) {
+		if !shown[ac.agent.ModelID] {
+			continue
+		}
+		agentURL := baseURL + "/" + ac.agent.ModelID
+		entry := AgentIndexEntry{
+			ModelID:      ac.agent.ModelID,
+			Name:         ac.agent.ModelID,
+			URL:          agentURL,
+			AgentCardURL: agentURL + agentCardPath,
+			Skills:       []models.AgentSkill{},
+		}
+		if ac.card != nil {
+			entry.Available = true
+			if ac.card.Name != "" {
+				entry.Name = ac.card.Name
+			}
+			entry.Description = ac.card.Description
+			entry.Version = ac.card.Version
+			if ac.card.Skills != nil {
+				entry.Skills = ac.card.Skills
+			}
+		}
+		index.Agents = append(index.Agents, entry)
+	}
+
+	if err := pluginkit.WriteJSON(w, http.StatusOK, index); err != nil {
+		log.Error("failed to write response:", err)
+	}
+}
+
+// fetchAgentCard fetches the agent card served at target
+func fetchAgentCard(ctx context.Context, target string, timeout time.Duration) (models.AgentCard, error) {
+	client, err := a2aclient.New(target, a2aclient.Options{HTTPClient: upstreamClient, Timeout: timeout})
+	if err != nil {
+		return models.AgentCard{}, err
+	}
+	return client.GetAgentCard(ctx)
+}
+
+// trustedProxies are the peers whose forwarded headers are believed, set from trusted_proxies
+var trustedProxies *trustedproxy.Allowlist
+
+// requestBaseURL returns the scheme and host a request was sent to. X-Forwarded-Proto can be set
+// by any client and is only honored if the peer is one of the trusted proxies.
+func requestBaseURL(req *http.Request) string {
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	if trustedProxies.Trusts(req) {
+		proto, _, _ := strings.Cut(req.Header.Get("X-Forwarded-Proto"), ",")
+		if proto = strings.TrimSpace(proto); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+	}
+	return fmt.Sprintf("%s://%s", scheme, req.Host)
+}
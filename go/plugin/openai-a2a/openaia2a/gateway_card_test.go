@@ -0,0 +1,267 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCardServer(t *testing.T, card string, fetches *atomic.Int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, agentCardPath, r.URL.Path)
+		fetches.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(card))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNewGatewayCard_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  GatewayCardConfig
+	}{
+		{name: "relative url", cfg: GatewayCardConfig{URL: "gateway"}},
+		{name: "invalid timeout", cfg: GatewayCardConfig{Timeout: "fast"}},
+		{name: "invalid cache ttl", cfg: GatewayCardConfig{CacheTTL: "short"}},
+		{name: "relative index path", cfg: GatewayCardConfig{IndexPath: "agents"}},
+		{name: "provider without organization", cfg: GatewayCardConfig{Provider: &models.AgentProvider{Url: "https://example.com"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newGatewayCard(&tt.cfg, nil)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestGatewayCard_AggregatesSkills(t *testing.T) {
+	var fetches atomic.Int32
+	weather := newCardServer(t, `{"name":"Weather","description":"Weather forecasts","skills":[{"id":"forecast","name":"Forecast","description":"Daily forecast","tags":["weather"]}]}`, &fetches)
+	travel := newCardServer(t, `{"name":"Travel","description":"Plans trips","skills":[]}`, &fetches)
+
+	mockHandler := &MockHandler{StatusCode: http.StatusOK}
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "weather-agent", "url": weather.URL},
+				map[string]interface{}{"model_id": "travel-agent", "url": travel.URL},
+				map[string]interface{}{"model_id": "offline-agent", "url": "http://127.0.0.1:1"},
+			},
+			"agent_card":      map[string]interface{}{"name": "Example Gateway", "timeout": "500ms"},
+			"trusted_proxies": []interface{}{"192.0.2.0/24"},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, agentCardPath, nil)
+	req.Host = "gateway.example.com"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Nil(t, mockHandler.ReceivedRequest)
+	var card models.AgentCard
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &card))
+	assert.Equal(t, "Example Gateway", card.Name)
+	assert.Equal(t, "https://gateway.example.com", card.Url)
+	assert.Equal(t, models.ProtocolVersion, card.ProtocolVersion)
+	assert.Contains(t, card.AdditionalInterfaces, models.AgentInterface{Transport: transportOpenAI, Url: "https://gateway.example.com/chat/completions"})
+	assert.Equal(t, []models.AgentSkill{
+		{Id: "weather-agent/forecast", Name: "Forecast", Description: "Daily forecast", Tags: []string{"weather", "weather-agent"}},
+		{Id: "travel-agent", Name: "travel-agent", Description: "Plans trips", Tags: []string{"travel-agent"}},
+		{Id: "offline-agent", Name: "offline-agent", Description: "Chat with the offline-agent agent", Tags: []string{"offline-agent"}},
+	}, card.Skills)
+
+	// Forwarded headers of other peers are ignored
+	req = httptest.NewRequest(http.MethodGet, agentCardPath, nil)
+	req.Host = "gateway.example.com"
+	req.RemoteAddr = "203.0.113.7:51234"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &card))
+	assert.Equal(t, "http://gateway.example.com", card.Url)
+}
+
+func TestGatewayCard_CachesSkills(t *testing.T) {
+	var fetches atomic.Int32
+	agent := newCardServer(t, `{"name":"Weather","skills":[{"id":"forecast","name":"Forecast","tags":[]}]}`, &fetches)
+
+	gc, err := newGatewayCard(&GatewayCardConfig{URL: "https://gateway.example.com/", CacheTTL: "1m"}, staticAgents([]AgentInfo{{ModelID: "weather-agent", URL: agent.URL}}))
+	assert.NoError(t, err)
+	now := time.Now()
+	gc.now = func() time.Time { return now }
+
+	req := httptest.NewRequest(http.MethodGet, agentCardPath, nil)
+	assert.Equal(t, "https://gateway.example.com", gc.card(req).Url)
+	gc.card(req)
+	assert.Equal(t, int32(1), fetches.Load())
+
+	now = now.Add(2 * time.Minute)
+	gc.card(req)
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+func TestGatewayCard_CanceledRequestDoesNotCacheCardsAsUnavailable(t *testing.T) {
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"Weather","skills":[]}`))
+	}))
+	defer agent.Close()
+
+	gc, err := newGatewayCard(&GatewayCardConfig{CacheTTL: "1m"}, staticAgents([]AgentInfo{{ModelID: "weather-agent", URL: agent.URL}}))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Empty(t, gc.agentCards(ctx))
+
+	// The fetches of the canceled request continue and are shared with the next request
+	close(release)
+	cards := gc.agentCards(context.Background())
+	assert.Len(t, cards, 1)
+	assert.NotNil(t, cards[0].card)
+	assert.Equal(t, int32(1), fetches.Load())
+}
+
+func TestGatewayCard_DisabledByDefault(t *testing.T) {
+	mockHandler := &MockHandler{StatusCode: http.StatusOK}
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{"agents": []interface{}{}},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, agentCardPath, nil))
+
+	assert.NotNil(t, mockHandler.ReceivedRequest)
+}
+
+func TestGatewayCard_ServesAgentIndex(t *testing.T) {
+	var fetches atomic.Int32
+	weather := newCardServer(t, `{"name":"Weather","description":"Weather forecasts","version":"1.2.0","skills":[{"id":"forecast","name":"Forecast","tags":[]}]}`, &fetches)
+
+	mockHandler := &MockHandler{StatusCode: http.StatusOK}
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "weather-agent", "url": weather.URL},
+				map[string]interface{}{"model_id": "offline-agent", "url": "http://127.0.0.1:1"},
+			},
+			"agent_card": map[string]interface{}{"url": "https://gateway.example.com", "timeout": "500ms"},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/agents", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Nil(t, mockHandler.ReceivedRequest)
+	var index AgentIndex
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &index))
+	assert.Equal(t, []AgentIndexEntry{
+		{
+			ModelID:      "weather-agent",
+			Name:         "Weather",
+			Description:  "Weather forecasts",
+			Version:      "1.2.0",
+			URL:          "https://gateway.example.com/weather-agent",
+			AgentCardURL: "https://gateway.example.com/weather-agent" + agentCardPath,
+			Skills:       []models.AgentSkill{{Id: "forecast", Name: "Forecast", Tags: []string{}}},
+			Available:    true,
+		},
+		{
+			ModelID:      "offline-agent",
+			Name:         "offline-agent",
+			URL:          "https://gateway.example.com/offline-agent",
+			AgentCardURL: "https://gateway.example.com/offline-agent" + agentCardPath,
+			Skills:       []models.AgentSkill{},
+		},
+	}, index.Agents)
+
+	// The index shares the card cache with the gateway card
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, agentCardPath, nil))
+	assert.Equal(t, int32(1), fetches.Load())
+}
+
+func TestGatewayCard_AgentIndexListsTheAgentsOfTheCaller(t *testing.T) {
+	var fetches atomic.Int32
+	agent := newCardServer(t, `{"name":"Agent","skills":[]}`, &fetches)
+
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "team-a/agent", "url": agent.URL},
+				map[string]interface{}{"model_id": "team-b/agent", "url": agent.URL},
+			},
+			"agent_card": map[string]interface{}{"url": "https://gateway.example.com"},
+			"api_keys": map[string]interface{}{
+				"keys": []interface{}{
+					map[string]interface{}{"id": "alice", "secret": "sk-alice-key"},
+					map[string]interface{}{"id": "ops", "secret": "sk-ops-key"},
+				},
+			},
+			"tenancy": map[string]interface{}{
+				"tenants": []interface{}{
+					map[string]interface{}{"id": "team-a", "principals": []interface{}{"alice"}, "models": []interface{}{"team-a/agent"}},
+					map[string]interface{}{"id": "platform", "principals": []interface{}{"ops"}, "models": []interface{}{"*"}},
+				},
+			},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, &MockHandler{})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		key        string
+		wantCode   int
+		wantModels []string
+	}{
+		{key: "", wantCode: http.StatusUnauthorized},
+		{key: "sk-invalid-key", wantCode: http.StatusUnauthorized},
+		{key: "sk-alice-key", wantCode: http.StatusOK, wantModels: []string{"team-a/agent"}},
+		{key: "sk-ops-key", wantCode: http.StatusOK, wantModels: []string{"team-a/agent", "team-b/agent"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/agents", nil)
+			if tt.key != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.key)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			var index AgentIndex
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &index))
+			var models []string
+			for _, entry := range index.Agents {
+				models = append(models, entry.ModelID)
+			}
+			assert.Equal(t, tt.wantModels, models)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package openaia2a
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2agrpc"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/go-http-utils/headers"
+)
+
+// transportGRPC marks agents that only expose the A2A gRPC service. Their url is
+// http://host:port for plaintext HTTP/2 or https://host:port for TLS.
+const transportGRPC = "GRPC"
+
+// isGRPC reports whether an agent is reached over the A2A gRPC service
+func isGRPC(agent AgentInfo) bool {
+	return strings.EqualFold(agent.Transport, transportGRPC)
+}
+
+// newGRPCClients creates the gRPC clients of all agents using the GRPC transport. Clients of agents
+// whose URL did not change are taken over from the previous set, so connections survive reloads.
+func newGRPCClients(agents []AgentInfo, previous *agentSet) (map[string]*a2agrpc.Client, error) {
+	clients := make(map[string]*a2agrpc.Client)
+	for _, agent := range agents {
+		if agent.Transport != "" && !isGRPC(agent) && !strings.EqualFold(agent.Transport, transportJSONRPC) {
+			return nil, fmt.Errorf("agent %s: unknown transport '%s', expected %s or %s", agent.ModelID, agent.Transport, transportJSONRPC, transportGRPC)
+		}
+		if !isGRPC(agent) {
+			continue
+		}
+		if agent.Ensemble != nil || agent.LoadBalancing != nil || agent.Canary != nil {
+			return nil, fmt.Errorf("agent %s: the %s transport does not support ensembles, load balancing or canaries", agent.ModelID, transportGRPC)
+		}
+		if previous != nil {
+			if old, ok := previous.agent(agent.ModelID); ok && isGRPC(old) && old.URL == agent.URL && reflect.DeepEqual(old.TLS, agent.TLS) && old.Proxy == agent.Proxy {
+				clients[agent.ModelID] = previous.grpc[agent.ModelID]
+				continue
+			}
+		}
+		var host string
+		if parsed, err := url.Parse(agent.URL); err == nil {
+			host = parsed.Host
+		}
+		client, err := a2agrpc.NewClient(agent.URL, upstreamTransport.ForHost(host))
+		if err != nil {
+			return nil, fmt.Errorf("agent %s: %w", agent.ModelID, err)
+		}
+		clients[agent.ModelID] = client
+	}
+	return clients, nil
+}
+
+// serveGRPC transcodes a native A2A request to the gRPC service of an agent
+func serveGRPC(w http.ResponseWriter, req *http.Request, client *a2agrpc.Client) {
+	log := logging.FromRequest(logger, req)
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		writeA2AError(w, http.StatusBadRequest, nil, &models.JSONRPCErrorResponseError{Code: a2aerrors.CodeJSONParse, Message: "cannot read request body"})
+		return
+	}
+	resp, err := client.Transcode(req.Context(), body)
+	if err != nil {
+		log.Error(fmt.Sprintf("grpc request to %s failed: %v", req.URL.Path, err))
+		writeA2AError(w, http.StatusBadGateway, nil, &models.JSONRPCErrorResponseError{Code: a2aerrors.CodeInternal, Message: "agent is not reachable"})
+		return
+	}
+
+	w.Header().Set(headers.ContentType, "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(resp); err != nil {
+		log.Error("failed to write response:", err)
+	}
+}
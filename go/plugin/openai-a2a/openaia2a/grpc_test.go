@@ -0,0 +1,134 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// newGRPCAgent starts an h2c server answering every A2AService call with a completed task
+func newGRPCAgent(t *testing.T, methods *[]string) string {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.ReadAll(r.Body)
+		method := strings.TrimPrefix(r.URL.Path, "/a2a.v1.A2AService/")
+		*methods = append(*methods, method)
+
+		part := protowire.AppendString(protowire.AppendTag(nil, 1, protowire.BytesType), "Sunny.")
+		artifact := protowire.AppendString(protowire.AppendTag(nil, 1, protowire.BytesType), "artifact-1")
+		artifact = protowire.AppendBytes(protowire.AppendTag(artifact, 5, protowire.BytesType), part)
+		status := protowire.AppendVarint(protowire.AppendTag(nil, 1, protowire.VarintType), 3)
+		task := protowire.AppendString(protowire.AppendTag(nil, 1, protowire.BytesType), "task-1")
+		task = protowire.AppendBytes(protowire.AppendTag(task, 3, protowire.BytesType), status)
+		task = protowire.AppendBytes(protowire.AppendTag(task, 4, protowire.BytesType), artifact)
+		resp := task
+		if method == "SendMessage" {
+			resp = protowire.AppendBytes(protowire.AppendTag(nil, 1, protowire.BytesType), task)
+		}
+
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status")
+		frame := make([]byte, 5)
+		binary.BigEndian.PutUint32(frame[1:], uint32(len(resp)))
+		_, _ = w.Write(append(frame, resp...))
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	server.Config.Protocols = &protocols
+	server.Start()
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func newGRPCHandler(t *testing.T, agentURL string, next http.Handler) http.Handler {
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "grpc-agent", "url": agentURL, "transport": "GRPC"},
+			},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, next)
+	assert.NoError(t, err)
+	return handler
+}
+
+func TestNewGRPCClients_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name  string
+		agent AgentInfo
+	}{
+		{name: "unknown transport", agent: AgentInfo{ModelID: "a", URL: "http://a:50051", Transport: "SOAP"}},
+		{name: "invalid target", agent: AgentInfo{ModelID: "a", URL: "a:50051", Transport: "GRPC"}},
+		{name: "ensemble", agent: AgentInfo{ModelID: "a", URL: "http://a:50051", Transport: "GRPC", Ensemble: &EnsembleConfig{}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newGRPCClients([]AgentInfo{tt.agent}, nil)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestGRPC_ChatCompletion(t *testing.T) {
+	var methods []string
+	next := &MockHandler{}
+	handler := newGRPCHandler(t, newGRPCAgent(t, &methods), next)
+
+	rec := sendChatCompletion(handler, "grpc-agent", "")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp models.OpenAIResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "Sunny.", resp.Choices[0].Message.Content)
+	assert.Equal(t, []string{"SendMessage"}, methods)
+	assert.Nil(t, next.ReceivedRequest)
+}
+
+func TestGRPC_NativeRequest(t *testing.T) {
+	var methods []string
+	next := &MockHandler{}
+	handler := newGRPCHandler(t, newGRPCAgent(t, &methods), next)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/grpc-agent",
+		strings.NewReader(`{"jsonrpc":"2.0","id":7,"method":"tasks/get","params":{"id":"task-1"}}`)))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp struct {
+		Id     int `json:"id"`
+		Result struct {
+			Id string `json:"id"`
+		} `json:"result"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, 7, resp.Id)
+	assert.Equal(t, "task-1", resp.Result.Id)
+	assert.Equal(t, []string{"GetTask"}, methods)
+	assert.Nil(t, next.ReceivedRequest)
+}
+
+func TestGRPC_UnreachableAgent(t *testing.T) {
+	handler := newGRPCHandler(t, "http://127.0.0.1:1", &MockHandler{})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/grpc-agent",
+		strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tasks/get","params":{"id":"task-1"}}`)))
+
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	rpcErr, ok := a2aerrors.Parse(rec.Body.Bytes())
+	if assert.True(t, ok) {
+		assert.Equal(t, a2aerrors.CodeInternal, rpcErr.Code)
+	}
+}
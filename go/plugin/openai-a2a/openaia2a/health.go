@@ -0,0 +1,434 @@
+package openaia2a
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+)
+
+// Health endpoint paths. The agent details are served below the configured endpoint.
+const (
+	defaultHealthEndpoint = "/health"
+	healthAgentsSuffix    = "/agents"
+	healthAgentsPath      = defaultHealthEndpoint + healthAgentsSuffix
+)
+
+// Health status constants
+const (
+	healthUp       = "up"
+	healthDegraded = "degraded"
+	healthDown     = "down"
+)
+
+const (
+	defaultHealthTimeout  = 2 * time.Second
+	defaultHealthCacheTTL = 5 * time.Second
+	defaultHealthPath     = agentCardPath
+)
+
+// HealthConfig controls how agents are probed by the health endpoints
+type HealthConfig struct {
+	Timeout  string `json:"timeout"`
+	CacheTTL string `json:"cache_ttl"`
+	Path     string `json:"path"`
+	// Endpoint is the path the gateway serves its health at (default /health), with the agent
+	// details at {endpoint}/agents. Change it if /health is routed to a backend.
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// TargetHealth is the probe result of a single agent URL
+type TargetHealth struct {
+	URL       string `json:"url,omitempty"`
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// AgentHealth aggregates the probe results of all URLs of an agent
+type AgentHealth struct {
+	ModelID   string          `json:"model_id"`
+	Status    string          `json:"status"`
+	Targets   []TargetHealth  `json:"targets"`
+	Readiness *AgentReadiness `json:"readiness,omitempty"`
+}
+
+// StoreHealth is the probe result of a store shared by gateway instances, e.g. of quotas
+type StoreHealth struct {
+	Name      string `json:"name"`
+	Driver    string `json:"driver"`
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// HealthReport is the response of the health endpoints
+type HealthReport struct {
+	Status    string        `json:"status"`
+	CheckedAt int64         `json:"checked_at"`
+	Agents    []AgentHealth `json:"agents,omitempty"`
+	Stores    []StoreHealth `json:"stores,omitempty"`
+}
+
+// redacted returns a copy of the report without the agent URLs, probe errors and eviction
+// reasons, which reveal the internal topology of the gateway
+func (r HealthReport) redacted() HealthReport {
+	agents := make([]AgentHealth, len(r.Agents))
+	for i, agent := range r.Agents {
+		targets := make([]TargetHealth, len(agent.Targets))
+		for j, target := range agent.Targets {
+			targets[j] = TargetHealth{Status: target.Status, LatencyMs: target.LatencyMs}
+		}
+		agent.Targets = targets
+		if agent.Readiness != nil {
+			readiness := *agent.Readiness
+			readiness.Reason = ""
+			agent.Readiness = &readiness
+		}
+		agents[i] = agent
+	}
+	stores := make([]StoreHealth, len(r.Stores))
+	for i, store := range r.Stores {
+		store.Error = ""
+		stores[i] = store
+	}
+	r.Agents = agents
+	r.Stores = stores
+	return r
+}
+
+// storeProbe is a store whose reachability is reported by the health endpoints
+type storeProbe interface {
+	ping(ctx context.Context) error
+	driver() string
+}
+
+type namedStore struct {
+	name  string
+	store storeProbe
+}
+
+// healthChecker actively probes all configured agents and caches the result briefly,
+// so frequent readiness probes do not multiply the load on agents. Concurrent checks share
+// one round of probes, which runs in the background and outlives the requests waiting for it.
+type healthChecker struct {
+	agents   func() []AgentInfo
+	timeout  time.Duration
+	cacheTTL time.Duration
+	path     string
+	endpoint string
+	stores   []namedStore
+	// readiness adds the readiness scores of agents, if enabled
+	readiness *readiness
+
+	mu     sync.Mutex
+	cached *HealthReport
+	expiry time.Time
+	// probing is closed when the running round of probes is done
+	probing chan struct{}
+	now     func() time.Time
+}
+
+// newHealthChecker creates the health checker for the agents returned by agents.
+// A nil config uses the defaults.
+func newHealthChecker(cfg *HealthConfig, agents func() []AgentInfo) (*healthChecker, error) {
+	hc := &healthChecker{
+		agents:   agents,
+		timeout:  defaultHealthTimeout,
+		cacheTTL: defaultHealthCacheTTL,
+		path:     defaultHealthPath,
+		endpoint: defaultHealthEndpoint,
+		now:      time.Now,
+	}
+	if cfg == nil {
+		return hc, nil
+	}
+
+	var err error
+	if cfg.Timeout != "" {
+		if hc.timeout, err = time.ParseDuration(cfg.Timeout); err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+	}
+	if cfg.CacheTTL != "" {
+		if hc.cacheTTL, err = time.ParseDuration(cfg.CacheTTL); err != nil {
+			return nil, fmt.Errorf("invalid cache_ttl: %w", err)
+		}
+	}
+	if cfg.Path != "" {
+		hc.path = cfg.Path
+	}
+	if cfg.Endpoint != "" {
+		if !strings.HasPrefix(cfg.Endpoint, "/") || strings.HasSuffix(cfg.Endpoint, "/") {
+			return nil, fmt.Errorf("invalid endpoint '%s', expected a path like /health", cfg.Endpoint)
+		}
+		hc.endpoint = cfg.Endpoint
+	}
+	return hc, nil
+}
+
+// addStore adds a store to the probes of the health endpoints
+func (hc *healthChecker) addStore(name string, store storeProbe) {
+	hc.stores = append(hc.stores, namedStore{name: name, store: store})
+}
+
+// handles reports whether the request targets a health endpoint
+func (hc *healthChecker) handles(req *http.Request) bool {
+	return req.Method == http.MethodGet && (req.URL.Path == hc.endpoint || req.URL.Path == hc.endpoint+healthAgentsSuffix)
+}
+
+// serveHTTP serves the health endpoints. /health returns only the overall status,
+// /health/agents includes per-agent and per-store details, with URLs and errors only if
+// detailed is set. Both return 503 only if all agents are down, since the gateway still
+// serves the others.
+func (hc *healthChecker) serveHTTP(w http.ResponseWriter, req *http.Request, detailed bool) {
+	log := logging.FromRequest(logger, req)
+	report, err := hc.check(req.Context())
+	if err != nil {
+		// The client is gone, the probes continue for the next check
+		log.Debug("health check canceled:", err)
+		return
+	}
+	if req.URL.Path == hc.endpoint {
+		report.Agents = nil
+		report.Stores = nil
+	} else if !detailed {
+		report = report.redacted()
+	}
+
+	statusCode := http.StatusOK
+	if report.Status == healthDown {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	if err := pluginkit.WriteJSON(w, statusCode, report); err != nil {
+		log.Error("failed to write response:", err)
+	}
+}
+
+// check returns the cached report, or waits for a new round of probes until ctx is done
+func (hc *healthChecker) check(ctx context.Context) (HealthReport, error) {
+	hc.mu.Lock()
+	if hc.cached != nil && hc.now().Before(hc.expiry) {
+		report := *hc.cached
+		hc.mu.Unlock()
+		return report, nil
+	}
+	done := hc.probing
+	if done == nil {
+		done = make(chan struct{})
+		hc.probing = done
+		go hc.refresh(done)
+	}
+	hc.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return HealthReport{}, ctx.Err()
+	}
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	return *hc.cached, nil
+}
+
+// refresh probes all agents and stores without holding the lock, caches the report and closes
+// done. Probes are bounded by the timeout only, not by the requests waiting for them.
+func (hc *healthChecker) refresh(done chan struct{}) {
+	report := hc.probeAll(context.Background())
+	hc.mu.Lock()
+	hc.cached = &report
+	hc.expiry = hc.now().Add(hc.cacheTTL)
+	hc.probing = nil
+	hc.mu.Unlock()
+	close(done)
+}
+
+// probeAll probes all agents and stores concurrently. The gateway is up if all agents are up,
+// down if all of them are down and degraded otherwise. Unreachable stores degrade the
+// gateway, since requests are still served without them.
+func (hc *healthChecker) probeAll(ctx context.Context) HealthReport {
+	agents := hc.agents()
+	report := HealthReport{
+		Status:    healthUp,
+		CheckedAt: hc.now().Unix(),
+		Agents:    make([]AgentHealth, len(agents)),
+	}
+	if len(hc.stores) > 0 {
+		report.Stores = make([]StoreHealth, len(hc.stores))
+	}
+
+	var wg sync.WaitGroup
+	for i, agent := range agents {
+		wg.Add(1)
+		go func(i int, agent AgentInfo) {
+			defer wg.Done()
+			report.Agents[i] = hc.probeAgent(ctx, agent)
+		}(i, agent)
+	}
+	for i, store := range hc.stores {
+		wg.Add(1)
+		go func(i int, store namedStore) {
+			defer wg.Done()
+			report.Stores[i] = hc.probeStore(ctx, store)
+		}(i, store)
+	}
+	wg.Wait()
+
+	down := 0
+	for _, agent := range report.Agents {
+		if agent.Status == healthDown {
+			down++
+		}
+		if agent.Status != healthUp {
+			report.Status = healthDegraded
+		}
+	}
+	if down > 0 && down == len(report.Agents) {
+		report.Status = healthDown
+	}
+	for _, store := range report.Stores {
+		if store.Status == healthDown && report.Status == healthUp {
+			report.Status = healthDegraded
+		}
+	}
+	return report
+}
+
+// probeAgent probes every URL an agent can be reached at. An agent is up if all
+// of its URLs respond, degraded if some do and down if none do.
+func (hc *healthChecker) probeAgent(ctx context.Context, agent AgentInfo) AgentHealth {
+	path := hc.path
+	if agent.HealthPath != "" {
+		path = agent.HealthPath
+	}
+
+	result := AgentHealth{ModelID: agent.ModelID, Readiness: hc.readiness.report(agent.ModelID)}
+	up := 0
+	for _, target := range agentTargets(agent) {
+		var th TargetHealth
+		if isGRPC(agent) {
+			th = hc.dial(ctx, target)
+		} else {
+			th = hc.probe(ctx, target, path)
+		}
+		if th.Status == healthUp {
+			up++
+		}
+		result.Targets = append(result.Targets, th)
+	}
+
+	switch {
+	case len(result.Targets) > 0 && up == len(result.Targets):
+		result.Status = healthUp
+	case up > 0:
+		result.Status = healthDegraded
+	default:
+		result.Status = healthDown
+	}
+	return result
+}
+
+// probeStore pings a store with the timeout of agent probes
+func (hc *healthChecker) probeStore(ctx context.Context, s namedStore) StoreHealth {
+	ctx, cancel := context.WithTimeout(ctx, hc.timeout)
+	defer cancel()
+
+	sh := StoreHealth{Name: s.name, Driver: s.store.driver(), Status: healthUp}
+	start := time.Now()
+	err := s.store.ping(ctx)
+	sh.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		sh.Status = healthDown
+		sh.Error = err.Error()
+	}
+	return sh
+}
+
+// probe issues a GET request against a single agent URL
+func (hc *healthChecker) probe(ctx context.Context, target, path string) TargetHealth {
+	ctx, cancel := context.WithTimeout(ctx, hc.timeout)
+	defer cancel()
+
+	th := TargetHealth{URL: target, Status: healthDown}
+	probeURL := strings.TrimSuffix(target, "/") + path
+
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, probeURL, nil)
+	if err != nil {
+		th.Error = err.Error()
+		return th
+	}
+	resp, err := upstreamClient.Do(req)
+	th.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		th.Error = err.Error()
+		return th
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		th.Error = fmt.Sprintf("unexpected status %d", resp.StatusCode)
+		return th
+	}
+	th.Status = healthUp
+	return th
+}
+
+// dial checks that a gRPC agent accepts connections; it has no HTTP endpoint to probe
+func (hc *healthChecker) dial(ctx context.Context, target string) TargetHealth {
+	ctx, cancel := context.WithTimeout(ctx, hc.timeout)
+	defer cancel()
+
+	th := TargetHealth{URL: target, Status: healthDown}
+	parsed, err := url.Parse(target)
+	if err != nil {
+		th.Error = err.Error()
+		return th
+	}
+	port := parsed.Port()
+	if port == "" {
+		port = "80"
+		if parsed.Scheme == "https" {
+			port = "443"
+		}
+	}
+
+	start := time.Now()
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(parsed.Hostname(), port))
+	th.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		th.Error = err.Error()
+		return th
+	}
+	_ = conn.Close()
+	th.Status = healthUp
+	return th
+}
+
+// agentTargets lists every URL an agent configuration can route to
+func agentTargets(agent AgentInfo) []string {
+	var targets []string
+	if agent.Ensemble != nil {
+		targets = append(targets, agent.Ensemble.URLs...)
+	} else if agent.LoadBalancing != nil {
+		for _, replica := range agent.LoadBalancing.Replicas {
+			targets = append(targets, replica.URL)
+		}
+	} else if agent.URL != "" {
+		targets = append(targets, agent.URL)
+	}
+	if agent.Canary != nil {
+		targets = append(targets, agent.Canary.URL)
+	}
+	return targets
+}
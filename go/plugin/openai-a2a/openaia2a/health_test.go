@@ -0,0 +1,285 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/memcache/memcachetest"
+	"github.com/stretchr/testify/assert"
+)
+
+func newProbeTarget(t *testing.T, statusCode int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statusCode)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNewHealthChecker_InvalidConfig(t *testing.T) {
+	_, err := newHealthChecker(&HealthConfig{Timeout: "fast"}, nil)
+	assert.Error(t, err)
+
+	_, err = newHealthChecker(&HealthConfig{CacheTTL: "short"}, nil)
+	assert.Error(t, err)
+}
+
+func TestHealthChecker_AggregatesAgentStatus(t *testing.T) {
+	up := newProbeTarget(t, http.StatusOK)
+	down := newProbeTarget(t, http.StatusServiceUnavailable)
+
+	tests := []struct {
+		name       string
+		agents     []AgentInfo
+		wantStatus string
+		wantCode   int
+	}{
+		{
+			name:       "all up",
+			agents:     []AgentInfo{{ModelID: "a", URL: up.URL}},
+			wantStatus: healthUp,
+			wantCode:   http.StatusOK,
+		},
+		{
+			name: "replica down",
+			agents: []AgentInfo{{ModelID: "a", LoadBalancing: &LoadBalancingConfig{
+				Replicas: []Replica{{URL: up.URL}, {URL: down.URL}},
+			}}},
+			wantStatus: healthDegraded,
+			wantCode:   http.StatusOK,
+		},
+		{
+			name:       "agent down",
+			agents:     []AgentInfo{{ModelID: "a", URL: up.URL}, {ModelID: "b", URL: down.URL}},
+			wantStatus: healthDegraded,
+			wantCode:   http.StatusOK,
+		},
+		{
+			name:       "all agents down",
+			agents:     []AgentInfo{{ModelID: "a", URL: down.URL}, {ModelID: "b", URL: down.URL}},
+			wantStatus: healthDown,
+			wantCode:   http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hc, err := newHealthChecker(nil, staticAgents(tt.agents))
+			assert.NoError(t, err)
+
+			rec := httptest.NewRecorder()
+			hc.serveHTTP(rec, httptest.NewRequest(http.MethodGet, healthAgentsPath, nil), true)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			var report HealthReport
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+			assert.Equal(t, tt.wantStatus, report.Status)
+			assert.Len(t, report.Agents, len(tt.agents))
+		})
+	}
+}
+
+func TestHealthChecker_ProbesConfiguredPath(t *testing.T) {
+	var probedPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probedPath = r.URL.Path
+	}))
+	defer server.Close()
+
+	hc, err := newHealthChecker(&HealthConfig{Path: "/healthz"}, staticAgents([]AgentInfo{{ModelID: "a", URL: server.URL}}))
+	assert.NoError(t, err)
+	hc.check(context.Background())
+	assert.Equal(t, "/healthz", probedPath)
+
+	hc, err = newHealthChecker(nil, staticAgents([]AgentInfo{{ModelID: "a", URL: server.URL, HealthPath: "/ready"}}))
+	assert.NoError(t, err)
+	hc.check(context.Background())
+	assert.Equal(t, "/ready", probedPath)
+}
+
+func TestHealthChecker_CachesResults(t *testing.T) {
+	probes := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		probes++
+	}))
+	defer server.Close()
+
+	hc, err := newHealthChecker(&HealthConfig{CacheTTL: "10s"}, staticAgents([]AgentInfo{{ModelID: "a", URL: server.URL}}))
+	assert.NoError(t, err)
+	now := time.Unix(1731679815, 0)
+	hc.now = func() time.Time { return now }
+
+	hc.check(context.Background())
+	hc.check(context.Background())
+	assert.Equal(t, 1, probes)
+
+	now = now.Add(11 * time.Second)
+	hc.check(context.Background())
+	assert.Equal(t, 2, probes)
+}
+
+func TestHealthChecker_CanceledCheckDoesNotCacheAgentsAsDown(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	hc, err := newHealthChecker(&HealthConfig{CacheTTL: "1m"}, staticAgents([]AgentInfo{{ModelID: "a", URL: server.URL}}))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = hc.check(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The probes of the canceled check continue and are shared with the next check
+	close(release)
+	report, err := hc.check(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, healthUp, report.Status)
+}
+
+func TestHealthEndpoint_ConfiguredEndpoint(t *testing.T) {
+	up := newProbeTarget(t, http.StatusOK)
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{map[string]interface{}{"model_id": "a", "url": up.URL}},
+			"health": map[string]interface{}{"endpoint": "/gateway/health"},
+		},
+	}
+	mockHandler := &MockHandler{}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/gateway/health/agents", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"agents"`)
+	assert.Nil(t, mockHandler.ReceivedRequest)
+
+	// /health is routed to the backend
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	assert.NotNil(t, mockHandler.ReceivedRequest)
+
+	_, err = newHealthChecker(&HealthConfig{Endpoint: "health"}, nil)
+	assert.Error(t, err)
+}
+
+func TestHealthEndpoint_SummaryOmitsAgents(t *testing.T) {
+	up := newProbeTarget(t, http.StatusOK)
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{map[string]interface{}{"model_id": "a", "url": up.URL}},
+		},
+	}
+	mockHandler := &MockHandler{}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, defaultHealthEndpoint, nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var report map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, healthUp, report["status"])
+	assert.NotContains(t, report, "agents")
+	assert.Nil(t, mockHandler.ReceivedRequest)
+}
+
+func TestHealthEndpoint_ReportsStores(t *testing.T) {
+	up := newProbeTarget(t, http.StatusOK)
+	server := memcachetest.NewServer()
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{map[string]interface{}{"model_id": "a", "url": up.URL}},
+			"health": map[string]interface{}{"cache_ttl": "1ns"},
+			"api_keys": map[string]interface{}{
+				"keys": []interface{}{map[string]interface{}{"id": "weather-team", "secret": "sk-weather-team-key"}},
+			},
+			"quotas": map[string]interface{}{
+				"daily":     map[string]interface{}{"requests": 100},
+				"memcached": map[string]interface{}{"servers": []interface{}{server.Addr}, "timeout": "100ms"},
+			},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, &MockHandler{})
+	assert.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, healthAgentsPath, nil))
+	var report HealthReport
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, healthUp, report.Status)
+	assert.Len(t, report.Stores, 1)
+	assert.Equal(t, StoreHealth{Name: "quotas", Driver: "memcached", Status: healthUp, LatencyMs: report.Stores[0].LatencyMs}, report.Stores[0])
+
+	server.Close()
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, healthAgentsPath, nil))
+	assert.Equal(t, http.StatusOK, rec.Code, "quotas admit requests without their store")
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, healthDegraded, report.Status)
+	assert.Equal(t, healthDown, report.Stores[0].Status)
+	assert.Empty(t, report.Stores[0].Error, "errors are reported to admin callers only")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, defaultHealthEndpoint, nil))
+	assert.NotContains(t, rec.Body.String(), "stores")
+}
+
+func TestHealthEndpoint_ReportsDetailsToAdminsOnly(t *testing.T) {
+	up := newProbeTarget(t, http.StatusOK)
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{map[string]interface{}{
+				"model_id": "a",
+				"load_balancing": map[string]interface{}{"replicas": []interface{}{
+					map[string]interface{}{"url": up.URL},
+					map[string]interface{}{"url": "http://127.0.0.1:1"},
+				}},
+			}},
+			"admin": map[string]interface{}{"keys": []interface{}{map[string]interface{}{"id": "2026", "secret": "admin-token"}}},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, &MockHandler{})
+	assert.NoError(t, err)
+
+	for _, token := range []string{"", "invalid-token"} {
+		req := httptest.NewRequest(http.MethodGet, healthAgentsPath, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.NotContains(t, rec.Body.String(), "127.0.0.1")
+		var report HealthReport
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.Equal(t, healthDegraded, report.Status)
+		assert.Len(t, report.Agents[0].Targets, 2)
+		for _, target := range report.Agents[0].Targets {
+			assert.Empty(t, target.URL)
+			assert.Empty(t, target.Error)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, healthAgentsPath, nil)
+	req.Header.Set("Authorization", "Bearer admin-token")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	var report HealthReport
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, up.URL, report.Agents[0].Targets[0].URL)
+	assert.Equal(t, "http://127.0.0.1:1", report.Agents[0].Targets[1].URL)
+	assert.NotEmpty(t, report.Agents[0].Targets[1].Error)
+}
+
+func staticAgents(agents []AgentInfo) func() []AgentInfo {
+	return func() []AgentInfo { return agents }
+}
@@ -0,0 +1,220 @@
+package openaia2a
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/go-http-utils/headers"
+)
+
+// History compaction strategies
+const (
+	historyTruncate  = "truncate"
+	historySummarize = "summarize"
+)
+
+const (
+	// historyMetadataKey holds the forwarded history in the A2A request metadata
+	historyMetadataKey = "history"
+	// historySummaryMetadataKey holds the summary of history turns that were compacted away
+	historySummaryMetadataKey = "history_summary"
+
+	defaultSummarizerTimeout = 10 * time.Second
+	defaultSummaryPrompt     = "Summarize the following conversation concisely. Keep the facts, decisions and open questions needed to continue it."
+)
+
+// HistoryConfig forwards the conversation preceding the merged messages in the A2A request metadata.
+// History exceeding max_messages or max_chars is compacted by dropping the oldest turns, or by
+// replacing them with a summary from an OpenAI-compatible summarizer model.
+type HistoryConfig struct {
+	MaxMessages int               `json:"max_messages"`
+	MaxChars    int               `json:"max_chars"`
+	Strategy    string            `json:"strategy"`
+	Summarizer  *SummarizerConfig `json:"summarizer"`
+}
+
+// SummarizerConfig is a chat completions endpoint and model that summarizes compacted history
+type SummarizerConfig struct {
+	Endpoint string `json:"endpoint"`
+	Model    string `json:"model"`
+	Prompt   string `json:"prompt"`
+	Timeout  string `json:"timeout"`
+}
+
+// historyMessage is a forwarded history turn
+type historyMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// historyForwarder attaches compacted history to A2A requests
+type historyForwarder struct {
+	maxMessages int
+	maxChars    int
+	strategy    string
+
+	summarizerEndpoint string
+	summarizerModel    string
+	summaryPrompt      string
+	summarizerTimeout  time.Duration
+}
+
+// newHistoryForwarder validates the configuration. A nil config disables history forwarding.
+func newHistoryForwarder(cfg *HistoryConfig) (*historyForwarder, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.MaxMessages < 0 || cfg.MaxChars < 0 {
+		return nil, errors.New("max_messages and max_chars must not be negative")
+	}
+	h := &historyForwarder{
+		maxMessages: cfg.MaxMessages,
+		maxChars:    cfg.MaxChars,
+		strategy:    strings.ToLower(cfg.Strategy),
+	}
+	switch h.strategy {
+	case "":
+		h.strategy = historyTruncate
+	case historyTruncate:
+	case historySummarize:
+		if err := h.configureSummarizer(cfg.Summarizer); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown strategy '%s', expected %s or %s", cfg.Strategy, historyTruncate, historySummarize)
+	}
+	return h, nil
+}
+
+func (h *historyForwarder) configureSummarizer(cfg *SummarizerConfig) error {
+	if cfg == nil {
+		return fmt.Errorf("strategy %s requires a summarizer", historySummarize)
+	}
+	if parsed, err := url.Parse(cfg.Endpoint); err != nil || parsed.Host == "" {
+		return fmt.Errorf("invalid summarizer endpoint '%s'", cfg.Endpoint)
+	}
+	if cfg.Model == "" {
+		return errors.New("summarizer model is required")
+	}
+	h.summarizerEndpoint = cfg.Endpoint
+	h.summarizerModel = cfg.Model
+	h.summaryPrompt = cfg.Prompt
+	if h.summaryPrompt == "" {
+		h.summaryPrompt = defaultSummaryPrompt
+	}
+	h.summarizerTimeout = defaultSummarizerTimeout
+	if cfg.Timeout != "" {
+		timeout, err := time.ParseDuration(cfg.Timeout)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid summarizer timeout '%s'", cfg.Timeout)
+		}
+		h.summarizerTimeout = timeout
+	}
+	return nil
+}
+
+// attach adds the messages preceding the merged messages to the A2A metadata. Turns exceeding
+// the limits are dropped oldest first; with the summarize strategy, their summary is attached
+// instead. A failing summarizer falls back to truncation so the request is still served.
+func (h *historyForwarder) attach(ctx context.Context, a2aReq *models.SendMessageRequest, messages []models.OpenAIMessage, mergeMode string) {
+	if h == nil {
+		return
+	}
+	start, err := mergeStart(messages, mergeMode)
+	if err != nil || start == 0 {
+		return
+	}
+
+	kept, dropped := h.compact(messages[:start])
+	if len(dropped) > 0 {
+		logger.Debug(fmt.Sprintf("compacting %d of %d history messages", len(dropped), start))
+		if h.strategy == historySummarize {
+			summary, err := h.summarize(ctx, dropped)
+			if err != nil {
+				logger.Warning("failed to summarize history, dropping oldest messages:", err)
+			} else {
+				a2aReq.Params.Metadata[historySummaryMetadataKey] = summary
+			}
+		}
+	}
+	if len(kept) > 0 {
+		a2aReq.Params.Metadata[historyMetadataKey] = kept
+	}
+}
+
+// compact splits history into the newest messages within the limits and the older remainder
+func (h *historyForwarder) compact(messages []models.OpenAIMessage) ([]historyMessage, []historyMessage) {
+	history := make([]historyMessage, len(messages))
+	for i, msg := range messages {
+		history[i] = historyMessage{Role: msg.Role, Content: msg.Content}
+	}
+
+	start, chars := len(history), 0
+	for start > 0 {
+		next := chars + len(history[start-1].Content)
+		if h.maxMessages > 0 && len(history)-start+1 > h.maxMessages {
+			break
+		}
+		if h.maxChars > 0 && next > h.maxChars {
+			break
+		}
+		start--
+		chars = next
+	}
+	return history[start:], history[:start]
+}
+
+// summarize asks the summarizer model for a summary of the compacted history turns
+func (h *historyForwarder) summarize(ctx context.Context, messages []historyMessage) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, h.summarizerTimeout)
+	defer cancel()
+
+	var transcript strings.Builder
+	for _, msg := range messages {
+		transcript.WriteString(msg.Role)
+		transcript.WriteString(": ")
+		transcript.WriteString(msg.Content)
+		transcript.WriteString("\n")
+	}
+	body, err := json.Marshal(models.OpenAIRequest{
+		Model: h.summarizerModel,
+		Messages: []models.OpenAIMessage{
+			{Role: "system", Content: h.summaryPrompt},
+			{Role: "user", Content: transcript.String()},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.summarizerEndpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set(headers.ContentType, "application/json")
+
+	resp, err := upstreamClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var result models.OpenAIResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("cannot parse summarizer response: %w", err)
+	}
+	if len(result.Choices) == 0 || result.Choices[0].Message.Content == "" {
+		return "", errors.New("summarizer returned no summary")
+	}
+	return result.Choices[0].Message.Content, nil
+}
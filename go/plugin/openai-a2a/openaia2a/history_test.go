@@ -0,0 +1,160 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+var longConversation = []models.OpenAIMessage{
+	{Role: "system", Content: "You are a travel assistant."},
+	{Role: "user", Content: "I want to visit Lisbon."},
+	{Role: "assistant", Content: "Great choice, when?"},
+	{Role: "user", Content: "In May."},
+	{Role: "assistant", Content: "May is sunny."},
+	{Role: "user", Content: "Book a hotel."},
+}
+
+func newHistoryHandler(t *testing.T, mockHandler http.Handler, history map[string]interface{}) http.Handler {
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "travel-agent", "url": "http://localhost:8001"},
+			},
+			"history": history,
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+	return handler
+}
+
+func receivedMetadata(t *testing.T, mockHandler *MockHandler) map[string]interface{} {
+	var a2aReq models.SendMessageRequest
+	assert.NoError(t, json.Unmarshal(mockHandler.ReceivedBody, &a2aReq))
+	return a2aReq.Params.Metadata
+}
+
+func TestNewHistoryForwarder_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  HistoryConfig
+	}{
+		{name: "negative limit", cfg: HistoryConfig{MaxChars: -1}},
+		{name: "unknown strategy", cfg: HistoryConfig{Strategy: "forget"}},
+		{name: "summarize without summarizer", cfg: HistoryConfig{Strategy: historySummarize}},
+		{name: "summarizer without model", cfg: HistoryConfig{Strategy: historySummarize, Summarizer: &SummarizerConfig{Endpoint: "http://summarizer:8080/chat/completions"}}},
+		{name: "invalid timeout", cfg: HistoryConfig{Strategy: historySummarize, Summarizer: &SummarizerConfig{Endpoint: "http://summarizer:8080/chat/completions", Model: "small", Timeout: "soon"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newHistoryForwarder(&tt.cfg)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestHistoryForwarder_Compact(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         HistoryConfig
+		wantKept    int
+		wantDropped int
+	}{
+		{name: "unlimited", cfg: HistoryConfig{}, wantKept: 5},
+		{name: "max messages", cfg: HistoryConfig{MaxMessages: 2}, wantKept: 2, wantDropped: 3},
+		{name: "max chars", cfg: HistoryConfig{MaxChars: 30}, wantKept: 2, wantDropped: 3},
+		{name: "newest message too long", cfg: HistoryConfig{MaxChars: 5}, wantKept: 0, wantDropped: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := newHistoryForwarder(&tt.cfg)
+			assert.NoError(t, err)
+
+			kept, dropped := h.compact(longConversation[:5])
+
+			assert.Len(t, kept, tt.wantKept)
+			assert.Len(t, dropped, tt.wantDropped)
+			if tt.wantKept > 0 {
+				assert.Equal(t, "May is sunny.", kept[len(kept)-1].Content)
+			}
+		})
+	}
+}
+
+func TestHistory_TruncatesOldestTurns(t *testing.T) {
+	mockHandler := &MockHandler{Response: []byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task"}}`), StatusCode: http.StatusOK}
+	handler := newHistoryHandler(t, mockHandler, map[string]interface{}{"max_messages": 2})
+
+	rec := sendChatCompletionMessages(handler, "travel-agent", longConversation)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	metadata := receivedMetadata(t, mockHandler)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"role": "user", "content": "In May."},
+		map[string]interface{}{"role": "assistant", "content": "May is sunny."},
+	}, metadata[historyMetadataKey])
+	assert.NotContains(t, metadata, historySummaryMetadataKey)
+}
+
+func TestHistory_SummarizesCompactedTurns(t *testing.T) {
+	var summarized models.OpenAIRequest
+	summarizer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&summarized)
+		_ = json.NewEncoder(w).Encode(models.OpenAIResponse{Choices: []models.OpenAIChoice{newChoice("User plans a trip to Lisbon.")}})
+	}))
+	defer summarizer.Close()
+
+	mockHandler := &MockHandler{Response: []byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task"}}`), StatusCode: http.StatusOK}
+	handler := newHistoryHandler(t, mockHandler, map[string]interface{}{
+		"max_messages": 1,
+		"strategy":     "summarize",
+		"summarizer":   map[string]interface{}{"endpoint": summarizer.URL, "model": "small-model"},
+	})
+
+	rec := sendChatCompletionMessages(handler, "travel-agent", longConversation)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "small-model", summarized.Model)
+	assert.Contains(t, summarized.Messages[1].Content, "user: I want to visit Lisbon.")
+	assert.NotContains(t, summarized.Messages[1].Content, "May is sunny.")
+	metadata := receivedMetadata(t, mockHandler)
+	assert.Equal(t, "User plans a trip to Lisbon.", metadata[historySummaryMetadataKey])
+	assert.Len(t, metadata[historyMetadataKey], 1)
+}
+
+func TestHistory_SummarizerFailureFallsBackToTruncation(t *testing.T) {
+	summarizer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer summarizer.Close()
+
+	mockHandler := &MockHandler{Response: []byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task"}}`), StatusCode: http.StatusOK}
+	handler := newHistoryHandler(t, mockHandler, map[string]interface{}{
+		"max_messages": 1,
+		"strategy":     "summarize",
+		"summarizer":   map[string]interface{}{"endpoint": summarizer.URL, "model": "small-model"},
+	})
+
+	rec := sendChatCompletionMessages(handler, "travel-agent", longConversation)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	metadata := receivedMetadata(t, mockHandler)
+	assert.NotContains(t, metadata, historySummaryMetadataKey)
+	assert.Len(t, metadata[historyMetadataKey], 1)
+}
+
+func newChoice(content string) models.OpenAIChoice {
+	choice := models.OpenAIChoice{FinishReason: "stop"}
+	choice.Message.Role = "assistant"
+	choice.Message.Content = content
+	return choice
+}
@@ -0,0 +1,54 @@
+package openaia2a
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const maxIDPrefixLength = 32
+
+// IDConfig controls gateway-originated IDs: A2A context, message and JSON-RPC request IDs,
+// task and artifact IDs of synthetic agents, and chat completion IDs. A prefix such as "gw-"
+// makes them recognizable in agent logs.
+type IDConfig struct {
+	Prefix string `json:"prefix"`
+}
+
+// idGenerator creates unique IDs. IDs are random, since task and context IDs must not be
+// guessable; tests replace the generator to get reproducible IDs.
+type idGenerator interface {
+	newID() string
+}
+
+// uuidGenerator creates random UUIDs
+type uuidGenerator struct {
+	prefix string
+}
+
+func (g uuidGenerator) newID() string {
+	return g.prefix + uuid.New().String()
+}
+
+// ids is the generator of the registered handler. Like the logger, it is shared by the plugin.
+var ids idGenerator = uuidGenerator{}
+
+// newID returns a new gateway-originated ID
+func newID() string {
+	return ids.newID()
+}
+
+// newIDGenerator validates the configuration. A nil config generates plain UUIDs.
+func newIDGenerator(cfg *IDConfig) (idGenerator, error) {
+	if cfg == nil {
+		return uuidGenerator{}, nil
+	}
+	if len(cfg.Prefix) > maxIDPrefixLength {
+		return nil, fmt.Errorf("prefix must not be longer than %d characters", maxIDPrefixLength)
+	}
+	if strings.ContainsFunc(cfg.Prefix, func(r rune) bool { return r <= ' ' || r > '~' }) {
+		return nil, fmt.Errorf("prefix '%s' must only contain printable ASCII characters", cfg.Prefix)
+	}
+	return uuidGenerator{prefix: cfg.Prefix}, nil
+}
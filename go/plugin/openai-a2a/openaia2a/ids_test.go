@@ -0,0 +1,94 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// sequentialGenerator counts up from 1, so test runs produce the same IDs
+type sequentialGenerator struct {
+	prefix string
+	last   atomic.Uint64
+}
+
+func (g *sequentialGenerator) newID() string {
+	return fmt.Sprintf("%s%d", g.prefix, g.last.Add(1))
+}
+
+func TestNewIDGenerator(t *testing.T) {
+	g, err := newIDGenerator(nil)
+	assert.NoError(t, err)
+	assert.Len(t, g.newID(), 36)
+
+	g, err = newIDGenerator(&IDConfig{Prefix: "gw-"})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(g.newID(), "gw-"))
+	assert.NotEqual(t, g.newID(), g.newID())
+}
+
+func TestNewIDGenerator_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  IDConfig
+	}{
+		{name: "whitespace in prefix", cfg: IDConfig{Prefix: "gw "}},
+		{name: "long prefix", cfg: IDConfig{Prefix: strings.Repeat("x", maxIDPrefixLength+1)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newIDGenerator(&tt.cfg)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestIDs_ReproducibleRequests(t *testing.T) {
+	defer func() { ids = uuidGenerator{} }()
+	mockHandler := &MockHandler{
+		Response:   []byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task","status":{"state":"completed"},"artifacts":[{"artifactId":"a","parts":[{"kind":"text","text":"Hi"}]}]}}`),
+		StatusCode: http.StatusOK,
+	}
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "agent", "url": "http://localhost:8001"},
+			},
+			"ids": map[string]interface{}{"prefix": "gw-"},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+	ids = &sequentialGenerator{prefix: "gw-"}
+
+	rec := sendChatCompletion(handler, "agent", "")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var a2aReq models.SendMessageRequest
+	assert.NoError(t, json.Unmarshal(mockHandler.ReceivedBody, &a2aReq))
+	assert.Equal(t, "gw-1", *a2aReq.Params.Message.ContextId)
+	assert.Equal(t, "gw-2", a2aReq.Params.Message.MessageId)
+	var openAIResp models.OpenAIResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &openAIResp))
+	assert.Equal(t, "gw-3", openAIResp.ID)
+}
+
+func TestIDs_NoPredictableGenerator(t *testing.T) {
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{},
+			"ids":    map[string]interface{}{"generator": "sequential"},
+		},
+	}
+	_, err := module.registerHandlers(context.Background(), extraConfig, &MockHandler{})
+	assert.ErrorContains(t, err, "additional properties 'generator' not allowed")
+}
@@ -0,0 +1,487 @@
+package openaia2a
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+)
+
+const (
+	defaultModelsClaim     = "allowed_models"
+	defaultJWKSRefresh     = time.Hour
+	defaultJWTLeeway       = 30 * time.Second
+	jwksFetchTimeout       = 5 * time.Second
+	minJWKSRefetchInterval = 10 * time.Second
+)
+
+// JWTConfig validates JWTs issued by an identity provider on the OpenAI-compatible endpoints.
+// The models claim lists the model IDs a user may call, or "*" for all models;
+// tokens without the claim may not call any model.
+type JWTConfig struct {
+	JWKSURL         string `json:"jwks_url"`
+	Issuer          string `json:"issuer"`
+	Audience        string `json:"audience"`
+	ModelsClaim     string `json:"models_claim"`
+	RefreshInterval string `json:"refresh_interval"`
+	Leeway          string `json:"leeway"`
+}
+
+// jwtHashes maps the supported signature algorithms to their hash.
+// Symmetric algorithms and "none" are rejected, since the keys come from a public JWKS.
+var jwtHashes = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// jwk is a JSON Web Key of a JWKS document
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// jwtIdentity is the authenticated user of a request
+type jwtIdentity struct {
+	subject string
+	models  []string
+	claims  map[string]interface{}
+}
+
+type jwtIdentityKey struct{}
+
+// jwtValidator validates JWTs against the keys of a JWKS endpoint. Keys are refreshed
+// periodically and when a token references an unknown key ID. Concurrent requests share
+// a single fetch, which runs without holding the lock.
+type jwtValidator struct {
+	jwksURL     string
+	issuer      string
+	audience    string
+	modelsClaim string
+	refresh     time.Duration
+	leeway      time.Duration
+	client      *http.Client
+	now         func() time.Time
+
+	mu          sync.Mutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+	fetchErr    error
+	// fetching is closed when the running fetch completes; nil while no fetch is running
+	fetching chan struct{}
+}
+
+// newJWTValidator validates the configuration. A nil config disables JWT validation.
+// Keys are fetched on first use, so an unavailable identity provider does not prevent startup.
+func newJWTValidator(cfg *JWTConfig) (*jwtValidator, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if parsed, err := url.Parse(cfg.JWKSURL); err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid jwks_url '%s'", cfg.JWKSURL)
+	}
+	v := &jwtValidator{
+		jwksURL:     cfg.JWKSURL,
+		issuer:      cfg.Issuer,
+		audience:    cfg.Audience,
+		modelsClaim: cfg.ModelsClaim,
+		refresh:     defaultJWKSRefresh,
+		leeway:      defaultJWTLeeway,
+		client:      upstreamClient,
+		now:         time.Now,
+	}
+	if v.modelsClaim == "" {
+		v.modelsClaim = defaultModelsClaim
+	}
+	var err error
+	if cfg.RefreshInterval != "" {
+		if v.refresh, err = time.ParseDuration(cfg.RefreshInterval); err != nil || v.refresh <= 0 {
+			return nil, fmt.Errorf("invalid refresh_interval '%s'", cfg.RefreshInterval)
+		}
+	}
+	if cfg.Leeway != "" {
+		if v.leeway, err = time.ParseDuration(cfg.Leeway); err != nil || v.leeway < 0 {
+			return nil, fmt.Errorf("invalid leeway '%s'", cfg.Leeway)
+		}
+	}
+	return v, nil
+}
+
+// looksLikeJWT reports whether a bearer token has the three segments of a JWS
+func looksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// bearerToken returns the bearer token of a request's Authorization header
+func bearerToken(req *http.Request) string {
+	token, _ := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return token
+}
+
+// authenticate validates the bearer JWT of a request and attaches the user's identity.
+// It writes an OpenAI 401 error and returns false on failure.
+func (v *jwtValidator) authenticate(w http.ResponseWriter, req *http.Request) (*http.Request, bool) {
+	log := logging.FromRequest(logger, req)
+	token := bearerToken(req)
+	if token == "" {
+		writeOpenAIError(w, http.StatusUnauthorized, models.OpenAIError{
+			Message: "You didn't provide a token. You need to provide a JWT in an Authorization header using Bearer auth.",
+			Type:    "invalid_request_error",
+		})
+		return req, false
+	}
+	identity, err := v.validate(req.Context(), token)
+	if err != nil {
+		log.Warning("rejected request with invalid JWT:", err)
+		code := "invalid_token"
+		writeOpenAIError(w, http.StatusUnauthorized, models.OpenAIError{
+			Message: "Invalid token.",
+			Type:    "invalid_request_error",
+			Code:    &code,
+		})
+		return req, false
+	}
+
+	log.Debug("authenticated principal:", identity.subject)
+	recordPrincipal(req.Context(), identity.subject)
+	ctx := context.WithValue(req.Context(), principalKey{}, identity.subject)
+	ctx = context.WithValue(ctx, jwtIdentityKey{}, identity)
+	return req.WithContext(ctx), true
+}
+
+// validate checks the signature and registered claims of a token and extracts the identity
+func (v *jwtValidator) validate(ctx context.Context, token string) (*jwtIdentity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("malformed header: %w", err)
+	}
+	hash, ok := jwtHashes[header.Alg]
+	if !ok {
+		return nil, fmt.Errorf("unsupported algorithm '%s'", header.Alg)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed signature")
+	}
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifySignature(header.Alg, hash, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed claims: %w", err)
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return nil, err
+	}
+	subject, _ := claims["sub"].(string)
+	return &jwtIdentity{subject: subject, models: stringList(claims[v.modelsClaim]), claims: claims}, nil
+}
+
+// checkClaims validates expiry, not-before, issuer and audience
+func (v *jwtValidator) checkClaims(claims map[string]interface{}) error {
+	now := v.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return errors.New("token has no expiry")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(v.leeway)) {
+		return errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(v.leeway).Before(time.Unix(int64(nbf), 0)) {
+		return errors.New("token not yet valid")
+	}
+	if v.issuer != "" && claims["iss"] != v.issuer {
+		return fmt.Errorf("unexpected issuer %v", claims["iss"])
+	}
+	if v.audience != "" && !slices.Contains(stringList(claims["aud"]), v.audience) {
+		return fmt.Errorf("token not issued for audience '%s'", v.audience)
+	}
+	return nil
+}
+
+// key returns the public key for a key ID. Stale keys stay in use while the JWKS is refreshed
+// in the background; requests with an unknown key ID wait for the refresh. Whether it succeeds
+// or not, the JWKS is fetched at most every minJWKSRefetchInterval, so tokens with made-up
+// key IDs cannot flood the identity provider.
+func (v *jwtValidator) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	key, known := v.keys[kid]
+	now := v.now()
+	fresh := now.Sub(v.fetchedAt) < v.refresh
+	recent := now.Sub(v.attemptedAt) < minJWKSRefetchInterval
+	if known && (fresh || recent) {
+		v.mu.Unlock()
+		return key, nil
+	}
+	if recent {
+		err := v.fetchErr
+		v.mu.Unlock()
+		return nil, unknownKey(kid, err)
+	}
+	done := v.refreshKeys()
+	v.mu.Unlock()
+	if known {
+		return key, nil
+	}
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if key, known = v.keys[kid]; known {
+		return key, nil
+	}
+	return nil, unknownKey(kid, v.fetchErr)
+}
+
+// refreshKeys starts fetching the JWKS unless a fetch is already running and returns a channel
+// closed once it completes. The fetch is not bound to the request that started it. v.mu must be held.
+func (v *jwtValidator) refreshKeys() chan struct{} {
+	if v.fetching != nil {
+		return v.fetching
+	}
+	done := make(chan struct{})
+	v.fetching = done
+	go func() {
+		keys, err := v.fetchKeys(context.Background())
+
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		v.attemptedAt = v.now()
+		v.fetchErr = err
+		if err != nil {
+			// Keep using cached keys while the identity provider is unavailable
+			logger.Warning("failed to refresh JWKS:", err)
+		} else {
+			v.keys = keys
+			v.fetchedAt = v.attemptedAt
+		}
+		v.fetching = nil
+		close(done)
+	}()
+	return done
+}
+
+func unknownKey(kid string, fetchErr error) error {
+	if fetchErr != nil {
+		return fmt.Errorf("cannot fetch JWKS: %w", fetchErr)
+	}
+	return fmt.Errorf("unknown key '%s'", kid)
+}
+
+// fetchKeys reads the JWKS document. Keys that are not meant for signatures or cannot be parsed are skipped.
+func (v *jwtValidator) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, jwksFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.jwksURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("cannot parse JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			logger.Warning(fmt.Sprintf("skipping JWKS key '%s': %v", k.Kid, err))
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+// publicKey converts an RSA or EC JSON Web Key
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve '%s'", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type '%s'", k.Kty)
+	}
+}
+
+// verifySignature checks a JWS signature with an RSA PKCS #1 v1.5 or ECDSA key
+func verifySignature(alg string, hash crypto.Hash, key crypto.PublicKey, signingInput, signature []byte) error {
+	h := hash.New()
+	h.Write(signingInput)
+	digest := h.Sum(nil)
+
+	switch pub := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(alg, "RS") {
+			return fmt.Errorf("algorithm %s does not match RSA key", alg)
+		}
+		if err := rsa.VerifyPKCS1v15(pub, hash, digest, signature); err != nil {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		if !strings.HasPrefix(alg, "ES") {
+			return fmt.Errorf("algorithm %s does not match EC key", alg)
+		}
+		// ECDSA signatures are the concatenated, fixed-size R and S values
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	default:
+		return errors.New("unsupported key")
+	}
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(raw) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
+
+// stringList reads a claim that is either a string array or a space-separated string
+func stringList(claim interface{}) []string {
+	switch v := claim.(type) {
+	case string:
+		return strings.Fields(v)
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// modelAllowed reports whether the JWT user of ctx may call a model.
+// Requests not authenticated by JWT are not restricted.
+func modelAllowed(ctx context.Context, modelID string) bool {
+	identity, ok := ctx.Value(jwtIdentityKey{}).(*jwtIdentity)
+	if !ok {
+		return true
+	}
+	return slices.Contains(identity.models, allModels) || slices.Contains(identity.models, modelID)
+}
+
+// allowedAgents filters /models to the agents the JWT user of ctx may call
+func allowedAgents(ctx context.Context, agents []AgentInfo) []AgentInfo {
+	if _, ok := ctx.Value(jwtIdentityKey{}).(*jwtIdentity); !ok {
+		return agents
+	}
+	allowed := make([]AgentInfo, 0, len(agents))
+	for _, agent := range agents {
+		if modelAllowed(ctx, agent.ModelID) {
+			allowed = append(allowed, agent)
+		}
+	}
+	return allowed
+}
@@ -0,0 +1,358 @@
+package openaia2a
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// testIdP serves a JWKS with one RSA and one EC key and signs tokens with them
+type testIdP struct {
+	server  *httptest.Server
+	rsaKey  *rsa.PrivateKey
+	ecKey   *ecdsa.PrivateKey
+	fetches atomic.Int32
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	idp := &testIdP{rsaKey: rsaKey, ecKey: ecKey}
+	encode := func(n *big.Int) string { return base64.RawURLEncoding.EncodeToString(n.Bytes()) }
+	jwks := map[string]interface{}{"keys": []interface{}{
+		map[string]interface{}{"kid": "rsa-1", "kty": "RSA", "use": "sig", "n": encode(rsaKey.N), "e": encode(big.NewInt(int64(rsaKey.E)))},
+		map[string]interface{}{"kid": "ec-1", "kty": "EC", "crv": "P-256", "x": encode(ecKey.X), "y": encode(ecKey.Y)},
+	}}
+	idp.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idp.fetches.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(jwks)
+	}))
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *testIdP) sign(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	encode := func(v interface{}) string {
+		raw, err := json.Marshal(v)
+		assert.NoError(t, err)
+		return base64.RawURLEncoding.EncodeToString(raw)
+	}
+	signingInput := encode(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + encode(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+
+	var signature []byte
+	switch alg {
+	case "RS256":
+		var err error
+		signature, err = rsa.SignPKCS1v15(rand.Reader, idp.rsaKey, crypto.SHA256, digest[:])
+		assert.NoError(t, err)
+	case "ES256":
+		r, s, err := ecdsa.Sign(rand.Reader, idp.ecKey, digest[:])
+		assert.NoError(t, err)
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func validClaims(models interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"sub":            "alice@example.com",
+		"iss":            "https://idp.example.com",
+		"aud":            []string{"agent-gateway"},
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"allowed_models": models,
+	}
+}
+
+func newJWTHandler(t *testing.T, idp *testIdP, mockHandler http.Handler) http.Handler {
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "weather-agent", "url": "http://localhost:8001"},
+				map[string]interface{}{"model_id": "finance-agent", "url": "http://localhost:8002"},
+			},
+			"jwt": map[string]interface{}{
+				"jwks_url": idp.server.URL,
+				"issuer":   "https://idp.example.com",
+				"audience": "agent-gateway",
+			},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+	return handler
+}
+
+func TestNewJWTValidator_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  JWTConfig
+	}{
+		{name: "missing jwks_url", cfg: JWTConfig{}},
+		{name: "invalid refresh_interval", cfg: JWTConfig{JWKSURL: "https://idp.example.com/jwks", RefreshInterval: "hourly"}},
+		{name: "negative leeway", cfg: JWTConfig{JWKSURL: "https://idp.example.com/jwks", Leeway: "-1s"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newJWTValidator(&tt.cfg)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestJWT_AuthorizesModelsByClaim(t *testing.T) {
+	idp := newTestIdP(t)
+	mockHandler := &MockHandler{Response: []byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task"}}`), StatusCode: http.StatusOK}
+	handler := newJWTHandler(t, idp, mockHandler)
+
+	weatherOnly := validClaims([]string{"weather-agent"})
+	expired := validClaims("*")
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	wrongAudience := validClaims("*")
+	wrongAudience["aud"] = "other-service"
+	noExpiry := validClaims("*")
+	delete(noExpiry, "exp")
+
+	tests := []struct {
+		name       string
+		model      string
+		token      string
+		wantStatus int
+	}{
+		{name: "allowed model", model: "weather-agent", token: idp.sign(t, "RS256", "rsa-1", weatherOnly), wantStatus: http.StatusOK},
+		{name: "model outside claim", model: "finance-agent", token: idp.sign(t, "RS256", "rsa-1", weatherOnly), wantStatus: http.StatusNotFound},
+		{name: "wildcard claim", model: "finance-agent", token: idp.sign(t, "ES256", "ec-1", validClaims("*")), wantStatus: http.StatusOK},
+		{name: "space-separated claim", model: "finance-agent", token: idp.sign(t, "RS256", "rsa-1", validClaims("weather-agent finance-agent")), wantStatus: http.StatusOK},
+		{name: "missing claim", model: "weather-agent", token: idp.sign(t, "RS256", "rsa-1", validClaims(nil)), wantStatus: http.StatusNotFound},
+		{name: "missing token", model: "weather-agent", wantStatus: http.StatusUnauthorized},
+		{name: "expired", model: "weather-agent", token: idp.sign(t, "RS256", "rsa-1", expired), wantStatus: http.StatusUnauthorized},
+		{name: "no expiry", model: "weather-agent", token: idp.sign(t, "RS256", "rsa-1", noExpiry), wantStatus: http.StatusUnauthorized},
+		{name: "wrong audience", model: "weather-agent", token: idp.sign(t, "RS256", "rsa-1", wrongAudience), wantStatus: http.StatusUnauthorized},
+		{name: "unknown key", model: "weather-agent", token: idp.sign(t, "RS256", "rsa-2", weatherOnly), wantStatus: http.StatusUnauthorized},
+		{name: "algorithm mismatch", model: "weather-agent", token: idp.sign(t, "ES256", "rsa-1", weatherOnly), wantStatus: http.StatusUnauthorized},
+		{name: "unsigned", model: "weather-agent", token: idp.sign(t, "none", "rsa-1", weatherOnly), wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := sendChatCompletion(handler, tt.model, tt.token)
+
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantStatus == http.StatusUnauthorized {
+				var errResp models.OpenAIErrorResponse
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+				assert.Equal(t, "invalid_request_error", errResp.Error.Type)
+			}
+		})
+	}
+}
+
+func TestJWT_ModelsFilteredByClaim(t *testing.T) {
+	idp := newTestIdP(t)
+	handler := newJWTHandler(t, idp, &MockHandler{})
+
+	req := httptest.NewRequest(http.MethodGet, "/models", nil)
+	req.Header.Set("Authorization", "Bearer "+idp.sign(t, "RS256", "rsa-1", validClaims([]string{"finance-agent"})))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp models.OpenAIModelsResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Len(t, resp.Data, 1)
+	assert.Equal(t, "finance-agent", resp.Data[0].ID)
+}
+
+func TestJWT_NativeA2ARestrictedByClaim(t *testing.T) {
+	idp := newTestIdP(t)
+	mockHandler := &MockHandler{Response: []byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task"}}`), StatusCode: http.StatusOK}
+	handler := newJWTHandler(t, idp, mockHandler)
+
+	req := httptest.NewRequest(http.MethodPost, "/finance-agent", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"message/send","params":{"message":{"role":"user","messageId":"m1","parts":[{"kind":"text","text":"Hi"}]}}}`))
+	req.Header.Set("Authorization", "Bearer "+idp.sign(t, "RS256", "rsa-1", validClaims([]string{"weather-agent"})))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), "agent finance-agent not found")
+	assert.Nil(t, mockHandler.ReceivedRequest)
+}
+
+func TestJWTValidator_CachesKeys(t *testing.T) {
+	idp := newTestIdP(t)
+	v, err := newJWTValidator(&JWTConfig{JWKSURL: idp.server.URL, RefreshInterval: "1m"})
+	assert.NoError(t, err)
+	now := time.Now()
+	v.now = func() time.Time { return now }
+
+	token := idp.sign(t, "RS256", "rsa-1", validClaims("*"))
+	for range 3 {
+		_, err := v.validate(context.Background(), token)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, int32(1), idp.fetches.Load())
+
+	// Unknown key IDs do not refetch more often than minJWKSRefetchInterval
+	_, err = v.validate(context.Background(), idp.sign(t, "RS256", "rsa-2", validClaims("*")))
+	assert.Error(t, err)
+	assert.Equal(t, int32(1), idp.fetches.Load())
+
+	// Stale keys are refreshed in the background
+	now = now.Add(2 * time.Minute)
+	identity, err := v.validate(context.Background(), token)
+	assert.NoError(t, err)
+	assert.Equal(t, "alice@example.com", identity.subject)
+	assert.Eventually(t, func() bool { return idp.fetches.Load() == 2 }, time.Second, 5*time.Millisecond)
+}
+
+func TestJWTValidator_SharesKeyFetches(t *testing.T) {
+	idp := newTestIdP(t)
+	release := make(chan struct{})
+	jwks := idp.server.Config.Handler
+	idp.server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		jwks.ServeHTTP(w, r)
+	})
+	v, err := newJWTValidator(&JWTConfig{JWKSURL: idp.server.URL})
+	assert.NoError(t, err)
+
+	token := idp.sign(t, "RS256", "rsa-1", validClaims("*"))
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := v.validate(context.Background(), token)
+			assert.NoError(t, err)
+		}()
+	}
+
+	// A request giving up does not cancel the fetch others wait for
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = v.validate(ctx, token)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The lock is not held during the fetch
+	assert.Eventually(t, func() bool {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		return v.fetching != nil
+	}, time.Second, 5*time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), idp.fetches.Load())
+}
+
+func TestJWTValidator_RateLimitsFailedFetches(t *testing.T) {
+	var fetches atomic.Int32
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(jwks.Close)
+	idp := newTestIdP(t)
+	v, err := newJWTValidator(&JWTConfig{JWKSURL: jwks.URL})
+	assert.NoError(t, err)
+	now := time.Now()
+	v.now = func() time.Time { return now }
+
+	for _, kid := range []string{"rsa-1", "made-up-1", "made-up-2"} {
+		_, err := v.validate(context.Background(), idp.sign(t, "RS256", kid, validClaims("*")))
+		assert.ErrorContains(t, err, "cannot fetch JWKS")
+	}
+	assert.Equal(t, int32(1), fetches.Load())
+
+	now = now.Add(minJWKSRefetchInterval)
+	_, err = v.validate(context.Background(), idp.sign(t, "RS256", "made-up-3", validClaims("*")))
+	assert.Error(t, err)
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+func TestJWTValidator_RejectsForgedTokens(t *testing.T) {
+	idp := newTestIdP(t)
+	v, err := newJWTValidator(&JWTConfig{JWKSURL: idp.server.URL})
+	assert.NoError(t, err)
+
+	encode := func(v interface{}) string {
+		raw, err := json.Marshal(v)
+		assert.NoError(t, err)
+		return base64.RawURLEncoding.EncodeToString(raw)
+	}
+	// hmacToken signs with the RSA public key as HMAC secret, as libraries that take the
+	// algorithm from the token would verify it
+	hmacToken := func(alg string) string {
+		publicKey, err := x509.MarshalPKIXPublicKey(&idp.rsaKey.PublicKey)
+		assert.NoError(t, err)
+		secret := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKey})
+		signingInput := encode(map[string]string{"alg": alg, "kid": "rsa-1", "typ": "JWT"}) + "." + encode(validClaims("*"))
+		mac := hmac.New(sha256.New, secret)
+		mac.Write([]byte(signingInput))
+		return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	}
+	unsigned := func(alg string) string {
+		return encode(map[string]string{"alg": alg, "kid": "rsa-1"}) + "." + encode(validClaims("*")) + "."
+	}
+	claims := func(change func(map[string]interface{})) string {
+		c := validClaims("*")
+		change(c)
+		return idp.sign(t, "RS256", "rsa-1", c)
+	}
+	valid := idp.sign(t, "RS256", "rsa-1", validClaims("*"))
+	parts := strings.Split(valid, ".")
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr string
+	}{
+		{name: "HS256 with the public key as secret", token: hmacToken("HS256"), wantErr: "unsupported algorithm 'HS256'"},
+		{name: "HS512 with the public key as secret", token: hmacToken("HS512"), wantErr: "unsupported algorithm 'HS512'"},
+		{name: "alg none", token: unsigned("none"), wantErr: "unsupported algorithm 'none'"},
+		{name: "alg None", token: unsigned("None"), wantErr: "unsupported algorithm 'None'"},
+		{name: "RS256 without signature", token: unsigned("RS256"), wantErr: "invalid signature"},
+		{name: "EC algorithm for RSA key", token: idp.sign(t, "ES256", "rsa-1", validClaims("*")), wantErr: "does not match RSA key"},
+		{name: "tampered claims", token: parts[0] + "." + encode(map[string]interface{}{"sub": "mallory", "exp": time.Now().Add(time.Hour).Unix()}) + "." + parts[2], wantErr: "invalid signature"},
+		{name: "missing exp", token: claims(func(c map[string]interface{}) { delete(c, "exp") }), wantErr: "token has no expiry"},
+		{name: "exp as string", token: claims(func(c map[string]interface{}) { c["exp"] = "4102444800" }), wantErr: "token has no expiry"},
+		{name: "expired", token: claims(func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Minute).Unix() }), wantErr: "token expired"},
+		{name: "nbf in the future", token: claims(func(c map[string]interface{}) { c["nbf"] = time.Now().Add(time.Hour).Unix() }), wantErr: "token not yet valid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := v.validate(context.Background(), tt.token)
+
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+
+	// Missing nbf is accepted; the token is valid from the start
+	_, err = v.validate(context.Background(), valid)
+	assert.NoError(t, err)
+}
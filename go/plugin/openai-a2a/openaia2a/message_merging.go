@@ -0,0 +1,84 @@
+package openaia2a
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+)
+
+// Message merging modes decide which OpenAI messages form the A2A message
+const (
+	// mergeAfterAssistant combines all messages after the last assistant message
+	mergeAfterAssistant = "after_assistant"
+	// mergeTrailing combines the trailing messages that share the role of the last message
+	mergeTrailing = "trailing"
+	// mergeStrict requires exactly one trailing user message
+	mergeStrict = "strict"
+)
+
+// validateMessageMerging checks the configured mode. An empty mode selects mergeAfterAssistant.
+func validateMessageMerging(mode string) error {
+	switch mode {
+	case "", mergeAfterAssistant, mergeTrailing, mergeStrict:
+		return nil
+	default:
+		return fmt.Errorf("unknown message_merging mode '%s', expected one of %s, %s, %s",
+			mode, mergeAfterAssistant, mergeTrailing, mergeStrict)
+	}
+}
+
+// mergeMessages combines the messages selected by mode into the text of the A2A message
+func mergeMessages(messages []models.OpenAIMessage, mode string) (string, error) {
+	start, err := mergeStart(messages, mode)
+	if err != nil {
+		return "", err
+	}
+	contents := make([]string, 0, len(messages)-start)
+	for _, msg := range messages[start:] {
+		contents = append(contents, msg.Content)
+	}
+	return strings.Join(contents, "\n"), nil
+}
+
+// mergeStart returns the index of the first message selected by mode; earlier messages are history.
+// Requests ending with an assistant message have no prompt and are rejected in all modes.
+func mergeStart(messages []models.OpenAIMessage, mode string) (int, error) {
+	n := len(messages)
+	if n == 0 {
+		return 0, &RequestValidationError{Param: "messages", Message: "at least one message is required"}
+	}
+	last := messages[n-1]
+	if last.Role == "assistant" {
+		return 0, &RequestValidationError{
+			Param:   fmt.Sprintf("messages[%d].role", n-1),
+			Message: "the last message must not be an assistant message, there is nothing to send to the agent",
+		}
+	}
+
+	start := n - 1
+	switch mode {
+	case mergeTrailing:
+		for start > 0 && messages[start-1].Role == last.Role {
+			start--
+		}
+	case mergeStrict:
+		if last.Role != "user" {
+			return 0, &RequestValidationError{
+				Param:   fmt.Sprintf("messages[%d].role", n-1),
+				Message: fmt.Sprintf("the last message must be a user message, got '%s'", last.Role),
+			}
+		}
+		if n > 1 && messages[n-2].Role == "user" {
+			return 0, &RequestValidationError{
+				Param:   "messages",
+				Message: "multiple trailing user messages are not allowed, combine them into one message",
+			}
+		}
+	default:
+		for start > 0 && messages[start-1].Role != "assistant" {
+			start--
+		}
+	}
+	return start, nil
+}
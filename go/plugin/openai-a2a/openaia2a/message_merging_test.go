@@ -0,0 +1,99 @@
+package openaia2a
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func sendChatCompletionMessages(handler http.Handler, model string, messages []models.OpenAIMessage) *httptest.ResponseRecorder {
+	reqBody, _ := json.Marshal(models.OpenAIRequest{Model: model, Messages: messages})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat/completions", bytes.NewReader(reqBody)))
+	return rec
+}
+
+func TestMergeMessages(t *testing.T) {
+	toolResult := []models.OpenAIMessage{
+		{Role: "user", Content: "Weather in Berlin?"},
+		{Role: "assistant", Content: ""},
+		{Role: "tool", Content: `{"temp": 21}`},
+	}
+	trailingUsers := []models.OpenAIMessage{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "Weather in Berlin?"},
+		{Role: "user", Content: "And tomorrow?"},
+	}
+	endsWithAssistant := []models.OpenAIMessage{
+		{Role: "user", Content: "Weather in Berlin?"},
+		{Role: "assistant", Content: "Sunny."},
+	}
+
+	tests := []struct {
+		name      string
+		messages  []models.OpenAIMessage
+		mode      string
+		want      string
+		wantParam string
+	}{
+		{name: "after assistant combines everything without assistant", messages: trailingUsers, mode: mergeAfterAssistant, want: "Be brief.\nWeather in Berlin?\nAnd tomorrow?"},
+		{name: "after assistant keeps tool results", messages: toolResult, mode: "", want: `{"temp": 21}`},
+		{name: "trailing combines same-role messages", messages: trailingUsers, mode: mergeTrailing, want: "Weather in Berlin?\nAnd tomorrow?"},
+		{name: "trailing tool messages", messages: toolResult, mode: mergeTrailing, want: `{"temp": 21}`},
+		{name: "strict single user message", messages: endsWithAssistant[:1], mode: mergeStrict, want: "Weather in Berlin?"},
+		{name: "strict rejects trailing user messages", messages: trailingUsers, mode: mergeStrict, wantParam: "messages"},
+		{name: "strict rejects tool message", messages: toolResult, mode: mergeStrict, wantParam: "messages[2].role"},
+		{name: "assistant message last", messages: endsWithAssistant, mode: mergeTrailing, wantParam: "messages[1].role"},
+		{name: "no messages", messages: nil, mode: "", wantParam: "messages"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := mergeMessages(tt.messages, tt.mode)
+
+			if tt.wantParam != "" {
+				var validationErr *RequestValidationError
+				assert.ErrorAs(t, err, &validationErr)
+				assert.Equal(t, tt.wantParam, validationErr.Param)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, content)
+		})
+	}
+}
+
+func TestChatCompletions_RejectsTrailingAssistantMessage(t *testing.T) {
+	mockHandler := &MockHandler{}
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents":          []interface{}{map[string]interface{}{"model_id": "agent", "url": "http://localhost:8001"}},
+			"message_merging": mergeTrailing,
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+
+	rec := sendChatCompletionMessages(handler, "agent", []models.OpenAIMessage{
+		{Role: "user", Content: "Hello"},
+		{Role: "assistant", Content: "Hi!"},
+	})
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Nil(t, mockHandler.ReceivedRequest)
+	var errResp models.OpenAIErrorResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	assert.Equal(t, "messages[1].role", *errResp.Error.Param)
+}
+
+func TestValidateMessageMerging(t *testing.T) {
+	assert.NoError(t, validateMessageMerging(""))
+	assert.NoError(t, validateMessageMerging(mergeStrict))
+	assert.Error(t, validateMessageMerging("last"))
+}
@@ -0,0 +1,98 @@
+package openaia2a
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+)
+
+const (
+	defaultMirrorTimeout = 30 * time.Second
+	// maxMirrorRequests limits the mirrored requests in flight, so a slow shadow agent cannot
+	// accumulate goroutines; requests beyond the limit are not mirrored
+	maxMirrorRequests = 64
+)
+
+// MirrorConfig duplicates a percentage of a model's chat completions to a shadow agent, e.g. a
+// replacement under evaluation. Mirrored responses are discarded and never reach clients.
+type MirrorConfig struct {
+	URL        string  `json:"url"`
+	Percentage float64 `json:"percentage"`
+	Timeout    string  `json:"timeout,omitempty"`
+}
+
+// mirrorRoll returns a random number in [0, 100) used to sample mirrored requests
+var mirrorRoll = func() float64 {
+	return rand.Float64() * 100
+}
+
+// mirrorSlots holds a token for every mirrored request in flight
+var mirrorSlots = make(chan struct{}, maxMirrorRequests)
+
+// timeout returns the configured timeout of mirrored requests or the default
+func (m *MirrorConfig) timeout() (time.Duration, error) {
+	if m.Timeout == "" {
+		return defaultMirrorTimeout, nil
+	}
+	d, err := time.ParseDuration(m.Timeout)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid timeout '%s'", m.Timeout)
+	}
+	return d, nil
+}
+
+// validateMirrors checks the mirror configuration of all agents
+func validateMirrors(agents []AgentInfo) error {
+	for _, agent := range agents {
+		if agent.MirrorTo == nil {
+			continue
+		}
+		if parsed, err := url.Parse(agent.MirrorTo.URL); err != nil || parsed.Host == "" {
+			return fmt.Errorf("agent %s: invalid mirror URL '%s'", agent.ModelID, agent.MirrorTo.URL)
+		}
+		if agent.MirrorTo.Percentage < 0 || agent.MirrorTo.Percentage > 100 {
+			return fmt.Errorf("agent %s: mirror percentage must be between 0 and 100, got %v", agent.ModelID, agent.MirrorTo.Percentage)
+		}
+		if _, err := agent.MirrorTo.timeout(); err != nil {
+			return fmt.Errorf("agent %s: %w", agent.ModelID, err)
+		}
+	}
+	return nil
+}
+
+// mirrorRequest sends a sampled copy of the A2A request of agent to its shadow agent in the
+// background. The copy outlives the client request; its outcome is only logged.
+func mirrorRequest(req *http.Request, agent AgentInfo, body []byte) {
+	mirror := agent.MirrorTo
+	if mirror == nil || mirrorRoll() >= mirror.Percentage {
+		return
+	}
+	log := logging.FromRequest(logger, req)
+	select {
+	case mirrorSlots <- struct{}{}:
+	default:
+		log.Warning(fmt.Sprintf("not mirroring model %s: %d mirrored requests in flight", agent.ModelID, maxMirrorRequests))
+		return
+	}
+
+	// Validated when agents are loaded
+	timeout, _ := mirror.timeout()
+	header := agentHeader(req)
+	go func() {
+		defer func() { <-mirrorSlots }()
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		start := time.Now()
+		if _, err := sendA2A(ctx, mirror.URL, body, header); err != nil {
+			log.Warning(fmt.Sprintf("mirrored request of model %s to %s failed: %v", agent.ModelID, mirror.URL, err))
+			return
+		}
+		log.Debug(fmt.Sprintf("mirrored request of model %s to %s answered in %s", agent.ModelID, mirror.URL, time.Since(start).Round(time.Millisecond)))
+	}()
+}
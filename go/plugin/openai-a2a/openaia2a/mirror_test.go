@@ -0,0 +1,104 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/testagent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateMirrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		mirror  MirrorConfig
+		wantErr bool
+	}{
+		{name: "valid", mirror: MirrorConfig{URL: "http://agent-v2:8000", Percentage: 10, Timeout: "5s"}},
+		{name: "invalid URL", mirror: MirrorConfig{URL: "agent-v2", Percentage: 10}, wantErr: true},
+		{name: "percentage above 100", mirror: MirrorConfig{URL: "http://agent-v2:8000", Percentage: 101}, wantErr: true},
+		{name: "negative percentage", mirror: MirrorConfig{URL: "http://agent-v2:8000", Percentage: -1}, wantErr: true},
+		{name: "invalid timeout", mirror: MirrorConfig{URL: "http://agent-v2:8000", Timeout: "soon"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMirrors([]AgentInfo{{ModelID: "agent", MirrorTo: &tt.mirror}})
+
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestChatCompletions_Mirror(t *testing.T) {
+	originalRoll := mirrorRoll
+	t.Cleanup(func() { mirrorRoll = originalRoll })
+
+	tests := []struct {
+		name       string
+		roll       float64
+		shadow     testagent.Response
+		wantMirror bool
+	}{
+		{name: "sampled", roll: 9.9, shadow: testagent.Response{Text: "shadow answer"}, wantMirror: true},
+		{name: "not sampled", roll: 10, shadow: testagent.Response{Text: "shadow answer"}},
+		{name: "failing shadow", roll: 0, shadow: testagent.Response{StatusCode: http.StatusInternalServerError}, wantMirror: true},
+		{name: "slow shadow", roll: 0, shadow: testagent.Response{Text: "late", Latency: time.Second}, wantMirror: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mirrorRoll = func() float64 { return tt.roll }
+			shadow := testagent.New(testagent.Options{Handler: func(models.Message) testagent.Response { return tt.shadow }})
+			t.Cleanup(shadow.Close)
+
+			extraConfig := map[string]interface{}{
+				"openai_a2a_config": map[string]interface{}{
+					"agents": []interface{}{
+						map[string]interface{}{
+							"model_id":  "test-agent",
+							"url":       "http://localhost:8001",
+							"mirror_to": map[string]interface{}{"url": shadow.URL, "percentage": 10},
+						},
+					},
+				},
+			}
+			mockHandler := &MockHandler{
+				Response: []byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task","artifacts":[{"artifactId":"a","parts":[{"kind":"text","text":"stable answer"}]}]}}`),
+			}
+			handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+			require.NoError(t, err)
+
+			start := time.Now()
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/chat/completions", strings.NewReader(`{"model":"test-agent","messages":[{"role":"user","content":"Hello"}]}`))
+			req.Header.Set("X-Conversation-ID", "conv-1")
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, http.StatusOK, rec.Code)
+			assert.Less(t, time.Since(start), 500*time.Millisecond, "the client does not wait for the shadow agent")
+			var resp models.OpenAIResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, "stable answer", resp.Choices[0].Message.Content)
+
+			if !tt.wantMirror {
+				time.Sleep(50 * time.Millisecond)
+				assert.Empty(t, shadow.Requests())
+				return
+			}
+			require.Eventually(t, func() bool { return len(shadow.Requests()) == 1 }, time.Second, 10*time.Millisecond)
+			mirrored := shadow.Requests()[0]
+			assert.Equal(t, "message/send", mirrored.Method)
+			var params models.MessageSendParams
+			require.NoError(t, json.Unmarshal(mirrored.Params, &params))
+			assert.Equal(t, "Hello", testagent.MessageText(params.Message))
+		})
+	}
+}
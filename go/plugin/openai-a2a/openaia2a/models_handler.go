@@ -0,0 +1,93 @@
+package openaia2a
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+)
+
+// handleModelsRequest handles GET /models requests by returning agents in OpenAI-compatible format.
+// Agents are provided via plugin configuration, their capabilities by discovery if enabled.
+func handleModelsRequest(w http.ResponseWriter, req *http.Request, agents []AgentInfo, discovery *capabilityDiscovery) {
+	log := logging.FromRequest(logger, req)
+	if req.Method != http.MethodGet {
+		log.Debug("invalid method for /models:", req.Method)
+		writeError(w, req, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	log.Debug(fmt.Sprintf("handling /models request with %d configured agents", len(agents)))
+
+	// Build OpenAI models response from configured agents
+	modelsList := make([]models.OpenAIModel, 0, len(agents))
+	cards := discovery.cards(req.Context(), agents)
+	for i, agent := range agents {
+		// Dates are validated when agents are loaded
+		deprecatedAt, sunset, _ := deprecationDates(agent)
+		modelsList = append(modelsList, models.OpenAIModel{
+			ID:           agent.ModelID,
+			Object:       "model",
+			Created:      agent.CreatedAt,
+			OwnedBy:      agent.OwnedBy,
+			DeprecatedAt: unixOrZero(deprecatedAt),
+			Sunset:       unixOrZero(sunset),
+
+			Description:      agent.Description,
+			Capabilities:     agent.Capabilities,
+			MaxContextLength: agent.MaxContextLength,
+			Tags:             agent.Tags,
+
+			AgentCapabilities: openAICapabilities(cards[i]),
+		})
+	}
+
+	response := models.OpenAIModelsResponse{
+		Object: "list",
+		Data:   modelsList,
+	}
+
+	// Marshal and send response
+	responseBody, err := json.Marshal(response)
+	if err != nil {
+		log.Error("failed to marshal response:", err)
+		writeError(w, req, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	log.Debug(fmt.Sprintf("returning %d models", len(modelsList)))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(responseBody); err != nil {
+		log.Error("failed to write response:", err)
+	}
+}
+
+// openAICapabilities lists the capabilities of an agent card for /models, nil if the card is unknown
+func openAICapabilities(card *models.AgentCard) *models.OpenAIAgentCapabilities {
+	if card == nil {
+		return nil
+	}
+	return &models.OpenAIAgentCapabilities{Streaming: declared(card.Capabilities.Streaming), PushNotifications: declared(card.Capabilities.PushNotifications)}
+}
+
+// validateMetadata checks the model metadata of all agents
+func validateMetadata(agents []AgentInfo) error {
+	for _, agent := range agents {
+		if agent.MaxContextLength < 0 {
+			return fmt.Errorf("agent %s: max_context_length %d is negative", agent.ModelID, agent.MaxContextLength)
+		}
+		for _, values := range [][]string{agent.Capabilities, agent.Tags} {
+			for _, value := range values {
+				if strings.TrimSpace(value) == "" {
+					return fmt.Errorf("agent %s: capabilities and tags must not be empty", agent.ModelID)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModels_Metadata(t *testing.T) {
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "plain-agent", "url": "http://localhost:8001"},
+				map[string]interface{}{
+					"model_id":           "weather-agent",
+					"url":                "http://localhost:8002",
+					"description":        "Forecasts for any city",
+					"capabilities":       []interface{}{"streaming", "files"},
+					"max_context_length": 32000,
+					"tags":               []interface{}{"weather", "beta"},
+				},
+			},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, &MockHandler{})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/models", nil))
+
+	var resp models.OpenAIModelsResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 2)
+	assert.Equal(t, models.OpenAIModel{
+		ID:               "weather-agent",
+		Object:           "model",
+		Description:      "Forecasts for any city",
+		Capabilities:     []string{"streaming", "files"},
+		MaxContextLength: 32000,
+		Tags:             []string{"weather", "beta"},
+	}, resp.Data[1])
+
+	var raw struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &raw))
+	assert.NotContains(t, raw.Data[0], "description", "unset metadata is omitted")
+	assert.NotContains(t, raw.Data[0], "tags")
+}
+
+func TestValidateMetadata(t *testing.T) {
+	tests := []struct {
+		name    string
+		agent   AgentInfo
+		wantErr string
+	}{
+		{name: "unset", agent: AgentInfo{ModelID: "a"}},
+		{name: "complete", agent: AgentInfo{ModelID: "a", Capabilities: []string{"streaming"}, MaxContextLength: 8192, Tags: []string{"x"}}},
+		{name: "negative context length", agent: AgentInfo{ModelID: "a", MaxContextLength: -1}, wantErr: "max_context_length -1 is negative"},
+		{name: "empty tag", agent: AgentInfo{ModelID: "a", Tags: []string{" "}}, wantErr: "must not be empty"},
+		{name: "empty capability", agent: AgentInfo{ModelID: "a", Capabilities: []string{""}}, wantErr: "must not be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateMetadata([]AgentInfo{tt.agent})
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
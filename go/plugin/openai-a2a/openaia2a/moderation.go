@@ -0,0 +1,236 @@
+package openaia2a
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/go-http-utils/headers"
+)
+
+// contentFilter is the OpenAI error code and finish reason for moderated content
+const contentFilter = "content_filter"
+
+const defaultModerationTimeout = 2 * time.Second
+
+// ModerationConfig rejects requests, and optionally redacts responses, whose text matches
+// a keyword denylist, a regular expression or is flagged by an external moderation endpoint.
+// The endpoint must implement the OpenAI moderation API.
+type ModerationConfig struct {
+	Denylist      []string `json:"denylist"`
+	Patterns      []string `json:"patterns"`
+	Endpoint      string   `json:"endpoint"`
+	Timeout       string   `json:"timeout"`
+	FailOpen      bool     `json:"fail_open"`
+	ScanResponses bool     `json:"scan_responses"`
+}
+
+// moderationRequest and moderationResponse follow the OpenAI moderation API
+type moderationRequest struct {
+	Input string `json:"input"`
+}
+
+type moderationResponse struct {
+	Results []struct {
+		Flagged    bool            `json:"flagged"`
+		Categories map[string]bool `json:"categories"`
+	} `json:"results"`
+}
+
+// moderator checks text against the configured guardrails
+type moderator struct {
+	denylist      []string
+	patterns      []*regexp.Regexp
+	endpoint      string
+	timeout       time.Duration
+	failOpen      bool
+	scanResponses bool
+}
+
+// newModerator validates the configuration. A nil config disables moderation.
+func newModerator(cfg *ModerationConfig) (*moderator, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	m := &moderator{
+		endpoint:      cfg.Endpoint,
+		timeout:       defaultModerationTimeout,
+		failOpen:      cfg.FailOpen,
+		scanResponses: cfg.ScanResponses,
+	}
+	for _, keyword := range cfg.Denylist {
+		if keyword == "" {
+			return nil, errors.New("denylist contains an empty keyword")
+		}
+		m.denylist = append(m.denylist, strings.ToLower(keyword))
+	}
+	for _, pattern := range cfg.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+		}
+		m.patterns = append(m.patterns, re)
+	}
+	if m.endpoint != "" {
+		if parsed, err := url.Parse(m.endpoint); err != nil || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid endpoint '%s'", m.endpoint)
+		}
+	}
+	if cfg.Timeout != "" {
+		var err error
+		if m.timeout, err = time.ParseDuration(cfg.Timeout); err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+	}
+	if len(m.denylist) == 0 && len(m.patterns) == 0 && m.endpoint == "" {
+		return nil, errors.New("at least one of denylist, patterns or endpoint is required")
+	}
+	return m, nil
+}
+
+// check reports the reason if text violates the guardrails. An unreachable endpoint
+// flags the text unless fail_open is set.
+func (m *moderator) check(ctx context.Context, text string) (string, bool) {
+	lower := strings.ToLower(text)
+	for _, keyword := range m.denylist {
+		if strings.Contains(lower, keyword) {
+			return "denylisted keyword", true
+		}
+	}
+	for _, re := range m.patterns {
+		if re.MatchString(text) {
+			return "denylisted pattern", true
+		}
+	}
+	if m.endpoint == "" {
+		return "", false
+	}
+
+	category, flagged, err := m.callEndpoint(ctx, text)
+	if err != nil {
+		logger.Error("moderation endpoint failed:", err)
+		if m.failOpen {
+			return "", false
+		}
+		return "moderation unavailable", true
+	}
+	return category, flagged
+}
+
+// callEndpoint asks the external moderation endpoint and returns a flagged category
+func (m *moderator) callEndpoint(ctx context.Context, text string) (string, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	body, err := json.Marshal(moderationRequest{Input: text})
+	if err != nil {
+		return "", false, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", false, err
+	}
+	req.Header.Set(headers.ContentType, "application/json")
+
+	resp, err := upstreamClient.Do(req)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", false, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var result moderationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", false, fmt.Errorf("cannot parse moderation response: %w", err)
+	}
+	for _, r := range result.Results {
+		if !r.Flagged {
+			continue
+		}
+		for category, flagged := range r.Categories {
+			if flagged {
+				return category, true, nil
+			}
+		}
+		return "flagged", true, nil
+	}
+	return "", false, nil
+}
+
+// checkRequest moderates the messages of a request. It writes a content_filter error
+// and returns false if the request must be rejected.
+func (m *moderator) checkRequest(w http.ResponseWriter, req *http.Request, openAIReq models.OpenAIRequest) bool {
+	log := logging.FromRequest(logger, req)
+	if m == nil {
+		return true
+	}
+	var text strings.Builder
+	for _, msg := range openAIReq.Messages {
+		text.WriteString(msg.Content)
+		text.WriteString("\n")
+	}
+
+	reason, flagged := m.check(req.Context(), text.String())
+	if !flagged {
+		return true
+	}
+	log.Warning(fmt.Sprintf("request for model %s rejected by moderation: %s", openAIReq.Model, reason))
+
+	code := contentFilter
+	writeOpenAIError(w, http.StatusBadRequest, models.OpenAIError{
+		Message: "The request was rejected by the gateway's content policy",
+		Type:    "invalid_request_error",
+		Code:    &code,
+	})
+	return false
+}
+
+// filterResponse redacts flagged choices like OpenAI does: the content is removed
+// and the finish reason is set to content_filter.
+func (m *moderator) filterResponse(ctx context.Context, openAIResp *models.OpenAIResponse) {
+	if m == nil || !m.scanResponses {
+		return
+	}
+	for i := range openAIResp.Choices {
+		choice := &openAIResp.Choices[i]
+		if reason, flagged := m.check(ctx, choice.Message.Content); flagged {
+			logger.Warning(fmt.Sprintf("response of model %s filtered by moderation: %s", openAIResp.Model, reason))
+			choice.Message.Content = ""
+			choice.FinishReason = contentFilter
+		}
+	}
+}
+
+// filterChunk moderates the content streamed so far together with the content of a chunk. A
+// flagged chunk loses its content and finishes the completion with content_filter, like
+// filterResponse. It reports whether the chunk was flagged.
+func (m *moderator) filterChunk(ctx context.Context, streamed string, chunk *models.OpenAIChatCompletionChunk) bool {
+	if m == nil || !m.scanResponses {
+		return false
+	}
+	for i := range chunk.Choices {
+		choice := &chunk.Choices[i]
+		if choice.Delta.Content == "" {
+			continue
+		}
+		if reason, flagged := m.check(ctx, streamed+choice.Delta.Content); flagged {
+			logger.Warning(fmt.Sprintf("stream of model %s filtered by moderation: %s", chunk.Model, reason))
+			finishReason := contentFilter
+			choice.Delta.Content = ""
+			choice.FinishReason = &finishReason
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newModerationEndpoint(t *testing.T, statusCode int, flagged bool) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req moderationRequest
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		if statusCode != http.StatusOK {
+			w.WriteHeader(statusCode)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"results": [{"flagged": %t, "categories": {"violence": %t}}]}`, flagged, flagged)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNewModerator_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  ModerationConfig
+	}{
+		{name: "nothing configured", cfg: ModerationConfig{ScanResponses: true}},
+		{name: "empty keyword", cfg: ModerationConfig{Denylist: []string{""}}},
+		{name: "invalid pattern", cfg: ModerationConfig{Patterns: []string{"("}}},
+		{name: "invalid endpoint", cfg: ModerationConfig{Endpoint: "not a url"}},
+		{name: "invalid timeout", cfg: ModerationConfig{Denylist: []string{"secret"}, Timeout: "soon"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newModerator(&tt.cfg)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestModerator_Check(t *testing.T) {
+	m, err := newModerator(&ModerationConfig{
+		Denylist: []string{"Password"},
+		Patterns: []string{`\b\d{3}-\d{2}-\d{4}\b`},
+	})
+	assert.NoError(t, err)
+
+	_, flagged := m.check(context.Background(), "what is the admin PASSWORD?")
+	assert.True(t, flagged)
+	_, flagged = m.check(context.Background(), "my SSN is 123-45-6789")
+	assert.True(t, flagged)
+	_, flagged = m.check(context.Background(), "what's the weather in Berlin?")
+	assert.False(t, flagged)
+}
+
+func TestModerator_Endpoint(t *testing.T) {
+	tests := []struct {
+		name        string
+		statusCode  int
+		flagged     bool
+		failOpen    bool
+		wantFlagged bool
+	}{
+		{name: "flagged", statusCode: http.StatusOK, flagged: true, wantFlagged: true},
+		{name: "not flagged", statusCode: http.StatusOK, flagged: false, wantFlagged: false},
+		{name: "unavailable fails closed", statusCode: http.StatusInternalServerError, wantFlagged: true},
+		{name: "unavailable fails open", statusCode: http.StatusInternalServerError, failOpen: true, wantFlagged: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint := newModerationEndpoint(t, tt.statusCode, tt.flagged)
+			m, err := newModerator(&ModerationConfig{Endpoint: endpoint.URL, FailOpen: tt.failOpen})
+			assert.NoError(t, err)
+
+			_, flagged := m.check(context.Background(), "some text")
+
+			assert.Equal(t, tt.wantFlagged, flagged)
+		})
+	}
+}
+
+func TestChatCompletions_ModerationRejectsRequest(t *testing.T) {
+	mockHandler := &MockHandler{StatusCode: http.StatusOK}
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "agent", "url": "http://localhost:8001"},
+			},
+			"moderation": map[string]interface{}{
+				"denylist": []interface{}{"sandbox"},
+			},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+
+	rec := sendChatCompletion(handler, "agent", "")
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Nil(t, mockHandler.ReceivedRequest, "the agent must not be invoked")
+	var errResp models.OpenAIErrorResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	assert.Equal(t, "invalid_request_error", errResp.Error.Type)
+	assert.Equal(t, contentFilter, *errResp.Error.Code)
+}
+
+func TestModerator_FilterResponse(t *testing.T) {
+	m, err := newModerator(&ModerationConfig{Denylist: []string{"internal"}, ScanResponses: true})
+	assert.NoError(t, err)
+	openAIResp := newOpenAIChoicesResponse([]string{"the internal api key is 42", "sunny"}, models.OpenAIRequest{Model: "agent"})
+
+	m.filterResponse(context.Background(), &openAIResp)
+
+	assert.Equal(t, "", openAIResp.Choices[0].Message.Content)
+	assert.Equal(t, contentFilter, openAIResp.Choices[0].FinishReason)
+	assert.Equal(t, "sunny", openAIResp.Choices[1].Message.Content)
+	assert.Equal(t, "stop", openAIResp.Choices[1].FinishReason)
+}
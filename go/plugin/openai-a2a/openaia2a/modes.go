@@ -0,0 +1,187 @@
+package openaia2a
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+)
+
+// textMode is the MIME type of the text the gateway sends to agents and returns to clients
+const textMode = "text/plain"
+
+// negotiateModes adapts the A2A request to the input and output modes of the agent card of model.
+// Images, audio and files of the prompt messages are sent as file parts, or rejected if the
+// agent does not accept their MIME type, and the agent is asked for the text modes it produces.
+func negotiateModes(a2aReq *models.SendMessageRequest, messages []models.OpenAIMessage, mergeMode, model string, card models.AgentCard) error {
+	start, err := mergeStart(messages, mergeMode)
+	if err != nil {
+		return err
+	}
+	message := &a2aReq.Params.Message
+	text := ""
+	if len(message.Parts) > 0 {
+		text, _ = models.PartText(message.Parts[0])
+	}
+	if text != "" && !acceptsMode(card.DefaultInputModes, textMode) {
+		return &RequestValidationError{Param: "messages", Message: unsupportedMode(model, "text", card)}
+	}
+
+	var files []models.MessagePartsElem
+	for i := start; i < len(messages); i++ {
+		for j, part := range messages[i].ContentParts {
+			param := fmt.Sprintf("messages[%d].content[%d]", i, j)
+			file, mimeType, err := filePart(part)
+			if err != nil {
+				return &RequestValidationError{Param: param, Message: err.Error()}
+			}
+			if file == nil {
+				continue
+			}
+			if !acceptsMode(card.DefaultInputModes, mimeType) {
+				return &RequestValidationError{Param: param, Message: unsupportedMode(model, mimeType, card)}
+			}
+			files = append(files, *file)
+		}
+	}
+	if text == "" && len(files) > 0 {
+		message.Parts = files
+	} else {
+		message.Parts = append(message.Parts, files...)
+	}
+
+	if modes := textOutputModes(card.DefaultOutputModes); len(modes) > 0 {
+		if a2aReq.Params.Configuration == nil {
+			a2aReq.Params.Configuration = &models.MessageSendConfiguration{}
+		}
+		a2aReq.Params.Configuration.AcceptedOutputModes = modes
+	}
+	return nil
+}
+
+func unsupportedMode(model, mimeType string, card models.AgentCard) string {
+	return fmt.Sprintf("model %s does not accept %s input, it accepts %s", model, mimeType, strings.Join(card.DefaultInputModes, ", "))
+}
+
+// filePart converts an image, audio or file content part into an A2A file part and returns the
+// MIME type to negotiate. Web images without a known extension are negotiated as "image/*". Text
+// and refusal parts are already part of the text and return nil.
+func filePart(part models.OpenAIContentPart) (*models.FilePart, string, error) {
+	var file models.FilePartFile
+	var mimeType string
+	switch part.Type {
+	case models.OpenAIContentImageURL:
+		if part.ImageURL == nil {
+			return nil, "", errors.New("image_url is required")
+		}
+		if data, ok := parseDataURL(part.ImageURL.URL); ok {
+			file.Bytes, mimeType = data.bytes, data.mimeType
+		} else {
+			file.Uri, mimeType = part.ImageURL.URL, extensionType(part.ImageURL.URL)
+		}
+		if !strings.HasPrefix(mimeType, "image/") {
+			mimeType = ""
+		}
+		if mimeType == "" {
+			mimeType = "image/*"
+		}
+	case models.OpenAIContentInputAudio:
+		if part.InputAudio == nil {
+			return nil, "", errors.New("input_audio is required")
+		}
+		file.Bytes = part.InputAudio.Data
+		mimeType = "audio/" + part.InputAudio.Format
+		if part.InputAudio.Format == "mp3" {
+			mimeType = "audio/mpeg"
+		}
+	case models.OpenAIContentFile:
+		if part.File == nil {
+			return nil, "", errors.New("file is required")
+		}
+		if part.File.FileData == "" {
+			return nil, "", errors.New("files uploaded to OpenAI cannot be forwarded to agents, send the content as file_data")
+		}
+		file.Bytes = part.File.FileData
+		if data, ok := parseDataURL(part.File.FileData); ok {
+			file.Bytes, mimeType = data.bytes, data.mimeType
+		}
+		if part.File.Filename != "" {
+			name := part.File.Filename
+			file.Name = &name
+			if mimeType == "" {
+				mimeType = extensionType(name)
+			}
+		}
+		if mimeType == "" {
+			mimeType = "application/octet-stream"
+		}
+	default:
+		return nil, "", nil
+	}
+	if !strings.HasSuffix(mimeType, "/*") {
+		file.MimeType = &mimeType
+	}
+	return &models.FilePart{Kind: models.PartKindFile, File: file}, mimeType, nil
+}
+
+// dataURL is the content of a base64 data URL
+type dataURL struct {
+	mimeType string
+	bytes    string
+}
+
+// parseDataURL splits a data URL of the form data:<type>;base64,<bytes>
+func parseDataURL(value string) (dataURL, bool) {
+	rest, ok := strings.CutPrefix(value, "data:")
+	if !ok {
+		return dataURL{}, false
+	}
+	header, data, ok := strings.Cut(rest, ",")
+	mimeType, ok2 := strings.CutSuffix(header, ";base64")
+	if !ok || !ok2 {
+		return dataURL{}, false
+	}
+	return dataURL{mimeType: mimeType, bytes: data}, true
+}
+
+// extensionType guesses the MIME type of a URL or file name from its extension
+func extensionType(name string) string {
+	name, _, _ = strings.Cut(name, "?")
+	mimeType, _, _ := strings.Cut(mime.TypeByExtension(path.Ext(name)), ";")
+	return mimeType
+}
+
+// acceptsMode reports whether the modes of an agent cover a MIME type. Agents that declare no modes
+// accept everything. Modes may be wildcards ("image/*", "*/*") or bare types ("text", "image"), and a
+// MIME type with wildcard subtype is covered by every mode of its type.
+func acceptsMode(modes []string, mimeType string) bool {
+	if len(modes) == 0 {
+		return true
+	}
+	typ, subtype, _ := strings.Cut(strings.ToLower(mimeType), "/")
+	for _, mode := range modes {
+		mode, _, _ = strings.Cut(strings.ToLower(strings.TrimSpace(mode)), ";")
+		modeType, modeSubtype, _ := strings.Cut(mode, "/")
+		if modeType == "*" || (modeType == typ && (modeSubtype == "" || modeSubtype == "*" || modeSubtype == subtype || subtype == "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// textOutputModes returns the output modes of an agent the gateway can return as chat completion
+func textOutputModes(modes []string) []string {
+	var text []string
+	for _, mode := range modes {
+		switch modeType, _, _ := strings.Cut(strings.ToLower(mode), "/"); modeType {
+		case "text":
+			text = append(text, mode)
+		case "*":
+			text = append(text, textMode)
+		}
+	}
+	return text
+}
@@ -0,0 +1,153 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/testagent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const imageRequest = `{"model":"%s","messages":[{"role":"user","content":[
+	{"type":"text","text":"What is on this picture?"},
+	{"type":"image_url","image_url":{"url":"data:image/png;base64,iVBORw0KGgo="}}]}]}`
+
+func TestChatCompletions_ModeNegotiation(t *testing.T) {
+	textAgent := testagent.New(testagent.Options{Card: &models.AgentCard{Name: "text", DefaultInputModes: []string{"text/plain"}, DefaultOutputModes: []string{"text/plain"}}})
+	t.Cleanup(textAgent.Close)
+	visionAgent := testagent.New(testagent.Options{Card: &models.AgentCard{Name: "vision", DefaultInputModes: []string{"text", "image/*"}, DefaultOutputModes: []string{"text/markdown", "application/json"}}})
+	t.Cleanup(visionAgent.Close)
+
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "text-agent", "url": textAgent.URL},
+				map[string]interface{}{"model_id": "vision-agent", "url": visionAgent.URL},
+			},
+			"capability_discovery": map[string]interface{}{},
+		},
+	}
+	mockHandler := &MockHandler{Response: []byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task","id":"t1","contextId":"c1","status":{"state":"completed"},
+		"artifacts":[{"artifactId":"a1","parts":[{"kind":"text","text":"A cat"}]}]}}`)}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat/completions", strings.NewReader(strings.Replace(imageRequest, "%s", "text-agent", 1))))
+
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	var errorResp models.OpenAIErrorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResp))
+	assert.Equal(t, "messages[0].content[1]", *errorResp.Error.Param)
+	assert.Contains(t, errorResp.Error.Message, "model text-agent does not accept image/png input, it accepts text/plain")
+	assert.Nil(t, mockHandler.ReceivedRequest)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat/completions", strings.NewReader(strings.Replace(imageRequest, "%s", "vision-agent", 1))))
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var a2aReq struct {
+		Params struct {
+			Message       json.RawMessage `json:"message"`
+			Configuration struct {
+				AcceptedOutputModes []string `json:"acceptedOutputModes"`
+			} `json:"configuration"`
+		} `json:"params"`
+	}
+	require.NoError(t, json.Unmarshal(mockHandler.ReceivedBody, &a2aReq))
+	var message models.Message
+	require.NoError(t, json.Unmarshal(a2aReq.Params.Message, &message))
+	require.Len(t, message.Parts, 2)
+	assert.Equal(t, models.TextPart{Kind: "text", Text: "What is on this picture?"}, message.Parts[0])
+	file := message.Parts[1].(models.FilePart).File
+	assert.Equal(t, "iVBORw0KGgo=", file.Bytes)
+	assert.Equal(t, "image/png", *file.MimeType)
+	assert.Equal(t, []string{"text/markdown"}, a2aReq.Params.Configuration.AcceptedOutputModes)
+}
+
+func TestAcceptsMode(t *testing.T) {
+	tests := []struct {
+		modes    []string
+		mimeType string
+		want     bool
+	}{
+		{modes: nil, mimeType: "image/png", want: true},
+		{modes: []string{"text/plain"}, mimeType: "text/plain", want: true},
+		{modes: []string{"text/plain"}, mimeType: "image/png", want: false},
+		{modes: []string{"text"}, mimeType: "text/plain", want: true},
+		{modes: []string{"image/*"}, mimeType: "image/jpeg", want: true},
+		{modes: []string{"image/png"}, mimeType: "image/*", want: true},
+		{modes: []string{"image/png"}, mimeType: "image/jpeg", want: false},
+		{modes: []string{"*/*"}, mimeType: "audio/wav", want: true},
+		{modes: []string{"Text/Plain; charset=utf-8"}, mimeType: "text/plain", want: true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, acceptsMode(tt.modes, tt.mimeType), "%v accepts %s", tt.modes, tt.mimeType)
+	}
+}
+
+func TestFilePart(t *testing.T) {
+	tests := []struct {
+		name     string
+		part     models.OpenAIContentPart
+		wantFile models.FilePartFile
+		wantType string
+		wantErr  bool
+	}{
+		{
+			name:     "web image",
+			part:     models.OpenAIContentPart{Type: "image_url", ImageURL: &models.OpenAIImageURL{URL: "https://example.com/cat.jpg?size=large"}},
+			wantFile: models.FilePartFile{Uri: "https://example.com/cat.jpg?size=large", MimeType: ptr("image/jpeg")},
+			wantType: "image/jpeg",
+		},
+		{
+			name:     "web image without extension",
+			part:     models.OpenAIContentPart{Type: "image_url", ImageURL: &models.OpenAIImageURL{URL: "https://example.com/cat"}},
+			wantFile: models.FilePartFile{Uri: "https://example.com/cat"},
+			wantType: "image/*",
+		},
+		{
+			name:     "audio",
+			part:     models.OpenAIContentPart{Type: "input_audio", InputAudio: &models.OpenAIInputAudio{Data: "UklGRg==", Format: "mp3"}},
+			wantFile: models.FilePartFile{Bytes: "UklGRg==", MimeType: ptr("audio/mpeg")},
+			wantType: "audio/mpeg",
+		},
+		{
+			name:     "file",
+			part:     models.OpenAIContentPart{Type: "file", File: &models.OpenAIFile{FileData: "data:application/pdf;base64,JVBERi0=", Filename: "report.pdf"}},
+			wantFile: models.FilePartFile{Bytes: "JVBERi0=", MimeType: ptr("application/pdf"), Name: ptr("report.pdf")},
+			wantType: "application/pdf",
+		},
+		{
+			name:    "uploaded file",
+			part:    models.OpenAIContentPart{Type: "file", File: &models.OpenAIFile{FileID: "file-123"}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			part, mimeType, err := filePart(tt.part)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantFile, part.File)
+			assert.Equal(t, tt.wantType, mimeType)
+		})
+	}
+
+	part, _, err := filePart(models.OpenAIContentPart{Type: "text", Text: "Hi"})
+	assert.NoError(t, err)
+	assert.Nil(t, part, "text is sent as text part")
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,605 @@
+package openaia2a
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/configschema"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/timing"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/trustedproxy"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/upstream"
+)
+
+const (
+	pluginName = "openai-a2a"
+	configKey  = "openai_a2a_config"
+)
+
+type registerer string
+
+// module holds the handlers of the plugin
+var module = registerer(pluginName)
+var logger = logging.New(pluginName)
+
+// Module registers openai-a2a as standalone plugin or as part of the gateway plugin
+var Module = pluginkit.Module{Name: pluginName, ConfigKey: configKey, Factory: module.registerHandlers, Logger: &logger}
+
+func init() {
+	logger.Debug("loaded")
+}
+
+func (r registerer) registerHandlers(ctx context.Context, extra map[string]interface{}, handler http.Handler) (http.Handler, error) {
+	var cfg config
+	err := parseConfig(extra, &cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := logging.Configure(pluginName, cfg.Logging); err != nil {
+		return nil, fmt.Errorf("invalid logging configuration: %w", err)
+	}
+	// Features capture the upstream client when they are created
+	if upstreamTransport, err = upstream.NewTransport(cfg.Upstream); err != nil {
+		return nil, fmt.Errorf("invalid upstream configuration: %w", err)
+	}
+	upstreamClient = &http.Client{Transport: upstreamTransport}
+	if cfg.Upstream != nil && cfg.Upstream.TLS != nil && cfg.Upstream.TLS.InsecureSkipVerify {
+		logger.Warning("upstream.tls.insecure_skip_verify is set: certificates of all agents are NOT verified and connections to them can be intercepted")
+	}
+	if trustedProxies, err = trustedproxy.New(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid trusted_proxies configuration: %w", err)
+	}
+	if err := validateMessageMerging(cfg.MessageMerging); err != nil {
+		return nil, err
+	}
+	if ids, err = newIDGenerator(cfg.IDs); err != nil {
+		return nil, fmt.Errorf("invalid ids configuration: %w", err)
+	}
+	if events, err = newWebhookNotifier(cfg.Webhooks); err != nil {
+		return nil, fmt.Errorf("invalid webhooks configuration: %w", err)
+	}
+	events.start(ctx)
+	if cfg.MaxResponseSize < 0 {
+		return nil, fmt.Errorf("invalid max_response_size configuration: %d is negative", cfg.MaxResponseSize)
+	}
+	maxResponseSize = defaultMaxResponseSize
+	if cfg.MaxResponseSize > 0 {
+		maxResponseSize = cfg.MaxResponseSize
+	}
+	cfg.scripts, err = newScriptEngine(cfg.Scripts)
+	if err != nil {
+		return nil, fmt.Errorf("cannot compile scripts: %w", err)
+	}
+	cfg.agents, err = newAgentStore(cfg.Agents, cfg.AgentsSource, newModelMatcher(cfg.ModelMatching))
+	if err != nil {
+		return nil, err
+	}
+	cfg.agents.watch(ctx)
+	cfg.sandbox, err = newSandbox(cfg.Sandbox)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sandbox configuration: %w", err)
+	}
+	cfg.admin, err = newAdmin(cfg.Admin)
+	if err != nil {
+		return nil, fmt.Errorf("invalid admin configuration: %w", err)
+	}
+	cfg.readiness, err = newReadiness(cfg.Readiness, cfg.agents.agents)
+	if err != nil {
+		return nil, fmt.Errorf("invalid readiness configuration: %w", err)
+	}
+	cfg.readiness.start(ctx)
+	cfg.health, err = newHealthChecker(cfg.Health, cfg.agents.agents)
+	if err != nil {
+		return nil, fmt.Errorf("invalid health configuration: %w", err)
+	}
+	cfg.health.readiness = cfg.readiness
+	cfg.accounts, err = newServiceAccounts(cfg.ServiceAccounts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service account configuration: %w", err)
+	}
+	if cfg.accounts != nil {
+		cfg.admin.register("service_accounts", cfg.accounts.keys)
+	}
+	cfg.anomalies, err = newAnomalyDetector(cfg.AnomalyDetection)
+	if err != nil {
+		return nil, fmt.Errorf("invalid anomaly detection configuration: %w", err)
+	}
+	if cfg.anomalies != nil {
+		cfg.admin.route(adminAnomaliesPath, cfg.anomalies.serveReport)
+	}
+	cfg.stats, err = newGatewayStats(cfg.Stats)
+	if err != nil {
+		return nil, fmt.Errorf("invalid stats configuration: %w", err)
+	}
+	if cfg.stats != nil && cfg.admin == nil {
+		return nil, errors.New("invalid stats configuration: the usage dashboard API requires admin endpoints")
+	}
+	cfg.moderator, err = newModerator(cfg.Moderation)
+	if err != nil {
+		return nil, fmt.Errorf("invalid moderation configuration: %w", err)
+	}
+	cfg.redactor, err = newRedactor(cfg.Redaction)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redaction configuration: %w", err)
+	}
+	cfg.tenancy, err = newTenancy(cfg.Tenancy)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tenancy configuration: %w", err)
+	}
+	cfg.apiKeys, err = newAPIKeys(cfg.APIKeys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid api key configuration: %w", err)
+	}
+	if cfg.apiKeys != nil {
+		cfg.admin.register("api_keys", cfg.apiKeys.keys)
+	}
+	cfg.jwt, err = newJWTValidator(cfg.JWT)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jwt configuration: %w", err)
+	}
+	// Tenants are derived from the authenticated principal, never from what the client claims
+	if cfg.tenancy != nil && cfg.apiKeys == nil && cfg.jwt == nil {
+		return nil, errors.New("invalid tenancy configuration: tenants require api_keys or jwt authentication")
+	}
+	// Quotas are counted for the authenticated principal, never for a token the client made up
+	if cfg.Quotas != nil && cfg.apiKeys == nil && cfg.jwt == nil {
+		return nil, errors.New("invalid quotas configuration: quotas require api_keys or jwt authentication")
+	}
+	if cfg.tenancy != nil && cfg.tenancy.claim != "" && cfg.jwt == nil {
+		return nil, errors.New("invalid tenancy configuration: claim requires jwt authentication")
+	}
+	cfg.history, err = newHistoryForwarder(cfg.History)
+	if err != nil {
+		return nil, fmt.Errorf("invalid history configuration: %w", err)
+	}
+	cfg.quotas, err = newQuotas(cfg.Quotas)
+	if err != nil {
+		return nil, fmt.Errorf("invalid quota configuration: %w", err)
+	}
+	if cfg.quotas != nil {
+		cfg.health.addStore("quotas", cfg.quotas.store)
+	}
+	cfg.exporter, err = newUsageExporter(cfg.UsageExport)
+	if err != nil {
+		return nil, fmt.Errorf("invalid usage export configuration: %w", err)
+	}
+	cfg.exporter.start(ctx)
+	cfg.card, err = newGatewayCard(cfg.AgentCard, cfg.agents.agents)
+	if err != nil {
+		return nil, fmt.Errorf("invalid agent card configuration: %w", err)
+	}
+	cfg.a2a, err = newA2AValidator(cfg.A2AValidation)
+	if err != nil {
+		return nil, fmt.Errorf("invalid a2a validation configuration: %w", err)
+	}
+	cfg.push, err = newPushRelay(cfg.PushNotifications)
+	if err != nil {
+		return nil, fmt.Errorf("invalid push notifications configuration: %w", err)
+	}
+	cfg.push.start(ctx)
+	cfg.tasks, err = newTaskStore(cfg.TaskStore)
+	if err != nil {
+		return nil, fmt.Errorf("invalid task store configuration: %w", err)
+	}
+	if cfg.tasks != nil {
+		cfg.tasks.tenancy = cfg.tenancy
+	}
+	cfg.artifacts, err = newArtifactProxy(cfg.Artifacts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid artifacts configuration: %w", err)
+	}
+	if cfg.artifacts != nil && cfg.tasks == nil {
+		logger.Warning("artifacts are configured without task_store, artifacts cannot be downloaded by task ID")
+	}
+	cfg.discovery, err = newCapabilityDiscovery(cfg.CapabilityDiscovery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid capability discovery configuration: %w", err)
+	}
+	if cfg.push != nil {
+		cfg.push.capabilities = cfg.discovery
+	}
+	cfg.skills, err = newSkillRouter(cfg.SkillRouting, cfg.discovery)
+	if err != nil {
+		return nil, fmt.Errorf("invalid skill routing configuration: %w", err)
+	}
+	cfg.pipelines, err = newOrchestrator(cfg.Orchestration)
+	if err != nil {
+		return nil, fmt.Errorf("invalid orchestration configuration: %w", err)
+	}
+	cfg.streamer, err = newStreamer(cfg.Streaming)
+	if err != nil {
+		return nil, fmt.Errorf("invalid streaming configuration: %w", err)
+	}
+	cfg.partials, err = newPartialResponder(cfg.PartialResponses)
+	if err != nil {
+		return nil, fmt.Errorf("invalid partial responses configuration: %w", err)
+	}
+	cfg.captures, err = newCaptures(cfg.Capture, cfg.agents)
+	if err != nil {
+		return nil, fmt.Errorf("invalid capture configuration: %w", err)
+	}
+	if cfg.captures != nil {
+		if cfg.admin == nil && cfg.Capture.File == nil {
+			return nil, errors.New("invalid capture configuration: captures are neither written to a file nor exposed by admin endpoints")
+		}
+		cfg.admin.route(adminCapturesPath, cfg.captures.serveCaptures)
+		cfg.admin.handle(http.MethodPost, adminReplayPath, cfg.captures.serveReplay)
+	}
+	cfg.debug, err = newDiagnostics(cfg.Diagnostics)
+	if err != nil {
+		return nil, fmt.Errorf("invalid diagnostics configuration: %w", err)
+	}
+	if cfg.debug != nil && cfg.admin == nil {
+		return nil, errors.New("invalid diagnostics configuration: diagnostics endpoints require admin endpoints")
+	}
+	summary := newStartupSummary(extra, cfg)
+	logStartupSummary(summary)
+	pluginkit.SetFeatures(pluginName, summary.Features)
+
+	return http.HandlerFunc(r.handleRequest(cfg, handler)), nil
+}
+
+func (r registerer) handleRequest(cfg config, handler http.Handler) func(w http.ResponseWriter, req *http.Request) {
+	serveRequest := r.serveRequest(cfg, handler)
+	serve := func(w http.ResponseWriter, req *http.Request) {
+		cfg.exporter.track(w, req, serveRequest)
+	}
+	return func(w http.ResponseWriter, req *http.Request) {
+		// Responses of the OpenAI endpoints report gateway and agent time in Server-Timing
+		if isOpenAIEndpoint(req) {
+			w, req = timing.Start(w, req)
+		}
+
+		// Serve the usage dashboard API to admins without counting it
+		if cfg.stats.handles(req) {
+			if cfg.admin.authorize(w, req) {
+				cfg.stats.serveHTTP(w, req)
+			}
+			return
+		}
+		cfg.stats.track(w, req, serve)
+	}
+}
+
+func (r registerer) serveRequest(cfg config, handler http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		// Handle admin endpoints
+		if cfg.admin.handles(req) {
+			cfg.admin.serveHTTP(w, req)
+			return
+		}
+
+		// Serve the diagnostics endpoints, protected by the admin keys
+		if cfg.debug.handles(req) {
+			if cfg.admin.authorize(w, req) {
+				cfg.debug.serveHTTP(w, req)
+			}
+			return
+		}
+
+		// Issue callback tokens to service accounts
+		if cfg.accounts.handlesTokenRequest(req) {
+			cfg.accounts.serveToken(w, req)
+			return
+		}
+
+		// Callback endpoints require a token with the matching scope
+		if scope, ok := cfg.accounts.requiredScope(req.URL.Path); ok {
+			if !cfg.accounts.authorizeCallback(w, req, scope) {
+				return
+			}
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		// Handle GET /health and /health/agents endpoints
+		if cfg.health.handles(req) {
+			cfg.health.serveHTTP(w, req, cfg.admin.authorized(req))
+			return
+		}
+
+		// Handle GET /.well-known/agent-card.json, making the gateway discoverable as an A2A agent
+		if cfg.card.handles(req) {
+			cfg.card.serveHTTP(w, req)
+			return
+		}
+
+		// Relay push notifications of agents to the webhooks registered by clients
+		if cfg.push.handlesWebhook(req) {
+			cfg.push.serveWebhook(w, req)
+			return
+		}
+
+		agents := cfg.agents.load()
+		agent, native := nativeA2AAgent(req, agents.agents)
+
+		// Authenticate callers of the OpenAI-compatible endpoints, of native A2A requests, of the agent
+		// index, of task queries and of artifact downloads, which record and check the principal that
+		// created a task.
+		// Sandbox keys are accepted by their prefix on /models and /chat/completions only. With both
+		// API keys and JWTs configured, bearer tokens shaped like a JWT are validated as such.
+		openAI := isOpenAIEndpoint(req) || cfg.quotas.handles(req) || cfg.pipelines.handles(req)
+		if openAI || native || cfg.card.handlesIndex(req) || cfg.tasks.handles(req) || cfg.artifacts.handles(req) {
+			if openAI && cfg.OpenAIErrors {
+				req = withOpenAIErrors(req)
+			}
+			if !cfg.sandbox.presents(req) || !isOpenAIEndpoint(req) {
+				var ok bool
+				if cfg.jwt != nil && (cfg.apiKeys == nil || looksLikeJWT(bearerToken(req))) {
+					req, ok = cfg.jwt.authenticate(w, req)
+				} else {
+					req, ok = cfg.apiKeys.authenticate(w, req)
+				}
+				if !ok {
+					return
+				}
+			}
+		}
+
+		// Serve agent files through the signed links that replaced their internal URIs
+		if cfg.artifacts.handles(req) {
+			cfg.artifacts.serveHTTP(w, req, handler, agents, cfg.tasks)
+			return
+		}
+
+		// Route tasks/get and tasks/cancel sent to the gateway to the agent owning the task
+		if cfg.tasks.handles(req) {
+			if cfg.artifacts != nil {
+				aw := cfg.artifacts.writer(w, req)
+				defer aw.done()
+				w = aw
+			}
+			cfg.tasks.serveHTTP(w, req, handler, agents)
+			return
+		}
+
+		// Handle GET /usage endpoint
+		if cfg.quotas.handles(req) {
+			cfg.quotas.serveUsage(w, req)
+			return
+		}
+
+		// Handle GET /models endpoint
+		if req.Method == http.MethodGet && req.URL.Path == "/models" {
+			// Sandbox keys only see synthetic agents
+			if cfg.sandbox.presents(req) {
+				handleModelsRequest(w, req, cfg.sandbox.modelList, nil)
+				return
+			}
+			// Agents evicted for not being ready are hidden until they recover
+			handleModelsRequest(w, req, cfg.readiness.filter(allowedAgents(req.Context(), cfg.tenancy.visibleAgents(req, cfg.agents.agents()))), cfg.discovery)
+			return
+		}
+
+		// Handle GET /agents, listing the agents of the caller with their gateway URLs like /models
+		if cfg.card.handlesIndex(req) {
+			cfg.card.serveIndex(w, req, cfg.readiness.filter(allowedAgents(req.Context(), cfg.tenancy.visibleAgents(req, cfg.agents.agents()))))
+			return
+		}
+
+		// Handle POST /chat/completions endpoint (OpenAI-compatible)
+		if req.Method == http.MethodPost && req.URL.Path == "/chat/completions" {
+			// Keys flagged for anomalous token usage are throttled temporarily
+			if cfg.anomalies.throttled(req) {
+				writeError(w, req, http.StatusTooManyRequests, "API key throttled due to anomalous token usage")
+				return
+			}
+			if !cfg.quotas.admit(w, req) {
+				return
+			}
+			// Clients may limit the latency of the request, agents get what remains of the budget
+			req, cancel, err := timing.WithBudget(req)
+			if err != nil {
+				writeError(w, req, http.StatusBadRequest, err.Error())
+				return
+			}
+			defer cancel()
+			cfg.captures.track(w, req, func(w http.ResponseWriter, req *http.Request) {
+				handleGlobalChatCompletions(w, req, handler, cfg)
+			})
+			return
+		}
+
+		// Handle POST /agents/orchestrate, executing pipelines of agents
+		if cfg.pipelines.handles(req) {
+			cfg.pipelines.serveHTTP(w, req, handler, cfg)
+			return
+		}
+
+		// Native A2A callers may only address their models
+		if native && !admitNativeA2A(w, req, cfg.tenancy, agent.ModelID) {
+			return
+		}
+
+		// Validate native A2A requests to /{agent} before forwarding them
+		if cfg.a2a.handles(req, cfg.agents.agents()) && !cfg.a2a.admit(w, req) {
+			return
+		}
+
+		// Native requests may only refer to tasks of the caller
+		if native && !cfg.tasks.admitNative(w, req, agent.ModelID) {
+			return
+		}
+
+		// Agents evicted for not being ready are not sent requests until they recover
+		if native && cfg.readiness.rejectA2A(w, agent.ModelID) {
+			return
+		}
+
+		// Remember which agent owns the tasks in native A2A responses
+		if native && cfg.tasks != nil {
+			recorder := cfg.tasks.recorder(w, req, agent.ModelID)
+			defer recorder.done()
+			w = recorder
+		}
+
+		// File URIs in native A2A responses are replaced by gateway links
+		if native && cfg.artifacts != nil {
+			aw := cfg.artifacts.writer(w, req)
+			defer aw.done()
+			w = aw
+		}
+
+		// Native A2A requests to agents that only expose gRPC are transcoded
+		if native && isGRPC(agent) {
+			serveGRPC(w, req, agents.grpc[agent.ModelID])
+			return
+		}
+
+		// Push notification configs of native A2A requests point agents to the gateway
+		if native && cfg.push.intercept(w, req, handler, agent) {
+			return
+		}
+
+		// Pass through all other requests
+		handler.ServeHTTP(w, req)
+	}
+}
+
+// isOpenAIEndpoint reports whether the request targets an OpenAI-compatible endpoint
+func isOpenAIEndpoint(req *http.Request) bool {
+	return (req.Method == http.MethodGet && req.URL.Path == "/models") ||
+		(req.Method == http.MethodPost && req.URL.Path == "/chat/completions")
+}
+
+// transformA2AToOpenAI converts an A2A Task response to OpenAI chat completion format.
+//
+// The A2A specification (https://a2a-protocol.org/latest/specification/) defines that
+// a message/send response returns a Task object which can carry agent output in multiple places:
+//
+//  1. artifacts: Discrete output objects (documents, data) produced by the task.
+//     This is the primary source for structured agent output.
+//  2. status.message: The message associated with the current task status transition.
+//     Some A2A implementations (e.g. Microsoft's A2A SDK) place the agent's response
+//     here rather than in artifacts, which is valid per the spec.
+//  3. history: The conversation history. Used as a last resort by searching backwards
+//     for the most recent agent message.
+//
+// We check all three in priority order to maximize compatibility across A2A implementations.
+func transformA2AToOpenAI(a2aResp models.SendMessageSuccessResponse, originalReq models.OpenAIRequest) models.OpenAIResponse {
+	return newOpenAIResponse(extractA2AContent(a2aResp), originalReq)
+}
+
+// extractA2AContent collects the agent's text output from an A2A Task response,
+// following the priority order described on transformA2AToOpenAI.
+func extractA2AContent(a2aResp models.SendMessageSuccessResponse) string {
+	var content strings.Builder
+
+	// First, try to get content from artifacts
+	if len(a2aResp.Result.Artifacts) > 0 {
+		for _, artifact := range a2aResp.Result.Artifacts {
+			for _, part := range artifact.Parts {
+				if text, ok := models.PartText(part); ok {
+					content.WriteString(text)
+				}
+			}
+		}
+	}
+
+	// If no artifacts, fall back to status.message
+	if content.Len() == 0 && a2aResp.Result.Status.Message != nil {
+		for _, part := range a2aResp.Result.Status.Message.Parts {
+			if text, ok := models.PartText(part); ok {
+				content.WriteString(text)
+			}
+		}
+	}
+
+	// If still no content, fall back to last agent message in history
+	if content.Len() == 0 {
+		for i := len(a2aResp.Result.History) - 1; i >= 0; i-- {
+			msg := a2aResp.Result.History[i]
+			if msg.Role == "agent" {
+				for _, part := range msg.Parts {
+					if text, ok := models.PartText(part); ok {
+						content.WriteString(text)
+					}
+				}
+				if content.Len() > 0 {
+					break
+				}
+			}
+		}
+	}
+
+	return content.String()
+}
+
+// newOpenAIResponse wraps assistant content in a single-choice OpenAI chat completion.
+func newOpenAIResponse(content string, originalReq models.OpenAIRequest) models.OpenAIResponse {
+	return newOpenAIChoicesResponse([]string{content}, originalReq)
+}
+
+// newOpenAIChoicesResponse builds a chat completion with one choice per content, in order
+func newOpenAIChoicesResponse(contents []string, originalReq models.OpenAIRequest) models.OpenAIResponse {
+	choices := make([]models.OpenAIChoice, len(contents))
+	for i, content := range contents {
+		choices[i] = models.OpenAIChoice{
+			Index: i,
+			Message: models.OpenAIMessage{
+				Role:    "assistant",
+				Content: content,
+			},
+			FinishReason: "stop",
+		}
+	}
+
+	return models.OpenAIResponse{
+		ID:      newID(),
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   originalReq.Model,
+		Choices: choices,
+		Usage:   estimateUsage(originalReq, contents...),
+	}
+}
+
+// transformOpenAIToA2A converts OpenAI chat completion request to A2A format
+func transformOpenAIToA2A(openAIReq models.OpenAIRequest, conversationId string, mergeMode string) (*models.SendMessageRequest, error) {
+	contextID := conversationId
+	messageID := newID()
+
+	content, err := mergeMessages(openAIReq.Messages, mergeMode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the main message
+	message := models.Message{
+		Kind:      "message",
+		MessageId: messageID,
+		ContextId: &contextID,
+		Role:      models.MessageRoleUser,
+		Parts: []models.MessagePartsElem{
+			models.TextPart{
+				Kind: "text",
+				Text: content,
+			},
+		},
+	}
+
+	a2aReq := models.SendMessageRequest{
+		Jsonrpc: "2.0",
+		Id:      1,
+		Method:  "message/send",
+		Params: models.MessageSendParams{
+			Message:  message,
+			Metadata: map[string]interface{}{},
+		},
+	}
+
+	return &a2aReq, nil
+}
+
+func parseConfig(extra map[string]interface{}, config *config) error {
+	if err := configschema.Validate(pluginName, extra[configKey]); err != nil {
+		return fmt.Errorf("invalid extra_config.%s: %w", configKey, err)
+	}
+	return pluginkit.DecodeConfig(extra, configKey, config)
+}
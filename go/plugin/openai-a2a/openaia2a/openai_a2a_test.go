@@ -1,16 +1,19 @@
-package main
+package openaia2a
 
 import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const (
@@ -69,7 +72,7 @@ func TestNonChatCompletionsEndpointPassthrough(t *testing.T) {
 		StatusCode: http.StatusNotFound,
 	}
 
-	handlers, _ := HandlerRegisterer.registerHandlers(context.Background(), extraConfig, mockHandler)
+	handlers, _ := module.registerHandlers(context.Background(), extraConfig, mockHandler)
 	ts := httptest.NewUnstartedServer(handlers)
 	ts.Start()
 	defer ts.Close()
@@ -147,7 +150,7 @@ func Test_transformOpenAIToA2A(t *testing.T) {
 		Temperature: 0.7,
 	}
 
-	a2aReq, err := transformOpenAIToA2A(openAIReq, "some-conversation-id")
+	a2aReq, err := transformOpenAIToA2A(openAIReq, "some-conversation-id", "")
 
 	assert.Nil(t, err)
 	assert.Equal(t, "2.0", a2aReq.Jsonrpc)
@@ -175,7 +178,7 @@ func Test_transformOpenAIToA2A_WithMultipleMessages_NoAssistant(t *testing.T) {
 		},
 	}
 
-	a2aReq, err := transformOpenAIToA2A(openAIReq, "some-conversation-id")
+	a2aReq, err := transformOpenAIToA2A(openAIReq, "some-conversation-id", "")
 
 	assert.Nil(t, err)
 
@@ -197,7 +200,7 @@ func Test_transformOpenAIToA2A_MessagesAfterAssistant(t *testing.T) {
 		},
 	}
 
-	a2aReq, err := transformOpenAIToA2A(openAIReq, "some-conversation-id")
+	a2aReq, err := transformOpenAIToA2A(openAIReq, "some-conversation-id", "")
 
 	assert.Nil(t, err)
 
@@ -220,7 +223,7 @@ func Test_transformOpenAIToA2A_MultipleMessagesAfterAssistant(t *testing.T) {
 		},
 	}
 
-	a2aReq, err := transformOpenAIToA2A(openAIReq, "some-conversation-id")
+	a2aReq, err := transformOpenAIToA2A(openAIReq, "some-conversation-id", "")
 
 	assert.Nil(t, err)
 
@@ -243,7 +246,7 @@ func Test_transformOpenAIToA2A_MultipleAssistantMessages(t *testing.T) {
 		},
 	}
 
-	a2aReq, err := transformOpenAIToA2A(openAIReq, "some-conversation-id")
+	a2aReq, err := transformOpenAIToA2A(openAIReq, "some-conversation-id", "")
 
 	assert.Nil(t, err)
 
@@ -525,7 +528,7 @@ func TestStreamingRequestReturnsError(t *testing.T) {
 
 	mockHandler := &MockHandler{}
 
-	handlers, _ := HandlerRegisterer.registerHandlers(context.Background(), extraConfig, mockHandler)
+	handlers, _ := module.registerHandlers(context.Background(), extraConfig, mockHandler)
 	ts := httptest.NewUnstartedServer(handlers)
 	ts.Start()
 	defer ts.Close()
@@ -559,7 +562,78 @@ func TestStreamingRequestReturnsError(t *testing.T) {
 	errorObj := errorResp["error"].(map[string]interface{})
 	assert.Equal(t, "Streaming is not currently supported by the Agent Gateway", errorObj["message"])
 	assert.Equal(t, "invalid_request_error", errorObj["type"])
+	assert.NotContains(t, errorObj, "param", "the parameter is only named with openai_errors enabled")
+	assert.Contains(t, errorObj, "code")
+	assert.Nil(t, errorObj["code"])
 
 	// Verify backend was not called (streaming check happens before agent resolution)
 	assert.Nil(t, mockHandler.ReceivedRequest)
 }
+
+func BenchmarkTransformOpenAIToA2A(b *testing.B) {
+	openAIReq := models.OpenAIRequest{Model: "gpt-4"}
+	for i := 0; i < 10; i++ {
+		openAIReq.Messages = append(openAIReq.Messages,
+			models.OpenAIMessage{Role: "user", Content: fmt.Sprintf("What is the weather in city %d?", i)},
+			models.OpenAIMessage{Role: "assistant", Content: fmt.Sprintf("The weather in city %d is sunny.", i)},
+		)
+	}
+	openAIReq.Messages = append(openAIReq.Messages, models.OpenAIMessage{Role: "user", Content: "And tomorrow?"})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, _ = transformOpenAIToA2A(openAIReq, "some-conversation-id", "")
+	}
+}
+
+func BenchmarkTransformA2AToOpenAI(b *testing.B) {
+	var history []models.Message
+	for i := 0; i < 20; i++ {
+		history = append(history, models.Message{
+			Kind:      "message",
+			MessageId: fmt.Sprintf("msg-%d", i),
+			Role:      "user",
+			Parts:     []models.MessagePartsElem{models.TextPart{Kind: "text", Text: "What is the weather?"}},
+		})
+	}
+	a2aResp := models.SendMessageSuccessResponse{
+		Jsonrpc: "2.0",
+		Id:      1,
+		Result: models.SendMessageSuccessResponseResult{
+			Artifacts: []models.Artifact{{
+				ArtifactId: "artifact-123",
+				Parts: []models.ArtifactPartsElem{
+					models.TextPart{Kind: "text", Text: strings.Repeat("The weather is sunny. ", 50)},
+					models.TextPart{Kind: "text", Text: "Tomorrow it rains."},
+				},
+			}},
+			ContextId: "context-123",
+			History:   history,
+			Id:        "task-123",
+			Kind:      "task",
+			Status:    models.TaskStatus{State: "completed"},
+		},
+	}
+	openAIReq := models.OpenAIRequest{Model: "gpt-4"}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = transformA2AToOpenAI(a2aResp, openAIReq)
+	}
+}
+
+func TestRegisterHandlers_Upstream(t *testing.T) {
+	var extraConfig map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(`{"openai_a2a_config": {"agents": [], "upstream": {"max_idle_conns_per_host": 64, "http2": false}}}`), &extraConfig))
+	_, err := module.registerHandlers(context.Background(), extraConfig, &MockHandler{})
+	require.NoError(t, err)
+
+	transport := upstreamTransport.ForHost("localhost:8001")
+	assert.Equal(t, 64, transport.MaxIdleConnsPerHost)
+	assert.False(t, transport.ForceAttemptHTTP2)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"openai_a2a_config": {"agents": [], "upstream": {"proxy": "ftp://proxy:21"}}}`), &extraConfig))
+	_, err = module.registerHandlers(context.Background(), extraConfig, &MockHandler{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid upstream configuration")
+}
@@ -0,0 +1,108 @@
+package openaia2a
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+)
+
+// maxPassthroughMessage bounds the agent error text that is wrapped in an OpenAI error
+const maxPassthroughMessage = 512
+
+type openAIErrorsKey struct{}
+
+// withOpenAIErrors marks a request whose errors are written in the OpenAI error envelope
+func withOpenAIErrors(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), openAIErrorsKey{}, true))
+}
+
+func openAIErrorsEnabled(ctx context.Context) bool {
+	enabled, _ := ctx.Value(openAIErrorsKey{}).(bool)
+	return enabled
+}
+
+// writeError reports an error on an OpenAI route. With openai_errors enabled it is written
+// in the OpenAI error envelope, otherwise as plain text.
+func writeError(w http.ResponseWriter, req *http.Request, statusCode int, message string) {
+	writeParamError(w, req, statusCode, message, "")
+}
+
+// writeParamError reports an error caused by a request parameter, e.g. the model
+func writeParamError(w http.ResponseWriter, req *http.Request, statusCode int, message, param string) {
+	if !openAIErrorsEnabled(req.Context()) {
+		http.Error(w, message, statusCode)
+		return
+	}
+	openAIErr := newOpenAIError(statusCode, message)
+	if param != "" {
+		openAIErr.Param = &param
+	}
+	writeOpenAIError(w, statusCode, openAIErr)
+}
+
+// writeStreamError rejects a streaming request. Streaming clients expect JSON, so the error is
+// always written in the OpenAI error envelope; with openai_errors enabled it names the parameter.
+func writeStreamError(w http.ResponseWriter, req *http.Request, message string) {
+	openAIErr := models.OpenAIError{Message: message, Type: a2aerrors.TypeInvalidRequest}
+	if openAIErrorsEnabled(req.Context()) {
+		param := "stream"
+		openAIErr.Param = &param
+	}
+	writeOpenAIError(w, http.StatusBadRequest, openAIErr)
+}
+
+// newOpenAIError derives the OpenAI error type and code from the status code
+func newOpenAIError(statusCode int, message string) models.OpenAIError {
+	openAIErr := models.OpenAIError{Message: message, Type: a2aerrors.TypeInvalidRequest}
+	code := ""
+	switch {
+	case statusCode >= http.StatusInternalServerError:
+		openAIErr.Type = a2aerrors.TypeServer
+	case statusCode == http.StatusUnauthorized:
+		code = "invalid_api_key"
+	case statusCode == http.StatusForbidden:
+		code = "forbidden"
+	case statusCode == http.StatusNotFound:
+		code = "not_found"
+	case statusCode == http.StatusMethodNotAllowed:
+		code = "method_not_allowed"
+	case statusCode == http.StatusTooManyRequests:
+		openAIErr.Type = "requests"
+		code = "rate_limit_exceeded"
+	}
+	if code != "" {
+		openAIErr.Code = &code
+	}
+	return openAIErr
+}
+
+// writePassthroughError writes a non-OK agent response that is not a JSON-RPC error.
+// With openai_errors enabled, the body becomes the message of an OpenAI error.
+func writePassthroughError(w http.ResponseWriter, req *http.Request, statusCode int, header http.Header, body []byte) {
+	log := logging.FromRequest(logger, req)
+	if openAIErrorsEnabled(req.Context()) {
+		message := strings.TrimSpace(string(body))
+		if len(message) > maxPassthroughMessage {
+			message = message[:maxPassthroughMessage]
+		}
+		if message == "" {
+			message = http.StatusText(statusCode)
+		}
+		writeOpenAIError(w, statusCode, newOpenAIError(statusCode, message))
+		return
+	}
+
+	for key, values := range header {
+		for _, value := range values {
+			w.Header().Add(key, value)
+		}
+	}
+	w.WriteHeader(statusCode)
+	if _, err := w.Write(body); err != nil {
+		log.Error("failed to write error response:", err)
+	}
+}
@@ -0,0 +1,102 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newOpenAIErrorsHandler(t *testing.T, mockHandler http.Handler, openAIErrors bool) http.Handler {
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "agent", "url": "http://localhost:8001"},
+			},
+			"openai_errors": openAIErrors,
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+	return handler
+}
+
+func TestOpenAIErrors_ModelNotFound(t *testing.T) {
+	handler := newOpenAIErrorsHandler(t, &MockHandler{}, true)
+
+	rec := sendChatCompletion(handler, "unknown-agent", "")
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var errResp models.OpenAIErrorResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	assert.Equal(t, "model not found", errResp.Error.Message)
+	assert.Equal(t, "invalid_request_error", errResp.Error.Type)
+	assert.Equal(t, "model", *errResp.Error.Param)
+	assert.Equal(t, "not_found", *errResp.Error.Code)
+}
+
+func TestOpenAIErrors_Disabled(t *testing.T) {
+	handler := newOpenAIErrorsHandler(t, &MockHandler{}, false)
+
+	rec := sendChatCompletion(handler, "unknown-agent", "")
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Equal(t, "model not found\n", rec.Body.String())
+}
+
+func TestOpenAIErrors_WrapsAgentErrors(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		wantMessage string
+		wantType    string
+	}{
+		{name: "plain text", body: []byte("upstream connect error"), wantMessage: "upstream connect error", wantType: "server_error"},
+		{name: "empty body", body: nil, wantMessage: "Service Unavailable", wantType: "server_error"},
+		{name: "non-OpenAI JSON", body: []byte(`{"detail": "missing field"}`), wantMessage: `{"detail": "missing field"}`, wantType: "server_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newOpenAIErrorsHandler(t, &MockHandler{StatusCode: http.StatusServiceUnavailable, Response: tt.body}, true)
+
+			rec := sendChatCompletion(handler, "agent", "")
+
+			assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+			var errResp models.OpenAIErrorResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+			assert.Equal(t, tt.wantMessage, errResp.Error.Message)
+			assert.Equal(t, tt.wantType, errResp.Error.Type)
+		})
+	}
+}
+
+func TestNewOpenAIError(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		wantType   string
+		wantCode   string
+	}{
+		{statusCode: http.StatusBadRequest, wantType: "invalid_request_error"},
+		{statusCode: http.StatusUnauthorized, wantType: "invalid_request_error", wantCode: "invalid_api_key"},
+		{statusCode: http.StatusTooManyRequests, wantType: "requests", wantCode: "rate_limit_exceeded"},
+		{statusCode: http.StatusBadGateway, wantType: "server_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.statusCode), func(t *testing.T) {
+			openAIErr := newOpenAIError(tt.statusCode, "message")
+
+			assert.Equal(t, tt.wantType, openAIErr.Type)
+			if tt.wantCode == "" {
+				assert.Nil(t, openAIErr.Code)
+			} else {
+				assert.Equal(t, tt.wantCode, *openAIErr.Code)
+			}
+		})
+	}
+}
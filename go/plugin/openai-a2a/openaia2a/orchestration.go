@@ -0,0 +1,338 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+)
+
+const (
+	defaultOrchestrationPath     = "/agents/orchestrate"
+	defaultOrchestrationMaxSteps = 10
+	defaultOrchestrationTimeout  = 5 * time.Minute
+	maxOrchestrationBodySize     = 1 << 20
+)
+
+// OrchestrationConfig enables an endpoint executing pipelines of agents server-side. Each step
+// sends a message rendered from the input and the outputs of earlier steps to a model over A2A.
+type OrchestrationConfig struct {
+	Path     string `json:"path,omitempty"`
+	MaxSteps int    `json:"max_steps,omitempty"`
+	Timeout  string `json:"timeout,omitempty"`
+}
+
+// OrchestrationRequest is a pipeline of agents fed with an input
+type OrchestrationRequest struct {
+	Input string              `json:"input"`
+	Steps []OrchestrationStep `json:"steps"`
+}
+
+// OrchestrationStep sends a message to a model. Message is a template that may reference
+// {{input}}, {{previous}} (the output of the preceding step) and {{steps.<name>}} (the output of
+// an earlier named step); it defaults to {{previous}}, or {{input}} for the first step.
+type OrchestrationStep struct {
+	Name    string `json:"name,omitempty"`
+	Model   string `json:"model"`
+	Message string `json:"message,omitempty"`
+}
+
+// OrchestrationResponse holds the output of the last step and the trace of every executed step.
+// Error is set if a step failed; the trace ends with the failed step.
+type OrchestrationResponse struct {
+	ID     string               `json:"id"`
+	Output string               `json:"output"`
+	Steps  []OrchestrationTrace `json:"steps"`
+	Usage  *models.OpenAIUsage  `json:"usage,omitempty"`
+	Error  string               `json:"error,omitempty"`
+}
+
+// OrchestrationTrace records what a step sent and received
+type OrchestrationTrace struct {
+	Name      string `json:"name,omitempty"`
+	Model     string `json:"model"`
+	Message   string `json:"message"`
+	Output    string `json:"output"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+// templateReference matches the placeholders of step messages
+var templateReference = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_.-]+)\s*\}\}`)
+
+// errContentPolicy fails a step whose message is flagged by moderation
+var errContentPolicy = errors.New("message rejected by the gateway's content policy")
+
+// orchestrator executes pipelines posted to its path
+type orchestrator struct {
+	path     string
+	maxSteps int
+	timeout  time.Duration
+}
+
+// newOrchestrator validates the configuration. A nil config disables the endpoint.
+func newOrchestrator(cfg *OrchestrationConfig) (*orchestrator, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	o := &orchestrator{path: defaultOrchestrationPath, maxSteps: defaultOrchestrationMaxSteps, timeout: defaultOrchestrationTimeout}
+	if cfg.Path != "" {
+		if !strings.HasPrefix(cfg.Path, "/") {
+			return nil, fmt.Errorf("path '%s' must start with /", cfg.Path)
+		}
+		o.path = cfg.Path
+	}
+	if cfg.MaxSteps < 0 {
+		return nil, fmt.Errorf("max_steps %d is negative", cfg.MaxSteps)
+	}
+	if cfg.MaxSteps > 0 {
+		o.maxSteps = cfg.MaxSteps
+	}
+	if cfg.Timeout != "" {
+		var err error
+		if o.timeout, err = time.ParseDuration(cfg.Timeout); err != nil || o.timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout '%s'", cfg.Timeout)
+		}
+	}
+	return o, nil
+}
+
+// handles reports whether the request targets the orchestration endpoint
+func (o *orchestrator) handles(req *http.Request) bool {
+	return o != nil && req.URL.Path == o.path
+}
+
+// serveHTTP validates a pipeline, resolves the models of all steps and executes the steps in order.
+// Callers are authenticated, throttled and restricted to their models like chat completion
+// clients. Sandbox keys are rejected, since they are limited to /models and /chat/completions.
+func (o *orchestrator) serveHTTP(w http.ResponseWriter, req *http.Request, handler http.Handler, cfg config) {
+	log := logging.FromRequest(logger, req)
+	if req.Method != http.MethodPost {
+		writeError(w, req, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if cfg.sandbox.presents(req) {
+		writeError(w, req, http.StatusForbidden, "sandbox keys cannot run pipelines")
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxOrchestrationBodySize))
+	if err != nil {
+		writeError(w, req, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+	var pipeline OrchestrationRequest
+	if err := json.Unmarshal(body, &pipeline); err != nil {
+		writeError(w, req, http.StatusBadRequest, "invalid orchestration request format")
+		return
+	}
+	if err := o.validate(pipeline); err != nil {
+		writeError(w, req, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tenant, ok := cfg.tenancy.admit(w, req)
+	if !ok {
+		return
+	}
+	// Keys flagged for anomalous token usage are throttled temporarily
+	if cfg.anomalies.throttled(req) {
+		writeError(w, req, http.StatusTooManyRequests, "API key throttled due to anomalous token usage")
+		return
+	}
+	if !cfg.quotas.admit(w, req) {
+		return
+	}
+	agents := cfg.agents.load()
+	routes := make([]*ModelInfo, len(pipeline.Steps))
+	allowed := func(modelID string) bool {
+		return tenant.allows(modelID) && modelAllowed(req.Context(), modelID)
+	}
+	for i, step := range pipeline.Steps {
+		if routes[i], err = cfg.skills.route(req.Context(), agents, step.Model, allowed); err != nil {
+			log.Info(fmt.Sprintf("cannot resolve model of step %d: %v", i, err))
+			writeError(w, req, http.StatusNotFound, fmt.Sprintf("steps[%d]: model %s not found", i, step.Model))
+			return
+		}
+	}
+	// Pipelines are not started if an agent of a step is evicted for not being ready
+	for _, route := range routes {
+		if cfg.readiness.rejectChat(w, req, route.ModelID) {
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), o.timeout)
+	defer cancel()
+	resp, err := o.run(ctx, req, handler, cfg, agents, pipeline, routes)
+	recordCompletionUsage(req, cfg, resp.Usage)
+
+	status := http.StatusOK
+	if err != nil {
+		log.Warning(fmt.Sprintf("orchestration %s failed: %s", resp.ID, resp.Error))
+		// Messages rejected by moderation or script rules are the caller's to fix
+		var rejErr *ScriptRejectionError
+		status = http.StatusBadGateway
+		if errors.Is(err, errContentPolicy) || errors.As(err, &rejErr) {
+			status = http.StatusBadRequest
+		}
+	}
+	if err := pluginkit.WriteJSON(w, status, resp); err != nil {
+		log.Error("failed to write response:", err)
+	}
+}
+
+// validate checks the number of steps and that templates only reference the input and the
+// outputs of earlier named steps
+func (o *orchestrator) validate(pipeline OrchestrationRequest) error {
+	if len(pipeline.Steps) == 0 {
+		return errors.New("at least one step is required")
+	}
+	if len(pipeline.Steps) > o.maxSteps {
+		return fmt.Errorf("pipelines are limited to %d steps", o.maxSteps)
+	}
+	names := make(map[string]bool)
+	for i, step := range pipeline.Steps {
+		if step.Model == "" {
+			return fmt.Errorf("steps[%d]: model is required", i)
+		}
+		for _, match := range templateReference.FindAllStringSubmatch(step.Message, -1) {
+			ref := match[1]
+			switch {
+			case ref == "input":
+			case ref == "previous" && i > 0:
+			case strings.HasPrefix(ref, "steps.") && names[strings.TrimPrefix(ref, "steps.")]:
+			default:
+				return fmt.Errorf("steps[%d]: unknown reference {{%s}}", i, ref)
+			}
+		}
+		if step.Name != "" {
+			if names[step.Name] {
+				return fmt.Errorf("steps[%d]: duplicate step name '%s'", i, step.Name)
+			}
+			names[step.Name] = true
+		}
+	}
+	return nil
+}
+
+// run executes the steps in order and stops at the first failure, whose error it returns
+func (o *orchestrator) run(ctx context.Context, req *http.Request, handler http.Handler, cfg config, agents *agentSet, pipeline OrchestrationRequest, routes []*ModelInfo) (OrchestrationResponse, error) {
+	resp := OrchestrationResponse{ID: newID(), Steps: []OrchestrationTrace{}}
+	outputs := make(map[string]string)
+	previous := pipeline.Input
+	var usage models.OpenAIUsage
+	var failure error
+	for i, step := range pipeline.Steps {
+		message := step.Message
+		if message == "" {
+			message = "{{previous}}"
+		}
+		message = templateReference.ReplaceAllStringFunc(message, func(placeholder string) string {
+			ref := templateReference.FindStringSubmatch(placeholder)[1]
+			switch ref {
+			case "input":
+				return pipeline.Input
+			case "previous":
+				return previous
+			}
+			return outputs[strings.TrimPrefix(ref, "steps.")]
+		})
+
+		trace := OrchestrationTrace{Name: step.Name, Model: routes[i].ModelID, Message: message}
+		openAIReq := models.OpenAIRequest{Model: routes[i].ModelID, Messages: []models.OpenAIMessage{{Role: "user", Content: message}}}
+		start := time.Now()
+		output, err := o.send(ctx, req, handler, cfg, agents, routes[i], openAIReq, resp.ID)
+		trace.LatencyMs = time.Since(start).Milliseconds()
+		if err != nil {
+			trace.Error = err.Error()
+			resp.Steps = append(resp.Steps, trace)
+			resp.Error = fmt.Sprintf("step %d (%s) failed: %v", i, routes[i].ModelID, err)
+			failure = err
+			break
+		}
+		trace.Output = output
+		resp.Steps = append(resp.Steps, trace)
+
+		stepUsage := estimateUsage(openAIReq, output)
+		usage.PromptTokens += stepUsage.PromptTokens
+		usage.CompletionTokens += stepUsage.CompletionTokens
+		usage.TotalTokens += stepUsage.TotalTokens
+		if step.Name != "" {
+			outputs[step.Name] = output
+		}
+		previous = output
+		resp.Output = output
+	}
+	if usage.TotalTokens > 0 {
+		resp.Usage = &usage
+	}
+	return resp, failure
+}
+
+// send delivers the message of a step like a single-choice chat completion: directly to ensembles,
+// canaries, load-balanced and gRPC agents, through KrakenD to all others. Messages are moderated,
+// redacted and evaluated by script rules, outputs are redacted and moderated, like chat completions.
+// The context of the orchestration is shared by its steps.
+func (o *orchestrator) send(ctx context.Context, req *http.Request, handler http.Handler, cfg config, agents *agentSet, modelInfo *ModelInfo, openAIReq models.OpenAIRequest, contextID string) (string, error) {
+	if cfg.moderator != nil {
+		if reason, flagged := cfg.moderator.check(ctx, openAIReq.Messages[0].Content); flagged {
+			logging.FromRequest(logger, req).Warning(fmt.Sprintf("step for model %s rejected by moderation: %s", modelInfo.ModelID, reason))
+			return "", errContentPolicy
+		}
+	}
+	cfg.redactor.redactRequest(&openAIReq)
+
+	a2aReq, err := transformOpenAIToA2A(openAIReq, contextID, "")
+	if err != nil {
+		return "", err
+	}
+	metadata, err := cfg.scripts.evaluate(openAIReq, req.Header)
+	if err != nil {
+		var rejErr *ScriptRejectionError
+		if errors.As(err, &rejErr) && rejErr.Message != "" {
+			return "", fmt.Errorf("%s: %w", rejErr.Message, err)
+		}
+		return "", err
+	}
+	for key, value := range metadata {
+		a2aReq.Params.Metadata[key] = value
+	}
+
+	agent, _ := agents.agent(modelInfo.ModelID)
+	injectSystemPrompt(a2aReq, agent)
+	body, err := json.Marshal(a2aReq)
+	if err != nil {
+		return "", fmt.Errorf("failed to create A2A request: %w", err)
+	}
+
+	send, _ := directRoute(&detachedWriter{header: http.Header{}}, req, agents, modelInfo, contextID)
+	if send == nil {
+		rpc := krakendRPC(req, handler, modelInfo.Path)
+		if grpcClient := agents.grpc[modelInfo.ModelID]; grpcClient != nil {
+			rpc = grpcClient.Transcode
+		}
+		send = rpcRoute(rpc)
+	}
+	content, err := send(ctx, body)
+	if err != nil {
+		return "", err
+	}
+	// Outputs are redacted and moderated before they are passed on, like completions returned to
+	// clients. A filtered output fails the step.
+	completion := newOpenAIResponse(content, openAIReq)
+	cfg.redactor.redactResponse(&completion)
+	cfg.moderator.filterResponse(ctx, &completion)
+	if completion.Choices[0].FinishReason == contentFilter {
+		return "", errors.New("output filtered by the gateway's content policy")
+	}
+	return completion.Choices[0].Message.Content, nil
+}
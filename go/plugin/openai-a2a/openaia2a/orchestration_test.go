@@ -0,0 +1,221 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/testagent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pipelineBackend answers A2A requests like KrakenD would for echo agents: "<agent>(<message>)".
+// The broken agent fails.
+func pipelineBackend(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/broken-agent" {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	var rpcReq models.SendMessageRequest
+	_ = json.NewDecoder(req.Body).Decode(&rpcReq)
+	reply := strings.TrimPrefix(req.URL.Path, "/") + "(" + testagent.MessageText(rpcReq.Params.Message) + ")"
+	_ = pluginkit.WriteJSON(w, http.StatusOK, map[string]interface{}{"jsonrpc": "2.0", "id": rpcReq.Id, "result": map[string]interface{}{
+		"kind": "task", "id": "t1", "contextId": "c1", "status": map[string]interface{}{"state": "completed"},
+		"artifacts": []interface{}{map[string]interface{}{"artifactId": "a1", "parts": []interface{}{map[string]interface{}{"kind": "text", "text": reply}}}},
+	}})
+}
+
+// newOrchestrationHandler configures the pipeline agents, orchestration and the given features
+func newOrchestrationHandler(t *testing.T, features map[string]interface{}) http.Handler {
+	gatewayConfig := map[string]interface{}{
+		"agents": []interface{}{
+			map[string]interface{}{"model_id": "weather-agent", "url": "http://weather-agent:8000"},
+			map[string]interface{}{"model_id": "travel-agent", "url": "http://travel-agent:8000"},
+			map[string]interface{}{"model_id": "broken-agent", "url": "http://broken-agent:8000"},
+		},
+		"orchestration": map[string]interface{}{"max_steps": 3},
+	}
+	for name, feature := range features {
+		gatewayConfig[name] = feature
+	}
+	extraConfig := map[string]interface{}{"openai_a2a_config": gatewayConfig}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, http.HandlerFunc(pipelineBackend))
+	require.NoError(t, err)
+	return handler
+}
+
+func orchestrate(handler http.Handler, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/agents/orchestrate", strings.NewReader(body)))
+	return rec
+}
+
+func TestOrchestration_RunsPipeline(t *testing.T) {
+	handler := newOrchestrationHandler(t, nil)
+
+	rec := orchestrate(handler, `{"input":"Paris","steps":[
+		{"name":"weather","model":"weather-agent","message":"Forecast for {{input}}"},
+		{"model":"travel-agent","message":"Plan {{ input }} with {{previous}}"},
+		{"model":"weather-agent","message":"Check {{steps.weather}}"}]}`)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp OrchestrationResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp.ID)
+	assert.Empty(t, resp.Error)
+	require.Len(t, resp.Steps, 3)
+	assert.Equal(t, "Forecast for Paris", resp.Steps[0].Message)
+	assert.Equal(t, "weather-agent(Forecast for Paris)", resp.Steps[0].Output)
+	assert.Equal(t, "Plan Paris with weather-agent(Forecast for Paris)", resp.Steps[1].Message)
+	assert.Equal(t, "travel-agent", resp.Steps[1].Model)
+	assert.Equal(t, "Check weather-agent(Forecast for Paris)", resp.Steps[2].Message)
+	assert.Equal(t, "weather-agent(Check weather-agent(Forecast for Paris))", resp.Output)
+	require.NotNil(t, resp.Usage)
+	assert.Positive(t, resp.Usage.TotalTokens)
+}
+
+func TestOrchestration_DefaultMessagesChainOutputs(t *testing.T) {
+	handler := newOrchestrationHandler(t, nil)
+
+	rec := orchestrate(handler, `{"input":"Paris","steps":[{"model":"weather-agent"},{"model":"travel-agent"}]}`)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp OrchestrationResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "travel-agent(weather-agent(Paris))", resp.Output)
+}
+
+func TestOrchestration_FailedStep(t *testing.T) {
+	handler := newOrchestrationHandler(t, nil)
+
+	rec := orchestrate(handler, `{"input":"Paris","steps":[{"model":"weather-agent"},{"model":"broken-agent"},{"model":"travel-agent"}]}`)
+
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	var resp OrchestrationResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Contains(t, resp.Error, "step 1 (broken-agent) failed")
+	require.Len(t, resp.Steps, 2, "the pipeline stops at the failed step")
+	assert.NotEmpty(t, resp.Steps[1].Error)
+	assert.Equal(t, "weather-agent(Paris)", resp.Output, "the output of the last successful step is kept")
+}
+
+func TestOrchestration_AppliesContentPolicies(t *testing.T) {
+	handler := newOrchestrationHandler(t, map[string]interface{}{
+		"moderation": map[string]interface{}{"denylist": []interface{}{"bomb", "travel-agent("}, "scan_responses": true},
+		"redaction":  map[string]interface{}{"detectors": []interface{}{"email"}, "requests": true, "responses": true},
+		"scripts": map[string]interface{}{
+			"rules": []interface{}{map[string]interface{}{"expression": `!request.messages[0].content.contains("secret")`, "message": "no secrets"}},
+		},
+	})
+
+	decode := func(rec *httptest.ResponseRecorder) OrchestrationResponse {
+		var resp OrchestrationResponse
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		return resp
+	}
+
+	rec := orchestrate(handler, `{"input":"Mail jane@example.com","steps":[{"model":"weather-agent"}]}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "weather-agent(Mail [REDACTED:email])", decode(rec).Output, "messages are redacted before they reach agents")
+
+	rec = orchestrate(handler, `{"input":"Paris","steps":[{"model":"weather-agent"},{"model":"weather-agent","message":"Build a bomb with {{previous}}"}]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	resp := decode(rec)
+	assert.Contains(t, resp.Error, "step 1 (weather-agent) failed: message rejected by the gateway's content policy")
+	assert.Equal(t, "weather-agent(Paris)", resp.Output)
+
+	rec = orchestrate(handler, `{"input":"Paris","steps":[{"model":"weather-agent","message":"Tell a secret of {{input}}"}]}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, decode(rec).Error, "no secrets")
+
+	rec = orchestrate(handler, `{"input":"Paris","steps":[{"model":"weather-agent"},{"model":"travel-agent"}]}`)
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	resp = decode(rec)
+	assert.Contains(t, resp.Error, "step 1 (travel-agent) failed: output filtered by the gateway's content policy")
+	assert.Empty(t, resp.Steps[1].Output, "filtered outputs are not returned")
+}
+
+func TestOrchestration_RejectsSandboxKeys(t *testing.T) {
+	handler := newOrchestrationHandler(t, map[string]interface{}{
+		"sandbox": map[string]interface{}{"key_prefix": "sk-sandbox-"},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/agents/orchestrate", strings.NewReader(`{"input":"Paris","steps":[{"model":"sandbox/echo"}]}`))
+	req.Header.Set("Authorization", "Bearer sk-sandbox-test")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "sandbox keys cannot run pipelines")
+}
+
+func TestOrchestration_RejectsPipelinesWithEvictedAgents(t *testing.T) {
+	captureEvents(t)
+	var mode atomic.Value
+	down := newReadinessTarget(t, &mode, 0)
+	mode.Store("down")
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "weather-agent", "url": "http://weather-agent:8000"},
+				map[string]interface{}{"model_id": "down-agent", "url": down.URL},
+			},
+			"orchestration": map[string]interface{}{},
+			"readiness":     map[string]interface{}{"interval": "20ms"},
+		},
+	}
+	handler, err := module.registerHandlers(ctx, extraConfig, http.HandlerFunc(pipelineBackend))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return orchestrate(handler, `{"input":"Paris","steps":[{"model":"weather-agent"},{"model":"down-agent"}]}`).Code == http.StatusServiceUnavailable
+	}, 2*time.Second, 10*time.Millisecond, "pipelines are not started if an agent is evicted")
+}
+
+func TestOrchestration_RejectsInvalidPipelines(t *testing.T) {
+	handler := newOrchestrationHandler(t, nil)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantMsg    string
+	}{
+		{name: "malformed", body: `{"steps":`, wantStatus: http.StatusBadRequest, wantMsg: "invalid orchestration request"},
+		{name: "no steps", body: `{"input":"Hi","steps":[]}`, wantStatus: http.StatusBadRequest, wantMsg: "at least one step"},
+		{name: "too many steps", body: `{"steps":[{"model":"a"},{"model":"a"},{"model":"a"},{"model":"a"}]}`, wantStatus: http.StatusBadRequest, wantMsg: "limited to 3 steps"},
+		{name: "previous in first step", body: `{"steps":[{"model":"weather-agent","message":"{{previous}}"}]}`, wantStatus: http.StatusBadRequest, wantMsg: "unknown reference {{previous}}"},
+		{name: "later step", body: `{"steps":[{"model":"weather-agent","message":"{{steps.plan}}"},{"name":"plan","model":"travel-agent"}]}`, wantStatus: http.StatusBadRequest, wantMsg: "unknown reference {{steps.plan}}"},
+		{name: "duplicate name", body: `{"steps":[{"name":"a","model":"weather-agent"},{"name":"a","model":"travel-agent"}]}`, wantStatus: http.StatusBadRequest, wantMsg: "duplicate step name"},
+		{name: "unknown model", body: `{"steps":[{"model":"weather-agent"},{"model":"chef-agent"}]}`, wantStatus: http.StatusNotFound, wantMsg: "steps[1]: model chef-agent not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := orchestrate(handler, tt.body)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.wantMsg)
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/agents/orchestrate", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestNewOrchestrator_InvalidConfig(t *testing.T) {
+	for _, cfg := range []OrchestrationConfig{{Path: "orchestrate"}, {MaxSteps: -1}, {Timeout: "soon"}} {
+		_, err := newOrchestrator(&cfg)
+		assert.Error(t, err, cfg)
+	}
+}
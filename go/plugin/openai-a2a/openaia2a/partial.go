@@ -0,0 +1,138 @@
+package openaia2a
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aclient"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/streamconv"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/timing"
+)
+
+const (
+	finishLength  = "length"
+	finishTimeout = "timeout"
+)
+
+// errAgentDeadline is the cause of agent calls cut off by the configured timeout
+var errAgentDeadline = errors.New("agent deadline exceeded")
+
+// PartialResponseConfig returns the content an agent produced before its deadline as a truncated
+// completion instead of a 504. Agents are called with message/stream to collect their output.
+type PartialResponseConfig struct {
+	// Timeout is the deadline of agent calls. The latency budget of the client applies as well.
+	Timeout string `json:"timeout,omitempty"`
+	// FinishReason of truncated completions: "length" (default) or "timeout"
+	FinishReason string `json:"finish_reason,omitempty"`
+}
+
+// partialResponder collects agent streams into completions that survive the deadline
+type partialResponder struct {
+	timeout      time.Duration
+	finishReason string
+}
+
+// newPartialResponder validates the configuration. A nil config discards partial output.
+func newPartialResponder(cfg *PartialResponseConfig) (*partialResponder, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	p := &partialResponder{finishReason: finishLength}
+	if cfg.Timeout != "" {
+		var err error
+		if p.timeout, err = time.ParseDuration(cfg.Timeout); err != nil || p.timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout '%s'", cfg.Timeout)
+		}
+	}
+	switch cfg.FinishReason {
+	case "":
+	case finishLength, finishTimeout:
+		p.finishReason = cfg.FinishReason
+	default:
+		return nil, fmt.Errorf("invalid finish_reason '%s', expected '%s' or '%s'", cfg.FinishReason, finishLength, finishTimeout)
+	}
+	return p, nil
+}
+
+// serve streams params to the agent at target and writes the collected content as a completion.
+// If the deadline passes after the agent produced content, the completion is truncated and
+// carries a warning; without content the request fails with 504 Gateway Timeout.
+func (p *partialResponder) serve(w http.ResponseWriter, req *http.Request, cfg config, target string, params models.MessageSendParams, openAIReq models.OpenAIRequest) {
+	log := logging.FromRequest(logger, req)
+	defer activities.begin(activityStreaming, openAIReq.Model)()
+	ctx := req.Context()
+	if p.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, p.timeout, errAgentDeadline)
+		defer cancel()
+	}
+	client, err := a2aclient.New(target, a2aclient.Options{HTTPClient: upstreamClient, Header: agentHeader(req)})
+	if err != nil {
+		log.Error("failed to create agent client:", err)
+		writeError(w, req, http.StatusInternalServerError, "failed to create A2A request")
+		return
+	}
+
+	stopUpstream := timing.Upstream(ctx)
+	content, finishReason, err := collect(ctx, client, params)
+	stopUpstream()
+	if err != nil && ctx.Err() == nil {
+		log.Error("direct agent request failed:", err)
+		writeUpstreamError(w, req, err, "agent did not return a response")
+		return
+	}
+	if err != nil && content == "" {
+		log.Warning(fmt.Sprintf("model %s returned no content before its deadline", openAIReq.Model))
+		writeError(w, req, http.StatusGatewayTimeout, context.Cause(ctx).Error())
+		return
+	}
+
+	openAIResp := newOpenAIResponse(content, openAIReq)
+	openAIResp.Choices[0].FinishReason = finishReason
+	if err != nil {
+		log.Warning(fmt.Sprintf("model %s exceeded its deadline, returning partial content", openAIReq.Model))
+		openAIResp.Choices[0].FinishReason = p.finishReason
+		openAIResp.Warning = fmt.Sprintf("the response is incomplete: %s", context.Cause(ctx))
+	}
+	writeCompletion(w, req, cfg, openAIResp)
+}
+
+// collect streams params to the agent and returns the text of its events and the finish reason of
+// the task. On errors, the text received so far is returned along with the error.
+func collect(ctx context.Context, client *a2aclient.Client, params models.MessageSendParams) (string, string, error) {
+	stream, err := client.StreamMessage(ctx, params)
+	if err != nil {
+		return "", "", err
+	}
+	defer stream.Close()
+
+	var content strings.Builder
+	finishReason := streamconv.FinishStop
+	conv := &streamconv.ToOpenAI{}
+	for !conv.Done() {
+		event, err := stream.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return content.String(), "", err
+		}
+		for _, chunk := range conv.Convert(event) {
+			content.WriteString(chunk.Choices[0].Delta.Content)
+			if chunk.Choices[0].FinishReason != nil {
+				finishReason = *chunk.Choices[0].FinishReason
+			}
+		}
+	}
+	if !conv.Done() && ctx.Err() != nil {
+		return content.String(), "", context.Cause(ctx)
+	}
+	return content.String(), finishReason, nil
+}
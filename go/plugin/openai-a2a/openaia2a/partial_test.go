@@ -0,0 +1,91 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/testagent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatCompletions_PartialResponse(t *testing.T) {
+	agent := testagent.New(testagent.Options{Handler: func(message models.Message) testagent.Response {
+		switch testagent.MessageText(message) {
+		case "slow":
+			return testagent.Response{Chunks: []string{"Sunny", ", 21°C"}, ChunkDelay: 200 * time.Millisecond}
+		case "silent":
+			return testagent.Response{Text: "Sunny", Latency: time.Second}
+		}
+		return testagent.Response{Chunks: []string{"Sunny", ", 21°C"}}
+	}})
+	t.Cleanup(agent.Close)
+
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents":            []interface{}{map[string]interface{}{"model_id": "weather-agent", "url": agent.URL}},
+			"partial_responses": map[string]interface{}{"timeout": "300ms", "finish_reason": "timeout"},
+		},
+	}
+	mockHandler := &MockHandler{}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name             string
+		content          string
+		wantStatus       int
+		wantContent      string
+		wantFinishReason string
+		wantWarning      bool
+	}{
+		{name: "completed in time", content: "fast", wantStatus: http.StatusOK, wantContent: "Sunny, 21°C", wantFinishReason: "stop"},
+		{name: "cut off after content", content: "slow", wantStatus: http.StatusOK, wantContent: "Sunny", wantFinishReason: "timeout", wantWarning: true},
+		{name: "cut off without content", content: "silent", wantStatus: http.StatusGatewayTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"model":"weather-agent","messages":[{"role":"user","content":"` + tt.content + `"}]}`
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat/completions", strings.NewReader(body)))
+
+			require.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantStatus != http.StatusOK {
+				assert.Contains(t, rec.Body.String(), "agent deadline exceeded")
+				return
+			}
+			var resp models.OpenAIResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			require.Len(t, resp.Choices, 1)
+			assert.Equal(t, tt.wantContent, resp.Choices[0].Message.Content)
+			assert.Equal(t, tt.wantFinishReason, resp.Choices[0].FinishReason)
+			assert.Equal(t, tt.wantWarning, resp.Warning != "", resp.Warning)
+		})
+	}
+	assert.Nil(t, mockHandler.ReceivedRequest, "agents are streamed to directly")
+	for _, request := range agent.Requests() {
+		assert.Equal(t, "message/stream", request.Method)
+	}
+}
+
+func TestNewPartialResponder(t *testing.T) {
+	p, err := newPartialResponder(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, p)
+
+	p, err = newPartialResponder(&PartialResponseConfig{})
+	require.NoError(t, err)
+	assert.Equal(t, finishLength, p.finishReason)
+	assert.Zero(t, p.timeout, "only the latency budget of the client applies")
+
+	_, err = newPartialResponder(&PartialResponseConfig{Timeout: "soon"})
+	assert.Error(t, err)
+	_, err = newPartialResponder(&PartialResponseConfig{FinishReason: "stop"})
+	assert.Error(t, err)
+}
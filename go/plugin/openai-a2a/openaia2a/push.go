@@ -0,0 +1,468 @@
+package openaia2a
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/netclass"
+	"github.com/go-http-utils/headers"
+)
+
+const (
+	defaultPushPath             = "/a2a/push"
+	defaultPushTTL              = 24 * time.Hour
+	defaultPushTimeout          = 10 * time.Second
+	defaultPushMaxRegistrations = 100
+	pushPurgeInterval           = time.Minute
+
+	// maxPushNotificationSize limits the notifications agents send to the webhooks
+	maxPushNotificationSize = 1 << 20
+)
+
+// pushTokenHeader carries the token of a push notification config, as defined by A2A
+const pushTokenHeader = "X-A2A-Notification-Token"
+
+// pushPrivateHost reports whether notifications must not be relayed to a host. Client callbacks
+// are only reached on public addresses, so clients cannot make the gateway call into the cluster.
+var pushPrivateHost = netclass.IsPrivateHost
+
+// PushNotificationConfig makes the gateway the webhook receiver of A2A push notifications.
+// CallbackURL is the base URL under which agents reach the gateway; it defaults to the
+// URL of the client request. MaxRegistrations caps the webhooks each caller has registered
+// at a time.
+type PushNotificationConfig struct {
+	Path             string `json:"path,omitempty"`
+	CallbackURL      string `json:"callback_url,omitempty"`
+	TTL              string `json:"ttl,omitempty"`
+	Timeout          string `json:"timeout,omitempty"`
+	MaxRegistrations int    `json:"max_registrations,omitempty"`
+}
+
+// pushRegistration maps a webhook of the gateway to the callback of a client and remembers the
+// caller that registered it and the model it was registered with
+type pushRegistration struct {
+	token   string
+	owner   string
+	model   string
+	client  map[string]interface{}
+	expires time.Time
+}
+
+// pushRelay registers the gateway in place of the clients' webhooks and relays the
+// notifications of agents, so agents never see client callback URLs or credentials
+type pushRelay struct {
+	path             string
+	callbackURL      string
+	ttl              time.Duration
+	timeout          time.Duration
+	maxRegistrations int
+	client           *http.Client
+
+	// capabilities lets the relay reject configs for agents without push notification support
+	capabilities *capabilityDiscovery
+
+	mu            sync.Mutex
+	registrations map[string]*pushRegistration
+	owners        map[string]int
+	now           func() time.Time
+}
+
+// newPushRelay validates the configuration. A nil config disables push notification relaying.
+func newPushRelay(cfg *PushNotificationConfig) (*pushRelay, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	p := &pushRelay{
+		path:             defaultPushPath,
+		ttl:              defaultPushTTL,
+		timeout:          defaultPushTimeout,
+		maxRegistrations: defaultPushMaxRegistrations,
+		client:           newPushClient(),
+		registrations:    make(map[string]*pushRegistration),
+		owners:           make(map[string]int),
+		now:              time.Now,
+	}
+
+	var err error
+	if cfg.Path != "" {
+		if !strings.HasPrefix(cfg.Path, "/") {
+			return nil, fmt.Errorf("path '%s' must start with /", cfg.Path)
+		}
+		p.path = strings.TrimSuffix(cfg.Path, "/")
+	}
+	if cfg.CallbackURL != "" {
+		if parsed, err := url.Parse(cfg.CallbackURL); err != nil || parsed.Host == "" {
+			return nil, fmt.Errorf("invalid callback_url '%s'", cfg.CallbackURL)
+		}
+		p.callbackURL = strings.TrimSuffix(cfg.CallbackURL, "/")
+	}
+	if cfg.TTL != "" {
+		if p.ttl, err = time.ParseDuration(cfg.TTL); err != nil || p.ttl <= 0 {
+			return nil, fmt.Errorf("invalid ttl '%s'", cfg.TTL)
+		}
+	}
+	if cfg.Timeout != "" {
+		if p.timeout, err = time.ParseDuration(cfg.Timeout); err != nil || p.timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout '%s'", cfg.Timeout)
+		}
+	}
+	if cfg.MaxRegistrations < 0 {
+		return nil, fmt.Errorf("max_registrations %d is negative", cfg.MaxRegistrations)
+	}
+	if cfg.MaxRegistrations > 0 {
+		p.maxRegistrations = cfg.MaxRegistrations
+	}
+	return p, nil
+}
+
+// newPushClient returns a client that refuses to connect to private addresses. The address is
+// checked when dialing, after name resolution and for every redirect, so host names resolving
+// into the cluster are refused as well. Notifications are never sent through a proxy, which
+// would hide the address.
+func newPushClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			if pushPrivateHost(address) {
+				return fmt.Errorf("push notification url resolves to the private address %s", address)
+			}
+			return nil
+		},
+	}
+	return &http.Client{Transport: &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: 10 * time.Second}}
+}
+
+// start purges expired registrations until ctx is done
+func (p *pushRelay) start(ctx context.Context) {
+	if p == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(pushPurgeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.purge()
+			}
+		}
+	}()
+}
+
+// purge removes expired registrations
+func (p *pushRelay) purge() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	for id, registration := range p.registrations {
+		if now.After(registration.expires) {
+			p.remove(id, registration)
+		}
+	}
+}
+
+// remove deletes a registration. The caller must hold p.mu.
+func (p *pushRelay) remove(id string, registration *pushRegistration) {
+	delete(p.registrations, id)
+	if p.owners[registration.owner]--; p.owners[registration.owner] <= 0 {
+		delete(p.owners, registration.owner)
+	}
+}
+
+// handlesWebhook reports whether the request is a notification of an agent
+func (p *pushRelay) handlesWebhook(req *http.Request) bool {
+	return p != nil && req.Method == http.MethodPost && strings.HasPrefix(req.URL.Path, p.path+"/")
+}
+
+// serveWebhook verifies the token of an agent notification and relays it to the client
+func (p *pushRelay) serveWebhook(w http.ResponseWriter, req *http.Request) {
+	log := logging.FromRequest(logger, req)
+	id := strings.TrimPrefix(req.URL.Path, p.path+"/")
+	registration, ok := p.lookup(id)
+	if !ok {
+		http.Error(w, "unknown push notification config", http.StatusNotFound)
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(req.Header.Get(pushTokenHeader)), []byte(registration.token)) != 1 {
+		log.Warning(fmt.Sprintf("rejected push notification with invalid token for %s", id))
+		http.Error(w, "invalid notification token", http.StatusUnauthorized)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxPushNotificationSize+1))
+	if err != nil {
+		http.Error(w, "cannot read notification", http.StatusBadRequest)
+		return
+	}
+	if len(body) > maxPushNotificationSize {
+		http.Error(w, "notification too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	var task models.Task
+	if json.Unmarshal(body, &task) == nil && task.Kind == "task" {
+		publishTaskCompleted(registration.model, task.Id, task.ContextId, task.Status.State)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), p.timeout)
+	defer cancel()
+
+	clientURL, _ := registration.client["url"].(string)
+	relayReq, err := http.NewRequestWithContext(ctx, http.MethodPost, clientURL, bytes.NewReader(body))
+	if err != nil {
+		http.Error(w, "invalid client callback", http.StatusBadGateway)
+		return
+	}
+	relayReq.Header.Set(headers.ContentType, req.Header.Get(headers.ContentType))
+	if token, ok := registration.client["token"].(string); ok {
+		relayReq.Header.Set(pushTokenHeader, token)
+	}
+	if authorization := pushAuthorization(registration.client); authorization != "" {
+		relayReq.Header.Set(headers.Authorization, authorization)
+	}
+
+	resp, err := p.client.Do(relayReq)
+	if err != nil {
+		log.Error(fmt.Sprintf("failed to relay push notification to %s: %v", clientURL, err))
+		http.Error(w, "client callback is not reachable", http.StatusBadGateway)
+		return
+	}
+	_ = resp.Body.Close()
+	w.WriteHeader(resp.StatusCode)
+}
+
+// pushAuthorization returns the Authorization header for the first supported scheme of the
+// client config, i.e. Bearer or Basic with the configured credentials
+func pushAuthorization(client map[string]interface{}) string {
+	auth, _ := client["authentication"].(map[string]interface{})
+	credentials, _ := auth["credentials"].(string)
+	schemes, _ := auth["schemes"].([]interface{})
+	if credentials == "" {
+		return ""
+	}
+	for _, scheme := range schemes {
+		if name, _ := scheme.(string); strings.EqualFold(name, "Bearer") || strings.EqualFold(name, "Basic") {
+			return name + " " + credentials
+		}
+	}
+	return ""
+}
+
+// intercept rewrites the push notification configs of native A2A requests. Configs sent with
+// tasks/pushNotificationConfig/set or message/send and message/stream are registered and replaced
+// by a webhook of the gateway. Responses of config methods are forwarded here and mapped back to
+// the client configs; false is returned for all requests that continue to the agent as usual.
+// Agents whose card does not declare push notifications are spared configs and config methods.
+func (p *pushRelay) intercept(w http.ResponseWriter, req *http.Request, handler http.Handler, agent AgentInfo) bool {
+	log := logging.FromRequest(logger, req)
+	if p == nil {
+		return false
+	}
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		writeError(w, req, http.StatusBadRequest, "cannot read request body")
+		return true
+	}
+	restore := func(body []byte) {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+		req.Header.Set(headers.ContentLength, fmt.Sprintf("%d", len(body)))
+	}
+
+	var rpcReq map[string]interface{}
+	if json.Unmarshal(body, &rpcReq) != nil {
+		restore(body)
+		return false
+	}
+	method, _ := rpcReq["method"].(string)
+	params, _ := rpcReq["params"].(map[string]interface{})
+
+	var config map[string]interface{}
+	switch method {
+	case "tasks/pushNotificationConfig/set":
+		config, _ = params["pushNotificationConfig"].(map[string]interface{})
+	case "message/send", "message/stream":
+		configuration, _ := params["configuration"].(map[string]interface{})
+		config, _ = configuration["pushNotificationConfig"].(map[string]interface{})
+	case "tasks/pushNotificationConfig/get", "tasks/pushNotificationConfig/list":
+	default:
+		restore(body)
+		return false
+	}
+	if (config != nil || strings.HasPrefix(method, "tasks/pushNotificationConfig/")) && !p.capabilities.supportsPushNotifications(req.Context(), agent) {
+		log.Info(fmt.Sprintf("rejected %s: model %s does not support push notifications", method, agent.ModelID))
+		writeA2AError(w, http.StatusBadRequest, rpcReq["id"], &models.JSONRPCErrorResponseError{Code: a2aerrors.CodePushNotificationNotSupported, Message: fmt.Sprintf("model %s does not support push notifications", agent.ModelID)})
+		return true
+	}
+	if config != nil {
+		if err := p.register(req, config, agent.ModelID); err != nil {
+			writeA2AError(w, http.StatusBadRequest, rpcReq["id"], &models.JSONRPCErrorResponseError{Code: a2aerrors.CodeInvalidParams, Message: err.Error()})
+			return true
+		}
+		if body, err = json.Marshal(rpcReq); err != nil {
+			writeError(w, req, http.StatusInternalServerError, "failed to create A2A request")
+			return true
+		}
+	}
+	if !strings.HasPrefix(method, "tasks/pushNotificationConfig/") {
+		restore(body)
+		return false
+	}
+
+	resp, err := krakendRPC(req, handler, req.URL.Path)(req.Context(), body)
+	if err != nil {
+		log.Error(fmt.Sprintf("push notification config request to %s failed: %v", req.URL.Path, err))
+		writeError(w, req, http.StatusBadGateway, "agent did not return a response")
+		return true
+	}
+	resp = p.restoreConfigs(req, resp)
+	w.Header().Set(headers.ContentType, "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(resp); err != nil {
+		log.Error("failed to write response:", err)
+	}
+	return true
+}
+
+// register stores the client config for model and replaces its url and token by a webhook of the
+// gateway. URLs of private hosts are rejected and each caller, identified by its principal or
+// address, may hold at most maxRegistrations webhooks.
+func (p *pushRelay) register(req *http.Request, config map[string]interface{}, model string) error {
+	clientURL, _ := config["url"].(string)
+	parsed, err := url.Parse(clientURL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return fmt.Errorf("invalid push notification url '%s'", clientURL)
+	}
+	if pushPrivateHost(parsed.Hostname()) {
+		return fmt.Errorf("push notification url '%s' is not public", clientURL)
+	}
+	token, err := newPushToken()
+	if err != nil {
+		return err
+	}
+
+	client := make(map[string]interface{}, len(config))
+	for key, value := range config {
+		client[key] = value
+	}
+	id := newID()
+	base := p.callbackURL
+	if base == "" {
+		base = requestBaseURL(req)
+	}
+
+	owner := pushOwner(req)
+	p.mu.Lock()
+	if p.owners[owner] >= p.maxRegistrations {
+		p.mu.Unlock()
+		return fmt.Errorf("too many push notification configs, at most %d are kept per caller", p.maxRegistrations)
+	}
+	p.owners[owner]++
+	p.registrations[id] = &pushRegistration{token: token, owner: owner, model: model, client: client, expires: p.now().Add(p.ttl)}
+	p.mu.Unlock()
+
+	config["url"] = base + p.path + "/" + id
+	config["token"] = token
+	delete(config, "authentication")
+	return nil
+}
+
+// pushOwner identifies the caller registering a webhook by its principal or, for anonymous
+// callers, its address
+func pushOwner(req *http.Request) string {
+	if id := principal(req.Context()); id != "" {
+		return id
+	}
+	if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+		return host
+	}
+	return req.RemoteAddr
+}
+
+// lookup returns the registration of a webhook that has not expired
+func (p *pushRelay) lookup(id string) (*pushRegistration, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	registration, ok := p.registrations[id]
+	if !ok || p.now().After(registration.expires) {
+		return nil, false
+	}
+	return registration, true
+}
+
+// restoreConfigs replaces gateway webhooks in a config method response by the client configs the
+// caller of req registered, without their credentials. Webhooks registered by other callers lose
+// their token, so they cannot be used to forge notifications.
+func (p *pushRelay) restoreConfigs(req *http.Request, body []byte) []byte {
+	owner := pushOwner(req)
+	var resp map[string]interface{}
+	if json.Unmarshal(body, &resp) != nil {
+		return body
+	}
+	results, ok := resp["result"].([]interface{})
+	if !ok {
+		results = []interface{}{resp["result"]}
+	}
+	for _, result := range results {
+		taskConfig, _ := result.(map[string]interface{})
+		config, _ := taskConfig["pushNotificationConfig"].(map[string]interface{})
+		webhook, _ := config["url"].(string)
+		_, id, found := strings.Cut(webhook, p.path+"/")
+		if !found {
+			continue
+		}
+		if registration, ok := p.lookup(id); ok && registration.owner == owner {
+			taskConfig["pushNotificationConfig"] = withoutCredentials(registration.client)
+		} else {
+			delete(config, "token")
+		}
+	}
+	restored, err := json.Marshal(resp)
+	if err != nil {
+		return body
+	}
+	return restored
+}
+
+// withoutCredentials returns a copy of a client config without authentication credentials
+func withoutCredentials(client map[string]interface{}) map[string]interface{} {
+	config := make(map[string]interface{}, len(client))
+	for key, value := range client {
+		config[key] = value
+	}
+	if auth, ok := client["authentication"].(map[string]interface{}); ok {
+		redacted := make(map[string]interface{}, len(auth))
+		for key, value := range auth {
+			if key != "credentials" {
+				redacted[key] = value
+			}
+		}
+		config["authentication"] = redacted
+	}
+	return config
+}
+
+// newPushToken returns a random token for agents to authenticate notifications
+func newPushToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("cannot create notification token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
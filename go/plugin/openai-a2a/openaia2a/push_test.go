@@ -0,0 +1,243 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/netclass"
+	"github.com/stretchr/testify/assert"
+)
+
+// echoConfigAgent answers push notification config requests with the config it received
+type echoConfigAgent struct {
+	received map[string]interface{}
+}
+
+func (a *echoConfigAgent) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var rpcReq map[string]interface{}
+	_ = json.NewDecoder(req.Body).Decode(&rpcReq)
+	a.received = rpcReq
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": rpcReq["id"], "result": rpcReq["params"]})
+}
+
+func newPushHandler(t *testing.T, agent http.Handler) http.Handler {
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "task-agent", "url": "http://localhost:8001"},
+			},
+			"push_notifications": map[string]interface{}{"callback_url": "http://gateway:10000"},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, agent)
+	assert.NoError(t, err)
+	return handler
+}
+
+func postA2A(handler http.Handler, path, body string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestNewPushRelay_InvalidConfig(t *testing.T) {
+	for _, cfg := range []PushNotificationConfig{{Path: "push"}, {CallbackURL: "gateway"}, {TTL: "forever"}, {TTL: "0s"}, {TTL: "-1h"}, {Timeout: "soon"}, {Timeout: "0s"}, {MaxRegistrations: -1}} {
+		_, err := newPushRelay(&cfg)
+		assert.Error(t, err, cfg)
+	}
+}
+
+// allowPrivatePushHosts lets tests relay notifications to clients on the loopback interface
+func allowPrivatePushHosts(t *testing.T) {
+	pushPrivateHost = func(string) bool { return false }
+	t.Cleanup(func() { pushPrivateHost = netclass.IsPrivateHost })
+}
+
+func TestPush_RelaysNotificationsToClient(t *testing.T) {
+	allowPrivatePushHosts(t)
+	var notification *http.Request
+	var notificationBody []byte
+	client := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		notification = r
+		notificationBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer client.Close()
+	agent := &echoConfigAgent{}
+	handler := newPushHandler(t, agent)
+
+	rec := postA2A(handler, "/task-agent", `{"jsonrpc":"2.0","id":1,"method":"tasks/pushNotificationConfig/set","params":{"taskId":"task-1",
+		"pushNotificationConfig":{"url":"`+client.URL+`/hook","token":"client-token","authentication":{"schemes":["Bearer"],"credentials":"secret"}}}}`, nil)
+
+	// The agent sees a webhook of the gateway with a gateway token
+	config := agent.received["params"].(map[string]interface{})["pushNotificationConfig"].(map[string]interface{})
+	webhook := config["url"].(string)
+	token := config["token"].(string)
+	assert.True(t, strings.HasPrefix(webhook, "http://gateway:10000/a2a/push/"), webhook)
+	assert.NotEqual(t, "client-token", token)
+	assert.Nil(t, config["authentication"])
+
+	// The client sees its own config
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp struct {
+		Result struct {
+			PushNotificationConfig map[string]interface{} `json:"pushNotificationConfig"`
+		} `json:"result"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, client.URL+"/hook", resp.Result.PushNotificationConfig["url"])
+	assert.Equal(t, "client-token", resp.Result.PushNotificationConfig["token"])
+	assert.Equal(t, map[string]interface{}{"schemes": []interface{}{"Bearer"}}, resp.Result.PushNotificationConfig["authentication"], "credentials are not echoed")
+
+	// Notifications of the agent are verified and relayed with the client credentials
+	webhookPath := strings.TrimPrefix(webhook, "http://gateway:10000")
+	update := `{"kind":"status-update","taskId":"task-1","status":{"state":"completed"},"final":true}`
+	rec = postA2A(handler, webhookPath, update, http.Header{"X-A2a-Notification-Token": {token}, "Content-Type": {"application/json"}})
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	if assert.NotNil(t, notification) {
+		assert.Equal(t, "/hook", notification.URL.Path)
+		assert.Equal(t, "client-token", notification.Header.Get(pushTokenHeader))
+		assert.Equal(t, "Bearer secret", notification.Header.Get("Authorization"))
+		assert.JSONEq(t, update, string(notificationBody))
+	}
+}
+
+func TestPush_RejectsInvalidNotifications(t *testing.T) {
+	agent := &echoConfigAgent{}
+	handler := newPushHandler(t, agent)
+	postA2A(handler, "/task-agent", `{"jsonrpc":"2.0","id":1,"method":"message/send","params":{"message":{"kind":"message","messageId":"m1","role":"user","parts":[]},
+		"configuration":{"pushNotificationConfig":{"url":"http://client:9000/hook"}}}}`, nil)
+	config := agent.received["params"].(map[string]interface{})["configuration"].(map[string]interface{})["pushNotificationConfig"].(map[string]interface{})
+	webhookPath := strings.TrimPrefix(config["url"].(string), "http://gateway:10000")
+
+	rec := postA2A(handler, webhookPath, `{}`, http.Header{"X-A2a-Notification-Token": {"forged"}})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = postA2A(handler, "/a2a/push/unknown", `{}`, http.Header{"X-A2a-Notification-Token": {config["token"].(string)}})
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestPush_RegistrationsExpire(t *testing.T) {
+	relay, err := newPushRelay(&PushNotificationConfig{TTL: "1h"})
+	assert.NoError(t, err)
+	now := time.Now()
+	relay.now = func() time.Time { return now }
+
+	config := map[string]interface{}{"url": "https://client.example.com/hook"}
+	assert.NoError(t, relay.register(httptest.NewRequest(http.MethodPost, "/task-agent", nil), config, "task-agent"))
+	id := strings.TrimPrefix(config["url"].(string), "http://example.com/a2a/push/")
+
+	_, ok := relay.lookup(id)
+	assert.True(t, ok)
+	now = now.Add(2 * time.Hour)
+	_, ok = relay.lookup(id)
+	assert.False(t, ok)
+
+	assert.Error(t, relay.register(httptest.NewRequest(http.MethodPost, "/task-agent", nil), map[string]interface{}{"url": "file:///etc/passwd"}, "task-agent"))
+
+	// Expired registrations are purged and no longer count against the caller
+	relay.purge()
+	assert.Empty(t, relay.registrations)
+	assert.Empty(t, relay.owners)
+}
+
+func TestPush_RejectsPrivateCallbacks(t *testing.T) {
+	relay, err := newPushRelay(&PushNotificationConfig{})
+	assert.NoError(t, err)
+	for _, clientURL := range []string{
+		"http://localhost:8080/hook",
+		"http://127.0.0.1/hook",
+		"http://[::1]/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.5/hook",
+		"http://agent.default.svc.cluster.local/hook",
+		"http://0.0.0.0/hook",
+	} {
+		err := relay.register(httptest.NewRequest(http.MethodPost, "/task-agent", nil), map[string]interface{}{"url": clientURL}, "task-agent")
+		assert.ErrorContains(t, err, "is not public", clientURL)
+	}
+	assert.Empty(t, relay.registrations)
+}
+
+func TestPush_RefusesToDialPrivateAddresses(t *testing.T) {
+	var called bool
+	client := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true }))
+	defer client.Close()
+	relay, err := newPushRelay(&PushNotificationConfig{})
+	assert.NoError(t, err)
+
+	// Addresses are checked again when dialing, so names resolving to private addresses are refused
+	_, err = relay.client.Get(client.URL)
+	assert.ErrorContains(t, err, "private address")
+	assert.False(t, called)
+}
+
+func TestPush_LimitsRegistrationsPerCaller(t *testing.T) {
+	relay, err := newPushRelay(&PushNotificationConfig{MaxRegistrations: 2})
+	assert.NoError(t, err)
+	register := func(remoteAddr string) error {
+		req := httptest.NewRequest(http.MethodPost, "/task-agent", nil)
+		req.RemoteAddr = remoteAddr
+		return relay.register(req, map[string]interface{}{"url": "https://client.example.com/hook"}, "task-agent")
+	}
+
+	assert.NoError(t, register("203.0.113.1:1234"))
+	assert.NoError(t, register("203.0.113.1:5678"))
+	assert.ErrorContains(t, register("203.0.113.1:1234"), "too many push notification configs")
+	assert.NoError(t, register("203.0.113.2:1234"), "other callers have their own limit")
+}
+
+func TestPush_RestoresConfigsForTheirOwnerOnly(t *testing.T) {
+	agent := &echoConfigAgent{}
+	handler := newPushHandler(t, agent)
+	postA2A(handler, "/task-agent", `{"jsonrpc":"2.0","id":1,"method":"tasks/pushNotificationConfig/set","params":{"taskId":"task-1",
+		"pushNotificationConfig":{"url":"https://client.example.com/hook","token":"client-token","authentication":{"schemes":["Bearer"],"credentials":"secret"}}}}`, nil)
+	gatewayConfig := agent.received["params"].(map[string]interface{})["pushNotificationConfig"].(map[string]interface{})
+	webhook := gatewayConfig["url"].(string)
+
+	// Another caller gets a response naming the gateway webhook, which the agent may report for any task
+	get := `{"jsonrpc":"2.0","id":2,"method":"tasks/pushNotificationConfig/get","params":{"id":"task-1"}}`
+	agentResponse := &MockHandler{Response: []byte(`{"jsonrpc":"2.0","id":2,"result":{"taskId":"task-1","pushNotificationConfig":{"url":"` + webhook + `","token":"` + gatewayConfig["token"].(string) + `"}}}`)}
+	handler = newPushHandler(t, agentResponse)
+	req := httptest.NewRequest(http.MethodPost, "/task-agent", strings.NewReader(get))
+	req.RemoteAddr = "203.0.113.9:1234"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, rec.Body.String(), "client.example.com")
+	assert.NotContains(t, rec.Body.String(), "secret")
+	assert.NotContains(t, rec.Body.String(), gatewayConfig["token"].(string), "the gateway token of a foreign webhook is removed")
+}
+
+func TestPush_PublishesCompletedTasksWithModel(t *testing.T) {
+	agent := &echoConfigAgent{}
+	handler := newPushHandler(t, agent)
+	queue := captureEvents(t)
+	postA2A(handler, "/task-agent", `{"jsonrpc":"2.0","id":1,"method":"tasks/pushNotificationConfig/set","params":{"taskId":"task-1",
+		"pushNotificationConfig":{"url":"https://client.example.com/hook"}}}`, nil)
+	config := agent.received["params"].(map[string]interface{})["pushNotificationConfig"].(map[string]interface{})
+	webhookPath := strings.TrimPrefix(config["url"].(string), "http://gateway:10000")
+	header := http.Header{"X-A2a-Notification-Token": {config["token"].(string)}}
+
+	rec := postA2A(handler, webhookPath, `{"kind":"task","id":"task-1","contextId":"ctx-1","status":{"state":"completed"},"padding":"`+strings.Repeat("x", maxPushNotificationSize)+`"}`, header)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Len(t, queue, 0)
+
+	postA2A(handler, webhookPath, `{"kind":"task","id":"task-1","contextId":"ctx-1","status":{"state":"completed"}}`, header)
+	event := <-queue
+	assert.Equal(t, eventTaskCompleted, event.Type)
+	assert.Equal(t, "task-agent", event.Data["model"])
+}
@@ -0,0 +1,509 @@
+package openaia2a
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/memcache"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/resp"
+	"github.com/go-http-utils/headers"
+)
+
+// Quota periods
+const (
+	quotaDaily   = "daily"
+	quotaMonthly = "monthly"
+)
+
+const (
+	usagePath                = "/usage"
+	insufficientQuota        = "insufficient_quota"
+	defaultQuotaKeyPrefix    = "openai-a2a:quota:"
+	quotaPruneInterval       = time.Hour
+	defaultQuotaStoreTimeout = 2 * time.Second
+)
+
+// QuotaLimits bounds the requests and estimated tokens of a period. Zero means unlimited.
+type QuotaLimits struct {
+	Requests int64 `json:"requests"`
+	Tokens   int64 `json:"tokens"`
+}
+
+// QuotaPeriods holds the limits per calendar day and month in UTC
+type QuotaPeriods struct {
+	Daily   *QuotaLimits `json:"daily,omitempty"`
+	Monthly *QuotaLimits `json:"monthly,omitempty"`
+}
+
+// QuotaConfig limits the usage of each authenticated principal, so it requires api_keys or jwt.
+// Overrides replace the default limits of a principal.
+// Counters are kept in memory, or in Redis or memcached to share them between gateway instances.
+type QuotaConfig struct {
+	QuotaPeriods
+	Overrides map[string]QuotaPeriods `json:"overrides"`
+	Redis     *QuotaRedisConfig       `json:"redis"`
+	Memcached *QuotaMemcachedConfig   `json:"memcached"`
+}
+
+// QuotaRedisConfig is the Redis (or Valkey) server holding the quota counters
+type QuotaRedisConfig struct {
+	Address   string `json:"address"`
+	Password  string `json:"password"`
+	DB        int    `json:"db"`
+	KeyPrefix string `json:"key_prefix"`
+	Timeout   string `json:"timeout"`
+}
+
+// QuotaMemcachedConfig is the memcached servers holding the quota counters. Keys are
+// distributed over the servers by hash.
+type QuotaMemcachedConfig struct {
+	Servers   []string `json:"servers"`
+	KeyPrefix string   `json:"key_prefix"`
+	Timeout   string   `json:"timeout"`
+}
+
+// quotaCounters is the consumption of a principal in one period window
+type quotaCounters struct {
+	Requests int64
+	Tokens   int64
+}
+
+// quotaStore adds to the counters of a window and returns their new values.
+// Windows expire when their period ends. Drivers report their reachability to the health endpoints.
+type quotaStore interface {
+	add(ctx context.Context, key string, delta quotaCounters, expiresAt time.Time) (quotaCounters, error)
+	ping(ctx context.Context) error
+	driver() string
+}
+
+// quotaWindow is the current window of a period
+type quotaWindow struct {
+	period   string
+	id       string
+	resetsAt time.Time
+	limits   *QuotaLimits
+}
+
+// quotas enforces usage quotas per API key
+type quotas struct {
+	defaults  QuotaPeriods
+	overrides map[string]QuotaPeriods
+	store     quotaStore
+	now       func() time.Time
+}
+
+// newQuotas validates the configuration. A nil config disables quotas.
+func newQuotas(cfg *QuotaConfig) (*quotas, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if err := validateQuotaPeriods(cfg.QuotaPeriods); err != nil {
+		return nil, err
+	}
+	for principal, periods := range cfg.Overrides {
+		if err := validateQuotaPeriods(periods); err != nil {
+			return nil, fmt.Errorf("override %s: %w", principal, err)
+		}
+	}
+	if cfg.Daily == nil && cfg.Monthly == nil && len(cfg.Overrides) == 0 {
+		return nil, errors.New("at least one daily or monthly limit is required")
+	}
+
+	q := &quotas{defaults: cfg.QuotaPeriods, overrides: cfg.Overrides, now: time.Now}
+	var err error
+	switch {
+	case cfg.Redis != nil && cfg.Memcached != nil:
+		return nil, errors.New("redis and memcached are mutually exclusive")
+	case cfg.Redis != nil:
+		if q.store, err = newRedisQuotaStore(cfg.Redis); err != nil {
+			return nil, fmt.Errorf("invalid redis configuration: %w", err)
+		}
+	case cfg.Memcached != nil:
+		if q.store, err = newMemcachedQuotaStore(cfg.Memcached); err != nil {
+			return nil, fmt.Errorf("invalid memcached configuration: %w", err)
+		}
+	default:
+		q.store = newMemoryQuotaStore()
+	}
+	return q, nil
+}
+
+func validateQuotaPeriods(periods QuotaPeriods) error {
+	for _, limits := range []*QuotaLimits{periods.Daily, periods.Monthly} {
+		if limits != nil && (limits.Requests < 0 || limits.Tokens < 0) {
+			return errors.New("limits must not be negative")
+		}
+	}
+	return nil
+}
+
+// identity returns the principal that quotas are counted for. Unauthenticated requests, i.e. of
+// sandbox keys, are not counted.
+func (q *quotas) identity(req *http.Request) (string, bool) {
+	p := principal(req.Context())
+	return p, p != ""
+}
+
+// windows returns the current windows of the periods limited for a principal
+func (q *quotas) windows(id string) []quotaWindow {
+	periods := q.defaults
+	if override, ok := q.overrides[id]; ok {
+		periods = override
+	}
+	now := q.now().UTC()
+	var windows []quotaWindow
+	if periods.Daily != nil {
+		day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		windows = append(windows, quotaWindow{period: quotaDaily, id: day.Format("2006-01-02"), resetsAt: day.AddDate(0, 0, 1), limits: periods.Daily})
+	}
+	if periods.Monthly != nil {
+		month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		windows = append(windows, quotaWindow{period: quotaMonthly, id: month.Format("2006-01"), resetsAt: month.AddDate(0, 1, 0), limits: periods.Monthly})
+	}
+	return windows
+}
+
+// key identifies the counters of a principal in the window. Principals are hashed, since they
+// may contain characters that stores cannot hold in keys, e.g. whitespace for memcached.
+func (w quotaWindow) key(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:]) + ":" + w.period + ":" + w.id
+}
+
+// admit counts a request and reports whether the caller is within its quotas. Over-quota
+// requests are rejected with a 429 insufficient_quota error and do not count against any
+// window. If the store is unavailable, requests are admitted so an outage of the store
+// does not take the gateway down.
+func (q *quotas) admit(w http.ResponseWriter, req *http.Request) bool {
+	log := logging.FromRequest(logger, req)
+	if q == nil {
+		return true
+	}
+	id, ok := q.identity(req)
+	if !ok {
+		return true
+	}
+	var counted, exhausted []quotaWindow
+	for _, window := range q.windows(id) {
+		counters, err := q.store.add(req.Context(), window.key(id), quotaCounters{Requests: 1}, window.resetsAt)
+		if err != nil {
+			log.Warning("failed to count request against quota, admitting it:", err)
+			continue
+		}
+		counted = append(counted, window)
+		limits := window.limits
+		if (limits.Requests > 0 && counters.Requests > limits.Requests) || (limits.Tokens > 0 && counters.Tokens >= limits.Tokens) {
+			log.Warning(fmt.Sprintf("%s exceeded its %s quota", id, window.period))
+			q.uncount(req, id, counted)
+			retryAfter := int(window.resetsAt.Sub(q.now()).Seconds()) + 1
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			code := insufficientQuota
+			writeOpenAIError(w, http.StatusTooManyRequests, models.OpenAIError{
+				Message: fmt.Sprintf("You exceeded your %s quota. Check your usage at %s.", window.period, usagePath),
+				Type:    insufficientQuota,
+				Code:    &code,
+			})
+			return false
+		}
+		if limits.Requests > 0 && counters.Requests == limits.Requests {
+			exhausted = append(exhausted, window)
+		}
+	}
+	// Counters only grow by admitted requests, so the request taking the last one is published once
+	for _, window := range exhausted {
+		publishQuotaExceeded(id, window, "requests")
+	}
+	return true
+}
+
+// uncount takes back the request counted in the windows of a rejected request
+func (q *quotas) uncount(req *http.Request, id string, windows []quotaWindow) {
+	for _, window := range windows {
+		if _, err := q.store.add(req.Context(), window.key(id), quotaCounters{Requests: -1}, window.resetsAt); err != nil {
+			logging.FromRequest(logger, req).Warning("failed to take back rejected request from quota:", err)
+		}
+	}
+}
+
+// record adds the estimated tokens of a completion to the caller's quotas
+func (q *quotas) record(req *http.Request, tokens int) {
+	log := logging.FromRequest(logger, req)
+	if q == nil || tokens <= 0 {
+		return
+	}
+	id, ok := q.identity(req)
+	if !ok {
+		return
+	}
+	for _, window := range q.windows(id) {
+		counters, err := q.store.add(req.Context(), window.key(id), quotaCounters{Tokens: int64(tokens)}, window.resetsAt)
+		if err != nil {
+			log.Warning("failed to record token usage against quota:", err)
+			continue
+		}
+		if limit := window.limits.Tokens; limit > 0 && counters.Tokens >= limit && counters.Tokens-int64(tokens) < limit {
+			publishQuotaExceeded(id, window, "tokens")
+		}
+	}
+}
+
+// publishQuotaExceeded publishes that a principal reached a limit of its quota window
+func publishQuotaExceeded(id string, window quotaWindow, limit string) {
+	events.publish(eventQuotaExceeded, map[string]interface{}{
+		"principal": id,
+		"period":    window.period,
+		"limit":     limit,
+		"resets_at": window.resetsAt.UTC(),
+	})
+}
+
+// handles reports whether the request targets the usage endpoint
+func (q *quotas) handles(req *http.Request) bool {
+	return q != nil && req.URL.Path == usagePath
+}
+
+// usageResponse reports the consumption of the caller for each limited period
+type usageResponse struct {
+	Object    string        `json:"object"`
+	Principal string        `json:"principal"`
+	Quotas    []periodUsage `json:"quotas"`
+}
+
+type periodUsage struct {
+	Period        string `json:"period"`
+	Requests      int64  `json:"requests"`
+	Tokens        int64  `json:"tokens"`
+	LimitRequests int64  `json:"limit_requests,omitempty"`
+	LimitTokens   int64  `json:"limit_tokens,omitempty"`
+	ResetsAt      int64  `json:"resets_at"`
+}
+
+// serveUsage answers GET /usage with the caller's consumption and limits
+func (q *quotas) serveUsage(w http.ResponseWriter, req *http.Request) {
+	log := logging.FromRequest(logger, req)
+	if req.Method != http.MethodGet {
+		writeError(w, req, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	id, ok := q.identity(req)
+	if !ok {
+		writeError(w, req, http.StatusUnauthorized, "an API key is required to check usage")
+		return
+	}
+
+	response := usageResponse{Object: "usage", Principal: id, Quotas: []periodUsage{}}
+	for _, window := range q.windows(id) {
+		counters, err := q.store.add(req.Context(), window.key(id), quotaCounters{}, window.resetsAt)
+		if err != nil {
+			log.Error("failed to read quota usage:", err)
+			writeError(w, req, http.StatusServiceUnavailable, "usage is temporarily unavailable")
+			return
+		}
+		response.Quotas = append(response.Quotas, periodUsage{
+			Period:        window.period,
+			Requests:      counters.Requests,
+			Tokens:        counters.Tokens,
+			LimitRequests: window.limits.Requests,
+			LimitTokens:   window.limits.Tokens,
+			ResetsAt:      window.resetsAt.Unix(),
+		})
+	}
+
+	w.Header().Set(headers.ContentType, "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		log.Error("failed to write response:", err)
+	}
+}
+
+// memoryQuotaStore keeps counters of a single gateway instance
+type memoryQuotaStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryQuotaEntry
+	nextPrune time.Time
+	now       func() time.Time
+}
+
+type memoryQuotaEntry struct {
+	counters  quotaCounters
+	expiresAt time.Time
+}
+
+func newMemoryQuotaStore() *memoryQuotaStore {
+	return &memoryQuotaStore{entries: make(map[string]memoryQuotaEntry), now: time.Now}
+}
+
+func (s *memoryQuotaStore) add(_ context.Context, key string, delta quotaCounters, expiresAt time.Time) (quotaCounters, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.After(s.nextPrune) {
+		for k, entry := range s.entries {
+			if now.After(entry.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+		s.nextPrune = now.Add(quotaPruneInterval)
+	}
+
+	entry := s.entries[key]
+	entry.counters.Requests += delta.Requests
+	entry.counters.Tokens += delta.Tokens
+	entry.expiresAt = expiresAt
+	s.entries[key] = entry
+	return entry.counters, nil
+}
+
+func (s *memoryQuotaStore) ping(context.Context) error { return nil }
+
+func (s *memoryQuotaStore) driver() string { return "memory" }
+
+// redisQuotaStore keeps counters in a hash per window, shared by all gateway instances
+type redisQuotaStore struct {
+	client *resp.Client
+	prefix string
+}
+
+func newRedisQuotaStore(cfg *QuotaRedisConfig) (*redisQuotaStore, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("address is required")
+	}
+	client := &resp.Client{Address: cfg.Address, Password: cfg.Password, DB: cfg.DB, Timeout: defaultQuotaStoreTimeout}
+	if cfg.Timeout != "" {
+		timeout, err := time.ParseDuration(cfg.Timeout)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout '%s'", cfg.Timeout)
+		}
+		client.Timeout = timeout
+	}
+	prefix := cfg.KeyPrefix
+	if prefix == "" {
+		prefix = defaultQuotaKeyPrefix
+	}
+	return &redisQuotaStore{client: client, prefix: prefix}, nil
+}
+
+func (s *redisQuotaStore) add(ctx context.Context, key string, delta quotaCounters, expiresAt time.Time) (quotaCounters, error) {
+	key = s.prefix + key
+	replies, err := s.client.Do(ctx,
+		[]string{"HINCRBY", key, "requests", strconv.FormatInt(delta.Requests, 10)},
+		[]string{"HINCRBY", key, "tokens", strconv.FormatInt(delta.Tokens, 10)},
+		[]string{"EXPIREAT", key, strconv.FormatInt(expiresAt.Unix(), 10)},
+	)
+	if err != nil {
+		return quotaCounters{}, err
+	}
+	var counters quotaCounters
+	if counters.Requests, err = resp.Int(replies[0]); err != nil {
+		return quotaCounters{}, err
+	}
+	if counters.Tokens, err = resp.Int(replies[1]); err != nil {
+		return quotaCounters{}, err
+	}
+	return counters, nil
+}
+
+func (s *redisQuotaStore) ping(ctx context.Context) error {
+	replies, err := s.client.Do(ctx, []string{"PING"})
+	if err != nil {
+		return err
+	}
+	if replyErr, ok := replies[0].(resp.Error); ok {
+		return replyErr
+	}
+	return nil
+}
+
+func (s *redisQuotaStore) driver() string { return "redis" }
+
+// memcachedQuotaStore keeps counters in a pair of keys per window, shared by all gateway instances.
+// Counters are created with the expiry of their window.
+type memcachedQuotaStore struct {
+	client *memcache.Client
+	prefix string
+}
+
+func newMemcachedQuotaStore(cfg *QuotaMemcachedConfig) (*memcachedQuotaStore, error) {
+	if len(cfg.Servers) == 0 {
+		return nil, errors.New("at least one server is required")
+	}
+	for _, server := range cfg.Servers {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			return nil, fmt.Errorf("invalid server '%s', expected host:port", server)
+		}
+	}
+	client := &memcache.Client{Servers: cfg.Servers, Timeout: defaultQuotaStoreTimeout}
+	if cfg.Timeout != "" {
+		timeout, err := time.ParseDuration(cfg.Timeout)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout '%s'", cfg.Timeout)
+		}
+		client.Timeout = timeout
+	}
+	prefix := cfg.KeyPrefix
+	if prefix == "" {
+		prefix = defaultQuotaKeyPrefix
+	}
+	// The longest key is the request counter of a monthly window
+	longest := quotaWindow{period: quotaMonthly, id: "2006-01"}.key("") + ":requests"
+	if !memcache.ValidKey(prefix + longest) {
+		return nil, fmt.Errorf("invalid key_prefix '%s'", prefix)
+	}
+	return &memcachedQuotaStore{client: client, prefix: prefix}, nil
+}
+
+func (s *memcachedQuotaStore) add(ctx context.Context, key string, delta quotaCounters, expiresAt time.Time) (quotaCounters, error) {
+	key = s.prefix + key
+	var counters quotaCounters
+	var err error
+	if counters.Requests, err = s.incr(ctx, key+":requests", delta.Requests, expiresAt); err != nil {
+		return quotaCounters{}, err
+	}
+	if counters.Tokens, err = s.incr(ctx, key+":tokens", delta.Tokens, expiresAt); err != nil {
+		return quotaCounters{}, err
+	}
+	return counters, nil
+}
+
+// incr adds to a counter, creating it if it does not exist. Negative deltas of missing
+// counters have nothing to take back.
+func (s *memcachedQuotaStore) incr(ctx context.Context, key string, delta int64, expiresAt time.Time) (int64, error) {
+	if delta < 0 {
+		value, err := s.client.Decr(ctx, key, uint64(-delta))
+		if errors.Is(err, memcache.ErrNotFound) {
+			return 0, nil
+		}
+		return int64(value), err
+	}
+	value, err := s.client.Incr(ctx, key, uint64(delta))
+	if errors.Is(err, memcache.ErrNotFound) {
+		if err = s.client.Add(ctx, key, strconv.FormatInt(delta, 10), expiresAt); err == nil {
+			return delta, nil
+		}
+		// Another instance created the counter in the meantime
+		if errors.Is(err, memcache.ErrNotStored) {
+			value, err = s.client.Incr(ctx, key, uint64(delta))
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	return int64(value), nil
+}
+
+func (s *memcachedQuotaStore) ping(ctx context.Context) error {
+	return s.client.Ping(ctx)
+}
+
+func (s *memcachedQuotaStore) driver() string { return "memcached" }
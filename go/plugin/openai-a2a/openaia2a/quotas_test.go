@@ -0,0 +1,277 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/memcache/memcachetest"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/resp/resptest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newQuotaHandler(t *testing.T, quotas map[string]interface{}) http.Handler {
+	mockHandler := &MockHandler{
+		Response:   []byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task","status":{"state":"completed"},"artifacts":[{"artifactId":"a","parts":[{"kind":"text","text":"Sunny and warm today."}]}]}}`),
+		StatusCode: http.StatusOK,
+	}
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "agent", "url": "http://localhost:8001"},
+			},
+			"api_keys": map[string]interface{}{
+				"keys": []interface{}{
+					map[string]interface{}{"id": "weather-team", "secret": "sk-weather-team-key"},
+					map[string]interface{}{"id": "platform", "secret": "sk-platform-key"},
+				},
+			},
+			"quotas": quotas,
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+	return handler
+}
+
+func getUsage(t *testing.T, handler http.Handler, apiKey string) usageResponse {
+	req := httptest.NewRequest(http.MethodGet, usagePath, nil)
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var usage usageResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &usage))
+	return usage
+}
+
+func TestNewQuotas_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  QuotaConfig
+	}{
+		{name: "no limits", cfg: QuotaConfig{}},
+		{name: "negative limit", cfg: QuotaConfig{QuotaPeriods: QuotaPeriods{Daily: &QuotaLimits{Requests: -1}}}},
+		{name: "negative override", cfg: QuotaConfig{Overrides: map[string]QuotaPeriods{"team": {Monthly: &QuotaLimits{Tokens: -1}}}}},
+		{name: "redis without address", cfg: QuotaConfig{QuotaPeriods: QuotaPeriods{Daily: &QuotaLimits{Requests: 1}}, Redis: &QuotaRedisConfig{}}},
+		{name: "memcached without servers", cfg: QuotaConfig{QuotaPeriods: QuotaPeriods{Daily: &QuotaLimits{Requests: 1}}, Memcached: &QuotaMemcachedConfig{}}},
+		{name: "memcached server without port", cfg: QuotaConfig{QuotaPeriods: QuotaPeriods{Daily: &QuotaLimits{Requests: 1}}, Memcached: &QuotaMemcachedConfig{Servers: []string{"memcached"}}}},
+		{name: "redis and memcached", cfg: QuotaConfig{QuotaPeriods: QuotaPeriods{Daily: &QuotaLimits{Requests: 1}}, Redis: &QuotaRedisConfig{Address: "redis:6379"}, Memcached: &QuotaMemcachedConfig{Servers: []string{"memcached:11211"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newQuotas(&tt.cfg)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestQuotas_RequireAuthentication(t *testing.T) {
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{},
+			"quotas": map[string]interface{}{"daily": map[string]interface{}{"requests": 100}},
+		},
+	}
+	_, err := module.registerHandlers(context.Background(), extraConfig, &MockHandler{})
+	assert.ErrorContains(t, err, "quotas require api_keys or jwt authentication")
+}
+
+func TestQuotas_RequestLimit(t *testing.T) {
+	handler := newQuotaHandler(t, map[string]interface{}{
+		"daily":     map[string]interface{}{"requests": 2},
+		"overrides": map[string]interface{}{"platform": map[string]interface{}{"daily": map[string]interface{}{"requests": 5}}},
+	})
+
+	for range 2 {
+		assert.Equal(t, http.StatusOK, sendChatCompletion(handler, "agent", "sk-weather-team-key").Code)
+	}
+	rec := sendChatCompletion(handler, "agent", "sk-weather-team-key")
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+	var errResp models.OpenAIErrorResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errResp))
+	assert.Equal(t, insufficientQuota, errResp.Error.Type)
+	assert.Equal(t, insufficientQuota, *errResp.Error.Code)
+
+	// The override of the platform key allows more requests
+	for range 3 {
+		assert.Equal(t, http.StatusOK, sendChatCompletion(handler, "agent", "sk-platform-key").Code)
+	}
+}
+
+func TestQuotas_TokenLimit(t *testing.T) {
+	handler := newQuotaHandler(t, map[string]interface{}{
+		"monthly": map[string]interface{}{"tokens": 5},
+	})
+
+	assert.Equal(t, http.StatusOK, sendChatCompletion(handler, "agent", "sk-weather-team-key").Code)
+	assert.Equal(t, http.StatusTooManyRequests, sendChatCompletion(handler, "agent", "sk-weather-team-key").Code)
+}
+
+func TestQuotas_UsageEndpoint(t *testing.T) {
+	handler := newQuotaHandler(t, map[string]interface{}{
+		"daily":   map[string]interface{}{"requests": 100},
+		"monthly": map[string]interface{}{"tokens": 10000},
+	})
+	sendChatCompletion(handler, "agent", "sk-weather-team-key")
+
+	usage := getUsage(t, handler, "sk-weather-team-key")
+
+	assert.Equal(t, "weather-team", usage.Principal)
+	assert.Len(t, usage.Quotas, 2)
+	assert.Equal(t, quotaDaily, usage.Quotas[0].Period)
+	assert.Equal(t, int64(1), usage.Quotas[0].Requests)
+	assert.Equal(t, int64(100), usage.Quotas[0].LimitRequests)
+	assert.Equal(t, quotaMonthly, usage.Quotas[1].Period)
+	assert.Positive(t, usage.Quotas[1].Tokens)
+	assert.Equal(t, int64(10000), usage.Quotas[1].LimitTokens)
+
+	// The usage endpoint requires a valid key
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, usagePath, nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestQuotas_Windows(t *testing.T) {
+	q, err := newQuotas(&QuotaConfig{QuotaPeriods: QuotaPeriods{Daily: &QuotaLimits{Requests: 1}, Monthly: &QuotaLimits{Requests: 1}}})
+	assert.NoError(t, err)
+	q.now = func() time.Time { return time.Date(2026, time.December, 31, 23, 30, 0, 0, time.UTC) }
+
+	windows := q.windows("team")
+
+	// sha256 of "team"
+	team := "ca8b22d0db83a22db163b560b3e4e51527e533d31d067b614a0c33c4d2df8432"
+	assert.Equal(t, team+":daily:2026-12-31", windows[0].key("team"))
+	assert.Equal(t, time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC), windows[0].resetsAt)
+	assert.Equal(t, team+":monthly:2026-12", windows[1].key("team"))
+	assert.Equal(t, time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC), windows[1].resetsAt)
+}
+
+func TestQuotas_RedisStore(t *testing.T) {
+	server := resptest.NewServer()
+	defer server.Close()
+	handler := newQuotaHandler(t, map[string]interface{}{
+		"daily": map[string]interface{}{"requests": 1},
+		"redis": map[string]interface{}{"address": server.Addr, "key_prefix": "test:"},
+	})
+
+	assert.Equal(t, http.StatusOK, sendChatCompletion(handler, "agent", "sk-weather-team-key").Code)
+	assert.Equal(t, http.StatusTooManyRequests, sendChatCompletion(handler, "agent", "sk-weather-team-key").Code)
+
+	key := "test:" + quotaWindow{period: quotaDaily, id: time.Now().UTC().Format("2006-01-02")}.key("weather-team")
+	assert.Equal(t, []string{key}, server.Keys())
+	_, hasExpiry := server.Expiry(key)
+	assert.True(t, hasExpiry)
+}
+
+func TestQuotas_MemcachedStore(t *testing.T) {
+	server := memcachetest.NewServer()
+	defer server.Close()
+	handler := newQuotaHandler(t, map[string]interface{}{
+		"daily":     map[string]interface{}{"requests": 1},
+		"memcached": map[string]interface{}{"servers": []interface{}{server.Addr}, "key_prefix": "test:"},
+	})
+
+	assert.Equal(t, http.StatusOK, sendChatCompletion(handler, "agent", "sk-weather-team-key").Code)
+	assert.Equal(t, http.StatusTooManyRequests, sendChatCompletion(handler, "agent", "sk-weather-team-key").Code)
+
+	key := "test:" + quotaWindow{period: quotaDaily, id: time.Now().UTC().Format("2006-01-02")}.key("weather-team")
+	assert.Equal(t, []string{key + ":requests", key + ":tokens"}, server.Keys())
+	requests, _ := server.Value(key + ":requests")
+	assert.Equal(t, "1", requests, "the rejected request is not counted")
+	expiry, hasExpiry := server.Expiry(key + ":requests")
+	assert.True(t, hasExpiry)
+	assert.True(t, expiry.After(time.Now()))
+
+	usage := getUsage(t, handler, "sk-weather-team-key")
+	assert.Equal(t, int64(1), usage.Quotas[0].Requests)
+	assert.Positive(t, usage.Quotas[0].Tokens)
+}
+
+func TestQuotas_MemcachedStoreLimitsPrincipalsWithWhitespace(t *testing.T) {
+	server := memcachetest.NewServer()
+	defer server.Close()
+	q, err := newQuotas(&QuotaConfig{
+		QuotaPeriods: QuotaPeriods{Daily: &QuotaLimits{Requests: 1}},
+		Memcached:    &QuotaMemcachedConfig{Servers: []string{server.Addr}},
+	})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/chat/completions", nil)
+	req = req.WithContext(context.WithValue(req.Context(), principalKey{}, "Weather Team"))
+
+	assert.True(t, q.admit(httptest.NewRecorder(), req))
+	assert.False(t, q.admit(httptest.NewRecorder(), req), "the principal is limited instead of admitted because of an invalid key")
+	for _, key := range server.Keys() {
+		assert.Regexp(t, `^openai-a2a:quota:[0-9a-f]{64}:daily:\d{4}-\d{2}-\d{2}:(requests|tokens)$`, key)
+	}
+}
+
+func TestQuotas_RejectedRequestsDoNotCount(t *testing.T) {
+	q, err := newQuotas(&QuotaConfig{QuotaPeriods: QuotaPeriods{Daily: &QuotaLimits{Requests: 5}, Monthly: &QuotaLimits{Requests: 2}}})
+	require.NoError(t, err)
+	now := time.Date(2026, time.March, 31, 12, 0, 0, 0, time.UTC)
+	q.now = func() time.Time { return now }
+	req := httptest.NewRequest(http.MethodPost, "/chat/completions", nil)
+	req = req.WithContext(context.WithValue(req.Context(), principalKey{}, "weather-team"))
+	counted := func(window quotaWindow) int64 {
+		counters, err := q.store.add(context.Background(), window.key("weather-team"), quotaCounters{}, window.resetsAt)
+		require.NoError(t, err)
+		return counters.Requests
+	}
+
+	for _, want := range []bool{true, true, false, false, false} {
+		assert.Equal(t, want, q.admit(httptest.NewRecorder(), req))
+	}
+	windows := q.windows("weather-team")
+	assert.Equal(t, int64(2), counted(windows[0]), "requests rejected by the monthly quota do not use up the daily one")
+	assert.Equal(t, int64(2), counted(windows[1]))
+
+	// A new month admits requests again, as the daily quota was not used up by rejected requests
+	now = now.Add(24 * time.Hour)
+	for _, want := range []bool{true, true, false} {
+		assert.Equal(t, want, q.admit(httptest.NewRecorder(), req))
+	}
+}
+
+func TestQuotaStores_Ping(t *testing.T) {
+	redisServer := resptest.NewServer()
+	defer redisServer.Close()
+	memcachedServer := memcachetest.NewServer()
+	defer memcachedServer.Close()
+
+	redisStore, err := newRedisQuotaStore(&QuotaRedisConfig{Address: redisServer.Addr})
+	require.NoError(t, err)
+	memcachedStore, err := newMemcachedQuotaStore(&QuotaMemcachedConfig{Servers: []string{memcachedServer.Addr}})
+	require.NoError(t, err)
+
+	for _, store := range []quotaStore{newMemoryQuotaStore(), redisStore, memcachedStore} {
+		assert.NoError(t, store.ping(context.Background()), store.driver())
+	}
+
+	redisServer.Close()
+	memcachedServer.Close()
+	assert.Error(t, redisStore.ping(context.Background()))
+	assert.Error(t, memcachedStore.ping(context.Background()))
+}
+
+func TestQuotas_StoreUnavailableAdmits(t *testing.T) {
+	server := resptest.NewServer()
+	server.Close()
+	handler := newQuotaHandler(t, map[string]interface{}{
+		"daily": map[string]interface{}{"requests": 1},
+		"redis": map[string]interface{}{"address": server.Addr, "timeout": "100ms"},
+	})
+
+	for range 2 {
+		assert.Equal(t, http.StatusOK, sendChatCompletion(handler, "agent", "sk-weather-team-key").Code)
+	}
+}
@@ -0,0 +1,344 @@
+package openaia2a
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+)
+
+const (
+	defaultReadinessInterval    = 15 * time.Second
+	defaultReadinessTimeout     = 2 * time.Second
+	defaultReadinessSlowLatency = time.Second
+	defaultEvictBelow           = 0.5
+	defaultAdmitAt              = 0.8
+)
+
+// ReadinessConfig scores agents by probing their agent cards and evicts agents that are not ready
+// until they recover. Scores range from 0 to 1.
+type ReadinessConfig struct {
+	Interval string `json:"interval,omitempty"`
+	Timeout  string `json:"timeout,omitempty"`
+	// SlowLatency is the card fetch latency above which the score decreases
+	SlowLatency string `json:"slow_latency,omitempty"`
+	// EvictBelow and AdmitAt are the scores below which an agent is evicted and at which it is
+	// admitted again. The gap keeps agents around the threshold from flapping.
+	EvictBelow float64 `json:"evict_below,omitempty"`
+	AdmitAt    float64 `json:"admit_at,omitempty"`
+}
+
+// AgentReadiness is the readiness of an agent as reported by /health/agents
+type AgentReadiness struct {
+	Score   float64 `json:"score"`
+	Evicted bool    `json:"evicted"`
+	Reason  string  `json:"reason,omitempty"`
+}
+
+// readiness probes the agent cards of all agents periodically. Each URL of an agent is scored
+// as 0.5^streak, streak being the consecutive failed probes, times SlowLatency divided by the
+// latency of the last successful probe if it was slower. A probe fails if the card cannot be
+// fetched or is not a valid A2A agent card. An agent scores as its best URL, so load-balanced
+// agents stay ready while a replica is.
+type readiness struct {
+	agents      func() []AgentInfo
+	interval    time.Duration
+	timeout     time.Duration
+	slowLatency time.Duration
+	evictBelow  float64
+	admitAt     float64
+	now         func() time.Time
+
+	mu        sync.Mutex
+	states    map[string]*agentReadiness
+	nextProbe time.Time
+}
+
+// agentReadiness is the probe state of an agent and its URLs
+type agentReadiness struct {
+	targets map[string]*targetReadiness
+	AgentReadiness
+}
+
+type targetReadiness struct {
+	streak  int
+	latency time.Duration
+	err     error
+}
+
+// probeResult is the outcome of probing one URL
+type probeResult struct {
+	model, target string
+	latency       time.Duration
+	err           error
+}
+
+// newReadiness validates the configuration. A nil config disables readiness scoring.
+func newReadiness(cfg *ReadinessConfig, agents func() []AgentInfo) (*readiness, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	r := &readiness{
+		agents:      agents,
+		interval:    defaultReadinessInterval,
+		timeout:     defaultReadinessTimeout,
+		slowLatency: defaultReadinessSlowLatency,
+		evictBelow:  defaultEvictBelow,
+		admitAt:     defaultAdmitAt,
+		now:         time.Now,
+		states:      make(map[string]*agentReadiness),
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+		field *time.Duration
+	}{
+		{"interval", cfg.Interval, &r.interval},
+		{"timeout", cfg.Timeout, &r.timeout},
+		{"slow_latency", cfg.SlowLatency, &r.slowLatency},
+	} {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid %s '%s'", d.name, d.value)
+		}
+		*d.field = parsed
+	}
+	if cfg.EvictBelow != 0 {
+		r.evictBelow = cfg.EvictBelow
+	}
+	if cfg.AdmitAt != 0 {
+		r.admitAt = cfg.AdmitAt
+	}
+	if r.evictBelow <= 0 || r.evictBelow > 1 || r.admitAt <= 0 || r.admitAt > 1 {
+		return nil, errors.New("evict_below and admit_at must be between 0 and 1")
+	}
+	if r.admitAt < r.evictBelow {
+		return nil, errors.New("admit_at must not be below evict_below")
+	}
+	return r, nil
+}
+
+// start probes all agents right away and then every interval until ctx is done
+func (r *readiness) start(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			r.probe(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// probe fetches the agent cards of all URLs concurrently and updates the scores. Agents only
+// reachable over gRPC have no card endpoint and are always ready.
+func (r *readiness) probe(ctx context.Context) {
+	agents := r.agents()
+	var (
+		mu      sync.Mutex
+		results []probeResult
+		wg      sync.WaitGroup
+	)
+	for _, agent := range agents {
+		if isGRPC(agent) {
+			continue
+		}
+		for _, target := range agentTargets(agent) {
+			wg.Add(1)
+			go func(model, target string) {
+				defer wg.Done()
+				result := r.probeTarget(ctx, model, target)
+				mu.Lock()
+				results = append(results, result)
+				mu.Unlock()
+			}(agent.ModelID, target)
+		}
+	}
+	wg.Wait()
+	r.update(agents, results)
+}
+
+func (r *readiness) probeTarget(ctx context.Context, model, target string) probeResult {
+	result := probeResult{model: model, target: target}
+	start := time.Now()
+	card, err := fetchAgentCard(ctx, target, r.timeout)
+	result.latency = time.Since(start)
+	if err == nil {
+		if err = card.Validate(); err != nil {
+			err = fmt.Errorf("invalid agent card: %w", err)
+		}
+	}
+	result.err = err
+	return result
+}
+
+// update applies probe results, drops the state of removed agents and URLs, and evicts or
+// admits agents whose score crossed a threshold
+func (r *readiness) update(agents []AgentInfo, results []probeResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextProbe = r.now().Add(r.interval)
+
+	current := make(map[string]map[string]bool, len(agents))
+	for _, agent := range agents {
+		if isGRPC(agent) {
+			continue
+		}
+		targets := make(map[string]bool)
+		for _, target := range agentTargets(agent) {
+			targets[target] = true
+		}
+		current[agent.ModelID] = targets
+	}
+	for model, state := range r.states {
+		if current[model] == nil {
+			delete(r.states, model)
+			continue
+		}
+		for target := range state.targets {
+			if !current[model][target] {
+				delete(state.targets, target)
+			}
+		}
+	}
+
+	for _, result := range results {
+		state, ok := r.states[result.model]
+		if !ok {
+			state = &agentReadiness{targets: make(map[string]*targetReadiness), AgentReadiness: AgentReadiness{Score: 1}}
+			r.states[result.model] = state
+		}
+		target, ok := state.targets[result.target]
+		if !ok {
+			target = &targetReadiness{}
+			state.targets[result.target] = target
+		}
+		target.err = result.err
+		if result.err != nil {
+			target.streak++
+		} else {
+			target.streak, target.latency = 0, result.latency
+		}
+	}
+
+	for model, state := range r.states {
+		state.Score, state.Reason = 0, ""
+		for url, target := range state.targets {
+			score := r.score(target)
+			if score >= state.Score {
+				state.Score = score
+				state.Reason = ""
+				if target.err != nil {
+					state.Reason = fmt.Sprintf("%s: %v", url, target.err)
+				}
+			}
+		}
+		state.Score = math.Round(state.Score*1000) / 1000
+
+		switch {
+		case !state.Evicted && state.Score < r.evictBelow:
+			state.Evicted = true
+			logger.Warning(fmt.Sprintf("evicting model %s: readiness score %.2f is below %.2f", model, state.Score, r.evictBelow))
+			events.publish(eventAgentEvicted, map[string]interface{}{"model": model, "score": state.Score})
+		case state.Evicted && state.Score >= r.admitAt:
+			state.Evicted = false
+			logger.Info(fmt.Sprintf("admitting model %s again: readiness score %.2f", model, state.Score))
+			events.publish(eventAgentAdmitted, map[string]interface{}{"model": model, "score": state.Score})
+		}
+	}
+}
+
+func (r *readiness) score(target *targetReadiness) float64 {
+	score := math.Pow(0.5, float64(target.streak))
+	if target.latency > r.slowLatency {
+		score *= float64(r.slowLatency) / float64(target.latency)
+	}
+	return score
+}
+
+// ready reports whether a model may receive requests
+func (r *readiness) ready(model string) bool {
+	if r == nil {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state, ok := r.states[model]
+	return !ok || !state.Evicted
+}
+
+// report returns the readiness of a model, or nil if it was not probed
+func (r *readiness) report(model string) *AgentReadiness {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	state, ok := r.states[model]
+	if !ok {
+		return nil
+	}
+	report := state.AgentReadiness
+	return &report
+}
+
+// filter returns the agents that are not evicted, for /models
+func (r *readiness) filter(agents []AgentInfo) []AgentInfo {
+	if r == nil {
+		return agents
+	}
+	ready := make([]AgentInfo, 0, len(agents))
+	for _, agent := range agents {
+		if r.ready(agent.ModelID) {
+			ready = append(ready, agent)
+		}
+	}
+	return ready
+}
+
+// retryAfter returns the seconds until the next probe may admit an agent again
+func (r *readiness) retryAfter() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return max(1, int(math.Ceil(r.nextProbe.Sub(r.now()).Seconds())))
+}
+
+// rejectChat answers a chat completion for an evicted model with 503 and reports whether it did
+func (r *readiness) rejectChat(w http.ResponseWriter, req *http.Request, model string) bool {
+	if r.ready(model) {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(r.retryAfter()))
+	writeParamError(w, req, http.StatusServiceUnavailable, fmt.Sprintf("model %s is temporarily unavailable as its agent is not ready, retry later", model), "model")
+	return true
+}
+
+// rejectA2A answers a native A2A request to an evicted agent with 503 and reports whether it did
+func (r *readiness) rejectA2A(w http.ResponseWriter, model string) bool {
+	if r.ready(model) {
+		return false
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(r.retryAfter()))
+	writeA2AError(w, http.StatusServiceUnavailable, nil, &models.JSONRPCErrorResponseError{
+		Code:    a2aerrors.CodeInternal,
+		Message: fmt.Sprintf("agent %s is temporarily unavailable as it is not ready, retry later", model),
+	})
+	return true
+}
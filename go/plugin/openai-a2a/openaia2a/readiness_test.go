@@ -0,0 +1,182 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const readyCard = `{"name": "Weather", "description": "Forecasts", "url": "http://weather:8000", "version": "1.0.0", "protocolVersion": "0.3.0",
+	"defaultInputModes": ["text/plain"], "defaultOutputModes": ["text/plain"], "skills": [{"id": "forecast", "name": "Forecast", "description": "Daily forecast", "tags": []}]}`
+
+// newReadinessTarget serves a valid agent card, a card missing required fields or 500 depending
+// on the mode
+func newReadinessTarget(t *testing.T, mode *atomic.Value, delay time.Duration) *httptest.Server {
+	mode.Store("ready")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		switch mode.Load() {
+		case "invalid":
+			_, _ = w.Write([]byte(`{"name": "Weather"}`))
+		case "down":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			_, _ = w.Write([]byte(readyCard))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestNewReadiness_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  ReadinessConfig
+		want string
+	}{
+		{name: "interval", cfg: ReadinessConfig{Interval: "often"}, want: "invalid interval 'often'"},
+		{name: "slow latency", cfg: ReadinessConfig{SlowLatency: "0s"}, want: "invalid slow_latency '0s'"},
+		{name: "evict below", cfg: ReadinessConfig{EvictBelow: 1.5}, want: "between 0 and 1"},
+		{name: "admit at", cfg: ReadinessConfig{EvictBelow: 0.6, AdmitAt: 0.4}, want: "admit_at must not be below evict_below"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newReadiness(&tt.cfg, nil)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestReadiness_EvictsAndAdmitsAgents(t *testing.T) {
+	queue := captureEvents(t)
+	var mode, replicaMode atomic.Value
+	weather := newReadinessTarget(t, &mode, 0)
+	replica := newReadinessTarget(t, &replicaMode, 0)
+	agents := []AgentInfo{
+		{ModelID: "weather-agent", URL: weather.URL},
+		{ModelID: "news-agent", LoadBalancing: &LoadBalancingConfig{Replicas: []Replica{{URL: replica.URL}, {URL: "http://127.0.0.1:1"}}}},
+		{ModelID: "grpc-agent", URL: "grpc://grpc-agent:50051", Transport: "GRPC"},
+	}
+	r, err := newReadiness(&ReadinessConfig{}, staticAgents(agents))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	r.probe(ctx)
+	assert.Equal(t, &AgentReadiness{Score: 1}, r.report("weather-agent"))
+	assert.Equal(t, &AgentReadiness{Score: 1}, r.report("news-agent"), "a load-balanced agent scores as its best replica")
+	assert.Nil(t, r.report("grpc-agent"), "gRPC agents are not probed")
+
+	// One failed probe halves the score, which is not yet below evict_below
+	mode.Store("down")
+	r.probe(ctx)
+	assert.Equal(t, 0.5, r.report("weather-agent").Score)
+	assert.True(t, r.ready("weather-agent"))
+
+	// An invalid card fails the probe as well
+	mode.Store("invalid")
+	r.probe(ctx)
+	report := r.report("weather-agent")
+	assert.Equal(t, 0.25, report.Score)
+	assert.True(t, report.Evicted)
+	assert.Contains(t, report.Reason, "invalid agent card")
+	assert.False(t, r.ready("weather-agent"))
+	assert.True(t, r.ready("news-agent"))
+	assert.Equal(t, []AgentInfo{agents[1], agents[2]}, r.filter(agents))
+	event := <-queue
+	assert.Equal(t, eventAgentEvicted, event.Type)
+	assert.Equal(t, map[string]interface{}{"model": "weather-agent", "score": 0.25}, event.Data)
+
+	mode.Store("ready")
+	r.probe(ctx)
+	assert.Equal(t, &AgentReadiness{Score: 1}, r.report("weather-agent"))
+	event = <-queue
+	assert.Equal(t, eventAgentAdmitted, event.Type)
+	assert.Len(t, queue, 0)
+
+	// Removed agents drop their state
+	r.agents = staticAgents(agents[1:])
+	r.probe(ctx)
+	assert.Nil(t, r.report("weather-agent"))
+}
+
+func TestReadiness_SlowAgentScoresLower(t *testing.T) {
+	captureEvents(t)
+	var mode atomic.Value
+	slow := newReadinessTarget(t, &mode, 50*time.Millisecond)
+	r, err := newReadiness(&ReadinessConfig{SlowLatency: "10ms"}, staticAgents([]AgentInfo{{ModelID: "slow-agent", URL: slow.URL}}))
+	require.NoError(t, err)
+
+	r.probe(context.Background())
+	report := r.report("slow-agent")
+	assert.LessOrEqual(t, report.Score, 0.2)
+	assert.True(t, report.Evicted)
+	assert.Empty(t, report.Reason, "a slow agent has no failed probe")
+}
+
+func TestReadiness_RejectsRequestsToEvictedAgents(t *testing.T) {
+	captureEvents(t)
+	var mode atomic.Value
+	down := newReadinessTarget(t, &mode, 0)
+	mode.Store("down")
+	var readyMode atomic.Value
+	up := newReadinessTarget(t, &readyMode, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	mockHandler := &MockHandler{}
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "down-agent", "url": down.URL},
+				map[string]interface{}{"model_id": "up-agent", "url": up.URL},
+			},
+			"readiness": map[string]interface{}{"interval": "20ms"},
+		},
+	}
+	handler, err := module.registerHandlers(ctx, extraConfig, mockHandler)
+	require.NoError(t, err)
+
+	listModels := func() []string {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/models", nil))
+		var list struct {
+			Data []struct {
+				ID string `json:"id"`
+			} `json:"data"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &list)
+		var ids []string
+		for _, model := range list.Data {
+			ids = append(ids, model.ID)
+		}
+		return ids
+	}
+	assert.Eventually(t, func() bool {
+		ids := listModels()
+		return len(ids) == 1 && ids[0] == "up-agent"
+	}, 2*time.Second, 10*time.Millisecond, "the evicted agent is hidden from /models")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat/completions", strings.NewReader(`{"model":"down-agent","messages":[{"role":"user","content":"Hello"}]}`)))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+	assert.Contains(t, rec.Body.String(), "temporarily unavailable")
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/down-agent", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"message/send","params":{"message":{"role":"user","messageId":"m1","parts":[{"kind":"text","text":"Hello"}]}}}`)))
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"jsonrpc":"2.0"`)
+	assert.Nil(t, mockHandler.ReceivedRequest)
+
+	// The agent is admitted again once it recovers
+	mode.Store("ready")
+	assert.Eventually(t, func() bool { return len(listModels()) == 2 }, 2*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,183 @@
+package openaia2a
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+)
+
+// Built-in detectors for common personal data
+const (
+	detectorEmail      = "email"
+	detectorPhone      = "phone"
+	detectorCreditCard = "credit_card"
+)
+
+// builtinDetectors run in this order, so that card numbers are not mistaken for phone numbers
+var builtinDetectors = []detector{
+	{name: detectorCreditCard, regex: regexp.MustCompile(`\b(?:\d[ \-]?){12,18}\d\b`), valid: luhnValid},
+	{name: detectorEmail, regex: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)},
+	// International or local numbers, optionally grouped by spaces, dots or dashes
+	{name: detectorPhone, regex: regexp.MustCompile(`(?:\+\d{1,3}[\s.\-]?)?(?:\(\d{1,4}\)[\s.\-]?)?\d{2,4}(?:[\s.\-]?\d{2,4}){2,4}\b`), valid: phoneValid},
+}
+
+// RedactionPattern is a custom detector
+type RedactionPattern struct {
+	Name  string `json:"name"`
+	Regex string `json:"regex"`
+}
+
+// RedactionConfig masks personal data in messages sent to agents and/or in agent responses.
+// Matches are replaced by "[REDACTED:<detector>]". Built-in detectors run before custom patterns.
+type RedactionConfig struct {
+	Detectors []string           `json:"detectors"`
+	Patterns  []RedactionPattern `json:"patterns"`
+	Requests  bool               `json:"requests"`
+	Responses bool               `json:"responses"`
+}
+
+// detector is a compiled redaction rule. If valid is set, only matches passing it are masked.
+type detector struct {
+	name  string
+	regex *regexp.Regexp
+	valid func(match string) bool
+}
+
+// redactor applies the enabled built-in detectors, then the custom patterns
+type redactor struct {
+	detectors []detector
+	requests  bool
+	responses bool
+}
+
+// newRedactor validates the configuration. A nil config disables redaction.
+func newRedactor(cfg *RedactionConfig) (*redactor, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if !cfg.Requests && !cfg.Responses {
+		return nil, errors.New("at least one of requests or responses must be enabled")
+	}
+	r := &redactor{requests: cfg.Requests, responses: cfg.Responses}
+	enabled := make(map[string]bool, len(cfg.Detectors))
+	for _, name := range cfg.Detectors {
+		if !slices.Contains(detectorNames(), name) {
+			return nil, fmt.Errorf("unknown detector '%s', expected one of %s", name, strings.Join(detectorNames(), ", "))
+		}
+		enabled[name] = true
+	}
+	for _, d := range builtinDetectors {
+		if enabled[d.name] {
+			r.detectors = append(r.detectors, d)
+		}
+	}
+	for _, pattern := range cfg.Patterns {
+		if pattern.Name == "" {
+			return nil, errors.New("pattern name is required")
+		}
+		regex, err := regexp.Compile(pattern.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s': %w", pattern.Name, err)
+		}
+		r.detectors = append(r.detectors, detector{name: pattern.Name, regex: regex})
+	}
+	if len(r.detectors) == 0 {
+		return nil, errors.New("at least one detector or pattern is required")
+	}
+	return r, nil
+}
+
+func detectorNames() []string {
+	names := make([]string, 0, len(builtinDetectors))
+	for _, d := range builtinDetectors {
+		names = append(names, d.name)
+	}
+	return names
+}
+
+// redact masks all matches in text
+func (r *redactor) redact(text string) string {
+	for _, d := range r.detectors {
+		mask := "[REDACTED:" + d.name + "]"
+		text = d.regex.ReplaceAllStringFunc(text, func(match string) string {
+			if d.valid != nil && !d.valid(match) {
+				return match
+			}
+			return mask
+		})
+	}
+	return text
+}
+
+// redactRequest masks the messages of a request before it is forwarded to an agent
+func (r *redactor) redactRequest(openAIReq *models.OpenAIRequest) {
+	if r == nil || !r.requests {
+		return
+	}
+	for i := range openAIReq.Messages {
+		openAIReq.Messages[i].Content = r.redact(openAIReq.Messages[i].Content)
+	}
+}
+
+// redactResponse masks the choices of a completion before it is returned to the client
+func (r *redactor) redactResponse(openAIResp *models.OpenAIResponse) {
+	if r == nil || !r.responses {
+		return
+	}
+	for i := range openAIResp.Choices {
+		openAIResp.Choices[i].Message.Content = r.redact(openAIResp.Choices[i].Message.Content)
+	}
+}
+
+// redactChunk masks the content of a streamed chunk before it is sent to the client. PII split
+// across chunks is not detected.
+func (r *redactor) redactChunk(chunk *models.OpenAIChatCompletionChunk) {
+	if r == nil || !r.responses {
+		return
+	}
+	for i := range chunk.Choices {
+		chunk.Choices[i].Delta.Content = r.redact(chunk.Choices[i].Delta.Content)
+	}
+}
+
+// phoneValid requires the 7 to 15 digits of a local or E.164 number, so that short IDs are kept
+func phoneValid(number string) bool {
+	digits := countDigits(number)
+	return digits >= 7 && digits <= 15
+}
+
+func countDigits(s string) int {
+	n := 0
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			n++
+		}
+	}
+	return n
+}
+
+// luhnValid reports whether the digits of a number pass the Luhn checksum,
+// which rules out most numbers that only look like credit card numbers
+func luhnValid(number string) bool {
+	sum, digits := 0, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if digits%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
@@ -0,0 +1,102 @@
+package openaia2a
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRedactor_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  RedactionConfig
+	}{
+		{name: "nothing to redact", cfg: RedactionConfig{Detectors: []string{detectorEmail}}},
+		{name: "no detector", cfg: RedactionConfig{Requests: true}},
+		{name: "unknown detector", cfg: RedactionConfig{Requests: true, Detectors: []string{"passport"}}},
+		{name: "unnamed pattern", cfg: RedactionConfig{Requests: true, Patterns: []RedactionPattern{{Regex: "x"}}}},
+		{name: "invalid pattern", cfg: RedactionConfig{Requests: true, Patterns: []RedactionPattern{{Name: "x", Regex: "("}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newRedactor(&tt.cfg)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestRedactor_Redact(t *testing.T) {
+	r, err := newRedactor(&RedactionConfig{
+		Requests:  true,
+		Detectors: []string{detectorPhone, detectorEmail, detectorCreditCard},
+		Patterns:  []RedactionPattern{{Name: "employee_id", Regex: `EMP-\d{6}`}},
+	})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "email", text: "mail jane.doe@example.com today", want: "mail [REDACTED:email] today"},
+		{name: "phone", text: "call +49 151 2345 6789", want: "call [REDACTED:phone]"},
+		{name: "credit card", text: "card 4111 1111 1111 1111 please", want: "card [REDACTED:credit_card] please"},
+		{name: "custom pattern", text: "I am EMP-123456", want: "I am [REDACTED:employee_id]"},
+		{name: "no personal data", text: "weather in Berlin", want: "weather in Berlin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, r.redact(tt.text))
+		})
+	}
+}
+
+func TestLuhnValid(t *testing.T) {
+	assert.True(t, luhnValid("4111-1111-1111-1111"))
+	assert.False(t, luhnValid("4111 1111 1111 1112"))
+	assert.False(t, luhnValid("1234"))
+}
+
+func TestChatCompletions_RedactsRequest(t *testing.T) {
+	mockHandler := &MockHandler{StatusCode: http.StatusServiceUnavailable}
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "agent", "url": "http://localhost:8001"},
+			},
+			"redaction": map[string]interface{}{
+				"requests":  true,
+				"detectors": []interface{}{"email"},
+			},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+
+	reqBody, _ := json.Marshal(models.OpenAIRequest{
+		Model:    "agent",
+		Messages: []models.OpenAIMessage{{Role: "user", Content: "I am jane.doe@example.com"}},
+	})
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/chat/completions", bytes.NewReader(reqBody)))
+
+	assert.Contains(t, string(mockHandler.ReceivedBody), "I am [REDACTED:email]")
+	assert.NotContains(t, string(mockHandler.ReceivedBody), "jane.doe")
+}
+
+func TestRedactor_RedactResponse(t *testing.T) {
+	r, err := newRedactor(&RedactionConfig{Responses: true, Detectors: []string{detectorEmail}})
+	assert.NoError(t, err)
+	openAIResp := newOpenAIResponse("contact ops@example.com", models.OpenAIRequest{Model: "agent"})
+
+	r.redactResponse(&openAIResp)
+
+	assert.Equal(t, "contact [REDACTED:email]", openAIResp.Choices[0].Message.Content)
+}
@@ -0,0 +1,194 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/consul"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/etcd"
+)
+
+// Registry drivers
+const (
+	registryConsul = "consul"
+	registryEtcd   = "etcd"
+)
+
+const (
+	defaultRegistryService  = "a2a-agent"
+	defaultRegistryPrefix   = "/agents/"
+	defaultRegistryWaitTime = 5 * time.Minute
+	// registryTimeout bounds registry requests that do not block
+	registryTimeout = 10 * time.Second
+	// registryMinInterval rate limits watches of registries whose registrations change constantly
+	registryMinInterval = time.Second
+)
+
+// RegistrySource discovers agents from the registrations of a service registry, which are
+// watched for changes
+type RegistrySource struct {
+	// Driver is consul or etcd
+	Driver string `json:"driver"`
+	// Address is the base URL of the Consul agent or etcd member, e.g. http://consul:8500
+	Address string `json:"address"`
+	// Service is the Consul service the agents register as (default a2a-agent)
+	Service string `json:"service,omitempty"`
+	// Token is the Consul ACL token
+	Token string `json:"token,omitempty"`
+	// Prefix is the etcd key prefix of the registrations (default /agents/)
+	Prefix string `json:"prefix,omitempty"`
+	// WaitTime bounds a watch, after which the registrations are read again (default 5m)
+	WaitTime string `json:"wait_time,omitempty"`
+}
+
+func newRegistryDiscovery(cfg *RegistrySource, client *http.Client, retry time.Duration) (agentDiscovery, error) {
+	if cfg.Address == "" {
+		return nil, errors.New("registry requires an address")
+	}
+	wait := defaultRegistryWaitTime
+	if cfg.WaitTime != "" {
+		var err error
+		if wait, err = time.ParseDuration(cfg.WaitTime); err != nil || wait < time.Second {
+			return nil, fmt.Errorf("invalid wait_time '%s', expected at least 1s", cfg.WaitTime)
+		}
+	}
+
+	switch cfg.Driver {
+	case registryConsul:
+		service := cfg.Service
+		if service == "" {
+			service = defaultRegistryService
+		}
+		return &consulDiscovery{
+			client:  &consul.Client{Address: cfg.Address, Token: cfg.Token, HTTP: client},
+			service: service,
+			wait:    wait,
+			retry:   retry,
+			delay:   retry,
+		}, nil
+	case registryEtcd:
+		prefix := cfg.Prefix
+		if prefix == "" {
+			prefix = defaultRegistryPrefix
+		}
+		return &etcdDiscovery{
+			client: &etcd.Client{Address: cfg.Address, HTTP: client},
+			prefix: prefix,
+			wait:   wait,
+			retry:  retry,
+			delay:  retry,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown registry driver '%s', use consul or etcd", cfg.Driver)
+	}
+}
+
+// consulDiscovery discovers the healthy instances of a Consul service with blocking queries
+type consulDiscovery struct {
+	client  *consul.Client
+	service string
+	wait    time.Duration
+	retry   time.Duration
+	index   uint64
+	delay   time.Duration
+}
+
+func (d *consulDiscovery) next() time.Duration {
+	return d.delay
+}
+
+// discover blocks until the instances changed since the previous call, except for the first
+// call. The service metadata carries the model ID and the agent settings like the TXT records
+// of DNS discovery; instances without model_id are skipped.
+func (d *consulDiscovery) discover(ctx context.Context) ([]AgentInfo, error) {
+	d.delay = d.retry
+	// Consul adds up to wait/16 to the wait time
+	ctx, cancel := context.WithTimeout(ctx, d.wait+d.wait/16+registryTimeout)
+	defer cancel()
+	instances, index, err := d.client.HealthyInstances(ctx, d.service, d.index, d.wait)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoints []discoveredEndpoint
+	for _, instance := range instances {
+		agent, err := agentFromMeta(instance.Meta, "", "http", instance.Address, instance.Port)
+		if err == nil && agent.ModelID == "" {
+			err = errors.New("missing model_id")
+		}
+		if err != nil {
+			logger.Warning(fmt.Sprintf("skipping consul instance %s: %v", instance.ID, err))
+			continue
+		}
+		endpoints = append(endpoints, discoveredEndpoint{agent: agent, weight: instance.Weight})
+	}
+	agents, err := mergeEndpoints(endpoints)
+	if err != nil {
+		return nil, err
+	}
+	d.index, d.delay = index, registryMinInterval
+	return agents, nil
+}
+
+// etcdDiscovery discovers the agents registered under a key prefix of etcd
+type etcdDiscovery struct {
+	client   *etcd.Client
+	prefix   string
+	wait     time.Duration
+	retry    time.Duration
+	revision int64
+	delay    time.Duration
+}
+
+func (d *etcdDiscovery) next() time.Duration {
+	return d.delay
+}
+
+// etcdRegistration is the value of a registration key: an agent like in the agents list, and
+// its weight if several keys register the same model
+type etcdRegistration struct {
+	AgentInfo
+	Weight int `json:"weight,omitempty"`
+}
+
+// discover blocks until a key under the prefix changed since the previous call, except for the
+// first call, and reads all keys again. Keys that are not a valid registration are skipped.
+func (d *etcdDiscovery) discover(ctx context.Context) ([]AgentInfo, error) {
+	d.delay = d.retry
+	if d.revision > 0 {
+		watchCtx, cancel := context.WithTimeout(ctx, d.wait)
+		err := d.client.Watch(watchCtx, d.prefix, d.revision+1)
+		cancel()
+		// The keys are read again once the wait time elapsed
+		if err != nil && watchCtx.Err() == nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, registryTimeout)
+	defer cancel()
+	kvs, revision, err := d.client.Range(ctx, d.prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var endpoints []discoveredEndpoint
+	for _, kv := range kvs {
+		var registration etcdRegistration
+		if err := json.Unmarshal([]byte(kv.Value), &registration); err != nil || registration.ModelID == "" {
+			logger.Warning(fmt.Sprintf("skipping etcd key %s: not an agent registration with model_id", kv.Key))
+			continue
+		}
+		endpoints = append(endpoints, discoveredEndpoint{agent: registration.AgentInfo, weight: registration.Weight})
+	}
+	agents, err := mergeEndpoints(endpoints)
+	if err != nil {
+		return nil, err
+	}
+	d.revision, d.delay = revision, registryMinInterval
+	return agents, nil
+}
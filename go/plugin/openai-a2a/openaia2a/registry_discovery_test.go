@@ -0,0 +1,182 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRegistry serves the registrations as Consul health API and etcd JSON gateway. Blocking
+// queries and watches return once the index passed by the client is outdated.
+type fakeRegistry struct {
+	mu            sync.Mutex
+	index         int
+	registrations map[string]string
+	queries       []string
+}
+
+func newFakeRegistry(t *testing.T) (*fakeRegistry, *httptest.Server) {
+	registry := &fakeRegistry{index: 1, registrations: make(map[string]string)}
+	server := httptest.NewServer(http.HandlerFunc(registry.serveHTTP))
+	t.Cleanup(server.Close)
+	return registry, server
+}
+
+func (f *fakeRegistry) set(key, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.index++
+	if value == "" {
+		delete(f.registrations, key)
+		return
+	}
+	f.registrations[key] = value
+}
+
+func (f *fakeRegistry) snapshot() (int, map[string]string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	registrations := make(map[string]string, len(f.registrations))
+	for k, v := range f.registrations {
+		registrations[k] = v
+	}
+	return f.index, registrations
+}
+
+func (f *fakeRegistry) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.queries = append(f.queries, r.URL.RequestURI())
+	f.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/v1/health/service/a2a-agent":
+		if since, _ := strconv.Atoi(r.URL.Query().Get("index")); since > 0 {
+			f.block(r.Context(), since)
+		}
+		index, registrations := f.snapshot()
+		entries := []json.RawMessage{}
+		for _, entry := range registrations {
+			entries = append(entries, json.RawMessage(entry))
+		}
+		w.Header().Set("X-Consul-Index", strconv.Itoa(index))
+		_ = json.NewEncoder(w).Encode(entries)
+	case "/v3/kv/range":
+		index, registrations := f.snapshot()
+		var kvs []map[string]string
+		for key, value := range registrations {
+			kvs = append(kvs, map[string]string{"key": base64.StdEncoding.EncodeToString([]byte(key)), "value": base64.StdEncoding.EncodeToString([]byte(value))})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"header": map[string]string{"revision": strconv.Itoa(index)}, "kvs": kvs})
+	case "/v3/watch":
+		var body struct {
+			CreateRequest struct {
+				StartRevision string `json:"start_revision"`
+			} `json:"create_request"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		start, _ := strconv.Atoi(body.CreateRequest.StartRevision)
+		_, _ = fmt.Fprintln(w, `{"result": {"created": true}}`)
+		w.(http.Flusher).Flush()
+		f.block(r.Context(), start-1)
+		_, _ = fmt.Fprintln(w, `{"result": {"events": [{"type": "PUT"}]}}`)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// block waits until the index is past since or the request is canceled
+func (f *fakeRegistry) block(ctx context.Context, since int) {
+	for {
+		if index, _ := f.snapshot(); index > since || ctx.Err() != nil {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestAgentStore_ConsulDiscovery(t *testing.T) {
+	registry, server := newFakeRegistry(t)
+	registry.set("weather-1", `{"Node": {"Address": "10.0.0.5"}, "Service": {"ID": "weather-1", "Port": 8000, "Meta": {"model_id": "weather-agent", "owned_by": "weather-team"}, "Weights": {"Passing": 2}}}`)
+	registry.set("weather-2", `{"Node": {"Address": "10.0.0.6"}, "Service": {"ID": "weather-2", "Port": 8000, "Meta": {"model_id": "weather-agent", "owned_by": "weather-team"}, "Weights": {"Passing": 1}}}`)
+	registry.set("cache", `{"Node": {"Address": "10.0.0.7"}, "Service": {"ID": "cache", "Port": 6379}}`)
+
+	source := &AgentsSource{Registry: &RegistrySource{Driver: "consul", Address: server.URL}}
+	store, err := newAgentStore(nil, source, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []AgentInfo{{ModelID: "weather-agent", OwnedBy: "weather-team", LoadBalancing: &LoadBalancingConfig{Replicas: []Replica{
+		{URL: "http://10.0.0.5:8000", Weight: 2},
+		{URL: "http://10.0.0.6:8000", Weight: 1},
+	}}}}, store.agents(), "instances without model_id are skipped")
+	assert.Equal(t, registryMinInterval, store.refreshDelay(time.Hour))
+
+	// The next reload blocks until the registrations change
+	reloaded := make(chan error, 1)
+	go func() { reloaded <- store.reload(context.Background()) }()
+	select {
+	case <-reloaded:
+		t.Fatal("the reload returned before the registrations changed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	registry.set("news-1", `{"Node": {"Address": "10.0.0.8"}, "Service": {"ID": "news-1", "Address": "news.agents", "Port": 9000, "Meta": {"model_id": "news-agent", "path": "/a2a"}}}`)
+	require.NoError(t, <-reloaded)
+
+	assert.Len(t, store.agents(), 2)
+	agent, ok := store.load().agent("news-agent")
+	assert.True(t, ok)
+	assert.Equal(t, "http://news.agents:9000/a2a", agent.URL)
+	assert.Equal(t, []string{"/v1/health/service/a2a-agent?passing=true", "/v1/health/service/a2a-agent?index=4&passing=true&wait=300s"}, registry.queries)
+}
+
+func TestAgentStore_EtcdDiscovery(t *testing.T) {
+	registry, server := newFakeRegistry(t)
+	registry.set("/agents/weather-agent", `{"model_id": "weather-agent", "url": "http://weather:8000", "description": "Forecasts"}`)
+	registry.set("/agents/broken", `{"model_id": `)
+
+	source := &AgentsSource{Registry: &RegistrySource{Driver: "etcd", Address: server.URL, WaitTime: "1m"}}
+	store, err := newAgentStore(nil, source, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []AgentInfo{{ModelID: "weather-agent", URL: "http://weather:8000", Description: "Forecasts"}}, store.agents(), "invalid registrations are skipped")
+
+	reloaded := make(chan error, 1)
+	go func() { reloaded <- store.reload(context.Background()) }()
+	registry.set("/agents/weather-agent-2", `{"model_id": "weather-agent", "url": "http://weather-2:8000", "weight": 3}`)
+	require.NoError(t, <-reloaded)
+	agent, ok := store.load().agent("weather-agent")
+	assert.True(t, ok)
+	assert.Equal(t, &LoadBalancingConfig{Replicas: []Replica{{URL: "http://weather-2:8000", Weight: 3}, {URL: "http://weather:8000"}}}, agent.LoadBalancing)
+
+	// A registry that cannot be reached keeps the current agents and is retried
+	server.Close()
+	registry.set("/agents/weather-agent-2", "")
+	assert.Error(t, store.reload(context.Background()))
+	assert.Len(t, store.agents(), 1)
+	assert.Equal(t, defaultRefreshInterval, store.refreshDelay(time.Hour))
+}
+
+func TestNewAgentStore_InvalidRegistrySource(t *testing.T) {
+	tests := []struct {
+		name     string
+		registry RegistrySource
+		want     string
+	}{
+		{name: "driver", registry: RegistrySource{Driver: "zookeeper", Address: "http://zk:2181"}, want: "unknown registry driver 'zookeeper'"},
+		{name: "address", registry: RegistrySource{Driver: "consul"}, want: "registry requires an address"},
+		{name: "wait time", registry: RegistrySource{Driver: "etcd", Address: "http://etcd:2379", WaitTime: "10ms"}, want: "invalid wait_time '10ms'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newAgentStore(nil, &AgentsSource{Registry: &tt.registry}, nil)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
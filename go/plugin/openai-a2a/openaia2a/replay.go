@@ -0,0 +1,337 @@
+package openaia2a
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+)
+
+const (
+	adminCapturesPath         = "/admin/captures"
+	adminReplayPath           = "/admin/replay"
+	defaultCaptureSize        = 100
+	defaultCaptureMaxBodySize = 64 << 10
+	replayTimeout             = 2 * time.Minute
+)
+
+// sensitiveHeaders are never stored with a capture
+var sensitiveHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key", "Api-Key"}
+
+// CaptureConfig records chat completions, so that nondeterministic agent behaviour can be
+// inspected and reproduced. Captures are kept in memory for the admin endpoints and
+// optionally appended to a file as JSON lines.
+type CaptureConfig struct {
+	Size        int            `json:"size"`
+	MaxBodySize int            `json:"max_body_size"`
+	File        *UsageFileSink `json:"file,omitempty"`
+}
+
+// Capture is a sanitized chat completion: credentials are removed from the headers, and the
+// A2A request is the one sent to the agent, i.e. after redaction and scripts
+type Capture struct {
+	ID         string          `json:"id"`
+	Timestamp  int64           `json:"timestamp"`
+	Model      string          `json:"model,omitempty"`
+	Header     http.Header     `json:"header"`
+	A2ARequest json.RawMessage `json:"a2a_request,omitempty"`
+	Status     int             `json:"status"`
+	Response   string          `json:"response"`
+	Truncated  bool            `json:"truncated,omitempty"`
+	LatencyMs  int64           `json:"latency_ms"`
+}
+
+// ReplayRequest selects a capture and the agent it is re-sent to. Without model and URL, the
+// capture is replayed to the agent of its model.
+type ReplayRequest struct {
+	ID    string `json:"id"`
+	Model string `json:"model,omitempty"`
+	URL   string `json:"url,omitempty"`
+}
+
+// ReplayResult is the outcome of a replayed capture
+type ReplayResult struct {
+	ID        string          `json:"id"`
+	Target    string          `json:"target"`
+	LatencyMs int64           `json:"latency_ms"`
+	Content   string          `json:"content,omitempty"`
+	Result    json.RawMessage `json:"result,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+type captureKey struct{}
+
+// captures keeps the most recent captures in a bounded ring buffer
+type captures struct {
+	maxBodySize int
+	file        *rotatingFile
+	agents      *agentStore
+	now         func() time.Time
+
+	mu      sync.Mutex
+	entries []Capture
+	next    int
+	size    int
+}
+
+// newCaptures validates the configuration. A nil config disables capturing.
+func newCaptures(cfg *CaptureConfig, agents *agentStore) (*captures, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.Size < 0 || cfg.MaxBodySize < 0 {
+		return nil, errors.New("size and max_body_size must not be negative")
+	}
+	c := &captures{
+		maxBodySize: cfg.MaxBodySize,
+		agents:      agents,
+		now:         time.Now,
+		size:        cfg.Size,
+	}
+	if c.size == 0 {
+		c.size = defaultCaptureSize
+	}
+	if c.maxBodySize == 0 {
+		c.maxBodySize = defaultCaptureMaxBodySize
+	}
+	if cfg.File != nil {
+		file, err := openRotatingFile(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		c.file = file
+	}
+	return c, nil
+}
+
+// captureWriter keeps a copy of the response body up to a limit
+type captureWriter struct {
+	http.ResponseWriter
+	status    int
+	body      bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (cw *captureWriter) WriteHeader(statusCode int) {
+	if cw.status == 0 {
+		cw.status = statusCode
+	}
+	cw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (cw *captureWriter) Write(b []byte) (int, error) {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	if remaining := cw.limit - cw.body.Len(); len(b) > remaining {
+		cw.body.Write(b[:max(remaining, 0)])
+		cw.truncated = true
+	} else {
+		cw.body.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush passes flushes of streamed responses on
+func (cw *captureWriter) Flush() {
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+	_ = http.NewResponseController(cw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the wrapped writer
+func (cw *captureWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// track serves the chat completion with next and stores it as a capture.
+// It is a no-op wrapper if capturing is disabled.
+func (c *captures) track(w http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	if c == nil {
+		next(w, req)
+		return
+	}
+	capture := &Capture{ID: newID(), Header: sanitizeHeader(req.Header)}
+	start := c.now()
+	cw := &captureWriter{ResponseWriter: w, limit: c.maxBodySize}
+	next(cw, req.WithContext(context.WithValue(req.Context(), captureKey{}, capture)))
+
+	capture.Timestamp = start.Unix()
+	capture.LatencyMs = c.now().Sub(start).Milliseconds()
+	capture.Status = cw.status
+	if capture.Status == 0 {
+		capture.Status = http.StatusOK
+	}
+	capture.Response = cw.body.String()
+	capture.Truncated = cw.truncated
+	c.add(*capture)
+}
+
+// recordA2ARequest stores the A2A request sent for the captured request of ctx. Requests larger
+// than max_body_size are not stored and cannot be replayed.
+func recordA2ARequest(ctx context.Context, model string, body []byte) {
+	capture, ok := ctx.Value(captureKey{}).(*Capture)
+	if !ok {
+		return
+	}
+	capture.Model = model
+	capture.A2ARequest = body
+}
+
+// sanitizeHeader returns a copy of header without credentials
+func sanitizeHeader(header http.Header) http.Header {
+	sanitized := header.Clone()
+	for _, name := range sensitiveHeaders {
+		sanitized.Del(name)
+	}
+	return sanitized
+}
+
+func (c *captures) add(capture Capture) {
+	if len(capture.A2ARequest) > c.maxBodySize {
+		capture.A2ARequest = nil
+		capture.Truncated = true
+	}
+	if c.file != nil {
+		if line, err := json.Marshal(capture); err != nil {
+			logger.Error("failed to marshal capture:", err)
+		} else if err := c.file.write(append(line, '\n')); err != nil {
+			logger.Error("failed to write capture:", err)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) < c.size {
+		c.entries = append(c.entries, capture)
+		return
+	}
+	c.entries[c.next] = capture
+	c.next = (c.next + 1) % c.size
+}
+
+// list returns the captures, newest first
+func (c *captures) list() []Capture {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	list := make([]Capture, 0, len(c.entries))
+	for i := len(c.entries) - 1; i >= 0; i-- {
+		list = append(list, c.entries[(c.next+i)%len(c.entries)])
+	}
+	return list
+}
+
+func (c *captures) get(id string) (Capture, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, capture := range c.entries {
+		if capture.ID == id {
+			return capture, true
+		}
+	}
+	return Capture{}, false
+}
+
+// serveCaptures lists the captures, or a single one selected by the id query parameter
+func (c *captures) serveCaptures(w http.ResponseWriter, req *http.Request) {
+	if id := req.URL.Query().Get("id"); id != "" {
+		capture, ok := c.get(id)
+		if !ok {
+			http.Error(w, fmt.Sprintf("capture %s not found", id), http.StatusNotFound)
+			return
+		}
+		if err := pluginkit.WriteJSON(w, http.StatusOK, capture); err != nil {
+			logger.Error("failed to write response:", err)
+		}
+		return
+	}
+
+	if err := pluginkit.WriteJSON(w, http.StatusOK, map[string]interface{}{"captures": c.list()}); err != nil {
+		logger.Error("failed to write response:", err)
+	}
+}
+
+// serveReplay re-sends the A2A request of a capture to an agent and returns its answer
+func (c *captures) serveReplay(w http.ResponseWriter, req *http.Request) {
+	var replay ReplayRequest
+	if err := json.NewDecoder(req.Body).Decode(&replay); err != nil || replay.ID == "" {
+		http.Error(w, "request body must be a JSON object with an id", http.StatusBadRequest)
+		return
+	}
+	capture, ok := c.get(replay.ID)
+	if !ok {
+		http.Error(w, fmt.Sprintf("capture %s not found", replay.ID), http.StatusNotFound)
+		return
+	}
+	if capture.A2ARequest == nil {
+		http.Error(w, fmt.Sprintf("capture %s has no A2A request to replay", replay.ID), http.StatusConflict)
+		return
+	}
+	target, err := c.replayTarget(replay, capture)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), replayTimeout)
+	defer cancel()
+	start := c.now()
+	a2aResp, err := sendA2A(ctx, target, capture.A2ARequest, nil)
+	result := ReplayResult{ID: capture.ID, Target: target, LatencyMs: c.now().Sub(start).Milliseconds()}
+	status := http.StatusOK
+	if err != nil {
+		result.Error = err.Error()
+		status = http.StatusBadGateway
+	} else {
+		result.Content = extractA2AContent(a2aResp)
+		result.Result, _ = json.Marshal(a2aResp.Result)
+	}
+	if err := pluginkit.WriteJSON(w, status, result); err != nil {
+		logger.Error("failed to write response:", err)
+	}
+}
+
+// replayTarget resolves the agent URL of a replay. Explicit URLs must belong to a configured
+// agent, so the admin endpoint cannot be used to send requests to arbitrary hosts.
+func (c *captures) replayTarget(replay ReplayRequest, capture Capture) (string, error) {
+	agents := c.agents.load()
+	if replay.URL != "" {
+		if parsed, err := url.Parse(replay.URL); err != nil || parsed.Host == "" {
+			return "", fmt.Errorf("invalid url '%s'", replay.URL)
+		}
+		for _, agent := range agents.agents {
+			targets := agentTargets(agent)
+			if agent.MirrorTo != nil {
+				targets = append(targets, agent.MirrorTo.URL)
+			}
+			if slices.Contains(targets, replay.URL) {
+				return replay.URL, nil
+			}
+		}
+		return "", fmt.Errorf("url '%s' does not belong to a configured agent", replay.URL)
+	}
+
+	model := replay.Model
+	if model == "" {
+		model = capture.Model
+	}
+	agent, ok := agents.agent(model)
+	if !ok {
+		return "", fmt.Errorf("model '%s' is not configured", model)
+	}
+	targets := agentTargets(agent)
+	if len(targets) == 0 {
+		return "", fmt.Errorf("model '%s' has no agent URL to replay to", model)
+	}
+	return targets[0], nil
+}
@@ -0,0 +1,133 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/testagent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatCompletions_CaptureAndReplay(t *testing.T) {
+	agent := testagent.New(testagent.Options{Handler: func(models.Message) testagent.Response {
+		return testagent.Response{Text: "replayed answer"}
+	}})
+	t.Cleanup(agent.Close)
+
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "test-agent", "url": agent.URL},
+			},
+			"admin": map[string]interface{}{
+				"keys": []interface{}{map[string]interface{}{"id": "ops", "secret": "admin-token"}},
+			},
+			"capture": map[string]interface{}{"size": 10},
+		},
+	}
+	mockHandler := &MockHandler{
+		Response: []byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task","artifacts":[{"artifactId":"a","parts":[{"kind":"text","text":"original answer"}]}]}}`),
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/chat/completions", strings.NewReader(`{"model":"test-agent","messages":[{"role":"user","content":"Hello"}]}`))
+	req.Header.Set("Authorization", "Bearer client-secret")
+	req.Header.Set("X-Conversation-ID", "conv-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	adminCall := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer admin-token")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec = adminCall(http.MethodGet, adminCapturesPath, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list struct {
+		Captures []Capture `json:"captures"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &list))
+	require.Len(t, list.Captures, 1)
+	capture := list.Captures[0]
+	assert.Equal(t, "test-agent", capture.Model)
+	assert.Equal(t, http.StatusOK, capture.Status)
+	assert.Empty(t, capture.Header.Get("Authorization"), "credentials are not captured")
+	assert.Equal(t, "conv-1", capture.Header.Get("X-Conversation-ID"))
+	assert.Contains(t, string(capture.A2ARequest), "Hello")
+	assert.Contains(t, capture.Response, "original answer")
+
+	rec = adminCall(http.MethodGet, adminCapturesPath+"?id="+capture.ID, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), capture.ID)
+
+	rec = adminCall(http.MethodPost, adminReplayPath, `{"id":"`+capture.ID+`"}`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var result ReplayResult
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+	assert.Equal(t, agent.URL, result.Target)
+	assert.Equal(t, "replayed answer", result.Content)
+	require.Len(t, agent.Requests(), 1)
+	var params models.MessageSendParams
+	require.NoError(t, json.Unmarshal(agent.Requests()[0].Params, &params))
+	assert.Equal(t, "Hello", testagent.MessageText(params.Message))
+
+	tests := []struct {
+		name       string
+		method     string
+		body       string
+		wantStatus int
+	}{
+		{name: "unknown capture", method: http.MethodPost, body: `{"id":"missing"}`, wantStatus: http.StatusNotFound},
+		{name: "missing id", method: http.MethodPost, body: `{}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown model", method: http.MethodPost, body: `{"id":"` + capture.ID + `","model":"other"}`, wantStatus: http.StatusBadRequest},
+		{name: "foreign url", method: http.MethodPost, body: `{"id":"` + capture.ID + `","url":"http://attacker.example"}`, wantStatus: http.StatusBadRequest},
+		{name: "GET is not routed", method: http.MethodGet, wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantStatus, adminCall(tt.method, adminReplayPath, tt.body).Code)
+		})
+	}
+	assert.Len(t, agent.Requests(), 1)
+}
+
+func TestCaptures_RingBuffer(t *testing.T) {
+	c, err := newCaptures(&CaptureConfig{Size: 2, MaxBodySize: 10}, nil)
+	require.NoError(t, err)
+
+	c.add(Capture{ID: "1"})
+	c.add(Capture{ID: "2", A2ARequest: json.RawMessage(`{"too":"large"}`)})
+	c.add(Capture{ID: "3"})
+
+	list := c.list()
+	require.Len(t, list, 2)
+	assert.Equal(t, "3", list[0].ID)
+	assert.Equal(t, "2", list[1].ID)
+	assert.Nil(t, list[1].A2ARequest, "oversized A2A requests are not kept")
+	assert.True(t, list[1].Truncated)
+}
+
+func TestNewCaptures_Invalid(t *testing.T) {
+	_, err := newCaptures(&CaptureConfig{Size: -1}, nil)
+	assert.Error(t, err)
+
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents":  []interface{}{map[string]interface{}{"model_id": "test-agent", "url": "http://localhost:8001"}},
+			"capture": map[string]interface{}{},
+		},
+	}
+	_, err = module.registerHandlers(context.Background(), extraConfig, &MockHandler{})
+	assert.ErrorContains(t, err, "neither written to a file nor exposed by admin endpoints")
+}
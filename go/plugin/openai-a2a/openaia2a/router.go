@@ -0,0 +1,552 @@
+package openaia2a
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aclient"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/bufpool"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/capture"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/timing"
+	"github.com/go-http-utils/headers"
+)
+
+// defaultMaxResponseSize limits how much of an agent response is buffered for transformation
+const defaultMaxResponseSize = 16 << 20
+
+// maxResponseSize is the configured limit of buffered agent responses
+var maxResponseSize = defaultMaxResponseSize
+
+// ModelInfo contains routing information for an agent
+type ModelInfo struct {
+	ModelID  string
+	Path     string
+	URL      string
+	Ensemble *EnsembleConfig
+	Canary   *CanaryConfig
+}
+
+// AgentResolutionError provides structured error information for agent resolution failures.
+type AgentResolutionError struct {
+	Type        string // "not_found", "configuration_error", "invalid_format"
+	InternalMsg string // Detailed message for logging
+	ClientMsg   string // Generic message for clients
+}
+
+func (e *AgentResolutionError) Error() string {
+	return e.InternalMsg
+}
+
+// validateModelParameter rejects model parameters that cannot be used as a routing path
+func validateModelParameter(model string) error {
+	if model == "" {
+		return &AgentResolutionError{
+			Type:        "invalid_format",
+			InternalMsg: "model parameter cannot be empty",
+			ClientMsg:   "model parameter is required",
+		}
+	}
+
+	// Check for invalid patterns
+	if strings.Contains(model, "..") {
+		return &AgentResolutionError{
+			Type:        "invalid_format",
+			InternalMsg: fmt.Sprintf("invalid model parameter '%s': contains invalid pattern '..'", model),
+			ClientMsg:   "invalid model parameter format",
+		}
+	}
+
+	// Validate model parameter contains only valid URL path characters
+	if strings.ContainsAny(model, "?#[]@!$&'()*+,;=") {
+		return &AgentResolutionError{
+			Type:        "invalid_format",
+			InternalMsg: fmt.Sprintf("invalid model parameter '%s': contains invalid characters", model),
+			ClientMsg:   "invalid model parameter format",
+		}
+	}
+	return nil
+}
+
+func modelNotFound(model string) error {
+	return &AgentResolutionError{
+		Type:        "not_found",
+		InternalMsg: fmt.Sprintf("model %s not found in configuration", model),
+		ClientMsg:   "model not found",
+	}
+}
+
+// resolveAgent derives the routing information of a single agent configuration
+func resolveAgent(agent AgentInfo) (*ModelInfo, error) {
+	model := agent.ModelID
+	if agent.Ensemble != nil {
+		return resolveEnsemble(agent)
+	}
+
+	if agent.LoadBalancing != nil && len(agent.LoadBalancing.Replicas) > 0 {
+		return &ModelInfo{
+			ModelID: model,
+			Path:    "/" + model,
+			URL:     agent.LoadBalancing.Replicas[0].URL,
+			Canary:  agent.Canary,
+		}, nil
+	}
+
+	if agent.URL == "" {
+		return nil, &AgentResolutionError{
+			Type:        "configuration_error",
+			InternalMsg: fmt.Sprintf("agent %s has no URL configured", model),
+			ClientMsg:   "model is not available",
+		}
+	}
+
+	// Parse the URL to extract scheme and host
+	parsedURL, err := url.Parse(agent.URL)
+	if err != nil {
+		return nil, &AgentResolutionError{
+			Type:        "configuration_error",
+			InternalMsg: fmt.Sprintf("failed to parse agent URL for %s: %v", model, err),
+			ClientMsg:   "model is not available",
+		}
+	}
+	if parsedURL.Host == "" {
+		return nil, &AgentResolutionError{
+			Type:        "configuration_error",
+			InternalMsg: fmt.Sprintf("agent URL for %s has no host: %s", model, agent.URL),
+			ClientMsg:   "model is not available",
+		}
+	}
+
+	// Scheme and host are case-insensitive; normalize them so equal backends compare equal
+	backendURL := fmt.Sprintf("%s://%s", strings.ToLower(parsedURL.Scheme), strings.ToLower(parsedURL.Host))
+
+	// Construct routing path from model ID
+	path := "/" + model
+
+	return &ModelInfo{
+		ModelID: model,
+		Path:    path,
+		URL:     backendURL,
+		Canary:  agent.Canary,
+	}, nil
+}
+
+// handleGlobalChatCompletions handles POST /chat/completions requests
+func handleGlobalChatCompletions(w http.ResponseWriter, req *http.Request, handler http.Handler, cfg config) {
+	log := logging.FromRequest(logger, req)
+	if req.Method != http.MethodPost {
+		log.Debug("invalid method for /chat/completions:", req.Method)
+		writeError(w, req, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	// Read and parse OpenAI request into a pooled buffer, which is only used until it is parsed
+	body := bufpool.Get()
+	defer body.Release()
+	if _, err := body.ReadFrom(req.Body); err != nil {
+		log.Error("failed to read request body:", err)
+		writeError(w, req, http.StatusBadRequest, "failed to read request body")
+		return
+	}
+	bodyBytes := body.Bytes()
+
+	// Validate against the chat completion schema for precise field-level errors
+	if err := validateChatCompletionRequest(bodyBytes); err != nil {
+		log.Debug("invalid OpenAI request:", err)
+		var validationErr *RequestValidationError
+		if errors.As(err, &validationErr) {
+			writeValidationError(w, validationErr)
+		} else {
+			writeError(w, req, http.StatusBadRequest, "invalid OpenAI request format")
+		}
+		return
+	}
+
+	var openAIReq models.OpenAIRequest
+	if err := json.Unmarshal(bodyBytes, &openAIReq); err != nil {
+		log.Error("failed to parse OpenAI request:", err)
+		writeError(w, req, http.StatusBadRequest, "invalid OpenAI request format")
+		return
+	}
+
+	// Streaming is only supported if enabled
+	if openAIReq.Stream && cfg.streamer == nil {
+		log.Warning("streaming request detected, returning error (streaming not enabled)")
+		writeStreamError(w, req, "Streaming is not currently supported by the Agent Gateway")
+		return
+	}
+
+	// Check the fields the schema cannot: value ranges and references between fields
+	if err := openAIReq.Validate(); err != nil {
+		log.Error("invalid OpenAI request:", err)
+		var fieldErrs models.FieldErrors
+		if errors.As(err, &fieldErrs) {
+			writeParamError(w, req, http.StatusBadRequest, fieldErrs[0].Error(), fieldErrs[0].Field)
+		} else {
+			writeError(w, req, http.StatusBadRequest, "invalid OpenAI request format")
+		}
+		return
+	}
+
+	recordModel(req.Context(), openAIReq.Model)
+	req = logging.WithModel(req, openAIReq.Model)
+	log = logging.FromRequest(logger, req)
+
+	// Sandbox keys belong to no tenant, they only reach synthetic agents
+	var tenant *tenant
+	sandboxed := cfg.sandbox.presents(req)
+	if !sandboxed {
+		var ok bool
+		if tenant, ok = cfg.tenancy.admit(w, req); !ok {
+			return
+		}
+		recordTenant(req.Context(), tenant)
+	}
+
+	// Guardrails run before any agent is invoked
+	if !cfg.moderator.checkRequest(w, req, openAIReq) {
+		return
+	}
+
+	cfg.redactor.redactRequest(&openAIReq)
+
+	// Sandbox keys are served by synthetic agents only
+	if sandboxed {
+		handleSandboxChatCompletions(w, req, cfg.sandbox, openAIReq, cfg.MessageMerging)
+		return
+	}
+
+	log.Debug("resolving agent for model:", openAIReq.Model)
+
+	// Resolve agent backend from the current agent set, which may be swapped by a reload
+	agents := cfg.agents.load()
+	// Models outside the tenant's scope or the user's JWT claims are reported as unknown
+	allowed := func(modelID string) bool {
+		return tenant.allows(modelID) && modelAllowed(req.Context(), modelID)
+	}
+	modelInfo, err := cfg.skills.route(req.Context(), agents, openAIReq.Model, allowed)
+	if err != nil {
+		log.Error("failed to resolve agent:", err)
+
+		// Handle structured errors
+		var resErr *AgentResolutionError
+		if errors.As(err, &resErr) {
+			statusCode := http.StatusBadRequest
+			if resErr.Type == "not_found" {
+				statusCode = http.StatusNotFound
+			}
+			writeParamError(w, req, statusCode, resErr.ClientMsg, "model")
+		} else {
+			// Fallback for unexpected errors
+			writeError(w, req, http.StatusInternalServerError, "internal server error")
+		}
+		return
+	}
+
+	if p := principal(req.Context()); p != "" {
+		log.Debug(fmt.Sprintf("resolved model %s with backend %s for principal %s", modelInfo.ModelID, modelInfo.URL, p))
+	} else {
+		log.Debug(fmt.Sprintf("resolved model %s with backend %s", modelInfo.ModelID, modelInfo.URL))
+	}
+
+	if _, ok := cfg.skills.skillID(openAIReq.Model); ok {
+		w.Header().Set(skillModelHeader, modelInfo.ModelID)
+	}
+
+	// Agents evicted for not being ready are not sent requests until they recover
+	if cfg.readiness.rejectChat(w, req, modelInfo.ModelID) {
+		return
+	}
+
+	// Give clients machine-readable notice of deprecated models
+	agent, _ := agents.agent(modelInfo.ModelID)
+	setDeprecationHeaders(w.Header(), agent)
+
+	// Get conversation ID from header
+	conversationId := req.Header.Get("X-Conversation-ID")
+	if conversationId == "" {
+		log.Warning("no X-Conversation-ID header found, generating new conversation ID")
+		conversationId = newID()
+	} else {
+		log.Debug("using conversation ID from header:", conversationId)
+	}
+
+	// Transform to A2A format
+	a2aReq, err := transformOpenAIToA2A(openAIReq, conversationId, cfg.MessageMerging)
+	if err != nil {
+		log.Error("failed to transform OpenAI request:", err)
+		var validationErr *RequestValidationError
+		if errors.As(err, &validationErr) {
+			writeValidationError(w, validationErr)
+		} else {
+			writeError(w, req, http.StatusBadRequest, "invalid OpenAI request")
+		}
+		return
+	}
+
+	// Agents with a known card receive files as file parts in the modes they declare
+	if card, ok := cfg.discovery.card(req.Context(), agent); ok {
+		if err := negotiateModes(a2aReq, openAIReq.Messages, cfg.MessageMerging, agent.ModelID, card); err != nil {
+			log.Info("request does not match the modes of the agent:", err)
+			var validationErr *RequestValidationError
+			if errors.As(err, &validationErr) {
+				writeValidationError(w, validationErr)
+			} else {
+				writeError(w, req, http.StatusBadRequest, "invalid OpenAI request")
+			}
+			return
+		}
+	}
+
+	// Forward the preceding conversation, compacted to the configured size
+	cfg.history.attach(req.Context(), a2aReq, openAIReq.Messages, cfg.MessageMerging)
+
+	// Evaluate configured rules and compute script metadata
+	metadata, err := cfg.scripts.evaluate(openAIReq, req.Header)
+	if err != nil {
+		var rejErr *ScriptRejectionError
+		if errors.As(err, &rejErr) {
+			log.Info(rejErr.Error())
+			message := rejErr.Message
+			if message == "" {
+				message = "request rejected by gateway policy"
+			}
+			writeError(w, req, http.StatusBadRequest, message)
+			return
+		}
+		log.Error("failed to evaluate scripts:", err)
+		writeError(w, req, http.StatusInternalServerError, "failed to evaluate request scripts")
+		return
+	}
+	for key, value := range metadata {
+		a2aReq.Params.Metadata[key] = value
+	}
+
+	// Platform instructions of the agent take precedence over client and script input
+	injectSystemPrompt(a2aReq, agent)
+
+	// Streamed completions relay the events of the agent as chunks
+	if openAIReq.Stream {
+		target, err := streamTarget(agents, agent)
+		if err == nil && openAIReq.N > 1 {
+			err = errors.New("streaming supports a single choice only")
+		}
+		if err == nil && !cfg.discovery.supportsStreaming(req.Context(), agent) {
+			err = fmt.Errorf("model %s does not support streaming: its agent card does not declare the streaming capability", agent.ModelID)
+		}
+		if err != nil {
+			writeStreamError(w, req, err.Error())
+			return
+		}
+		cfg.streamer.serve(w, req, cfg, target, a2aReq.Params, openAIReq)
+		return
+	}
+
+	// Agents with cancellation return their task immediately and are polled
+	if agent.Cancellation != nil {
+		nonBlocking(a2aReq)
+	}
+
+	// Marshal A2A request
+	a2aBody, err := json.Marshal(a2aReq)
+	if err != nil {
+		log.Error("failed to marshal A2A request:", err)
+		writeError(w, req, http.StatusInternalServerError, "failed to create A2A request")
+		return
+	}
+
+	// Shadow agents receive a sampled copy of the request in the background
+	mirrorRequest(req, agent, a2aBody)
+	recordA2ARequest(req.Context(), agent.ModelID, a2aBody)
+
+	// Agents cut off by their deadline answer with the content they produced so far
+	if cfg.partials != nil && openAIReq.N <= 1 && agent.Cancellation == nil && cfg.discovery.supportsStreaming(req.Context(), agent) {
+		if target, err := streamTarget(agents, agent); err == nil {
+			cfg.partials.serve(w, req, cfg, target, a2aReq.Params, openAIReq)
+			return
+		}
+	}
+
+	// Agents that only expose gRPC are called through the transcoder instead of KrakenD
+	rpc := krakendRPC(req, handler, modelInfo.Path)
+	grpcClient := agents.grpc[modelInfo.ModelID]
+	if grpcClient != nil {
+		rpc = grpcClient.Transcode
+	}
+
+	// Ensembles, canaries and load-balanced agents bypass KrakenD routing and call agents directly
+	send, failure := directRoute(w, req, agents, modelInfo, conversationId)
+	if send == nil && agent.Cancellation != nil {
+		interval, _ := agent.Cancellation.pollInterval()
+		send, failure = cancellableRoute(rpc, interval), "agent did not return a response"
+	}
+	if send == nil && grpcClient != nil {
+		send, failure = rpcRoute(rpc), "agent did not return a response"
+	}
+
+	// Several choices are independent A2A requests sent concurrently
+	if openAIReq.N > 1 {
+		if send == nil {
+			send, failure = rpcRoute(rpc), "agent did not return a response"
+		}
+		handleMultipleChoices(w, req, cfg, openAIReq, *a2aReq, send, failure)
+		return
+	}
+
+	if send != nil {
+		stop := timing.Upstream(req.Context())
+		content, err := send(req.Context(), a2aBody)
+		stop()
+		if err != nil {
+			log.Error("direct agent request failed:", err)
+			writeUpstreamError(w, req, err, failure)
+			return
+		}
+		writeCompletion(w, req, cfg, newOpenAIResponse(content, openAIReq))
+		return
+	}
+
+	// Create new request to backend
+	req.Body = io.NopCloser(bytes.NewReader(a2aBody))
+	req.ContentLength = int64(len(a2aBody))
+	req.URL.Path = modelInfo.Path
+	req.Header.Set(headers.ContentType, "application/json")
+	req.Header.Set(headers.ContentLength, fmt.Sprintf("%d", len(a2aBody)))
+
+	// Wrap response writer to capture A2A response
+	rw := capture.New(w, maxResponseSize)
+	defer rw.Release()
+
+	// Forward request to backend via KrakenD
+	stop := timing.Upstream(req.Context())
+	handler.ServeHTTP(rw, req)
+	stop()
+
+	// Responses too large to buffer have been passed through untransformed
+	if rw.Passthrough() {
+		log.Warning(fmt.Sprintf("response of model %s exceeds %d bytes - passed through without transformation", modelInfo.ModelID, maxResponseSize))
+		return
+	}
+	if timing.BudgetExceeded(req.Context()) {
+		writeError(w, req, http.StatusGatewayTimeout, timing.ErrBudgetExceeded.Error())
+		return
+	}
+	capture.CopyHeader(w.Header(), rw.Header())
+
+	// Report JSON-RPC errors of the agent with a matching status and OpenAI error type
+	if rpcErr, ok := a2aerrors.Parse(rw.Body()); ok {
+		log.Info(fmt.Sprintf("agent returned JSON-RPC error %d: %s", rpcErr.Code, rpcErr.Message))
+		writeJSONRPCError(w, rpcErr)
+		return
+	}
+
+	// Only transform successful responses
+	if rw.StatusCode() != http.StatusOK {
+		log.Info(fmt.Sprintf("backend returned non-OK status: %d, passing through", rw.StatusCode()))
+		writePassthroughError(w, req, rw.StatusCode(), rw.Header(), rw.Body())
+		return
+	}
+
+	// Parse A2A response
+	var a2aResp models.SendMessageSuccessResponse
+	a2aRespBytes := rw.Body()
+	if err := json.Unmarshal(a2aRespBytes, &a2aResp); err != nil {
+		log.Error("failed to parse A2A response:", err)
+		writeError(w, req, http.StatusInternalServerError, "failed to parse backend response")
+		return
+	}
+
+	if a2aResp.Result.Kind == "task" {
+		cfg.tasks.record(req.Context(), modelInfo.ModelID, a2aResp.Result.Id)
+		publishTaskCompleted(modelInfo.ModelID, a2aResp.Result.Id, a2aResp.Result.ContextId, a2aResp.Result.Status.State)
+	}
+
+	// Transform A2A response back to OpenAI format
+	openAIResp := transformA2AToOpenAI(a2aResp, openAIReq)
+
+	writeCompletion(w, req, cfg, openAIResp)
+}
+
+// writeCompletion redacts and moderates the completion, records the token usage of the caller and writes it
+func writeCompletion(w http.ResponseWriter, req *http.Request, cfg config, openAIResp models.OpenAIResponse) {
+	cfg.redactor.redactResponse(&openAIResp)
+	cfg.moderator.filterResponse(req.Context(), &openAIResp)
+	recordCompletionUsage(req, cfg, openAIResp.Usage)
+	writeOpenAIResponse(w, req, openAIResp)
+}
+
+// recordCompletionUsage counts the tokens of a completion, streamed or not, for anomaly
+// detection, quotas and the usage export
+func recordCompletionUsage(req *http.Request, cfg config, usage *models.OpenAIUsage) {
+	if usage == nil {
+		return
+	}
+	cfg.anomalies.record(req, usage.TotalTokens)
+	cfg.quotas.record(req, usage.TotalTokens)
+	recordUsage(req.Context(), usage)
+}
+
+// writeOpenAIResponse marshals and writes a successful OpenAI chat completion response
+func writeOpenAIResponse(w http.ResponseWriter, req *http.Request, openAIResp models.OpenAIResponse) {
+	log := logging.FromRequest(logger, req)
+
+	// Marshal and send OpenAI response
+	openAIRespBody := bufpool.Get()
+	defer openAIRespBody.Release()
+	if err := openAIRespBody.EncodeJSON(openAIResp); err != nil {
+		log.Error("failed to marshal OpenAI response:", err)
+		writeError(w, req, http.StatusInternalServerError, "failed to create OpenAI response")
+		return
+	}
+
+	// Write the transformed response
+	w.Header().Set(headers.ContentType, "application/json")
+	w.Header().Del(headers.ContentLength)
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := w.Write(openAIRespBody.Bytes()); err != nil {
+		log.Error("failed to write response:", err)
+	}
+}
+
+// writeOpenAIError writes an error in OpenAI error format
+func writeOpenAIError(w http.ResponseWriter, statusCode int, openAIErr models.OpenAIError) {
+	w.Header().Del(headers.ContentLength)
+	if err := pluginkit.WriteJSON(w, statusCode, models.OpenAIErrorResponse{Error: openAIErr}); err != nil {
+		logger.Error("failed to write response:", err)
+	}
+}
+
+// writeJSONRPCError reports an agent's JSON-RPC error as an OpenAI error response
+func writeJSONRPCError(w http.ResponseWriter, rpcErr *models.JSONRPCErrorResponseError) {
+	statusCode, errorResponse := a2aerrors.OpenAIError(rpcErr)
+	w.Header().Del(headers.ContentLength)
+	if err := pluginkit.WriteJSON(w, statusCode, errorResponse); err != nil {
+		logger.Error("failed to write response:", err)
+	}
+}
+
+// writeUpstreamError reports a failed direct agent call. JSON-RPC errors are mapped
+// via the shared error table, calls cut off by the latency budget of the client are reported
+// as 504 Gateway Timeout, all other failures as 502 Bad Gateway.
+func writeUpstreamError(w http.ResponseWriter, req *http.Request, err error, message string) {
+	var rpcErr *a2aclient.RPCError
+	if errors.As(err, &rpcErr) {
+		writeJSONRPCError(w, rpcErr.Response())
+		return
+	}
+	if timing.BudgetExceeded(req.Context()) {
+		writeError(w, req, http.StatusGatewayTimeout, timing.ErrBudgetExceeded.Error())
+		return
+	}
+	writeError(w, req, http.StatusBadGateway, message)
+}
@@ -0,0 +1,246 @@
+package openaia2a
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/timing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoutingTable_ResolveFound(t *testing.T) {
+	agents := []AgentInfo{
+		{
+			ModelID:   "test-agent-v1",
+			URL:       "http://localhost:8001",
+			OwnedBy:   "test-corp",
+			CreatedAt: 1731679815,
+		},
+		{
+			ModelID:   "prod/weather-agent",
+			URL:       "http://localhost:8002",
+			OwnedBy:   "weather-team",
+			CreatedAt: 1731696200,
+		},
+	}
+
+	modelInfo, err := newRoutingTable(agents, nil).resolve("test-agent-v1")
+
+	assert.NoError(t, err)
+	assert.NotNil(t, modelInfo)
+	assert.Equal(t, "test-agent-v1", modelInfo.ModelID)
+	assert.Equal(t, "/test-agent-v1", modelInfo.Path)
+	assert.Equal(t, "http://localhost:8001", modelInfo.URL)
+}
+
+func TestRoutingTable_ResolveNotFound(t *testing.T) {
+	agents := []AgentInfo{
+		{
+			ModelID:   "agent-alpha",
+			URL:       "http://localhost:8001",
+			OwnedBy:   "team-a",
+			CreatedAt: 1731679815,
+		},
+	}
+
+	_, err := newRoutingTable(agents, nil).resolve("non-existent-agent")
+
+	assert.Error(t, err)
+	resErr, ok := err.(*AgentResolutionError)
+	assert.True(t, ok)
+	assert.Equal(t, "not_found", resErr.Type)
+	assert.Contains(t, resErr.ClientMsg, "model not found")
+}
+
+func TestRoutingTable_ResolveEmptyModel(t *testing.T) {
+	agents := []AgentInfo{
+		{
+			ModelID:   "my-agent",
+			URL:       "http://localhost:8001",
+			OwnedBy:   "engineering",
+			CreatedAt: 1731679815,
+		},
+	}
+
+	_, err := newRoutingTable(agents, nil).resolve("")
+
+	assert.Error(t, err)
+	resErr, ok := err.(*AgentResolutionError)
+	assert.True(t, ok)
+	assert.Equal(t, "invalid_format", resErr.Type)
+}
+
+func TestRoutingTable_ResolvePathTraversal(t *testing.T) {
+	agents := []AgentInfo{
+		{
+			ModelID:   "secure-agent",
+			URL:       "http://localhost:8001",
+			OwnedBy:   "security",
+			CreatedAt: 1731679815,
+		},
+	}
+
+	_, err := newRoutingTable(agents, nil).resolve("../etc/passwd")
+
+	assert.Error(t, err)
+	resErr, ok := err.(*AgentResolutionError)
+	assert.True(t, ok)
+	assert.Equal(t, "invalid_format", resErr.Type)
+	assert.Contains(t, resErr.InternalMsg, "invalid pattern '..'")
+}
+
+func TestRoutingTable_ResolveMissingURL(t *testing.T) {
+	agents := []AgentInfo{
+		{
+			ModelID:   "org/incomplete-agent",
+			URL:       "", // Missing URL
+			OwnedBy:   "org",
+			CreatedAt: 1731679815,
+		},
+	}
+
+	_, err := newRoutingTable(agents, nil).resolve("org/incomplete-agent")
+
+	assert.Error(t, err)
+	resErr, ok := err.(*AgentResolutionError)
+	assert.True(t, ok)
+	assert.Equal(t, "configuration_error", resErr.Type)
+	assert.Contains(t, resErr.InternalMsg, "no URL configured")
+}
+
+func TestRoutingTable_ResolveEmptyAgentsList(t *testing.T) {
+	agents := []AgentInfo{}
+
+	_, err := newRoutingTable(agents, nil).resolve("any-agent-id")
+
+	assert.Error(t, err)
+	resErr, ok := err.(*AgentResolutionError)
+	assert.True(t, ok)
+	assert.Equal(t, "not_found", resErr.Type)
+}
+func TestChatCompletions_MapsJSONRPCErrors(t *testing.T) {
+	mockHandler := &MockHandler{
+		Response: []byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"Incompatible content types"}}`),
+	}
+	var extraConfig map[string]interface{}
+	json.Unmarshal([]byte(configStrWithAgents), &extraConfig)
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+
+	reqBody, _ := json.Marshal(models.OpenAIRequest{
+		Model:    "test-agent-v2",
+		Messages: []models.OpenAIMessage{{Role: "user", Content: "Hello"}},
+	})
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat/completions", bytes.NewReader(reqBody)))
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, rec.Code)
+	var errorResp models.OpenAIErrorResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResp))
+	assert.Equal(t, "Incompatible content types", errorResp.Error.Message)
+	assert.Equal(t, "invalid_request_error", errorResp.Error.Type)
+	assert.Equal(t, "content_type_not_supported", *errorResp.Error.Code)
+}
+
+func TestChatCompletions_PassesThroughOversizedResponses(t *testing.T) {
+	response := `{"jsonrpc":"2.0","id":1,"result":{"kind":"message","messageId":"m1","role":"agent","parts":[{"kind":"text","text":"` + strings.Repeat("x", 100) + `"}]}}`
+	mockHandler := &MockHandler{Response: []byte(response)}
+	var extraConfig map[string]interface{}
+	json.Unmarshal([]byte(configStrWithAgents), &extraConfig)
+	extraConfig[configKey].(map[string]interface{})["max_response_size"] = 64
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+
+	rec := sendChatCompletion(handler, "test-agent-v2", "")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, response, rec.Body.String())
+
+	// Responses within the default limit are transformed
+	extraConfig[configKey].(map[string]interface{})["max_response_size"] = 0
+	handler, err = module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+	var openAIResp models.OpenAIResponse
+	assert.NoError(t, json.Unmarshal(sendChatCompletion(handler, "test-agent-v2", "").Body.Bytes(), &openAIResp))
+	assert.Equal(t, "chat.completion", openAIResp.Object)
+}
+
+func TestRegisterHandlers_NegativeMaxResponseSize(t *testing.T) {
+	var extraConfig map[string]interface{}
+	json.Unmarshal([]byte(configStrWithAgents), &extraConfig)
+	extraConfig[configKey].(map[string]interface{})["max_response_size"] = -1
+
+	_, err := module.registerHandlers(context.Background(), extraConfig, &MockHandler{})
+
+	assert.Error(t, err)
+}
+
+func TestChatCompletions_ServerTimingAndLatencyBudget(t *testing.T) {
+	response := []byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"message","messageId":"m1","role":"agent","parts":[{"kind":"text","text":"Hi"}]}}`)
+	slowBackend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+			_, _ = w.Write(response)
+		}
+	})
+	var extraConfig map[string]interface{}
+	json.Unmarshal([]byte(configStrWithAgents), &extraConfig)
+
+	tests := []struct {
+		name       string
+		handler    http.Handler
+		budget     string
+		wantStatus int
+	}{
+		{name: "no budget", handler: &MockHandler{Response: response}, wantStatus: http.StatusOK},
+		{name: "budget met", handler: &MockHandler{Response: response}, budget: "5s", wantStatus: http.StatusOK},
+		{name: "budget exceeded", handler: slowBackend, budget: "50", wantStatus: http.StatusGatewayTimeout},
+		{name: "invalid budget", handler: &MockHandler{Response: response}, budget: "soon", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := module.registerHandlers(context.Background(), extraConfig, tt.handler)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/chat/completions", strings.NewReader(`{"model":"test-agent-v2","messages":[{"role":"user","content":"Hello"}]}`))
+			if tt.budget != "" {
+				req.Header.Set(timing.BudgetHeader, tt.budget)
+			}
+			start := time.Now()
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			assert.Less(t, time.Since(start), 500*time.Millisecond)
+			assert.Regexp(t, `^gateway;dur=[0-9.]+, upstream;dur=[0-9.]+, total;dur=[0-9.]+$`, rec.Header().Get(timing.Header))
+		})
+	}
+}
+
+func BenchmarkChatCompletions(b *testing.B) {
+	mockHandler := &MockHandler{
+		Response: []byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"message","messageId":"m1","role":"agent","parts":[{"kind":"text","text":"` + strings.Repeat("Sunny, 21°C. ", 50) + `"}]}}`),
+	}
+	var extraConfig map[string]interface{}
+	_ = json.Unmarshal([]byte(configStrWithAgents), &extraConfig)
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	require.NoError(b, err)
+	reqBody, _ := json.Marshal(models.OpenAIRequest{
+		Model:    "test-agent-v2",
+		Messages: []models.OpenAIMessage{{Role: "user", Content: strings.Repeat("What is the weather? ", 50)}},
+	})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/chat/completions", bytes.NewReader(reqBody)))
+	}
+}
@@ -0,0 +1,184 @@
+package openaia2a
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// ModelMatchingConfig relaxes how the model parameter of a request is matched against
+// model IDs and aliases. Exact matches always take precedence.
+type ModelMatchingConfig struct {
+	IgnoreCase       bool `json:"ignore_case"`
+	TrimWhitespace   bool `json:"trim_whitespace"`
+	NormalizeUnicode bool `json:"normalize_unicode"`
+}
+
+// modelMatcher normalizes model names for tolerant matching. A nil matcher matches exactly.
+type modelMatcher struct {
+	ignoreCase       bool
+	trimWhitespace   bool
+	normalizeUnicode bool
+}
+
+// newModelMatcher returns nil if no option is enabled
+func newModelMatcher(cfg *ModelMatchingConfig) *modelMatcher {
+	if cfg == nil || (!cfg.IgnoreCase && !cfg.TrimWhitespace && !cfg.NormalizeUnicode) {
+		return nil
+	}
+	return &modelMatcher{
+		ignoreCase:       cfg.IgnoreCase,
+		trimWhitespace:   cfg.TrimWhitespace,
+		normalizeUnicode: cfg.NormalizeUnicode,
+	}
+}
+
+func (m *modelMatcher) normalize(model string) string {
+	if m.trimWhitespace {
+		model = strings.TrimSpace(model)
+	}
+	if m.normalizeUnicode {
+		model = norm.NFKC.String(model)
+	}
+	if m.ignoreCase {
+		model = strings.ToLower(model)
+	}
+	return model
+}
+
+// route is the pre-resolved routing information of an agent, or why it cannot be routed
+type route struct {
+	info *ModelInfo
+	err  error
+}
+
+// routingTable holds the routing information of all agents, resolved once when the agents
+// are loaded so that requests do not parse agent URLs. Routes are indexed by model ID and alias,
+// and additionally by their normalized names if tolerant matching is enabled.
+type routingTable struct {
+	agents     []AgentInfo
+	routes     []route
+	index      map[string]int
+	matcher    *modelMatcher
+	normalized map[string]int
+}
+
+// newRoutingTable resolves all agents. Misconfigured agents are logged and kept in the table,
+// so requests for them fail with the same error as before instead of failing the whole configuration.
+func newRoutingTable(agents []AgentInfo, matcher *modelMatcher) *routingTable {
+	rt := &routingTable{
+		agents:  agents,
+		routes:  make([]route, len(agents)),
+		index:   make(map[string]int, len(agents)),
+		matcher: matcher,
+	}
+	for i, agent := range agents {
+		info, err := resolveAgent(agent)
+		if err != nil {
+			logger.Warning(fmt.Sprintf("agent %s cannot be routed: %v", agent.ModelID, err))
+		}
+		rt.routes[i] = route{info: info, err: err}
+
+		// Like the previous linear scan, the first agent of a duplicated model ID wins
+		if _, ok := rt.index[agent.ModelID]; ok {
+			logger.Warning("duplicate model ID, only the first agent is routed:", agent.ModelID)
+			continue
+		}
+		rt.index[agent.ModelID] = i
+	}
+	for i, agent := range agents {
+		for _, alias := range agent.Aliases {
+			rt.index[alias] = i
+		}
+	}
+	if matcher != nil {
+		rt.normalized = make(map[string]int, len(rt.index))
+		for i, agent := range agents {
+			rt.addNormalized(agent.ModelID, i)
+		}
+		for i, agent := range agents {
+			for _, alias := range agent.Aliases {
+				rt.addNormalized(alias, i)
+			}
+		}
+	}
+	return rt
+}
+
+// addNormalized indexes the normalized name of a model ID or alias. Names that only differ
+// in case, whitespace or Unicode form are ambiguous; the first agent keeps the normalized name.
+func (rt *routingTable) addNormalized(name string, i int) {
+	key := rt.matcher.normalize(name)
+	if j, ok := rt.normalized[key]; ok {
+		if j != i {
+			logger.Warning(fmt.Sprintf("model %s matches agent %s when matched tolerantly, it is only routed exactly",
+				name, rt.agents[j].ModelID))
+		}
+		return
+	}
+	rt.normalized[key] = i
+}
+
+// lookup finds the agent of a model name, falling back to tolerant matching
+func (rt *routingTable) lookup(model string) (int, bool) {
+	if i, ok := rt.index[model]; ok {
+		return i, true
+	}
+	if rt.matcher == nil {
+		return 0, false
+	}
+	i, ok := rt.normalized[rt.matcher.normalize(model)]
+	return i, ok
+}
+
+// validateAliases checks that aliases are unique and do not shadow model IDs
+func validateAliases(agents []AgentInfo) error {
+	names := make(map[string]bool, len(agents))
+	for _, agent := range agents {
+		names[agent.ModelID] = true
+	}
+	for _, agent := range agents {
+		for _, alias := range agent.Aliases {
+			if alias == "" {
+				return fmt.Errorf("agent %s: empty alias", agent.ModelID)
+			}
+			if err := validateModelParameter(alias); err != nil {
+				return fmt.Errorf("agent %s: invalid alias '%s'", agent.ModelID, alias)
+			}
+			if names[alias] {
+				return fmt.Errorf("agent %s: alias '%s' is already used as a model ID or alias", agent.ModelID, alias)
+			}
+			names[alias] = true
+		}
+	}
+	return nil
+}
+
+// agent returns the agent configured for a model ID or alias
+func (rt *routingTable) agent(model string) (AgentInfo, bool) {
+	i, ok := rt.lookup(model)
+	if !ok {
+		return AgentInfo{}, false
+	}
+	return rt.agents[i], true
+}
+
+// resolve returns the routing information of a model
+func (rt *routingTable) resolve(model string) (*ModelInfo, error) {
+	if err := validateModelParameter(model); err != nil {
+		return nil, err
+	}
+
+	i, ok := rt.lookup(model)
+	if !ok {
+		return nil, modelNotFound(model)
+	}
+	r := rt.routes[i]
+	if r.err != nil {
+		return nil, r.err
+	}
+	// Callers get their own copy of the shared entry
+	info := *r.info
+	return &info, nil
+}
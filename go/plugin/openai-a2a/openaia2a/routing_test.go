@@ -0,0 +1,160 @@
+package openaia2a
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoutingTable_Resolve(t *testing.T) {
+	agents := []AgentInfo{
+		{ModelID: "test-agent-v1", URL: "HTTP://LocalHost:8001/base/"},
+		{ModelID: "missing-url"},
+		{ModelID: "no-host", URL: "localhost"},
+		{ModelID: "ensemble", Ensemble: &EnsembleConfig{URLs: []string{"http://a:8000"}}},
+		{ModelID: "balanced", LoadBalancing: &LoadBalancingConfig{Replicas: []Replica{{URL: "http://b:8000"}}}},
+	}
+	rt := newRoutingTable(agents, nil)
+
+	tests := []struct {
+		model   string
+		wantURL string
+		wantErr string
+	}{
+		{model: "test-agent-v1", wantURL: "http://localhost:8001"},
+		{model: "missing-url", wantErr: "configuration_error"},
+		{model: "no-host", wantErr: "configuration_error"},
+		{model: "ensemble"},
+		{model: "balanced", wantURL: "http://b:8000"},
+		{model: "unknown", wantErr: "not_found"},
+		{model: "", wantErr: "invalid_format"},
+		{model: "../etc", wantErr: "invalid_format"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			info, err := rt.resolve(tt.model)
+
+			if tt.wantErr != "" {
+				var resErr *AgentResolutionError
+				assert.ErrorAs(t, err, &resErr)
+				assert.Equal(t, tt.wantErr, resErr.Type)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.model, info.ModelID)
+			assert.Equal(t, "/"+tt.model, info.Path)
+			assert.Equal(t, tt.wantURL, info.URL)
+		})
+	}
+}
+
+func TestRoutingTable_ReturnsCopies(t *testing.T) {
+	rt := newRoutingTable([]AgentInfo{{ModelID: "a", URL: "http://a:8000"}}, nil)
+
+	info, _ := rt.resolve("a")
+	info.URL = "http://modified:8000"
+
+	again, _ := rt.resolve("a")
+	assert.Equal(t, "http://a:8000", again.URL)
+}
+
+func TestRoutingTable_Aliases(t *testing.T) {
+	rt := newRoutingTable([]AgentInfo{
+		{ModelID: "weather-agent-v2", URL: "http://weather:8000", Aliases: []string{"weather", "weather-latest"}},
+		{ModelID: "duplicate", URL: "http://first:8000"},
+		{ModelID: "duplicate", URL: "http://second:8000"},
+	}, nil)
+
+	for _, model := range []string{"weather-agent-v2", "weather", "weather-latest"} {
+		info, err := rt.resolve(model)
+		assert.NoError(t, err)
+		assert.Equal(t, "weather-agent-v2", info.ModelID)
+		assert.Equal(t, "/weather-agent-v2", info.Path)
+	}
+
+	info, _ := rt.resolve("duplicate")
+	assert.Equal(t, "http://first:8000", info.URL)
+}
+
+func TestValidateAliases(t *testing.T) {
+	tests := []struct {
+		name    string
+		agents  []AgentInfo
+		wantErr bool
+	}{
+		{name: "unique", agents: []AgentInfo{{ModelID: "a", Aliases: []string{"b"}}, {ModelID: "c", Aliases: []string{"d"}}}},
+		{name: "alias shadows model", agents: []AgentInfo{{ModelID: "a", Aliases: []string{"c"}}, {ModelID: "c"}}, wantErr: true},
+		{name: "alias used twice", agents: []AgentInfo{{ModelID: "a", Aliases: []string{"x"}}, {ModelID: "c", Aliases: []string{"x"}}}, wantErr: true},
+		{name: "invalid alias", agents: []AgentInfo{{ModelID: "a", Aliases: []string{"a?b"}}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAliases(tt.agents)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func benchmarkAgents(n int) []AgentInfo {
+	agents := make([]AgentInfo, n)
+	for i := range agents {
+		agents[i] = AgentInfo{ModelID: fmt.Sprintf("team-%d/agent", i), URL: fmt.Sprintf("http://agent-%d.agents.svc:8000/a2a", i)}
+	}
+	return agents
+}
+
+func BenchmarkRoutingTableResolve(b *testing.B) {
+	for _, n := range []int{50, 500} {
+		rt := newRoutingTable(benchmarkAgents(n), nil)
+		model := fmt.Sprintf("team-%d/agent", n-1)
+		b.Run(fmt.Sprintf("agents=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, _ = rt.resolve(model)
+			}
+		})
+	}
+}
+
+func TestRoutingTable_TolerantMatching(t *testing.T) {
+	agents := []AgentInfo{
+		{ModelID: "Weather-Agent", URL: "http://weather:8000", Aliases: []string{"ｗｅａｔｈｅｒ"}},
+		{ModelID: "weather-agent", URL: "http://exact:8000"},
+	}
+	matcher := newModelMatcher(&ModelMatchingConfig{IgnoreCase: true, TrimWhitespace: true, NormalizeUnicode: true})
+	rt := newRoutingTable(agents, matcher)
+
+	tests := []struct {
+		model   string
+		wantURL string
+	}{
+		{model: "Weather-Agent ", wantURL: "http://weather:8000"},
+		{model: "WEATHER-AGENT", wantURL: "http://weather:8000"},
+		{model: "weather", wantURL: "http://weather:8000"},
+		// Exact matches take precedence over the normalized names
+		{model: "weather-agent", wantURL: "http://exact:8000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			info, err := rt.resolve(tt.model)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantURL, info.URL)
+		})
+	}
+
+	_, err := newRoutingTable(agents, nil).resolve("Weather-Agent ")
+	assert.Error(t, err)
+}
+
+func TestNewModelMatcher_Disabled(t *testing.T) {
+	assert.Nil(t, newModelMatcher(nil))
+	assert.Nil(t, newModelMatcher(&ModelMatchingConfig{}))
+}
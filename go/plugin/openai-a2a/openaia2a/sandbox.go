@@ -0,0 +1,204 @@
+package openaia2a
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+)
+
+// Synthetic agent behavior constants
+const (
+	behaviorEcho  = "echo"
+	behaviorFixed = "fixed"
+)
+
+const (
+	defaultSandboxRequestsPerMinute = 600
+	defaultSandboxModelID           = "sandbox/echo"
+	sandboxOwner                    = "sandbox"
+)
+
+// SyntheticAgent is a built-in agent answering sandbox requests without a backend
+type SyntheticAgent struct {
+	ModelID  string `json:"model_id"`
+	Behavior string `json:"behavior"`
+	Response string `json:"response"`
+}
+
+// SandboxConfig routes requests to /models and /chat/completions with specially prefixed API keys
+// to synthetic agents only. Anyone can make up a sandbox key, so requests are limited per client host.
+type SandboxConfig struct {
+	KeyPrefix         string           `json:"key_prefix"`
+	RequestsPerMinute int              `json:"requests_per_minute"`
+	Agents            []SyntheticAgent `json:"agents"`
+}
+
+// sandbox serves sandbox keys and enforces their per-host request limit
+type sandbox struct {
+	keyPrefix string
+	limit     int
+	agents    map[string]SyntheticAgent
+	modelList []AgentInfo
+
+	mu          sync.Mutex
+	windowStart time.Time
+	counts      map[string]int
+	now         func() time.Time
+}
+
+// newSandbox validates the sandbox configuration. A nil config disables sandbox mode.
+// Without configured agents, a single echo agent is provided.
+func newSandbox(cfg *SandboxConfig) (*sandbox, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.KeyPrefix == "" {
+		return nil, errors.New("key_prefix is required")
+	}
+	if cfg.RequestsPerMinute < 0 {
+		return nil, errors.New("requests_per_minute must not be negative")
+	}
+
+	agents := cfg.Agents
+	if len(agents) == 0 {
+		agents = []SyntheticAgent{{ModelID: defaultSandboxModelID, Behavior: behaviorEcho}}
+	}
+
+	sb := &sandbox{
+		keyPrefix: cfg.KeyPrefix,
+		limit:     cfg.RequestsPerMinute,
+		agents:    make(map[string]SyntheticAgent, len(agents)),
+		counts:    make(map[string]int),
+		now:       time.Now,
+	}
+	if sb.limit == 0 {
+		sb.limit = defaultSandboxRequestsPerMinute
+	}
+
+	for _, agent := range agents {
+		if agent.ModelID == "" {
+			return nil, errors.New("synthetic agent without model_id")
+		}
+		behavior := strings.ToLower(agent.Behavior)
+		if behavior == "" {
+			behavior = behaviorEcho
+		}
+		if behavior != behaviorEcho && behavior != behaviorFixed {
+			return nil, fmt.Errorf("synthetic agent %s has unknown behavior '%s'", agent.ModelID, agent.Behavior)
+		}
+		agent.Behavior = behavior
+		sb.agents[agent.ModelID] = agent
+		sb.modelList = append(sb.modelList, AgentInfo{ModelID: agent.ModelID, OwnedBy: sandboxOwner})
+	}
+	return sb, nil
+}
+
+// presents reports whether the bearer token of a request is a sandbox key
+func (sb *sandbox) presents(req *http.Request) bool {
+	if sb == nil {
+		return false
+	}
+	key, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return ok && strings.HasPrefix(key, sb.keyPrefix)
+}
+
+// allow counts a request for the client host and reports whether it is within the per-minute
+// limit. Hosts are counted instead of keys, since a client can make up any number of keys.
+func (sb *sandbox) allow(req *http.Request) bool {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+
+	now := sb.now()
+	if now.Sub(sb.windowStart) >= time.Minute {
+		sb.windowStart = now
+		sb.counts = make(map[string]int)
+	}
+	sb.counts[host]++
+	return sb.counts[host] <= sb.limit
+}
+
+// respond answers an A2A request like a real agent would, with a completed task
+func (agent SyntheticAgent) respond(a2aReq models.SendMessageRequest) models.SendMessageSuccessResponse {
+	text := agent.Response
+	if agent.Behavior == behaviorEcho {
+		var input strings.Builder
+		for _, part := range a2aReq.Params.Message.Parts {
+			if textPart, ok := part.(models.TextPart); ok {
+				input.WriteString(textPart.Text)
+			}
+		}
+		text = input.String()
+	}
+
+	var contextID string
+	if a2aReq.Params.Message.ContextId != nil {
+		contextID = *a2aReq.Params.Message.ContextId
+	}
+
+	return models.SendMessageSuccessResponse{
+		Jsonrpc: "2.0",
+		Id:      a2aReq.Id,
+		Result: models.SendMessageSuccessResponseResult{
+			Id:        newID(),
+			Kind:      "task",
+			ContextId: contextID,
+			Status:    models.TaskStatus{State: models.TaskStateCompleted},
+			Artifacts: []models.Artifact{
+				{
+					ArtifactId: newID(),
+					Parts:      []models.ArtifactPartsElem{models.TextPart{Kind: "text", Text: text}},
+				},
+			},
+		},
+	}
+}
+
+// handleSandboxChatCompletions serves a chat completion for a sandbox key from a synthetic agent.
+// Sandbox requests never reach real agents.
+func handleSandboxChatCompletions(w http.ResponseWriter, req *http.Request, sb *sandbox, openAIReq models.OpenAIRequest, mergeMode string) {
+	log := logging.FromRequest(logger, req)
+	if !sb.allow(req) {
+		log.Warning("sandbox client exceeded its request limit")
+		writeError(w, req, http.StatusTooManyRequests, "sandbox request limit exceeded")
+		return
+	}
+
+	agent, ok := sb.agents[openAIReq.Model]
+	if !ok {
+		log.Debug("sandbox model not found:", openAIReq.Model)
+		writeParamError(w, req, http.StatusNotFound, "model not found", "model")
+		return
+	}
+
+	conversationId := req.Header.Get("X-Conversation-ID")
+	if conversationId == "" {
+		conversationId = newID()
+	}
+
+	a2aReq, err := transformOpenAIToA2A(openAIReq, conversationId, mergeMode)
+	if err != nil {
+		log.Error("failed to transform OpenAI request:", err)
+		var validationErr *RequestValidationError
+		if errors.As(err, &validationErr) {
+			writeValidationError(w, validationErr)
+		} else {
+			writeError(w, req, http.StatusBadRequest, "invalid OpenAI request")
+		}
+		return
+	}
+
+	log.Debug("serving sandbox request from synthetic agent:", agent.ModelID)
+	writeOpenAIResponse(w, req, transformA2AToOpenAI(agent.respond(*a2aReq), openAIReq))
+}
@@ -0,0 +1,193 @@
+package openaia2a
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func newSandboxHandler(t *testing.T, mockHandler *MockHandler) http.Handler {
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "test-agent", "url": "http://localhost:8001"},
+			},
+			"sandbox": map[string]interface{}{
+				"key_prefix":          "sk-sandbox-",
+				"requests_per_minute": 2,
+				"agents": []interface{}{
+					map[string]interface{}{"model_id": "sandbox/echo"},
+					map[string]interface{}{"model_id": "sandbox/fixed", "behavior": "fixed", "response": "canned answer"},
+				},
+			},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+	return handler
+}
+
+func sendChatCompletion(handler http.Handler, model, apiKey string) *httptest.ResponseRecorder {
+	reqBody, _ := json.Marshal(models.OpenAIRequest{
+		Model:    model,
+		Messages: []models.OpenAIMessage{{Role: "user", Content: "Hello sandbox"}},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/chat/completions", bytes.NewReader(reqBody))
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestNewSandbox_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  SandboxConfig
+	}{
+		{name: "missing prefix", cfg: SandboxConfig{}},
+		{name: "negative limit", cfg: SandboxConfig{KeyPrefix: "sk-sandbox-", RequestsPerMinute: -1}},
+		{name: "unknown behavior", cfg: SandboxConfig{KeyPrefix: "sk-sandbox-", Agents: []SyntheticAgent{{ModelID: "a", Behavior: "llm"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newSandbox(&tt.cfg)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestSandbox_SyntheticAgents(t *testing.T) {
+	mockHandler := &MockHandler{}
+	handler := newSandboxHandler(t, mockHandler)
+
+	tests := []struct {
+		model       string
+		wantContent string
+	}{
+		{model: "sandbox/echo", wantContent: "Hello sandbox"},
+		{model: "sandbox/fixed", wantContent: "canned answer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			rec := sendChatCompletion(handler, tt.model, "sk-sandbox-"+tt.model)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			var openAIResp models.OpenAIResponse
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &openAIResp))
+			assert.Equal(t, tt.wantContent, openAIResp.Choices[0].Message.Content)
+		})
+	}
+
+	assert.Nil(t, mockHandler.ReceivedRequest)
+}
+
+func TestSandbox_RealAgentsNotReachable(t *testing.T) {
+	mockHandler := &MockHandler{}
+	handler := newSandboxHandler(t, mockHandler)
+
+	rec := sendChatCompletion(handler, "test-agent", "sk-sandbox-dev")
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Nil(t, mockHandler.ReceivedRequest)
+}
+
+func TestSandbox_ModelsListsSyntheticAgentsOnly(t *testing.T) {
+	handler := newSandboxHandler(t, &MockHandler{})
+
+	req := httptest.NewRequest(http.MethodGet, "/models", nil)
+	req.Header.Set("Authorization", "Bearer sk-sandbox-dev")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var modelsResp models.OpenAIModelsResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &modelsResp))
+	assert.Len(t, modelsResp.Data, 2)
+	for _, model := range modelsResp.Data {
+		assert.Equal(t, sandboxOwner, model.OwnedBy)
+	}
+}
+
+func TestSandbox_RequestLimit(t *testing.T) {
+	sb, err := newSandbox(&SandboxConfig{KeyPrefix: "sk-sandbox-", RequestsPerMinute: 2})
+	assert.NoError(t, err)
+	now := time.Unix(1731679815, 0)
+	sb.now = func() time.Time { return now }
+
+	request := func(remoteAddr, key string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/chat/completions", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Authorization", "Bearer "+key)
+		return req
+	}
+
+	assert.True(t, sb.allow(request("10.0.0.1:50001", "sk-sandbox-a")))
+	assert.True(t, sb.allow(request("10.0.0.1:50002", "sk-sandbox-a")))
+	assert.False(t, sb.allow(request("10.0.0.1:50003", "sk-sandbox-b")), "new keys share the limit of the client")
+	assert.True(t, sb.allow(request("10.0.0.2:50001", "sk-sandbox-a")))
+
+	now = now.Add(time.Minute)
+	assert.True(t, sb.allow(request("10.0.0.1:50004", "sk-sandbox-a")))
+}
+
+func TestSandbox_LimitedToModelsAndChatCompletions(t *testing.T) {
+	mockHandler := &MockHandler{}
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "test-agent", "url": "http://localhost:8001"},
+			},
+			"api_keys": map[string]interface{}{
+				"keys": []interface{}{map[string]interface{}{"id": "alice", "secret": "sk-alice-key"}},
+			},
+			"tenancy": map[string]interface{}{
+				"required": true,
+				"tenants":  []interface{}{map[string]interface{}{"id": "team-a", "principals": []interface{}{"alice"}, "models": []interface{}{"*"}}},
+			},
+			"quotas":        map[string]interface{}{"daily": map[string]interface{}{"requests": 100}},
+			"orchestration": map[string]interface{}{},
+			"sandbox":       map[string]interface{}{"key_prefix": "sk-sandbox-"},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+
+	// Sandbox keys belong to no tenant, but are served even if tenants are required
+	rec := sendChatCompletion(handler, defaultSandboxModelID, "sk-sandbox-dev")
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Nil(t, mockHandler.ReceivedRequest)
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/usage", nil),
+		httptest.NewRequest(http.MethodPost, "/agents/orchestrate", strings.NewReader(`{"input":"hi","steps":[{"model":"sandbox/echo"}]}`)),
+		httptest.NewRequest(http.MethodPost, "/test-agent", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"message/send"}`)),
+	} {
+		req.Header.Set("Authorization", "Bearer sk-sandbox-dev")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, req.URL.Path)
+	}
+	assert.Nil(t, mockHandler.ReceivedRequest)
+}
+
+func TestSandbox_NonSandboxKeyUsesRealAgents(t *testing.T) {
+	mockHandler := &MockHandler{Response: []byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task"}}`)}
+	handler := newSandboxHandler(t, mockHandler)
+
+	rec := sendChatCompletion(handler, "test-agent", "sk-live-123")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotNil(t, mockHandler.ReceivedRequest)
+}
@@ -0,0 +1,168 @@
+package openaia2a
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/ext"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ScriptRule rejects a request when its CEL expression evaluates to false
+type ScriptRule struct {
+	Expression string `json:"expression"`
+	Message    string `json:"message"`
+}
+
+// ScriptConfig holds CEL expressions evaluated at routing time.
+// Expressions can access the OpenAI request as `request` and the request headers as `headers`
+// (lower-cased names, first value only). The CEL strings extension is available.
+type ScriptConfig struct {
+	Rules    []ScriptRule      `json:"rules"`
+	Metadata map[string]string `json:"metadata"`
+}
+
+// ScriptRejectionError is returned when a request fails a script rule
+type ScriptRejectionError struct {
+	Expression string
+	Message    string
+}
+
+func (e *ScriptRejectionError) Error() string {
+	return fmt.Sprintf("request rejected by rule '%s'", e.Expression)
+}
+
+type compiledRule struct {
+	rule    ScriptRule
+	program cel.Program
+}
+
+type compiledMetadata struct {
+	key     string
+	program cel.Program
+}
+
+// scriptEngine evaluates the compiled CEL programs of a ScriptConfig
+type scriptEngine struct {
+	rules    []compiledRule
+	metadata []compiledMetadata
+}
+
+// newScriptEngine compiles all configured expressions, failing fast on syntax or type errors
+func newScriptEngine(cfg ScriptConfig) (*scriptEngine, error) {
+	env, err := cel.NewEnv(
+		cel.Variable("request", cel.MapType(cel.StringType, cel.DynType)),
+		cel.Variable("headers", cel.MapType(cel.StringType, cel.StringType)),
+		ext.Strings(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create CEL environment: %w", err)
+	}
+
+	engine := &scriptEngine{}
+	for _, rule := range cfg.Rules {
+		program, err := compileExpression(env, rule.Expression, cel.BoolType)
+		if err != nil {
+			return nil, err
+		}
+		engine.rules = append(engine.rules, compiledRule{rule: rule, program: program})
+	}
+
+	// Sort keys so metadata evaluation order is deterministic
+	keys := make([]string, 0, len(cfg.Metadata))
+	for key := range cfg.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		program, err := compileExpression(env, cfg.Metadata[key], nil)
+		if err != nil {
+			return nil, err
+		}
+		engine.metadata = append(engine.metadata, compiledMetadata{key: key, program: program})
+	}
+
+	return engine, nil
+}
+
+// compileExpression compiles a CEL expression, optionally checking its result type
+func compileExpression(env *cel.Env, expression string, outputType *cel.Type) (cel.Program, error) {
+	ast, issues := env.Compile(expression)
+	if issues != nil && issues.Err() != nil {
+		return nil, fmt.Errorf("invalid expression '%s': %w", expression, issues.Err())
+	}
+	if outputType != nil && !ast.OutputType().IsExactType(outputType) && !ast.OutputType().IsExactType(cel.DynType) {
+		return nil, fmt.Errorf("expression '%s' must return %s, got %s", expression, outputType, ast.OutputType())
+	}
+	program, err := env.Program(ast)
+	if err != nil {
+		return nil, fmt.Errorf("cannot build program for '%s': %w", expression, err)
+	}
+	return program, nil
+}
+
+// evaluate runs all rules against the request and computes the configured metadata values.
+// A *ScriptRejectionError is returned for the first rule that does not hold.
+func (e *scriptEngine) evaluate(openAIReq models.OpenAIRequest, header http.Header) (map[string]interface{}, error) {
+	metadata := map[string]interface{}{}
+	if e == nil || (len(e.rules) == 0 && len(e.metadata) == 0) {
+		return metadata, nil
+	}
+
+	activation, err := scriptActivation(openAIReq, header)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rule := range e.rules {
+		out, _, err := rule.program.Eval(activation)
+		if err != nil {
+			return nil, fmt.Errorf("cannot evaluate rule '%s': %w", rule.rule.Expression, err)
+		}
+		if ok, isBool := out.Value().(bool); !isBool || !ok {
+			return nil, &ScriptRejectionError{Expression: rule.rule.Expression, Message: rule.rule.Message}
+		}
+	}
+
+	for _, entry := range e.metadata {
+		out, _, err := entry.program.Eval(activation)
+		if err != nil {
+			return nil, fmt.Errorf("cannot evaluate metadata '%s': %w", entry.key, err)
+		}
+		native, err := out.ConvertToNative(reflect.TypeOf(&structpb.Value{}))
+		if err != nil {
+			return nil, fmt.Errorf("cannot convert metadata '%s': %w", entry.key, err)
+		}
+		metadata[entry.key] = native.(*structpb.Value).AsInterface()
+	}
+
+	return metadata, nil
+}
+
+// scriptActivation exposes the OpenAI request in its JSON shape so expressions use the wire field names
+func scriptActivation(openAIReq models.OpenAIRequest, header http.Header) (map[string]interface{}, error) {
+	raw, err := json.Marshal(openAIReq)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal request for scripts: %w", err)
+	}
+	var request map[string]interface{}
+	if err := json.Unmarshal(raw, &request); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal request for scripts: %w", err)
+	}
+
+	headerMap := make(map[string]string, len(header))
+	for name := range header {
+		headerMap[strings.ToLower(name)] = header.Get(name)
+	}
+
+	return map[string]interface{}{
+		"request": request,
+		"headers": headerMap,
+	}, nil
+}
@@ -0,0 +1,129 @@
+package openaia2a
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewScriptEngine_InvalidExpressions(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  ScriptConfig
+	}{
+		{name: "syntax error", cfg: ScriptConfig{Rules: []ScriptRule{{Expression: "request.messages.size( <"}}}},
+		{name: "rule not boolean", cfg: ScriptConfig{Rules: []ScriptRule{{Expression: "'text'"}}}},
+		{name: "unknown variable", cfg: ScriptConfig{Metadata: map[string]string{"key": "response.id"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newScriptEngine(tt.cfg)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestScriptEngine_Evaluate(t *testing.T) {
+	engine, err := newScriptEngine(ScriptConfig{
+		Rules: []ScriptRule{
+			{Expression: "request.messages.size() < 3", Message: "too many messages"},
+		},
+		Metadata: map[string]string{
+			"team":     "request.model.split('/')[0]",
+			"tenant":   "'x-tenant' in headers ? headers['x-tenant'] : 'none'",
+			"messages": "request.messages.size()",
+		},
+	})
+	assert.NoError(t, err)
+
+	header := http.Header{}
+	header.Set("X-Tenant", "acme")
+	openAIReq := models.OpenAIRequest{
+		Model:    "weather/forecast",
+		Messages: []models.OpenAIMessage{{Role: "user", Content: "Hello"}},
+	}
+
+	metadata, err := engine.evaluate(openAIReq, header)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "weather", metadata["team"])
+	assert.Equal(t, "acme", metadata["tenant"])
+	assert.Equal(t, float64(1), metadata["messages"])
+}
+
+func TestScriptEngine_EvaluateRejects(t *testing.T) {
+	engine, err := newScriptEngine(ScriptConfig{
+		Rules: []ScriptRule{{Expression: "request.messages.size() < 2", Message: "too many messages"}},
+	})
+	assert.NoError(t, err)
+
+	openAIReq := models.OpenAIRequest{
+		Messages: []models.OpenAIMessage{{Role: "user", Content: "a"}, {Role: "user", Content: "b"}},
+	}
+
+	_, err = engine.evaluate(openAIReq, http.Header{})
+
+	rejErr, ok := err.(*ScriptRejectionError)
+	assert.True(t, ok)
+	assert.Equal(t, "too many messages", rejErr.Message)
+}
+
+func TestChatCompletions_ScriptsApplied(t *testing.T) {
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "test-agent", "url": "http://localhost:8001"},
+			},
+			"scripts": map[string]interface{}{
+				"rules": []interface{}{
+					map[string]interface{}{"expression": "request.messages.size() < 2", "message": "too many messages"},
+				},
+				"metadata": map[string]interface{}{"model": "request.model"},
+			},
+		},
+	}
+
+	mockHandler := &MockHandler{Response: []byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task"}}`)}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+
+	send := func(messages []models.OpenAIMessage) *httptest.ResponseRecorder {
+		reqBody, _ := json.Marshal(models.OpenAIRequest{Model: "test-agent", Messages: messages})
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat/completions", bytes.NewReader(reqBody)))
+		return rec
+	}
+
+	rec := send([]models.OpenAIMessage{{Role: "user", Content: "Hello"}})
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var a2aReq models.SendMessageRequest
+	assert.NoError(t, json.Unmarshal(mockHandler.ReceivedBody, &a2aReq))
+	assert.Equal(t, "test-agent", a2aReq.Params.Metadata["model"])
+
+	rec = send([]models.OpenAIMessage{{Role: "user", Content: "a"}, {Role: "user", Content: "b"}})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "too many messages")
+}
+
+func TestRegisterHandlers_InvalidScriptFails(t *testing.T) {
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"scripts": map[string]interface{}{
+				"rules": []interface{}{map[string]interface{}{"expression": "request."}},
+			},
+		},
+	}
+
+	_, err := module.registerHandlers(context.Background(), extraConfig, &MockHandler{})
+
+	assert.Error(t, err)
+}
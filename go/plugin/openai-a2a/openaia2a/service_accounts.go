@@ -0,0 +1,227 @@
+package openaia2a
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/keyring"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+)
+
+// tokenPath is where service accounts exchange their credentials for a short-lived token
+const tokenPath = "/auth/token"
+
+const defaultTokenTTL = 5 * time.Minute
+
+// ServiceAccount is a backend allowed to call back into the gateway
+type ServiceAccount struct {
+	Name   string   `json:"name"`
+	Secret string   `json:"secret"`
+	Scopes []string `json:"scopes"`
+}
+
+// ServiceAccountConfig configures token issuance for agent-to-gateway callbacks.
+// CallbackScopes maps path prefixes of callback endpoints to the scope they require.
+type ServiceAccountConfig struct {
+	SigningKeys    []keyring.KeyConfig `json:"signing_keys"`
+	TokenTTL       string              `json:"token_ttl"`
+	Accounts       []ServiceAccount    `json:"accounts"`
+	CallbackScopes map[string]string   `json:"callback_scopes"`
+}
+
+// callbackClaims are the claims carried by a callback token
+type callbackClaims struct {
+	Subject   string   `json:"sub"`
+	Scopes    []string `json:"scopes"`
+	IssuedAt  int64    `json:"iat"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// serviceAccounts issues callback tokens and guards the callback endpoints
+type serviceAccounts struct {
+	keys     *keyring.Keyring
+	ttl      time.Duration
+	accounts map[string]ServiceAccount
+	// prefixes are sorted longest first so the most specific callback scope wins
+	prefixes []string
+	scopes   map[string]string
+	now      func() time.Time
+}
+
+// newServiceAccounts validates the configuration. A nil config disables callback tokens.
+func newServiceAccounts(cfg *ServiceAccountConfig) (*serviceAccounts, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if len(cfg.SigningKeys) == 0 {
+		return nil, errors.New("at least one signing key is required")
+	}
+	keys, err := keyring.FromConfig(cfg.SigningKeys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing keys: %w", err)
+	}
+
+	sa := &serviceAccounts{
+		keys:     keys,
+		ttl:      defaultTokenTTL,
+		accounts: make(map[string]ServiceAccount, len(cfg.Accounts)),
+		scopes:   cfg.CallbackScopes,
+		now:      time.Now,
+	}
+	if cfg.TokenTTL != "" {
+		if sa.ttl, err = time.ParseDuration(cfg.TokenTTL); err != nil {
+			return nil, fmt.Errorf("invalid token_ttl: %w", err)
+		}
+	}
+
+	for _, account := range cfg.Accounts {
+		if account.Name == "" || account.Secret == "" {
+			return nil, errors.New("service accounts require a name and a secret")
+		}
+		if _, ok := sa.accounts[account.Name]; ok {
+			return nil, fmt.Errorf("duplicate service account '%s'", account.Name)
+		}
+		sa.accounts[account.Name] = account
+	}
+
+	for prefix := range cfg.CallbackScopes {
+		if !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("callback path '%s' must start with '/'", prefix)
+		}
+		sa.prefixes = append(sa.prefixes, prefix)
+	}
+	sort.Slice(sa.prefixes, func(i, j int) bool {
+		return len(sa.prefixes[i]) > len(sa.prefixes[j])
+	})
+	return sa, nil
+}
+
+// handlesTokenRequest reports whether the request asks for a callback token
+func (sa *serviceAccounts) handlesTokenRequest(req *http.Request) bool {
+	return sa != nil && req.Method == http.MethodPost && req.URL.Path == tokenPath
+}
+
+// requiredScope returns the scope a callback endpoint requires, if the path is protected
+func (sa *serviceAccounts) requiredScope(path string) (string, bool) {
+	if sa == nil {
+		return "", false
+	}
+	for _, prefix := range sa.prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return sa.scopes[prefix], true
+		}
+	}
+	return "", false
+}
+
+// serveToken issues a token for a service account authenticated via HTTP basic auth
+func (sa *serviceAccounts) serveToken(w http.ResponseWriter, req *http.Request) {
+	log := logging.FromRequest(logger, req)
+	name, secret, ok := req.BasicAuth()
+	account, known := sa.accounts[name]
+	if !ok || !known || subtle.ConstantTimeCompare([]byte(secret), []byte(account.Secret)) != 1 {
+		log.Warning("rejected token request for service account:", name)
+		w.Header().Set("WWW-Authenticate", `Basic realm="gateway"`)
+		http.Error(w, "invalid service account credentials", http.StatusUnauthorized)
+		return
+	}
+
+	token, err := sa.issue(account)
+	if err != nil {
+		log.Error("failed to issue callback token:", err)
+		http.Error(w, "failed to issue token", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	if err := pluginkit.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": token,
+		"token_type":   "Bearer",
+		"expires_in":   int64(sa.ttl.Seconds()),
+		"scope":        strings.Join(account.Scopes, " "),
+	}); err != nil {
+		log.Error("failed to write response:", err)
+	}
+}
+
+// issue creates a signed token of the form base64url(claims).base64url(signature)
+func (sa *serviceAccounts) issue(account ServiceAccount) (string, error) {
+	now := sa.now()
+	claims, err := json.Marshal(callbackClaims{
+		Subject:   account.Name,
+		Scopes:    account.Scopes,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(sa.ttl).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(claims)
+	_, signature, err := sa.keys.Sign([]byte(payload))
+	if err != nil {
+		return "", err
+	}
+	return payload + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// validate checks signature, expiry and scope of a callback token
+func (sa *serviceAccounts) validate(token, scope string) (*callbackClaims, error) {
+	payload, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, errors.New("malformed token")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return nil, errors.New("malformed token signature")
+	}
+	rawClaims, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, errors.New("malformed token payload")
+	}
+	var claims callbackClaims
+	if err := json.Unmarshal(rawClaims, &claims); err != nil {
+		return nil, errors.New("malformed token claims")
+	}
+	if _, ok := sa.keys.VerifyHMAC([]byte(payload), signature, claims.Subject); !ok {
+		return nil, errors.New("invalid token signature")
+	}
+	if sa.now().Unix() >= claims.ExpiresAt {
+		return nil, errors.New("token expired")
+	}
+	if !slices.Contains(claims.Scopes, scope) {
+		return &claims, fmt.Errorf("token lacks scope '%s'", scope)
+	}
+	return &claims, nil
+}
+
+// authorizeCallback validates the bearer token of a callback request.
+// It writes the error response and returns false if the request must be rejected.
+func (sa *serviceAccounts) authorizeCallback(w http.ResponseWriter, req *http.Request, scope string) bool {
+	log := logging.FromRequest(logger, req)
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		http.Error(w, "callback token required", http.StatusUnauthorized)
+		return false
+	}
+	claims, err := sa.validate(token, scope)
+	if err != nil {
+		log.Warning(fmt.Sprintf("rejected callback to %s: %v", req.URL.Path, err))
+		if claims != nil {
+			http.Error(w, "insufficient scope", http.StatusForbidden)
+		} else {
+			http.Error(w, "invalid callback token", http.StatusUnauthorized)
+		}
+		return false
+	}
+	log.Debug(fmt.Sprintf("authorized callback to %s for service account %s", req.URL.Path, claims.Subject))
+	return true
+}
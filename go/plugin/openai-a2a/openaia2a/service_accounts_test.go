@@ -0,0 +1,112 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/keyring"
+	"github.com/stretchr/testify/assert"
+)
+
+var testServiceAccountConfig = map[string]interface{}{
+	"signing_keys": []interface{}{map[string]interface{}{"id": "k1", "secret": "signing-secret"}},
+	"token_ttl":    "1m",
+	"accounts": []interface{}{
+		map[string]interface{}{"name": "weather-agent", "secret": "agent-secret", "scopes": []interface{}{"push_notifications"}},
+	},
+	"callback_scopes": map[string]interface{}{
+		"/callbacks/push":      "push_notifications",
+		"/callbacks/artifacts": "artifacts",
+	},
+}
+
+func requestToken(handler http.Handler, name, secret string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, tokenPath, nil)
+	req.SetBasicAuth(name, secret)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestNewServiceAccounts_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  ServiceAccountConfig
+	}{
+		{name: "no signing keys", cfg: ServiceAccountConfig{}},
+		{name: "invalid ttl", cfg: ServiceAccountConfig{SigningKeys: []keyring.KeyConfig{{ID: "k", Secret: "s"}}, TokenTTL: "1 minute"}},
+		{name: "account without secret", cfg: ServiceAccountConfig{SigningKeys: []keyring.KeyConfig{{ID: "k", Secret: "s"}}, Accounts: []ServiceAccount{{Name: "a"}}}},
+		{name: "relative callback path", cfg: ServiceAccountConfig{SigningKeys: []keyring.KeyConfig{{ID: "k", Secret: "s"}}, CallbackScopes: map[string]string{"callbacks": "x"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newServiceAccounts(&tt.cfg)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestServiceAccounts_CallbackFlow(t *testing.T) {
+	mockHandler := &MockHandler{}
+	handler, err := module.registerHandlers(context.Background(), map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{"service_accounts": testServiceAccountConfig},
+	}, mockHandler)
+	assert.NoError(t, err)
+
+	assert.Equal(t, http.StatusUnauthorized, requestToken(handler, "weather-agent", "wrong").Code)
+
+	rec := requestToken(handler, "weather-agent", "agent-secret")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var tokenResp struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tokenResp))
+	assert.Equal(t, int64(60), tokenResp.ExpiresIn)
+
+	callback := func(path, token string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, callback("/callbacks/push/task-1", ""))
+	assert.Equal(t, http.StatusUnauthorized, callback("/callbacks/push/task-1", tokenResp.AccessToken+"x"))
+	assert.Equal(t, http.StatusForbidden, callback("/callbacks/artifacts/task-1", tokenResp.AccessToken))
+	assert.Nil(t, mockHandler.ReceivedRequest)
+
+	assert.Equal(t, http.StatusOK, callback("/callbacks/push/task-1", tokenResp.AccessToken))
+	assert.Equal(t, "/callbacks/push/task-1", mockHandler.ReceivedRequest.URL.Path)
+}
+
+func TestServiceAccounts_TokenExpires(t *testing.T) {
+	var cfg ServiceAccountConfig
+	raw, _ := json.Marshal(testServiceAccountConfig)
+	assert.NoError(t, json.Unmarshal(raw, &cfg))
+	sa, err := newServiceAccounts(&cfg)
+	assert.NoError(t, err)
+
+	now := time.Unix(1731679815, 0)
+	sa.now = func() time.Time { return now }
+
+	token, err := sa.issue(sa.accounts["weather-agent"])
+	assert.NoError(t, err)
+
+	_, err = sa.validate(token, "push_notifications")
+	assert.NoError(t, err)
+
+	now = now.Add(time.Minute)
+	_, err = sa.validate(token, "push_notifications")
+	assert.EqualError(t, err, "token expired")
+}
@@ -0,0 +1,95 @@
+package openaia2a
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const defaultSkillPrefix = "skill:"
+
+// skillModelHeader names the model that served a request addressed to a skill
+const skillModelHeader = "X-Agent-Model"
+
+// SkillRoutingConfig lets clients address agents by the skills of their agent cards instead of
+// by model ID, e.g. "skill:weather-forecast". Agent cards are read by capability discovery.
+type SkillRoutingConfig struct {
+	// Prefix marks model parameters that reference a skill ID, "skill:" by default
+	Prefix string `json:"prefix,omitempty"`
+}
+
+// skillRouter resolves skill references to the models of the agents advertising them
+type skillRouter struct {
+	prefix    string
+	discovery *capabilityDiscovery
+}
+
+// newSkillRouter validates the configuration. A nil config routes by model ID only.
+func newSkillRouter(cfg *SkillRoutingConfig, discovery *capabilityDiscovery) (*skillRouter, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if discovery == nil {
+		return nil, errors.New("skill routing requires capability_discovery to read agent cards")
+	}
+	s := &skillRouter{prefix: defaultSkillPrefix, discovery: discovery}
+	if cfg.Prefix != "" {
+		if err := validateModelParameter(cfg.Prefix); err != nil {
+			return nil, fmt.Errorf("invalid prefix '%s'", cfg.Prefix)
+		}
+		s.prefix = cfg.Prefix
+	}
+	return s, nil
+}
+
+// skillID returns the skill referenced by a model parameter, or false if it names a model
+func (s *skillRouter) skillID(model string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+	return strings.CutPrefix(model, s.prefix)
+}
+
+// route resolves the agent of a model parameter, which names a model or references a skill.
+// Models the caller may not use are reported as unknown.
+func (s *skillRouter) route(ctx context.Context, agents *agentSet, model string, allowed func(modelID string) bool) (*ModelInfo, error) {
+	if skill, ok := s.skillID(model); ok {
+		modelID, err := s.resolve(ctx, agents.agents, skill, allowed)
+		if err != nil {
+			return nil, err
+		}
+		model = modelID
+	}
+	modelInfo, err := agents.routes.resolve(model)
+	if err == nil && !allowed(modelInfo.ModelID) {
+		return nil, modelNotFound(model)
+	}
+	return modelInfo, err
+}
+
+// resolve returns the model ID of the first agent, in configuration order, whose card advertises
+// the skill and that the caller may use. Agents without a reachable card are skipped.
+func (s *skillRouter) resolve(ctx context.Context, agents []AgentInfo, skill string, allowed func(modelID string) bool) (string, error) {
+	var candidates []AgentInfo
+	for _, agent := range agents {
+		if allowed(agent.ModelID) {
+			candidates = append(candidates, agent)
+		}
+	}
+	for i, card := range s.discovery.cards(ctx, candidates) {
+		if card == nil {
+			continue
+		}
+		for _, advertised := range card.Skills {
+			if advertised.Id == skill {
+				return candidates[i].ModelID, nil
+			}
+		}
+	}
+	return "", &AgentResolutionError{
+		Type:        "not_found",
+		InternalMsg: fmt.Sprintf("no agent advertises skill %s", skill),
+		ClientMsg:   fmt.Sprintf("no agent advertises skill '%s'", skill),
+	}
+}
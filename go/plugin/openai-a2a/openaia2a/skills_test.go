@@ -0,0 +1,87 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/testagent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatCompletions_SkillRouting(t *testing.T) {
+	weatherAgent := testagent.New(testagent.Options{Card: &models.AgentCard{Name: "weather", Skills: []models.AgentSkill{{Id: "weather-forecast"}}}})
+	t.Cleanup(weatherAgent.Close)
+	travelAgent := testagent.New(testagent.Options{Card: &models.AgentCard{Name: "travel", Skills: []models.AgentSkill{{Id: "flight-booking"}, {Id: "weather-forecast"}}}})
+	t.Cleanup(travelAgent.Close)
+
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "offline-agent", "url": "http://127.0.0.1:1"},
+				map[string]interface{}{"model_id": "weather-agent", "url": weatherAgent.URL},
+				map[string]interface{}{"model_id": "travel-agent", "url": travelAgent.URL},
+			},
+			"capability_discovery": map[string]interface{}{},
+			"skill_routing":        map[string]interface{}{},
+		},
+	}
+	mockHandler := &MockHandler{Response: []byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task","id":"t1","contextId":"c1","status":{"state":"completed"},
+		"artifacts":[{"artifactId":"a1","parts":[{"kind":"text","text":"Sunny"}]}]}}`)}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	require.NoError(t, err)
+
+	tests := []struct {
+		model     string
+		wantModel string
+	}{
+		{model: "skill:weather-forecast", wantModel: "weather-agent"},
+		{model: "skill:flight-booking", wantModel: "travel-agent"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			body := `{"model":"` + tt.model + `","messages":[{"role":"user","content":"Hi"}]}`
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat/completions", strings.NewReader(body)))
+
+			require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+			assert.Equal(t, "/"+tt.wantModel, mockHandler.ReceivedRequest.URL.Path)
+			assert.Equal(t, tt.wantModel, rec.Header().Get(skillModelHeader))
+			var resp models.OpenAIResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.Equal(t, tt.model, resp.Model, "clients see the model they asked for")
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat/completions", strings.NewReader(`{"model":"skill:translation","messages":[{"role":"user","content":"Hi"}]}`)))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.Contains(t, rec.Body.String(), "no agent advertises skill 'translation'")
+}
+
+func TestNewSkillRouter(t *testing.T) {
+	s, err := newSkillRouter(nil, nil)
+	assert.NoError(t, err)
+	assert.Nil(t, s)
+
+	_, err = newSkillRouter(&SkillRoutingConfig{}, nil)
+	assert.ErrorContains(t, err, "capability_discovery")
+
+	discovery, err := newCapabilityDiscovery(&CapabilityDiscoveryConfig{})
+	require.NoError(t, err)
+	s, err = newSkillRouter(&SkillRoutingConfig{Prefix: "capability:"}, discovery)
+	require.NoError(t, err)
+	skill, ok := s.skillID("capability:weather-forecast")
+	assert.True(t, ok)
+	assert.Equal(t, "weather-forecast", skill)
+	_, ok = s.skillID("weather-agent")
+	assert.False(t, ok)
+
+	_, err = newSkillRouter(&SkillRoutingConfig{Prefix: "skill?"}, discovery)
+	assert.Error(t, err)
+}
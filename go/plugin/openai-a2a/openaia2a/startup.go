@@ -0,0 +1,118 @@
+package openaia2a
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+)
+
+// startupSummary describes the configuration a plugin instance was started with
+type startupSummary struct {
+	Plugin       string   `json:"plugin"`
+	Version      string   `json:"version"`
+	ConfigDigest string   `json:"config_digest"`
+	Agents       int      `json:"agents"`
+	Features     []string `json:"features"`
+	Warnings     []string `json:"warnings"`
+}
+
+// configDigest fingerprints the plugin configuration. Map keys are marshalled in sorted order,
+// so equal configurations have equal digests.
+func configDigest(extra map[string]interface{}) string {
+	raw, err := json.Marshal(extra[configKey])
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// newStartupSummary collects the enabled features and likely misconfigurations of cfg
+func newStartupSummary(extra map[string]interface{}, cfg config) startupSummary {
+	agents := cfg.agents.load()
+	summary := startupSummary{
+		Plugin:       pluginName,
+		Version:      pluginkit.Version(),
+		ConfigDigest: configDigest(extra),
+		Agents:       len(agents.agents),
+		Features:     []string{},
+		Warnings:     []string{},
+	}
+
+	features := []struct {
+		name    string
+		enabled bool
+	}{
+		{"agents_source", cfg.AgentsSource != nil},
+		{"model_matching", cfg.agents.matcher != nil},
+		{"scripts", len(cfg.Scripts.Rules) > 0 || len(cfg.Scripts.Metadata) > 0},
+		{"sandbox", cfg.sandbox != nil},
+		{"admin", cfg.admin != nil},
+		{"health", cfg.health != nil},
+		{"readiness", cfg.readiness != nil},
+		{"service_accounts", cfg.accounts != nil},
+		{"anomaly_detection", cfg.anomalies != nil},
+		{"stats", cfg.stats != nil},
+		{"moderation", cfg.moderator != nil},
+		{"redaction", cfg.redactor != nil},
+		{"tenancy", cfg.tenancy != nil},
+		{"api_keys", cfg.apiKeys != nil},
+		{"jwt", cfg.jwt != nil},
+		{"history", cfg.history != nil},
+		{"quotas", cfg.quotas != nil},
+		{"usage_export", cfg.exporter != nil},
+		{"ids", cfg.IDs != nil},
+		{"webhooks", cfg.Webhooks != nil},
+		{"agent_card", cfg.card != nil},
+		{"a2a_validation", cfg.a2a != nil},
+		{"grpc_transport", len(agents.grpc) > 0},
+		{"push_notifications", cfg.push != nil},
+		{"task_store", cfg.tasks != nil},
+		{"artifacts", cfg.artifacts != nil},
+		{"capture", cfg.captures != nil},
+		{"streaming", cfg.streamer != nil},
+		{"partial_responses", cfg.partials != nil},
+		{"capability_discovery", cfg.discovery != nil},
+		{"skill_routing", cfg.skills != nil},
+		{"orchestration", cfg.pipelines != nil},
+		{"diagnostics", cfg.debug != nil},
+		{"upstream", cfg.Upstream != nil},
+		{"trusted_proxies", len(cfg.TrustedProxies) > 0},
+	}
+	for _, feature := range features {
+		if feature.enabled {
+			summary.Features = append(summary.Features, feature.name)
+		}
+	}
+
+	if len(agents.agents) == 0 {
+		summary.Warnings = append(summary.Warnings, "no agents configured")
+	}
+	now := time.Now()
+	for i, agent := range agents.agents {
+		if err := agents.routes.routes[i].err; err != nil {
+			summary.Warnings = append(summary.Warnings, fmt.Sprintf("agent %s cannot be routed: %v", agent.ModelID, err))
+		}
+		if _, sunset, err := deprecationDates(agent); err == nil && !sunset.IsZero() && sunset.Before(now) {
+			summary.Warnings = append(summary.Warnings, fmt.Sprintf("agent %s is past its sunset date", agent.ModelID))
+		}
+	}
+	if cfg.moderator != nil && cfg.moderator.failOpen {
+		summary.Warnings = append(summary.Warnings, "moderation fails open")
+	}
+	return summary
+}
+
+// logStartupSummary logs the summary as a single JSON line
+func logStartupSummary(summary startupSummary) {
+	raw, err := json.Marshal(summary)
+	if err != nil {
+		logger.Error("failed to marshal startup summary:", err)
+		return
+	}
+	logger.Info("started:", string(raw))
+}
@@ -0,0 +1,49 @@
+package openaia2a
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStartupSummary(t *testing.T) {
+	extra := map[string]interface{}{
+		configKey: map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "agent", "url": "http://agent:8000"},
+				map[string]interface{}{"model_id": "retired-agent", "url": "http://retired:8000", "sunset": "2020-01-01"},
+				map[string]interface{}{"model_id": "broken-agent", "url": "http://"},
+			},
+			"stats":     map[string]interface{}{},
+			"redaction": map[string]interface{}{"requests": true, "detectors": []interface{}{"email"}},
+		},
+	}
+	var cfg config
+	assert.NoError(t, parseConfig(extra, &cfg))
+	store, err := newAgentStore(cfg.Agents, nil, nil)
+	assert.NoError(t, err)
+	cfg.agents = store
+	cfg.stats, _ = newGatewayStats(cfg.Stats)
+	cfg.redactor, _ = newRedactor(cfg.Redaction)
+
+	summary := newStartupSummary(extra, cfg)
+
+	assert.Equal(t, pluginName, summary.Plugin)
+	assert.NotEmpty(t, summary.Version)
+	assert.Len(t, summary.ConfigDigest, 12)
+	assert.Equal(t, 3, summary.Agents)
+	assert.Equal(t, []string{"stats", "redaction"}, summary.Features)
+	assert.Equal(t, []string{
+		"agent retired-agent is past its sunset date",
+		"agent broken-agent cannot be routed: agent URL for broken-agent has no host: http://",
+	}, summary.Warnings)
+}
+
+func TestConfigDigest_IgnoresKeyOrder(t *testing.T) {
+	a := map[string]interface{}{configKey: map[string]interface{}{"agents": []interface{}{}, "stats": map[string]interface{}{}}}
+	b := map[string]interface{}{configKey: map[string]interface{}{"stats": map[string]interface{}{}, "agents": []interface{}{}}}
+	c := map[string]interface{}{configKey: map[string]interface{}{"agents": []interface{}{}}}
+
+	assert.Equal(t, configDigest(a), configDigest(b))
+	assert.NotEqual(t, configDigest(a), configDigest(c))
+}
@@ -0,0 +1,305 @@
+package openaia2a
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+)
+
+// statsPath serves in-process usage aggregates of the gateway
+const statsPath = "/__gateway/stats"
+
+const (
+	defaultStatsWindow = 5 * time.Minute
+	// maxStatsSamples bounds memory use; the oldest samples are dropped beyond it
+	maxStatsSamples = 10000
+	topEntriesLimit = 5
+)
+
+// StatsConfig enables the usage dashboard API
+type StatsConfig struct {
+	Window string `json:"window"`
+}
+
+// ModelStats aggregates the requests of a single model
+type ModelStats struct {
+	Model    string `json:"model"`
+	Requests int    `json:"requests"`
+	Errors   int    `json:"errors"`
+}
+
+// PrincipalStats aggregates the requests of a single authenticated principal
+type PrincipalStats struct {
+	Principal string `json:"principal"`
+	Requests  int    `json:"requests"`
+	Errors    int    `json:"errors"`
+}
+
+// LatencyStats are latency percentiles in milliseconds
+type LatencyStats struct {
+	P50 int64 `json:"p50"`
+	P95 int64 `json:"p95"`
+}
+
+// StatsReport is the response of the usage dashboard API
+type StatsReport struct {
+	WindowSeconds int64            `json:"window_seconds"`
+	UptimeSeconds int64            `json:"uptime_seconds"`
+	Requests      int              `json:"requests"`
+	Errors        int              `json:"errors"`
+	ClientErrors  int              `json:"client_errors"`
+	Latency       LatencyStats     `json:"latency_ms"`
+	TopModels     []ModelStats     `json:"top_models"`
+	TopPrincipals []PrincipalStats `json:"top_principals"`
+	InFlight      int64            `json:"in_flight"`
+}
+
+// statsSample is a single finished request. The model and principal are set while the request is handled.
+type statsSample struct {
+	model     string
+	principal string
+	status    int
+	latency   time.Duration
+	at        time.Time
+}
+
+type statsSampleKey struct{}
+
+// gatewayStats keeps the samples of recent requests in a bounded ring buffer
+type gatewayStats struct {
+	window  time.Duration
+	started time.Time
+
+	inFlight atomic.Int64
+
+	mu      sync.Mutex
+	samples []statsSample
+	next    int
+	now     func() time.Time
+}
+
+// newGatewayStats validates the configuration. A nil config disables the stats endpoint.
+func newGatewayStats(cfg *StatsConfig) (*gatewayStats, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	s := &gatewayStats{
+		window: defaultStatsWindow,
+		now:    time.Now,
+	}
+	if cfg.Window != "" {
+		var err error
+		if s.window, err = time.ParseDuration(cfg.Window); err != nil || s.window <= 0 {
+			return nil, fmt.Errorf("invalid window '%s'", cfg.Window)
+		}
+	}
+	s.started = s.now()
+	return s, nil
+}
+
+// handles reports whether the request targets the stats endpoint
+func (s *gatewayStats) handles(req *http.Request) bool {
+	return s != nil && req.Method == http.MethodGet && req.URL.Path == statsPath
+}
+
+// statusWriter captures the status code written by a handler
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusWriter) WriteHeader(statusCode int) {
+	if sw.status == 0 {
+		sw.status = statusCode
+	}
+	sw.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	return sw.ResponseWriter.Write(b)
+}
+
+// Flush passes flushes of streamed responses on
+func (sw *statusWriter) Flush() {
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	_ = http.NewResponseController(sw.ResponseWriter).Flush()
+}
+
+// Unwrap lets http.ResponseController reach the wrapped writer
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
+
+// track serves a request through next and records its status and latency
+func (s *gatewayStats) track(w http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	if s == nil {
+		next(w, req)
+		return
+	}
+
+	s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+
+	sample := &statsSample{at: s.now()}
+	sw := &statusWriter{ResponseWriter: w}
+	next(sw, req.WithContext(context.WithValue(req.Context(), statsSampleKey{}, sample)))
+
+	sample.status = sw.status
+	if sample.status == 0 {
+		sample.status = http.StatusOK
+	}
+	sample.latency = s.now().Sub(sample.at)
+	s.add(*sample)
+}
+
+// recordModel attributes the tracked request and usage record of ctx to a model
+func recordModel(ctx context.Context, model string) {
+	if sample, ok := ctx.Value(statsSampleKey{}).(*statsSample); ok {
+		sample.model = model
+	}
+	if record, ok := ctx.Value(usageRecordKey{}).(*UsageRecord); ok {
+		record.Model = model
+	}
+}
+
+// recordPrincipal attributes the tracked request and usage record of ctx to an authenticated principal
+func recordPrincipal(ctx context.Context, principal string) {
+	if sample, ok := ctx.Value(statsSampleKey{}).(*statsSample); ok {
+		sample.principal = principal
+	}
+	if record, ok := ctx.Value(usageRecordKey{}).(*UsageRecord); ok {
+		record.Principal = principal
+	}
+}
+
+func (s *gatewayStats) add(sample statsSample) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.samples) < maxStatsSamples {
+		s.samples = append(s.samples, sample)
+		return
+	}
+	s.samples[s.next] = sample
+	s.next = (s.next + 1) % maxStatsSamples
+}
+
+// report aggregates all samples within the window
+func (s *gatewayStats) report() StatsReport {
+	now := s.now()
+	report := StatsReport{
+		WindowSeconds: int64(s.window.Seconds()),
+		UptimeSeconds: int64(now.Sub(s.started).Seconds()),
+		TopModels:     []ModelStats{},
+		TopPrincipals: []PrincipalStats{},
+		InFlight:      s.inFlight.Load(),
+	}
+
+	var latencies []time.Duration
+	byModel := make(map[string]*ModelStats)
+	byPrincipal := make(map[string]*PrincipalStats)
+
+	s.mu.Lock()
+	for _, sample := range s.samples {
+		if now.Sub(sample.at) > s.window {
+			continue
+		}
+		report.Requests++
+		latencies = append(latencies, sample.latency)
+		failed := sample.status >= http.StatusInternalServerError
+		if failed {
+			report.Errors++
+		} else if sample.status >= http.StatusBadRequest {
+			report.ClientErrors++
+		}
+
+		if sample.principal != "" {
+			p, ok := byPrincipal[sample.principal]
+			if !ok {
+				p = &PrincipalStats{Principal: sample.principal}
+				byPrincipal[sample.principal] = p
+			}
+			p.Requests++
+			if failed {
+				p.Errors++
+			}
+		}
+
+		if sample.model == "" {
+			continue
+		}
+		model, ok := byModel[sample.model]
+		if !ok {
+			model = &ModelStats{Model: sample.model}
+			byModel[sample.model] = model
+		}
+		model.Requests++
+		if failed {
+			model.Errors++
+		}
+	}
+	s.mu.Unlock()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	report.Latency = LatencyStats{
+		P50: percentile(latencies, 50).Milliseconds(),
+		P95: percentile(latencies, 95).Milliseconds(),
+	}
+
+	for _, model := range byModel {
+		report.TopModels = append(report.TopModels, *model)
+	}
+	sort.Slice(report.TopModels, func(i, j int) bool {
+		if report.TopModels[i].Requests != report.TopModels[j].Requests {
+			return report.TopModels[i].Requests > report.TopModels[j].Requests
+		}
+		return report.TopModels[i].Model < report.TopModels[j].Model
+	})
+	if len(report.TopModels) > topEntriesLimit {
+		report.TopModels = report.TopModels[:topEntriesLimit]
+	}
+
+	for _, p := range byPrincipal {
+		report.TopPrincipals = append(report.TopPrincipals, *p)
+	}
+	sort.Slice(report.TopPrincipals, func(i, j int) bool {
+		if report.TopPrincipals[i].Requests != report.TopPrincipals[j].Requests {
+			return report.TopPrincipals[i].Requests > report.TopPrincipals[j].Requests
+		}
+		return report.TopPrincipals[i].Principal < report.TopPrincipals[j].Principal
+	})
+	if len(report.TopPrincipals) > topEntriesLimit {
+		report.TopPrincipals = report.TopPrincipals[:topEntriesLimit]
+	}
+	return report
+}
+
+// percentile returns the nearest-rank percentile of sorted values
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// serveHTTP serves the usage dashboard API
+func (s *gatewayStats) serveHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	if err := pluginkit.WriteJSON(w, http.StatusOK, s.report()); err != nil {
+		logger.Error("failed to write response:", err)
+	}
+}
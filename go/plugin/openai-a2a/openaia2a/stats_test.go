@@ -0,0 +1,110 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGatewayStats_Report(t *testing.T) {
+	s, err := newGatewayStats(&StatsConfig{Window: "1m"})
+	assert.NoError(t, err)
+	now := time.Unix(1731679800, 0)
+	s.now = func() time.Time { return now }
+
+	// Outside the window
+	s.add(statsSample{model: "old", status: http.StatusOK, at: now.Add(-2 * time.Minute)})
+	for i := 1; i <= 20; i++ {
+		s.add(statsSample{model: "weather", status: http.StatusOK, latency: time.Duration(i) * time.Millisecond, at: now})
+	}
+	s.add(statsSample{model: "news", status: http.StatusBadGateway, latency: time.Second, at: now})
+	s.add(statsSample{status: http.StatusNotFound, at: now})
+
+	report := s.report()
+
+	assert.Equal(t, int64(60), report.WindowSeconds)
+	assert.Equal(t, 22, report.Requests)
+	assert.Equal(t, 1, report.Errors)
+	assert.Equal(t, 1, report.ClientErrors)
+	assert.Equal(t, int64(10), report.Latency.P50)
+	assert.Equal(t, int64(20), report.Latency.P95)
+	assert.Equal(t, []ModelStats{
+		{Model: "weather", Requests: 20},
+		{Model: "news", Requests: 1, Errors: 1},
+	}, report.TopModels)
+}
+
+func TestGatewayStats_BoundedSamples(t *testing.T) {
+	s, err := newGatewayStats(&StatsConfig{})
+	assert.NoError(t, err)
+
+	for i := 0; i < maxStatsSamples+10; i++ {
+		s.add(statsSample{status: http.StatusOK, at: time.Now()})
+	}
+
+	assert.Len(t, s.samples, maxStatsSamples)
+}
+
+func TestGatewayStats_Endpoint(t *testing.T) {
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "test-agent", "url": "http://localhost:8001"},
+			},
+			"admin": map[string]interface{}{
+				"keys": []interface{}{map[string]interface{}{"id": "2026", "secret": "admin-token"}},
+			},
+			"stats": map[string]interface{}{},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, &MockHandler{StatusCode: http.StatusServiceUnavailable})
+	assert.NoError(t, err)
+
+	sendChatCompletion(handler, "test-agent", "")
+	sendChatCompletion(handler, "unknown-agent", "")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, statsPath, nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, statsPath, nil)
+	req.Header.Set("Authorization", "Bearer admin-token")
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var report StatsReport
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+	assert.Equal(t, 2, report.Requests)
+	assert.Equal(t, 1, report.Errors)
+	assert.Equal(t, 1, report.ClientErrors)
+	assert.Len(t, report.TopModels, 2)
+	assert.Equal(t, int64(0), report.InFlight)
+}
+
+func TestGatewayStats_RequiresAdmin(t *testing.T) {
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{},
+			"stats":  map[string]interface{}{},
+		},
+	}
+	_, err := module.registerHandlers(context.Background(), extraConfig, &MockHandler{})
+	assert.ErrorContains(t, err, "requires admin endpoints")
+}
+
+func TestGatewayStats_DisabledByDefault(t *testing.T) {
+	mockHandler := &MockHandler{}
+	handler, err := module.registerHandlers(context.Background(), map[string]interface{}{}, mockHandler)
+	assert.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, statsPath, nil))
+
+	assert.NotNil(t, mockHandler.ReceivedRequest, "the path is passed through when stats are disabled")
+}
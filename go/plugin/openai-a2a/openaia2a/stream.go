@@ -0,0 +1,135 @@
+package openaia2a
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aclient"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/streamconv"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/timing"
+	"github.com/go-http-utils/headers"
+)
+
+const defaultHeartbeatInterval = 15 * time.Second
+
+// StreamingConfig enables chat completions with stream=true. Agents are called with
+// message/stream, and their events are relayed to the client as chat completion chunks.
+type StreamingConfig struct {
+	// HeartbeatInterval is the idle time after which a comment is sent to keep the connection open
+	HeartbeatInterval string `json:"heartbeat_interval,omitempty"`
+	// Progress relays status updates without text as chunks with an empty delta
+	Progress bool `json:"progress,omitempty"`
+}
+
+// streamer relays agent event streams to OpenAI clients
+type streamer struct {
+	heartbeat time.Duration
+	progress  bool
+}
+
+// newStreamer validates the configuration. A nil config rejects streaming requests.
+func newStreamer(cfg *StreamingConfig) (*streamer, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	s := &streamer{heartbeat: defaultHeartbeatInterval, progress: cfg.Progress}
+	if cfg.HeartbeatInterval != "" {
+		var err error
+		if s.heartbeat, err = time.ParseDuration(cfg.HeartbeatInterval); err != nil || s.heartbeat <= 0 {
+			return nil, fmt.Errorf("invalid heartbeat_interval '%s'", cfg.HeartbeatInterval)
+		}
+	}
+	return s, nil
+}
+
+// streamTarget returns the agent URL the streams of a model are sent to: the agent URL or its
+// first replica. Canaries and shadow agents receive no streams.
+func streamTarget(agents *agentSet, agent AgentInfo) (string, error) {
+	if agent.Ensemble != nil {
+		return "", errors.New("streaming is not supported for ensemble models")
+	}
+	if agents.grpc[agent.ModelID] != nil {
+		return "", errors.New("streaming is not supported for gRPC agents")
+	}
+	if agent.URL != "" {
+		return agent.URL, nil
+	}
+	if targets := agentTargets(agent); len(targets) > 0 {
+		return targets[0], nil
+	}
+	return "", fmt.Errorf("model %s has no agent URL", agent.ModelID)
+}
+
+// serve sends params with message/stream to the agent at target and relays its events as chunks
+// until the task ends. Heartbeats keep the connection open while the agent is silent. The tokens
+// of the streamed content count like those of other completions.
+func (s *streamer) serve(w http.ResponseWriter, req *http.Request, cfg config, target string, params models.MessageSendParams, openAIReq models.OpenAIRequest) {
+	log := logging.FromRequest(logger, req)
+	model := openAIReq.Model
+	defer activities.begin(activityStreaming, model)()
+	client, err := a2aclient.New(target, a2aclient.Options{HTTPClient: upstreamClient, Header: agentHeader(req)})
+	if err != nil {
+		log.Error("failed to create agent client:", err)
+		writeError(w, req, http.StatusInternalServerError, "failed to create A2A request")
+		return
+	}
+
+	stopUpstream := timing.Upstream(req.Context())
+	defer stopUpstream()
+	stream, err := client.StreamMessage(req.Context(), params)
+	if err != nil {
+		log.Error("agent stream request failed:", err)
+		writeUpstreamError(w, req, err, "agent did not return a stream")
+		return
+	}
+	defer stream.Close()
+
+	w.Header().Set(headers.ContentType, "text/event-stream")
+	w.Header().Set(headers.CacheControl, "no-cache")
+	w.Header().Del(headers.ContentLength)
+	w.WriteHeader(http.StatusOK)
+
+	chunks := streamconv.NewOpenAIWriter(w)
+	stopHeartbeats := chunks.KeepAlive(s.heartbeat)
+	defer stopHeartbeats()
+	conv := &streamconv.ToOpenAI{ID: newID(), Model: model, Created: time.Now().Unix(), Progress: s.progress}
+	var content strings.Builder
+	if err := relayChunks(chunks, stream, conv, req, cfg, &content); err != nil {
+		log.Error(fmt.Sprintf("stream of model %s ended early:", model), err)
+	}
+	recordCompletionUsage(req, cfg, estimateUsage(openAIReq, content.String()))
+}
+
+// relayChunks writes the chunks for the events of src to dst until the final event or the end
+// of src, then ends dst with [DONE]. Chunks are redacted and moderated like completions; a
+// flagged chunk finishes the completion with content_filter. The content sent to the client is
+// appended to content. Errors of src are returned without ending dst.
+func relayChunks(dst *streamconv.OpenAIWriter, src streamconv.A2AEvents, conv *streamconv.ToOpenAI, req *http.Request, cfg config, content *strings.Builder) error {
+	for !conv.Done() {
+		event, err := src.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		for _, chunk := range conv.Convert(event) {
+			cfg.redactor.redactChunk(&chunk)
+			filtered := cfg.moderator.filterChunk(req.Context(), content.String(), &chunk)
+			content.WriteString(chunk.Choices[0].Delta.Content)
+			if err := dst.Write(chunk); err != nil {
+				return err
+			}
+			if filtered {
+				return dst.Done()
+			}
+		}
+	}
+	return dst.Done()
+}
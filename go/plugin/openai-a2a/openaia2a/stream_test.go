@@ -0,0 +1,167 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/streamconv"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/testagent"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChatCompletions_Stream(t *testing.T) {
+	agent := testagent.New(testagent.Options{Handler: func(models.Message) testagent.Response {
+		return testagent.Response{Chunks: []string{"Sunny", ", 21°C"}, ChunkDelay: 60 * time.Millisecond}
+	}})
+	t.Cleanup(agent.Close)
+
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "weather-agent", "url": agent.URL},
+				map[string]interface{}{"model_id": "ensemble", "ensemble": map[string]interface{}{"urls": []interface{}{agent.URL, agent.URL}}},
+			},
+			"streaming": map[string]interface{}{"heartbeat_interval": "20ms"},
+		},
+	}
+	mockHandler := &MockHandler{}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat/completions", strings.NewReader(`{"model":"weather-agent","stream":true,"messages":[{"role":"user","content":"Weather?"}]}`)))
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "text/event-stream", rec.Header().Get("Content-Type"))
+	assert.Nil(t, mockHandler.ReceivedRequest, "streams bypass KrakenD")
+	assert.Contains(t, rec.Body.String(), ": keep-alive\n\n", "silent agents are covered by heartbeats")
+	assert.True(t, strings.HasSuffix(rec.Body.String(), "data: [DONE]\n\n"))
+
+	var content strings.Builder
+	var finishReason string
+	chunks := streamconv.NewOpenAIReader(strings.NewReader(rec.Body.String()))
+	for {
+		chunk, err := chunks.Next()
+		if err != nil {
+			break
+		}
+		assert.Equal(t, "chat.completion.chunk", chunk.Object)
+		assert.Equal(t, "weather-agent", chunk.Model)
+		content.WriteString(chunk.Choices[0].Delta.Content)
+		if chunk.Choices[0].FinishReason != nil {
+			finishReason = *chunk.Choices[0].FinishReason
+		}
+	}
+	assert.Equal(t, "Sunny, 21°C", content.String())
+	assert.Equal(t, "stop", finishReason)
+	require.Len(t, agent.Requests(), 1)
+	assert.Equal(t, "message/stream", agent.Requests()[0].Method)
+
+	tests := []struct {
+		name    string
+		body    string
+		wantMsg string
+	}{
+		{name: "ensemble", body: `{"model":"ensemble","stream":true,"messages":[{"role":"user","content":"Hi"}]}`, wantMsg: "not supported for ensemble models"},
+		{name: "several choices", body: `{"model":"weather-agent","stream":true,"n":2,"messages":[{"role":"user","content":"Hi"}]}`, wantMsg: "single choice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat/completions", strings.NewReader(tt.body)))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			var errorResp models.OpenAIErrorResponse
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResp))
+			assert.Contains(t, errorResp.Error.Message, tt.wantMsg)
+		})
+	}
+}
+
+func TestChatCompletions_StreamAppliesResponsePolicies(t *testing.T) {
+	agent := testagent.New(testagent.Options{Handler: func(models.Message) testagent.Response {
+		return testagent.Response{Chunks: []string{"Mail jane@example.com", " about the forbidden plan", " and more"}}
+	}})
+	t.Cleanup(agent.Close)
+
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents":     []interface{}{map[string]interface{}{"model_id": "weather-agent", "url": agent.URL}},
+			"streaming":  map[string]interface{}{},
+			"api_keys":   map[string]interface{}{"keys": []interface{}{map[string]interface{}{"id": "weather-team", "secret": "sk-weather-team-key"}}},
+			"quotas":     map[string]interface{}{"monthly": map[string]interface{}{"tokens": 1000}},
+			"redaction":  map[string]interface{}{"detectors": []interface{}{"email"}, "responses": true},
+			"moderation": map[string]interface{}{"denylist": []interface{}{"forbidden"}, "scan_responses": true},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, &MockHandler{})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/chat/completions", strings.NewReader(`{"model":"weather-agent","stream":true,"messages":[{"role":"user","content":"Weather?"}]}`))
+	req.Header.Set("Authorization", "Bearer sk-weather-team-key")
+	req.Header.Set("Cookie", "session=secret")
+	req.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	// Chunks are redacted, and the flagged chunk ends the completion
+	var content strings.Builder
+	var finishReason string
+	chunks := streamconv.NewOpenAIReader(strings.NewReader(rec.Body.String()))
+	for {
+		chunk, err := chunks.Next()
+		if err != nil {
+			break
+		}
+		content.WriteString(chunk.Choices[0].Delta.Content)
+		if chunk.Choices[0].FinishReason != nil {
+			finishReason = *chunk.Choices[0].FinishReason
+		}
+	}
+	assert.Equal(t, "Mail [REDACTED:email]", content.String())
+	assert.Equal(t, "content_filter", finishReason)
+	assert.True(t, strings.HasSuffix(rec.Body.String(), "data: [DONE]\n\n"))
+
+	// Only allowlisted headers reach the agent
+	require.Len(t, agent.Requests(), 1)
+	header := agent.Requests()[0].Header
+	assert.Empty(t, header.Get("Cookie"))
+	assert.NotContains(t, header.Get("Authorization"), "sk-weather-team-key")
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", header.Get("Traceparent"))
+
+	// The streamed tokens count against the quota
+	assert.Positive(t, getUsage(t, handler, "sk-weather-team-key").Quotas[0].Tokens)
+}
+
+func TestAgentHeader(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/chat/completions", nil)
+	req.Header.Set("Authorization", "Bearer sk-key")
+	req.Header.Set("Cookie", "session=secret")
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("X-Conversation-ID", "conv-1")
+	req.Header.Set("Accept-Language", "de")
+
+	assert.Equal(t, http.Header{"X-Conversation-Id": {"conv-1"}, "Accept-Language": {"de"}}, agentHeader(req))
+}
+
+func TestNewStreamer(t *testing.T) {
+	s, err := newStreamer(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, s)
+
+	s, err = newStreamer(&StreamingConfig{Progress: true})
+	require.NoError(t, err)
+	assert.Equal(t, defaultHeartbeatInterval, s.heartbeat)
+	assert.True(t, s.progress)
+
+	_, err = newStreamer(&StreamingConfig{HeartbeatInterval: "0s"})
+	assert.Error(t, err)
+}
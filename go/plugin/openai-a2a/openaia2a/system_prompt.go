@@ -0,0 +1,49 @@
+package openaia2a
+
+import (
+	"fmt"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+)
+
+// System prompt placement constants
+const (
+	systemPromptMessage  = "message"
+	systemPromptMetadata = "metadata"
+)
+
+// systemPromptMetadataKey holds the system prompt in the A2A request metadata
+const systemPromptMetadataKey = "system_prompt"
+
+// validateSystemPrompts checks the system prompt configuration of all agents
+func validateSystemPrompts(agents []AgentInfo) error {
+	for _, agent := range agents {
+		switch agent.SystemPromptMode {
+		case "", systemPromptMessage, systemPromptMetadata:
+		default:
+			return fmt.Errorf("agent %s: unknown system_prompt_mode '%s'", agent.ModelID, agent.SystemPromptMode)
+		}
+	}
+	return nil
+}
+
+// injectSystemPrompt adds the agent's configured system prompt to an A2A request, regardless of
+// what the client sent. By default it becomes the first part of the message, marked with the
+// system role in its metadata; in metadata mode it is passed as request metadata instead.
+func injectSystemPrompt(a2aReq *models.SendMessageRequest, agent AgentInfo) {
+	if agent.SystemPrompt == "" {
+		return
+	}
+
+	if agent.SystemPromptMode == systemPromptMetadata {
+		a2aReq.Params.Metadata[systemPromptMetadataKey] = agent.SystemPrompt
+		return
+	}
+
+	prompt := models.TextPart{
+		Kind:     "text",
+		Text:     agent.SystemPrompt,
+		Metadata: map[string]interface{}{"role": "system"},
+	}
+	a2aReq.Params.Message.Parts = append([]models.MessagePartsElem{prompt}, a2aReq.Params.Message.Parts...)
+}
@@ -0,0 +1,342 @@
+package openaia2a
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/go-http-utils/headers"
+)
+
+const (
+	defaultTaskTTL = 24 * time.Hour
+
+	// maxTaskCapture limits how much of an agent response is kept to find task IDs
+	maxTaskCapture = 1 << 20
+)
+
+// TaskStoreConfig lets clients call tasks/get and tasks/cancel on the gateway itself.
+// Task IDs are remembered for TTL after the gateway last saw them in an agent response.
+type TaskStoreConfig struct {
+	TTL string `json:"ttl,omitempty"`
+}
+
+// taskCaller identifies who created a task: the authenticated principal and its tenant
+type taskCaller struct {
+	principal string
+	tenant    string
+}
+
+type taskEntry struct {
+	model   string
+	caller  taskCaller
+	expires time.Time
+}
+
+// taskStore maps the task IDs returned by agents to the agent owning the task and to the
+// caller that created it
+type taskStore struct {
+	ttl time.Duration
+
+	// tenancy lets callers of the same tenant share their tasks
+	tenancy *tenancy
+
+	mu        sync.Mutex
+	tasks     map[string]taskEntry
+	nextPurge time.Time
+	now       func() time.Time
+}
+
+// newTaskStore validates the configuration. A nil config disables the task store.
+func newTaskStore(cfg *TaskStoreConfig) (*taskStore, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	s := &taskStore{ttl: defaultTaskTTL, tasks: make(map[string]taskEntry), now: time.Now}
+	if cfg.TTL != "" {
+		var err error
+		if s.ttl, err = time.ParseDuration(cfg.TTL); err != nil || s.ttl <= 0 {
+			return nil, fmt.Errorf("invalid ttl '%s'", cfg.TTL)
+		}
+	}
+	return s, nil
+}
+
+// record remembers that model owns the tasks, created by the caller of ctx. Tasks already known
+// keep the model and caller they were first seen with, so other callers cannot take them over by
+// continuing them. Expired tasks are purged at most once per minute.
+func (s *taskStore) record(ctx context.Context, model string, taskIDs ...string) {
+	if s == nil || len(taskIDs) == 0 {
+		return
+	}
+	caller := s.caller(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if now.After(s.nextPurge) {
+		for id, entry := range s.tasks {
+			if now.After(entry.expires) {
+				delete(s.tasks, id)
+			}
+		}
+		s.nextPurge = now.Add(time.Minute)
+	}
+	for _, id := range taskIDs {
+		entry, ok := s.tasks[id]
+		if !ok || now.After(entry.expires) {
+			entry = taskEntry{model: model, caller: caller}
+		}
+		entry.expires = now.Add(s.ttl)
+		s.tasks[id] = entry
+	}
+}
+
+// owner returns the model owning a task that has not expired. Tasks are only found for the
+// caller of ctx that created them, or for callers of the same tenant.
+func (s *taskStore) owner(ctx context.Context, taskID string) (string, bool) {
+	caller := s.caller(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.tasks[taskID]
+	if !ok || s.now().After(entry.expires) || !entry.caller.allows(caller) {
+		return "", false
+	}
+	return entry.model, true
+}
+
+// known reports whether a task has been seen and has not expired, whoever created it
+func (s *taskStore) known(taskID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.tasks[taskID]
+	return ok && !s.now().After(entry.expires)
+}
+
+// caller returns the authenticated principal of ctx and its tenant
+func (s *taskStore) caller(ctx context.Context) taskCaller {
+	c := taskCaller{principal: principal(ctx)}
+	if s.tenancy != nil {
+		if t, err := s.tenancy.tenantOf(ctx); err == nil && t != nil {
+			c.tenant = t.id
+		}
+	}
+	return c
+}
+
+// allows reports whether the caller that created a task lets another caller access it
+func (c taskCaller) allows(other taskCaller) bool {
+	return c.principal == other.principal || (c.tenant != "" && c.tenant == other.tenant)
+}
+
+// handles reports whether the request is an A2A request to the gateway itself, i.e. a POST to /
+func (s *taskStore) handles(req *http.Request) bool {
+	return s != nil && req.Method == http.MethodPost && req.URL.Path == "/"
+}
+
+// serveHTTP routes tasks/get and tasks/cancel to the agent owning the task
+func (s *taskStore) serveHTTP(w http.ResponseWriter, req *http.Request, handler http.Handler, agents *agentSet) {
+	log := logging.FromRequest(logger, req)
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		writeA2AError(w, http.StatusBadRequest, nil, &models.JSONRPCErrorResponseError{Code: a2aerrors.CodeJSONParse, Message: "cannot read request body"})
+		return
+	}
+	var rpcReq struct {
+		Id     interface{}         `json:"id"`
+		Method string              `json:"method"`
+		Params models.TaskIdParams `json:"params"`
+	}
+	if err := json.Unmarshal(body, &rpcReq); err != nil {
+		writeA2AError(w, http.StatusBadRequest, nil, &models.JSONRPCErrorResponseError{Code: a2aerrors.CodeJSONParse, Message: "request body is not valid JSON"})
+		return
+	}
+	if rpcReq.Method != "tasks/get" && rpcReq.Method != "tasks/cancel" {
+		writeA2AError(w, http.StatusBadRequest, rpcReq.Id, &models.JSONRPCErrorResponseError{
+			Code:    a2aerrors.CodeMethodNotFound,
+			Message: fmt.Sprintf("method '%s' is not supported by the gateway, send it to an agent", rpcReq.Method),
+		})
+		return
+	}
+
+	model, ok := s.owner(req.Context(), rpcReq.Params.Id)
+	agent, known := agents.agent(model)
+	if !ok || !known {
+		writeA2AError(w, a2aerrors.Lookup(a2aerrors.CodeTaskNotFound).HTTPStatus, rpcReq.Id, &models.JSONRPCErrorResponseError{
+			Code:    a2aerrors.CodeTaskNotFound,
+			Message: fmt.Sprintf("task '%s' is not known to the gateway", rpcReq.Params.Id),
+		})
+		return
+	}
+
+	rpc := krakendRPC(req, handler, "/"+agent.ModelID)
+	if client := agents.grpc[agent.ModelID]; client != nil {
+		rpc = client.Transcode
+	}
+	log.Debug(fmt.Sprintf("routing %s of task %s to %s", rpcReq.Method, rpcReq.Params.Id, agent.ModelID))
+	resp, err := rpc(req.Context(), body)
+	if err != nil {
+		log.Error(fmt.Sprintf("%s of task %s failed: %v", rpcReq.Method, rpcReq.Params.Id, err))
+		writeA2AError(w, http.StatusBadGateway, rpcReq.Id, &models.JSONRPCErrorResponseError{Code: a2aerrors.CodeInternal, Message: "agent did not return a response"})
+		return
+	}
+	s.record(req.Context(), agent.ModelID, taskIDs(resp)...)
+
+	w.Header().Set(headers.ContentType, "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(resp); err != nil {
+		log.Error("failed to write response:", err)
+	}
+}
+
+// admitNative checks that a native A2A request to model only refers to tasks of the caller. Task
+// methods for tasks of other callers, of other agents or unknown to the gateway are answered with
+// task not found, like messages continuing a known task of another caller. tasks/list is rejected,
+// as it would list the tasks of other callers. The body is restored for forwarding.
+func (s *taskStore) admitNative(w http.ResponseWriter, req *http.Request, model string) bool {
+	if s == nil {
+		return true
+	}
+	log := logging.FromRequest(logger, req)
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		writeA2AError(w, http.StatusBadRequest, nil, &models.JSONRPCErrorResponseError{Code: a2aerrors.CodeJSONParse, Message: "cannot read request body"})
+		return false
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	var rpcReq struct {
+		Id     interface{} `json:"id"`
+		Method string      `json:"method"`
+		Params struct {
+			Id      string `json:"id"`
+			TaskId  string `json:"taskId"`
+			Message struct {
+				TaskId string `json:"taskId"`
+			} `json:"message"`
+		} `json:"params"`
+	}
+	if json.Unmarshal(body, &rpcReq) != nil {
+		// Malformed requests are left to the agent or the A2A validation
+		return true
+	}
+	var taskID string
+	switch rpcReq.Method {
+	case "tasks/get", "tasks/cancel", "tasks/resubscribe", "tasks/pushNotificationConfig/get",
+		"tasks/pushNotificationConfig/list", "tasks/pushNotificationConfig/delete":
+		taskID = rpcReq.Params.Id
+	case "tasks/pushNotificationConfig/set":
+		taskID = rpcReq.Params.TaskId
+	case "message/send", "message/stream":
+		// Messages may continue tasks the gateway has not seen yet
+		taskID = rpcReq.Params.Message.TaskId
+		if taskID == "" || !s.known(taskID) {
+			return true
+		}
+	case "tasks/list":
+		writeA2AError(w, http.StatusBadRequest, rpcReq.Id, &models.JSONRPCErrorResponseError{
+			Code:    a2aerrors.CodeMethodNotFound,
+			Message: "method 'tasks/list' is not supported by the gateway",
+		})
+		return false
+	default:
+		return true
+	}
+
+	if owner, ok := s.owner(req.Context(), taskID); !ok || owner != model {
+		log.Info(fmt.Sprintf("rejected %s of task %s not owned by the caller", rpcReq.Method, taskID))
+		writeA2AError(w, a2aerrors.Lookup(a2aerrors.CodeTaskNotFound).HTTPStatus, rpcReq.Id, &models.JSONRPCErrorResponseError{
+			Code:    a2aerrors.CodeTaskNotFound,
+			Message: fmt.Sprintf("task '%s' is not known to the gateway", taskID),
+		})
+		return false
+	}
+	return true
+}
+
+// recorder wraps w to record the tasks in the response of a native A2A request to model
+func (s *taskStore) recorder(w http.ResponseWriter, req *http.Request, model string) *taskRecorder {
+	return &taskRecorder{ResponseWriter: w, store: s, ctx: req.Context(), model: model}
+}
+
+// taskRecorder passes an agent response through unchanged, streamed or not, and keeps
+// a copy of its beginning to find the task IDs in it
+type taskRecorder struct {
+	http.ResponseWriter
+	store *taskStore
+	ctx   context.Context
+	model string
+	body  bytes.Buffer
+}
+
+func (r *taskRecorder) Write(b []byte) (int, error) {
+	if room := maxTaskCapture - r.body.Len(); room > 0 {
+		r.body.Write(b[:min(len(b), room)])
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController flush streamed responses
+func (r *taskRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Flush passes flushes of streamed responses on
+func (r *taskRecorder) Flush() {
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// done records the tasks of the response
+func (r *taskRecorder) done() {
+	r.store.record(r.ctx, r.model, taskIDs(r.body.Bytes())...)
+}
+
+// taskIDs returns the task IDs in a JSON-RPC response or the events of an SSE stream:
+// the id of task results and the taskId of messages and task updates
+func taskIDs(body []byte) []string {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		return appendTaskID(nil, trimmed)
+	}
+	var ids []string
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), maxTaskCapture)
+	for scanner.Scan() {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data:"); ok {
+			ids = appendTaskID(ids, []byte(strings.TrimSpace(data)))
+		}
+	}
+	return ids
+}
+
+func appendTaskID(ids []string, data []byte) []string {
+	var resp struct {
+		Result struct {
+			Kind   string `json:"kind"`
+			Id     string `json:"id"`
+			TaskId string `json:"taskId"`
+		} `json:"result"`
+	}
+	if json.Unmarshal(data, &resp) != nil {
+		return ids
+	}
+	switch {
+	case resp.Result.Kind == "task" && resp.Result.Id != "":
+		ids = append(ids, resp.Result.Id)
+	case resp.Result.TaskId != "":
+		ids = append(ids, resp.Result.TaskId)
+	}
+	if len(ids) > 1 && ids[len(ids)-1] == ids[len(ids)-2] {
+		ids = ids[:len(ids)-1]
+	}
+	return ids
+}
@@ -0,0 +1,186 @@
+package openaia2a
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/a2aerrors"
+	"github.com/stretchr/testify/assert"
+)
+
+const workingTask = `{"jsonrpc":"2.0","id":1,"result":{"kind":"task","id":"task-42","contextId":"ctx-1","status":{"state":"working"}}}`
+
+func newTaskStoreHandler(t *testing.T, agent http.Handler) http.Handler {
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "task-agent", "url": "http://localhost:8001"},
+				map[string]interface{}{"model_id": "other-agent", "url": "http://localhost:8002"},
+			},
+			"task_store": map[string]interface{}{"ttl": "1h"},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, agent)
+	assert.NoError(t, err)
+	return handler
+}
+
+func TestNewTaskStore_InvalidConfig(t *testing.T) {
+	for _, ttl := range []string{"forever", "-1h", "0s"} {
+		_, err := newTaskStore(&TaskStoreConfig{TTL: ttl})
+		assert.Error(t, err, ttl)
+	}
+}
+
+func TestTaskStore_RoutesTaskQueriesToOwner(t *testing.T) {
+	agent := &MockHandler{Response: []byte(workingTask)}
+	handler := newTaskStoreHandler(t, agent)
+
+	rec := postA2A(handler, "/task-agent", `{"jsonrpc":"2.0","id":1,"method":"message/send","params":{"message":{"kind":"message","messageId":"m1","role":"user","parts":[]}}}`, nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, workingTask, rec.Body.String())
+
+	for _, method := range []string{"tasks/get", "tasks/cancel"} {
+		agent.ReceivedRequest = nil
+		body := `{"jsonrpc":"2.0","id":2,"method":"` + method + `","params":{"id":"task-42"}}`
+		rec = postA2A(handler, "/", body, nil)
+
+		assert.Equal(t, http.StatusOK, rec.Code, method)
+		if assert.NotNil(t, agent.ReceivedRequest, method) {
+			assert.Equal(t, "/task-agent", agent.ReceivedRequest.URL.Path)
+			assert.JSONEq(t, body, string(agent.ReceivedBody))
+		}
+	}
+}
+
+func TestTaskStore_Errors(t *testing.T) {
+	handler := newTaskStoreHandler(t, &MockHandler{Response: []byte(workingTask)})
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantCode   int
+	}{
+		{name: "unknown task", body: `{"jsonrpc":"2.0","id":1,"method":"tasks/get","params":{"id":"task-7"}}`, wantStatus: http.StatusNotFound, wantCode: a2aerrors.CodeTaskNotFound},
+		{name: "other method", body: `{"jsonrpc":"2.0","id":1,"method":"message/send","params":{}}`, wantStatus: http.StatusBadRequest, wantCode: a2aerrors.CodeMethodNotFound},
+		{name: "malformed json", body: `{`, wantStatus: http.StatusBadRequest, wantCode: a2aerrors.CodeJSONParse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := postA2A(handler, "/", tt.body, nil)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			rpcErr, ok := a2aerrors.Parse(rec.Body.Bytes())
+			if assert.True(t, ok) {
+				assert.Equal(t, tt.wantCode, rpcErr.Code)
+			}
+		})
+	}
+}
+
+func TestTaskStore_TasksBelongToTheirCaller(t *testing.T) {
+	agent := &MockHandler{Response: []byte(workingTask)}
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{map[string]interface{}{"model_id": "task-agent", "url": "http://localhost:8001"}},
+			"api_keys": map[string]interface{}{"keys": []interface{}{
+				map[string]interface{}{"id": "alice", "secret": "sk-alice-key"},
+				map[string]interface{}{"id": "bob", "secret": "sk-bob-key"},
+				map[string]interface{}{"id": "carol", "secret": "sk-carol-key"},
+			}},
+			"tenancy": map[string]interface{}{
+				"tenants": []interface{}{map[string]interface{}{"id": "team-a", "principals": []interface{}{"alice", "carol"}, "models": []interface{}{"*"}}},
+			},
+			"task_store": map[string]interface{}{},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, agent)
+	assert.NoError(t, err)
+	as := func(key string) http.Header {
+		if key == "" {
+			return nil
+		}
+		return http.Header{"Authorization": {"Bearer " + key}}
+	}
+	send := `{"jsonrpc":"2.0","id":1,"method":"message/send","params":{"message":{"kind":"message","messageId":"m1","role":"user","taskId":"task-42","parts":[]}}}`
+	get := `{"jsonrpc":"2.0","id":2,"method":"tasks/get","params":{"id":"task-42"}}`
+
+	assert.Equal(t, http.StatusUnauthorized, postA2A(handler, "/task-agent", send, nil).Code, "native A2A requests are authenticated")
+	assert.Equal(t, http.StatusOK, postA2A(handler, "/task-agent", send, as("sk-alice-key")).Code)
+
+	// Other callers can neither continue nor query the task natively
+	assert.Equal(t, http.StatusNotFound, postA2A(handler, "/task-agent", send, as("sk-bob-key")).Code)
+	assert.Equal(t, http.StatusNotFound, postA2A(handler, "/task-agent", get, as("sk-bob-key")).Code)
+	cancel := `{"jsonrpc":"2.0","id":3,"method":"tasks/cancel","params":{"id":"task-42"}}`
+	assert.Equal(t, http.StatusNotFound, postA2A(handler, "/task-agent", cancel, as("sk-bob-key")).Code)
+	assert.Equal(t, http.StatusNotFound, postA2A(handler, "/task-agent", `{"jsonrpc":"2.0","id":4,"method":"tasks/get","params":{"id":"task-7"}}`, as("sk-alice-key")).Code,
+		"tasks unknown to the gateway are not found")
+	assert.Equal(t, http.StatusOK, postA2A(handler, "/task-agent", get, as("sk-carol-key")).Code, "callers of the same tenant share tasks")
+	assert.Equal(t, http.StatusBadRequest, postA2A(handler, "/task-agent", `{"jsonrpc":"2.0","id":5,"method":"tasks/list","params":{}}`, as("sk-alice-key")).Code)
+
+	tests := []struct {
+		key        string
+		wantStatus int
+	}{
+		{key: "", wantStatus: http.StatusUnauthorized},
+		{key: "sk-bob-key", wantStatus: http.StatusNotFound},
+		{key: "sk-alice-key", wantStatus: http.StatusOK},
+		{key: "sk-carol-key", wantStatus: http.StatusOK},
+	}
+	for _, tt := range tests {
+		agent.ReceivedRequest = nil
+		rec := postA2A(handler, "/", get, as(tt.key))
+		assert.Equal(t, tt.wantStatus, rec.Code, tt.key)
+		if tt.wantStatus != http.StatusOK {
+			assert.Nil(t, agent.ReceivedRequest, tt.key)
+		}
+	}
+}
+
+func TestTaskStore_RecordsChatCompletionTasks(t *testing.T) {
+	agent := &MockHandler{Response: []byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task","id":"task-9","status":{"state":"completed"},
+		"artifacts":[{"artifactId":"a","parts":[{"kind":"text","text":"done"}]}]}}`)}
+	handler := newTaskStoreHandler(t, agent)
+
+	sendChatCompletion(handler, "other-agent", "")
+	agent.ReceivedRequest = nil
+	rec := postA2A(handler, "/", `{"jsonrpc":"2.0","id":1,"method":"tasks/get","params":{"id":"task-9"}}`, nil)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	if assert.NotNil(t, agent.ReceivedRequest) {
+		assert.Equal(t, "/other-agent", agent.ReceivedRequest.URL.Path)
+	}
+}
+
+func TestTaskIDs(t *testing.T) {
+	stream := "data: {\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{\"kind\":\"task\",\"id\":\"t1\"}}\n\n" +
+		"data: {\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{\"kind\":\"status-update\",\"taskId\":\"t1\"}}\n\n" +
+		"data: {\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{\"kind\":\"artifact-update\",\"taskId\":\"t2\"}}\n\n"
+
+	assert.Equal(t, []string{"t1", "t2"}, taskIDs([]byte(stream)))
+	assert.Equal(t, []string{"t3"}, taskIDs([]byte(`{"result":{"kind":"message","messageId":"m1","taskId":"t3"}}`)))
+	assert.Empty(t, taskIDs([]byte(`{"result":{"kind":"message","messageId":"m1"}}`)))
+}
+
+func TestTaskStore_Expiry(t *testing.T) {
+	store, err := newTaskStore(&TaskStoreConfig{TTL: "1h"})
+	assert.NoError(t, err)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	store.record(ctx, "task-agent", "t1")
+	model, ok := store.owner(ctx, "t1")
+	assert.True(t, ok)
+	assert.Equal(t, "task-agent", model)
+
+	now = now.Add(2 * time.Hour)
+	_, ok = store.owner(ctx, "t1")
+	assert.False(t, ok)
+	store.record(ctx, "task-agent", "t2")
+	assert.Len(t, store.tasks, 1)
+}
@@ -0,0 +1,212 @@
+package openaia2a
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+)
+
+const (
+	defaultTenantHeader = "X-Tenant-ID"
+	// allModels grants a tenant access to every configured model
+	allModels = "*"
+)
+
+// TenancyConfig scopes requests to tenants derived from the authenticated principal, either
+// from the JWT claim Claim or from the principals listed per tenant. Each tenant may only
+// address its own models and has its own request limit. A tenant header sent by the client
+// must match the derived tenant. Callers without tenant are unrestricted unless Required is set.
+type TenancyConfig struct {
+	Header   string         `json:"header"`
+	Claim    string         `json:"claim"`
+	Required bool           `json:"required"`
+	Tenants  []TenantConfig `json:"tenants"`
+}
+
+// TenantConfig lists the principals (API key IDs or JWT subjects) of a tenant and the model IDs
+// it may address. A limit of 0 means unlimited.
+type TenantConfig struct {
+	ID                string   `json:"id"`
+	Principals        []string `json:"principals"`
+	Models            []string `json:"models"`
+	RequestsPerMinute int      `json:"requests_per_minute"`
+}
+
+// tenant is the resolved tenant of a request. A nil tenant may address all models.
+type tenant struct {
+	id     string
+	models []string
+	limit  int
+}
+
+// tenantWindow counts the requests of a tenant in the current minute
+type tenantWindow struct {
+	start time.Time
+	count int
+}
+
+// tenancy identifies tenants and enforces their per-minute request limits
+type tenancy struct {
+	header     string
+	claim      string
+	required   bool
+	tenants    map[string]*tenant
+	principals map[string]*tenant
+
+	mu      sync.Mutex
+	windows map[string]*tenantWindow
+	now     func() time.Time
+}
+
+// newTenancy validates the configuration. A nil config disables tenancy.
+func newTenancy(cfg *TenancyConfig) (*tenancy, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if len(cfg.Tenants) == 0 {
+		return nil, errors.New("at least one tenant is required")
+	}
+	tn := &tenancy{
+		header:     cfg.Header,
+		claim:      cfg.Claim,
+		required:   cfg.Required,
+		tenants:    make(map[string]*tenant, len(cfg.Tenants)),
+		principals: make(map[string]*tenant),
+		windows:    make(map[string]*tenantWindow, len(cfg.Tenants)),
+		now:        time.Now,
+	}
+	if tn.header == "" {
+		tn.header = defaultTenantHeader
+	}
+	for _, t := range cfg.Tenants {
+		if t.ID == "" {
+			return nil, errors.New("tenant without id")
+		}
+		if _, ok := tn.tenants[t.ID]; ok {
+			return nil, fmt.Errorf("duplicate tenant %s", t.ID)
+		}
+		if len(t.Models) == 0 {
+			return nil, fmt.Errorf("tenant %s: models are required, use \"%s\" for all models", t.ID, allModels)
+		}
+		if t.RequestsPerMinute < 0 {
+			return nil, fmt.Errorf("tenant %s: requests_per_minute must not be negative", t.ID)
+		}
+		if len(t.Principals) == 0 && tn.claim == "" {
+			return nil, fmt.Errorf("tenant %s: principals are required unless tenants are identified by a JWT claim", t.ID)
+		}
+		tn.tenants[t.ID] = &tenant{id: t.ID, models: t.Models, limit: t.RequestsPerMinute}
+		for _, p := range t.Principals {
+			if other, ok := tn.principals[p]; ok {
+				return nil, fmt.Errorf("principal %s belongs to tenants %s and %s", p, other.id, t.ID)
+			}
+			tn.principals[p] = tn.tenants[t.ID]
+		}
+	}
+	return tn, nil
+}
+
+// identify returns the tenant of the authenticated caller of a request. It fails for unknown
+// tenants, for tenant headers that do not match the caller's tenant, and for callers without
+// tenant if one is required.
+func (tn *tenancy) identify(req *http.Request) (*tenant, error) {
+	if tn == nil {
+		return nil, nil
+	}
+	t, err := tn.tenantOf(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	if id := req.Header.Get(tn.header); id != "" && (t == nil || id != t.id) {
+		return nil, fmt.Errorf("%s header does not match the tenant of the caller", tn.header)
+	}
+	if t == nil && tn.required {
+		return nil, errors.New("the caller does not belong to a tenant")
+	}
+	return t, nil
+}
+
+// tenantOf derives the tenant from the tenant claim of a JWT, or from the principal
+func (tn *tenancy) tenantOf(ctx context.Context) (*tenant, error) {
+	if identity, ok := ctx.Value(jwtIdentityKey{}).(*jwtIdentity); ok && tn.claim != "" {
+		id, _ := identity.claims[tn.claim].(string)
+		if id == "" {
+			return nil, nil
+		}
+		t, ok := tn.tenants[id]
+		if !ok {
+			return nil, fmt.Errorf("unknown tenant '%s'", id)
+		}
+		return t, nil
+	}
+	return tn.principals[principal(ctx)], nil
+}
+
+// admit identifies the tenant of a chat completion request and counts it against the
+// tenant's limit. It writes an error and returns false if the request is rejected.
+func (tn *tenancy) admit(w http.ResponseWriter, req *http.Request) (*tenant, bool) {
+	log := logging.FromRequest(logger, req)
+	t, err := tn.identify(req)
+	if err != nil {
+		log.Warning("rejected request:", err)
+		writeError(w, req, http.StatusForbidden, err.Error())
+		return nil, false
+	}
+	if t != nil && !tn.allow(t) {
+		log.Warning("tenant exceeded its request limit:", t.id)
+		writeError(w, req, http.StatusTooManyRequests, "tenant request limit exceeded")
+		return nil, false
+	}
+	return t, true
+}
+
+// allow counts a request for the tenant and reports whether it is within the per-minute limit
+func (tn *tenancy) allow(t *tenant) bool {
+	if t.limit == 0 {
+		return true
+	}
+	tn.mu.Lock()
+	defer tn.mu.Unlock()
+
+	now := tn.now()
+	window, ok := tn.windows[t.id]
+	if !ok || now.Sub(window.start) >= time.Minute {
+		window = &tenantWindow{start: now}
+		tn.windows[t.id] = window
+	}
+	// Rejected requests are not counted, so a tenant can resume as soon as the window ends
+	if window.count >= t.limit {
+		return false
+	}
+	window.count++
+	return true
+}
+
+// allows reports whether the tenant may address a model ID
+func (t *tenant) allows(modelID string) bool {
+	return t == nil || slices.Contains(t.models, allModels) || slices.Contains(t.models, modelID)
+}
+
+// visibleAgents returns the agents a /models request may see. Requests that fail
+// tenant identification see no agents.
+func (tn *tenancy) visibleAgents(req *http.Request, agents []AgentInfo) []AgentInfo {
+	t, err := tn.identify(req)
+	if err != nil {
+		return []AgentInfo{}
+	}
+	if t == nil {
+		return agents
+	}
+	visible := make([]AgentInfo, 0, len(agents))
+	for _, agent := range agents {
+		if t.allows(agent.ModelID) {
+			visible = append(visible, agent)
+		}
+	}
+	return visible
+}
@@ -0,0 +1,245 @@
+package openaia2a
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+const tenantTaskResponse = `{"jsonrpc":"2.0","id":1,"result":{"kind":"task"}}`
+
+func newTenantHandler(t *testing.T, mockHandler http.Handler) http.Handler {
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "team-a/agent", "url": "http://a:8000", "aliases": []interface{}{"agent-a"}},
+				map[string]interface{}{"model_id": "team-b/agent", "url": "http://b:8000"},
+			},
+			"api_keys": map[string]interface{}{
+				"keys": []interface{}{
+					map[string]interface{}{"id": "alice", "secret": "sk-alice-key"},
+					map[string]interface{}{"id": "ops", "secret": "sk-ops-key"},
+					map[string]interface{}{"id": "guest", "secret": "sk-guest-key"},
+				},
+			},
+			"tenancy": map[string]interface{}{
+				"required": true,
+				"tenants": []interface{}{
+					map[string]interface{}{"id": "team-a", "principals": []interface{}{"alice"}, "models": []interface{}{"team-a/agent"}, "requests_per_minute": 2},
+					map[string]interface{}{"id": "platform", "principals": []interface{}{"ops"}, "models": []interface{}{"*"}},
+				},
+			},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+	return handler
+}
+
+func sendTenantChatCompletion(handler http.Handler, model, apiKey, tenantID string) *httptest.ResponseRecorder {
+	reqBody, _ := json.Marshal(models.OpenAIRequest{
+		Model:    model,
+		Messages: []models.OpenAIMessage{{Role: "user", Content: "Hello"}},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/chat/completions", bytes.NewReader(reqBody))
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+	if tenantID != "" {
+		req.Header.Set(defaultTenantHeader, tenantID)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestNewTenancy_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  TenancyConfig
+	}{
+		{name: "no tenants", cfg: TenancyConfig{}},
+		{name: "missing id", cfg: TenancyConfig{Tenants: []TenantConfig{{Principals: []string{"p"}, Models: []string{"*"}}}}},
+		{name: "missing models", cfg: TenancyConfig{Tenants: []TenantConfig{{ID: "a", Principals: []string{"p"}}}}},
+		{name: "missing principals", cfg: TenancyConfig{Tenants: []TenantConfig{{ID: "a", Models: []string{"*"}}}}},
+		{name: "duplicate tenant", cfg: TenancyConfig{Tenants: []TenantConfig{{ID: "a", Principals: []string{"p"}, Models: []string{"*"}}, {ID: "a", Principals: []string{"q"}, Models: []string{"*"}}}}},
+		{name: "principal in two tenants", cfg: TenancyConfig{Tenants: []TenantConfig{{ID: "a", Principals: []string{"p"}, Models: []string{"*"}}, {ID: "b", Principals: []string{"p"}, Models: []string{"*"}}}}},
+		{name: "negative limit", cfg: TenancyConfig{Tenants: []TenantConfig{{ID: "a", Principals: []string{"p"}, Models: []string{"*"}, RequestsPerMinute: -1}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTenancy(&tt.cfg)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestChatCompletions_TenantScope(t *testing.T) {
+	tests := []struct {
+		name       string
+		model      string
+		apiKey     string
+		tenantID   string
+		wantStatus int
+	}{
+		{name: "own model", model: "team-a/agent", apiKey: "sk-alice-key", wantStatus: http.StatusOK},
+		{name: "own model by alias", model: "agent-a", apiKey: "sk-alice-key", wantStatus: http.StatusOK},
+		{name: "matching header", model: "team-a/agent", apiKey: "sk-alice-key", tenantID: "team-a", wantStatus: http.StatusOK},
+		{name: "other tenant's model", model: "team-b/agent", apiKey: "sk-alice-key", wantStatus: http.StatusNotFound},
+		{name: "wildcard", model: "team-b/agent", apiKey: "sk-ops-key", wantStatus: http.StatusOK},
+		{name: "caller without tenant", model: "team-a/agent", apiKey: "sk-guest-key", wantStatus: http.StatusForbidden},
+		{name: "header of another tenant", model: "team-b/agent", apiKey: "sk-alice-key", tenantID: "platform", wantStatus: http.StatusForbidden},
+		{name: "header without tenant", model: "team-a/agent", apiKey: "sk-guest-key", tenantID: "team-a", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := newTenantHandler(t, &MockHandler{Response: []byte(tenantTaskResponse), StatusCode: http.StatusOK})
+
+			rec := sendTenantChatCompletion(handler, tt.model, tt.apiKey, tt.tenantID)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+		})
+	}
+}
+
+func TestChatCompletions_TenantRateLimit(t *testing.T) {
+	handler := newTenantHandler(t, &MockHandler{Response: []byte(tenantTaskResponse), StatusCode: http.StatusOK})
+
+	assert.Equal(t, http.StatusOK, sendTenantChatCompletion(handler, "team-a/agent", "sk-alice-key", "").Code)
+	assert.Equal(t, http.StatusOK, sendTenantChatCompletion(handler, "team-a/agent", "sk-alice-key", "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, sendTenantChatCompletion(handler, "team-a/agent", "sk-alice-key", "").Code)
+	// Other tenants are not affected
+	assert.Equal(t, http.StatusOK, sendTenantChatCompletion(handler, "team-a/agent", "sk-ops-key", "").Code)
+}
+
+func TestTenancy_WindowPerTenant(t *testing.T) {
+	tn, err := newTenancy(&TenancyConfig{Tenants: []TenantConfig{
+		{ID: "a", Principals: []string{"alice"}, Models: []string{"*"}, RequestsPerMinute: 1},
+		{ID: "b", Principals: []string{"bob"}, Models: []string{"*"}, RequestsPerMinute: 1},
+	}})
+	assert.NoError(t, err)
+	now := time.Now()
+	tn.now = func() time.Time { return now }
+	a, b := tn.tenants["a"], tn.tenants["b"]
+
+	assert.True(t, tn.allow(a))
+	now = now.Add(30 * time.Second)
+	assert.True(t, tn.allow(b))
+	assert.False(t, tn.allow(a))
+
+	// The window of a started earlier and ends before the one of b
+	now = now.Add(31 * time.Second)
+	assert.True(t, tn.allow(a))
+	assert.False(t, tn.allow(b))
+}
+
+func TestTenancy_TenantFromJWTClaim(t *testing.T) {
+	idp := newTestIdP(t)
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "team-a/agent", "url": "http://a:8000"},
+				map[string]interface{}{"model_id": "team-b/agent", "url": "http://b:8000"},
+			},
+			"jwt": map[string]interface{}{"jwks_url": idp.server.URL},
+			"tenancy": map[string]interface{}{
+				"claim":    "tenant",
+				"required": true,
+				"tenants": []interface{}{
+					map[string]interface{}{"id": "team-a", "models": []interface{}{"team-a/agent"}},
+				},
+			},
+		},
+	}
+	handler, err := module.registerHandlers(context.Background(), extraConfig, &MockHandler{Response: []byte(tenantTaskResponse), StatusCode: http.StatusOK})
+	assert.NoError(t, err)
+
+	token := func(tenantID string) string {
+		claims := validClaims("*")
+		if tenantID != "" {
+			claims["tenant"] = tenantID
+		}
+		return idp.sign(t, "RS256", "rsa-1", claims)
+	}
+	assert.Equal(t, http.StatusOK, sendTenantChatCompletion(handler, "team-a/agent", token("team-a"), "").Code)
+	assert.Equal(t, http.StatusNotFound, sendTenantChatCompletion(handler, "team-b/agent", token("team-a"), "").Code)
+	assert.Equal(t, http.StatusForbidden, sendTenantChatCompletion(handler, "team-a/agent", token("intruder"), "").Code)
+	assert.Equal(t, http.StatusForbidden, sendTenantChatCompletion(handler, "team-a/agent", token(""), "team-a").Code)
+}
+
+func TestTenancy_RequiresAuthentication(t *testing.T) {
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{},
+			"tenancy": map[string]interface{}{
+				"tenants": []interface{}{map[string]interface{}{"id": "a", "principals": []interface{}{"alice"}, "models": []interface{}{"*"}}},
+			},
+		},
+	}
+	_, err := module.registerHandlers(context.Background(), extraConfig, &MockHandler{})
+	assert.ErrorContains(t, err, "tenants require api_keys or jwt authentication")
+}
+
+func TestModels_TenantScope(t *testing.T) {
+	handler := newTenantHandler(t, &MockHandler{})
+
+	listModels := func(apiKey string) []string {
+		req := httptest.NewRequest(http.MethodGet, "/models", nil)
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		var resp models.OpenAIModelsResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		var ids []string
+		for _, model := range resp.Data {
+			ids = append(ids, model.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"team-a/agent"}, listModels("sk-alice-key"))
+	assert.Equal(t, []string{"team-a/agent", "team-b/agent"}, listModels("sk-ops-key"))
+	assert.Empty(t, listModels("sk-guest-key"))
+}
+
+func TestNativeA2A_TenantScope(t *testing.T) {
+	const message = `{"jsonrpc":"2.0","id":1,"method":"message/send","params":{"message":{"role":"user","messageId":"m1","parts":[{"kind":"text","text":"Hello"}]}}}`
+	tests := []struct {
+		name       string
+		path       string
+		apiKey     string
+		wantStatus int
+	}{
+		{name: "own agent", path: "/team-a/agent", apiKey: "sk-alice-key", wantStatus: http.StatusOK},
+		{name: "other tenant's agent", path: "/team-b/agent", apiKey: "sk-alice-key", wantStatus: http.StatusNotFound},
+		{name: "wildcard", path: "/team-b/agent", apiKey: "sk-ops-key", wantStatus: http.StatusOK},
+		{name: "caller without tenant", path: "/team-a/agent", apiKey: "sk-guest-key", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockHandler := &MockHandler{Response: []byte(tenantTaskResponse), StatusCode: http.StatusOK}
+			handler := newTenantHandler(t, mockHandler)
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewReader([]byte(message)))
+			req.Header.Set("Authorization", "Bearer "+tt.apiKey)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			if tt.wantStatus != http.StatusOK {
+				assert.Contains(t, rec.Body.String(), `"jsonrpc":"2.0"`)
+				assert.Nil(t, mockHandler.ReceivedRequest, "requests to forbidden agents are not forwarded")
+			}
+		})
+	}
+}
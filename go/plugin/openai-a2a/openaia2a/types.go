@@ -0,0 +1,127 @@
+package openaia2a
+
+import (
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/upstream"
+)
+
+// AgentInfo represents an agent configuration
+type AgentInfo struct {
+	ModelID   string          `json:"model_id"`
+	URL       string          `json:"url"`
+	OwnedBy   string          `json:"owned_by"`
+	CreatedAt int64           `json:"createdAt"`
+	Aliases   []string        `json:"aliases,omitempty"`
+	Ensemble  *EnsembleConfig `json:"ensemble,omitempty"`
+
+	LoadBalancing *LoadBalancingConfig `json:"load_balancing,omitempty"`
+	Canary        *CanaryConfig        `json:"canary,omitempty"`
+	MirrorTo      *MirrorConfig        `json:"mirror_to,omitempty"`
+	HealthPath    string               `json:"health_path,omitempty"`
+	Transport     string               `json:"transport,omitempty"`
+	DeprecatedAt  string               `json:"deprecated_at,omitempty"`
+	Sunset        string               `json:"sunset,omitempty"`
+	Cancellation  *CancellationConfig  `json:"cancellation,omitempty"`
+
+	// Description, capabilities, context length and tags are listed in /models for model pickers
+	Description      string   `json:"description,omitempty"`
+	Capabilities     []string `json:"capabilities,omitempty"`
+	MaxContextLength int      `json:"max_context_length,omitempty"`
+	Tags             []string `json:"tags,omitempty"`
+
+	SystemPrompt     string `json:"system_prompt,omitempty"`
+	SystemPromptMode string `json:"system_prompt_mode,omitempty"`
+
+	// TLS overrides the upstream TLS configuration for the requests the plugin sends to the agent
+	TLS *upstream.TLSConfig `json:"tls,omitempty"`
+	// Proxy overrides the upstream proxy, e.g. for agents outside the cluster behind an egress proxy
+	Proxy string `json:"proxy,omitempty"`
+}
+
+// EnsembleConfig maps a single model ID to several agent URLs that receive the same A2A request
+type EnsembleConfig struct {
+	URLs     []string `json:"urls"`
+	Strategy string   `json:"strategy"`
+}
+
+type config struct {
+	Agents       []AgentInfo    `json:"agents"`
+	AgentsSource *AgentsSource  `json:"agents_source,omitempty"`
+	Scripts      ScriptConfig   `json:"scripts"`
+	Sandbox      *SandboxConfig `json:"sandbox,omitempty"`
+	Admin        *AdminConfig   `json:"admin,omitempty"`
+	Health       *HealthConfig  `json:"health,omitempty"`
+
+	ServiceAccounts   *ServiceAccountConfig   `json:"service_accounts,omitempty"`
+	AnomalyDetection  *AnomalyConfig          `json:"anomaly_detection,omitempty"`
+	Stats             *StatsConfig            `json:"stats,omitempty"`
+	Moderation        *ModerationConfig       `json:"moderation,omitempty"`
+	ModelMatching     *ModelMatchingConfig    `json:"model_matching,omitempty"`
+	Redaction         *RedactionConfig        `json:"redaction,omitempty"`
+	Tenancy           *TenancyConfig          `json:"tenancy,omitempty"`
+	APIKeys           *APIKeysConfig          `json:"api_keys,omitempty"`
+	JWT               *JWTConfig              `json:"jwt,omitempty"`
+	MessageMerging    string                  `json:"message_merging,omitempty"`
+	History           *HistoryConfig          `json:"history,omitempty"`
+	Quotas            *QuotaConfig            `json:"quotas,omitempty"`
+	UsageExport       *UsageExportConfig      `json:"usage_export,omitempty"`
+	IDs               *IDConfig               `json:"ids,omitempty"`
+	AgentCard         *GatewayCardConfig      `json:"agent_card,omitempty"`
+	A2AValidation     *A2AValidationConfig    `json:"a2a_validation,omitempty"`
+	PushNotifications *PushNotificationConfig `json:"push_notifications,omitempty"`
+	TaskStore         *TaskStoreConfig        `json:"task_store,omitempty"`
+	Artifacts         *ArtifactConfig         `json:"artifacts,omitempty"`
+	Capture           *CaptureConfig          `json:"capture,omitempty"`
+	Streaming         *StreamingConfig        `json:"streaming,omitempty"`
+	PartialResponses  *PartialResponseConfig  `json:"partial_responses,omitempty"`
+	MaxResponseSize   int                     `json:"max_response_size,omitempty"`
+	// OpenAIErrors writes all errors of /chat/completions and /models in the OpenAI error envelope
+	OpenAIErrors bool `json:"openai_errors,omitempty"`
+	// CapabilityDiscovery adapts streaming, push notifications and content modes to the agent cards of models
+	CapabilityDiscovery *CapabilityDiscoveryConfig `json:"capability_discovery,omitempty"`
+	// SkillRouting routes model parameters like "skill:weather-forecast" to the agent advertising the skill
+	SkillRouting *SkillRoutingConfig `json:"skill_routing,omitempty"`
+	// Orchestration executes pipelines of agents posted to /agents/orchestrate
+	Orchestration *OrchestrationConfig `json:"orchestration,omitempty"`
+	// Webhooks posts signed gateway lifecycle events to operator-defined URLs
+	Webhooks *WebhooksConfig `json:"webhooks,omitempty"`
+	// Diagnostics exposes goroutine and buffer counters at /__debug/ to detect leaks
+	Diagnostics *DiagnosticsConfig `json:"diagnostics,omitempty"`
+	// Upstream tunes the connection pool of the requests the plugin sends to agents itself
+	Upstream *upstream.Config `json:"upstream,omitempty"`
+	// Readiness scores agents by probing their agent cards and evicts those that are not ready
+	Readiness *ReadinessConfig `json:"readiness,omitempty"`
+	// TrustedProxies lists the peers whose X-Forwarded-Proto is honored in the URLs the gateway builds
+	TrustedProxies []string `json:"trusted_proxies,omitempty"`
+
+	scripts   *scriptEngine
+	agents    *agentStore
+	sandbox   *sandbox
+	admin     *admin
+	health    *healthChecker
+	accounts  *serviceAccounts
+	anomalies *anomalyDetector
+	stats     *gatewayStats
+	moderator *moderator
+	redactor  *redactor
+	tenancy   *tenancy
+	apiKeys   *apiKeys
+	jwt       *jwtValidator
+	history   *historyForwarder
+	quotas    *quotas
+	exporter  *usageExporter
+	card      *gatewayCard
+	a2a       *a2aValidator
+	push      *pushRelay
+	tasks     *taskStore
+	artifacts *artifactProxy
+	captures  *captures
+	streamer  *streamer
+	partials  *partialResponder
+	discovery *capabilityDiscovery
+	skills    *skillRouter
+	pipelines *orchestrator
+	debug     *diagnostics
+	readiness *readiness
+	Logging   logging.Options `json:"logging,omitempty"`
+}
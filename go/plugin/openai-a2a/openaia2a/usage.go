@@ -0,0 +1,33 @@
+package openaia2a
+
+import (
+	"unicode/utf8"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+)
+
+// charsPerToken approximates the average token length of English text.
+// A2A agents do not report token consumption, so usage is estimated from text length.
+const charsPerToken = 4
+
+// estimateTokens approximates the number of tokens of a text
+func estimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
+
+// estimateUsage approximates the token usage of a chat completion with one or more choices
+func estimateUsage(req models.OpenAIRequest, completions ...string) *models.OpenAIUsage {
+	prompt := 0
+	for _, msg := range req.Messages {
+		prompt += estimateTokens(msg.Content)
+	}
+	completionTokens := 0
+	for _, completion := range completions {
+		completionTokens += estimateTokens(completion)
+	}
+	return &models.OpenAIUsage{
+		PromptTokens:     prompt,
+		CompletionTokens: completionTokens,
+		TotalTokens:      prompt + completionTokens,
+	}
+}
@@ -0,0 +1,364 @@
+package openaia2a
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/go-http-utils/headers"
+)
+
+const (
+	defaultExportQueueSize     = 1000
+	defaultExportBatchSize     = 100
+	defaultExportFlushInterval = 10 * time.Second
+	defaultExportTimeout       = 5 * time.Second
+	defaultExportMaxSizeMB     = 100
+	defaultExportMaxBackups    = 5
+)
+
+// UsageExportConfig writes a usage record per chat completion as newline-delimited JSON
+// to a rotating file, an HTTP sink, or both, for billing systems to ingest.
+type UsageExportConfig struct {
+	File          *UsageFileSink `json:"file"`
+	HTTP          *UsageHTTPSink `json:"http"`
+	QueueSize     int            `json:"queue_size"`
+	BatchSize     int            `json:"batch_size"`
+	FlushInterval string         `json:"flush_interval"`
+}
+
+// UsageFileSink appends records to a file that is rotated when it exceeds max_size_mb
+type UsageFileSink struct {
+	Path       string `json:"path"`
+	MaxSizeMB  int    `json:"max_size_mb"`
+	MaxBackups int    `json:"max_backups"`
+}
+
+// UsageHTTPSink posts batches of records to a URL
+type UsageHTTPSink struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Timeout string            `json:"timeout"`
+}
+
+// UsageRecord is the billing record of a single chat completion. Tokens are estimated
+// like the usage of the completion response.
+type UsageRecord struct {
+	Timestamp        time.Time `json:"timestamp"`
+	Tenant           string    `json:"tenant,omitempty"`
+	Principal        string    `json:"principal,omitempty"`
+	Model            string    `json:"model"`
+	Status           int       `json:"status"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	TotalTokens      int       `json:"total_tokens"`
+	LatencyMs        int64     `json:"latency_ms"`
+}
+
+type usageRecordKey struct{}
+
+// usageExporter queues records and writes them in batches from a background goroutine,
+// so slow sinks never delay responses. Records are dropped when the queue is full.
+type usageExporter struct {
+	queue         chan UsageRecord
+	batchSize     int
+	flushInterval time.Duration
+	file          *rotatingFile
+	sink          *httpSink
+	now           func() time.Time
+	done          chan struct{}
+}
+
+// newUsageExporter validates the configuration. A nil config disables the export.
+func newUsageExporter(cfg *UsageExportConfig) (*usageExporter, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if cfg.File == nil && cfg.HTTP == nil {
+		return nil, errors.New("at least one of file or http is required")
+	}
+	if cfg.QueueSize < 0 || cfg.BatchSize < 0 {
+		return nil, errors.New("queue_size and batch_size must not be negative")
+	}
+	e := &usageExporter{
+		batchSize:     cfg.BatchSize,
+		flushInterval: defaultExportFlushInterval,
+		now:           time.Now,
+		done:          make(chan struct{}),
+	}
+	queueSize := cfg.QueueSize
+	if queueSize == 0 {
+		queueSize = defaultExportQueueSize
+	}
+	e.queue = make(chan UsageRecord, queueSize)
+	if e.batchSize == 0 {
+		e.batchSize = defaultExportBatchSize
+	}
+	if cfg.FlushInterval != "" {
+		var err error
+		if e.flushInterval, err = time.ParseDuration(cfg.FlushInterval); err != nil || e.flushInterval <= 0 {
+			return nil, fmt.Errorf("invalid flush_interval '%s'", cfg.FlushInterval)
+		}
+	}
+
+	if cfg.HTTP != nil {
+		sink, err := newHTTPSink(cfg.HTTP)
+		if err != nil {
+			return nil, err
+		}
+		e.sink = sink
+	}
+	if cfg.File != nil {
+		file, err := openRotatingFile(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		e.file = file
+	}
+	return e, nil
+}
+
+// start writes queued records until ctx is done, then flushes the remaining records
+func (e *usageExporter) start(ctx context.Context) {
+	if e == nil {
+		return
+	}
+	go func() {
+		defer close(e.done)
+		ticker := time.NewTicker(e.flushInterval)
+		defer ticker.Stop()
+
+		var batch []UsageRecord
+		for {
+			select {
+			case record := <-e.queue:
+				batch = append(batch, record)
+				if len(batch) >= e.batchSize {
+					e.flush(batch)
+					batch = nil
+				}
+			case <-ticker.C:
+				e.flush(batch)
+				batch = nil
+			case <-ctx.Done():
+				for len(e.queue) > 0 {
+					batch = append(batch, <-e.queue)
+					if len(batch) >= e.batchSize {
+						e.flush(batch)
+						batch = nil
+					}
+				}
+				e.flush(batch)
+				e.file.close()
+				return
+			}
+		}
+	}()
+}
+
+// track serves a chat completion through next and queues its usage record.
+// Requests rejected before a model was read are not billed.
+func (e *usageExporter) track(w http.ResponseWriter, req *http.Request, next http.HandlerFunc) {
+	log := logging.FromRequest(logger, req)
+	if e == nil || req.Method != http.MethodPost || req.URL.Path != "/chat/completions" {
+		next(w, req)
+		return
+	}
+
+	record := &UsageRecord{Timestamp: e.now().UTC()}
+	sw := &statusWriter{ResponseWriter: w}
+	next(sw, req.WithContext(context.WithValue(req.Context(), usageRecordKey{}, record)))
+
+	if record.Model == "" {
+		return
+	}
+	record.Status = sw.status
+	if record.Status == 0 {
+		record.Status = http.StatusOK
+	}
+	record.LatencyMs = e.now().Sub(record.Timestamp).Milliseconds()
+	select {
+	case e.queue <- *record:
+	default:
+		log.Warning("usage export queue is full, dropping record")
+	}
+}
+
+// recordUsage attributes the token usage of a completion to the usage record of ctx
+func recordUsage(ctx context.Context, usage *models.OpenAIUsage) {
+	if record, ok := ctx.Value(usageRecordKey{}).(*UsageRecord); ok && usage != nil {
+		record.PromptTokens = usage.PromptTokens
+		record.CompletionTokens = usage.CompletionTokens
+		record.TotalTokens = usage.TotalTokens
+	}
+}
+
+// recordTenant attributes the usage record of ctx to a tenant
+func recordTenant(ctx context.Context, t *tenant) {
+	if record, ok := ctx.Value(usageRecordKey{}).(*UsageRecord); ok && t != nil {
+		record.Tenant = t.id
+	}
+}
+
+// flush writes a batch to all sinks. Failed writes are logged; the records are not retried.
+func (e *usageExporter) flush(batch []UsageRecord) {
+	if len(batch) == 0 {
+		return
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, record := range batch {
+		if err := enc.Encode(record); err != nil {
+			logger.Error("failed to encode usage record:", err)
+			return
+		}
+	}
+	if e.file != nil {
+		if err := e.file.write(buf.Bytes()); err != nil {
+			logger.Error(fmt.Sprintf("failed to write %d usage records to file: %v", len(batch), err))
+		}
+	}
+	if e.sink != nil {
+		if err := e.sink.post(buf.Bytes()); err != nil {
+			logger.Error(fmt.Sprintf("failed to export %d usage records: %v", len(batch), err))
+		}
+	}
+}
+
+// httpSink posts newline-delimited JSON to a URL
+type httpSink struct {
+	url     string
+	headers map[string]string
+	timeout time.Duration
+}
+
+func newHTTPSink(cfg *UsageHTTPSink) (*httpSink, error) {
+	if parsed, err := url.Parse(cfg.URL); err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid http sink url '%s'", cfg.URL)
+	}
+	sink := &httpSink{url: cfg.URL, headers: cfg.Headers, timeout: defaultExportTimeout}
+	if cfg.Timeout != "" {
+		var err error
+		if sink.timeout, err = time.ParseDuration(cfg.Timeout); err != nil || sink.timeout <= 0 {
+			return nil, fmt.Errorf("invalid http sink timeout '%s'", cfg.Timeout)
+		}
+	}
+	return sink, nil
+}
+
+func (s *httpSink) post(body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(headers.ContentType, "application/x-ndjson")
+	for key, value := range s.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := upstreamClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// rotatingFile appends to a file and rotates it to path.1, path.2, ... when it exceeds maxSize
+type rotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+func openRotatingFile(cfg *UsageFileSink) (*rotatingFile, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("file path is required")
+	}
+	if cfg.MaxSizeMB < 0 || cfg.MaxBackups < 0 {
+		return nil, errors.New("max_size_mb and max_backups must not be negative")
+	}
+	f := &rotatingFile{path: cfg.Path, maxSize: int64(cfg.MaxSizeMB) << 20, maxBackups: cfg.MaxBackups}
+	if f.maxSize == 0 {
+		f.maxSize = defaultExportMaxSizeMB << 20
+	}
+	if f.maxBackups == 0 {
+		f.maxBackups = defaultExportMaxBackups
+	}
+	if err := f.open(); err != nil {
+		return nil, fmt.Errorf("cannot open %s: %w", f.path, err)
+	}
+	return f, nil
+}
+
+func (f *rotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+func (f *rotatingFile) write(data []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.size > 0 && f.size+int64(len(data)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			return fmt.Errorf("cannot rotate %s: %w", f.path, err)
+		}
+	}
+	n, err := f.file.Write(data)
+	f.size += int64(n)
+	return err
+}
+
+// rotate shifts the backups, dropping the oldest, and starts a new file
+func (f *rotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	for i := f.maxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(fmt.Sprintf("%s.%d", f.path, i), fmt.Sprintf("%s.%d", f.path, i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(f.path, f.path+".1"); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+func (f *rotatingFile) close() {
+	if f == nil {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.file.Close(); err != nil {
+		logger.Error(fmt.Sprintf("failed to close %s:", f.path), err)
+	}
+}
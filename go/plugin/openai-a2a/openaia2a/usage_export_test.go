@@ -0,0 +1,164 @@
+package openaia2a
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func readUsageRecords(t *testing.T, r io.Reader) []UsageRecord {
+	var records []UsageRecord
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var record UsageRecord
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestNewUsageExporter_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  UsageExportConfig
+	}{
+		{name: "no sink", cfg: UsageExportConfig{}},
+		{name: "invalid flush interval", cfg: UsageExportConfig{HTTP: &UsageHTTPSink{URL: "http://billing:8080"}, FlushInterval: "often"}},
+		{name: "invalid url", cfg: UsageExportConfig{HTTP: &UsageHTTPSink{URL: "billing"}}},
+		{name: "missing path", cfg: UsageExportConfig{File: &UsageFileSink{}}},
+		{name: "negative queue size", cfg: UsageExportConfig{File: &UsageFileSink{Path: "usage.ndjson"}, QueueSize: -1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newUsageExporter(&tt.cfg)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestUsageExport_WritesRecordsToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.ndjson")
+	mockHandler := &MockHandler{
+		Response:   []byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task","status":{"state":"completed"},"artifacts":[{"artifactId":"a","parts":[{"kind":"text","text":"Sunny."}]}]}}`),
+		StatusCode: http.StatusOK,
+	}
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "agent", "url": "http://localhost:8001"},
+			},
+			"api_keys": map[string]interface{}{
+				"keys": []interface{}{map[string]interface{}{"id": "weather-team", "secret": "sk-weather-team-key"}},
+			},
+			"tenancy": map[string]interface{}{
+				"tenants": []interface{}{map[string]interface{}{"id": "acme", "principals": []interface{}{"weather-team"}, "models": []interface{}{"*"}}},
+			},
+			"usage_export": map[string]interface{}{"file": map[string]interface{}{"path": path}, "flush_interval": "10ms"},
+		},
+	}
+	handler, err := module.registerHandlers(t.Context(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+
+	send := func(model, apiKey string) int {
+		reqBody, _ := json.Marshal(models.OpenAIRequest{Model: model, Messages: []models.OpenAIMessage{{Role: "user", Content: "Hello"}}})
+		req := httptest.NewRequest(http.MethodPost, "/chat/completions", bytes.NewReader(reqBody))
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+		req.Header.Set("X-Tenant-ID", "acme")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusOK, send("agent", "sk-weather-team-key"))
+	assert.Equal(t, http.StatusNotFound, send("unknown-agent", "sk-weather-team-key"))
+	// Requests rejected before the model is known are not exported
+	assert.Equal(t, http.StatusUnauthorized, send("agent", "invalid-key"))
+
+	var records []UsageRecord
+	assert.Eventually(t, func() bool {
+		content, err := os.ReadFile(path)
+		records = readUsageRecords(t, strings.NewReader(string(content)))
+		return err == nil && len(records) == 2
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "acme", records[0].Tenant)
+	assert.Equal(t, "weather-team", records[0].Principal)
+	assert.Equal(t, "agent", records[0].Model)
+	assert.Equal(t, http.StatusOK, records[0].Status)
+	assert.Positive(t, records[0].TotalTokens)
+	assert.Equal(t, records[0].PromptTokens+records[0].CompletionTokens, records[0].TotalTokens)
+	assert.Equal(t, "unknown-agent", records[1].Model)
+	assert.Equal(t, http.StatusNotFound, records[1].Status)
+	assert.Zero(t, records[1].TotalTokens)
+}
+
+func TestUsageExport_PostsBatches(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/x-ndjson", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer billing-token", r.Header.Get("Authorization"))
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		mu.Unlock()
+	}))
+	defer sink.Close()
+
+	e, err := newUsageExporter(&UsageExportConfig{
+		HTTP:      &UsageHTTPSink{URL: sink.URL, Headers: map[string]string{"Authorization": "Bearer billing-token"}},
+		BatchSize: 2,
+	})
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	e.start(ctx)
+
+	for _, model := range []string{"a", "b", "c"} {
+		req := httptest.NewRequest(http.MethodPost, "/chat/completions", nil)
+		e.track(httptest.NewRecorder(), req, func(w http.ResponseWriter, req *http.Request) {
+			recordModel(req.Context(), model)
+			w.WriteHeader(http.StatusOK)
+		})
+	}
+	cancel()
+	<-e.done
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Len(t, bodies, 2)
+	assert.Len(t, readUsageRecords(t, strings.NewReader(bodies[0])), 2)
+	assert.Len(t, readUsageRecords(t, strings.NewReader(bodies[1])), 1)
+}
+
+func TestRotatingFile_Rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.ndjson")
+	f, err := openRotatingFile(&UsageFileSink{Path: path, MaxBackups: 2})
+	assert.NoError(t, err)
+	f.maxSize = 10
+	defer f.close()
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		assert.NoError(t, f.write([]byte(line)))
+	}
+
+	for suffix, want := range map[string]string{"": "fourth\n", ".1": "third\n", ".2": "second\n"} {
+		content, err := os.ReadFile(path + suffix)
+		assert.NoError(t, err)
+		assert.Equal(t, want, string(content))
+	}
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+}
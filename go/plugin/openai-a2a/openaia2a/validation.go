@@ -0,0 +1,118 @@
+package openaia2a
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+const chatCompletionSchemaURL = "chat_completion.schema.json"
+
+//go:embed chat_completion.schema.json
+var chatCompletionSchemaJSON []byte
+
+// chatCompletionSchema validates incoming chat completion requests before transformation
+var chatCompletionSchema = mustCompileSchema(chatCompletionSchemaURL, chatCompletionSchemaJSON)
+
+var schemaMessages = message.NewPrinter(language.English)
+
+// RequestValidationError describes the first field of a request violating the schema
+type RequestValidationError struct {
+	Param   string
+	Message string
+}
+
+func (e *RequestValidationError) Error() string {
+	if e.Param == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("invalid value for '%s': %s", e.Param, e.Message)
+}
+
+func mustCompileSchema(url string, raw []byte) *jsonschema.Schema {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(raw))
+	if err != nil {
+		panic(fmt.Sprintf("invalid schema %s: %v", url, err))
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(url, doc); err != nil {
+		panic(fmt.Sprintf("invalid schema %s: %v", url, err))
+	}
+	return compiler.MustCompile(url)
+}
+
+// validateChatCompletionRequest checks a raw request body against the chat completion schema.
+// Malformed JSON and schema violations are both reported as a RequestValidationError.
+func validateChatCompletionRequest(body []byte) error {
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return &RequestValidationError{Message: "request body is not valid JSON"}
+	}
+
+	err = chatCompletionSchema.Validate(instance)
+	if err == nil {
+		return nil
+	}
+	validationErr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return err
+	}
+
+	leaf := firstLeafError(validationErr)
+	param := paramFromLocation(leaf.InstanceLocation)
+	if required, ok := leaf.ErrorKind.(*kind.Required); ok && len(required.Missing) > 0 {
+		param = joinParam(param, required.Missing[0])
+	}
+	return &RequestValidationError{
+		Param:   param,
+		Message: leaf.ErrorKind.LocalizedString(schemaMessages),
+	}
+}
+
+// firstLeafError descends to the most specific cause of a validation error
+func firstLeafError(err *jsonschema.ValidationError) *jsonschema.ValidationError {
+	for len(err.Causes) > 0 {
+		err = err.Causes[0]
+	}
+	return err
+}
+
+// paramFromLocation converts a JSON pointer like /messages/0/role to messages[0].role
+func paramFromLocation(location []string) string {
+	var param string
+	for _, token := range location {
+		param = joinParam(param, token)
+	}
+	return param
+}
+
+func joinParam(param, token string) string {
+	if _, err := strconv.Atoi(token); err == nil {
+		return param + "[" + token + "]"
+	}
+	if param == "" {
+		return token
+	}
+	return param + "." + token
+}
+
+// writeValidationError writes a request validation error in OpenAI error format
+func writeValidationError(w http.ResponseWriter, err *RequestValidationError) {
+	openAIErr := models.OpenAIError{
+		Message: err.Error(),
+		Type:    "invalid_request_error",
+	}
+	if err.Param != "" {
+		param := err.Param
+		openAIErr.Param = &param
+	}
+	writeOpenAIError(w, http.StatusBadRequest, openAIErr)
+}
@@ -0,0 +1,71 @@
+package openaia2a
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateChatCompletionRequest(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantParam string
+		wantErr   bool
+	}{
+		{name: "valid", body: `{"model":"a","messages":[{"role":"user","content":"Hi"}],"temperature":0.7}`},
+		{name: "malformed JSON", body: `{"model":`, wantErr: true},
+		{name: "missing messages", body: `{"model":"a"}`, wantParam: "messages", wantErr: true},
+		{name: "empty messages", body: `{"model":"a","messages":[]}`, wantParam: "messages", wantErr: true},
+		{name: "unknown role", body: `{"model":"a","messages":[{"role":"robot","content":"Hi"}]}`, wantParam: "messages[0].role", wantErr: true},
+		{name: "missing content", body: `{"model":"a","messages":[{"role":"user","content":"Hi"},{"role":"user"}]}`, wantParam: "messages[1].content", wantErr: true},
+		{name: "null content", body: `{"model":"a","messages":[{"role":"user","content":null}]}`, wantParam: "messages[0].content", wantErr: true},
+		{name: "assistant tool calls without content", body: `{"model":"a","messages":[{"role":"user","content":"Hi"},{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"forecast","arguments":"{}"}}]},{"role":"tool","tool_call_id":"call_1","content":"Sunny"}]}`},
+		{name: "assistant without tool calls", body: `{"model":"a","messages":[{"role":"assistant","content":null}]}`, wantParam: "messages[0].content", wantErr: true},
+		{name: "content parts", body: `{"model":"a","messages":[{"role":"user","content":[{"type":"text","text":"Hi"},{"type":"image_url","image_url":{"url":"https://example.com/cat.png"}}]}]}`},
+		{name: "unknown content part", body: `{"model":"a","messages":[{"role":"user","content":[{"type":"video"}]}]}`, wantParam: "messages[0].content[0].type", wantErr: true},
+		{name: "temperature out of range", body: `{"model":"a","messages":[{"role":"user","content":"Hi"}],"temperature":3}`, wantParam: "temperature", wantErr: true},
+		{name: "fractional n", body: `{"model":"a","messages":[{"role":"user","content":"Hi"}],"n":1.5}`, wantParam: "n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateChatCompletionRequest([]byte(tt.body))
+
+			if !tt.wantErr {
+				assert.NoError(t, err)
+				return
+			}
+			var validationErr *RequestValidationError
+			assert.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, tt.wantParam, validationErr.Param)
+			assert.NotEmpty(t, validationErr.Message)
+		})
+	}
+}
+
+func TestChatCompletions_ValidationErrorFormat(t *testing.T) {
+	mockHandler := &MockHandler{}
+	var extraConfig map[string]interface{}
+	json.Unmarshal([]byte(configStrWithAgents), &extraConfig)
+	handler, err := module.registerHandlers(context.Background(), extraConfig, mockHandler)
+	assert.NoError(t, err)
+
+	body := `{"model":"test-agent-v2","messages":[{"role":"user","content":"Hi"}],"top_p":1.5}`
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/chat/completions", bytes.NewReader([]byte(body))))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Nil(t, mockHandler.ReceivedRequest)
+	var errorResp models.OpenAIErrorResponse
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &errorResp))
+	assert.Equal(t, "invalid_request_error", errorResp.Error.Type)
+	assert.Equal(t, "top_p", *errorResp.Error.Param)
+	assert.Contains(t, errorResp.Error.Message, "'top_p'")
+}
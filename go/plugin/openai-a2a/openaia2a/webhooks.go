@@ -0,0 +1,229 @@
+package openaia2a
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/models"
+	"github.com/go-http-utils/headers"
+)
+
+const (
+	defaultWebhookQueueSize = 1000
+	defaultWebhookTimeout   = 5 * time.Second
+)
+
+// Gateway lifecycle events delivered to webhooks
+const (
+	eventAgentRegistered   = "agent.registered"
+	eventAgentUnregistered = "agent.unregistered"
+	eventAgentEvicted      = "agent.evicted"
+	eventAgentAdmitted     = "agent.admitted"
+	eventCircuitOpened     = "circuit.opened"
+	eventQuotaExceeded     = "quota.exceeded"
+	eventTaskCompleted     = "task.completed"
+	eventUsageAnomaly      = "usage.anomaly"
+)
+
+var webhookEvents = []string{eventAgentRegistered, eventAgentUnregistered, eventAgentEvicted, eventAgentAdmitted, eventCircuitOpened, eventQuotaExceeded, eventTaskCompleted, eventUsageAnomaly}
+
+// webhookEventHeader names the event type of a delivery, webhookSignatureHeader carries
+// "t=<unix timestamp>,v1=<hex HMAC-SHA256 of timestamp.body>" for endpoints with a secret
+const (
+	webhookEventHeader     = "X-Gateway-Event"
+	webhookSignatureHeader = "X-Gateway-Signature"
+)
+
+// WebhooksConfig posts gateway lifecycle events as JSON to operator-defined endpoints
+type WebhooksConfig struct {
+	Endpoints []WebhookEndpoint `json:"endpoints"`
+	QueueSize int               `json:"queue_size,omitempty"`
+	Timeout   string            `json:"timeout,omitempty"`
+}
+
+// WebhookEndpoint receives the listed events, or all events if none are listed.
+// Deliveries are signed if a secret is configured, either inline or by environment variable.
+type WebhookEndpoint struct {
+	URL       string   `json:"url"`
+	Events    []string `json:"events,omitempty"`
+	Secret    string   `json:"secret,omitempty"`
+	SecretEnv string   `json:"secret_env,omitempty"`
+}
+
+// WebhookEvent is the body of a delivery
+type WebhookEvent struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	Timestamp time.Time              `json:"timestamp"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// webhookTarget is a validated endpoint
+type webhookTarget struct {
+	url    string
+	events []string
+	secret []byte
+}
+
+// webhookNotifier queues events and delivers them from a background goroutine, so slow
+// endpoints never delay requests. Events are dropped when the queue is full; failed
+// deliveries are logged and not retried.
+type webhookNotifier struct {
+	targets []webhookTarget
+	timeout time.Duration
+	queue   chan WebhookEvent
+	now     func() time.Time
+}
+
+// events is the webhook notifier of the registered handler. Like ids, it is shared by the
+// plugin, so components publish events without holding the configuration.
+var events *webhookNotifier
+
+// newWebhookNotifier validates the configuration. A nil config disables webhooks.
+func newWebhookNotifier(cfg *WebhooksConfig) (*webhookNotifier, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	if len(cfg.Endpoints) == 0 {
+		return nil, errors.New("at least one endpoint is required")
+	}
+	if cfg.QueueSize < 0 {
+		return nil, errors.New("queue_size must not be negative")
+	}
+	n := &webhookNotifier{timeout: defaultWebhookTimeout, now: time.Now}
+	queueSize := cfg.QueueSize
+	if queueSize == 0 {
+		queueSize = defaultWebhookQueueSize
+	}
+	n.queue = make(chan WebhookEvent, queueSize)
+	if cfg.Timeout != "" {
+		var err error
+		if n.timeout, err = time.ParseDuration(cfg.Timeout); err != nil || n.timeout <= 0 {
+			return nil, fmt.Errorf("invalid timeout '%s'", cfg.Timeout)
+		}
+	}
+
+	for i, endpoint := range cfg.Endpoints {
+		if parsed, err := url.Parse(endpoint.URL); err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return nil, fmt.Errorf("endpoints[%d]: invalid url '%s'", i, endpoint.URL)
+		}
+		for _, event := range endpoint.Events {
+			if !slices.Contains(webhookEvents, event) {
+				return nil, fmt.Errorf("endpoints[%d]: unknown event '%s'", i, event)
+			}
+		}
+		secret := endpoint.Secret
+		if endpoint.SecretEnv != "" {
+			if secret = os.Getenv(endpoint.SecretEnv); secret == "" {
+				return nil, fmt.Errorf("endpoints[%d]: environment variable %s is not set", i, endpoint.SecretEnv)
+			}
+		}
+		n.targets = append(n.targets, webhookTarget{url: endpoint.URL, events: endpoint.Events, secret: []byte(secret)})
+	}
+	return n, nil
+}
+
+// start delivers queued events until ctx is done
+func (n *webhookNotifier) start(ctx context.Context) {
+	if n == nil {
+		return
+	}
+	go func() {
+		for {
+			select {
+			case event := <-n.queue:
+				n.deliver(ctx, event)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// publish queues an event for delivery. It is a no-op if webhooks are disabled.
+func (n *webhookNotifier) publish(eventType string, data map[string]interface{}) {
+	if n == nil {
+		return
+	}
+	event := WebhookEvent{ID: newID(), Type: eventType, Timestamp: n.now().UTC(), Data: data}
+	select {
+	case n.queue <- event:
+	default:
+		logger.Warning(fmt.Sprintf("webhook queue is full, dropping %s event", eventType))
+	}
+}
+
+// deliver posts an event to every endpoint subscribed to its type
+func (n *webhookNotifier) deliver(ctx context.Context, event WebhookEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		logger.Error("failed to encode webhook event:", err)
+		return
+	}
+	for _, target := range n.targets {
+		if len(target.events) > 0 && !slices.Contains(target.events, event.Type) {
+			continue
+		}
+		if err := n.post(ctx, target, event, body); err != nil {
+			logger.Error(fmt.Sprintf("failed to deliver %s event %s to %s: %v", event.Type, event.ID, target.url, err))
+		}
+	}
+}
+
+func (n *webhookNotifier) post(ctx context.Context, target webhookTarget, event WebhookEvent, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set(headers.ContentType, "application/json")
+	req.Header.Set(webhookEventHeader, event.Type)
+	if len(target.secret) > 0 {
+		req.Header.Set(webhookSignatureHeader, signWebhook(target.secret, event.Timestamp, body))
+	}
+	resp, err := upstreamClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// signWebhook returns the signature header of a delivery. The timestamp is signed along
+// with the body so receivers can reject replayed deliveries.
+func signWebhook(secret []byte, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(t + "."))
+	mac.Write(body)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// publishTaskCompleted publishes a task reported by an agent once it is completed. The model
+// is unknown for push notifications.
+func publishTaskCompleted(model, taskID, contextID string, state models.TaskState) {
+	if state != models.TaskStateCompleted {
+		return
+	}
+	data := map[string]interface{}{"task_id": taskID, "context_id": contextID}
+	if model != "" {
+		data["model"] = model
+	}
+	events.publish(eventTaskCompleted, data)
+}
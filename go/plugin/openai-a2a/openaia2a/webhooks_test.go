@@ -0,0 +1,189 @@
+package openaia2a
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookDelivery is an event received by a test endpoint along with its raw body and headers
+type webhookDelivery struct {
+	event  WebhookEvent
+	body   []byte
+	header http.Header
+}
+
+func newWebhookReceiver(t *testing.T) (*httptest.Server, chan webhookDelivery) {
+	deliveries := make(chan webhookDelivery, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		var event WebhookEvent
+		assert.NoError(t, json.Unmarshal(body, &event))
+		deliveries <- webhookDelivery{event: event, body: body, header: req.Header}
+	}))
+	t.Cleanup(server.Close)
+	return server, deliveries
+}
+
+func receiveDelivery(t *testing.T, deliveries chan webhookDelivery) webhookDelivery {
+	select {
+	case delivery := <-deliveries:
+		return delivery
+	case <-time.After(5 * time.Second):
+		t.Fatal("no webhook delivery received")
+		return webhookDelivery{}
+	}
+}
+
+// captureEvents replaces the shared notifier by one whose queue is read by the test
+func captureEvents(t *testing.T) chan WebhookEvent {
+	previous := events
+	events = &webhookNotifier{queue: make(chan WebhookEvent, 10), now: time.Now}
+	t.Cleanup(func() { events = previous })
+	return events.queue
+}
+
+func TestWebhooks_DeliversSignedEvents(t *testing.T) {
+	circuitServer, circuitDeliveries := newWebhookReceiver(t)
+	server, deliveries := newWebhookReceiver(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	t.Cleanup(func() { events = nil })
+
+	mockHandler := &MockHandler{
+		Response:   []byte(`{"jsonrpc":"2.0","id":1,"result":{"kind":"task","id":"task-1","contextId":"ctx-1","status":{"state":"completed"},"artifacts":[{"artifactId":"a","parts":[{"kind":"text","text":"Sunny"}]}]}}`),
+		StatusCode: http.StatusOK,
+	}
+	extraConfig := map[string]interface{}{
+		"openai_a2a_config": map[string]interface{}{
+			"agents": []interface{}{
+				map[string]interface{}{"model_id": "agent", "url": "http://localhost:8001"},
+			},
+			"api_keys": map[string]interface{}{
+				"keys": []interface{}{map[string]interface{}{"id": "weather-team", "secret": "sk-weather-team-key"}},
+			},
+			"quotas": map[string]interface{}{"daily": map[string]interface{}{"requests": 1}},
+			"webhooks": map[string]interface{}{
+				"endpoints": []interface{}{
+					map[string]interface{}{"url": circuitServer.URL, "events": []interface{}{"circuit.opened"}},
+					map[string]interface{}{"url": server.URL, "secret": "whsec"},
+				},
+			},
+		},
+	}
+	handler, err := module.registerHandlers(ctx, extraConfig, mockHandler)
+	require.NoError(t, err)
+
+	for _, wantStatus := range []int{http.StatusOK, http.StatusTooManyRequests, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodPost, "/chat/completions", strings.NewReader(`{"model":"agent","messages":[{"role":"user","content":"Hi"}]}`))
+		req.Header.Set("Authorization", "Bearer sk-weather-team-key")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		require.Equal(t, wantStatus, rec.Code)
+	}
+
+	// The request taking the last slot of the quota publishes it before its task completes
+	delivery := receiveDelivery(t, deliveries)
+	assert.Equal(t, eventQuotaExceeded, delivery.event.Type)
+	assert.Equal(t, "weather-team", delivery.event.Data["principal"])
+	assert.Equal(t, "daily", delivery.event.Data["period"])
+	assert.Equal(t, "requests", delivery.event.Data["limit"])
+
+	delivery = receiveDelivery(t, deliveries)
+	assert.Equal(t, eventTaskCompleted, delivery.event.Type)
+	assert.Equal(t, eventTaskCompleted, delivery.header.Get(webhookEventHeader))
+	assert.Equal(t, map[string]interface{}{"model": "agent", "task_id": "task-1", "context_id": "ctx-1"}, delivery.event.Data)
+	timestamp, _, _ := strings.Cut(strings.TrimPrefix(delivery.header.Get(webhookSignatureHeader), "t="), ",")
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	require.NoError(t, err)
+	assert.Equal(t, signWebhook([]byte("whsec"), time.Unix(unix, 0), delivery.body), delivery.header.Get(webhookSignatureHeader))
+
+	// The filtered endpoint is delivered to first, so it would have received events by now
+	assert.Empty(t, circuitDeliveries)
+	select {
+	case delivery := <-deliveries:
+		t.Fatalf("unexpected %s event, the quota is only published once", delivery.event.Type)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestPublishAgentChanges(t *testing.T) {
+	queue := captureEvents(t)
+
+	publishAgentChanges(
+		[]AgentInfo{{ModelID: "weather-agent"}, {ModelID: "travel-agent"}},
+		[]AgentInfo{{ModelID: "travel-agent"}, {ModelID: "chef-agent"}},
+	)
+
+	require.Len(t, queue, 2)
+	registered := <-queue
+	assert.Equal(t, eventAgentRegistered, registered.Type)
+	assert.Equal(t, "chef-agent", registered.Data["model"])
+	unregistered := <-queue
+	assert.Equal(t, eventAgentUnregistered, unregistered.Type)
+	assert.Equal(t, "weather-agent", unregistered.Data["model"])
+}
+
+func TestReplicaBalancer_MarkFailedOpensCircuit(t *testing.T) {
+	queue := captureEvents(t)
+	b, err := newReplicaBalancer(LoadBalancingConfig{Replicas: []Replica{{URL: "http://replica-1:8000"}}, UnhealthyCooldown: "30s"})
+	require.NoError(t, err)
+	b.model = "weather-agent"
+	now := time.Now()
+	b.now = func() time.Time { return now }
+
+	b.markFailed("http://replica-1:8000")
+	b.markFailed("http://replica-1:8000")
+	require.Len(t, queue, 1, "a replica that is already out of rotation does not open its circuit again")
+	event := <-queue
+	assert.Equal(t, eventCircuitOpened, event.Type)
+	assert.Equal(t, map[string]interface{}{"model": "weather-agent", "replica": "http://replica-1:8000", "cooldown": "30s"}, event.Data)
+
+	now = now.Add(time.Minute)
+	b.markFailed("http://replica-1:8000")
+	assert.Len(t, queue, 1)
+}
+
+func TestPublishTaskCompleted(t *testing.T) {
+	queue := captureEvents(t)
+
+	publishTaskCompleted("", "task-1", "ctx-1", "working")
+	publishTaskCompleted("", "task-1", "ctx-1", "completed")
+
+	require.Len(t, queue, 1)
+	assert.Equal(t, map[string]interface{}{"task_id": "task-1", "context_id": "ctx-1"}, (<-queue).Data)
+}
+
+func TestNewWebhookNotifier_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  WebhooksConfig
+	}{
+		{name: "no endpoints", cfg: WebhooksConfig{}},
+		{name: "invalid url", cfg: WebhooksConfig{Endpoints: []WebhookEndpoint{{URL: "hooks.example.com"}}}},
+		{name: "unknown event", cfg: WebhooksConfig{Endpoints: []WebhookEndpoint{{URL: "https://hooks.example.com", Events: []string{"agent.deleted"}}}}},
+		{name: "unset secret_env", cfg: WebhooksConfig{Endpoints: []WebhookEndpoint{{URL: "https://hooks.example.com", SecretEnv: "GATEWAY_TEST_UNSET_WEBHOOK_SECRET"}}}},
+		{name: "negative queue_size", cfg: WebhooksConfig{Endpoints: []WebhookEndpoint{{URL: "https://hooks.example.com"}}, QueueSize: -1}},
+		{name: "invalid timeout", cfg: WebhooksConfig{Endpoints: []WebhookEndpoint{{URL: "https://hooks.example.com"}}, Timeout: "soon"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newWebhookNotifier(&tt.cfg)
+			assert.Error(t, err)
+		})
+	}
+
+	n, err := newWebhookNotifier(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, n)
+	n.publish(eventAgentRegistered, nil)
+}
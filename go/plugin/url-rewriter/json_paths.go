@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// jsonPaths select the string values that are rewritten. Segments are object keys or "*" for all
+// keys and array elements, e.g. result.artifacts.*.parts.*.file.uri. Without paths, all string
+// values of a response are candidates.
+type jsonPaths [][]string
+
+func parseJSONPaths(paths []string) (jsonPaths, error) {
+	parsed := make(jsonPaths, 0, len(paths))
+	for _, path := range paths {
+		segments := strings.Split(path, ".")
+		for _, segment := range segments {
+			if segment == "" {
+				return nil, fmt.Errorf("invalid JSON path '%s'", path)
+			}
+		}
+		parsed = append(parsed, segments)
+	}
+	return parsed, nil
+}
+
+// apply replaces the selected string values of a decoded JSON value by fn of them
+func (p jsonPaths) apply(value interface{}, fn func(string) string) interface{} {
+	if len(p) == 0 {
+		return applyAll(value, fn)
+	}
+	for _, path := range p {
+		value = applyPath(value, path, fn)
+	}
+	return value
+}
+
+// applyAll replaces every string value, but not object keys
+func applyAll(value interface{}, fn func(string) string) interface{} {
+	switch v := value.(type) {
+	case string:
+		return fn(v)
+	case map[string]interface{}:
+		for key, child := range v {
+			v[key] = applyAll(child, fn)
+		}
+	case []interface{}:
+		for i, child := range v {
+			v[i] = applyAll(child, fn)
+		}
+	}
+	return value
+}
+
+func applyPath(value interface{}, path []string, fn func(string) string) interface{} {
+	if len(path) == 0 {
+		if s, ok := value.(string); ok {
+			return fn(s)
+		}
+		return value
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if path[0] == "*" || path[0] == key {
+				v[key] = applyPath(child, path[1:], fn)
+			}
+		}
+	case []interface{}:
+		if path[0] != "*" {
+			return value
+		}
+		for i, child := range v {
+			v[i] = applyPath(child, path[1:], fn)
+		}
+	}
+	return value
+}
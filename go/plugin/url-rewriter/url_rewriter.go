@@ -0,0 +1,263 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/agentic-layer/agent-gateway-krakend/lib/capture"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/configschema"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/logging"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/netclass"
+	"github.com/agentic-layer/agent-gateway-krakend/lib/pluginkit"
+)
+
+const (
+	pluginName = "url-rewriter"
+	configKey  = "url_rewriter_config"
+
+	// defaultMaxBodySize limits how much of a response is buffered to rewrite it
+	defaultMaxBodySize = 8 << 20
+)
+
+// invalidatedHeaders describe the original body and are dropped from rewritten responses
+var invalidatedHeaders = []string{"Content-Length", "ETag", "Content-MD5", "Digest", "Content-Digest", "Repr-Digest"}
+
+type config struct {
+	Endpoints     []string        `json:"endpoints"`
+	JSONPaths     []string        `json:"json_paths,omitempty"`
+	InternalHosts []string        `json:"internal_hosts,omitempty"`
+	ExternalURL   string          `json:"external_url,omitempty"`
+	MaxBodySize   int             `json:"max_body_size,omitempty"`
+	Logging       logging.Options `json:"logging,omitempty"`
+}
+
+// settings holds the validated configuration
+type settings struct {
+	endpoints   []string
+	paths       jsonPaths
+	internal    *netclass.Classifier
+	externalURL string
+	maxBodySize int
+}
+
+type registerer string
+
+// HandlerRegisterer is the symbol KrakenD looks up to register plugins
+var HandlerRegisterer = registerer(pluginName)
+var logger = logging.New(pluginName)
+
+func main() {}
+
+func init() {
+	logger.Info("loaded")
+}
+
+// RegisterHandlers registers the plugin with KrakenD
+func (r registerer) RegisterHandlers(f func(
+	name string,
+	handler func(context.Context, map[string]interface{}, http.Handler) (http.Handler, error),
+)) {
+	pluginkit.RegisterHandlers(f, string(r), r.registerHandlers, logger)
+}
+
+func (r registerer) RegisterLogger(v interface{}) {
+	pluginkit.RegisterLogger(v, pluginName, &logger)
+}
+
+func parseConfig(extra map[string]interface{}) (config, error) {
+	var cfg config
+	if err := configschema.Validate(pluginName, extra[configKey]); err != nil {
+		return cfg, fmt.Errorf("invalid extra_config.%s: %w", configKey, err)
+	}
+	err := pluginkit.DecodeConfig(extra, configKey, &cfg)
+	return cfg, err
+}
+
+func (r registerer) registerHandlers(_ context.Context, extra map[string]interface{}, handler http.Handler) (http.Handler, error) {
+	cfg, err := parseConfig(extra)
+	if err != nil {
+		return nil, err
+	}
+	if err := logging.Configure(pluginName, cfg.Logging); err != nil {
+		return nil, fmt.Errorf("invalid logging configuration: %w", err)
+	}
+	s, err := newSettings(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info(fmt.Sprintf("plugin initialized successfully for %d endpoints", len(s.endpoints)))
+	return http.HandlerFunc(r.handleRequest(handler, s)), nil
+}
+
+func newSettings(cfg config) (settings, error) {
+	s := settings{maxBodySize: defaultMaxBodySize}
+	if len(cfg.Endpoints) == 0 {
+		return s, fmt.Errorf("invalid endpoints configuration: at least one endpoint is required")
+	}
+	for _, endpoint := range cfg.Endpoints {
+		if !strings.HasPrefix(endpoint, "/") || endpoint == "/" {
+			return s, fmt.Errorf("invalid endpoints configuration: endpoint '%s' must start with / and name an agent", endpoint)
+		}
+		s.endpoints = append(s.endpoints, strings.TrimSuffix(endpoint, "/"))
+	}
+
+	var err error
+	if s.paths, err = parseJSONPaths(cfg.JSONPaths); err != nil {
+		return s, fmt.Errorf("invalid json_paths configuration: %w", err)
+	}
+	if s.internal, err = netclass.New(cfg.InternalHosts); err != nil {
+		return s, fmt.Errorf("invalid internal_hosts configuration: %w", err)
+	}
+	if cfg.ExternalURL != "" {
+		parsed, err := url.Parse(cfg.ExternalURL)
+		if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			return s, fmt.Errorf("invalid external_url configuration: '%s', expected http(s)://host", cfg.ExternalURL)
+		}
+		s.externalURL = strings.TrimSuffix(cfg.ExternalURL, "/")
+	}
+	if cfg.MaxBodySize < 0 {
+		return s, fmt.Errorf("invalid max_body_size configuration: %d is negative", cfg.MaxBodySize)
+	}
+	if cfg.MaxBodySize > 0 {
+		s.maxBodySize = cfg.MaxBodySize
+	}
+	return s, nil
+}
+
+// endpoint returns the configured endpoint a request path belongs to
+func (s settings) endpoint(path string) (string, bool) {
+	for _, endpoint := range s.endpoints {
+		if path == endpoint || strings.HasPrefix(path, endpoint+"/") {
+			return endpoint, true
+		}
+	}
+	return "", false
+}
+
+// gatewayURL returns the external URL of the gateway: the configured one or the scheme and host
+// the request was sent to
+func (s settings) gatewayURL(req *http.Request) string {
+	if s.externalURL != "" {
+		return s.externalURL
+	}
+	scheme := "http"
+	if req.TLS != nil {
+		scheme = "https"
+	}
+	if proto := strings.ToLower(req.Header.Get("X-Forwarded-Proto")); proto == "http" || proto == "https" {
+		scheme = proto
+	}
+	host := req.Host
+	if forwarded := strings.TrimSpace(strings.Split(req.Header.Get("X-Forwarded-Host"), ",")[0]); forwarded != "" {
+		host = forwarded
+	}
+	return scheme + "://" + host
+}
+
+func (r registerer) handleRequest(handler http.Handler, s settings) func(w http.ResponseWriter, req *http.Request) {
+	return func(w http.ResponseWriter, req *http.Request) {
+		log := logging.FromRequest(logger, req)
+		endpoint, ok := s.endpoint(req.URL.Path)
+		if !ok {
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		rw := capture.New(w, s.maxBodySize)
+		defer rw.Release()
+		handler.ServeHTTP(rw, req)
+
+		// Responses too large to buffer have been passed through unchanged
+		if rw.Passthrough() {
+			log.Warning(fmt.Sprintf("response of %s exceeds %d bytes - passed through without rewriting", req.URL.Path, s.maxBodySize))
+			return
+		}
+
+		body, rewritten := rewriteResponse(rw, s, s.gatewayURL(req)+endpoint)
+		if rewritten {
+			log.Debug(fmt.Sprintf("rewrote internal URLs in response of %s", req.URL.Path))
+			capture.CopyHeader(w.Header(), rw.Header(), invalidatedHeaders...)
+		} else {
+			capture.CopyHeader(w.Header(), rw.Header())
+		}
+		w.WriteHeader(rw.StatusCode())
+		if _, err := w.Write(body); err != nil {
+			log.Error("failed to write response:", err)
+		}
+	}
+}
+
+// rewriteResponse rewrites the internal URLs in an uncompressed JSON response. The original body
+// is returned if the response is not JSON or contains no internal URLs.
+func rewriteResponse(rw *capture.Writer, s settings, baseURL string) ([]byte, bool) {
+	original := rw.Body()
+	if encoding := rw.Header().Get("Content-Encoding"); encoding != "" && encoding != "identity" {
+		return original, false
+	}
+	mediaType, _, _ := mime.ParseMediaType(rw.Header().Get("Content-Type"))
+	if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+		return original, false
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(original, &value); err != nil {
+		logger.Warning(fmt.Sprintf("cannot parse JSON response: %v - passed through without rewriting", err))
+		return original, false
+	}
+	r := &rewriter{internal: s.internal, baseURL: baseURL}
+	value = s.paths.apply(value, r.rewrite)
+	if r.count == 0 {
+		return original, false
+	}
+
+	body, err := json.Marshal(value)
+	if err != nil {
+		logger.Error("failed to marshal rewritten response:", err)
+		return original, false
+	}
+	return body, true
+}
+
+// rewriter replaces internal URLs by URLs below the gateway URL of the endpoint
+type rewriter struct {
+	internal *netclass.Classifier
+	baseURL  string
+	count    int
+}
+
+// rewrite returns the gateway URL for a string value that is an internal http(s) or ws(s) URL.
+// The path, query and fragment of the internal URL are kept below the endpoint.
+func (r *rewriter) rewrite(value string) string {
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Host == "" || !r.internal.IsPrivateHost(parsed.Hostname()) {
+		return value
+	}
+	base, err := url.Parse(r.baseURL)
+	if err != nil {
+		return value
+	}
+
+	switch parsed.Scheme {
+	case "http", "https":
+	case "ws", "wss":
+		if base.Scheme == "https" {
+			base.Scheme = "wss"
+		} else {
+			base.Scheme = "ws"
+		}
+	default:
+		return value
+	}
+	base.Path = strings.TrimSuffix(base.Path, "/") + parsed.Path
+	base.RawPath = ""
+	base.RawQuery = parsed.RawQuery
+	base.Fragment = parsed.Fragment
+	r.count++
+	return base.String()
+}
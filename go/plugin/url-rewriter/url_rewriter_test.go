@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func jsonBackend(body string, header http.Header) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for name, values := range header {
+			w.Header()[name] = values
+		}
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		w.Header().Set("Content-Length", "999")
+		_, _ = w.Write([]byte(body))
+	})
+}
+
+func createHandler(t *testing.T, cfg map[string]interface{}, backend http.Handler) http.Handler {
+	handler, err := HandlerRegisterer.registerHandlers(context.Background(), map[string]interface{}{configKey: cfg}, backend)
+	assert.NoError(t, err)
+	return handler
+}
+
+func request(handler http.Handler, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, nil)
+	req.Host = "gateway.example.com"
+	req.Header.Set("X-Forwarded-Proto", "https")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRegisterHandlers_InvalidConfig(t *testing.T) {
+	for _, cfg := range []map[string]interface{}{
+		{},
+		{"endpoints": []interface{}{"weather-agent"}},
+		{"endpoints": []interface{}{"/"}},
+		{"endpoints": []interface{}{"/weather-agent"}, "json_paths": []interface{}{"result..uri"}},
+		{"endpoints": []interface{}{"/weather-agent"}, "internal_hosts": []interface{}{"10.0.0.0/33"}},
+		{"endpoints": []interface{}{"/weather-agent"}, "external_url": "agents.example.com"},
+		{"endpoints": []interface{}{"/weather-agent"}, "max_body_size": -1},
+	} {
+		_, err := HandlerRegisterer.registerHandlers(context.Background(), map[string]interface{}{configKey: cfg}, http.NotFoundHandler())
+		assert.Error(t, err, cfg)
+	}
+}
+
+func TestRewrite_AllStringValues(t *testing.T) {
+	backend := jsonBackend(`{
+		"jsonrpc": "2.0",
+		"result": {
+			"callback": "http://weather.agents.svc.cluster.local:8000/callbacks/1?token=abc",
+			"stream": "ws://10.0.3.7:8000/events",
+			"artifacts": [{"parts": [{"kind": "file", "file": {"uri": "http://localhost:9000/files/report.pdf#page=2"}}]}],
+			"public": "https://example.com/docs",
+			"text": "see http://localhost/readme",
+			"http://localhost/key": 1
+		}
+	}`, http.Header{"Etag": {`"v1"`}, "Cache-Control": {"no-store"}})
+	handler := createHandler(t, map[string]interface{}{"endpoints": []interface{}{"/weather-agent"}}, backend)
+
+	rec := request(handler, "/weather-agent")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("ETag"))
+	assert.Empty(t, rec.Header().Get("Content-Length"))
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+
+	var body struct {
+		Result map[string]interface{} `json:"result"`
+	}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	result := body.Result
+	assert.Equal(t, "https://gateway.example.com/weather-agent/callbacks/1?token=abc", result["callback"])
+	assert.Equal(t, "wss://gateway.example.com/weather-agent/events", result["stream"])
+	file := result["artifacts"].([]interface{})[0].(map[string]interface{})["parts"].([]interface{})[0].(map[string]interface{})["file"].(map[string]interface{})
+	assert.Equal(t, "https://gateway.example.com/weather-agent/files/report.pdf#page=2", file["uri"])
+	assert.Equal(t, "https://example.com/docs", result["public"])
+	assert.Equal(t, "see http://localhost/readme", result["text"])
+	assert.Contains(t, result, "http://localhost/key")
+}
+
+func TestRewrite_JSONPaths(t *testing.T) {
+	backend := jsonBackend(`{"result":{"callback":"http://localhost/cb","artifacts":[{"uri":"http://minio:9000/a"},{"uri":"http://localhost/b"}]}}`, nil)
+	handler := createHandler(t, map[string]interface{}{
+		"endpoints":      []interface{}{"/weather-agent/"},
+		"json_paths":     []interface{}{"result.artifacts.*.uri"},
+		"internal_hosts": []interface{}{"minio"},
+		"external_url":   "https://agents.example.com/",
+	}, backend)
+
+	rec := request(handler, "/weather-agent/v1/message:send")
+
+	assert.JSONEq(t, `{"result":{"callback":"http://localhost/cb","artifacts":[
+		{"uri":"https://agents.example.com/weather-agent/a"},
+		{"uri":"https://agents.example.com/weather-agent/b"}
+	]}}`, rec.Body.String())
+}
+
+func TestRewrite_PassesThrough(t *testing.T) {
+	internal := `{"uri":"http://localhost/a"}`
+	tests := []struct {
+		name   string
+		path   string
+		header http.Header
+		cfg    map[string]interface{}
+	}{
+		{name: "other endpoint", path: "/weather-agent-v2"},
+		{name: "no internal URLs", path: "/weather-agent", header: http.Header{"Etag": {`"v1"`}}},
+		{name: "not JSON", path: "/weather-agent", header: http.Header{"Content-Type": {"text/event-stream"}}},
+		{name: "compressed", path: "/weather-agent", header: http.Header{"Content-Encoding": {"br"}}},
+		{name: "too large", path: "/weather-agent", cfg: map[string]interface{}{"max_body_size": 8}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := internal
+			if tt.name == "no internal URLs" {
+				body = `{"uri":"https://example.com/a"}`
+			}
+			cfg := map[string]interface{}{"endpoints": []interface{}{"/weather-agent"}}
+			for key, value := range tt.cfg {
+				cfg[key] = value
+			}
+			handler := createHandler(t, cfg, jsonBackend(body, tt.header))
+
+			rec := request(handler, tt.path)
+
+			assert.Equal(t, body, rec.Body.String())
+			assert.Equal(t, "999", rec.Header().Get("Content-Length"))
+			if tt.header.Get("Etag") != "" {
+				assert.Equal(t, `"v1"`, rec.Header().Get("ETag"))
+			}
+		})
+	}
+}
+
+func TestRewrite_KeepsStatus(t *testing.T) {
+	backend := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"type":"http://127.0.0.1/errors/not-found"}`))
+	})
+	handler := createHandler(t, map[string]interface{}{"endpoints": []interface{}{"/weather-agent"}}, backend)
+
+	rec := request(handler, "/weather-agent/tasks/1")
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	assert.True(t, strings.Contains(rec.Body.String(), "https://gateway.example.com/weather-agent/errors/not-found"))
+}